@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+)
+
+// adaptiveBlocks enables the experimental --adaptive mode of decode.
+var adaptiveBlocks bool
+
+// newAdaptiveDecoder runs the analysis pass of --adaptive over inputFile,
+// read with inChannels channels (1 for a duplicated mono input), and
+// returns a decoder for the resulting plan. The allowed block sizes are
+// half, once and twice the configured one.
+func newAdaptiveDecoder(inputFile string, inChannels int, sampleRate uint32, newDecoder func(blockSize, overlap int) *sq.Decoder) (*decoder.AdaptiveDecoder, error) {
+	config := decoder.DefaultAdaptiveConfig(blockSize, overlap)
+	analyzer, err := decoder.NewTransientAnalyzer(int(sampleRate), config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --adaptive settings: %w", err)
+	}
+
+	input, err := openStream(inputFile, inChannels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	defer input.Close()
+	buf := make([][]float64, inChannels)
+	for ch := range buf {
+		buf[ch] = make([]float64, 1<<16)
+	}
+	for {
+		n, err := input.source.ReadFrames(buf)
+		if n > 0 {
+			// A mono input is analyzed as LT = RT.
+			analyzer.Add([][]float64{buf[0][:n], buf[inChannels-1][:n]})
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+	}
+
+	// The output is timed like a decode with the longest block.
+	plan := analyzer.Plan()
+	longest := config.BlockSizes[len(config.BlockSizes)-1]
+	latency := decoder.LatencyFor(longest, longest*overlap/blockSize)
+	logger.Info("adaptive analysis",
+		"block_sizes", fmt.Sprint(config.BlockSizes),
+		"thresholds_per_s", fmt.Sprint(config.Thresholds),
+		"regions", len(plan),
+		"latency_samples", latency,
+		"latency", samplesDuration(latency, sampleRate))
+	for _, region := range plan {
+		logger.Info("adaptive region",
+			"start", samplesDuration(region.Start, sampleRate),
+			"end", samplesDuration(region.End, sampleRate),
+			"block_size", region.BlockSize,
+			"onsets_per_s", region.Density)
+	}
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("--adaptive: input has no frames")
+	}
+	return decoder.NewAdaptiveDecoder(plan, config, newDecoder)
+}
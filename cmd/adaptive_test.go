@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecode_AdaptiveSteadyInputUsesLongBlocks(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 16000)
+	adaptive := filepath.Join(dir, "adaptive.wav")
+	fixed := filepath.Join(dir, "fixed.wav")
+
+	// Steady tones have no onsets, so the whole input is one region of
+	// twice the block size.
+	if err := runCLI(t, "decode", input, adaptive, "--adaptive", "-b", "1024", "-o", "512"); err != nil {
+		t.Fatalf("decode --adaptive error = %v", err)
+	}
+	if err := runCLI(t, "decode", input, fixed, "-b", "2048", "-o", "1024"); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	got, err := os.ReadFile(adaptive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("--adaptive output differs from a decode with block size 2048")
+	}
+
+	if err := runCLI(t, "decode", input, adaptive, "--adaptive", "--skip-silence"); err == nil {
+		t.Fatalf("decode --adaptive --skip-silence succeeded")
+	}
+}
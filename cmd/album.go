@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/loudness"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Album loudness normalization of batch.
+var (
+	albumGain    bool
+	albumTarget  float64
+	albumCeiling float64
+)
+
+func addAlbumFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&albumGain, "album-gain", false, "loudness-match the outputs with one gain for all files (decodes twice)")
+	flags.Float64Var(&albumTarget, "target-lufs", -18, "integrated loudness of all outputs together with --album-gain")
+	flags.Float64Var(&albumCeiling, "max-true-peak", -1, "true-peak ceiling in dBTP that --album-gain does not exceed")
+}
+
+// measureOutput returns the BS.1770 measurement of a decoded output.
+func measureOutput(path string) (*loudness.Meter, error) {
+	mode, err := decoder.ParseBackChannelMode(backMode)
+	if err != nil {
+		return nil, err
+	}
+	channels := mode.Channels()
+	in, err := openStream(path, channels)
+	if err != nil {
+		return nil, fmt.Errorf("loudness measurement: %w", err)
+	}
+	defer in.Close()
+	meter, err := loudness.NewMeter(int(in.sampleRate), loudness.QuadWeights(channels))
+	if err != nil {
+		return nil, err
+	}
+	buf := make([][]float64, channels)
+	for ch := range buf {
+		buf[ch] = make([]float64, 1<<16)
+	}
+	for {
+		n, err := in.source.ReadFrames(buf)
+		if n > 0 {
+			frames := make([][]float64, channels)
+			for ch := range frames {
+				frames[ch] = buf[ch][:n]
+			}
+			if err := meter.Add(frames); err != nil {
+				return nil, err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return meter, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loudness measurement: %w", err)
+		}
+	}
+}
+
+// applyAlbumGain is the second pass of --album-gain: it computes the album
+// gain from the first-pass measurements of the successfully decoded
+// entries and decodes those files again with it. save is called after
+// every file. It returns the entries that failed in the second pass.
+func applyAlbumGain(cmd *cobra.Command, manifest *batch.Manifest, meters []*loudness.Meter, save func() error) ([]batch.Entry, error) {
+	var measured []*loudness.Meter
+	for _, m := range meters {
+		if m != nil {
+			measured = append(measured, m)
+		}
+	}
+	album := loudness.AlbumGain(measured, albumTarget, albumCeiling)
+	logger.Info("album loudness",
+		"loudness_lufs", album.LoudnessLUFS,
+		"true_peak_dbtp", album.TruePeakDBTP,
+		"gain_db", album.GainDB,
+		"limited", album.Limited)
+	if album.Limited {
+		logger.Warn("album gain limited by the true-peak ceiling",
+			"target_lufs", albumTarget,
+			"reached_lufs", album.LoudnessLUFS+album.GainDB,
+			"max_true_peak", albumCeiling)
+	}
+	manifest.Album = &batch.Album{
+		TargetLUFS:   albumTarget,
+		CeilingDBTP:  albumCeiling,
+		LoudnessLUFS: finite(album.LoudnessLUFS),
+		TruePeakDBTP: finite(album.TruePeakDBTP),
+		GainDB:       album.GainDB,
+		Limited:      album.Limited,
+	}
+
+	var failures []batch.Entry
+	base := outputGain
+	defer func() { outputGain = base }()
+	outputGain = base + album.GainDB
+	for i, m := range meters {
+		if m == nil {
+			continue
+		}
+		first := manifest.Files[i]
+		logger.Info("applying album gain", "path", first.Input, "gain_db", album.GainDB)
+		entry := decodeBatchFile(cmd, first.Input, first.Output)
+		entry.Elapsed += first.Elapsed
+		entry.Loudness = &batch.Loudness{
+			IntegratedLUFS: finite(m.Integrated()),
+			TruePeakDBTP:   finite(m.TruePeak()),
+			AppliedGainDB:  album.GainDB,
+		}
+		manifest.Files[i] = entry
+		if err := save(); err != nil {
+			return nil, err
+		}
+		if !entry.OK() {
+			logger.Warn("decoding failed", "path", entry.Input, "error", entry.Error)
+			failures = append(failures, entry)
+			if batchOnError == "stop" {
+				break
+			}
+		}
+	}
+	return failures, nil
+}
+
+// finite returns a pointer to v, or nil when v is infinite, which JSON
+// cannot represent.
+func finite(v float64) *float64 {
+	if math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+	"github.com/cwbudde/go-sq-tool/internal/loudness"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// writeAlbumDir writes stereo 1 kHz tones of the given peak levels as
+// 1.wav, 2.wav, ... at 16 kHz and returns the directory.
+func writeAlbumDir(t *testing.T, levels ...float64) string {
+	t.Helper()
+	const rate, frames = 16000, 3 * 16000
+	dir := t.TempDir()
+	for n, level := range levels {
+		data := &wav.AudioData{SampleRate: rate, NumSamples: frames, Samples: make([][]float64, 2)}
+		for ch := range data.Samples {
+			data.Samples[ch] = make([]float64, frames)
+			for i := range data.Samples[ch] {
+				data.Samples[ch][i] = level * math.Sin(2*math.Pi*1000*float64(i)/rate)
+			}
+		}
+		if err := wav.WriteStereoWAV(filepath.Join(dir, string(rune('1'+n))+".wav"), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// albumMeters measures the decoded outputs.
+func albumMeters(t *testing.T, dir string, names ...string) []*loudness.Meter {
+	t.Helper()
+	meters := make([]*loudness.Meter, len(names))
+	for i, name := range names {
+		m, err := measureOutput(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		meters[i] = m
+	}
+	return meters
+}
+
+func TestBatch_AlbumGain(t *testing.T) {
+	// Two tracks 12 dB apart.
+	in := writeAlbumDir(t, 0.2, 0.05)
+	out := t.TempDir()
+	if err := runCLI(t, "batch", "--album-gain", "--target-lufs", "-20", in, out); err != nil {
+		t.Fatalf("batch --album-gain error = %v", err)
+	}
+	manifest, err := batch.Load(filepath.Join(out, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	album := manifest.Album
+	if album == nil || album.LoudnessLUFS == nil || album.Limited {
+		t.Fatalf("manifest album = %+v, want an unlimited album gain", album)
+	}
+	if math.Abs(album.GainDB-(-20-*album.LoudnessLUFS)) > 1e-9 {
+		t.Fatalf("album gain = %g dB, want %g", album.GainDB, -20-*album.LoudnessLUFS)
+	}
+
+	// Together the outputs are at the target, and they are still 12 dB
+	// apart.
+	meters := albumMeters(t, out, "1.wav", "2.wav")
+	if got := loudness.AlbumGain(meters, -20, 0).LoudnessLUFS; math.Abs(got+20) > 0.1 {
+		t.Fatalf("album loudness of the outputs = %.2f LUFS, want -20", got)
+	}
+	if diff := meters[0].Integrated() - meters[1].Integrated(); math.Abs(diff-12.04) > 0.1 {
+		t.Fatalf("track loudness difference = %.2f LU, want 12.04", diff)
+	}
+	for i, entry := range manifest.Files {
+		l := entry.Loudness
+		if l == nil || l.IntegratedLUFS == nil || l.AppliedGainDB != album.GainDB {
+			t.Fatalf("entry %d loudness = %+v, want the album gain %g", i, l, album.GainDB)
+		}
+		if got := *l.IntegratedLUFS + l.AppliedGainDB; math.Abs(got-meters[i].Integrated()) > 0.1 {
+			t.Fatalf("entry %d: measured %.2f + gain %.2f dB, output reads %.2f LUFS",
+				i, *l.IntegratedLUFS, l.AppliedGainDB, meters[i].Integrated())
+		}
+	}
+}
+
+func TestBatch_AlbumGainTruePeakCeiling(t *testing.T) {
+	in := writeAlbumDir(t, 0.2, 0.1)
+	out := t.TempDir()
+	if err := runCLI(t, "batch", "--album-gain", "--target-lufs", "0", "--max-true-peak", "-3", in, out); err != nil {
+		t.Fatalf("batch --album-gain error = %v", err)
+	}
+	manifest, err := batch.Load(filepath.Join(out, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Album == nil || !manifest.Album.Limited {
+		t.Fatalf("manifest album = %+v, want a limited gain", manifest.Album)
+	}
+	for _, m := range albumMeters(t, out, "1.wav", "2.wav") {
+		if peak := m.TruePeak(); peak > -3+0.05 {
+			t.Fatalf("output true peak = %.2f dBTP, above the -3 dBTP ceiling", peak)
+		}
+	}
+}
+
+func TestBatch_AlbumGainRejectsResume(t *testing.T) {
+	in := writeAlbumDir(t, 0.2)
+	out := t.TempDir()
+	if err := runCLI(t, "batch", "--album-gain", "--resume", in, out); err == nil {
+		t.Fatalf("batch --album-gain --resume succeeded")
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Fatalf("rejected batch wrote %d files", len(entries))
+	}
+}
@@ -1,18 +1,28 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+	"github.com/cwbudde/go-sq-tool/internal/audiofile"
 	"github.com/cwbudde/go-sq-tool/internal/decoder"
-	"github.com/cwbudde/go-sq-tool/internal/encoder"
 	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/repair"
+	"github.com/cwbudde/go-sq-tool/internal/report"
 	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
 	"github.com/spf13/cobra"
 )
 
 var analyzeCmd = &cobra.Command{
-	Use:   "analyze [input.wav]",
+	Use:   "analyze [input]",
 	Short: "Measure channel separation for a quad input via encode/decode",
 	Args:  cobra.ExactArgs(1),
 	RunE:  runAnalyze,
@@ -22,7 +32,14 @@ func init() {
 	analyzeCmd.Flags().StringVar(&analyzeLeakMode, "leak-mode", "max", "leakage aggregation: max or avg")
 	analyzeCmd.Flags().Float64Var(&analyzeFMin, "fmin", 0, "min frequency for band-limited analysis (Hz)")
 	analyzeCmd.Flags().Float64Var(&analyzeFMax, "fmax", 0, "max frequency for band-limited analysis (Hz)")
+	analyzeCmd.Flags().StringVar(&analyzeWindow, "analysis-window", "rect", "window applied before the band-limited FFT: rect, hann, hamming or blackman")
 	analyzeCmd.Flags().StringVar(&analyzePairMode, "pair-mode", "isolated", "pair separation mode: isolated or full")
+	analyzeCmd.Flags().BoolVar(&analyzeDetect, "detect", false, "warn about polarity-inverted channels in the input before measuring")
+	analyzeCmd.Flags().IntVar(&analyzeChannels, "channels", 4, "channel count of the input; only 4 (LF, RF, LB, RB) is measured so far")
+	analyzeCmd.Flags().StringVar(&analyzeHTML, "report", "", "also write a self-contained HTML report with charts to this file")
+	addImageReportFlag(analyzeCmd.Flags())
+	addMatrixFlag(analyzeCmd.Flags())
+	addOutputFlag(analyzeCmd.Flags(), analyzeArtifacts)
 }
 
 var (
@@ -30,24 +47,18 @@ var (
 	analyzeFMin     float64
 	analyzeFMax     float64
 	analyzePairMode string
+	analyzeWindow   string
+	analyzeDetect   bool
+	analyzeHTML     string
+	analyzeChannels int
 )
 
+// quadNames are the channels of the layout analyze measures.
+var quadNames = []string{"LF", "RF", "LB", "RB"}
+
 func runAnalyze(cmd *cobra.Command, args []string) error {
 	inputFile := args[0]
 
-	audioData, err := wav.ReadWAVChannels(inputFile, 4)
-	if err != nil {
-		return fmt.Errorf("failed to read input WAV: %w", err)
-	}
-
-	channelNames := []string{"LF", "RF", "LB", "RB"}
-	fmt.Printf("Separation analysis (encode -> decode, isolated channels)\n")
-	fmt.Printf("Input: %s\n", inputFile)
-	if logic {
-		fmt.Printf("Logic steering: enabled\n")
-	}
-	fmt.Printf("\nChannel  TargetRMS   LeakRMS  Sep(dB)\n")
-
 	switch analyzeLeakMode {
 	case string(metrics.LeakModeMax), string(metrics.LeakModeAvg):
 	default:
@@ -58,32 +69,111 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	default:
 		return fmt.Errorf("invalid pair-mode %q (use isolated or full)", analyzePairMode)
 	}
+	if err := validateImageReport(); err != nil {
+		return err
+	}
+	if analyzeChannels != len(quadNames) {
+		return fmt.Errorf("--channels %d is not supported: analyze measures the %d-channel quad layout (%s)",
+			analyzeChannels, len(quadNames), strings.Join(quadNames, ", "))
+	}
+
+	analysisWindow, err := sqmath.ParseWindowType(analyzeWindow)
+	if err != nil {
+		return fmt.Errorf("invalid analysis-window: %w", err)
+	}
+	matrixSystem, err := decoder.ParseMatrix(matrixName)
+	if err != nil {
+		return err
+	}
+	if err := checkBlockParams(); err != nil {
+		return err
+	}
+
+	outputs, err := newOutputs(analyzeArtifacts, outputSpecs, map[string]string{"html": analyzeHTML})
+	if err != nil {
+		return err
+	}
+	audioData, source, err := readAudio(inputFile, analyzeChannels)
+	var channelErr *wav.ChannelCountError
+	if errors.As(err, &channelErr) {
+		return fmt.Errorf("%s: expected %d channels (%s), got %d; analyze takes a quad source, decode SQ stereo with decode instead",
+			inputFile, channelErr.Want, strings.Join(quadNames, ", "), channelErr.Got)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	if analyzeDetect {
+		if err := warnInvertedChannels(audioData); err != nil {
+			return err
+		}
+	}
+	if err := createOutputs(outputs); err != nil {
+		return err
+	}
+	err = analyze(outputs, inputFile, audioData, source.Format, analysisWindow, matrixSystem)
+	return finishOutputs(outputs, err)
+}
+
+// analyze writes the separation report of audioData, encoded and decoded
+// with matrixSystem, to the report output or stdout.
+func analyze(outputs *artifact.Set, inputFile string, audioData *wav.AudioData, format audiofile.Format, analysisWindow sqmath.WindowType, matrixSystem decoder.Matrix) error {
+	var w io.Writer = os.Stdout
+	if outputs.Has("report") {
+		w = outputs.File("report")
+	}
+
+	fmt.Fprintf(w, "Separation analysis (encode -> decode, isolated channels)\n")
+	fmt.Fprintf(w, "Input: %s\n", inputFile)
+	if format.Lossy {
+		fmt.Fprintf(w, "Source: %s (lossy)\n", format.Name)
+	}
+	if matrixSystem != decoder.MatrixSQ {
+		fmt.Fprintf(w, "Matrix: %s\n", matrixSystem)
+	}
+	if logic {
+		fmt.Fprintf(w, "Logic steering: enabled\n")
+	}
+	fmt.Fprintf(w, "\nChannel  TargetRMS   LeakRMS  Sep(dB)\n")
 
 	options := metrics.SeparationOptions{
-		LeakMode:   metrics.LeakMode(analyzeLeakMode),
-		SampleRate: int(audioData.SampleRate),
-		FMin:       analyzeFMin,
-		FMax:       analyzeFMax,
+		LeakMode:       metrics.LeakMode(analyzeLeakMode),
+		SampleRate:     int(audioData.SampleRate),
+		FMin:           analyzeFMin,
+		FMax:           analyzeFMax,
+		AnalysisWindow: analysisWindow,
 	}
 	pairSeps := [4]float64{}
+	results := make([]metrics.SeparationResult, 4)
+	isolatedDecodes := make([][][]float64, 4)
 
+	// The full mix is always decoded for the balance check.
 	var decodedFull [][]float64
-	if analyzePairMode == "full" {
-		fullEncoder := encoder.NewSQEncoderWithParams(blockSize, overlap)
-		fullDecoder := decoder.NewSQDecoderWithParams(blockSize, overlap)
-		fullDecoder.SetSampleRate(int(audioData.SampleRate))
-		if logic {
-			fullDecoder.EnableLogicSteering(true)
-		}
-
-		encodedFull, err := fullEncoder.Process(audioData.Samples)
-		if err != nil {
-			return fmt.Errorf("encoding failed: %w", err)
-		}
-		decodedFull, err = fullDecoder.Process(encodedFull)
-		if err != nil {
-			return fmt.Errorf("decoding failed: %w", err)
-		}
+	wantImage := imageReport != "" || outputs.Has("image")
+	// One encoder and decoder serve all passes, reset in between.
+	sqOptions := []sq.Option{
+		sq.WithBlockSize(blockSize),
+		sq.WithOverlap(overlap),
+		sq.WithMatrix(matrixSystem),
+		sq.WithSampleRate(int(audioData.SampleRate)),
+	}
+	if logic {
+		sqOptions = append(sqOptions, sq.WithLogicSteering(sq.DefaultLogicSteeringConfig()))
+	}
+	sqEncoder, err := sq.NewEncoder(sqOptions...)
+	if err != nil {
+		return err
+	}
+	sqDecoder, err := sq.NewDecoder(sqOptions...)
+	if err != nil {
+		return err
+	}
+	encodedFull, err := sqEncoder.Process(audioData.Samples)
+	if err != nil {
+		return fmt.Errorf("encoding failed: %w", err)
+	}
+	decodedFull, err = sqDecoder.Process(encodedFull)
+	if err != nil {
+		return fmt.Errorf("decoding failed: %w", err)
 	}
 
 	for ch := 0; ch < 4; ch++ {
@@ -93,13 +183,8 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		}
 		copy(isolated[ch], audioData.Samples[ch])
 
-		sqEncoder := encoder.NewSQEncoderWithParams(blockSize, overlap)
-		sqDecoder := decoder.NewSQDecoderWithParams(blockSize, overlap)
-		sqDecoder.SetSampleRate(int(audioData.SampleRate))
-		if logic {
-			sqDecoder.EnableLogicSteering(true)
-		}
-
+		sqEncoder.Reset()
+		sqDecoder.Reset()
 		encoded, err := sqEncoder.Process(isolated)
 		if err != nil {
 			return fmt.Errorf("encoding failed: %w", err)
@@ -110,8 +195,9 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		}
 
 		result := metrics.ChannelSeparation(decoded, ch, options)
-		fmt.Printf("%-7s %9.6f %9.6f %7s\n",
-			channelNames[ch],
+		results[ch], isolatedDecodes[ch] = result, decoded
+		fmt.Fprintf(w, "%-7s %9.6f %9.6f %7s\n",
+			quadNames[ch],
 			result.TargetRMS,
 			result.LeakRMS,
 			formatSeparation(result.SeparationDB),
@@ -131,21 +217,130 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if analyzePairMode == "full" && decodedFull != nil {
+	if analyzePairMode == "full" {
 		pairSeps[0] = metrics.ChannelPairSeparation(decodedFull, 0, 1, options).SeparationDB
 		pairSeps[1] = metrics.ChannelPairSeparation(decodedFull, 1, 0, options).SeparationDB
 		pairSeps[2] = metrics.ChannelPairSeparation(decodedFull, 2, 3, options).SeparationDB
 		pairSeps[3] = metrics.ChannelPairSeparation(decodedFull, 3, 2, options).SeparationDB
 	}
 
-	fmt.Printf("\nPair separation (dB)\n")
-	fmt.Printf("LF->RF: %s  RF->LF: %s  LB->RB: %s  RB->LB: %s\n",
+	fmt.Fprintf(w, "\nPair separation (dB)\n")
+	fmt.Fprintf(w, "LF->RF: %s  RF->LF: %s  LB->RB: %s  RB->LB: %s\n",
 		formatSeparation(pairSeps[0]),
 		formatSeparation(pairSeps[1]),
 		formatSeparation(pairSeps[2]),
 		formatSeparation(pairSeps[3]),
 	)
 
+	inputBalance, decodedBalance := metrics.Balance(audioData.Samples), metrics.Balance(decodedFull)
+	printBalance(w, inputBalance, decodedBalance)
+
+	if outputs.Has("html") {
+		pairNames := []string{"LF->RF", "RF->LF", "LB->RB", "RB->LB"}
+		a := report.Analysis{
+			Title:          "SQ separation analysis",
+			Input:          inputFile,
+			Generated:      time.Now(),
+			Config:         analysisParams(audioData, format, analysisWindow),
+			Channels:       quadNames,
+			Separation:     results,
+			Bands:          metrics.OctaveBands(int(audioData.SampleRate)),
+			TimeWindow:     reportTimeWindow,
+			InputBalance:   inputBalance,
+			DecodedBalance: decodedBalance,
+		}
+		for i, sep := range pairSeps {
+			a.Pairs = append(a.Pairs, report.Pair{Name: pairNames[i], SeparationDB: sep})
+		}
+		windowFrames := int(reportTimeWindow * float64(audioData.SampleRate))
+		for ch, decoded := range isolatedDecodes {
+			a.Crosstalk = append(a.Crosstalk, metrics.Crosstalk(decoded, ch, options))
+			a.BandSeparation = append(a.BandSeparation, metrics.BandSeparation(decoded, ch, a.Bands, options))
+			a.TimeSeparation = append(a.TimeSeparation, metrics.SeparationOverTime(decoded, ch, windowFrames, options))
+		}
+		if err := report.WriteHTML(outputs.File("html"), a); err != nil {
+			return err
+		}
+	}
+
+	if wantImage {
+		windows := metrics.ImageReport(decodedFull, int(audioData.SampleRate), metrics.DefaultImageWindow)
+		if outputs.Has("image") {
+			return printImageReport(outputs.File("image"), windows, imageReportFormat(outputs.Path("image")))
+		}
+		return printImageReport(w, windows, imageReport)
+	}
+	return nil
+}
+
+// reportTimeWindow is the window length in seconds of the separation over
+// time in the HTML report.
+const reportTimeWindow = 1.0
+
+// analysisParams lists the settings of an analysis for the HTML report.
+func analysisParams(audioData *wav.AudioData, format audiofile.Format, analysisWindow sqmath.WindowType) []report.Param {
+	params := []report.Param{
+		{Name: "Format", Value: format.Name},
+		{Name: "Sample rate", Value: fmt.Sprintf("%d Hz", audioData.SampleRate)},
+		{Name: "Duration", Value: samplesDuration(audioData.NumSamples, audioData.SampleRate).String()},
+		{Name: "Block size", Value: fmt.Sprint(blockSize)},
+		{Name: "Overlap", Value: fmt.Sprint(overlap)},
+		{Name: "Logic steering", Value: fmt.Sprint(logic)},
+		{Name: "Leak mode", Value: analyzeLeakMode},
+		{Name: "Pair mode", Value: analyzePairMode},
+	}
+	if analyzeFMin > 0 || analyzeFMax > 0 {
+		params = append(params,
+			report.Param{Name: "Band", Value: fmt.Sprintf("%g to %g Hz", analyzeFMin, analyzeFMax)},
+			report.Param{Name: "Analysis window", Value: string(analysisWindow)})
+	}
+	return params
+}
+
+// balanceWarnDB is the shift of the left/right balance between input and
+// decode above which analyze warns of a lopsided decode. The front/back
+// balance is not checked: the passive matrix leaves the backs about 3 dB
+// down on uncorrelated material by design.
+const balanceWarnDB = 1.0
+
+// printBalance prints the level balance of the input and the decoded full
+// mix and warns when decoding shifted it to one side.
+func printBalance(w io.Writer, input, decoded metrics.BalanceReport) {
+	fmt.Fprintf(w, "\nBalance (dB)   L/R     F/B\n")
+	fmt.Fprintf(w, "Input      %7s %7s\n", formatBalance(input.LeftRightDB), formatBalance(input.FrontBackDB))
+	fmt.Fprintf(w, "Decoded    %7s %7s\n", formatBalance(decoded.LeftRightDB), formatBalance(decoded.FrontBackDB))
+
+	if shift := decoded.LeftRightDB - input.LeftRightDB; math.Abs(shift) > balanceWarnDB {
+		logger.Warn("decode shifts the left/right balance", "shift_db", fmt.Sprintf("%+.2f", shift))
+	}
+}
+
+func formatBalance(db float64) string {
+	if math.IsInf(db, 0) || math.IsNaN(db) {
+		return formatSeparation(db)
+	}
+	if math.Abs(db) < 0.005 {
+		db = 0
+	}
+	return fmt.Sprintf("%+.2f", db)
+}
+
+// warnInvertedChannels warns about quad channel pairs whose bass is
+// anti-phase, which points to an inverted channel and makes the measured
+// separation describe a broken source.
+func warnInvertedChannels(audioData *wav.AudioData) error {
+	pairs, err := repair.InvertedQuadPairs(audioData.Samples, int(audioData.SampleRate))
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		logger.Warn("anti-phase bass, one of the channels may be inverted",
+			"channels", quadNames[pair.A]+"/"+quadNames[pair.B],
+			"correlation", fmt.Sprintf("%+.2f", pair.Correlation))
+	}
+	if len(pairs) == 0 {
+		logger.Info("channel check passed")
+	}
 	return nil
 }
 
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// writeQuad writes a short 4-channel test file to dir, one tone per channel.
+func writeQuad(t *testing.T, dir string, frames int) string {
+	t.Helper()
+	data := &wav.AudioData{SampleRate: 8000, NumSamples: frames, Samples: make([][]float64, 4)}
+	for ch := range data.Samples {
+		data.Samples[ch] = make([]float64, frames)
+		for i := range data.Samples[ch] {
+			data.Samples[ch][i] = 0.25 * math.Sin(2*math.Pi*float64((ch+1)*250*i)/8000)
+		}
+	}
+	path := filepath.Join(dir, "quad.wav")
+	if err := wav.WriteWAV(path, data); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAnalyze_HTMLReport(t *testing.T) {
+	dir := t.TempDir()
+	input := writeQuad(t, dir, 16000)
+	path := filepath.Join(dir, "report.html")
+
+	if err := runCLI(t, "analyze", input, "--report", path); err != nil {
+		t.Fatalf("analyze --report error = %v", err)
+	}
+	page, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+	// The SQ decode of a single front channel leaks 3 dB down into its
+	// neighbour; a back channel leaks 3 dB up into the fronts.
+	for _, want := range []string{"<svg", "<table id=\"bands\">", "<td>LF</td>", "3.01", "-3.01", "quad.wav"} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("report lacks %q", want)
+		}
+	}
+	if strings.Contains(html, "<script") {
+		t.Fatalf("report contains a script")
+	}
+}
+
+func TestAnalyze_ChannelCount(t *testing.T) {
+	input := writeStereo(t, t.TempDir(), 8000)
+
+	err := runCLI(t, "analyze", input)
+	if err == nil || !strings.Contains(err.Error(), "expected 4 channels") || !strings.Contains(err.Error(), "got 2") {
+		t.Fatalf("analyze of a stereo file error = %v, want expected 4 channels, got 2", err)
+	}
+	err = runCLI(t, "analyze", writeQuad(t, t.TempDir(), 8000), "--channels", "6")
+	if err == nil || !strings.Contains(err.Error(), "--channels 6 is not supported") {
+		t.Fatalf("analyze --channels 6 error = %v", err)
+	}
+}
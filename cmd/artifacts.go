@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+	"github.com/spf13/pflag"
+)
+
+// outputSpecs are the --output artifact specs shared by decode, encode and
+// analyze, e.g. "main=out.wav,image=image.csv". The flag may be repeated.
+var outputSpecs []string
+
+// The artifacts each command can write, in creation order.
+var (
+	mainArtifacts = []artifact.Kind{
+		{Name: "main", Usage: "output WAV"},
+	}
+	decodeArtifacts = []artifact.Kind{
+		{Name: "main", Options: audioOptions, Usage: "decoded WAV"},
+		{Name: "extra", Repeat: true, Options: audioOptions, Usage: "further decoded WAV from the same pass"},
+		{Name: "front", Options: pairOptions, Usage: "stereo WAV of LF and RF, as --split-fb"},
+		{Name: "back", Options: pairOptions, Usage: "stereo WAV of LB and RB, as --split-fb"},
+		{Name: "debug", Dir: true, Usage: "intermediate signals, as --debug-outputs"},
+		{Name: "image", Usage: "image report, CSV for a .csv path"},
+	}
+	encodeArtifacts = []artifact.Kind{
+		{Name: "main", Usage: "SQ stereo WAV"},
+		{Name: "verify", Usage: "verification report, implies --verify"},
+	}
+	analyzeArtifacts = []artifact.Kind{
+		{Name: "report", Usage: "separation report instead of stdout"},
+		{Name: "image", Usage: "image report, CSV for a .csv path"},
+		{Name: "html", Usage: "self-contained HTML report with charts, as --report"},
+	}
+	calibrateArtifacts = []artifact.Kind{
+		{Name: "report", Usage: "calibration report instead of stdout"},
+	}
+)
+
+func addOutputFlag(flags *pflag.FlagSet, kinds []artifact.Kind) {
+	flags.StringArrayVar(&outputSpecs, "output", nil, "comma-separated kind[:option...]=path outputs, repeatable: "+artifact.Usage(kinds))
+}
+
+// newOutputs parses specs against kinds, adds the outputs given as
+// positional arguments or older per-artifact flags (paths by kind name,
+// empty for none) and checks that every output can be written, before any
+// processing starts.
+func newOutputs(kinds []artifact.Kind, specs []string, given map[string]string) (*artifact.Set, error) {
+	outputs, err := artifact.Parse(strings.Join(specs, ","), kinds)
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range kinds {
+		if path := given[kind.Name]; path != "" {
+			if err := outputs.Add(kind.Name, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := outputs.Validate(); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// rejectOverwrite fails if one of the outputs is the input file: a command
+// that streams its input would truncate it on creating the output, before
+// reading it.
+func rejectOverwrite(outputs *artifact.Set, input string) error {
+	if id := outputs.Overwrites(input); id != "" {
+		return fmt.Errorf("output %s is the input file %s; write to a different path", id, input)
+	}
+	return nil
+}
+
+// requireMain returns the main output path, which may come from the
+// positional argument or from --output main=.
+func requireMain(outputs *artifact.Set) (string, error) {
+	if !outputs.Has("main") {
+		return "", fmt.Errorf("no output file: pass it as the last argument or with --output main=path")
+	}
+	return outputs.Path("main"), nil
+}
+
+// createOutputs creates every output; on failure the ones already created
+// are removed again.
+func createOutputs(outputs *artifact.Set) error {
+	if err := outputs.Create(); err != nil {
+		outputs.Close(false)
+		return err
+	}
+	return nil
+}
+
+// finishOutputs closes the outputs after a run that ended with err,
+// removing all of them unless it succeeded.
+func finishOutputs(outputs *artifact.Set, err error) error {
+	if closeErr := outputs.Close(err == nil); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("interrupted, removed incomplete %s", strings.Join(outputs.Paths(), ", "))
+	}
+	return err
+}
+
+// reportOutput returns where a command prints its results: standard error
+// when one of its outputs is written to standard output.
+func reportOutput(outputs *artifact.Set) io.Writer {
+	if outputs.WritesStdout() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// imageReportFormat returns the format of the image report written to
+// path: --image-report if given, otherwise CSV for a .csv path.
+func imageReportFormat(path string) string {
+	if imageReport != "" {
+		return imageReport
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return "csv"
+	}
+	return "table"
+}
+
+// optionalArg returns args[i], or "" if there are fewer arguments.
+func optionalArg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
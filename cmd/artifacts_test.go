@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+	"github.com/spf13/pflag"
+)
+
+// runCLI runs the root command with args. The command flags are package
+// globals, so the ones the tests use are reset first, along with the
+// Changed marks a previous run left; these tests must not run in parallel.
+func runCLI(t *testing.T, args ...string) error {
+	t.Helper()
+	outputSpecs = nil
+	stdinData = nil
+	strictOutputs = false
+	imageReport = ""
+	debugOutputDir = ""
+	encodeVerify = false
+	hrirSet = ""
+	rearEQName = ""
+	fixApply = false
+	errorOnClip = false
+	inputGain, outputGain = 0, 0
+	blockSize, overlap = decoder.DefaultBlockSize, decoder.DefaultOverlap
+	quality, windowName = "", "hann"
+	selfTestPresets, selfTestLatency = false, false
+	maxMemoryMiB = 0
+	batchOnError, batchManifest, batchResume, batchQA = "skip", "", false, false
+	albumGain, albumTarget, albumCeiling = false, -18, -1
+	joinSplit, joinCue, allowResample = false, "", false
+	adaptiveBlocks, skipSilence, hilbertFIRPath = false, false, ""
+	analyzeHTML, refToneSpec, analyzeChannels = "", "", 4
+	calibrateMaxLag, calibrateRounds = 1, 3
+	crossfeedAmount, crossfeedDelay, backSoftness = 0, 0.3, 0
+	rearLowpass, tailMode = 0, "zero"
+	monoPolicy, logic = monoError, false
+	splitFB = nil
+	preview, previewLength, previewEvery = false, 10, 60
+	bitDepth, bits, float32 = "16", 16, false
+	compatMinCorrelation, compatMaxMonoLoss, fixCompat = -0.2, 6, false
+	encodeHeadroom, headroomCeiling = false, -1
+	sidecar, infoJSON = false, false
+	logicTwoPass, matrixName, backMode = false, "sq", "discrete"
+	hilbertScale, debugMarkerEvery = sqmath.DefaultHilbertScale, 0
+	qualifyProfile, qualifyJSON = "default", false
+	profileName, saveProfileName = "", ""
+	for _, c := range append(rootCmd.Commands(), rootCmd) {
+		c.Flags().VisitAll(func(f *pflag.Flag) { f.Changed = false })
+	}
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	return rootCmd.Execute()
+}
+
+// writeStereo writes a short SQ stereo test file to dir.
+func writeStereo(t *testing.T, dir string, frames int) string {
+	t.Helper()
+	data := &wav.AudioData{SampleRate: 8000, NumSamples: frames, Samples: make([][]float64, 2)}
+	for ch := range data.Samples {
+		data.Samples[ch] = make([]float64, frames)
+		for i := range data.Samples[ch] {
+			data.Samples[ch][i] = 0.5 * math.Sin(2*math.Pi*float64((ch+1)*440*i)/8000)
+		}
+	}
+	path := filepath.Join(dir, "input.wav")
+	if err := wav.WriteStereoWAV(path, data); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && path != dir {
+			rel, _ := filepath.Rel(dir, path)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestDecode_OutputSpec(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	out := t.TempDir()
+
+	spec := "image=" + filepath.Join(out, "image.csv") +
+		",main=" + filepath.Join(out, "out.wav") +
+		",debug=" + filepath.Join(out, "taps") + "/"
+	if err := runCLI(t, "decode", input, "--output", spec); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+
+	want := "image.csv,out.wav,taps,taps/hlt.wav,taps/hrt.wav,taps/lb_prelogic.wav,taps/rb_prelogic.wav"
+	if got := strings.Join(listDir(t, out), ","); got != want {
+		t.Fatalf("outputs = %s, want %s", got, want)
+	}
+	decoded, err := wav.ReadWAVChannels(filepath.Join(out, "out.wav"), 4)
+	if err != nil || decoded.NumSamples != 8000 {
+		t.Fatalf("decoded output = %v, %v, want 8000 frames", decoded, err)
+	}
+	report, err := os.ReadFile(filepath.Join(out, "image.csv"))
+	if err != nil || !strings.HasPrefix(string(report), "start_s,") {
+		t.Fatalf("image report = %q, %v, want CSV", report, err)
+	}
+}
+
+func TestDecode_OutputSpecRejectedUpFront(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	out := t.TempDir()
+	mainPath := filepath.Join(out, "out.wav")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no output", []string{"decode", input}},
+		{"main twice", []string{"decode", input, mainPath, "--output", "main=" + mainPath}},
+		{"unknown kind", []string{"decode", input, mainPath, "--output", "stems=" + out}},
+		{"duplicate path", []string{"decode", input, mainPath, "--output", "image=" + mainPath}},
+		{"legacy flag duplicate", []string{"decode", input, mainPath, "--debug-outputs", out, "--output", "debug=" + out}},
+		{"unwritable", []string{"decode", input, mainPath, "--output", "image=" + filepath.Join(out, "missing", "image.txt")}},
+	}
+	for _, tt := range tests {
+		if err := runCLI(t, tt.args...); err == nil {
+			t.Fatalf("%s: expected error", tt.name)
+		}
+		if names := listDir(t, out); len(names) != 0 {
+			t.Fatalf("%s: left %v behind", tt.name, names)
+		}
+	}
+}
+
+func TestDecode_FailureRemovesOutputs(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	// Cut the data chunk short, so decoding fails after the outputs exist.
+	data, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, data[:len(data)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	spec := "main=" + filepath.Join(out, "out.wav") +
+		",image=" + filepath.Join(out, "image.txt") +
+		",debug=" + filepath.Join(out, "new", "taps")
+	if err := runCLI(t, "decode", input, "--output", spec); err == nil {
+		t.Fatalf("decode of a truncated input succeeded")
+	}
+	if names := listDir(t, out); len(names) != 0 {
+		t.Fatalf("failed decode left %v behind", names)
+	}
+}
+
+func TestEncode_VerifyOutput(t *testing.T) {
+	dir := t.TempDir()
+	stereo := writeStereo(t, dir, 8000)
+	quad := filepath.Join(dir, "quad.wav")
+	if err := runCLI(t, "decode", stereo, quad); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+
+	out := t.TempDir()
+	spec := "verify=" + filepath.Join(out, "verify.txt") + ",main=" + filepath.Join(out, "sq.wav")
+	if err := runCLI(t, "encode", quad, "--output", spec); err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+	report, err := os.ReadFile(filepath.Join(out, "verify.txt"))
+	if err != nil || !strings.Contains(string(report), "Verify") {
+		t.Fatalf("verify report = %q, %v", report, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "sq.wav")); err != nil {
+		t.Fatalf("encoded output missing: %v", err)
+	}
+}
+
+func TestDecode_OutputOntoInputRejected(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	want, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := [][]string{
+		{"decode", input, input},
+		{"decode", input, filepath.Join(dir, "out.wav"), "--output", "image=" + filepath.Join(dir, ".", "input.wav")},
+		{"encode", input, input},
+	}
+	for _, args := range tests {
+		err := runCLI(t, args...)
+		if err == nil || !strings.Contains(err.Error(), "is the input file") {
+			t.Fatalf("%v: error = %v, want the input rejected as output", args, err)
+		}
+		got, err := os.ReadFile(input)
+		if err != nil || string(got) != string(want) {
+			t.Fatalf("%v: input changed (%d bytes, %v)", args, len(got), err)
+		}
+	}
+	if names := listDir(t, dir); len(names) != 1 {
+		t.Fatalf("rejected runs left %v", names)
+	}
+}
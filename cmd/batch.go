@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/audiofile"
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+	"github.com/cwbudde/go-sq-tool/internal/logging"
+	"github.com/cwbudde/go-sq-tool/internal/loudness"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <input-dir> <output-dir>",
+	Short: "Decode every audio file in a directory",
+	Long: `Decode every WAV, Ogg Vorbis or MP3 file in input-dir to a quad WAV of
+the same name in output-dir. It takes the options of decode, except
+--output and --debug-outputs. output-dir must differ from input-dir, and
+two inputs of the same name in different formats (a.wav and a.mp3) are
+rejected, as both would be decoded to a.wav.
+
+--on-error selects what happens when a file fails to decode: "skip" (the
+default) reports it and continues with the next file, "stop" ends the batch
+at the first failure. Failed files and their errors are listed in the
+summary, and the command exits with a non-zero status if any file failed.
+
+Every run writes a JSON manifest (--manifest, default manifest.json in
+output-dir) with the tool version, the start and end time of the run and,
+per file, the input and output paths and SHA-256 hashes, the decoder
+parameters, the audio duration, the processing time, the clipped samples,
+warnings and the error, if any. The manifest is rewritten after every file.
+--resume reads the manifest of an earlier run and skips the files it records
+as decoded, as long as neither the input nor the output changed since.
+
+--album-gain loudness-matches the outputs as an album instead of leaving
+each at its decoded level. A first pass decodes every file and measures the
+integrated loudness (ITU-R BS.1770, backs weighted as surrounds) and true
+peak of its output. One gain then brings all outputs together to
+--target-lufs, reduced where needed so that no true peak exceeds
+--max-true-peak, and a second pass decodes the files again with that gain
+added to --output-gain. The level differences between the files are kept.
+The manifest records the album measurement and, per file, the measured
+loudness, true peak and applied gain. It cannot be combined with --resume.
+
+--qa scans every output for defects after decoding: periodic artifacts at
+the block rate (a line at the hop rate in the envelope spectrum), gain
+jumps of one channel from abrupt steering, runs of clipped samples and
+short dropouts. Each finding is logged as a warning and recorded in the
+manifest with its channel and time, together with a score per file that
+starts at 100 and drops with every finding. Use it to pick the files worth
+listening to; the findings do not fail the batch.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runBatch,
+}
+
+var (
+	batchOnError  string
+	batchManifest string
+	batchResume   bool
+)
+
+func init() {
+	batchCmd.Flags().StringVar(&batchOnError, "on-error", "skip", "on a failed file: skip (continue with the next file) or stop")
+	batchCmd.Flags().StringVar(&batchManifest, "manifest", "", "path of the JSON run manifest (default <output-dir>/manifest.json)")
+	batchCmd.Flags().BoolVar(&batchResume, "resume", false, "skip the files the existing manifest records as decoded")
+	batchCmd.Flags().BoolVar(&batchQA, "qa", false, "scan every output for audible defects and record them in the manifest")
+	addAlbumFlags(batchCmd.Flags())
+}
+
+// addDecodeFlags gives batch the options of decode, except the ones that
+// name single output files. It runs from the root init, after decode
+// defined its flags.
+func addDecodeFlags() {
+	decodeCmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name != "output" && f.Name != "debug-outputs" {
+			batchCmd.Flags().AddFlag(f)
+		}
+	})
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	inputDir, outputDir := args[0], args[1]
+	if batchOnError != "skip" && batchOnError != "stop" {
+		return fmt.Errorf("--on-error must be skip or stop, got %q", batchOnError)
+	}
+	if albumGain && batchResume {
+		return fmt.Errorf("--album-gain cannot be combined with --resume")
+	}
+
+	files, err := batchInputs(inputDir)
+	if err != nil {
+		return err
+	}
+	outputs, err := batchOutputs(inputDir, outputDir, files)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	manifestPath := batchManifest
+	if manifestPath == "" {
+		manifestPath = filepath.Join(outputDir, "manifest.json")
+	}
+	var previous batch.Manifest
+	if batchResume {
+		if previous, err = batch.Load(manifestPath); err != nil {
+			return err
+		}
+	}
+	manifest := batch.Manifest{Tool: rootCmd.Name(), ToolVersion: toolVersion(), Start: time.Now()}
+	save := func() error {
+		manifest.End = time.Now()
+		return batch.Save(manifestPath, manifest)
+	}
+
+	var failures []batch.Entry
+	// meters holds the --album-gain measurement per manifest entry, nil
+	// for failed files.
+	var meters []*loudness.Meter
+	decoded, resumed := 0, 0
+	for i, path := range files {
+		output := outputs[i]
+		entry, ok := resumeEntry(previous, path, output)
+		if ok {
+			logger.Info("already decoded", "path", path)
+			resumed++
+		} else {
+			entry = decodeBatchFile(cmd, path, output)
+		}
+		var meter *loudness.Meter
+		if albumGain && entry.OK() {
+			if meter, err = measureOutput(output); err != nil {
+				entry.Error = err.Error()
+			}
+		}
+		meters = append(meters, meter)
+		manifest.Files = append(manifest.Files, entry)
+		if err := save(); err != nil {
+			return err
+		}
+
+		if !entry.OK() {
+			logger.Warn("decoding failed", "path", path, "error", entry.Error)
+			failures = append(failures, entry)
+			if batchOnError == "stop" {
+				break
+			}
+			continue
+		}
+		decoded++
+	}
+	if albumGain && (len(failures) == 0 || batchOnError != "stop") {
+		albumFailures, err := applyAlbumGain(cmd, &manifest, meters, save)
+		if err != nil {
+			return err
+		}
+		failures = append(failures, albumFailures...)
+		decoded -= len(albumFailures)
+	}
+	if batchQA {
+		if err := checkOutputs(&manifest, save); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\nDecoded %d of %d files", decoded, len(files))
+	if resumed > 0 {
+		fmt.Printf(" (%d from the previous run)", resumed)
+	}
+	fmt.Printf("\nManifest: %s\n", manifestPath)
+	if len(failures) == 0 {
+		return nil
+	}
+	fmt.Printf("Failed:\n")
+	for _, f := range failures {
+		fmt.Printf("  %s: %s\n", f.Input, f.Error)
+	}
+	if batchOnError == "stop" {
+		return fmt.Errorf("batch stopped at %s: %s", failures[0].Input, failures[0].Error)
+	}
+	return fmt.Errorf("%d of %d files failed", len(failures), len(files))
+}
+
+// resumeEntry returns the entry of previous for input when it records a
+// successful decode to output and both files still have the recorded
+// hashes.
+func resumeEntry(previous batch.Manifest, input, output string) (batch.Entry, bool) {
+	entry, ok := previous.Succeeded(input)
+	if !ok || entry.Output != output {
+		return batch.Entry{}, false
+	}
+	if hash, err := batch.HashFile(input); err != nil || hash != entry.InputSHA256 {
+		return batch.Entry{}, false
+	}
+	if hash, err := batch.HashFile(output); err != nil || hash != entry.OutputSHA256 {
+		return batch.Entry{}, false
+	}
+	entry.Resumed = true
+	return entry, true
+}
+
+// decodeBatchFile decodes input to output and records the result. The
+// diagnostics of the decode are collected from the log records.
+func decodeBatchFile(cmd *cobra.Command, input, output string) batch.Entry {
+	entry := batch.Entry{Input: input, Output: output, Start: time.Now()}
+	hash, err := batch.HashFile(input)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.InputSHA256 = hash
+
+	console := logger
+	recorder := logging.NewRecorder(console.Handler(), slog.LevelInfo)
+	logger = slog.New(recorder)
+	err = runDecode(cmd, []string{input, output})
+	logger = console
+	entry.Elapsed = time.Since(entry.Start).Seconds()
+
+	if d := recorder.Find("input")["duration"]; d.Kind() == slog.KindDuration {
+		entry.Duration = d.Duration().Seconds()
+	}
+	entry.Parameters = recordedAttrs(recorder, "decoder configuration")
+	entry.Warnings = recordedWarnings(recorder)
+	for _, record := range recorder.Records() {
+		if record.Message != "output clipped" {
+			continue
+		}
+		record.Attrs(func(a slog.Attr) bool {
+			if a.Key == "samples" && a.Value.Kind() == slog.KindInt64 {
+				entry.ClippedSamples += a.Value.Int64()
+			}
+			return true
+		})
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	if entry.OutputSHA256, err = batch.HashFile(output); err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// toolVersion returns the module version the binary was built from.
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// batchInputs returns the audio files directly in dir, sorted by name.
+func batchInputs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if _, err := audiofile.Detect(e.Name(), nil); err == nil {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no audio files in %s", dir)
+	}
+	return files, nil
+}
+
+// batchOutputs returns the output path of every input file: a WAV of the
+// same name in outputDir. It fails if outputDir is inputDir, where the
+// outputs would replace the inputs, or if two inputs map to the same
+// output, as a.wav and a.mp3 do.
+func batchOutputs(inputDir, outputDir string, files []string) ([]string, error) {
+	in, err := os.Stat(inputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+	if out, err := os.Stat(outputDir); err == nil && os.SameFile(in, out) {
+		return nil, fmt.Errorf("output directory %s is the input directory; decode to a different one", outputDir)
+	}
+	outputs := make([]string, len(files))
+	// seen maps the output names, folded for case-insensitive file
+	// systems, to the input that claimed them.
+	seen := make(map[string]string)
+	for i, path := range files {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ".wav"
+		if other, ok := seen[strings.ToLower(name)]; ok {
+			return nil, fmt.Errorf("%s and %s would both be decoded to %s", other, path, name)
+		}
+		seen[strings.ToLower(name)] = path
+		outputs[i] = filepath.Join(outputDir, name)
+	}
+	return outputs, nil
+}
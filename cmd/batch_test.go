@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+)
+
+// writeBatchDir writes a valid, a corrupt and another valid input, in that
+// name order, and returns the directory.
+func writeBatchDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	valid := writeStereo(t, t.TempDir(), 4000)
+	data, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string][]byte{
+		"a.wav": data,
+		"b.wav": []byte("RIFF\x00\x00\x00\x00WAVEjunk"),
+		"c.wav": data,
+		"notes": []byte("not audio"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBatch_SkipContinues(t *testing.T) {
+	in := writeBatchDir(t)
+	out := t.TempDir()
+
+	err := runCLI(t, "batch", in, out)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 files failed") {
+		t.Fatalf("batch error = %v, want 1 of 3 files failed", err)
+	}
+	if got := strings.Join(listDir(t, out), ","); got != "a.wav,c.wav,manifest.json" {
+		t.Fatalf("outputs = %s, want a.wav,c.wav,manifest.json", got)
+	}
+}
+
+func TestBatch_StopHalts(t *testing.T) {
+	in := writeBatchDir(t)
+	out := t.TempDir()
+
+	err := runCLI(t, "batch", in, out, "--on-error", "stop")
+	if err == nil || !strings.Contains(err.Error(), "b.wav") {
+		t.Fatalf("batch error = %v, want a stop at b.wav", err)
+	}
+	if got := strings.Join(listDir(t, out), ","); got != "a.wav,manifest.json" {
+		t.Fatalf("outputs = %s, want a.wav,manifest.json", got)
+	}
+}
+
+func TestBatch_InvalidPolicy(t *testing.T) {
+	if err := runCLI(t, "batch", writeBatchDir(t), t.TempDir(), "--on-error", "retry"); err == nil {
+		t.Fatalf("batch --on-error retry succeeded")
+	}
+}
+
+func TestBatch_OutputsMustNotReplaceInputs(t *testing.T) {
+	in := t.TempDir()
+	valid := writeStereo(t, t.TempDir(), 4000)
+	data, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.wav", "b.wav"} {
+		if err := os.WriteFile(filepath.Join(in, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, out := range []string{in, filepath.Join(in, ".")} {
+		err := runCLI(t, "batch", in, out)
+		if err == nil || !strings.Contains(err.Error(), "is the input directory") {
+			t.Fatalf("batch %s %s error = %v, want the input directory rejected", in, out, err)
+		}
+	}
+	if got := strings.Join(listDir(t, in), ","); got != "a.wav,b.wav" {
+		t.Fatalf("input directory = %s, want a.wav,b.wav untouched", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(in, "a.caf"), []byte("caff"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	err = runCLI(t, "batch", in, out)
+	if err == nil || !strings.Contains(err.Error(), "would both be decoded to a.wav") {
+		t.Fatalf("batch error = %v, want a.caf and a.wav rejected", err)
+	}
+	if names := listDir(t, out); len(names) != 0 {
+		t.Fatalf("rejected batch wrote %v", names)
+	}
+}
+
+func TestBatch_ResumeAfterPartialFailure(t *testing.T) {
+	in := writeBatchDir(t)
+	out := t.TempDir()
+	manifestPath := filepath.Join(out, "manifest.json")
+
+	if err := runCLI(t, "batch", in, out, "--output-gain", "20"); err == nil {
+		t.Fatalf("batch with a corrupt file succeeded")
+	}
+	first, err := batch.Load(manifestPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(first.Files) != 3 || first.End.Before(first.Start) {
+		t.Fatalf("manifest = %+v, want 3 files", first)
+	}
+	a := first.Files[0]
+	if !a.OK() || a.InputSHA256 == "" || a.OutputSHA256 == "" || a.Duration != 0.5 ||
+		a.Parameters["block_size"] == "" || a.ClippedSamples == 0 || len(a.Warnings) == 0 {
+		t.Fatalf("entry of a.wav = %+v", a)
+	}
+	if b := first.Files[1]; b.OK() || b.OutputSHA256 != "" {
+		t.Fatalf("entry of b.wav = %+v, want an error", b)
+	}
+
+	// Repair b.wav and touch the output of c.wav: resuming decodes both
+	// again and keeps a.wav from the first run.
+	valid, err := os.ReadFile(filepath.Join(in, "a.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(in, "b.wav"), valid, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "c.wav"), []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCLI(t, "batch", in, out, "--output-gain", "20", "--resume"); err != nil {
+		t.Fatalf("batch --resume error = %v", err)
+	}
+	second, err := batch.Load(manifestPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for i, want := range []bool{true, false, false} {
+		e := second.Files[i]
+		if !e.OK() || e.Resumed != want {
+			t.Fatalf("entry %d = %+v, want resumed=%v", i, e, want)
+		}
+	}
+	if second.Files[0].Start != first.Files[0].Start {
+		t.Fatalf("resumed entry was not carried over")
+	}
+	if second.Files[2].OutputSHA256 != first.Files[2].OutputSHA256 {
+		t.Fatalf("c.wav was not decoded again")
+	}
+
+	if err := runCLI(t, "batch", in, t.TempDir(), "--resume"); err == nil {
+		t.Fatalf("batch --resume without a manifest succeeded")
+	}
+}
+
+func TestBatch_QA(t *testing.T) {
+	in := writeBatchDir(t)
+	out := t.TempDir()
+	manifestPath := filepath.Join(out, "manifest.json")
+
+	// The gain drives the outputs into clipping.
+	if err := runCLI(t, "batch", in, out, "--qa", "--output-gain", "20"); err == nil {
+		t.Fatalf("batch with a corrupt file succeeded")
+	}
+	manifest, err := batch.Load(manifestPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, entry := range manifest.Files {
+		if !entry.OK() {
+			if entry.QA != nil {
+				t.Fatalf("failed entry %s has QA %+v", entry.Input, entry.QA)
+			}
+			continue
+		}
+		if entry.QA == nil || len(entry.QA.Findings) == 0 || entry.QA.Score >= 100 {
+			t.Fatalf("entry %s QA = %+v, want clipping findings", entry.Input, entry.QA)
+		}
+		for _, f := range entry.QA.Findings {
+			if f.Kind != "clipping" || f.Channel == "" || f.Value < 3 {
+				t.Fatalf("entry %s finding = %+v, want clipping", entry.Input, f)
+			}
+		}
+	}
+
+	if err := runCLI(t, "batch", in, out, "--qa"); err == nil {
+		t.Fatalf("batch with a corrupt file succeeded")
+	}
+	if manifest, err = batch.Load(manifestPath); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, entry := range manifest.Files {
+		if entry.OK() && (entry.QA == nil || entry.QA.Score != 100) {
+			t.Fatalf("entry %s QA = %+v, want a clean output", entry.Input, entry.QA)
+		}
+	}
+}
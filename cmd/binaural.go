@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/assets"
+	"github.com/cwbudde/go-sq-tool/internal/binaural"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/spf13/pflag"
+)
+
+var (
+	hrirSet    string
+	rearEQName string
+)
+
+func addHeadphoneFlags(flags *pflag.FlagSet) {
+	presets, _ := assets.ListEQPresets()
+	names := make([]string, len(presets))
+	for i, preset := range presets {
+		names[i] = preset.Name
+	}
+	flags.StringVar(&hrirSet, "hrir", "", "render binaural stereo for headphones with this embedded HRIR set ("+strings.Join(assets.HRIRNames(), ", ")+")")
+	flags.StringVar(&rearEQName, "rear-eq", "", "apply this high-shelf preset to the back channels ("+strings.Join(names, ", ")+")")
+}
+
+// rearEQProcessor applies the --rear-eq preset to the back channels of the
+// decoder output. Every back channel layout is a linear combination of LB
+// and RB, so the shelf applies to all channels from the third on.
+func rearEQProcessor(process pipeline.Processor, sampleRate uint32) (pipeline.Processor, error) {
+	if rearEQName == "" {
+		return process, nil
+	}
+	preset, err := assets.LookupEQPreset(rearEQName)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("rear EQ",
+		"preset", preset.Name,
+		"frequency_hz", preset.FrequencyHz,
+		"gain_db", preset.GainDB)
+	return pipeline.FilterProcessor(process, 2, preset.Filter(float64(sampleRate))), nil
+}
+
+// binauralProcessor renders the decoder output to two ear signals with the
+// --hrir set, resampled to sampleRate if it is not embedded at that rate.
+func binauralProcessor(process pipeline.Processor, mode decoder.BackChannelMode, sampleRate uint32) (pipeline.Processor, error) {
+	hrir, err := assets.LoadHRIR(hrirSet, int(sampleRate))
+	if err != nil {
+		return nil, err
+	}
+	renderer, err := binaural.NewRenderer(hrir)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("binaural rendering",
+		"hrir", hrir.Name,
+		"taps", len(hrir.Left[0]))
+	return func(input [][]float64, numOutput int) ([][]float64, error) {
+		output, err := process(input, numOutput)
+		if err != nil {
+			return nil, err
+		}
+		return renderer.Process(discreteChannels(output, mode))
+	}, nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestDecode_CAF(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	stereo, err := wav.ReadWAV(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cafInput := filepath.Join(dir, "input.caf")
+	if err := wav.WriteCAF(cafInput, stereo, 2, wav.WriteOptions{Format: wav.FormatPCM16}); err != nil {
+		t.Fatal(err)
+	}
+
+	quad := filepath.Join(dir, "quad.wav")
+	quadCAF := filepath.Join(dir, "quad.caf")
+	if err := runCLI(t, "decode", input, "--bit-depth", "32f", "--output", "main="+quad+",extra="+quadCAF); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	fromCAF := filepath.Join(dir, "from-caf.wav")
+	if err := runCLI(t, "decode", cafInput, fromCAF, "--bit-depth", "32f"); err != nil {
+		t.Fatalf("decode of CAF input error = %v", err)
+	}
+
+	// A CAF file named .wav is recognized by its contents.
+	misnamed := filepath.Join(dir, "misnamed.wav")
+	if err := wav.WriteCAF(misnamed, stereo, 2, wav.WriteOptions{Format: wav.FormatPCM16}); err != nil {
+		t.Fatal(err)
+	}
+	fromMisnamed := filepath.Join(dir, "from-misnamed.wav")
+	if err := runCLI(t, "decode", misnamed, fromMisnamed, "--bit-depth", "32f"); err != nil {
+		t.Fatalf("decode of CAF input named .wav error = %v", err)
+	}
+
+	want := readChannels(t, quad, 4)
+	caf, err := wav.ReadCAF(quadCAF, 4)
+	if err != nil {
+		t.Fatalf("ReadCAF() error = %v", err)
+	}
+	decoded := readChannels(t, fromCAF, 4)
+	misnamedDecoded := readChannels(t, fromMisnamed, 4)
+	for ch := range want {
+		for i, v := range want[ch] {
+			if caf.Samples[ch][i] != v {
+				t.Fatalf("CAF output channel %d frame %d = %v, want %v", ch, i, caf.Samples[ch][i], v)
+			}
+			if decoded[ch][i] != v || misnamedDecoded[ch][i] != v {
+				t.Fatalf("decoded CAF input channel %d frame %d = %v and %v, want %v", ch, i, decoded[ch][i], misnamedDecoded[ch][i], v)
+			}
+		}
+	}
+}
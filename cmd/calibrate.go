@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+	"github.com/cwbudde/go-sq-tool/internal/calibrate"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/spf13/cobra"
+)
+
+var calibrateCmd = &cobra.Command{
+	Use:   "calibrate <input> <reference> [<input> <reference>...]",
+	Short: "Fit the decoder parameters to recordings of a hardware decoder",
+	Long: `Compare the decoder with a hardware SQ decoder and search for the settings
+that bring it closest. Each pair names an SQ stereo input and the 4-channel
+capture (LF, RF, LB, RB) of the hardware decoding it. All files must have
+the same sample rate unless --allow-resample converts the ones that differ
+from the most common rate.
+
+Every capture is time-aligned to a decode of its input by cross-correlation
+within --max-lag, and each candidate decode is level-matched to it with one
+gain per pair. The search then tries the front blend (crossfeed without
+delay), the phase shifter window and the logic steering settings one at a
+time on a fixed grid, keeping whatever lowers the residual, for up to
+--rounds passes, once with logic steering off and once with it on.
+
+The report gives the alignment of every pair, the best parameters and, per
+channel, the residual relative to the capture in dB under the starting
+parameters (from --window and --logic) and the best ones: -20 dB means the
+difference carries a hundredth of the capture's energy. --block-size,
+--overlap and --quality apply as in decode.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 || len(args)%2 != 0 {
+			return fmt.Errorf("expected input/reference pairs, got %d arguments", len(args))
+		}
+		return nil
+	},
+	RunE: runCalibrate,
+}
+
+var (
+	calibrateMaxLag float64
+	calibrateRounds int
+)
+
+func init() {
+	calibrateCmd.Flags().Float64Var(&calibrateMaxLag, "max-lag", 1, "largest offset in seconds between a capture and its input")
+	calibrateCmd.Flags().IntVar(&calibrateRounds, "rounds", 3, "most passes over all parameters")
+	addOutputFlag(calibrateCmd.Flags(), calibrateArtifacts)
+	addResampleFlag(calibrateCmd.Flags())
+}
+
+func runCalibrate(cmd *cobra.Command, args []string) error {
+	if calibrateMaxLag < 0 {
+		return fmt.Errorf("--max-lag must not be negative, got %g", calibrateMaxLag)
+	}
+	outputs, err := newOutputs(calibrateArtifacts, outputSpecs, nil)
+	if err != nil {
+		return err
+	}
+
+	files := make([]*wav.AudioData, len(args))
+	rates := make([]uint32, len(args))
+	for i := range args {
+		channels, kind := 2, "input"
+		if i%2 == 1 {
+			channels, kind = calibrate.Channels, "reference"
+		}
+		data, _, err := readAudio(args[i], channels)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", kind, err)
+		}
+		files[i], rates[i] = data, data.SampleRate
+	}
+	sampleRate, err := commonSampleRate(args, rates)
+	if err != nil {
+		return err
+	}
+	var pairs []calibrate.Pair
+	for i, data := range files {
+		if data.SampleRate != sampleRate {
+			resampleAudio(args[i], data, sampleRate)
+		}
+		if i%2 == 1 {
+			pairs = append(pairs, calibrate.Pair{Name: args[i], Input: files[i-1].Samples, Reference: data.Samples})
+		}
+	}
+
+	window, err := applyQuality(cmd, sampleRate)
+	if err != nil {
+		return err
+	}
+	start := calibrate.DefaultParams()
+	start.Window, start.Logic = window, logic
+	config := calibrate.Config{
+		SampleRate: int(sampleRate),
+		BlockSize:  blockSize,
+		Overlap:    overlap,
+		MaxLag:     int(math.Round(calibrateMaxLag * float64(sampleRate))),
+		Rounds:     calibrateRounds,
+		Start:      start,
+	}
+	logger.Info("calibrating", "pairs", len(pairs), "sample_rate", sampleRate, "block_size", blockSize, "overlap", overlap, "start", start.String())
+	result, err := calibrate.Search(pairs, config)
+	if err != nil {
+		return err
+	}
+	logger.Info("calibration done", "evaluations", result.Evaluations, "match_db", result.Match.Total)
+
+	if err := createOutputs(outputs); err != nil {
+		return err
+	}
+	return finishOutputs(outputs, writeCalibration(outputs, pairs, config, result))
+}
+
+// writeCalibration writes the calibration report to the report output or
+// stdout.
+func writeCalibration(outputs *artifact.Set, pairs []calibrate.Pair, config calibrate.Config, result calibrate.Result) error {
+	var w io.Writer = os.Stdout
+	if outputs.Has("report") {
+		w = outputs.File("report")
+	}
+
+	fmt.Fprintf(w, "Calibration against %d capture(s) at %d Hz\n\n", len(pairs), config.SampleRate)
+	fmt.Fprintf(w, "Alignment:\n")
+	for i, pair := range pairs {
+		a := result.Alignments[i]
+		fmt.Fprintf(w, "  %s: lag %d frames (%.1f ms), gain %s dB\n",
+			pair.Name, a.Lag, 1000*float64(a.Lag)/float64(config.SampleRate), formatLevel(math.Abs(a.Gain)))
+	}
+	fmt.Fprintf(w, "\nStart: %s\n", config.Start)
+	fmt.Fprintf(w, "Best:  %s\n", result.Params)
+	fmt.Fprintf(w, "(%d parameter sets decoded)\n", result.Evaluations)
+
+	fmt.Fprintf(w, "\nResidual relative to the capture:\n")
+	fmt.Fprintf(w, "Channel  Start(dB)  Best(dB)\n")
+	for ch, name := range quadNames {
+		fmt.Fprintf(w, "%-7s  %9s  %8s\n", name, formatResidual(result.Initial.Channels[ch]), formatResidual(result.Match.Channels[ch]))
+	}
+	_, err := fmt.Fprintf(w, "%-7s  %9s  %8s\n", "Total", formatResidual(result.Initial.Total), formatResidual(result.Match.Total))
+	return err
+}
+
+func formatResidual(db float64) string {
+	if math.IsInf(db, 0) {
+		return fmt.Sprint(db)
+	}
+	return fmt.Sprintf("%.1f", db)
+}
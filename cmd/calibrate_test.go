@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestCalibrate_FindsHardwareSettings(t *testing.T) {
+	dir := t.TempDir()
+	quad := writeQuad(t, dir, 16000)
+	input := filepath.Join(dir, "sq.wav")
+	if err := runCLI(t, "encode", quad, input); err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+	// The "hardware" is this decoder with a front blend and another
+	// window.
+	capture := filepath.Join(dir, "capture.wav")
+	if err := runCLI(t, "decode", input, capture, "--bit-depth", "32f", "--window", "blackman", "--crossfeed", "0.3", "--crossfeed-delay", "0"); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+
+	report := filepath.Join(dir, "report.txt")
+	if err := runCLI(t, "calibrate", input, capture, "--max-lag", "0.1", "--output", "report="+report); err != nil {
+		t.Fatalf("calibrate error = %v", err)
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, want := range []string{"Best:  front blend 0.30, window blackman, logic off", "capture.wav: lag", "Channel  Start(dB)  Best(dB)"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("report lacks %q:\n%s", want, text)
+		}
+	}
+
+	if err := runCLI(t, "calibrate", input); err == nil || !strings.Contains(err.Error(), "input/reference pairs") {
+		t.Fatalf("calibrate with one file error = %v", err)
+	}
+	if err := runCLI(t, "calibrate", input, input); err == nil || !strings.Contains(err.Error(), "channels") {
+		t.Fatalf("calibrate with a stereo reference error = %v", err)
+	}
+}
+
+func TestCalibrate_SampleRateMismatch(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 4000)
+	// A capture at 16 kHz of the 8 kHz input.
+	capture := filepath.Join(dir, "capture.wav")
+	quad := &wav.AudioData{SampleRate: 16000, NumSamples: 8000, Samples: make([][]float64, 4)}
+	for ch := range quad.Samples {
+		quad.Samples[ch] = make([]float64, quad.NumSamples)
+	}
+	if err := wav.WriteWAV(capture, quad); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runCLI(t, "calibrate", input, capture, "--max-lag", "0.01", "--rounds", "1")
+	if err == nil || !strings.Contains(err.Error(), "inputs must have the same sample rate") || !strings.Contains(err.Error(), "--allow-resample") {
+		t.Fatalf("calibrate with a 16 kHz capture error = %v, want the shared sample rate mismatch", err)
+	}
+	report := filepath.Join(dir, "report.txt")
+	if err := runCLI(t, "calibrate", input, capture, "--max-lag", "0.01", "--rounds", "1", "--allow-resample", "--output", "report="+report); err != nil {
+		t.Fatalf("calibrate --allow-resample error = %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cwbudde/go-sq-tool/internal/compat"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/spf13/pflag"
+)
+
+// Limits and --fix-compat of the stereo compatibility check of encode.
+var (
+	compatMinCorrelation float64
+	compatMaxMonoLoss    float64
+	fixCompat            bool
+)
+
+func addCompatFlags(flags *pflag.FlagSet) {
+	defaults := compat.DefaultConfig()
+	flags.Float64Var(&compatMinCorrelation, "compat-min-corr", defaults.MinCorrelation, "lowest acceptable LT/RT correlation of a window")
+	flags.Float64Var(&compatMaxMonoLoss, "compat-max-loss", defaults.MaxMonoLossDB, "largest acceptable mono fold-down loss of a window in dB")
+	flags.BoolVar(&fixCompat, "fix-compat", false, "narrow the stereo width of the windows that fail the compatibility check")
+}
+
+func compatConfig() compat.Config {
+	config := compat.DefaultConfig()
+	config.MinCorrelation = compatMinCorrelation
+	config.MaxMonoLossDB = compatMaxMonoLoss
+	return config
+}
+
+// checkCompat scans the encoded stereo in file for windows that fail the
+// compatibility limits and logs them as warnings. With --fix-compat the
+// failing windows are narrowed and file is rewritten in place; the whole
+// output is then held in memory.
+func checkCompat(file *os.File, sampleRate uint32, loops []wav.Loop) error {
+	config := compatConfig()
+	scanner, err := compat.NewScanner(int(sampleRate), config)
+	if err != nil {
+		return fmt.Errorf("invalid compatibility limits: %w", err)
+	}
+
+	input, err := openStream(file.Name(), 2)
+	if err != nil {
+		return fmt.Errorf("compatibility check: %w", err)
+	}
+	defer input.Close()
+	buf := [][]float64{make([]float64, 1<<16), make([]float64, 1<<16)}
+	for {
+		n, err := input.source.ReadFrames(buf)
+		if n > 0 {
+			scanner.Add(buf[0][:n], buf[1][:n])
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("compatibility check: %w", err)
+		}
+	}
+
+	failures := compat.Failures(scanner.Windows(), config)
+	for _, r := range failures {
+		logger.Warn("stereo compatibility",
+			"start", samplesDuration(r.Start, sampleRate),
+			"end", samplesDuration(r.End, sampleRate),
+			"correlation", r.MinCorrelation,
+			"mono_loss_db", r.MaxMonoLossDB)
+	}
+	if len(failures) == 0 || !fixCompat {
+		return nil
+	}
+
+	data, _, err := readAudio(file.Name(), 2)
+	if err != nil {
+		return fmt.Errorf("compatibility fix: %w", err)
+	}
+	corrections, err := compat.Fix(data.Samples[0], data.Samples[1], int(sampleRate), config)
+	if err != nil {
+		return fmt.Errorf("compatibility fix: %w", err)
+	}
+	for _, c := range corrections {
+		logger.Info("narrowed stereo width",
+			"start", samplesDuration(c.Start, sampleRate),
+			"end", samplesDuration(c.End, sampleRate),
+			"width", c.Width)
+	}
+
+	options, err := writeOptions()
+	if err != nil {
+		return err
+	}
+	options.Loops = loops
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("compatibility fix: %w", err)
+	}
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("compatibility fix: %w", err)
+	}
+	write := wav.WriteWAVWithOptionsToWriter
+	if isCAF(file.Name()) {
+		write = wav.WriteCAFToWriter
+	}
+	if err := write(file, data, 2, options); err != nil {
+		return fmt.Errorf("compatibility fix: %w", err)
+	}
+	logger.Info("corrected stereo compatibility", "path", file.Name(), "windows", len(corrections))
+	return nil
+}
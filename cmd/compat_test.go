@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/compat"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestEncode_FixCompat(t *testing.T) {
+	dir := t.TempDir()
+	// Center back, LB = RB, is encoded anti-phase and fails the check.
+	const frames = 16000
+	data := &wav.AudioData{SampleRate: 8000, NumSamples: frames, Samples: make([][]float64, 4)}
+	for ch := range data.Samples {
+		data.Samples[ch] = make([]float64, frames)
+	}
+	for i := 0; i < frames; i++ {
+		v := 0.25 * math.Sin(2*math.Pi*float64(300*i)/8000)
+		data.Samples[2][i], data.Samples[3][i] = v, v
+	}
+	input := filepath.Join(dir, "quad.wav")
+	if err := wav.WriteWAV(input, data); err != nil {
+		t.Fatal(err)
+	}
+	failures := func(path string) int {
+		t.Helper()
+		encoded, err := wav.ReadWAVChannels(path, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		windows, err := compat.Scan(encoded.Samples[0], encoded.Samples[1], 8000, compat.DefaultConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(compat.Failures(windows, compat.DefaultConfig()))
+	}
+
+	plain := filepath.Join(dir, "plain.wav")
+	if err := runCLI(t, "encode", input, plain); err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+	if failures(plain) == 0 {
+		t.Fatalf("center back encode passes the compatibility check")
+	}
+
+	fixed := filepath.Join(dir, "fixed.wav")
+	if err := runCLI(t, "encode", input, fixed, "--fix-compat"); err != nil {
+		t.Fatalf("encode --fix-compat error = %v", err)
+	}
+	if n := failures(fixed); n != 0 {
+		t.Fatalf("%d ranges still fail after --fix-compat", n)
+	}
+}
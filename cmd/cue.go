@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/cwbudde/go-sq-tool/internal/cue"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// cueTracks returns the frame count and INFO tags of every track of sheet
+// on the joined stream of inputs with inputFrames frames each. A cue sheet
+// must list one FILE per input; the start times of a JSON list are on the
+// joined stream. Audio before the first track (a hidden pregap) goes to the
+// first track, so the tracks cover the stream without gaps.
+func cueTracks(sheet *cue.Sheet, inputFrames []int, sampleRate uint32) ([]int, []wav.InfoTags, error) {
+	offsets := []int{0}
+	if sheet.Files > 1 {
+		if sheet.Files != len(inputFrames) {
+			return nil, nil, fmt.Errorf("cue sheet lists %d files, got %d inputs", sheet.Files, len(inputFrames))
+		}
+		for _, n := range inputFrames[:len(inputFrames)-1] {
+			offsets = append(offsets, offsets[len(offsets)-1]+n)
+		}
+	}
+	total := 0
+	for _, n := range inputFrames {
+		total += n
+	}
+
+	starts := make([]int, len(sheet.Tracks))
+	for i, t := range sheet.Tracks {
+		starts[i] = offsets[t.File] + t.StartFrame(int(sampleRate))
+		if i > 0 && starts[i] >= total {
+			return nil, nil, fmt.Errorf("track %d starts at %v, after the end of the input", t.Number, t.Start)
+		}
+	}
+	starts[0] = 0
+
+	frames := make([]int, len(starts))
+	tags := make([]wav.InfoTags, len(starts))
+	for i, t := range sheet.Tracks {
+		end := total
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		frames[i] = end - starts[i]
+
+		performer := t.Performer
+		if performer == "" {
+			performer = sheet.Performer
+		}
+		tags[i] = wav.InfoTags{wav.InfoTrack: strconv.Itoa(t.Number)}
+		for id, value := range map[string]string{wav.InfoTitle: t.Title, wav.InfoArtist: performer, wav.InfoAlbum: sheet.Title} {
+			if value != "" {
+				tags[i][id] = value
+			}
+		}
+	}
+	return frames, tags, nil
+}
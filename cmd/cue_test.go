@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/cue"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestJoinDecode_CueTracksMatchSingleDecode(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	whole := filepath.Join(dir, "whole.wav")
+	if err := runCLI(t, "decode", input, whole); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+
+	list := filepath.Join(dir, "tracks.json")
+	tracks := `{"title": "Side A", "performer": "Band", "tracks": [
+		{"title": "One", "start": 0}, {"title": "Two", "performer": "Guest", "start": 0.3}, {"title": "Three", "start": 0.71}]}`
+	if err := os.WriteFile(list, []byte(tracks), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "quad.wav")
+	if err := runCLI(t, "join-decode", "--cue", list, input, out); err != nil {
+		t.Fatalf("join-decode --cue error = %v", err)
+	}
+
+	// The tracks split at the cue positions and join to the single decode.
+	want, err := wav.ReadWAVChannels(whole, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := make([][]float64, 4)
+	for i, wantFrames := range []int{2400, 3280, 2320} {
+		path := splitOutputNames(out, 3)[i]
+		track, err := wav.ReadWAVChannels(path, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if track.NumSamples != wantFrames {
+			t.Fatalf("track %d has %d frames, want %d", i+1, track.NumSamples, wantFrames)
+		}
+		for ch := range joined {
+			joined[ch] = append(joined[ch], track.Samples[ch]...)
+		}
+	}
+	for ch := range want.Samples {
+		for i, w := range want.Samples[ch] {
+			if joined[ch][i] != w {
+				t.Fatalf("ch %d frame %d: tracks %v, single decode %v", ch, i, joined[ch][i], w)
+			}
+		}
+	}
+
+	f, err := os.Open(splitOutputNames(out, 3)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tags, err := wav.ScanInfo(f)
+	if err != nil {
+		t.Fatalf("ScanInfo() error = %v", err)
+	}
+	for id, want := range map[string]string{wav.InfoTitle: "Two", wav.InfoArtist: "Guest", wav.InfoAlbum: "Side A", wav.InfoTrack: "2"} {
+		if tags[id] != want {
+			t.Fatalf("track 2 tag %s = %q, want %q", id, tags[id], want)
+		}
+	}
+
+	if err := runCLI(t, "join-decode", "--cue", list, "--split", input, out); err == nil {
+		t.Fatalf("join-decode --cue --split succeeded")
+	}
+}
+
+func TestCueTracks_FilesMapToInputs(t *testing.T) {
+	sheet, err := cue.Parse(strings.NewReader(`PERFORMER "Band"
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:10
+  TRACK 02 AUDIO
+    INDEX 01 00:01:00
+FILE "b.wav" WAVE
+  TRACK 03 AUDIO
+    INDEX 01 00:00:00
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Track 1 takes the audio before its INDEX 01; track 3 starts with b.wav.
+	frames, tags, err := cueTracks(sheet, []int{3000, 5000}, 1000)
+	if err != nil {
+		t.Fatalf("cueTracks() error = %v", err)
+	}
+	if len(frames) != 3 || frames[0] != 1000 || frames[1] != 2000 || frames[2] != 5000 {
+		t.Fatalf("frames = %v, want [1000 2000 5000]", frames)
+	}
+	if tags[2][wav.InfoTrack] != "3" || tags[2][wav.InfoArtist] != "Band" {
+		t.Fatalf("tags of track 3 = %v", tags[2])
+	}
+
+	if _, _, err := cueTracks(sheet, []int{8000}, 1000); err == nil {
+		t.Fatalf("cueTracks() with 1 input for 2 files expected error")
+	}
+}
+
+func TestDecode_KeepsCuePoints(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 4000)
+	data, err := wav.ReadWAV(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Cues = []wav.Cue{{Position: 0, Label: "Track 1"}, {Position: 2500, Label: "Track 2"}}
+	if err := wav.WriteStereoWAV(input, data); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "quad.wav")
+	if err := runCLI(t, "decode", input, output); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	quad, err := wav.ReadWAVChannels(output, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quad.Cues) != 2 || quad.Cues[0] != data.Cues[0] || quad.Cues[1] != data.Cues[1] {
+		t.Fatalf("output cues = %+v, want %+v", quad.Cues, data.Cues)
+	}
+}
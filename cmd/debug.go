@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// debugOutputNames are the files written by --debug-outputs, in the buffer
+// order of decoder.HookIntermediate.
+var debugOutputNames = []string{"hlt.wav", "hrt.wav", "lb_prelogic.wav", "rb_prelogic.wav"}
+
+// debugOutputs writes the decoder's intermediate signals to one mono WAV
+// per signal in the debug output directory. It is fed from a
+// decoder.HookIntermediate block hook.
+type debugOutputs struct {
+	writers []*wav.Writer
+	err     error
+}
+
+func openDebugOutputs(outputs *artifact.Set, sampleRate uint32, numFrames int) (*debugOutputs, error) {
+	options, err := writeOptions()
+	if err != nil {
+		return nil, err
+	}
+	// The taps are diagnostics; --error-on-clip guards the main output.
+	options.ErrorOnClip = false
+
+	o := &debugOutputs{}
+	for _, name := range debugOutputNames {
+		file, err := outputs.CreateIn("debug", name)
+		if err != nil {
+			return nil, err
+		}
+		writer, err := wav.NewWriter(file, sampleRate, 1, numFrames, options)
+		if err != nil {
+			return nil, err
+		}
+		o.writers = append(o.writers, writer)
+	}
+	return o, nil
+}
+
+// hook is the decoder.BlockHook writing each tapped block. The first write
+// error is kept and reported by Close.
+func (o *debugOutputs) hook(blockIdx int, buffers [][]float64) {
+	if o.err != nil {
+		return
+	}
+	for i, writer := range o.writers {
+		if err := writer.WriteFrames(buffers[i : i+1]); err != nil {
+			o.err = fmt.Errorf("failed to write %s: %w", debugOutputNames[i], err)
+			return
+		}
+	}
+}
+
+// Close finalizes the debug files and reports the first write error. The
+// files belong to the decode outputs, which remove them on failure.
+func (o *debugOutputs) Close() error {
+	if o.err != nil {
+		return o.err
+	}
+	for _, writer := range o.writers {
+		if err := writer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
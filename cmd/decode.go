@@ -2,103 +2,495 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cwbudde/go-sq-tool/internal/decoder"
-	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/internal/dynamics"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
 	"github.com/spf13/cobra"
 )
 
 var decodeCmd = &cobra.Command{
-	Use:   "decode [input.wav] [output.wav]",
+	Use:   "decode [input] [output.wav]",
 	Short: "Decode SQ-encoded stereo to quadrophonic WAV",
-	Args:  cobra.ExactArgs(2),
-	RunE:  runDecode,
+	Long: `Decode SQ-encoded stereo to quadrophonic WAV.
+
+The input may be WAV, Ogg Vorbis or MP3; the format is detected from the
+file header or extension. Lossy inputs are decoded at their native sample
+rate.
+
+A mono input, in any format, is rejected by default. --mono-policy duplicate
+decodes it as LT = RT, which places the source at the front center: the
+backs are then the same signal in opposite polarity, so one Hilbert
+transform is computed instead of two. reject-with-hint fails with a
+suggestion of what to do instead.
+
+Besides the decoded WAV, --output selects further artifacts as a
+comma-separated list of kind=path entries, e.g.
+"main=out.wav,image=image.csv,debug=taps/". The output WAV may be given
+either as the last argument or as main=. All outputs are checked before
+decoding starts and removed again if decoding fails.
+
+One pass can write the decoded audio several times: every extra= entry
+adds a WAV, and main and extra entries take options after the kind, a
+sample format (pcm8, pcm16, pcm24 or float32, overriding --bit-depth)
+and stereo for a downmix with the backs at -3 dB, e.g.
+"--output main:float32=quad.wav --output extra:stereo:pcm16=mix.wav". An
+output that fails is reported and removed while the others are finished;
+--strict fails the whole run instead.
+
+--split-fb front.wav,back.wav writes LF/RF and LB/RB to two stereo files
+(--output front= and back=), for editors that handle stereo better than
+quad; the quad output is then optional. Both take a sample format option.
+
+--preview decodes only --preview-length seconds out of every
+--preview-every seconds, from the start of the input, at half the block
+size, and writes the segments with short fades as one shorter file
+labeled as a preview in its INFO chunk. Each segment is decoded on its own
+with 2 s of warm-up, so its start is as clean as in a full decode.
+
+--hrir renders the decoded channels to binaural stereo for headphones with
+one of the embedded HRIR sets; --rear-eq applies one of the embedded
+high-shelf presets to the back channels.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runDecode,
+}
+
+var (
+	debugOutputDir    string
+	compress          bool
+	compressThreshold float64
+	compressRatio     float64
+	bassCrossover     float64
+	rearLowpass       float64
+	crossfeedAmount   float64
+	crossfeedDelay    float64
+	backSoftness      float64
+	skipSilence       bool
+	silenceThreshold  float64
+	silenceMin        float64
+	tailMode          string
+	hilbertFIRPath    string
+	splitFB           []string
+)
+
+func init() {
+	defaults := dynamics.DefaultCompressorConfig()
+	decodeCmd.Flags().StringVar(&debugOutputDir, "debug-outputs", "", "also write H(LT), H(RT) and pre-logic LB/RB as mono WAVs to this directory")
+	decodeCmd.Flags().BoolVar(&compress, "compress", false, "apply a linked 3-band compressor to the decoded channels")
+	decodeCmd.Flags().Float64Var(&compressThreshold, "compress-threshold", defaults.ThresholdDB, "compressor threshold in dBFS (band RMS)")
+	decodeCmd.Flags().Float64Var(&compressRatio, "compress-ratio", defaults.Ratio, "compressor ratio above the threshold")
+	addImageReportFlag(decodeCmd.Flags())
+	addGainFlags(decodeCmd.Flags())
+	addSkewFlags(decodeCmd.Flags())
+	addHeadphoneFlags(decodeCmd.Flags())
+	addOutputFlag(decodeCmd.Flags(), decodeArtifacts)
+	addStrictFlag(decodeCmd.Flags())
+	addMemoryFlag(decodeCmd.Flags())
+	addSidecarFlag(decodeCmd.Flags())
+	addMatrixFlag(decodeCmd.Flags())
+	addHilbertScaleFlag(decodeCmd.Flags())
+	addDebugMarkersFlag(decodeCmd.Flags())
+	silence := decoder.DefaultSilenceSkipConfig()
+	decodeCmd.Flags().BoolVar(&skipSilence, "skip-silence", false, "skip the FFT path for long silent passages and output exact zeros")
+	decodeCmd.Flags().Float64Var(&silenceThreshold, "silence-threshold", silence.ThresholdDB, "level in dBFS at or below which input counts as silent")
+	decodeCmd.Flags().Float64Var(&silenceMin, "silence-min", silence.MinDuration, "seconds of silence before blocks are skipped")
+	decodeCmd.Flags().Float64Var(&bassCrossover, "bass-crossover", 0, "fold back-channel bass below this frequency (Hz) into the fronts (0 = off)")
+	decodeCmd.Flags().Float64Var(&rearLowpass, "rear-lowpass", 0, "low-pass the back channels at this frequency (Hz) like vintage decoders (0 = off, e.g. 10000)")
+	decodeCmd.Flags().Float64Var(&crossfeedAmount, "crossfeed", 0, "mix this much of each front channel, delayed, into the other for headphones (0-1, 0 = off)")
+	decodeCmd.Flags().Float64Var(&crossfeedDelay, "crossfeed-delay", 0.3, "delay of the --crossfeed copy in ms")
+	decodeCmd.Flags().Float64Var(&backSoftness, "back-softness", 0, "experimental: soft knee on the back channels to round off transient peaks (0-1, 0 = off)")
+	decodeCmd.Flags().StringVar(&tailMode, "tail", "zero", "padding of the last block past the end of the input: zero, mirror or hold")
+	decodeCmd.Flags().StringVar(&hilbertFIRPath, "hilbert-fir", "", "use the Hilbert FIR in this file (whitespace-separated coefficients) instead of the built-in design")
+	decodeCmd.Flags().StringSliceVar(&splitFB, "split-fb", nil, "also write LF/RF and LB/RB as two stereo files: front.wav,back.wav")
+	decodeCmd.Flags().BoolVar(&preview, "preview", false, "decode only --preview-length seconds out of every --preview-every seconds for quick audition")
+	decodeCmd.Flags().Float64Var(&previewLength, "preview-length", 10, "length in seconds of each --preview segment")
+	decodeCmd.Flags().Float64Var(&previewEvery, "preview-every", 60, "distance in seconds between the starts of the --preview segments")
+	decodeCmd.Flags().StringVar(&monoPolicy, "mono-policy", monoError, "handling of a mono input: error, duplicate or reject-with-hint")
+	decodeCmd.Flags().BoolVar(&logicTwoPass, "logic-two-pass", false, "logic steering planned from a first pass over the whole input, without attack lag or release pumping (implies --logic; input files only)")
+	decodeCmd.Flags().BoolVar(&adaptiveBlocks, "adaptive", false, "experimental: choose half, once or twice the block size per region from its transient density")
+}
+
+// newCompressor creates the --compress stage. Its hop is kept a multiple of
+// the decoder hop so that pipeline chunks line up with both stages.
+func newCompressor(sampleRate uint32, hop int) (*dynamics.Compressor, error) {
+	if hop <= 0 {
+		return nil, fmt.Errorf("overlap must be > 0, got %d", hop)
+	}
+	cfg := dynamics.DefaultCompressorConfig()
+	cfg.ThresholdDB = compressThreshold
+	cfg.Ratio = compressRatio
+	cfg.BlockSize = dynamics.DefaultCompressorBlockSize
+	for (cfg.BlockSize/2)%hop != 0 {
+		cfg.BlockSize *= 2
+		if cfg.BlockSize > 1<<16 {
+			return nil, fmt.Errorf("--compress requires a power-of-2 overlap, got %d", hop)
+		}
+	}
+	return dynamics.NewCompressor(cfg, int(sampleRate))
 }
 
 func runDecode(cmd *cobra.Command, args []string) error {
 	inputFile := args[0]
-	outputFile := args[1]
 
-	if verbose {
-		fmt.Printf("SQ Quadrophonic Decoder\n")
-		fmt.Printf("=======================\n\n")
+	if err := applyProfile(cmd, "decode"); err != nil {
+		return err
+	}
+	if len(splitFB) != 0 && len(splitFB) != 2 {
+		return fmt.Errorf("--split-fb takes two paths, front.wav,back.wav, got %d", len(splitFB))
+	}
+	outputs, err := newOutputs(decodeArtifacts, outputSpecs, map[string]string{
+		"main":  optionalArg(args, 1),
+		"debug": debugOutputDir,
+		"front": optionalArg(splitFB, 0),
+		"back":  optionalArg(splitFB, 1),
+	})
+	if err != nil {
+		return err
+	}
+	if err := rejectOverwrite(outputs, inputFile); err != nil {
+		return err
+	}
+	if !outputs.Has("front") && !outputs.Has("back") {
+		if _, err := requireMain(outputs); err != nil {
+			return err
+		}
+	}
+	run, err := startSidecar(cmd, args, outputs)
+	if err != nil {
+		return err
 	}
+	defer run.stop()
 
-	// Read input WAV
-	if verbose {
-		fmt.Printf("Reading input file: %s\n", inputFile)
+	if err := validateMonoPolicy(); err != nil {
+		return err
+	}
+	if err := validateLogicTwoPass(inputFile); err != nil {
+		return err
 	}
 
-	audioData, err := wav.ReadWAV(inputFile)
+	start := time.Now()
+	logger.Info("reading input", "path", inputFile)
+	input, inChannels, err := openDecodeInput(inputFile)
 	if err != nil {
-		return fmt.Errorf("failed to read input WAV: %w", err)
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	defer input.Close()
+
+	sampleRate := input.sampleRate
+	numSamples := input.numFrames
+
+	logger.Info("input",
+		"format", input.format.Name,
+		"sample_rate", sampleRate,
+		"frames", numSamples,
+		"duration", samplesDuration(numSamples, sampleRate))
+	if input.format.Lossy {
+		logger.Info("lossy source; codec artifacts and phase smearing reduce achievable separation")
 	}
 
-	if verbose {
-		fmt.Printf("  Sample rate: %d Hz\n", audioData.SampleRate)
-		fmt.Printf("  Samples: %d\n", audioData.NumSamples)
-		fmt.Printf("  Duration: %.2f seconds\n\n", float64(audioData.NumSamples)/float64(audioData.SampleRate))
+	backChannelMode, err := decoder.ParseBackChannelMode(backMode)
+	if err != nil {
+		return err
+	}
+	matrixSystem, err := decoder.ParseMatrix(matrixName)
+	if err != nil {
+		return err
+	}
+	if err := checkHilbertScale(); err != nil {
+		return err
+	}
+	if err := checkDebugMarkers(); err != nil {
+		return err
+	}
+	if hilbertScale != sqmath.DefaultHilbertScale && hilbertFIRPath != "" {
+		return fmt.Errorf("--hilbert-scale applies to the built-in filter and cannot be combined with --hilbert-fir")
+	}
+	if bassCrossover < 0 || bassCrossover >= float64(sampleRate)/2 {
+		return fmt.Errorf("--bass-crossover must be between 0 and %d Hz, got %g", sampleRate/2, bassCrossover)
+	}
+	if rearLowpass < 0 || rearLowpass >= float64(sampleRate)/2 {
+		return fmt.Errorf("--rear-lowpass must be between 0 and %d Hz, got %g", sampleRate/2, rearLowpass)
+	}
+	if crossfeedAmount < 0 || crossfeedAmount > 1 {
+		return fmt.Errorf("--crossfeed must be between 0 and 1, got %g", crossfeedAmount)
+	}
+	if backSoftness < 0 || backSoftness > 1 {
+		return fmt.Errorf("--back-softness must be between 0 and 1, got %g", backSoftness)
+	}
+	if crossfeedDelay < 0 || crossfeedDelay > decoder.MaxCrossfeedDelayMs {
+		return fmt.Errorf("--crossfeed-delay must be between 0 and %g ms, got %g", decoder.MaxCrossfeedDelayMs, crossfeedDelay)
+	}
+	if err := validateImageReport(); err != nil {
+		return err
+	}
+	if err := correctSkew(inputFile, input); err != nil {
+		return err
+	}
+	precision, err := sqmath.ParsePrecision(fftPrecision)
+	if err != nil {
+		return err
+	}
+	tail, err := decoder.ParseTailHandling(tailMode)
+	if err != nil {
+		return err
+	}
+	window, err := applyQuality(cmd, sampleRate)
+	if err != nil {
+		return err
+	}
+	if adaptiveBlocks && (skipSilence || outputs.Has("debug") || hilbertFIRPath != "") {
+		return fmt.Errorf("--adaptive cannot be combined with --skip-silence, --hilbert-fir or debug outputs")
+	}
+	if preview {
+		if err := validatePreview(); err != nil {
+			return err
+		}
+		if adaptiveBlocks || outputs.Has("debug") || hilbertFIRPath != "" {
+			return fmt.Errorf("--preview cannot be combined with --adaptive, --hilbert-fir or debug outputs")
+		}
 	}
 
 	// Create decoder
-	sqDecoder := decoder.NewSQDecoderWithParams(blockSize, overlap)
-	sqDecoder.SetSampleRate(int(audioData.SampleRate))
-	if logic {
-		sqDecoder.EnableLogicSteering(true)
-	}
-
-	if verbose {
-		fmt.Printf("Decoder configuration:\n")
-		fmt.Printf("  Block size: %d samples\n", blockSize)
-		fmt.Printf("  Overlap: %d samples\n", overlap)
-		if logic {
-			fmt.Printf("  Logic steering: enabled\n")
+	options := []sq.Option{
+		sq.WithSampleRate(int(sampleRate)),
+		sq.WithMatrix(matrixSystem),
+		sq.WithWindow(window),
+		sq.WithPrecision(precision),
+		sq.WithHilbertScale(hilbertScale),
+	}
+	if logic || logicTwoPass {
+		options = append(options, sq.WithLogicSteering(sq.DefaultLogicSteeringConfig()))
+	}
+	newDecoder := func(blockSize, overlap int) *sq.Decoder {
+		d, err := sq.NewDecoder(append(options, sq.WithBlockSize(blockSize), sq.WithOverlap(overlap))...)
+		if err != nil {
+			// checkBlockParams has validated the main block size; the
+			// adaptive and preview ones are fixed.
+			panic(err)
+		}
+		d.SetSanitizeInput(sanitize)
+		d.SetBackChannelMode(backChannelMode)
+		d.SetBassManagement(bassCrossover)
+		d.SetRearLowpass(rearLowpass)
+		d.SetCrossfeed(crossfeedAmount, crossfeedDelay)
+		d.SetBackChannelSoftness(backSoftness)
+		d.SetTailHandling(tail)
+		d.SetSilenceSkip(decoder.SilenceSkipConfig{
+			Enabled:     skipSilence,
+			ThresholdDB: silenceThreshold,
+			MinDuration: silenceMin,
+		})
+		return d
+	}
+	sqDecoder := newDecoder(blockSize, overlap)
+	filterTaps := decoder.FilterLength(blockSize, overlap)
+	if hilbertFIRPath != "" {
+		fir, err := decoder.LoadHilbertFIR(hilbertFIRPath)
+		if err != nil {
+			return err
 		}
-		fmt.Printf("  Latency: %d samples (%.2f ms)\n\n",
-			sqDecoder.GetLatency(),
-			float64(sqDecoder.GetLatency())/float64(audioData.SampleRate)*1000.0)
-		fmt.Printf("Processing...\n")
+		if err := sqDecoder.SetHilbertFIR(fir); err != nil {
+			return fmt.Errorf("--hilbert-fir: %w", err)
+		}
+		filterTaps = len(fir)
+		logger.Info("custom Hilbert FIR", "path", hilbertFIRPath, "taps", len(fir))
 	}
 
-	// Decode
-	output, err := sqDecoder.Process(audioData.Samples)
-	if err != nil {
-		return fmt.Errorf("decoding failed: %w", err)
+	logger.Info("decoder configuration",
+		"quality", quality,
+		"matrix", matrixSystem.String(),
+		"block_size", blockSize,
+		"overlap", overlap,
+		"window", string(window),
+		"filter_taps", filterTaps,
+		"logic", logic || logicTwoPass,
+		"logic_two_pass", logicTwoPass,
+		"back_mode", backChannelMode.String(),
+		"precision", precision.String(),
+		"bass_crossover_hz", bassCrossover,
+		"rear_lowpass_hz", rearLowpass,
+		"crossfeed", crossfeedAmount,
+		"back_softness", backSoftness,
+		"tail", tail.String(),
+		"adaptive", adaptiveBlocks,
+		"input_gain_db", inputGain,
+		"output_gain_db", outputGain,
+		"latency_samples", sqDecoder.GetLatency(),
+		"latency", samplesDuration(sqDecoder.GetLatency(), sampleRate))
+
+	if logicTwoPass {
+		if err := planLogicSteering(sqDecoder, inputFile, inChannels, sampleRate); err != nil {
+			return err
+		}
 	}
 
-	// Prepare output data
-	outputData := &wav.AudioData{
-		SampleRate: audioData.SampleRate,
-		Samples:    output,
-		NumSamples: audioData.NumSamples,
+	process := pipeline.Processor(sqDecoder.ProcessSegment)
+	if inChannels == 1 {
+		process = sqDecoder.ProcessMonoSegment
+	}
+	cfg := pipeline.DefaultConfig(blockSize, overlap)
+	hop := overlap
+	codecs, codecBlock := 1, blockSize
+	if adaptiveBlocks {
+		adaptive, err := newAdaptiveDecoder(inputFile, inChannels, sampleRate, newDecoder)
+		if err != nil {
+			return err
+		}
+		process = adaptive.ProcessSegment
+		if inChannels == 1 {
+			process = duplicateMono(process)
+		}
+		hop = adaptive.Hop()
+		cfg = pipeline.DefaultConfig(hop, hop)
+		cfg.Lookahead = adaptive.Lookahead()
+		codecs, codecBlock = 3, 2*blockSize
+	}
+	stream, streamChannels := input, inChannels
+	if preview {
+		// The preview source decodes; the stages after the decoder run on
+		// its output as usual.
+		previewBlock, previewOverlap := previewBlocks(blockSize, overlap)
+		src, frames := newPreviewSource(pipeline.GainSource(input.source, inputGain), inChannels, numSamples, sampleRate,
+			func() *sq.Decoder { return newDecoder(previewBlock, previewOverlap) }, previewBlock, previewOverlap)
+		stream = &streamInput{source: src, format: input.format, sampleRate: sampleRate, numFrames: frames, info: previewInfo(), gained: true}
+		streamChannels = backChannelMode.Channels()
+		process = passDecoded
+		cfg = pipeline.DefaultConfig(hop, hop)
+		logger.Info("preview",
+			"segments", len(src.starts),
+			"segment_length", previewLength,
+			"every", previewEvery,
+			"block_size", previewBlock,
+			"overlap", previewOverlap,
+			"duration", samplesDuration(frames, sampleRate))
+	}
+	stream.markers = debugMarkers(stream.numFrames, hop, debugMarkerEvery, sqDecoder.GetLatency(), true)
+	granule := hop
+	if compress {
+		comp, err := newCompressor(sampleRate, hop)
+		if err != nil {
+			return fmt.Errorf("invalid compressor settings: %w", err)
+		}
+		compHop := comp.Lookahead()
+		granule = compHop
+		process = pipeline.Chain(process, comp.ProcessSegment, compHop)
+		cfg.Lookahead += compHop
+		cfg.ChunkFrames = (cfg.ChunkFrames + compHop - 1) / compHop * compHop
+		logger.Info("compressor",
+			"threshold_db", compressThreshold,
+			"ratio", compressRatio)
+	}
+
+	process, err = rearEQProcessor(process, sampleRate)
+	if err != nil {
+		return err
 	}
 
-	// Write output WAV
-	if verbose {
-		fmt.Printf("Writing output file: %s\n", outputFile)
-		if float32 {
-			fmt.Printf("  Format: 32-bit IEEE float\n")
-		} else {
-			fmt.Printf("  Format: 16-bit PCM\n")
+	var image *metrics.ImageAnalyzer
+	if imageReport != "" || outputs.Has("image") {
+		image = metrics.NewImageAnalyzer(int(sampleRate), metrics.DefaultImageWindow)
+		decode := process
+		process = func(input [][]float64, numOutput int) ([][]float64, error) {
+			output, err := decode(input, numOutput)
+			if err == nil {
+				image.Add(discreteChannels(output, backChannelMode))
+			}
+			return output, err
 		}
 	}
 
-	if float32 {
-		if err := wav.WriteFloat32WAV(outputFile, outputData); err != nil {
-			return fmt.Errorf("failed to write output WAV: %w", err)
+	outChannels := backChannelMode.Channels()
+	channelNames := backChannelMode.ChannelNames()
+	if hrirSet != "" {
+		process, err = binauralProcessor(process, backChannelMode, sampleRate)
+		if err != nil {
+			return err
 		}
-	} else {
-		if err := wav.WriteWAV(outputFile, outputData); err != nil {
-			return fmt.Errorf("failed to write output WAV: %w", err)
+		outChannels = 2
+		channelNames = []string{"L", "R"}
+	}
+
+	cfg, err = planMemory(newMemoryJob(input, inChannels, outChannels, codecs, codecBlock), cfg, granule)
+	if err != nil {
+		return err
+	}
+	audio, err := audioOutputs(outputs, outChannels)
+	if err != nil {
+		return err
+	}
+
+	if err := createOutputs(outputs); err != nil {
+		return err
+	}
+	var debug *debugOutputs
+	if outputs.Has("debug") {
+		debug, err = openDebugOutputs(outputs, sampleRate, numSamples)
+		if err != nil {
+			return finishOutputs(outputs, err)
 		}
+		sqDecoder.SetBlockHook(decoder.HookIntermediate, debug.hook)
+		logger.Info("writing intermediate signals", "dir", outputs.Path("debug"))
 	}
 
-	if verbose {
-		fmt.Printf("\nDone! Decoded to 4-channel quadrophonic audio.\n")
-		fmt.Printf("Channels: LF (Left Front), RF (Right Front), LB (Left Back), RB (Right Back)\n")
-	} else {
-		fmt.Printf("Successfully decoded %s -> %s\n", inputFile, outputFile)
+	for _, out := range audio {
+		logger.Info("writing output", "path", out.path, "format", out.options.Format.String(), "channels", out.channels)
+	}
+
+	// Decode, overlapping file reading and writing with processing
+	err = streamProcess(stream, streamChannels, outputs, outChannels, process, cfg)
+	if err == nil && debug != nil {
+		err = debug.Close()
+	}
+	if err == nil && outputs.Has("image") {
+		err = printImageReport(outputs.File("image"), image.Windows(), imageReportFormat(outputs.Path("image")))
+	}
+	if err := finishOutputs(outputs, err); err != nil {
+		return fmt.Errorf("decoding failed: %w", err)
+	}
+
+	if skipSilence {
+		logSilenceStats(sqDecoder.SilenceStats(), time.Since(start), sampleRate)
+	}
+	written := writtenAudio(outputs)
+	logger.Info("decoded",
+		"path", written,
+		"channels", strings.Join(channelNames, ","),
+		"elapsed", time.Since(start))
+	if err := run.finish(decodeInputs(inputFile), audioPaths(outputs), "decoder configuration"); err != nil {
+		return err
+	}
+	fmt.Fprintf(reportOutput(outputs), "Successfully decoded %s -> %s\n", inputFile, written)
+	if image != nil && !outputs.Has("image") {
+		return printImageReport(reportOutput(outputs), image.Windows(), imageReport)
 	}
 
 	return nil
 }
+
+// decodeInputs returns the files a decode of inputFile read: the input and
+// the --hilbert-fir file, if any.
+func decodeInputs(inputFile string) []string {
+	if hilbertFIRPath != "" {
+		return []string{inputFile, hilbertFIRPath}
+	}
+	return []string{inputFile}
+}
+
+// logSilenceStats reports the skipped blocks and estimates the processing
+// time they saved from the average time of the decoded blocks.
+func logSilenceStats(stats decoder.SilenceStats, elapsed time.Duration, sampleRate uint32) {
+	var saved time.Duration
+	if decodedBlocks := stats.Blocks - stats.SkippedBlocks; decodedBlocks > 0 {
+		saved = elapsed * time.Duration(stats.SkippedBlocks) / time.Duration(decodedBlocks)
+	}
+	logger.Info("silence skip",
+		"blocks", stats.Blocks,
+		"skipped_blocks", stats.SkippedBlocks,
+		"skipped_audio", samplesDuration(stats.SkippedBlocks*overlap, sampleRate),
+		"time_saved_estimate", saved.Round(time.Millisecond))
+}
@@ -2,91 +2,259 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
 
-	"github.com/cwbudde/go-sq-tool/internal/encoder"
-	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/selftest"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
 	"github.com/spf13/cobra"
 )
 
 var encodeCmd = &cobra.Command{
 	Use:   "encode [input.wav] [output.wav]",
 	Short: "Encode quadrophonic WAV to SQ-encoded stereo",
-	Args:  cobra.ExactArgs(2),
-	RunE:  runEncode,
+	Long: `Encode quadrophonic WAV to SQ-encoded stereo.
+
+The output WAV may be given either as the last argument or with
+--output main=path; --output verify=path writes the --verify report to a
+file. All outputs are checked before encoding starts and removed again if
+encoding fails.
+
+The encoded stereo is checked for compatibility in 400 ms windows: a
+window whose LT/RT correlation is below --compat-min-corr or whose mono
+fold-down (LT+RT)/2 loses more than --compat-max-loss dB is reported as a
+warning. --fix-compat narrows the stereo width of those windows until they
+pass, leaving the rest of the output untouched.
+
+--ref-tone freq,dBFS,seconds prepends a line-up tone to both channels of
+the output, e.g. --ref-tone 1000,-18,2 for 2 s of 1 kHz at -18 dBFS peak,
+as used to calibrate a tape machine or cutting lathe. The tone is not
+encoded or affected by --output-gain.
+
+The energy gain of the encode matrix and the output peak are logged with
+-v. Correlated back channels can sum to more than full scale in the
+stereo; --encode-headroom encodes the input once to measure the peak and
+then again attenuated so that the output peak stays within
+--headroom-ceiling (default -1 dBFS). It only attenuates.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runEncode,
+}
+
+var encodeVerify bool
+
+func init() {
+	encodeCmd.Flags().BoolVar(&encodeVerify, "verify", false, "decode the result and report how well each channel is recovered")
+	addGainFlags(encodeCmd.Flags())
+	addCompatFlags(encodeCmd.Flags())
+	addHeadroomFlags(encodeCmd.Flags())
+	addSidecarFlag(encodeCmd.Flags())
+	encodeCmd.Flags().StringVar(&refToneSpec, "ref-tone", "", "prepend a reference tone to the output: freq,dBFS,seconds (e.g. 1000,-18,2)")
+	addMemoryFlag(encodeCmd.Flags())
+	addHilbertScaleFlag(encodeCmd.Flags())
+	addDebugMarkersFlag(encodeCmd.Flags())
+	addOutputFlag(encodeCmd.Flags(), encodeArtifacts)
 }
 
 func runEncode(cmd *cobra.Command, args []string) error {
 	inputFile := args[0]
-	outputFile := args[1]
 
-	if verbose {
-		fmt.Printf("SQ Quadrophonic Encoder\n")
-		fmt.Printf("=======================\n\n")
+	if err := applyProfile(cmd, "encode"); err != nil {
+		return err
+	}
+	outputs, err := newOutputs(encodeArtifacts, outputSpecs, map[string]string{"main": optionalArg(args, 1)})
+	if err != nil {
+		return err
+	}
+	if err := rejectOverwrite(outputs, inputFile); err != nil {
+		return err
+	}
+	outputFile, err := requireMain(outputs)
+	if err != nil {
+		return err
+	}
+	toStdout := outputFile == artifact.Stdout
+	if toStdout && (encodeVerify || outputs.Has("verify") || fixCompat) {
+		// Both read the written file back.
+		return fmt.Errorf("--verify and --fix-compat need an output file, not stdout")
+	}
+	if toStdout {
+		logger.Info("output is stdout, skipping the stereo compatibility check")
 	}
+	run, err := startSidecar(cmd, args, outputs)
+	if err != nil {
+		return err
+	}
+	defer run.stop()
 
-	if verbose {
-		fmt.Printf("Reading input file: %s\n", inputFile)
+	precision, err := sqmath.ParsePrecision(fftPrecision)
+	if err != nil {
+		return err
+	}
+	if err := checkHilbertScale(); err != nil {
+		return err
+	}
+	if err := checkDebugMarkers(); err != nil {
+		return err
 	}
 
-	audioData, err := wav.ReadWAVChannels(inputFile, 4)
+	start := time.Now()
+	logger.Info("reading input", "path", inputFile)
+	input, err := openStream(inputFile, 4)
 	if err != nil {
-		return fmt.Errorf("failed to read input WAV: %w", err)
+		return fmt.Errorf("failed to read input: %w", err)
 	}
+	defer input.Close()
+
+	sampleRate := input.sampleRate
+	numSamples := input.numFrames
+
+	logger.Info("input",
+		"format", input.format.Name,
+		"sample_rate", sampleRate,
+		"frames", numSamples,
+		"duration", samplesDuration(numSamples, sampleRate))
 
-	if verbose {
-		fmt.Printf("  Sample rate: %d Hz\n", audioData.SampleRate)
-		fmt.Printf("  Samples: %d\n", audioData.NumSamples)
-		fmt.Printf("  Duration: %.2f seconds\n\n", float64(audioData.NumSamples)/float64(audioData.SampleRate))
+	window, err := applyQuality(cmd, sampleRate)
+	if err != nil {
+		return err
+	}
+	tone, err := parseRefTone(refToneSpec, sampleRate)
+	if err != nil {
+		return err
+	}
+	if tone.Seconds > 0 {
+		input.lead = tone.Samples(sampleRate, 2)
+		logger.Info("reference tone", "frequency_hz", tone.Freq, "level_dbfs", tone.LevelDB, "frames", input.leadFrames())
+	}
+	newEncoder := func() (*sq.Encoder, error) {
+		return sq.NewEncoder(sq.WithBlockSize(blockSize), sq.WithOverlap(overlap), sq.WithWindow(window), sq.WithPrecision(precision), sq.WithHilbertScale(hilbertScale))
+	}
+	sqEncoder, err := newEncoder()
+	if err != nil {
+		return err
 	}
 
-	sqEncoder := encoder.NewSQEncoderWithParams(blockSize, overlap)
+	logger.Info("encoder configuration",
+		"quality", quality,
+		"block_size", blockSize,
+		"overlap", overlap,
+		"window", string(window),
+		"filter_taps", decoder.FilterLength(blockSize, overlap),
+		"precision", precision.String(),
+		"input_gain_db", inputGain,
+		"output_gain_db", outputGain,
+		"latency_samples", sqEncoder.GetLatency(),
+		"latency", samplesDuration(sqEncoder.GetLatency(), sampleRate))
+	input.markers = debugMarkers(numSamples, overlap, debugMarkerEvery, sqEncoder.GetLatency(), true)
 
-	if verbose {
-		fmt.Printf("Encoder configuration:\n")
-		fmt.Printf("  Block size: %d samples\n", blockSize)
-		fmt.Printf("  Overlap: %d samples\n", overlap)
-		fmt.Printf("  Latency: %d samples (%.2f ms)\n\n",
-			sqEncoder.GetLatency(),
-			float64(sqEncoder.GetLatency())/float64(audioData.SampleRate)*1000.0)
-		fmt.Printf("Processing...\n")
+	if format, err := outputFormat(); err == nil {
+		logger.Info("writing output", "path", outputFile, "format", format.String())
 	}
 
-	output, err := sqEncoder.Process(audioData.Samples)
+	job := newMemoryJob(input, 4, 2, 1, blockSize)
+	job.ResidentSamples += int64(input.leadFrames())
+	if fixCompat {
+		// --fix-compat reads the whole output back.
+		job.ResidentSamples += int64(input.leadFrames()+numSamples) * 2
+	}
+	cfg, err := planMemory(job, pipeline.DefaultConfig(blockSize, overlap), overlap)
 	if err != nil {
+		return err
+	}
+
+	if encodeHeadroom {
+		logger.Info("measuring output peak for --encode-headroom")
+		measureEncoder, err := newEncoder()
+		if err != nil {
+			return err
+		}
+		energy, err := measureEncode(inputFile, measureEncoder, cfg)
+		if err != nil {
+			return err
+		}
+		scale := headroomScale(energy.Peak)
+		sqEncoder.SetOutputScale(scale)
+		logger.Info("headroom",
+			"peak_dbfs", energy.PeakDB()+outputGain,
+			"ceiling_dbfs", headroomCeiling,
+			"attenuation_db", -20*math.Log10(scale))
+	}
+
+	if err := createOutputs(outputs); err != nil {
+		return err
+	}
+	sqEncoder.SetEnergyMeasurement(true)
+	err = streamProcess(input, 4, outputs, 2, sqEncoder.ProcessSegment, cfg)
+	if err == nil {
+		energy := sqEncoder.Energy()
+		logger.Info("encoder energy", "gain_db", energy.GainDB(), "peak_dbfs", energy.PeakDB()+outputGain)
+	}
+	if err == nil && !toStdout {
+		err = checkCompat(outputs.File("main"), sampleRate, shiftLoops(input.loops, input.leadFrames()))
+	}
+	if err == nil && outputs.Has("verify") {
+		err = verifyEncode(outputs.File("verify"), inputFile, outputFile, input.leadFrames(), window)
+	}
+	if err := finishOutputs(outputs, err); err != nil {
 		return fmt.Errorf("encoding failed: %w", err)
 	}
 
-	outputData := &wav.AudioData{
-		SampleRate: audioData.SampleRate,
-		Samples:    output,
-		NumSamples: audioData.NumSamples,
+	logger.Info("encoded", "path", outputFile, "channels", "LT,RT", "elapsed", time.Since(start))
+	if err := run.finish([]string{inputFile}, []string{outputFile}, "encoder configuration"); err != nil {
+		return err
 	}
+	fmt.Fprintf(reportOutput(outputs), "Successfully encoded %s -> %s\n", inputFile, outputFile)
 
-	if verbose {
-		fmt.Printf("Writing output file: %s\n", outputFile)
-		if float32 {
-			fmt.Printf("  Format: 32-bit IEEE float\n")
-		} else {
-			fmt.Printf("  Format: 16-bit PCM\n")
-		}
+	if encodeVerify && !outputs.Has("verify") {
+		return verifyEncode(os.Stdout, inputFile, outputFile, input.leadFrames(), window)
 	}
+	return nil
+}
 
-	if float32 {
-		if err := wav.WriteStereoFloat32WAV(outputFile, outputData); err != nil {
-			return fmt.Errorf("failed to write output WAV: %w", err)
-		}
-	} else {
-		if err := wav.WriteStereoWAV(outputFile, outputData); err != nil {
-			return fmt.Errorf("failed to write output WAV: %w", err)
-		}
+// verifyEncode decodes the written stereo file, after its first skip
+// frames, and reports the recovery separation of every channel against the
+// original quad input to w. Poor recovery is reported as a warning, not an
+// error.
+func verifyEncode(w io.Writer, inputFile, outputFile string, skip int, window sqmath.WindowType) error {
+	original, _, err := readAudio(inputFile, 4)
+	if err != nil {
+		return fmt.Errorf("verify: failed to read input: %w", err)
+	}
+	encoded, _, err := readAudio(outputFile, 2)
+	if err != nil {
+		return fmt.Errorf("verify: failed to read output: %w", err)
+	}
+	for ch := range encoded.Samples {
+		encoded.Samples[ch] = encoded.Samples[ch][skip:]
 	}
 
-	if verbose {
-		fmt.Printf("\nDone! Encoded to 2-channel SQ stereo audio.\n")
-		fmt.Printf("Channels: LT (Left Total), RT (Right Total)\n")
-	} else {
-		fmt.Printf("Successfully encoded %s -> %s\n", inputFile, outputFile)
+	config := selftest.Config{Name: "encode", BlockSize: blockSize, Overlap: overlap, Window: window}
+	result, err := selftest.Verify(original.Samples, encoded.Samples, config,
+		int(original.SampleRate), selftest.DefaultVerifyThresholdDB)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
 	}
 
+	channelNames := []string{"LF", "RF", "LB", "RB"}
+	fmt.Fprintf(w, "\nVerify (decode -> compare with input)\n")
+	for ch, name := range channelNames {
+		if !result.Active[ch] {
+			fmt.Fprintf(w, "  %s: silent\n", name)
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %s dB\n", name, formatSeparation(result.Separation[ch]))
+	}
+	for _, warning := range result.Warnings {
+		logger.Warn("poor channel recovery", "detail", warning)
+	}
+	if !result.Passed() {
+		logger.Warn("poor recovery usually means the source has content the SQ matrix cannot represent, such as anti-phase material")
+	}
 	return nil
 }
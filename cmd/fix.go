@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cwbudde/go-sq-tool/internal/repair"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/spf13/cobra"
+)
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [input] [output.wav]",
+	Short: "Detect and correct swapped or polarity-inverted SQ stereo channels",
+	Long: `Checks an SQ stereo file for the two most common faults of rips: swapped
+LT/RT channels and one channel with inverted polarity, and reports each
+with a confidence.
+
+An inverted channel shows as anti-phase bass, which SQ records do not
+carry. Swapped channels show in the phase of sources shared by the front
+and back speakers of one side; material without such side images gives
+little evidence either way, which the confidence reflects.
+
+With --apply the suspected faults are corrected and the result is written
+to the output file: the channels are swapped and/or RT is inverted.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runFix,
+}
+
+var fixApply bool
+
+func init() {
+	fixCmd.Flags().BoolVar(&fixApply, "apply", false, "write the corrected file to the output")
+}
+
+func runFix(cmd *cobra.Command, args []string) error {
+	inputFile := args[0]
+	outputFile := optionalArg(args, 1)
+	if fixApply && outputFile == "" {
+		return fmt.Errorf("--apply needs an output file")
+	}
+	if !fixApply && outputFile != "" {
+		return fmt.Errorf("an output file is only written with --apply")
+	}
+	outputs, err := newOutputs(mainArtifacts, nil, map[string]string{"main": outputFile})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("reading input", "path", inputFile)
+	audioData, _, err := readAudio(inputFile, 2)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	report, err := repair.Detect(audioData.Samples, int(audioData.SampleRate))
+	if err != nil {
+		return err
+	}
+	printFixReport(reportOutput(outputs), inputFile, report)
+	if !fixApply {
+		return nil
+	}
+
+	correction := report.Correction()
+	if err := repair.Apply(audioData.Samples, correction); err != nil {
+		return err
+	}
+	options, err := writeOptions()
+	if err != nil {
+		return err
+	}
+	if err := createOutputs(outputs); err != nil {
+		return err
+	}
+	err = wav.WriteWAVWithOptionsToWriter(outputs.File("main"), audioData, 2, options)
+	if err := finishOutputs(outputs, err); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	logger.Info("corrected", "path", outputFile, "correction", correction.String())
+	fmt.Fprintf(reportOutput(outputs), "Wrote %s (correction: %s)\n", outputFile, correction)
+	return nil
+}
+
+func printFixReport(w io.Writer, inputFile string, report repair.Report) {
+	fmt.Fprintf(w, "Channel check: %s\n\n", inputFile)
+	fmt.Fprintf(w, "Polarity: bass correlation %+.2f -> %s\n",
+		report.BassCorrelation, verdict(report.Inverted, "one channel inverted", "in phase"))
+	fmt.Fprintf(w, "Order:    side coherence   %+.2f -> %s\n",
+		report.SideCoherence, verdict(report.Swapped, "LT/RT swapped", "LT/RT in order"))
+	fmt.Fprintf(w, "\nSuggested correction: %s\n", report.Correction())
+}
+
+func verdict(f repair.Finding, suspected, fine string) string {
+	text := fine
+	if f.Suspected {
+		text = suspected
+	}
+	return fmt.Sprintf("%s (confidence %.0f%%)", text, 100*f.Confidence)
+}
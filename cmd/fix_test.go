@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestFix_AppliesPolarityCorrection(t *testing.T) {
+	dir := t.TempDir()
+	const frames = 16000
+	data := &wav.AudioData{SampleRate: 8000, NumSamples: frames, Samples: [][]float64{make([]float64, frames), make([]float64, frames)}}
+	for i := range data.Samples[0] {
+		v := 0.5 * math.Sin(2*math.Pi*60*float64(i)/8000)
+		data.Samples[0][i] = v
+		data.Samples[1][i] = -v
+	}
+	input := filepath.Join(dir, "inverted.wav")
+	if err := wav.WriteStereoWAV(input, data); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "fixed.wav")
+	if err := runCLI(t, "fix", input, output); err == nil {
+		t.Fatalf("output without --apply accepted")
+	}
+	if err := runCLI(t, "fix", input, output, "--apply"); err != nil {
+		t.Fatalf("fix error = %v", err)
+	}
+	fixed, err := wav.ReadWAVChannels(output, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range fixed.Samples[0] {
+		if math.Abs(fixed.Samples[1][i]-fixed.Samples[0][i]) > 1e-4 {
+			t.Fatalf("frame %d: LT %v, RT %v, want RT inverted back", i, fixed.Samples[0][i], fixed.Samples[1][i])
+		}
+	}
+}
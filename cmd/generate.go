@@ -2,9 +2,8 @@ package cmd
 
 import (
 	"fmt"
-	"math"
-	"math/rand"
 
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
 	"github.com/cwbudde/go-sq-tool/internal/wav"
 	"github.com/spf13/cobra"
 )
@@ -16,6 +15,14 @@ var (
 	genNoise     float64
 )
 
+var (
+	calSlot       float64
+	calRate       int
+	calLevel      float64
+	calSweepStart float64
+	calSweepEnd   float64
+)
+
 var generateCmd = &cobra.Command{
 	Use:   "generate-test [output.wav]",
 	Short: "Generate a 4-channel test WAV with tones and noise",
@@ -23,7 +30,23 @@ var generateCmd = &cobra.Command{
 	RunE:  runGenerate,
 }
 
+var generateCalCmd = &cobra.Command{
+	Use:   "generate-cal [output.wav]",
+	Short: "Generate a 4-channel calibration WAV with one isolated log sweep per channel",
+	Long: `Generates a 4-channel calibration file for separation testing. Each channel
+(LF, RF, LB, RB) carries a logarithmic sine sweep in its own time slot while
+the other channels are silent, so the file can be fed directly to "analyze".`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGenerateCal,
+}
+
 func init() {
+	generateCalCmd.Flags().Float64Var(&calSlot, "slot", 2.0, "per-channel slot length in seconds")
+	generateCalCmd.Flags().IntVar(&calRate, "rate", 44100, "sample rate in Hz")
+	generateCalCmd.Flags().Float64Var(&calLevel, "level", 0.5, "sweep amplitude (0-1)")
+	generateCalCmd.Flags().Float64Var(&calSweepStart, "sweep-start", 20.0, "sweep start frequency in Hz")
+	generateCalCmd.Flags().Float64Var(&calSweepEnd, "sweep-end", 20000.0, "sweep end frequency in Hz")
+
 	generateCmd.Flags().Float64Var(&genDuration, "duration", 5.0, "duration in seconds")
 	generateCmd.Flags().IntVar(&genRate, "rate", 44100, "sample rate in Hz")
 	generateCmd.Flags().Float64Var(&genToneLevel, "tone-level", 0.6, "tone amplitude (0-1)")
@@ -50,30 +73,34 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("duration too short for sample rate")
 	}
 
-	freqs := []float64{100.0, 200.0, 400.0, 800.0}
-	samples := make([][]float64, 4)
-	for ch := range 4 {
-		samples[ch] = make([]float64, numSamples)
-	}
+	samples := testsignal.QuadTones(genRate, numSamples, genToneLevel, genNoise)
 
-	rng := rand.New(rand.NewSource(1))
-	for i := range numSamples {
-		t := float64(i) / float64(genRate)
-		for ch := range 4 {
-			tone := genToneLevel * math.Sin(2.0*math.Pi*freqs[ch]*t)
-			noise := genNoise * (rng.Float64()*2.0 - 1.0)
-			samples[ch][i] = tone + noise
-		}
-	}
+	return writeAudio(outputFile, wav.NewAudioData(uint32(genRate), samples), 4)
+}
 
-	audioData := &wav.AudioData{
-		SampleRate: uint32(genRate),
-		Samples:    samples,
-		NumSamples: numSamples,
+func runGenerateCal(cmd *cobra.Command, args []string) error {
+	outputFile := args[0]
+	if calSlot <= 0 {
+		return fmt.Errorf("slot must be > 0")
+	}
+	if calRate <= 0 {
+		return fmt.Errorf("rate must be > 0")
+	}
+	if calLevel < 0 || calLevel > 1 {
+		return fmt.Errorf("level must be between 0 and 1")
+	}
+	if calSweepStart <= 0 || calSweepEnd <= 0 {
+		return fmt.Errorf("sweep frequencies must be > 0")
+	}
+	if nyquist := float64(calRate) / 2.0; calSweepStart > nyquist || calSweepEnd > nyquist {
+		return fmt.Errorf("sweep frequencies must not exceed Nyquist (%.0f Hz)", nyquist)
 	}
 
-	if float32 {
-		return wav.WriteFloat32WAV(outputFile, audioData)
+	slotSamples := int(calSlot * float64(calRate))
+	if slotSamples <= 0 {
+		return fmt.Errorf("slot too short for sample rate")
 	}
-	return wav.WriteWAV(outputFile, audioData)
+
+	samples := testsignal.QuadSweepSlots(calRate, slotSamples, calSweepStart, calSweepEnd, calLevel)
+	return writeAudio(outputFile, wav.NewAudioData(uint32(calRate), samples), 4)
 }
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/spf13/pflag"
+)
+
+// encodeHeadroom and headroomCeiling are encode --encode-headroom: attenuate
+// the encoded stereo so that its peak stays at or below headroomCeiling
+// dBFS.
+var (
+	encodeHeadroom  bool
+	headroomCeiling float64
+)
+
+func addHeadroomFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&encodeHeadroom, "encode-headroom", false, "attenuate the output to keep its peak within --headroom-ceiling (encodes twice)")
+	flags.Float64Var(&headroomCeiling, "headroom-ceiling", -1, "peak ceiling in dBFS for --encode-headroom")
+}
+
+// discardSink drops every frame, for passes that only measure.
+type discardSink struct{}
+
+func (discardSink) WriteFrames([][]float64) error { return nil }
+
+// measureEncode encodes the input once without writing it and returns the
+// energy measurement of the encoder, after --input-gain.
+func measureEncode(inputFile string, enc *sq.Encoder, cfg pipeline.Config) (encoder.EnergyStats, error) {
+	in, err := openStream(inputFile, 4)
+	if err != nil {
+		return encoder.EnergyStats{}, fmt.Errorf("headroom measurement: %w", err)
+	}
+	defer in.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	enc.SetEnergyMeasurement(true)
+	cfg.Logger = logger
+	if err := pipeline.Run(ctx, pipeline.GainSource(in.source, inputGain), 4, discardSink{}, enc.ProcessSegment, cfg); err != nil {
+		return encoder.EnergyStats{}, fmt.Errorf("headroom measurement: %w", err)
+	}
+	return enc.Energy(), nil
+}
+
+// headroomScale returns the encoder output scale of --encode-headroom for
+// an encoder output peak of peak. The ceiling applies after --output-gain.
+func headroomScale(peak float64) float64 {
+	return encoder.HeadroomScale(peak, headroomCeiling-outputGain)
+}
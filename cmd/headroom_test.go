@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"math"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestEncode_Headroom(t *testing.T) {
+	dir := t.TempDir()
+	// A full-scale tone in all four channels sums to well over full scale
+	// in the stereo.
+	const frames = 16000
+	data := &wav.AudioData{SampleRate: 8000, NumSamples: frames, Samples: make([][]float64, 4)}
+	for ch := range data.Samples {
+		data.Samples[ch] = make([]float64, frames)
+		for i := range data.Samples[ch] {
+			data.Samples[ch][i] = math.Sin(2 * math.Pi * float64(250*i) / 8000)
+		}
+	}
+	input := filepath.Join(dir, "quad.wav")
+	if err := wav.WriteWAVWithOptions(input, data, 4, wav.WriteOptions{Format: wav.FormatFloat32}); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.wav")
+
+	if err := runCLI(t, "encode", input, out, "--error-on-clip"); err == nil {
+		t.Fatalf("plain encode of a full-scale quad did not clip")
+	}
+
+	for _, ceiling := range []float64{-1, -6} {
+		err := runCLI(t, "encode", input, out, "--bit-depth", "32f", "--error-on-clip", "--encode-headroom", "--headroom-ceiling", strconv.FormatFloat(ceiling, 'g', -1, 64))
+		if err != nil {
+			t.Fatalf("encode --encode-headroom error = %v", err)
+		}
+		want := math.Pow(10, ceiling/20)
+		// float32 rounding of the written samples.
+		if peak := peakOf(readChannels(t, out, 2)); peak > want*(1+1e-6) || peak < 0.99*want {
+			t.Fatalf("ceiling %g dBFS: peak = %g, want %g", ceiling, peak, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/spf13/pflag"
+)
+
+// imageReport is the --image-report format shared by analyze and decode;
+// empty disables the report.
+var imageReport string
+
+func addImageReportFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&imageReport, "image-report", "", "print the energy centroid and image width per 10 s window: table or csv")
+	flags.Lookup("image-report").NoOptDefVal = "table"
+}
+
+func validateImageReport() error {
+	switch imageReport {
+	case "", "table", "csv":
+		return nil
+	default:
+		return fmt.Errorf("invalid image-report %q (use table or csv)", imageReport)
+	}
+}
+
+// discreteChannels returns LF, RF, LB, RB of decoder output in mode.
+func discreteChannels(output [][]float64, mode decoder.BackChannelMode) [][]float64 {
+	if mode != decoder.BackChannelSumDiff {
+		return output[:4]
+	}
+	sum, diff := output[2], output[3]
+	lb := make([]float64, len(sum))
+	rb := make([]float64, len(sum))
+	for i := range sum {
+		lb[i] = (sum[i] + diff[i]) / 2
+		rb[i] = (sum[i] - diff[i]) / 2
+	}
+	return [][]float64{output[0], output[1], lb, rb}
+}
+
+func printImageReport(w io.Writer, windows []metrics.ImageWindow, format string) error {
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"start_s", "duration_s", "left_right_pct", "front_back_pct", "width_pct", "rms"})
+		for _, win := range windows {
+			cw.Write([]string{
+				strconv.FormatFloat(win.Start, 'f', 3, 64),
+				strconv.FormatFloat(win.Duration, 'f', 3, 64),
+				strconv.FormatFloat(win.LeftRight, 'f', 2, 64),
+				strconv.FormatFloat(win.FrontBack, 'f', 2, 64),
+				strconv.FormatFloat(win.Width, 'f', 2, 64),
+				strconv.FormatFloat(win.RMS, 'f', 6, 64),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	fmt.Fprintf(w, "\nImage report (L/R: -100 left .. +100 right, F/B: -100 back .. +100 front)\n")
+	fmt.Fprintf(w, "   Start      End   L/R(%%)   F/B(%%)  Width(%%)       RMS\n")
+	for _, win := range windows {
+		fmt.Fprintf(w, "%7.1fs %7.1fs %8.1f %8.1f %9.1f %9.6f\n",
+			win.Start, win.Start+win.Duration, win.LeftRight, win.FrontBack, win.Width, win.RMS)
+	}
+	return nil
+}
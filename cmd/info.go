@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/spf13/cobra"
+)
+
+// infoJSON is the --json flag of info.
+var infoJSON bool
+
+var infoCmd = &cobra.Command{
+	Use:   "info <input.wav>",
+	Short: "Print the format, levels and decoder latency of a WAV file",
+	Long: `Reads a WAV file of any channel count and prints its sample rate, channel
+count, sample format, length, and the peak and RMS level of every channel,
+without decoding it. The decoder latency is that of the current
+--block-size and --overlap, or of the --quality preset at the file's sample
+rate. The round trip adds the encoder latency at the same settings, the
+delay to compensate for when an encoded and decoded file has to stay in
+sync with the original.
+
+With --json the same is written as a JSON object for scripts; levels are
+then linear, relative to full scale, rather than in dBFS.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := readFileInfo(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := applyQuality(cmd, info.SampleRate); err != nil {
+			return err
+		}
+		info.BlockSize, info.Overlap = blockSize, overlap
+		info.Latency = decoder.LatencyFor(blockSize, overlap)
+		info.RoundTripLatency = metrics.RoundTripLatency(blockSize, overlap)
+		if infoJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(info)
+		}
+		return writeFileInfo(cmd.OutOrStdout(), info)
+	},
+}
+
+func init() {
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, "write the information as JSON")
+}
+
+// fileInfo is the report of info.
+type fileInfo struct {
+	Path          string `json:"path"`
+	SampleRate    uint32 `json:"sample_rate"`
+	Channels      int    `json:"channels"`
+	BitsPerSample int    `json:"bits_per_sample"`
+	// Format is "pcm" for integer or "float" for IEEE float samples.
+	Format   string  `json:"format"`
+	Samples  int     `json:"samples"`
+	Duration float64 `json:"duration_seconds"`
+	// Peak and RMS are per channel, linear relative to full scale.
+	Peak      []float64 `json:"peak"`
+	RMS       []float64 `json:"rms"`
+	BlockSize int       `json:"block_size"`
+	Overlap   int       `json:"overlap"`
+	Latency   int       `json:"latency_samples"`
+	// RoundTripLatency is the latency of encoding and then decoding.
+	RoundTripLatency int `json:"round_trip_latency_samples"`
+}
+
+// readFileInfo reads the WAV file at path, of any channel count, and
+// returns its format and levels.
+func readFileInfo(path string) (fileInfo, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return fileInfo{}, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+	reader, err := wav.NewReader(file, wav.AnyChannels)
+	if err != nil {
+		return fileInfo{}, fmt.Errorf("failed to read WAV: %w", err)
+	}
+
+	header := reader.Info()
+	info := fileInfo{
+		Path:          path,
+		SampleRate:    header.SampleRate,
+		Channels:      header.Channels,
+		BitsPerSample: header.BitsPerSample,
+		Format:        "pcm",
+		Peak:          make([]float64, header.Channels),
+		RMS:           make([]float64, header.Channels),
+	}
+	if header.Float {
+		info.Format = "float"
+	}
+
+	buf := make([][]float64, header.Channels)
+	for ch := range buf {
+		buf[ch] = make([]float64, 8192)
+	}
+	// Sums of squares, accumulated in RMS until the length is known.
+	for {
+		n, err := reader.ReadFrames(buf)
+		for ch, samples := range buf {
+			for _, v := range samples[:n] {
+				info.Peak[ch] = max(info.Peak[ch], math.Abs(v))
+				info.RMS[ch] += v * v
+			}
+		}
+		info.Samples += n
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fileInfo{}, fmt.Errorf("failed to read WAV: %w", err)
+		}
+	}
+	if info.Samples > 0 {
+		for ch := range info.RMS {
+			info.RMS[ch] = math.Sqrt(info.RMS[ch] / float64(info.Samples))
+		}
+	}
+	info.Duration = float64(info.Samples) / float64(info.SampleRate)
+	return info, nil
+}
+
+// writeFileInfo writes info as text to w.
+func writeFileInfo(w io.Writer, info fileInfo) error {
+	format := "PCM"
+	if info.Format == "float" {
+		format = "IEEE float"
+	}
+	latencyMs := 1000 * float64(info.Latency) / float64(info.SampleRate)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "File:\t%s\n", info.Path)
+	fmt.Fprintf(tw, "Format:\t%d-bit %s\n", info.BitsPerSample, format)
+	fmt.Fprintf(tw, "Sample rate:\t%d Hz\n", info.SampleRate)
+	fmt.Fprintf(tw, "Channels:\t%d\n", info.Channels)
+	fmt.Fprintf(tw, "Samples:\t%d (%.3f s)\n", info.Samples, info.Duration)
+	fmt.Fprintf(tw, "Decoder latency:\t%d samples (%.1f ms) at block size %d, overlap %d\n",
+		info.Latency, latencyMs, info.BlockSize, info.Overlap)
+	fmt.Fprintf(tw, "Round trip:\t%d samples (%.1f ms) encode -> decode\n",
+		info.RoundTripLatency, 1000*float64(info.RoundTripLatency)/float64(info.SampleRate))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Channel\tPeak (dBFS)\tRMS (dBFS)\t\n")
+	for ch := range info.Channels {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t\n", ch+1, formatLevel(info.Peak[ch]), formatLevel(info.RMS[ch]))
+	}
+	return tw.Flush()
+}
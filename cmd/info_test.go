@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestInfo(t *testing.T) {
+	// Six channels: the nth a square wave at n/8 of full scale, whose peak
+	// and RMS are both n/8; the first is silent.
+	const channels, frames = 6, 4800
+	data := &wav.AudioData{SampleRate: 48000, Samples: make([][]float64, channels), NumSamples: frames}
+	for ch := range data.Samples {
+		data.Samples[ch] = make([]float64, frames)
+		for i := range frames {
+			data.Samples[ch][i] = float64(ch) / 8 * float64(1-2*(i%2))
+		}
+	}
+	input := filepath.Join(t.TempDir(), "six.wav")
+	if err := wav.WriteWAVWithOptions(input, data, channels, wav.WriteOptions{Format: wav.FormatFloat32}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := readFileInfo(input)
+	if err != nil {
+		t.Fatalf("readFileInfo() error = %v", err)
+	}
+	if info.Channels != channels || info.SampleRate != 48000 || info.Samples != frames || info.Duration != 0.1 {
+		t.Fatalf("readFileInfo() = %+v, want %d channels of %d samples at 48 kHz", info, channels, frames)
+	}
+	if info.BitsPerSample != 32 || info.Format != "float" {
+		t.Fatalf("format = %d-bit %s, want 32-bit float", info.BitsPerSample, info.Format)
+	}
+	for ch := range channels {
+		want := float64(ch) / 8
+		if info.Peak[ch] != want || math.Abs(info.RMS[ch]-want) > 1e-12 {
+			t.Fatalf("channel %d peak %g, RMS %g, want %g", ch, info.Peak[ch], info.RMS[ch], want)
+		}
+	}
+
+	info.BlockSize, info.Overlap, info.Latency = 2048, 1024, 1536
+	info.RoundTripLatency = 3072
+	var out bytes.Buffer
+	if err := writeFileInfo(&out, info); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"32-bit IEEE float", "Channels:         6", "1536 samples (32.0 ms)", "3072 samples (64.0 ms)", "-inf", "-6.0"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("info output = %q, want %q", out.String(), want)
+		}
+	}
+
+	if err := runCLI(t, "info", "--json", input); err != nil {
+		t.Fatalf("info --json error = %v", err)
+	}
+	if err := runCLI(t, "info", filepath.Join(t.TempDir(), "missing.wav")); err == nil {
+		t.Fatalf("info accepted a missing file")
+	}
+}
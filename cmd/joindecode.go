@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/cue"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+	"github.com/spf13/cobra"
+)
+
+var joinDecodeCmd = &cobra.Command{
+	Use:   "join-decode [input]... [output.wav]",
+	Short: "Decode several SQ stereo files as one continuous stream",
+	Long: `Decode several SQ stereo files, such as the sides of a digitized LP, as
+one continuous stream. The decoder state (block position and logic steering
+envelopes) carries over from one file to the next, so there is no warm-up
+transient or discontinuity at the joins: the result is identical to decoding
+the files concatenated.
+
+All inputs must have the same sample rate; with --allow-resample the inputs
+that differ from the most common rate are converted to it in memory. By
+default a single output is written; with --split each input gets its own
+output of the same length, named after the output with a -1, -2, ... suffix.
+
+--cue splits the output into tracks instead. It takes a CD cue sheet, which
+must list one FILE per input, or a JSON track list with start times in
+seconds on the joined inputs:
+
+  {"title": "...", "performer": "...",
+   "tracks": [{"title": "...", "performer": "...", "start": 0}, ...]}
+
+The whole stream is still decoded in one pass, so the tracks join without
+gaps or level jumps; a single input is allowed. Each track is named like
+--split and gets its number, title, performer and the album title in its
+LIST/INFO chunk.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if joinCue != "" {
+			return cobra.MinimumNArgs(2)(cmd, args)
+		}
+		return cobra.MinimumNArgs(3)(cmd, args)
+	},
+	RunE: runJoinDecode,
+}
+
+var (
+	joinSplit bool
+	joinCue   string
+)
+
+func init() {
+	joinDecodeCmd.Flags().BoolVar(&joinSplit, "split", false, "write one output per input (output-1.wav, output-2.wav, ...)")
+	addResampleFlag(joinDecodeCmd.Flags())
+	joinDecodeCmd.Flags().StringVar(&joinCue, "cue", "", "split the output into the tracks of this cue sheet or JSON track list")
+}
+
+// splitOutputNames returns the per-input output paths for output.
+func splitOutputNames(output string, n int) []string {
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%d%s", base, i+1, ext)
+	}
+	return names
+}
+
+func runJoinDecode(cmd *cobra.Command, args []string) error {
+	inputFiles := args[:len(args)-1]
+	outputFile := args[len(args)-1]
+
+	start := time.Now()
+	inputs := make([]*streamInput, 0, len(inputFiles))
+	defer func() {
+		for _, in := range inputs {
+			in.Close()
+		}
+	}()
+	for _, name := range inputFiles {
+		logger.Info("reading input", "path", name)
+		in, err := openStream(name, 2)
+		if err != nil {
+			return fmt.Errorf("failed to read input %s: %w", name, err)
+		}
+		inputs = append(inputs, in)
+		logger.Info("input",
+			"path", name,
+			"format", in.format.Name,
+			"sample_rate", in.sampleRate,
+			"frames", in.numFrames,
+			"duration", samplesDuration(in.numFrames, in.sampleRate))
+	}
+	sampleRate, err := reconcileSampleRates(inputFiles, inputs, 2)
+	if err != nil {
+		return err
+	}
+	sources := make([]pipeline.Source, len(inputs))
+	frames := make([]int, len(inputs))
+	total := 0
+	for i, in := range inputs {
+		sources[i] = in.source
+		frames[i] = in.numFrames
+		total += in.numFrames
+	}
+
+	backChannelMode, err := decoder.ParseBackChannelMode(backMode)
+	if err != nil {
+		return err
+	}
+	precision, err := sqmath.ParsePrecision(fftPrecision)
+	if err != nil {
+		return err
+	}
+	window, err := applyQuality(cmd, sampleRate)
+	if err != nil {
+		return err
+	}
+	options := []sq.Option{
+		sq.WithBlockSize(blockSize),
+		sq.WithOverlap(overlap),
+		sq.WithSampleRate(int(sampleRate)),
+		sq.WithWindow(window),
+		sq.WithPrecision(precision),
+	}
+	if logic {
+		options = append(options, sq.WithLogicSteering(sq.DefaultLogicSteeringConfig()))
+	}
+	sqDecoder, err := sq.NewDecoder(options...)
+	if err != nil {
+		return err
+	}
+	sqDecoder.SetSanitizeInput(sanitize)
+	sqDecoder.SetBackChannelMode(backChannelMode)
+
+	logger.Info("decoder configuration",
+		"quality", quality,
+		"block_size", blockSize,
+		"overlap", overlap,
+		"window", string(window),
+		"filter_taps", decoder.FilterLength(blockSize, overlap),
+		"logic", logic,
+		"back_mode", backChannelMode.String(),
+		"precision", precision.String(),
+		"latency_samples", sqDecoder.GetLatency(),
+		"latency", samplesDuration(sqDecoder.GetLatency(), sampleRate))
+
+	if joinCue != "" && joinSplit {
+		return fmt.Errorf("--cue and --split cannot be combined")
+	}
+	outputs := []string{outputFile}
+	var info []wav.InfoTags
+	switch {
+	case joinCue != "":
+		sheet, err := cue.Load(joinCue)
+		if err != nil {
+			return err
+		}
+		if frames, info, err = cueTracks(sheet, frames, sampleRate); err != nil {
+			return err
+		}
+		outputs = splitOutputNames(outputFile, len(frames))
+		logger.Info("track list", "path", joinCue, "tracks", len(frames))
+	case joinSplit:
+		outputs = splitOutputNames(outputFile, len(inputFiles))
+	default:
+		frames = []int{total}
+	}
+
+	src := pipeline.NewConcatSource(sources...)
+	cfg := pipeline.DefaultConfig(blockSize, overlap)
+	if err := streamJoin(src, sampleRate, outputs, frames, info, backChannelMode.Channels(), sqDecoder.ProcessSegment, cfg); err != nil {
+		return fmt.Errorf("decoding failed: %w", err)
+	}
+
+	logger.Info("decoded",
+		"outputs", strings.Join(outputs, ","),
+		"frames", total,
+		"elapsed", time.Since(start))
+	fmt.Printf("Successfully decoded %d inputs -> %s\n", len(inputFiles), strings.Join(outputs, ", "))
+	return nil
+}
+
+// streamJoin pipelines src through process into the outputs, writing
+// frames[i] frames and the INFO tags info[i], if any, to outputs[i]. On
+// error or SIGINT every output is removed.
+func streamJoin(src pipeline.Source, sampleRate uint32, outputs []string, frames []int, info []wav.InfoTags, outChannels int, process pipeline.Processor, cfg pipeline.Config) error {
+	options, err := writeOptions()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var files []*os.File
+	err = func() error {
+		writers := make([]audioWriter, len(outputs))
+		sinks := make([]pipeline.Sink, len(outputs))
+		for i, name := range outputs {
+			file, err := os.Create(name)
+			if err != nil {
+				return fmt.Errorf("failed to create WAV file: %w", err)
+			}
+			files = append(files, file)
+			fileOptions := options
+			if info != nil {
+				fileOptions.Info = info[i]
+			}
+			writers[i], err = newAudioWriter(name, file, sampleRate, outChannels, frames[i], fileOptions)
+			if err != nil {
+				return err
+			}
+			sinks[i] = writers[i]
+		}
+		sink, err := pipeline.NewSplitSink(sinks, frames)
+		if err != nil {
+			return err
+		}
+
+		cfg.Logger = logger
+		if err := pipeline.Run(ctx, src, 2, sink, process, cfg); err != nil {
+			return err
+		}
+		for i, w := range writers {
+			if err := w.Close(); err != nil {
+				return err
+			}
+			warnClipped(outputs[i], w)
+		}
+		return nil
+	}()
+
+	for _, file := range files {
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close WAV file: %w", closeErr)
+		}
+	}
+	if err != nil {
+		for _, file := range files {
+			os.Remove(file.Name())
+		}
+		if errors.Is(err, context.Canceled) {
+			return fmt.Errorf("interrupted, removed incomplete %s", strings.Join(outputs, ", "))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// checkWAVLength checks that the WAV file at path holds wantFrames frames
+// and that its RIFF and data chunk sizes agree with the file.
+func checkWAVLength(t *testing.T, path string, wantFrames int) {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if riff := binary.LittleEndian.Uint32(raw[4:8]); int(riff) != len(raw)-8 {
+		t.Fatalf("%s: RIFF size %d, file has %d bytes after the RIFF header", path, riff, len(raw)-8)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	info, err := wav.ReadInfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.NumFrames != wantFrames {
+		t.Fatalf("%s: header declares %d frames, want %d", path, info.NumFrames, wantFrames)
+	}
+	data, err := wav.ReadWAVChannels(path, info.Channels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.Validate(); err != nil || data.NumSamples != wantFrames {
+		t.Fatalf("%s: read %d frames, Validate() error = %v; want %d frames", path, data.NumSamples, err, wantFrames)
+	}
+}
+
+// TestOutputLength_HeadersMatchSamples covers the stages that change the
+// output length or delay it: tail padding with the latency dropped, the
+// rear low-pass delay, resampled inputs and a prepended reference tone.
+func TestOutputLength_HeadersMatchSamples(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 5000)
+
+	tail := filepath.Join(dir, "tail.wav")
+	if err := runCLI(t, "decode", "--tail", "hold", "--block-size", "1024", "--overlap", "256", input, tail); err != nil {
+		t.Fatalf("decode --tail hold error = %v", err)
+	}
+	checkWAVLength(t, tail, 5000)
+
+	rear := filepath.Join(dir, "rear.wav")
+	if err := runCLI(t, "decode", "--rear-lowpass", "2000", "--tail", "mirror", input, rear); err != nil {
+		t.Fatalf("decode --rear-lowpass error = %v", err)
+	}
+	checkWAVLength(t, rear, 5000)
+
+	a := writeStereoAt(t, dir, "a.wav", 8000, 3000)
+	b := writeStereoAt(t, dir, "b.wav", 16000, 4001)
+	joined := filepath.Join(dir, "joined.wav")
+	if err := runCLI(t, "join-decode", "--allow-resample", a, b, joined); err != nil {
+		t.Fatalf("join-decode --allow-resample error = %v", err)
+	}
+	resampled, err := wav.ReadWAVChannels(joined, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkWAVLength(t, joined, resampled.NumSamples)
+	if resampled.NumSamples < 3000+2000 || resampled.NumSamples > 3000+2001 {
+		t.Fatalf("joined %d frames, want 3000 plus the 4001 resampled to half", resampled.NumSamples)
+	}
+
+	quad := filepath.Join(dir, "quad.wav")
+	if err := runCLI(t, "decode", input, quad); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	toned := filepath.Join(dir, "toned.wav")
+	if err := runCLI(t, "encode", "--ref-tone", "1000,-18,0.5", quad, toned); err != nil {
+		t.Fatalf("encode --ref-tone error = %v", err)
+	}
+	checkWAVLength(t, toned, 5000+4000)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/cwbudde/go-sq-tool/internal/matrix"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the accepted values of --window and --matrix",
+}
+
+var listWindowsCmd = &cobra.Command{
+	Use:   "windows",
+	Short: "List the phase shifter windows accepted by --window",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listWindows(cmd.OutOrStdout())
+	},
+}
+
+var listMatricesCmd = &cobra.Command{
+	Use:   "matrices",
+	Short: "List the matrix presets accepted by --matrix and matrix-info",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listMatrices(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	listCmd.AddCommand(listWindowsCmd, listMatricesCmd)
+}
+
+// listWindows writes the window types and their descriptions to w.
+func listWindows(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, window := range sqmath.Windows() {
+		fmt.Fprintf(tw, "%s\t%s\n", window.Type, window.Description)
+	}
+	return tw.Flush()
+}
+
+// listMatrices writes the matrix presets and their descriptions to w.
+func listMatrices(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, preset := range matrix.Presets() {
+		fmt.Fprintf(tw, "%s\t%s\n", preset.Name, preset.Description)
+	}
+	return tw.Flush()
+}
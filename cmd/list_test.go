@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	var windows bytes.Buffer
+	if err := listWindows(&windows); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"hann", "blackman"} {
+		if !strings.Contains(windows.String(), name+"  ") {
+			t.Fatalf("list windows = %q, want %s", windows.String(), name)
+		}
+	}
+
+	var matrices bytes.Buffer
+	if err := listMatrices(&matrices); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(matrices.String(), "sq  ") {
+		t.Fatalf("list matrices = %q, want sq", matrices.String())
+	}
+
+	if err := runCLI(t, "list", "windows"); err != nil {
+		t.Fatalf("list windows error = %v", err)
+	}
+	if err := runCLI(t, "list", "matrices", "extra"); err == nil {
+		t.Fatal("list matrices accepted an argument")
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/cwbudde/go-sq-tool/internal/matrix"
+	"github.com/spf13/cobra"
+)
+
+var localizeCmd = &cobra.Command{
+	Use:   "localize",
+	Short: "Report where a matrix places a source with the given quad gains",
+	Long: `Encodes a source with the LF, RF, LB, RB gains of --gain through a matrix
+preset (default "sq") and reports the LT/RT magnitudes and phases, assuming
+a perfect 90° shifter, together with the nominal playback angle they imply.
+
+The angle is that of the constant-power panned source whose LT/RT amplitude
+ratio and phase difference come closest: 0° is center front, -45° LF, 45° RF,
+135° RB and ±180° center back. The deviation is the remaining distance on
+the Scheiber sphere; sources that are not a simple pan (e.g. LF and RB at
+once) show a large deviation and are where a decoder cannot tell the
+position.`,
+	Args: cobra.NoArgs,
+	RunE: runLocalize,
+}
+
+var (
+	localizeGains  []float64
+	localizeMatrix string
+)
+
+func init() {
+	localizeCmd.Flags().Float64SliceVar(&localizeGains, "gain", nil, "LF,RF,LB,RB gains of the source, e.g. 1,0,0,0")
+	localizeCmd.Flags().StringVar(&localizeMatrix, "matrix", "sq", "matrix preset")
+	localizeCmd.MarkFlagRequired("gain")
+}
+
+func runLocalize(cmd *cobra.Command, args []string) error {
+	preset, err := matrix.Lookup(localizeMatrix)
+	if err != nil {
+		return err
+	}
+	loc, err := matrix.Localize(preset.Encode, localizeGains)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Matrix: %s\n", preset.Name)
+	fmt.Printf("Source gains: LF %.3f  RF %.3f  LB %.3f  RB %.3f\n",
+		localizeGains[0], localizeGains[1], localizeGains[2], localizeGains[3])
+	fmt.Printf("\n%-4s %10s %10s %10s\n", "", "Magnitude", "dB", "Phase")
+	for _, ch := range []struct {
+		name string
+		v    complex128
+	}{{"LT", loc.LT}, {"RT", loc.RT}} {
+		fmt.Printf("%-4s %10.3f %10s %9.1f°\n", ch.name, cmplx.Abs(ch.v), formatLevel(cmplx.Abs(ch.v)), phaseDegrees(ch.v))
+	}
+	if loc.LT != 0 && loc.RT != 0 {
+		fmt.Printf("\nLT-RT phase difference: %.1f°\n", phaseDegrees(loc.LT/loc.RT))
+	}
+	fmt.Printf("Nominal angle: %.1f° (deviation %.1f°)\n", loc.Angle, loc.Deviation)
+	return nil
+}
+
+// formatLevel renders a linear magnitude in dB.
+func formatLevel(v float64) string {
+	if v == 0 {
+		return "-inf"
+	}
+	return fmt.Sprintf("%.1f", 20*math.Log10(v))
+}
+
+func phaseDegrees(v complex128) float64 {
+	return cmplx.Phase(v) * 180 / math.Pi
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logLevel  string
+	logFormat string
+
+	// logger receives all diagnostics. Result lines meant for the user are
+	// printed to stdout separately.
+	logger = logging.Discard()
+)
+
+// setupLogging builds logger from --log-level and --log-format. --verbose
+// raises the default level to info unless --log-level is given.
+func setupLogging(cmd *cobra.Command) error {
+	name := logLevel
+	if verbose && !cmd.Flags().Changed("log-level") {
+		name = "info"
+	}
+	level, err := logging.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	l, err := logging.New(os.Stderr, level, logFormat)
+	if err != nil {
+		return err
+	}
+	logger = l
+	slog.SetDefault(l)
+	return nil
+}
+
+// samplesDuration converts a sample count at sampleRate to a duration.
+func samplesDuration(samples int, sampleRate uint32) time.Duration {
+	if sampleRate == 0 {
+		return 0
+	}
+	return time.Duration(float64(samples) / float64(sampleRate) * float64(time.Second))
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+)
+
+// logicTwoPass is the --logic-two-pass flag of decode.
+var logicTwoPass bool
+
+// validateLogicTwoPass checks that --logic-two-pass can read the input
+// twice and has one decoder to plan for.
+func validateLogicTwoPass(inputFile string) error {
+	if !logicTwoPass {
+		return nil
+	}
+	if inputFile == stdinInput {
+		return fmt.Errorf("--logic-two-pass needs an input file, not stdin")
+	}
+	if adaptiveBlocks || preview {
+		return fmt.Errorf("--logic-two-pass cannot be combined with --adaptive or --preview")
+	}
+	return nil
+}
+
+// planLogicSteering runs the first pass of --logic-two-pass: it decodes
+// inputFile with d, discarding the output, and installs the logic steering
+// planned from it. d is reset for the second pass. The steering only
+// depends on the four speaker feeds, so the pass decodes them as discrete
+// channels whatever --back-mode asks for.
+func planLogicSteering(d *sq.Decoder, inputFile string, inChannels int, sampleRate uint32) error {
+	input, err := openStream(inputFile, inChannels)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	defer input.Close()
+
+	backMode := d.BackChannelMode()
+	d.SetBackChannelMode(sq.BackChannelDiscrete)
+	defer d.SetBackChannelMode(backMode)
+
+	plan := d.StartLogicAnalysis()
+	buf := make([][]float64, inChannels)
+	for ch := range buf {
+		buf[ch] = make([]float64, 1<<16)
+	}
+	for {
+		n, err := input.source.ReadFrames(buf)
+		if n > 0 {
+			// A mono input decodes as LT = RT.
+			if _, err := d.ProcessStream([][]float64{buf[0][:n], buf[inChannels-1][:n]}); err != nil {
+				return fmt.Errorf("logic analysis: %w", err)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+	}
+	if _, err := d.Flush(); err != nil {
+		return fmt.Errorf("logic analysis: %w", err)
+	}
+	d.SetLogicPlan(plan)
+	d.Reset()
+
+	frames, steered := plan.Frames()
+	logger.Info("logic steering plan",
+		"frames", frames,
+		"steered_frames", steered,
+		"duration", samplesDuration(input.numFrames, sampleRate))
+	return nil
+}
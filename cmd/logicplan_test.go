@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestDecode_LogicTwoPass(t *testing.T) {
+	// A left-front source, which logic steering boosts.
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 16000)
+	data, err := wav.ReadWAV(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clear(data.Samples[1])
+	if err := wav.WriteStereoWAV(input, data); err != nil {
+		t.Fatal(err)
+	}
+	single := filepath.Join(dir, "single.wav")
+	twoPass := filepath.Join(dir, "two-pass.wav")
+	if err := runCLI(t, "decode", "--logic", "--bit-depth", "32f", input, single); err != nil {
+		t.Fatalf("decode --logic error = %v", err)
+	}
+	if err := runCLI(t, "decode", "--logic-two-pass", "--bit-depth", "32f", input, twoPass); err != nil {
+		t.Fatalf("decode --logic-two-pass error = %v", err)
+	}
+	a, err := wav.ReadWAVChannels(single, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := wav.ReadWAVChannels(twoPass, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.NumSamples != b.NumSamples {
+		t.Fatalf("two-pass output has %d frames, want %d", b.NumSamples, a.NumSamples)
+	}
+	if slices.Equal(a.Samples[2], b.Samples[2]) {
+		t.Fatalf("two-pass output equals single-pass output")
+	}
+
+	// The plan is made on the discrete back channels and applies to the
+	// sum and difference ones as well.
+	both := filepath.Join(dir, "both.wav")
+	if err := runCLI(t, "decode", "--logic-two-pass", "--back-mode", "both", "--bit-depth", "32f", input, both); err != nil {
+		t.Fatalf("decode --logic-two-pass --back-mode both error = %v", err)
+	}
+	c, err := wav.ReadWAVChannels(both, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(c.Samples[2], b.Samples[2]) {
+		t.Fatalf("--back-mode both changed the two-pass steering of LB")
+	}
+
+	if err := runCLI(t, "decode", "--logic-two-pass", "-", filepath.Join(dir, "stdin.wav")); err == nil {
+		t.Fatalf("--logic-two-pass accepted stdin")
+	}
+	if err := runCLI(t, "decode", "--logic-two-pass", "--adaptive", input, filepath.Join(dir, "adaptive.wav")); err == nil {
+		t.Fatalf("--logic-two-pass accepted --adaptive")
+	}
+}
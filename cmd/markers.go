@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/spf13/pflag"
+)
+
+// debugMarkerEvery is the --debug-markers flag of decode and encode: the
+// number of hops between block boundary markers, 0 for none.
+var debugMarkerEvery int
+
+func addDebugMarkersFlag(flags *pflag.FlagSet) {
+	flags.IntVar(&debugMarkerEvery, "debug-markers", 0, "write WAV cue markers at the latency point and every N-th block boundary (0 = off)")
+}
+
+// checkDebugMarkers validates --debug-markers.
+func checkDebugMarkers() error {
+	if debugMarkerEvery < 0 {
+		return fmt.Errorf("--debug-markers must be 0 or positive, got %d", debugMarkerEvery)
+	}
+	return nil
+}
+
+// debugMarkers returns the cue points of --debug-markers for numFrames of
+// processed output: a latency marker, then a marker at every every-th block
+// boundary, every hop frames. The latency marker is at the first frame when
+// the output is compensated for the latency, as the files of decode and
+// encode are, and at latency otherwise.
+func debugMarkers(numFrames, hop, every, latency int, compensated bool) []wav.Cue {
+	if every <= 0 || hop <= 0 {
+		return nil
+	}
+	cues := []wav.Cue{{Label: fmt.Sprintf("latency %d", latency)}}
+	if compensated {
+		cues[0].Label += " (compensated)"
+	} else {
+		cues[0].Position = uint32(latency)
+	}
+	for block := every; block*hop < numFrames; block += every {
+		cues = append(cues, wav.Cue{Position: uint32(block * hop), Label: fmt.Sprintf("block %d", block)})
+	}
+	return cues
+}
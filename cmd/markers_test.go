@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestDebugMarkers(t *testing.T) {
+	cues := debugMarkers(2000, 256, 2, 768, false)
+	want := []wav.Cue{{Position: 768, Label: "latency 768"}, {Position: 512, Label: "block 2"}, {Position: 1024, Label: "block 4"}, {Position: 1536, Label: "block 6"}}
+	if fmt.Sprint(cues) != fmt.Sprint(want) {
+		t.Fatalf("debugMarkers() = %+v, want %+v", cues, want)
+	}
+	if cues := debugMarkers(2000, 256, 2, 768, true); cues[0] != (wav.Cue{Label: "latency 768 (compensated)"}) {
+		t.Fatalf("compensated latency marker = %+v, want at 0", cues[0])
+	}
+	if cues := debugMarkers(2000, 256, 0, 768, true); cues != nil {
+		t.Fatalf("debugMarkers() with every 0 = %+v, want none", cues)
+	}
+}
+
+// scanOutputCues parses the cue chunk of the WAV file at path.
+func scanOutputCues(t *testing.T, path string) []wav.Cue {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	cues, err := wav.ScanCues(file)
+	if err != nil {
+		t.Fatalf("ScanCues error = %v", err)
+	}
+	return cues
+}
+
+func TestDecode_DebugMarkers(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 4000)
+	data, err := wav.ReadWAV(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Cues = []wav.Cue{{Position: 100, Label: "Track 1"}}
+	if err := wav.WriteStereoWAV(input, data); err != nil {
+		t.Fatal(err)
+	}
+
+	const hop, every = 256, 3
+	output := filepath.Join(dir, "quad.wav")
+	if err := runCLI(t, "decode", "--block-size", "512", "--overlap", fmt.Sprint(hop), "--debug-markers", fmt.Sprint(every), input, output); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	cues := scanOutputCues(t, output)
+	// The input's cue point comes first, then the latency marker at the
+	// start of the compensated output, then the block boundaries.
+	if len(cues) < 3 || cues[0] != data.Cues[0] || cues[1].Position != 0 || cues[1].Label != "latency 384 (compensated)" {
+		t.Fatalf("output cues = %+v", cues)
+	}
+	blocks := cues[2:]
+	if len(blocks) != (4000-1)/(every*hop) {
+		t.Fatalf("%d block markers, want %d", len(blocks), (4000-1)/(every*hop))
+	}
+	for i, cue := range blocks {
+		if block := (i + 1) * every; cue.Position != uint32(block*hop) || cue.Label != fmt.Sprintf("block %d", block) {
+			t.Fatalf("block marker %d = %+v, want block %d at %d", i, cue, block, block*hop)
+		}
+	}
+
+	if err := runCLI(t, "decode", "--debug-markers", "-1", input, output); err == nil {
+		t.Fatalf("decode accepted --debug-markers -1")
+	}
+}
+
+func TestEncode_DebugMarkers(t *testing.T) {
+	dir := t.TempDir()
+	input := writeQuad(t, dir, 4000)
+	output := filepath.Join(dir, "stereo.wav")
+	if err := runCLI(t, "encode", "--debug-markers", "2", input, output); err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+	cues := scanOutputCues(t, output)
+	// 4000 frames hold the boundaries of blocks 2, 4 and 6 at the default
+	// overlap of 512.
+	if len(cues) != 4 || cues[0].Position != 0 || cues[3] != (wav.Cue{Position: 3072, Label: "block 6"}) {
+		t.Fatalf("output cues = %+v", cues)
+	}
+}
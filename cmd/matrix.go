@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/internal/matrix"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// matrixName is the --matrix flag of decode and analyze.
+var matrixName string
+
+func addMatrixFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&matrixName, "matrix", "sq", "matrix system of the SQ stereo input: sq or qs (Sansui QS regular matrix)")
+}
+
+var matrixInfoCmd = &cobra.Command{
+	Use:   "matrix-info [name]",
+	Short: "Print a matrix preset and its theoretical channel separation",
+	Long: `Prints the encode and decode coefficients of a matrix preset (default "sq"),
+the singular values and condition number of the decode matrix, and the
+ideal source -> output separation assuming a perfect 90° shifter.
+
+The condition number is the ratio of the largest to the smallest singular
+value of the decode matrix: how much more the decoder amplifies input noise
+in its most sensitive direction than in its least. 1 (0 dB) means noise is
+amplified evenly whatever its direction in the stereo pair.
+
+Coefficients with a "j" suffix are applied to the Hilbert-shifted signal.
+Compare the theoretical figures with "analyze" to see how close the
+implementation gets to the ceiling.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMatrixInfo,
+}
+
+func runMatrixInfo(cmd *cobra.Command, args []string) error {
+	name := "sq"
+	if len(args) == 1 {
+		name = args[0]
+	}
+	preset, err := matrix.Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	quadNames := []string{"LF", "RF", "LB", "RB"}
+	stereoNames := []string{"LT", "RT"}
+
+	fmt.Printf("Matrix: %s - %s\n", preset.Name, preset.Description)
+
+	fmt.Printf("\nEncode (rows LT/RT, columns LF RF LB RB)\n")
+	for i, row := range preset.Encode {
+		fmt.Printf("%-4s", stereoNames[i])
+		for _, c := range row {
+			fmt.Printf(" %14s", matrix.FormatCoefficient(c))
+		}
+		fmt.Printf("\n")
+	}
+
+	fmt.Printf("\nDecode (rows LF/RF/LB/RB, columns LT RT)\n")
+	for i, row := range preset.Decode {
+		fmt.Printf("%-4s", quadNames[i])
+		for _, c := range row {
+			fmt.Printf(" %14s", matrix.FormatCoefficient(c))
+		}
+		fmt.Printf("\n")
+	}
+
+	sv, err := matrix.SingularValues(preset.Decode)
+	if err != nil {
+		return fmt.Errorf("decode singular values: %w", err)
+	}
+	cond, err := matrix.ConditionNumber(preset.Decode)
+	if err != nil {
+		return fmt.Errorf("decode condition number: %w", err)
+	}
+	fmt.Printf("\nDecode singular values: %.4f, %.4f\n", sv[0], sv[1])
+	fmt.Printf("Decode condition number: %.4f (%.2f dB)\n", cond, 20*math.Log10(cond))
+
+	sep, err := metrics.TheoreticalSeparation(preset.Encode, preset.Decode)
+	if err != nil {
+		return fmt.Errorf("theoretical separation: %w", err)
+	}
+
+	fmt.Printf("\nTheoretical separation (dB, source -> output)\n")
+	fmt.Printf("%-6s", "Source")
+	for _, name := range quadNames {
+		fmt.Printf(" %7s", name)
+	}
+	fmt.Printf("\n")
+	for src, row := range sep {
+		fmt.Printf("%-6s", quadNames[src])
+		for out, v := range row {
+			if out == src {
+				fmt.Printf(" %7s", "-")
+				continue
+			}
+			fmt.Printf(" %7s", formatSeparation(v))
+		}
+		fmt.Printf("\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/spf13/pflag"
+)
+
+// codecScratchBytes is the state of one decoder or encoder per sample of
+// its block size: the block and overlap buffers, and two phase shifters
+// with their FFT plan, transfer function and per-block complex buffers.
+const codecScratchBytes = 320
+
+// maxMemoryMiB is the --max-memory budget; 0 means no limit.
+var maxMemoryMiB int64
+
+func addMemoryFlag(flags *pflag.FlagSet) {
+	flags.Int64Var(&maxMemoryMiB, "max-memory", 0, "memory budget in MiB; buffering is reduced to fit, or the command fails (0 = no limit)")
+}
+
+// memoryEstimate is the upfront estimate of the sample memory a command
+// holds at once.
+type memoryEstimate struct {
+	// Resident is the signal held in memory as a whole: lossy input,
+	// which is decoded up front, and --fix-compat output.
+	Resident int64
+	// Pipeline is the chunk buffering of pipeline.Run.
+	Pipeline int64
+	// Scratch is the state of the decoders or encoders.
+	Scratch int64
+}
+
+// Total returns the sum of all parts.
+func (m memoryEstimate) Total() int64 {
+	return m.Resident + m.Pipeline + m.Scratch
+}
+
+// memoryJob describes what a command will hold in memory.
+type memoryJob struct {
+	Frames      int
+	InChannels  int
+	OutChannels int
+	// ResidentSamples counts the samples held as a whole besides the
+	// streamed chunks.
+	ResidentSamples int64
+	// Codecs is the number of decoders or encoders, each with a block of
+	// BlockSize.
+	Codecs    int
+	BlockSize int
+}
+
+// newMemoryJob returns the job of streaming in through codecs codecs.
+// Input other than WAV is decoded into memory before streaming starts.
+func newMemoryJob(in *streamInput, inChannels, outChannels, codecs, blockSize int) memoryJob {
+	job := memoryJob{
+		Frames:      in.numFrames,
+		InChannels:  inChannels,
+		OutChannels: outChannels,
+		Codecs:      codecs,
+		BlockSize:   blockSize,
+	}
+	if !in.format.Streamed() {
+		job.ResidentSamples = int64(in.numFrames) * int64(inChannels)
+	}
+	return job
+}
+
+func (j memoryJob) estimate(cfg pipeline.Config) memoryEstimate {
+	return memoryEstimate{
+		Resident: j.ResidentSamples * 8,
+		Pipeline: cfg.MemoryBytes(j.InChannels, j.OutChannels),
+		Scratch:  int64(j.Codecs) * int64(j.BlockSize) * codecScratchBytes,
+	}
+}
+
+// fitMemory returns cfg reduced until the estimate of job fits budget
+// bytes: first fewer chunks in flight between the stages, then smaller
+// chunks down to one granule, which must divide cfg.ChunkFrames. A budget
+// of 0 or less leaves cfg unchanged. It fails when even the smallest
+// buffering does not fit.
+func fitMemory(job memoryJob, cfg pipeline.Config, granule int, budget int64) (pipeline.Config, memoryEstimate, error) {
+	if cfg.QueueDepth <= 0 {
+		cfg.QueueDepth = pipeline.DefaultQueueDepth
+	}
+	est := job.estimate(cfg)
+	if budget <= 0 {
+		return cfg, est, nil
+	}
+	for est.Total() > budget && cfg.QueueDepth > 1 {
+		cfg.QueueDepth--
+		est = job.estimate(cfg)
+	}
+	for est.Total() > budget && cfg.ChunkFrames > granule {
+		cfg.ChunkFrames = max(granule, cfg.ChunkFrames/2/granule*granule)
+		est = job.estimate(cfg)
+	}
+	if est.Total() > budget {
+		return cfg, est, fmt.Errorf("needs at least %s of memory (%s for the signal held in memory), over --max-memory %s",
+			formatMiB(est.Total()), formatMiB(est.Resident), formatMiB(budget))
+	}
+	return cfg, est, nil
+}
+
+// planMemory applies --max-memory to cfg for job and logs the estimate.
+func planMemory(job memoryJob, cfg pipeline.Config, granule int) (pipeline.Config, error) {
+	if cfg.QueueDepth <= 0 {
+		cfg.QueueDepth = pipeline.DefaultQueueDepth
+	}
+	planned, est, err := fitMemory(job, cfg, granule, maxMemoryMiB<<20)
+	if err != nil {
+		return cfg, err
+	}
+	logger.Info("memory estimate",
+		"total", formatMiB(est.Total()),
+		"resident", formatMiB(est.Resident),
+		"pipeline", formatMiB(est.Pipeline),
+		"scratch", formatMiB(est.Scratch),
+		"queue_depth", planned.QueueDepth,
+		"chunk_frames", planned.ChunkFrames)
+	if planned.QueueDepth != cfg.QueueDepth || planned.ChunkFrames != cfg.ChunkFrames {
+		logger.Info("reduced buffering to fit --max-memory", "max_memory_mib", maxMemoryMiB)
+	}
+	return planned, nil
+}
+
+// formatMiB formats a byte count in MiB.
+func formatMiB(bytes int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+}
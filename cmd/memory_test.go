@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+)
+
+func TestMemoryJob_Estimate(t *testing.T) {
+	job := memoryJob{Frames: 96000 * 3600, InChannels: 2, OutChannels: 4, ResidentSamples: 96000 * 3600 * 2, Codecs: 1, BlockSize: 1024}
+	cfg := pipeline.DefaultConfig(1024, 512)
+	est := job.estimate(cfg)
+	if want := int64(96000 * 3600 * 2 * 8); est.Resident != want {
+		t.Fatalf("Resident = %d, want %d", est.Resident, want)
+	}
+	if want := cfg.MemoryBytes(2, 4); est.Pipeline != want {
+		t.Fatalf("Pipeline = %d, want %d", est.Pipeline, want)
+	}
+	if want := int64(1024 * codecScratchBytes); est.Scratch != want {
+		t.Fatalf("Scratch = %d, want %d", est.Scratch, want)
+	}
+	if est.Total() != est.Resident+est.Pipeline+est.Scratch {
+		t.Fatalf("Total() = %d, want the sum of the parts", est.Total())
+	}
+}
+
+func TestFitMemory(t *testing.T) {
+	job := memoryJob{Frames: 44100 * 60, InChannels: 2, OutChannels: 4, Codecs: 1, BlockSize: 1024}
+	cfg := pipeline.DefaultConfig(1024, 512)
+	full := job.estimate(cfg).Total()
+
+	// No budget, or enough of it, leaves the configuration alone.
+	for _, budget := range []int64{0, full} {
+		got, _, err := fitMemory(job, cfg, 512, budget)
+		if err != nil || got.QueueDepth != cfg.QueueDepth || got.ChunkFrames != cfg.ChunkFrames {
+			t.Fatalf("budget %d: got depth %d chunk %d (%v), want unchanged", budget, got.QueueDepth, got.ChunkFrames, err)
+		}
+	}
+
+	// Fewer chunks in flight come first.
+	shallow := cfg
+	shallow.QueueDepth = 2
+	budget := job.estimate(shallow).Total()
+	got, est, err := fitMemory(job, cfg, 512, budget)
+	if err != nil || got.QueueDepth != 2 || got.ChunkFrames != cfg.ChunkFrames {
+		t.Fatalf("got depth %d chunk %d (%v), want depth 2 and the full chunk", got.QueueDepth, got.ChunkFrames, err)
+	}
+	if est.Total() > budget {
+		t.Fatalf("estimate %d over budget %d", est.Total(), budget)
+	}
+
+	// Then smaller chunks, in whole granules.
+	small := cfg
+	small.QueueDepth, small.ChunkFrames = 1, 1024
+	budget = job.estimate(small).Total()
+	got, _, err = fitMemory(job, cfg, 512, budget)
+	if err != nil || got.QueueDepth != 1 || got.ChunkFrames != 1024 {
+		t.Fatalf("got depth %d chunk %d (%v), want depth 1 and chunk 1024", got.QueueDepth, got.ChunkFrames, err)
+	}
+
+	// The signal held in memory cannot be reduced.
+	job.ResidentSamples = int64(job.Frames) * 2
+	if _, _, err := fitMemory(job, cfg, 512, job.ResidentSamples*8); err == nil {
+		t.Fatalf("budget below the resident input accepted")
+	}
+}
+
+func TestDecode_MaxMemory(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 40000)
+	want := filepath.Join(dir, "want.wav")
+	got := filepath.Join(dir, "got.wav")
+
+	if err := runCLI(t, "decode", input, want); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	// 1 MiB is below the default buffering but above the minimum.
+	if err := runCLI(t, "decode", input, got, "--max-memory", "1"); err != nil {
+		t.Fatalf("decode --max-memory 1 error = %v", err)
+	}
+	a, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Fatalf("reduced buffering changed the output")
+	}
+}
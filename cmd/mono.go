@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// Policies for mono decode inputs, see --mono-policy.
+const (
+	monoError     = "error"
+	monoDuplicate = "duplicate"
+	monoHint      = "reject-with-hint"
+)
+
+var monoPolicy string
+
+func validateMonoPolicy() error {
+	switch monoPolicy {
+	case monoError, monoDuplicate, monoHint:
+		return nil
+	}
+	return fmt.Errorf("invalid --mono-policy %q (use %s, %s or %s)", monoPolicy, monoError, monoDuplicate, monoHint)
+}
+
+// openDecodeInput opens a decode input as stereo, or applies --mono-policy
+// when it is mono. It returns the input and its channel count, 1 when a
+// mono input is to be duplicated.
+func openDecodeInput(path string) (*streamInput, int, error) {
+	input, err := openStream(path, 2)
+	var channelErr *wav.ChannelCountError
+	if err == nil || !errors.As(err, &channelErr) || channelErr.Got != 1 {
+		return input, 2, err
+	}
+	switch monoPolicy {
+	case monoDuplicate:
+		logger.Info("mono input, decoding it as LT = RT (front center)", "path", path)
+		input, err = openStream(path, 1)
+		return input, 1, err
+	case monoHint:
+		return nil, 0, fmt.Errorf("%s is mono, but SQ decoding needs the LT/RT stereo pair; "+
+			"use --mono-policy %s to decode it as a front-center source, or encode a quad mix to SQ first", path, monoDuplicate)
+	}
+	return nil, 0, err
+}
+
+// duplicateMono adapts a stereo processor to mono input by feeding the
+// channel as both LT and RT.
+func duplicateMono(process pipeline.Processor) pipeline.Processor {
+	return func(input [][]float64, numOutput int) ([][]float64, error) {
+		return process([][]float64{input[0], input[0]}, numOutput)
+	}
+}
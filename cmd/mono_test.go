@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// writeMono writes a mono test file and the same signal as LT = RT stereo
+// to dir and returns both paths.
+func writeMono(t *testing.T, dir string, frames int) (mono, stereo string) {
+	t.Helper()
+	signal := make([]float64, frames)
+	for i := range signal {
+		signal[i] = 0.5*math.Sin(2*math.Pi*float64(440*i)/8000) + 0.2*math.Sin(2*math.Pi*float64(1250*i)/8000)
+	}
+	mono = filepath.Join(dir, "mono.wav")
+	data := &wav.AudioData{SampleRate: 8000, NumSamples: frames, Samples: [][]float64{signal}}
+	if err := wav.WriteWAVWithOptions(mono, data, 1, wav.WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	stereo = filepath.Join(dir, "dual.wav")
+	data.Samples = [][]float64{signal, signal}
+	if err := wav.WriteStereoWAV(stereo, data); err != nil {
+		t.Fatal(err)
+	}
+	return mono, stereo
+}
+
+func TestDecode_MonoPolicy(t *testing.T) {
+	dir := t.TempDir()
+	mono, stereo := writeMono(t, dir, 9000)
+	out := filepath.Join(dir, "out.wav")
+
+	err := runCLI(t, "decode", mono, out)
+	if err == nil || !strings.Contains(err.Error(), "got 1") {
+		t.Fatalf("decode of mono input error = %v, want a channel count error", err)
+	}
+	err = runCLI(t, "decode", mono, out, "--mono-policy", "reject-with-hint")
+	if err == nil || !strings.Contains(err.Error(), "is mono") || !strings.Contains(err.Error(), "--mono-policy duplicate") {
+		t.Fatalf("reject-with-hint error = %v, want a hint", err)
+	}
+	if err := runCLI(t, "decode", mono, out, "--mono-policy", "sum"); err == nil || !strings.Contains(err.Error(), "invalid --mono-policy") {
+		t.Fatalf("unknown policy error = %v", err)
+	}
+
+	// duplicate takes the one-Hilbert path and must match the full decode
+	// of LT = RT, with and without logic steering and in --adaptive mode.
+	for _, extra := range [][]string{nil, {"--logic"}, {"--adaptive"}} {
+		full := filepath.Join(dir, "full.wav")
+		if err := runCLI(t, append([]string{"decode", stereo, full}, extra...)...); err != nil {
+			t.Fatalf("decode %v of LT = RT error = %v", extra, err)
+		}
+		if err := runCLI(t, append([]string{"decode", mono, out, "--mono-policy", "duplicate"}, extra...)...); err != nil {
+			t.Fatalf("decode %v --mono-policy duplicate error = %v", extra, err)
+		}
+		got, want := readChannels(t, out, 4), readChannels(t, full, 4)
+		for ch := range want {
+			if len(got[ch]) != len(want[ch]) {
+				t.Fatalf("%v channel %d: %d frames, want %d", extra, ch, len(got[ch]), len(want[ch]))
+			}
+			for i := range want[ch] {
+				if got[ch][i] != want[ch][i] {
+					t.Fatalf("%v channel %d frame %d = %v, want %v", extra, ch, i, got[ch][i], want[ch][i])
+				}
+			}
+		}
+		if extra == nil && peakOf(got[2:]) < 0.01 {
+			t.Fatalf("duplicated mono decoded to silent backs")
+		}
+	}
+
+	// Mono lossy sources follow the same policy.
+	for _, name := range []string{"mono.ogg", "mono.mp3"} {
+		lossy := filepath.Join("..", "internal", "audiofile", "testdata", name)
+		err := runCLI(t, "decode", lossy, out)
+		if err == nil || !strings.Contains(err.Error(), "got 1") {
+			t.Fatalf("decode of %s error = %v, want a channel count error", name, err)
+		}
+		if err := runCLI(t, "decode", lossy, out, "--mono-policy", "duplicate"); err != nil {
+			t.Fatalf("decode %s --mono-policy duplicate error = %v", name, err)
+		}
+	}
+}
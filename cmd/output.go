@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/spf13/pflag"
+)
+
+// inputGain and outputGain are the --input-gain and --output-gain levels in
+// dB, applied before and after processing.
+var (
+	inputGain  float64
+	outputGain float64
+)
+
+func addGainFlags(flags *pflag.FlagSet) {
+	flags.Float64Var(&inputGain, "input-gain", 0, "gain in dB applied to the input before processing")
+	flags.Float64Var(&outputGain, "output-gain", 0, "gain in dB applied to the output after processing")
+}
+
+// writeOptions returns the WAV writer options selected by the global
+// output flags.
+func writeOptions() (wav.WriteOptions, error) {
+	layout, err := wav.ParseChunkLayout(chunkLayout)
+	if err != nil {
+		return wav.WriteOptions{}, err
+	}
+	format, err := outputFormat()
+	if err != nil {
+		return wav.WriteOptions{}, err
+	}
+	return wav.WriteOptions{
+		Format:      format,
+		Layout:      layout,
+		ErrorOnClip: errorOnClip,
+	}, nil
+}
+
+// audioWriter streams frames into an output file: a *wav.Writer, or a
+// *wav.CAFWriter for a .caf path.
+type audioWriter interface {
+	WriteFrames(frames [][]float64) error
+	Close() error
+	Clipped() int
+}
+
+// newAudioWriter returns the writer for the output at path, choosing the
+// container by its extension.
+func newAudioWriter(path string, w io.Writer, sampleRate uint32, channels, numFrames int, options wav.WriteOptions) (audioWriter, error) {
+	if isCAF(path) {
+		return wav.NewCAFWriter(w, sampleRate, channels, numFrames, options)
+	}
+	return wav.NewWriter(w, sampleRate, channels, numFrames, options)
+}
+
+// isCAF reports whether path names a CAF file.
+func isCAF(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".caf")
+}
+
+// warnClipped logs the samples writer had to clamp; with --error-on-clip
+// the write fails instead.
+func warnClipped(path string, writer audioWriter) {
+	if n := writer.Clipped(); n > 0 {
+		logger.Warn("output clipped", "path", path, "samples", n)
+	}
+}
+
+// outputFormat returns the sample format selected by --bit-depth, or by the
+// deprecated --float32 and --bits it replaces.
+func outputFormat() (wav.SampleFormat, error) {
+	if !float32 && bits == 16 {
+		format, err := wav.ParseBitDepth(bitDepth)
+		if err != nil {
+			return 0, fmt.Errorf("--bit-depth: %w", err)
+		}
+		return format, nil
+	}
+	if bitDepth != "16" {
+		return 0, fmt.Errorf("--bit-depth cannot be combined with --float32 or --bits")
+	}
+	if float32 {
+		if bits != 16 {
+			return 0, fmt.Errorf("--bits %d cannot be combined with --float32", bits)
+		}
+		return wav.FormatFloat32, nil
+	}
+	switch bits {
+	case 8:
+		return wav.FormatPCM8, nil
+	default:
+		return 0, fmt.Errorf("unsupported --bits %d (want 8 or 16)", bits)
+	}
+}
+
+// writeAudio writes data with the given channel count using the global
+// output flags, as CAF for a .caf filename and as WAV otherwise.
+func writeAudio(filename string, data *wav.AudioData, channels int) error {
+	options, err := writeOptions()
+	if err != nil {
+		return err
+	}
+	if isCAF(filename) {
+		return wav.WriteCAF(filename, data, channels, options)
+	}
+	return wav.WriteWAVWithOptions(filename, data, channels, options)
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestDecode_ErrorOnClip(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	output := filepath.Join(dir, "out.wav")
+
+	// The test signal peaks at -6 dBFS; +20 dB drives it far into clipping.
+	if err := runCLI(t, "decode", input, output, "--output-gain", "20"); err != nil {
+		t.Fatalf("decode without --error-on-clip error = %v", err)
+	}
+	if err := runCLI(t, "decode", input, output, "--output-gain", "20", "--error-on-clip"); err == nil {
+		t.Fatalf("decode of a clipping signal with --error-on-clip succeeded")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Fatalf("clipped output left behind: %v", err)
+	}
+	if err := runCLI(t, "decode", input, output, "--error-on-clip"); err != nil {
+		t.Fatalf("decode within full scale with --error-on-clip error = %v", err)
+	}
+}
+
+func TestDecode_Quality(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	output := filepath.Join(dir, "out.wav")
+
+	best, err := decoder.LookupQuality("best")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBlock, wantOverlap := best.Resolve(8000)
+
+	if err := runCLI(t, "decode", input, output, "--quality", "best"); err != nil {
+		t.Fatalf("decode --quality best error = %v", err)
+	}
+	if blockSize != wantBlock || overlap != wantOverlap {
+		t.Fatalf("--quality best at 8 kHz: block %d overlap %d, want %d and %d", blockSize, overlap, wantBlock, wantOverlap)
+	}
+
+	// An explicit flag wins over the preset; the others still follow it.
+	if err := runCLI(t, "decode", input, output, "--quality", "best", "-b", "4096"); err != nil {
+		t.Fatalf("decode --quality best -b 4096 error = %v", err)
+	}
+	if blockSize != 4096 || overlap != wantOverlap {
+		t.Fatalf("--quality best -b 4096: block %d overlap %d, want 4096 and %d", blockSize, overlap, wantOverlap)
+	}
+
+	if err := runCLI(t, "decode", input, output, "--quality", "ultra"); err == nil {
+		t.Fatalf("decode --quality ultra succeeded")
+	}
+}
+
+func TestDecode_HilbertFIR(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	builtin := filepath.Join(dir, "builtin.wav")
+	custom := filepath.Join(dir, "custom.wav")
+	fir := filepath.Join(dir, "fir.txt")
+	if err := os.WriteFile(fir, []byte("0 -0.2122 0 -0.6366 0 0.6366 0 0.2122 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runCLI(t, "decode", input, builtin); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if err := runCLI(t, "decode", input, custom, "--hilbert-fir", fir); err != nil {
+		t.Fatalf("decode --hilbert-fir error = %v", err)
+	}
+	a, err := os.ReadFile(builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) {
+		t.Fatalf("--hilbert-fir output equals the built-in filter's")
+	}
+
+	long := filepath.Join(dir, "long.txt")
+	if err := os.WriteFile(long, bytes.Repeat([]byte("0.1\n"), 513), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCLI(t, "decode", input, custom, "--hilbert-fir", long); err == nil {
+		t.Fatalf("decode with a 513-tap FIR at overlap 512 succeeded")
+	}
+}
+
+func TestHilbertScale(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	builtin := filepath.Join(dir, "builtin.wav")
+	scaled := filepath.Join(dir, "scaled.wav")
+
+	if err := runCLI(t, "decode", input, builtin, "--bit-depth", "32f"); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if err := runCLI(t, "decode", input, scaled, "--bit-depth", "32f", "--hilbert-scale", "3.6"); err != nil {
+		t.Fatalf("decode --hilbert-scale error = %v", err)
+	}
+	a, err := os.ReadFile(builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(scaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) {
+		t.Fatalf("--hilbert-scale 3.6 output equals the default's")
+	}
+
+	fir := filepath.Join(dir, "fir.txt")
+	if err := os.WriteFile(fir, []byte("-0.6366 0 0.6366\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"decode", input, scaled, "--hilbert-scale", "0"},
+		{"decode", input, scaled, "--hilbert-scale", "2", "--hilbert-fir", fir},
+		{"encode", writeQuad(t, dir, 8000), scaled, "--hilbert-scale", "-1"},
+	} {
+		if err := runCLI(t, args...); err == nil {
+			t.Fatalf("%v succeeded", args)
+		}
+	}
+}
+
+func TestDecode_BitDepth(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	output := filepath.Join(dir, "out.wav")
+
+	for _, tc := range []struct {
+		args  []string
+		bits  int
+		float bool
+	}{
+		{nil, 16, false},
+		{[]string{"--bit-depth", "24"}, 24, false},
+		{[]string{"--bit-depth", "32f"}, 32, true},
+		{[]string{"--bit-depth", "8"}, 8, false},
+		// The deprecated flags keep working.
+		{[]string{"--float32"}, 32, true},
+		{[]string{"--bits", "8"}, 8, false},
+	} {
+		if err := runCLI(t, append([]string{"decode", input, output}, tc.args...)...); err != nil {
+			t.Fatalf("decode %v error = %v", tc.args, err)
+		}
+		file, err := os.Open(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := wav.ReadInfo(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("ReadInfo() error = %v", err)
+		}
+		if info.BitsPerSample != tc.bits || info.Float != tc.float {
+			t.Fatalf("decode %v wrote %d bits (float %v), want %d (float %v)", tc.args, info.BitsPerSample, info.Float, tc.bits, tc.float)
+		}
+	}
+
+	if err := runCLI(t, "decode", input, output, "--bit-depth", "32"); err == nil {
+		t.Fatal("decode accepted --bit-depth 32")
+	}
+	if err := runCLI(t, "decode", input, output, "--bit-depth", "24", "--float32"); err == nil {
+		t.Fatal("decode accepted --bit-depth with --float32")
+	}
+}
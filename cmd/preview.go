@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+)
+
+// preview, previewLength and previewEvery are decode --preview: decode
+// previewLength seconds out of every previewEvery seconds.
+var (
+	preview       bool
+	previewLength float64
+	previewEvery  float64
+)
+
+const (
+	// previewWarmup is the input in seconds decoded ahead of each preview
+	// segment so that the logic steering envelopes, bass management and
+	// crossfeed have settled when it starts.
+	previewWarmup = 2.0
+	// previewFade is the fade in and out of each segment in seconds.
+	previewFade = 0.01
+	// previewMinBlockSize is the smallest block size of a preview decode.
+	previewMinBlockSize = 256
+)
+
+// previewBlocks returns the reduced block size and overlap of a preview
+// decode: half the configured ones, down to previewMinBlockSize.
+func previewBlocks(blockSize, overlap int) (int, int) {
+	if blockSize/2 < previewMinBlockSize {
+		return blockSize, overlap
+	}
+	return blockSize / 2, max(overlap/2, 1)
+}
+
+func validatePreview() error {
+	if previewLength <= 0 || previewEvery < previewLength {
+		return fmt.Errorf("--preview-length must be > 0 and at most --preview-every, got %g and %g", previewLength, previewEvery)
+	}
+	return nil
+}
+
+// previewSegments returns the first frame of every preview segment of an
+// input of numFrames frames and the length of a full segment. Segment k
+// starts at k·--preview-every; the last one may be cut short by the end of
+// the input.
+func previewSegments(numFrames int, sampleRate uint32) ([]int, int) {
+	length := max(int(math.Round(previewLength*float64(sampleRate))), 1)
+	var starts []int
+	for k := 0; ; k++ {
+		start := int(math.Round(float64(k) * previewEvery * float64(sampleRate)))
+		if start >= numFrames {
+			return starts, length
+		}
+		starts = append(starts, start)
+	}
+}
+
+// previewSource serves the preview of an input: every segment is decoded
+// on its own by a fresh decoder, starting previewWarmup seconds early on a
+// block boundary and reading a block past its end, then cut out, faded in
+// and out and appended. The input is read once, front to back.
+type previewSource struct {
+	src        pipeline.Source
+	inChannels int
+	numFrames  int
+	newDecoder func() *sq.Decoder
+	blockSize  int
+	hop        int
+	warmup     int
+	fade       int
+	starts     []int
+	length     int
+	next       int
+	// in holds the input from frame inStart on; eof is set once src is
+	// exhausted.
+	in      [][]float64
+	inStart int
+	eof     bool
+	// out holds the decoded frames not served yet.
+	out [][]float64
+}
+
+// newPreviewSource returns the preview of the input read from src, which
+// has inChannels channels (1 for a duplicated mono input) and numFrames
+// frames, decoded by decoders from newDecoder with the given block size
+// and hop. It also returns the preview length in frames.
+func newPreviewSource(src pipeline.Source, inChannels, numFrames int, sampleRate uint32, newDecoder func() *sq.Decoder, blockSize, hop int) (*previewSource, int) {
+	starts, length := previewSegments(numFrames, sampleRate)
+	frames := 0
+	for _, start := range starts {
+		frames += min(length, numFrames-start)
+	}
+	p := &previewSource{
+		src:        src,
+		inChannels: inChannels,
+		numFrames:  numFrames,
+		newDecoder: newDecoder,
+		blockSize:  blockSize,
+		hop:        hop,
+		warmup:     int(previewWarmup * float64(sampleRate)),
+		fade:       int(previewFade * float64(sampleRate)),
+		starts:     starts,
+		length:     length,
+		in:         make([][]float64, inChannels),
+	}
+	return p, frames
+}
+
+// ReadFrames implements pipeline.Source.
+func (p *previewSource) ReadFrames(dst [][]float64) (int, error) {
+	for len(p.out) == 0 || len(p.out[0]) == 0 {
+		if p.next == len(p.starts) {
+			return 0, io.EOF
+		}
+		if err := p.decodeSegment(); err != nil {
+			return 0, err
+		}
+	}
+	if len(dst) != len(p.out) {
+		return 0, fmt.Errorf("destination must have %d channels, got %d", len(p.out), len(dst))
+	}
+	n := 0
+	for ch := range dst {
+		n = copy(dst[ch], p.out[ch])
+		p.out[ch] = p.out[ch][n:]
+	}
+	return n, nil
+}
+
+// decodeSegment decodes the next segment into out.
+func (p *previewSource) decodeSegment() error {
+	start := p.starts[p.next]
+	end := min(start+p.length, p.numFrames)
+	p.next++
+	from := max(start-p.warmup, 0) / p.hop * p.hop
+	to := min(end+p.blockSize, p.numFrames)
+	if err := p.fill(from, to); err != nil {
+		return err
+	}
+
+	window := make([][]float64, 2)
+	for ch := range window {
+		window[ch] = p.in[min(ch, p.inChannels-1)][from-p.inStart : to-p.inStart]
+	}
+	decoded, err := p.newDecoder().Process(window)
+	if err != nil {
+		return err
+	}
+	p.out = make([][]float64, len(decoded))
+	for ch := range decoded {
+		p.out[ch] = append([]float64(nil), decoded[ch][start-from:end-from]...)
+		fadeEdges(p.out[ch], p.fade)
+	}
+	return nil
+}
+
+// fill reads the input up to frame to and drops the frames before from.
+func (p *previewSource) fill(from, to int) error {
+	if drop := min(from-p.inStart, len(p.in[0])); drop > 0 {
+		for ch := range p.in {
+			p.in[ch] = append(p.in[ch][:0], p.in[ch][drop:]...)
+		}
+		p.inStart += drop
+	}
+	buf := make([][]float64, p.inChannels)
+	for p.inStart+len(p.in[0]) < to && !p.eof {
+		for ch := range buf {
+			buf[ch] = make([]float64, to-p.inStart-len(p.in[0]))
+		}
+		n, err := p.src.ReadFrames(buf)
+		// Frames before from are only read to be skipped; the buffer is
+		// empty then.
+		skip := min(max(from-p.inStart-len(p.in[0]), 0), n)
+		p.inStart += skip
+		for ch := range p.in {
+			p.in[ch] = append(p.in[ch], buf[ch][skip:n]...)
+		}
+		if errors.Is(err, io.EOF) {
+			p.eof = true
+		} else if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+	}
+	if p.inStart+len(p.in[0]) < to {
+		return fmt.Errorf("failed to read input: %w", io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// fadeEdges fades x in and out linearly over fade samples each.
+func fadeEdges(x []float64, fade int) {
+	fade = min(fade, len(x)/2)
+	for i := 0; i < fade; i++ {
+		g := float64(i) / float64(fade)
+		x[i] *= g
+		x[len(x)-1-i] *= g
+	}
+}
+
+// passDecoded is the processor of a preview decode, whose source already
+// delivers decoded frames.
+func passDecoded(input [][]float64, numOutput int) ([][]float64, error) {
+	output := make([][]float64, len(input))
+	for ch := range input {
+		output[ch] = input[ch][:numOutput]
+	}
+	return output, nil
+}
+
+// previewInfo labels the preview output in its INFO chunk.
+func previewInfo() wav.InfoTags {
+	return wav.InfoTags{
+		wav.InfoComment: fmt.Sprintf("SQ decode preview: %g s of every %g s", previewLength, previewEvery),
+	}
+}
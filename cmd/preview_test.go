@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestDecode_Preview(t *testing.T) {
+	dir := t.TempDir()
+	// 6.5 s at 8 kHz: segments of 1 s at 0, 3 and 6 s, the last one cut to
+	// 0.5 s by the end of the input.
+	input := writeStereo(t, dir, 52000)
+	out := filepath.Join(dir, "preview.wav")
+	full := filepath.Join(dir, "full.wav")
+
+	if err := runCLI(t, "decode", input, out, "--preview", "--preview-length", "1", "--preview-every", "3"); err != nil {
+		t.Fatalf("decode --preview error = %v", err)
+	}
+	// The preview decodes at half the default block size.
+	if err := runCLI(t, "decode", input, full, "--block-size", "512", "--overlap", "256"); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	got, want := readChannels(t, out, 4), readChannels(t, full, 4)
+	if len(got[0]) != 20000 {
+		t.Fatalf("preview has %d frames, want 20000", len(got[0]))
+	}
+
+	// Every segment matches the full decode between its fades, and starts
+	// faded out.
+	const fade = 80
+	offset := 0
+	for _, segment := range []struct{ start, length int }{{0, 8000}, {24000, 8000}, {48000, 4000}} {
+		for ch := range got {
+			if got[ch][offset] != 0 {
+				t.Fatalf("segment at %d channel %d starts at %v, want a fade from 0", segment.start, ch, got[ch][offset])
+			}
+			for i := fade; i < segment.length-fade; i++ {
+				if got[ch][offset+i] != want[ch][segment.start+i] {
+					t.Fatalf("segment at %d channel %d frame %d = %v, want %v",
+						segment.start, ch, i, got[ch][offset+i], want[ch][segment.start+i])
+				}
+			}
+		}
+		offset += segment.length
+	}
+
+	file, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	info, err := wav.ScanInfo(file)
+	if err != nil {
+		t.Fatalf("ScanInfo() error = %v", err)
+	}
+	if comment := info[wav.InfoComment]; !strings.Contains(comment, "preview") || !strings.Contains(comment, "1 s of every 3 s") {
+		t.Fatalf("INFO comment = %q, want a preview label", comment)
+	}
+
+	if err := runCLI(t, "decode", input, out, "--preview", "--preview-length", "5", "--preview-every", "3"); err == nil {
+		t.Fatal("decode accepted segments longer than their distance")
+	}
+}
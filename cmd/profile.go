@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"github.com/cwbudde/go-sq-tool/internal/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	profileName     string
+	saveProfileName string
+)
+
+// profileExcludedFlags are not processing options and never saved. Output
+// paths and reports belong to one run, not to the option set.
+var profileExcludedFlags = []string{
+	"profile", "save-profile", "help", "verbose", "log-level", "log-format",
+	"output", "split-fb", "debug-outputs", "image-report",
+}
+
+// applyProfile loads --profile (explicit flags win over loaded values) and
+// writes the effective option set to --save-profile.
+func applyProfile(cmd *cobra.Command, command string) error {
+	fs := cmd.Flags()
+
+	if profileName != "" {
+		path, err := profile.Path(profileName)
+		if err != nil {
+			return err
+		}
+		p, err := profile.Load(path)
+		if err != nil {
+			return err
+		}
+		if err := p.Apply(fs); err != nil {
+			return err
+		}
+		logger.Info("loaded profile", "path", path)
+	}
+
+	if saveProfileName != "" {
+		path, err := profile.Path(saveProfileName)
+		if err != nil {
+			return err
+		}
+		exclude := append(qualityDerivedFlags(cmd), profileExcludedFlags...)
+		if err := profile.Save(path, profile.FromFlags(command, fs, exclude...)); err != nil {
+			return err
+		}
+		logger.Info("saved profile", "path", path)
+	}
+
+	return nil
+}
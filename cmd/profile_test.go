@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecode_SaveThenLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	profilePath := filepath.Join(dir, "p.json")
+	first, second := filepath.Join(dir, "out.wav"), filepath.Join(dir, "out2.wav")
+
+	if err := runCLI(t, "decode", "--save-profile", profilePath, "--logic", input, first); err != nil {
+		t.Fatalf("decode --save-profile error = %v", err)
+	}
+	saved, err := os.ReadFile(profilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range profileExcludedFlags {
+		if strings.Contains(string(saved), `"`+name+`"`) {
+			t.Fatalf("profile saves %q:\n%s", name, saved)
+		}
+	}
+
+	if err := runCLI(t, "decode", "--profile", profilePath, input, second); err != nil {
+		t.Fatalf("decode --profile error = %v", err)
+	}
+	want, _ := os.ReadFile(first)
+	got, _ := os.ReadFile(second)
+	if len(want) == 0 || !bytes.Equal(got, want) {
+		t.Fatalf("decode with the loaded profile wrote %d bytes differing from the %d of the saving run", len(got), len(want))
+	}
+}
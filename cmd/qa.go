@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/qa"
+)
+
+// batchQA is --qa of batch.
+var batchQA bool
+
+// analyzeOutput scans a decoded output for audible defects and returns the
+// result for the manifest.
+func analyzeOutput(path string) (*batch.QA, error) {
+	mode, err := decoder.ParseBackChannelMode(backMode)
+	if err != nil {
+		return nil, err
+	}
+	channels := mode.Channels()
+	in, err := openStream(path, channels)
+	if err != nil {
+		return nil, fmt.Errorf("QA: %w", err)
+	}
+	defer in.Close()
+	analyzer, err := qa.NewAnalyzer(int(in.sampleRate), channels, qa.DefaultConfig(overlap))
+	if err != nil {
+		return nil, err
+	}
+	buf := make([][]float64, channels)
+	for ch := range buf {
+		buf[ch] = make([]float64, 1<<16)
+	}
+	for {
+		n, err := in.source.ReadFrames(buf)
+		if n > 0 {
+			frames := make([][]float64, channels)
+			for ch := range frames {
+				frames[ch] = buf[ch][:n]
+			}
+			if err := analyzer.Add(frames); err != nil {
+				return nil, err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("QA: %w", err)
+		}
+	}
+
+	report := analyzer.Report()
+	names := mode.ChannelNames()
+	result := &batch.QA{Score: report.Score, Findings: []batch.QAFinding{}}
+	for _, f := range report.Findings {
+		result.Findings = append(result.Findings, batch.QAFinding{
+			Kind:            string(f.Kind),
+			Channel:         names[f.Channel],
+			StartSeconds:    f.Start,
+			DurationSeconds: f.Duration,
+			Value:           f.Value,
+		})
+	}
+	return result, nil
+}
+
+// checkOutputs runs --qa on the successfully decoded entries of manifest
+// that have not been checked yet. save is called after every file.
+func checkOutputs(manifest *batch.Manifest, save func() error) error {
+	for i := range manifest.Files {
+		entry := &manifest.Files[i]
+		if !entry.OK() || entry.QA != nil {
+			continue
+		}
+		result, err := analyzeOutput(entry.Output)
+		if err != nil {
+			logger.Warn("QA failed", "path", entry.Output, "error", err)
+			entry.Warnings = append(entry.Warnings, "QA failed: "+err.Error())
+			continue
+		}
+		entry.QA = result
+		for _, f := range result.Findings {
+			logger.Warn("QA finding",
+				"path", entry.Output,
+				"kind", f.Kind,
+				"channel", f.Channel,
+				"start_seconds", f.StartSeconds,
+				"duration_seconds", f.DurationSeconds,
+				"value", f.Value)
+		}
+		logger.Info("QA", "path", entry.Output, "score", result.Score, "findings", len(result.Findings))
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// TestDecode_MatrixQS decodes LT alone with the QS matrix: the fronts take
+// it at cos and sin 22.5°, where SQ passes it to LF only.
+func TestDecode_MatrixQS(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	data, err := wav.ReadWAV(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clear(data.Samples[1])
+	if err := wav.WriteStereoWAV(input, data); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "qs.wav")
+	if err := runCLI(t, "decode", "--matrix", "qs", input, out); err != nil {
+		t.Fatalf("decode --matrix qs error = %v", err)
+	}
+	quad, err := wav.ReadWAVChannels(out, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rms := func(x []float64) float64 {
+		var sum float64
+		for _, v := range x[2000:6000] {
+			sum += v * v
+		}
+		return math.Sqrt(sum / 4000)
+	}
+	if ratio := rms(quad.Samples[1]) / rms(quad.Samples[0]); math.Abs(ratio-math.Tan(math.Pi/8)) > 0.01 {
+		t.Fatalf("RF/LF = %.4f, want tan 22.5° = %.4f", ratio, math.Tan(math.Pi/8))
+	}
+
+	if err := runCLI(t, "decode", "--matrix", "cd4", input, out); err == nil || !strings.Contains(err.Error(), "unknown matrix") {
+		t.Fatalf("decode --matrix cd4 error = %v, want unknown matrix", err)
+	}
+}
+
+func TestAnalyze_MatrixQS(t *testing.T) {
+	dir := t.TempDir()
+	input := writeQuad(t, dir, 16000)
+	path := filepath.Join(dir, "report.txt")
+	if err := runCLI(t, "analyze", "--matrix", "qs", input, "--output", "report="+path); err != nil {
+		t.Fatalf("analyze --matrix qs error = %v", err)
+	}
+	report, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(report), "Matrix: qs") {
+		t.Fatalf("report lacks the matrix:\n%s", report)
+	}
+}
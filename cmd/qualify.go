@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/cwbudde/go-sq-tool/internal/qualify"
+	"github.com/spf13/cobra"
+)
+
+// qualifyProfile and qualifyJSON are the flags of qualify. Its --profile
+// names a qualification profile and shadows the processing --profile of
+// the root command.
+var (
+	qualifyProfile string
+	qualifyJSON    bool
+)
+
+var qualifyCmd = &cobra.Command{
+	Use:   "qualify",
+	Short: "Score round-trip separation per octave band with pink noise",
+	Long: `Drives each channel alone with pink noise confined to one octave band at a
+time, runs encode -> decode, and measures in that band how far every other
+output stays below the driven channel. Unlike tone-based measurements, the
+result does not depend on where the test frequencies fall.
+
+The profile, a JSON file, sets the sample rate, burst length and level, the
+block size, overlap and matrix, the octave bands measured, and the minimum
+separation of each source -> leak pair, optionally limited to a frequency
+range. --profile takes a file path or the name of a built-in profile
+(default: "default", which checks the left/right pairs SQ keeps discrete).
+
+With --json the scorecard is written as JSON. Exits with a non-zero status
+if any threshold is missed.`,
+	Args: cobra.NoArgs,
+	RunE: runQualify,
+}
+
+func init() {
+	qualifyCmd.Flags().StringVar(&qualifyProfile, "profile", "default", "qualification profile: a JSON file or a built-in name")
+	qualifyCmd.Flags().BoolVar(&qualifyJSON, "json", false, "write the scorecard as JSON")
+}
+
+func runQualify(cmd *cobra.Command, args []string) error {
+	profile, err := qualify.LoadProfile(qualifyProfile)
+	if err != nil {
+		return err
+	}
+	logger.Info("qualifying", "profile", profile.Name, "thresholds", len(profile.Thresholds))
+	card, err := qualify.Run(profile)
+	if err != nil {
+		return fmt.Errorf("qualification failed to run: %w", err)
+	}
+
+	if qualifyJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(card); err != nil {
+			return err
+		}
+	} else if err := writeScorecard(cmd.OutOrStdout(), profile, card); err != nil {
+		return err
+	}
+	if !card.Passed() {
+		return fmt.Errorf("qualification failed: %d band(s) below threshold", len(card.Failures))
+	}
+	return nil
+}
+
+// writeScorecard writes card as a table of every source -> leak pair by
+// band, followed by the result.
+func writeScorecard(w io.Writer, profile qualify.Profile, card qualify.Scorecard) error {
+	fmt.Fprintf(w, "SQ qualification, profile %q (separation in dB, pink noise per octave)\n", profile.Name)
+	if profile.Description != "" {
+		fmt.Fprintf(w, "%s\n", profile.Description)
+	}
+	fmt.Fprintf(w, "\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Pair\t")
+	for _, hz := range card.BandsHz {
+		fmt.Fprintf(tw, "%s\t", formatBandHz(hz))
+	}
+	fmt.Fprintf(tw, "\n")
+	for src, name := range qualify.ChannelNames {
+		for leak, leakName := range qualify.ChannelNames {
+			if leak == src {
+				continue
+			}
+			fmt.Fprintf(tw, "%s->%s\t", name, leakName)
+			for _, sep := range card.Separation[src][leak] {
+				fmt.Fprintf(tw, "%.1f\t", sep)
+			}
+			fmt.Fprintf(tw, "\n")
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if card.Passed() {
+		fmt.Fprintf(w, "\nAll %d thresholds met.\n", len(profile.Thresholds))
+		return nil
+	}
+	fmt.Fprintf(w, "\nFailures:\n")
+	for _, f := range card.Failures {
+		fmt.Fprintf(w, "  %s\n", f)
+	}
+	return nil
+}
+
+// formatBandHz formats an octave band center as 62.5, 125 or 1k.
+func formatBandHz(hz float64) string {
+	if hz >= 1000 {
+		return fmt.Sprintf("%gk", hz/1000)
+	}
+	return fmt.Sprintf("%g", hz)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/qualify"
+)
+
+func TestQualify(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile := func(name, thresholds string) string {
+		path := filepath.Join(dir, name)
+		data := `{"name": "` + name + `", "sample_rate": 44100, "duration_seconds": 0.5, "level_dbfs": -20,
+			"min_band_hz": 1000, "max_band_hz": 1000, "thresholds": [` + thresholds + `]}`
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	pass := writeProfile("pass.json", `{"source": "LF", "leak": "RF", "min_db": 30}`)
+	fail := writeProfile("fail.json", `{"source": "LF", "leak": "RB", "min_db": 30}`)
+
+	if err := runCLI(t, "qualify", "--profile", pass); err != nil {
+		t.Fatalf("qualify error = %v", err)
+	}
+	if err := runCLI(t, "qualify", "--profile", pass, "--json"); err != nil {
+		t.Fatalf("qualify --json error = %v", err)
+	}
+	if err := runCLI(t, "qualify", "--profile", fail); err == nil {
+		t.Fatalf("qualify with a missed threshold succeeded")
+	}
+	if err := runCLI(t, "qualify", "--profile", filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatalf("qualify with a missing profile succeeded")
+	}
+
+	profile, err := qualify.LoadProfile(fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := qualify.Run(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := writeScorecard(&out, profile, card); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"1k", "LF->RB", "Failures:", "LF -> RB at 1000 Hz"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("scorecard = %q, want %q", out.String(), want)
+		}
+	}
+}
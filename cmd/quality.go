@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+	"github.com/spf13/cobra"
+)
+
+var (
+	quality    string
+	windowName string
+)
+
+// qualityFlags are the flags a --quality preset sets.
+var qualityFlags = []string{"block-size", "overlap", "window"}
+
+// applyQuality resolves --quality at sampleRate into the block size and
+// overlap globals and returns the phase shifter window. Explicit
+// --block-size, --overlap and --window flags win over the preset.
+func applyQuality(cmd *cobra.Command, sampleRate uint32) (sqmath.WindowType, error) {
+	fs := cmd.Flags()
+	window, err := sqmath.ParseWindowType(windowName)
+	if err != nil {
+		return "", err
+	}
+	if quality == "" {
+		return window, checkBlockParams()
+	}
+
+	preset, err := decoder.LookupQuality(quality)
+	if err != nil {
+		return "", err
+	}
+	presetBlockSize, presetOverlap := preset.Resolve(int(sampleRate))
+	if !fs.Changed("block-size") {
+		blockSize = presetBlockSize
+	}
+	if !fs.Changed("overlap") {
+		overlap = presetOverlap
+	}
+	if !fs.Changed("window") {
+		window = preset.Window
+	}
+	logger.Info("quality preset", "quality", preset.Name, "summary", preset.Summary)
+	return window, checkBlockParams()
+}
+
+// checkBlockParams rejects a --block-size and --overlap the decoder cannot
+// run with, before any decoder is built from them, naming the flag at fault.
+func checkBlockParams() error {
+	if err := decoder.ValidateParams(blockSize, overlap); err != nil {
+		flag := "--block-size"
+		var paramErr *decoder.ParamError
+		if errors.As(err, &paramErr) && paramErr.Param == "overlap" {
+			flag = "--overlap"
+		}
+		return fmt.Errorf("invalid %s: %w", flag, err)
+	}
+	return nil
+}
+
+// qualityDerivedFlags returns the flags whose values come from --quality
+// rather than from the user; saved profiles leave them out so that the
+// preset resolves again for every input's sample rate.
+func qualityDerivedFlags(cmd *cobra.Command) []string {
+	if quality == "" {
+		return nil
+	}
+	var derived []string
+	for _, name := range qualityFlags {
+		if f := cmd.Flags().Lookup(name); f != nil && !f.Changed {
+			derived = append(derived, name)
+		}
+	}
+	return derived
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecode_InvalidBlockParams(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 4000)
+	quad := writeQuad(t, dir, 4000)
+	out := filepath.Join(dir, "out.wav")
+
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"decode", "--block-size", "1000", input, out}, "invalid --block-size: block size 1000 is not a power of two"},
+		{[]string{"decode", "--overlap", "0", input, out}, "invalid --overlap: overlap 0 is not positive"},
+		{[]string{"encode", "--overlap", "4096", quad, out}, "invalid --overlap: overlap 4096 exceeds block size 1024"},
+		{[]string{"analyze", "--block-size", "3", quad}, "invalid --block-size: block size 3 is not a power of two"},
+		{[]string{"hilbert", "--overlap", "-1", input, out}, "invalid --overlap: overlap -1 is not positive"},
+	} {
+		err := runCLI(t, test.args...)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Fatalf("%v error = %v, want %q", test.args, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// refToneSpec is --ref-tone of encode.
+var refToneSpec string
+
+// refTone is a line-up tone prepended to the encoded output.
+type refTone struct {
+	Freq    float64
+	LevelDB float64
+	Seconds float64
+}
+
+// parseRefTone parses "freq,dBFS,seconds". An empty spec returns a tone of
+// zero length.
+func parseRefTone(spec string, sampleRate uint32) (refTone, error) {
+	if spec == "" {
+		return refTone{}, nil
+	}
+	fields := strings.Split(spec, ",")
+	if len(fields) != 3 {
+		return refTone{}, fmt.Errorf("--ref-tone must be freq,dBFS,seconds, got %q", spec)
+	}
+	var values [3]float64
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return refTone{}, fmt.Errorf("--ref-tone: %w", err)
+		}
+		values[i] = v
+	}
+	tone := refTone{Freq: values[0], LevelDB: values[1], Seconds: values[2]}
+	if tone.Freq <= 0 || tone.Freq >= float64(sampleRate)/2 {
+		return refTone{}, fmt.Errorf("--ref-tone frequency must be between 0 and %d Hz, got %g", sampleRate/2, tone.Freq)
+	}
+	if tone.LevelDB > 0 || math.IsNaN(tone.LevelDB) {
+		return refTone{}, fmt.Errorf("--ref-tone level must be <= 0 dBFS, got %g", tone.LevelDB)
+	}
+	if tone.Seconds <= 0 || math.IsInf(tone.Seconds, 0) {
+		return refTone{}, fmt.Errorf("--ref-tone duration must be > 0 s, got %g", tone.Seconds)
+	}
+	return tone, nil
+}
+
+// Frames returns the length of the tone at sampleRate.
+func (r refTone) Frames(sampleRate uint32) int {
+	return int(math.Round(r.Seconds * float64(sampleRate)))
+}
+
+// Samples returns the tone in each of channels channels. Its level is the
+// peak level of the sine.
+func (r refTone) Samples(sampleRate uint32, channels int) [][]float64 {
+	tone := testsignal.ReferenceTone(int(sampleRate), r.Frames(sampleRate), r.Freq, math.Pow(10, r.LevelDB/20))
+	samples := make([][]float64, channels)
+	for ch := range samples {
+		samples[ch] = tone
+	}
+	return samples
+}
+
+// shiftLoops returns loops moved frames later, for output that has frames
+// frames prepended.
+func shiftLoops(loops []wav.Loop, frames int) []wav.Loop {
+	if frames == 0 || len(loops) == 0 {
+		return loops
+	}
+	shifted := make([]wav.Loop, len(loops))
+	for i, loop := range loops {
+		loop.Start += uint32(frames)
+		loop.End += uint32(frames)
+		shifted[i] = loop
+	}
+	return shifted
+}
+
+// shiftCues is shiftLoops for cue points.
+func shiftCues(cues []wav.Cue, frames int) []wav.Cue {
+	if frames == 0 || len(cues) == 0 {
+		return cues
+	}
+	shifted := make([]wav.Cue, len(cues))
+	for i, cue := range cues {
+		cue.Position += uint32(frames)
+		shifted[i] = cue
+	}
+	return shifted
+}
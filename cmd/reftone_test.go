@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func TestEncode_RefTone(t *testing.T) {
+	dir := t.TempDir()
+	const frames = 6000
+	input := writeQuad(t, dir, frames)
+	plain := filepath.Join(dir, "plain.wav")
+	if err := runCLI(t, "encode", input, plain); err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+	toned := filepath.Join(dir, "toned.wav")
+	if err := runCLI(t, "encode", "--ref-tone", "1000,-18,0.5", input, toned); err != nil {
+		t.Fatalf("encode --ref-tone error = %v", err)
+	}
+
+	want, err := wav.ReadWAVChannels(plain, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := wav.ReadWAVChannels(toned, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const toneFrames = 4000
+	if got.NumSamples != toneFrames+frames {
+		t.Fatalf("output has %d frames, want %d", got.NumSamples, toneFrames+frames)
+	}
+
+	// Both channels start with a 1 kHz tone of -18 dBFS peak: measure its
+	// amplitude and its DFT at 1 kHz against a neighbouring frequency,
+	// away from the fades.
+	peak := math.Pow(10, -18.0/20)
+	from, to := 400, 3600
+	for ch := range got.Samples {
+		x := got.Samples[ch]
+		if amp := toneAmplitudeAt(x, 1000, from, to); math.Abs(amp-peak) > 0.002 {
+			t.Fatalf("channel %d 1 kHz amplitude = %g, want %g", ch, amp, peak)
+		}
+		if off := toneAmplitudeAt(x, 1250, from, to); off > 0.001 {
+			t.Fatalf("channel %d 1.25 kHz amplitude = %g, want a pure tone", ch, off)
+		}
+	}
+
+	// The encoded program follows unchanged.
+	for ch := range want.Samples {
+		for i, v := range want.Samples[ch] {
+			if got.Samples[ch][toneFrames+i] != v {
+				t.Fatalf("channel %d frame %d = %g after the tone, want %g", ch, i, got.Samples[ch][toneFrames+i], v)
+			}
+		}
+	}
+}
+
+func TestEncode_RefToneInvalid(t *testing.T) {
+	dir := t.TempDir()
+	input := writeQuad(t, dir, 1000)
+	out := filepath.Join(dir, "out.wav")
+	for _, spec := range []string{"1000,-18", "5000,-18,2", "1000,3,2", "1000,-18,0", "1k,-18,2"} {
+		if err := runCLI(t, "encode", "--ref-tone", spec, input, out); err == nil {
+			t.Fatalf("encode --ref-tone %s succeeded", spec)
+		}
+	}
+}
+
+// toneAmplitudeAt returns the amplitude of the freq component of
+// x[from:to] at 8000 Hz.
+func toneAmplitudeAt(x []float64, freq float64, from, to int) float64 {
+	var re, im float64
+	for i := from; i < to; i++ {
+		phase := 2 * math.Pi * freq * float64(i) / 8000
+		re += x[i] * math.Cos(phase)
+		im += x[i] * math.Sin(phase)
+	}
+	return 2 * math.Hypot(re, im) / float64(to-from)
+}
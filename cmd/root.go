@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"runtime/debug"
 
 	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
 	"github.com/spf13/cobra"
 )
 
@@ -13,7 +17,15 @@ var (
 	blockSize int
 	overlap   int
 	float32   bool
+	bits      int
+	bitDepth  string
 	logic     bool
+	sanitize  bool
+	backMode  string
+
+	chunkLayout  string
+	fftPrecision string
+	errorOnClip  bool
 )
 
 var rootCmd = &cobra.Command{
@@ -32,10 +44,24 @@ Encode Output: 2-channel WAV file (LT, RT - Left Total, Right Total)
 
 Based on the SQ² decoder implementation with FFT-based Hilbert transformer
 for superior channel separation compared to simple recursive filters.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setupLogging(cmd)
+	},
 	RunE: runRoot,
 }
 
 func Execute() {
+	// A panic is a bug, but the user still gets a message rather than a
+	// stack trace; --log-level debug prints the stack for a report.
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "Error: internal error: %v\nPlease report this with the command line that caused it.\n", r)
+			if logger != nil && logger.Enabled(context.Background(), slog.LevelDebug) {
+				os.Stderr.Write(debug.Stack())
+			}
+			os.Exit(2)
+		}
+	}()
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -43,15 +69,46 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output (same as --log-level info)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "diagnostic log level: error, warn, info or debug")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "diagnostic log format: text or json")
 	rootCmd.PersistentFlags().IntVarP(&blockSize, "block-size", "b", decoder.DefaultBlockSize, "FFT block size (power of 2)")
 	rootCmd.PersistentFlags().IntVarP(&overlap, "overlap", "o", decoder.DefaultOverlap, "overlap in samples")
+	rootCmd.PersistentFlags().StringVar(&quality, "quality", "", "parameter preset: fast, default or best (sets --block-size, --overlap and --window for the input's sample rate)")
+	rootCmd.PersistentFlags().StringVar(&windowName, "window", string(sqmath.WindowHann), "phase shifter window: hann, hamming, blackman or rect (see list windows)")
+	rootCmd.PersistentFlags().StringVar(&bitDepth, "bit-depth", "16", "output sample format: 8 (unsigned PCM), 16 or 24 (PCM) or 32f (IEEE float)")
 	rootCmd.PersistentFlags().BoolVar(&float32, "float32", false, "output 32-bit IEEE float WAV instead of 16-bit PCM")
+	rootCmd.PersistentFlags().IntVar(&bits, "bits", 16, "PCM output bit depth: 8 (unsigned) or 16")
+	rootCmd.PersistentFlags().MarkDeprecated("float32", "use --bit-depth 32f")
+	rootCmd.PersistentFlags().MarkDeprecated("bits", "use --bit-depth")
+	rootCmd.PersistentFlags().BoolVar(&errorOnClip, "error-on-clip", false, "fail instead of clamping when an output sample exceeds full scale")
+	rootCmd.PersistentFlags().StringVar(&chunkLayout, "chunk-layout", "minimal", "output WAV chunk layout: minimal, standard or trailing")
 	rootCmd.PersistentFlags().BoolVar(&logic, "logic", false, "enable CBS-style logic steering for decoding")
+	rootCmd.PersistentFlags().StringVar(&backMode, "back-mode", "discrete", "decoded back channels: discrete (LB, RB), sumdiff (LB+RB, LB-RB) or both (6 channels)")
+	rootCmd.PersistentFlags().StringVar(&fftPrecision, "precision", "64", "FFT precision of the phase shifter: 64 or 32 (float32, less memory)")
+	rootCmd.PersistentFlags().BoolVar(&sanitize, "sanitize", false, "replace NaN/Inf input samples with 0 before decoding")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "load decode/encode options from a saved profile (name or .json path)")
+	rootCmd.PersistentFlags().StringVar(&saveProfileName, "save-profile", "", "save the effective decode/encode options as a profile (name or .json path)")
 	rootCmd.AddCommand(decodeCmd)
 	rootCmd.AddCommand(encodeCmd)
 	rootCmd.AddCommand(analyzeCmd)
+	rootCmd.AddCommand(joinDecodeCmd)
+	rootCmd.AddCommand(batchCmd)
+	addDecodeFlags()
+	rootCmd.AddCommand(hilbertCmd)
+	rootCmd.AddCommand(fixCmd)
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(generateCalCmd)
+	rootCmd.AddCommand(selfTestCmd)
+	rootCmd.AddCommand(qualifyCmd)
+	rootCmd.AddCommand(matrixInfoCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(localizeCmd)
+	rootCmd.AddCommand(calibrateCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(genVectorsCmd)
+	rootCmd.AddCommand(verifyHashCmd)
+	rootCmd.AddCommand(infoCmd)
 }
 
 func runRoot(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+	"github.com/spf13/pflag"
+)
+
+// allowResample is --allow-resample of the commands that take several
+// inputs.
+var allowResample bool
+
+func addResampleFlag(flags *pflag.FlagSet) {
+	flags.BoolVar(&allowResample, "allow-resample", false, "resample inputs whose sample rate differs from the others instead of failing")
+}
+
+// commonSampleRate returns the sample rate several inputs are processed at:
+// the rate most of them have, ties going to the earlier input. Differing
+// rates are an error unless --allow-resample is set.
+func commonSampleRate(names []string, rates []uint32) (uint32, error) {
+	count := make(map[uint32]int)
+	common := rates[0]
+	for _, rate := range rates {
+		count[rate]++
+		if count[rate] > count[common] {
+			common = rate
+		}
+	}
+	if count[common] == len(rates) || allowResample {
+		return common, nil
+	}
+	var differing []string
+	for i, rate := range rates {
+		if rate != common {
+			differing = append(differing, fmt.Sprintf("%s has %d Hz", names[i], rate))
+		}
+	}
+	return 0, fmt.Errorf("inputs must have the same sample rate, most have %d Hz but %s; use --allow-resample to convert them",
+		common, strings.Join(differing, ", "))
+}
+
+// reconcileSampleRates converts the inputs that are not at the common
+// sample rate, which are read into memory for it, and returns the common
+// rate. Every conversion is logged.
+func reconcileSampleRates(names []string, inputs []*streamInput, channels int) (uint32, error) {
+	rates := make([]uint32, len(inputs))
+	for i, in := range inputs {
+		rates[i] = in.sampleRate
+	}
+	rate, err := commonSampleRate(names, rates)
+	if err != nil {
+		return 0, err
+	}
+	for i, in := range inputs {
+		if in.sampleRate == rate {
+			continue
+		}
+		if err := resampleInput(in, channels, rate); err != nil {
+			return 0, fmt.Errorf("failed to resample %s: %w", names[i], err)
+		}
+		logger.Info("resampled input",
+			"path", names[i],
+			"from_hz", rates[i],
+			"to_hz", rate,
+			"frames", in.numFrames)
+	}
+	return rate, nil
+}
+
+// resampleAudio converts data, read from name, to rate in memory and logs
+// the conversion, for the commands that read their inputs whole.
+func resampleAudio(name string, data *wav.AudioData, rate uint32) {
+	from := data.SampleRate
+	for ch := range data.Samples {
+		data.Samples[ch] = sqmath.Resample(data.Samples[ch], int(from), int(rate))
+	}
+	data.SampleRate = rate
+	data.NumSamples = len(data.Samples[0])
+	logger.Info("resampled input",
+		"path", name,
+		"from_hz", from,
+		"to_hz", rate,
+		"frames", data.NumSamples)
+}
+
+// resampleInput reads the rest of in and serves it resampled to rate from
+// memory.
+func resampleInput(in *streamInput, channels int, rate uint32) error {
+	samples := make([][]float64, channels)
+	buf := make([][]float64, channels)
+	for ch := range buf {
+		buf[ch] = make([]float64, 1<<16)
+	}
+	for {
+		n, err := in.source.ReadFrames(buf)
+		for ch := range samples {
+			samples[ch] = append(samples[ch], buf[ch][:n]...)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for ch := range samples {
+		samples[ch] = sqmath.Resample(samples[ch], int(in.sampleRate), int(rate))
+	}
+	in.source = pipeline.NewSliceSource(samples)
+	in.sampleRate = rate
+	in.numFrames = len(samples[0])
+	// Loop and cue points refer to the original rate and are dropped.
+	in.loops, in.cues = nil, nil
+	return nil
+}
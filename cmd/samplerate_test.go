@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// writeStereoAt writes a 440 Hz stereo tone of frames frames at rate to
+// name in dir.
+func writeStereoAt(t *testing.T, dir, name string, rate uint32, frames int) string {
+	t.Helper()
+	data := &wav.AudioData{SampleRate: rate, NumSamples: frames, Samples: make([][]float64, 2)}
+	for ch := range data.Samples {
+		data.Samples[ch] = make([]float64, frames)
+		for i := range data.Samples[ch] {
+			data.Samples[ch][i] = 0.5 * math.Sin(2*math.Pi*float64((ch+1)*440*i)/float64(rate))
+		}
+	}
+	path := filepath.Join(dir, name)
+	if err := wav.WriteStereoWAV(path, data); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommonSampleRate(t *testing.T) {
+	names := []string{"a.wav", "b.wav", "c.wav"}
+	tests := []struct {
+		rates    []uint32
+		resample bool
+		want     uint32
+		wantErr  bool
+	}{
+		{[]uint32{44100, 44100, 44100}, false, 44100, false},
+		{[]uint32{44100, 48000, 48000}, false, 0, true},
+		{[]uint32{44100, 48000, 48000}, true, 48000, false},
+		{[]uint32{48000, 44100}, true, 48000, false},
+		{[]uint32{44100, 48000}, true, 44100, false},
+	}
+	defer func() { allowResample = false }()
+	for _, tt := range tests {
+		allowResample = tt.resample
+		got, err := commonSampleRate(names[:len(tt.rates)], tt.rates)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Fatalf("commonSampleRate(%v, resample %t) = %d, %v; want %d, error %t",
+				tt.rates, tt.resample, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && (!strings.Contains(err.Error(), "a.wav has 44100 Hz") || !strings.Contains(err.Error(), "--allow-resample")) {
+			t.Fatalf("error %q does not name the differing input and the flag", err)
+		}
+	}
+}
+
+func TestJoinDecode_SampleRateMismatch(t *testing.T) {
+	dir := t.TempDir()
+	a := writeStereoAt(t, dir, "a.wav", 8000, 4000)
+	b := writeStereoAt(t, dir, "b.wav", 16000, 6000)
+	c := writeStereoAt(t, dir, "c.wav", 8000, 2000)
+	out := filepath.Join(dir, "quad.wav")
+
+	err := runCLI(t, "join-decode", a, b, c, out)
+	if err == nil || !strings.Contains(err.Error(), "b.wav has 16000 Hz") {
+		t.Fatalf("join-decode with mixed rates error = %v, want a sample rate error", err)
+	}
+
+	if err := runCLI(t, "join-decode", "--allow-resample", a, b, c, out); err != nil {
+		t.Fatalf("join-decode --allow-resample error = %v", err)
+	}
+	got, err := wav.ReadWAVChannels(out, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SampleRate != 8000 || got.NumSamples != 4000+3000+2000 {
+		t.Fatalf("output is %d frames at %d Hz, want 9000 at 8000 Hz", got.NumSamples, got.SampleRate)
+	}
+	// The resampled middle input decodes to the same tone as its
+	// neighbours: LF carries the 440 Hz LT at full level.
+	var sum float64
+	for i := 5000; i < 6000; i++ {
+		sum += got.Samples[0][i] * got.Samples[0][i]
+	}
+	if rms := math.Sqrt(sum / 1000); math.Abs(rms-0.5/math.Sqrt2) > 0.02 {
+		t.Fatalf("resampled LF RMS = %g, want %g", rms, 0.5/math.Sqrt2)
+	}
+}
+
+func TestJoinDecode_EqualRatesUnchangedByAllowResample(t *testing.T) {
+	dir := t.TempDir()
+	a := writeStereoAt(t, dir, "a.wav", 8000, 3000)
+	b := writeStereoAt(t, dir, "b.wav", 8000, 2500)
+	plain := filepath.Join(dir, "plain.wav")
+	allowed := filepath.Join(dir, "allowed.wav")
+	if err := runCLI(t, "join-decode", a, b, plain); err != nil {
+		t.Fatalf("join-decode error = %v", err)
+	}
+	if err := runCLI(t, "join-decode", "--allow-resample", a, b, allowed); err != nil {
+		t.Fatalf("join-decode --allow-resample error = %v", err)
+	}
+	want, err := wav.ReadWAVChannels(plain, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := wav.ReadWAVChannels(allowed, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ch := range want.Samples {
+		for i, v := range want.Samples[ch] {
+			if got.Samples[ch][i] != v {
+				t.Fatalf("channel %d frame %d = %g, want %g", ch, i, got.Samples[ch][i], v)
+			}
+		}
+	}
+}
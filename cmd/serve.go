@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen     string
+	serveThreads    int
+	serveMaxBodyMiB int64
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an HTTP service exposing decode and encode",
+	Long: `Starts an HTTP server with the following endpoints:
+
+  POST /decode   2-channel WAV in, decoded WAV out
+  POST /encode   4-channel WAV in, SQ stereo WAV out
+  GET  /healthz  liveness check
+
+The WAV is sent as the raw request body or as a file part of a
+multipart/form-data body. Query parameters: block_size, overlap,
+window (hann, hamming, blackman, rect), logic (decode only) and
+format (pcm16, pcm8, pcm24, float32).
+
+Errors are returned as JSON: {"error": {"code": "...", "message": "..."}}.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "address to listen on")
+	serveCmd.Flags().IntVar(&serveThreads, "threads", runtime.NumCPU(), "maximum number of requests processed concurrently")
+	serveCmd.Flags().Int64Var(&serveMaxBodyMiB, "max-body-mb", server.DefaultMaxBodyBytes>>20, "maximum request body size in MiB")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if serveThreads <= 0 {
+		return fmt.Errorf("--threads must be > 0, got %d", serveThreads)
+	}
+	if serveMaxBodyMiB <= 0 {
+		return fmt.Errorf("--max-body-mb must be > 0, got %d", serveMaxBodyMiB)
+	}
+
+	srv := &http.Server{
+		Addr: serveListen,
+		Handler: server.New(server.Config{
+			MaxBodyBytes:  serveMaxBodyMiB << 20,
+			MaxConcurrent: serveThreads,
+			Logger:        logger,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	fmt.Printf("Listening on %s (max %d concurrent requests)\n", serveListen, serveThreads)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("shutdown failed: %w", err)
+	}
+	return nil
+}
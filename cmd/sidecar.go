@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+	"github.com/cwbudde/go-sq-tool/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// sidecar is the --sidecar flag of decode, encode and batch.
+var sidecar bool
+
+func addSidecarFlag(flags *pflag.FlagSet) {
+	flags.BoolVar(&sidecar, "sidecar", false, "write a provenance record with the configuration and file hashes next to every output (<output>.json)")
+}
+
+// sidecarRun collects the provenance of one run for --sidecar. It records
+// the log while the command runs, so the sidecar gets the resolved
+// configuration and the warnings whatever the console log level.
+type sidecarRun struct {
+	record   batch.Sidecar
+	console  *slog.Logger
+	recorder *logging.Recorder
+}
+
+// startSidecar starts recording the run of cmd with args for --sidecar, or
+// returns nil without it. Stop must be called when the command returns.
+func startSidecar(cmd *cobra.Command, args []string, outputs *artifact.Set) (*sidecarRun, error) {
+	if !sidecar {
+		return nil, nil
+	}
+	if args[0] == stdinInput || outputs.WritesStdout() {
+		return nil, fmt.Errorf("--sidecar needs input and output files, not stdin or stdout")
+	}
+	flags := make(map[string]string)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	s := &sidecarRun{
+		record: batch.Sidecar{
+			Tool:        cmd.Root().Name(),
+			ToolVersion: toolVersion(),
+			CommandLine: os.Args,
+			Command:     cmd.CommandPath(),
+			Args:        args,
+			Flags:       flags,
+			Start:       time.Now(),
+		},
+		console:  logger,
+		recorder: logging.NewRecorder(logger.Handler(), slog.LevelInfo),
+	}
+	logger = slog.New(s.recorder)
+	return s, nil
+}
+
+// stop restores the console logger.
+func (s *sidecarRun) stop() {
+	if s != nil {
+		logger = s.console
+	}
+}
+
+// finish writes the sidecar of every output, with the configuration logged
+// under configMsg and the hashes of inputs.
+func (s *sidecarRun) finish(inputs, outputs []string, configMsg string) error {
+	if s == nil {
+		return nil
+	}
+	record := s.record
+	record.End = time.Now()
+	record.Elapsed = record.End.Sub(record.Start).Seconds()
+	record.Config = recordedAttrs(s.recorder, configMsg)
+	record.Warnings = recordedWarnings(s.recorder)
+	for _, path := range inputs {
+		hash, err := batch.HashFile(path)
+		if err != nil {
+			return fmt.Errorf("sidecar: %w", err)
+		}
+		record.Inputs = append(record.Inputs, batch.FileHash{Path: path, SHA256: hash})
+	}
+	for _, path := range outputs {
+		hash, err := batch.HashFile(path)
+		if err != nil {
+			return fmt.Errorf("sidecar: %w", err)
+		}
+		record.Output = batch.FileHash{Path: path, SHA256: hash}
+		sidecarPath := batch.SidecarPath(path)
+		if err := batch.SaveSidecar(sidecarPath, record); err != nil {
+			return err
+		}
+		s.console.Info("wrote sidecar", "path", sidecarPath)
+	}
+	return nil
+}
+
+// recordedAttrs returns the attributes of the first record with message
+// msg as strings, or nil.
+func recordedAttrs(recorder *logging.Recorder, msg string) map[string]string {
+	attrs := recorder.Find(msg)
+	if attrs == nil {
+		return nil
+	}
+	values := make(map[string]string, len(attrs))
+	for key, value := range attrs {
+		values[key] = value.String()
+	}
+	return values
+}
+
+// recordedWarnings returns the recorded warnings and errors, each as its
+// message followed by its attributes.
+func recordedWarnings(recorder *logging.Recorder) []string {
+	var warnings []string
+	for _, record := range recorder.Records() {
+		if record.Level < slog.LevelWarn {
+			continue
+		}
+		warning := record.Message
+		record.Attrs(func(a slog.Attr) bool {
+			warning += " " + a.String()
+			return true
+		})
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
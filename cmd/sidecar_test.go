@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+)
+
+func TestSidecar(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	decoded := filepath.Join(dir, "decoded.wav")
+	encoded := filepath.Join(dir, "encoded.wav")
+
+	if err := runCLI(t, "decode", input, decoded, "--sidecar", "--block-size", "512", "--overlap", "256"); err != nil {
+		t.Fatalf("decode --sidecar error = %v", err)
+	}
+	record, err := batch.LoadSidecar(batch.SidecarPath(decoded))
+	if err != nil {
+		t.Fatalf("LoadSidecar() error = %v", err)
+	}
+	if record.Tool != "go-sq-tool" || record.Command != "go-sq-tool decode" || !slices.Equal(record.Args, []string{input, decoded}) {
+		t.Fatalf("sidecar command = %s %q %v", record.Tool, record.Command, record.Args)
+	}
+	if record.Flags["block-size"] != "512" || record.Flags["sidecar"] != "true" {
+		t.Fatalf("sidecar flags = %v", record.Flags)
+	}
+	if record.Config["block_size"] != "512" || record.Config["overlap"] != "256" || record.Config["window"] != "hann" {
+		t.Fatalf("sidecar config = %v", record.Config)
+	}
+	checkHash(t, record.Inputs[0], input)
+	checkHash(t, record.Output, decoded)
+	if len(record.Inputs) != 1 || record.End.Before(record.Start) {
+		t.Fatalf("sidecar = %+v", record)
+	}
+
+	if err := runCLI(t, "encode", decoded, encoded, "--sidecar"); err != nil {
+		t.Fatalf("encode --sidecar error = %v", err)
+	}
+	record, err = batch.LoadSidecar(batch.SidecarPath(encoded))
+	if err != nil {
+		t.Fatalf("LoadSidecar() error = %v", err)
+	}
+	if record.Command != "go-sq-tool encode" || record.Config["block_size"] != "1024" {
+		t.Fatalf("encode sidecar = %+v", record)
+	}
+	checkHash(t, record.Inputs[0], decoded)
+	checkHash(t, record.Output, encoded)
+
+	if err := runCLI(t, "verify-hash", decoded, encoded); err != nil {
+		t.Fatalf("verify-hash error = %v", err)
+	}
+
+	// A changed output fails verification, as does one without a sidecar.
+	data, err := os.ReadFile(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 1
+	if err := os.WriteFile(encoded, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var report strings.Builder
+	if err := verifyHashes(&report, []string{decoded, encoded, input}); err == nil {
+		t.Fatal("verify-hash accepted a tampered output")
+	}
+	want := []string{"OK      " + decoded, "FAILED  " + encoded + ": ", "FAILED  " + input + ": "}
+	for _, line := range want {
+		if !strings.Contains(report.String(), line) {
+			t.Fatalf("verify-hash report %q, want a line %q", report.String(), line)
+		}
+	}
+
+	if err := runCLI(t, "decode", input, "-", "--sidecar"); err == nil {
+		t.Fatal("decode --sidecar accepted stdout")
+	}
+}
+
+func TestBatch_Sidecar(t *testing.T) {
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	input := writeStereo(t, inputDir, 8000)
+
+	if err := runCLI(t, "batch", inputDir, outputDir, "--sidecar"); err != nil {
+		t.Fatalf("batch --sidecar error = %v", err)
+	}
+	output := filepath.Join(outputDir, "input.wav")
+	record, err := batch.LoadSidecar(batch.SidecarPath(output))
+	if err != nil {
+		t.Fatalf("LoadSidecar() error = %v", err)
+	}
+	if record.Command != "go-sq-tool batch" || !slices.Equal(record.Args, []string{input, output}) || record.Config["block_size"] != "1024" {
+		t.Fatalf("batch sidecar = %+v", record)
+	}
+	checkHash(t, record.Inputs[0], input)
+	checkHash(t, record.Output, output)
+	if err := runCLI(t, "verify-hash", output); err != nil {
+		t.Fatalf("verify-hash error = %v", err)
+	}
+}
+
+// checkHash fails unless got records path with its current hash.
+func checkHash(t *testing.T, got batch.FileHash, path string) {
+	t.Helper()
+	hash, err := batch.HashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Path != path || got.SHA256 != hash {
+		t.Fatalf("sidecar records %s %s, want %s %s", got.Path, got.SHA256, path, hash)
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/spf13/pflag"
+)
+
+// strictOutputs is --strict: with several audio outputs, one that fails
+// fails the run instead of being removed while the others are finished.
+var strictOutputs bool
+
+func addStrictFlag(flags *pflag.FlagSet) {
+	flags.BoolVar(&strictOutputs, "strict", false, "fail the run when any output fails instead of finishing the others")
+}
+
+// audioOptions are the options of an audio output in --output: a sample
+// format overriding --bit-depth, and a stereo downmix of the quad output.
+var audioOptions = []string{"pcm8", "pcm16", "pcm24", "float32", "stereo"}
+
+// pairOptions are the options of the front and back outputs of decode,
+// which take the sample format only.
+var pairOptions = audioOptions[:4]
+
+// pairFirst gives the first quad channel each stereo pair output takes.
+var pairFirst = map[string]int{"front": 0, "back": 2}
+
+// downmixGain is the level (-3 dB) at which each back channel is added to
+// the front of its side in the stereo downmix.
+const downmixGain = math.Sqrt2 / 2
+
+// audioOutput is one WAV written from the processed stream.
+type audioOutput struct {
+	id, path string
+	// file is nil until the outputs are created.
+	file     *os.File
+	options  wav.WriteOptions
+	channels int
+	// downmix, if set, writes the stereo downmix of a stream in this back
+	// channel mode.
+	downmix *decoder.BackChannelMode
+	// pair, if set, writes the stereo pair from channel first on of the
+	// discrete channels of a stream in this back channel mode.
+	pair  *decoder.BackChannelMode
+	first int
+}
+
+// audioOutputs returns the main output, the extra ones and the front and
+// back pairs of outputs, in that order. Each starts from the global output flags, overridden by its
+// own options; channels is the channel count of the processed stream.
+func audioOutputs(outputs *artifact.Set, channels int) ([]audioOutput, error) {
+	base, err := writeOptions()
+	if err != nil {
+		return nil, err
+	}
+	var outs []audioOutput
+	for _, entry := range audioEntries(outputs) {
+		out := audioOutput{
+			id:       entry.ID,
+			path:     entry.Path,
+			file:     outputs.File(entry.ID),
+			options:  base,
+			channels: channels,
+		}
+		if first, ok := pairFirst[entry.ID]; ok {
+			if channels < 4 {
+				return nil, fmt.Errorf("output %s: the front/back split needs the quad output, got %d channels", entry.ID, channels)
+			}
+			mode, err := decoder.ParseBackChannelMode(backMode)
+			if err != nil {
+				return nil, err
+			}
+			out.pair, out.first, out.channels = &mode, first, 2
+		}
+		var formats []string
+		for _, option := range entry.Options {
+			switch option {
+			case "pcm8":
+				out.options.Format = wav.FormatPCM8
+			case "pcm16":
+				out.options.Format = wav.FormatPCM16
+			case "pcm24":
+				out.options.Format = wav.FormatPCM24
+			case "float32":
+				out.options.Format = wav.FormatFloat32
+			case "stereo":
+				if channels < 4 {
+					return nil, fmt.Errorf("output %s: a stereo downmix needs the quad output, got %d channels", entry.ID, channels)
+				}
+				mode, err := decoder.ParseBackChannelMode(backMode)
+				if err != nil {
+					return nil, err
+				}
+				out.downmix, out.channels = &mode, 2
+				continue
+			}
+			formats = append(formats, option)
+		}
+		if len(formats) > 1 {
+			return nil, fmt.Errorf("output %s: conflicting formats %s", entry.ID, strings.Join(formats, ", "))
+		}
+		outs = append(outs, out)
+	}
+	return outs, nil
+}
+
+// sink returns the sink writing the processed stream to writer.
+func (o *audioOutput) sink(writer audioWriter) pipeline.Sink {
+	switch {
+	case o.downmix != nil:
+		return downmixSink{sink: writer, mode: *o.downmix}
+	case o.pair != nil:
+		return pairSink{sink: writer, mode: *o.pair, first: o.first}
+	}
+	return writer
+}
+
+// audioEntries returns the audio output entries of outputs: main, extra,
+// front and back.
+func audioEntries(outputs *artifact.Set) []artifact.Entry {
+	return slices.Concat(outputs.Entries("main"), outputs.Entries("extra"), outputs.Entries("front"), outputs.Entries("back"))
+}
+
+// downmixSink writes the stereo downmix of a decoded stream.
+type downmixSink struct {
+	sink pipeline.Sink
+	mode decoder.BackChannelMode
+}
+
+// WriteFrames implements pipeline.Sink.
+func (d downmixSink) WriteFrames(frames [][]float64) error {
+	quad := discreteChannels(frames, d.mode)
+	left := make([]float64, len(quad[0]))
+	right := make([]float64, len(quad[1]))
+	for i := range left {
+		left[i] = quad[0][i] + downmixGain*quad[2][i]
+		right[i] = quad[1][i] + downmixGain*quad[3][i]
+	}
+	return d.sink.WriteFrames([][]float64{left, right})
+}
+
+// pairSink writes two adjacent discrete channels of a decoded stream, LF/RF
+// or LB/RB.
+type pairSink struct {
+	sink  pipeline.Sink
+	mode  decoder.BackChannelMode
+	first int
+}
+
+// WriteFrames implements pipeline.Sink.
+func (p pairSink) WriteFrames(frames [][]float64) error {
+	return p.sink.WriteFrames(discreteChannels(frames, p.mode)[p.first : p.first+2])
+}
+
+// writtenAudio lists the audio outputs that were written, for the summary
+// of a run.
+func writtenAudio(outputs *artifact.Set) string {
+	return strings.Join(audioPaths(outputs), ", ")
+}
+
+// audioPaths returns the paths of the audio outputs.
+func audioPaths(outputs *artifact.Set) []string {
+	var paths []string
+	for _, entry := range audioEntries(outputs) {
+		paths = append(paths, entry.Path)
+	}
+	return paths
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+func readChannels(t *testing.T, path string, channels int) [][]float64 {
+	t.Helper()
+	data, err := wav.ReadWAVChannels(path, channels)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	return data.Samples
+}
+
+func peakOf(samples [][]float64) float64 {
+	var peak float64
+	for _, ch := range samples {
+		for _, v := range ch {
+			peak = max(peak, math.Abs(v))
+		}
+	}
+	return peak
+}
+
+func TestDecode_MultipleOutputs(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	out := t.TempDir()
+	quad := filepath.Join(out, "quad.wav")
+	copyPath := filepath.Join(out, "copy.wav")
+	pcm := filepath.Join(out, "quad16.wav")
+	mix := filepath.Join(out, "mix.wav")
+
+	err := runCLI(t, "decode", input,
+		"--output", "main:float32="+quad,
+		"--output", "extra:float32="+copyPath+",extra="+pcm,
+		"--output", "extra:stereo:float32="+mix)
+	if err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if got := strings.Join(listDir(t, out), ","); got != "copy.wav,mix.wav,quad.wav,quad16.wav" {
+		t.Fatalf("outputs = %s", got)
+	}
+
+	want := readChannels(t, quad, 4)
+	copied := readChannels(t, copyPath, 4)
+	quantized := readChannels(t, pcm, 4)
+	downmix := readChannels(t, mix, 2)
+	for ch := range want {
+		for i, v := range want[ch] {
+			if copied[ch][i] != v {
+				t.Fatalf("copy channel %d frame %d = %v, want %v", ch, i, copied[ch][i], v)
+			}
+			if d := math.Abs(quantized[ch][i] - v); d > 1.0/32768 {
+				t.Fatalf("16-bit channel %d frame %d = %v, want %v", ch, i, quantized[ch][i], v)
+			}
+		}
+	}
+	for side := range downmix {
+		for i, v := range downmix[side] {
+			front, back := want[side][i], want[side+2][i]
+			if d := math.Abs(v - (front + math.Sqrt2/2*back)); d > 1e-6 {
+				t.Fatalf("downmix channel %d frame %d = %v, want %v", side, i, v, front+math.Sqrt2/2*back)
+			}
+		}
+	}
+}
+
+func TestDecode_SplitFrontBack(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	out := t.TempDir()
+	quad := filepath.Join(out, "quad.wav")
+	front := filepath.Join(out, "front.wav")
+	back := filepath.Join(out, "back.wav")
+
+	if err := runCLI(t, "decode", input, quad, "--split-fb", front+","+back); err != nil {
+		t.Fatalf("decode --split-fb error = %v", err)
+	}
+	want, err := wav.ReadWAVChannels(quad, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for first, path := range []string{front, back} {
+		pair, err := wav.ReadWAVChannels(path, 2)
+		if err != nil {
+			t.Fatalf("reading %s: %v", path, err)
+		}
+		if pair.SampleRate != want.SampleRate {
+			t.Fatalf("%s sample rate = %d, want %d", path, pair.SampleRate, want.SampleRate)
+		}
+		for ch := range pair.Samples {
+			for i, v := range pair.Samples[ch] {
+				if v != want.Samples[2*first+ch][i] {
+					t.Fatalf("%s channel %d frame %d = %v, want %v", path, ch, i, v, want.Samples[2*first+ch][i])
+				}
+			}
+		}
+	}
+
+	// The pairs alone, without the quad file.
+	split := t.TempDir()
+	if err := runCLI(t, "decode", input, "--split-fb", filepath.Join(split, "f.wav")+","+filepath.Join(split, "b.wav")); err != nil {
+		t.Fatalf("decode --split-fb without main error = %v", err)
+	}
+	if got := strings.Join(listDir(t, split), ","); got != "b.wav,f.wav" {
+		t.Fatalf("outputs = %s", got)
+	}
+	if err := runCLI(t, "decode", input, quad, "--split-fb", front); err == nil {
+		t.Fatal("decode accepted --split-fb with one path")
+	}
+}
+
+func TestDecode_FailedOutputKeepsOthers(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	out := t.TempDir()
+	quad := filepath.Join(out, "quad.wav")
+	mix := filepath.Join(out, "mix.wav")
+
+	// Raise the output until the quad output peaks just below full scale,
+	// where the downmix clips.
+	if err := runCLI(t, "decode", input, "--bit-depth", "32f", "--output", "main="+quad+",extra:stereo="+mix); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	quadPeak, mixPeak := peakOf(readChannels(t, quad, 4)), peakOf(readChannels(t, mix, 2))
+	if mixPeak < 1.1*quadPeak {
+		t.Fatalf("downmix peak %v is not above the quad peak %v", mixPeak, quadPeak)
+	}
+	gain := fmt.Sprint(20 * math.Log10(0.95/quadPeak))
+
+	args := []string{"decode", input, "--bit-depth", "32f", "--error-on-clip", "--output-gain", gain, "--output", "main=" + quad + ",extra:stereo=" + mix}
+	if err := runCLI(t, args...); err != nil {
+		t.Fatalf("decode error = %v, want the clipping output dropped", err)
+	}
+	if got := strings.Join(listDir(t, out), ","); got != "quad.wav" {
+		t.Fatalf("outputs = %s, want quad.wav", got)
+	}
+	if peak := peakOf(readChannels(t, quad, 4)); math.Abs(peak-0.95) > 1e-3 {
+		t.Fatalf("quad peak = %v, want 0.95", peak)
+	}
+
+	if err := runCLI(t, append(args, "--strict")...); err == nil || !strings.Contains(err.Error(), "clipped") {
+		t.Fatalf("decode --strict error = %v, want clipping", err)
+	}
+	if names := listDir(t, out); len(names) != 0 {
+		t.Fatalf("failed strict decode left %v behind", names)
+	}
+}
+
+func TestDecode_OutputOptionsRejected(t *testing.T) {
+	dir := t.TempDir()
+	input := writeStereo(t, dir, 8000)
+	out := t.TempDir()
+	mainPath := filepath.Join(out, "out.wav")
+
+	tests := []struct {
+		name, spec, want string
+	}{
+		{"unknown option", "main:mp3=" + mainPath, `unknown option "mp3"`},
+		{"conflicting formats", "main:pcm8:float32=" + mainPath, "conflicting formats"},
+		{"option on report", "main=" + mainPath + ",image:stereo=" + filepath.Join(out, "i.txt"), "takes no options"},
+	}
+	for _, tt := range tests {
+		err := runCLI(t, "decode", input, "--output", tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+		if names := listDir(t, out); len(names) != 0 {
+			t.Fatalf("%s: left %v behind", tt.name, names)
+		}
+	}
+}
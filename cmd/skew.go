@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/internal/skew"
+	"github.com/spf13/pflag"
+)
+
+// fixSkew and skewMicros are the --fix-skew and --skew-us settings of
+// decode.
+var (
+	fixSkew    bool
+	skewMicros float64
+)
+
+func addSkewFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&fixSkew, "fix-skew", false, "estimate the time offset of RT against LT (azimuth error) and remove it before decoding")
+	flags.Float64Var(&skewMicros, "skew-us", 0, "remove this offset of RT against LT in µs (positive when RT lags) instead of estimating it")
+}
+
+// correctSkew wraps the source of input, read from inputFile, with the skew
+// correction selected by the flags. A nonzero --skew-us is used as is;
+// --fix-skew estimates the offset in a first pass over the file and, if the
+// estimate fails, decodes uncorrected. The value, not whether the flag was
+// set, selects the manual offset, so the 0 a loaded profile sets does not
+// override --fix-skew.
+func correctSkew(inputFile string, input *streamInput) error {
+	manual := skewMicros != 0
+	if !fixSkew && !manual {
+		return nil
+	}
+	if math.IsNaN(skewMicros) || math.IsInf(skewMicros, 0) {
+		return fmt.Errorf("invalid --skew-us %g", skewMicros)
+	}
+
+	seconds := skewMicros * 1e-6
+	if !manual {
+		estimate, err := estimateSkew(inputFile)
+		if err != nil {
+			logger.Warn("skew estimate failed; decoding uncorrected", "error", err)
+			return nil
+		}
+		seconds = estimate
+	}
+	input.source = skew.CorrectSource(input.source, seconds, int(input.sampleRate))
+	logger.Info("skew correction",
+		"skew_us", seconds*1e6,
+		"skew_samples", seconds*float64(input.sampleRate),
+		"estimated", !manual)
+	return nil
+}
+
+// estimateSkew reads inputFile to the end and estimates the offset of RT
+// against LT in seconds.
+func estimateSkew(inputFile string) (float64, error) {
+	input, err := openStream(inputFile, 2)
+	if err != nil {
+		return 0, err
+	}
+	defer input.Close()
+
+	estimator, err := skew.NewEstimator(int(input.sampleRate))
+	if err != nil {
+		return 0, err
+	}
+	buf := [][]float64{make([]float64, 65536), make([]float64, 65536)}
+	for {
+		n, err := input.source.ReadFrames(buf)
+		estimator.Add([][]float64{buf[0][:n], buf[1][:n]})
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return estimator.Skew()
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withStdio runs fn with standard input read from the file in and standard
+// output written to the file out.
+func withStdio(t *testing.T, in, out string, fn func() error) error {
+	t.Helper()
+	stdin, err := os.Open(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	stdout, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+
+	savedIn, savedOut := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+	defer func() { os.Stdin, os.Stdout = savedIn, savedOut }()
+	return fn()
+}
+
+// unsizeWAV rewrites the WAV file at path as a writer to a pipe does, with
+// the RIFF and data sizes left at 0xFFFFFFFF.
+func unsizeWAV(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := bytes.Index(data, []byte("data"))
+	if i < 0 {
+		t.Fatalf("%s has no data chunk", path)
+	}
+	binary.LittleEndian.PutUint32(data[4:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(data[i+4:], 0xFFFFFFFF)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStdio(t *testing.T) {
+	dir := t.TempDir()
+	stereo, quad := writeStereo(t, dir, 9000), writeQuad(t, dir, 9000)
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+	}{
+		{"decode", []string{"decode"}, stereo},
+		// --adaptive opens the input twice.
+		{"decode --adaptive", []string{"decode", "--adaptive"}, stereo},
+		{"encode", []string{"encode"}, quad},
+	}
+	for _, tt := range tests {
+		want := filepath.Join(dir, "want.wav")
+		if err := runCLI(t, append(tt.args, tt.input, want)...); err != nil {
+			t.Fatalf("%s error = %v", tt.name, err)
+		}
+		// Piped input does not know its length.
+		piped := filepath.Join(dir, "piped.wav")
+		data, err := os.ReadFile(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(piped, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		unsizeWAV(t, piped)
+
+		got := filepath.Join(dir, "got.wav")
+		err = withStdio(t, piped, got, func() error {
+			return runCLI(t, append(tt.args, "-", "-")...)
+		})
+		if err != nil {
+			t.Fatalf("%s - - error = %v", tt.name, err)
+		}
+		gotData, err := os.ReadFile(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantData, err := os.ReadFile(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Nothing but the WAV file may reach stdout.
+		if !bytes.Equal(gotData, wantData) {
+			t.Fatalf("%s - - wrote %d bytes to stdout, want the %d bytes of the file output", tt.name, len(gotData), len(wantData))
+		}
+	}
+
+	err := withStdio(t, quad, filepath.Join(dir, "got.wav"), func() error {
+		return runCLI(t, "encode", "-", "-", "--verify")
+	})
+	if err == nil || !strings.Contains(err.Error(), "not stdout") {
+		t.Fatalf("encode - - --verify error = %v, want a stdout error", err)
+	}
+	if err := runCLI(t, "decode", stereo, "x.wav", "--output", "debug=-"); err == nil {
+		t.Fatalf("decode accepted a debug directory on stdout")
+	}
+}
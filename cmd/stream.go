@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+	"github.com/cwbudde/go-sq-tool/internal/audiofile"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// streamInput is an open audio input. WAV files are decoded chunk by chunk;
+// other formats are decoded up front and served from memory.
+type streamInput struct {
+	file       io.ReadSeekCloser
+	source     pipeline.Source
+	format     audiofile.Format
+	sampleRate uint32
+	numFrames  int
+	// loops and cues are the WAV loop and cue points, carried over to the
+	// output.
+	loops []wav.Loop
+	cues  []wav.Cue
+	// markers are cue points of the processed input, such as
+	// --debug-markers, written after those of the input.
+	markers []wav.Cue
+	// lead ([channel][frame]) is written to the output ahead of the
+	// processed input, such as encode --ref-tone.
+	lead [][]float64
+	// info is written to the INFO chunk of every audio output.
+	info wav.InfoTags
+	// gained marks a source that applies --input-gain itself, such as the
+	// decode preview, which applies it ahead of its decoders.
+	gained bool
+}
+
+// leadFrames returns the length of in.lead.
+func (s *streamInput) leadFrames() int {
+	if len(s.lead) == 0 {
+		return 0
+	}
+	return len(s.lead[0])
+}
+
+// stdinInput is the input path that reads standard input.
+const stdinInput = "-"
+
+// stdinData caches standard input, read whole on first use: it cannot be
+// rewound to scan the loop points and is opened again by passes such as
+// the --adaptive analysis.
+var stdinData []byte
+
+// openInput opens the input file, or standard input for "-".
+func openInput(filename string) (io.ReadSeekCloser, error) {
+	if filename != stdinInput {
+		return os.Open(filename)
+	}
+	if stdinData == nil {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read stdin: %w", err)
+		}
+		stdinData = data
+	}
+	return nopCloser{bytes.NewReader(stdinData)}, nil
+}
+
+// nopCloser is a ReadSeeker with a Close method that does nothing.
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+func openStream(filename string, channels int) (*streamInput, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
+	}
+
+	br := bufio.NewReader(file)
+	header, _ := br.Peek(audiofile.HeaderSize)
+	format, err := audiofile.Detect(filename, header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	noteExtension(filename, format)
+
+	in := &streamInput{file: file, format: format}
+	if format.Streamed() {
+		// The smpl and cue chunks may follow the data, so find them before
+		// streaming.
+		if err := scanMarkers(in, filename); err != nil {
+			file.Close()
+			return nil, err
+		}
+		br.Reset(file)
+
+		reader, err := wav.NewReader(br, channels)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read WAV: %w", err)
+		}
+		in.sampleRate = reader.SampleRate()
+		if reader.NumFrames() < 0 {
+			// A WAV stream written without knowing its length, typically to
+			// a pipe, is read to its end up front to learn the frame count.
+			data, err := readAllFrames(reader, channels)
+			file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read WAV: %w", err)
+			}
+			in.source = pipeline.NewSliceSource(data)
+			in.numFrames = len(data[0])
+			return in, nil
+		}
+		in.source = reader
+		in.numFrames = reader.NumFrames()
+		return in, nil
+	}
+
+	data, err := audiofile.Decode(br, format, channels)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	in.source = pipeline.NewSliceSource(data.Samples)
+	in.sampleRate = data.SampleRate
+	in.numFrames = data.NumSamples
+	return in, nil
+}
+
+// readAudio reads a whole input file, detecting its format from its
+// contents.
+func readAudio(filename string, channels int) (*wav.AudioData, audiofile.SourceInfo, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, audiofile.SourceInfo{}, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+
+	data, info, err := audiofile.OpenReader(file, filename, channels)
+	if err == nil {
+		noteExtension(filename, info.Format)
+		logger.Debug("read input", "path", filename, "source", info.String())
+	}
+	return data, info, err
+}
+
+// noteExtension logs when the extension of an input disagrees with the
+// format detected from its contents, such as AIFF saved as .wav.
+func noteExtension(filename string, format audiofile.Format) {
+	if !format.MatchesExtension(filename) {
+		logger.Info("file extension does not match its contents", "path", filename, "format", format.Name)
+	}
+}
+
+// readAllFrames reads every remaining frame of reader.
+func readAllFrames(reader *wav.Reader, channels int) ([][]float64, error) {
+	data := make([][]float64, channels)
+	buf := make([][]float64, channels)
+	for ch := range buf {
+		buf[ch] = make([]float64, 1<<16)
+	}
+	for {
+		n, err := reader.ReadFrames(buf)
+		for ch := range data {
+			data[ch] = append(data[ch], buf[ch][:n]...)
+		}
+		if errors.Is(err, io.EOF) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// scanMarkers reads the loop and cue points of the WAV input at path and
+// rewinds the file. Malformed metadata is logged and dropped rather than
+// failing.
+func scanMarkers(in *streamInput, path string) error {
+	if _, err := in.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind audio file: %w", err)
+	}
+	loops, err := wav.ScanLoops(in.file)
+	if err != nil {
+		logger.Warn("ignoring loop points", "path", path, "error", err)
+	}
+	in.loops = loops
+	if _, err := in.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind audio file: %w", err)
+	}
+	cues, err := wav.ScanCues(in.file)
+	if err != nil {
+		logger.Warn("ignoring cue points", "path", path, "error", err)
+	}
+	in.cues = cues
+	if _, err := in.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind audio file: %w", err)
+	}
+	return nil
+}
+
+func (s *streamInput) Close() error {
+	return s.file.Close()
+}
+
+// streamProcess pipelines every frame of in through process into the
+// audio outputs of outputs (the main output and any extra ones) using the
+// global output flags, their own options and the chunking of cfg. Reading,
+// processing and writing run concurrently, --input-gain and --output-gain
+// are applied around process, and loop and cue points of the input are
+// carried over, followed by in.markers. in.lead, if any, is written first,
+// unprocessed. An output that fails is removed while the others are
+// finished, unless --strict is set or no output is left. On SIGINT the
+// stages drain and context.Canceled is returned; the caller removes the
+// incomplete outputs.
+func streamProcess(in *streamInput, inChannels int, outputs *artifact.Set, outChannels int, process pipeline.Processor, cfg pipeline.Config) error {
+	outs, err := audioOutputs(outputs, outChannels)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return runStream(ctx, in, inChannels, outputs, outs, process, cfg)
+}
+
+func runStream(ctx context.Context, in *streamInput, inChannels int, outputs *artifact.Set, outs []audioOutput, process pipeline.Processor, cfg pipeline.Config) error {
+	loops := shiftLoops(in.loops, in.leadFrames())
+	cues := shiftCues(slices.Concat(in.cues, in.markers), in.leadFrames())
+	writers := make([]audioWriter, len(outs))
+	sinks := make([]pipeline.Sink, len(outs))
+	for i, out := range outs {
+		options := out.options
+		options.Loops = loops
+		options.Cues = cues
+		options.Info = in.info
+		writer, err := newAudioWriter(out.path, out.file, in.sampleRate, out.channels, in.leadFrames()+in.numFrames, options)
+		if err != nil {
+			return err
+		}
+		writers[i], sinks[i] = writer, out.sink(writer)
+	}
+	fan, err := pipeline.NewFanoutSink(sinks, strictOutputs)
+	if err != nil {
+		return err
+	}
+	if in.lead != nil {
+		if err := fan.WriteFrames(in.lead); err != nil {
+			return err
+		}
+	}
+	cfg.Logger = logger
+	src := in.source
+	if !in.gained {
+		src = pipeline.GainSource(src, inputGain)
+	}
+	process = pipeline.GainProcessor(process, outputGain)
+	if err := pipeline.Run(ctx, src, inChannels, fan, process, cfg); err != nil {
+		return err
+	}
+
+	errs := make([]error, len(outs))
+	var failed []error
+	for i, out := range outs {
+		errs[i] = fan.Err(i)
+		if errs[i] == nil {
+			errs[i] = writers[i].Close()
+		}
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+			continue
+		}
+		warnClipped(out.path, writers[i])
+	}
+	if len(failed) > 0 && (strictOutputs || len(failed) == len(outs)) {
+		return errors.Join(failed...)
+	}
+	for i, out := range outs {
+		if errs[i] != nil {
+			logger.Warn("output failed, removed", "path", out.path, "error", errs[i])
+			outputs.Discard(out.id)
+		}
+	}
+	return nil
+}
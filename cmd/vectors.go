@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cwbudde/go-sq-tool/internal/golden"
+	"github.com/spf13/cobra"
+)
+
+var genVectorsCmd = &cobra.Command{
+	Use:   "gen-vectors",
+	Short: "Regenerate the golden regression fingerprints (maintenance)",
+	Long: `Encodes and decodes the built-in deterministic test vectors at the pinned
+configuration and writes their fingerprints (per-block RMS and a hash of the
+16-bit samples) to the reference file used by the regression test.
+
+Run it from the repository root after an intentional algorithm change. The
+differences to the previous reference are listed before it is overwritten
+so they can be reviewed with the change.`,
+	Args:   cobra.NoArgs,
+	Hidden: true,
+	RunE:   runGenVectors,
+}
+
+var vectorsPath string
+
+func init() {
+	genVectorsCmd.Flags().StringVar(&vectorsPath, "out", golden.DefaultPath, "fingerprint file to write")
+}
+
+func runGenVectors(cmd *cobra.Command, args []string) error {
+	set, err := golden.Generate(golden.DefaultConfig())
+	if err != nil {
+		return fmt.Errorf("failed to generate vectors: %w", err)
+	}
+
+	previous, err := golden.Load(vectorsPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		fmt.Printf("No previous reference at %s\n", vectorsPath)
+	case err != nil:
+		return err
+	default:
+		diffs := golden.Compare(previous, set)
+		if len(diffs) == 0 {
+			fmt.Printf("Fingerprints unchanged (%d signals)\n", len(set.Fingerprints))
+			return nil
+		}
+		fmt.Printf("Changes to the previous reference:\n")
+		for _, diff := range diffs {
+			fmt.Printf("  %s\n", diff)
+		}
+	}
+
+	if err := golden.Save(vectorsPath, set); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d fingerprints to %s\n", len(set.Fingerprints), vectorsPath)
+	return nil
+}
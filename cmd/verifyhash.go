@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+	"github.com/spf13/cobra"
+)
+
+var verifyHashCmd = &cobra.Command{
+	Use:   "verify-hash <file>...",
+	Short: "Check outputs against the hashes in their sidecars",
+	Long: `Hash every file and compare it with the SHA-256 recorded in its sidecar,
+the <file>.json that decode, encode and batch write with --sidecar. Each
+file is reported as OK or FAILED with the reason; the command fails if any
+file is missing, has no readable sidecar or changed since it was written.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return verifyHashes(cmd.OutOrStdout(), args)
+	},
+}
+
+// verifyHashes checks every file of paths against its sidecar and reports
+// the results to w.
+func verifyHashes(w io.Writer, paths []string) error {
+	failed := 0
+	for _, path := range paths {
+		err := verifySidecar(path)
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "FAILED  %s: %v\n", path, err)
+			continue
+		}
+		fmt.Fprintf(w, "OK      %s\n", path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed verification", failed, len(paths))
+	}
+	return nil
+}
+
+// verifySidecar checks the file at path against its sidecar.
+func verifySidecar(path string) error {
+	record, err := batch.LoadSidecar(batch.SidecarPath(path))
+	if err != nil {
+		return err
+	}
+	return record.VerifyOutput(path)
+}
@@ -0,0 +1,444 @@
+// Package artifact parses and manages the output files of a command.
+//
+// A command registers the kinds of artifact it can write (the main WAV, a
+// report, a directory of debug taps, ...) and the user selects them with a
+// single spec such as "main=out.wav,image=image.csv,debug=taps/". An entry
+// may qualify its kind with options, as in "extra:stereo=mix.wav", and
+// repeatable kinds may be selected several times. The set is validated
+// before any processing starts, creates its artifacts in registry order and
+// removes them again if the command fails.
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Stdout is the path that selects standard output for a file artifact.
+const Stdout = "-"
+
+// Kind describes one artifact a command can write.
+type Kind struct {
+	Name string
+	// Dir marks artifacts that are a directory of files rather than a
+	// single file.
+	Dir bool
+	// Repeat allows the artifact to be selected more than once, each time
+	// with its own path and options.
+	Repeat bool
+	// Options lists the options an entry may give as kind:option=path.
+	Options []string
+	Usage   string
+}
+
+// Entry is one selected artifact. ID names it in the set: the kind name for
+// the first entry of a kind, the name and a count ("extra#2") for further
+// entries of a repeatable one.
+type Entry struct {
+	ID      string
+	Path    string
+	Options []string
+}
+
+// Set is the selection of artifacts of one command run.
+type Set struct {
+	kinds []Kind
+	// entries holds the selected entries of every kind name in the order
+	// they were given.
+	entries map[string][]Entry
+	files   map[string]*os.File
+	// created lists every path the set created, in creation order, and
+	// dirs the directories among them.
+	created []string
+	dirs    map[string]bool
+	// stdout is standard output once a file artifact was created on it;
+	// it is neither closed nor removed by the set.
+	stdout *os.File
+}
+
+// Parse parses a comma-separated list of kind=path entries against the
+// kinds a command supports. An empty spec selects nothing.
+func Parse(spec string, kinds []Kind) (*Set, error) {
+	s := &Set{
+		kinds:   kinds,
+		entries: make(map[string][]Entry),
+		files:   make(map[string]*os.File),
+		dirs:    make(map[string]bool),
+	}
+	if strings.TrimSpace(spec) == "" {
+		return s, nil
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		name, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid output %q (use kind=path)", strings.TrimSpace(entry))
+		}
+		name, options, _ := strings.Cut(name, ":")
+		var opts []string
+		if options != "" {
+			opts = strings.Split(options, ":")
+		}
+		if err := s.add(strings.TrimSpace(name), opts, strings.TrimSpace(path)); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add selects the artifact name at path, with the same checks as Parse.
+// Commands use it to merge positional arguments and older per-artifact
+// flags into the set.
+func (s *Set) Add(name, path string) error {
+	return s.add(name, nil, path)
+}
+
+func (s *Set) add(name string, options []string, path string) error {
+	kind, ok := s.kind(name)
+	if !ok {
+		return fmt.Errorf("unknown output %q (available: %s)", name, strings.Join(s.names(), ", "))
+	}
+	for i, option := range options {
+		options[i] = strings.TrimSpace(option)
+		if !slices.Contains(kind.Options, options[i]) {
+			if len(kind.Options) == 0 {
+				return fmt.Errorf("output %s takes no options, got %q", name, options[i])
+			}
+			return fmt.Errorf("unknown option %q for output %s (available: %s)", options[i], name, strings.Join(kind.Options, ", "))
+		}
+	}
+	if path == "" {
+		return fmt.Errorf("output %s has an empty path", name)
+	}
+	if len(s.entries[name]) > 0 && !kind.Repeat {
+		return fmt.Errorf("output %s given more than once", name)
+	}
+	if kind.Dir && path == Stdout {
+		return fmt.Errorf("output %s is a directory and cannot be written to stdout", name)
+	}
+	if !kind.Dir && strings.HasSuffix(path, "/") {
+		return fmt.Errorf("output %s must be a file, got directory %s", name, path)
+	}
+	clean := filepath.Clean(path)
+	for _, entries := range s.entries {
+		for _, other := range entries {
+			if samePath(clean, other.Path) {
+				return fmt.Errorf("outputs %s and %s both write %s", other.ID, name, path)
+			}
+		}
+	}
+	id := name
+	if n := len(s.entries[name]); n > 0 {
+		id = fmt.Sprintf("%s#%d", name, n+1)
+	}
+	s.entries[name] = append(s.entries[name], Entry{ID: id, Path: clean, Options: options})
+	return nil
+}
+
+// Has reports whether the artifact name is selected.
+func (s *Set) Has(name string) bool {
+	return len(s.entries[name]) > 0
+}
+
+// Path returns the path of the artifact name, or "" if it is not selected.
+// For a repeatable artifact it is the path of the first entry.
+func (s *Set) Path(name string) string {
+	if !s.Has(name) {
+		return ""
+	}
+	return s.entries[name][0].Path
+}
+
+// WritesStdout reports whether an artifact is written to standard output,
+// so that the command reports to standard error instead.
+func (s *Set) WritesStdout() bool {
+	for _, sel := range s.selected() {
+		if sel.Path == Stdout {
+			return true
+		}
+	}
+	return false
+}
+
+// Entries returns the selected entries of the artifact name in the order
+// they were given.
+func (s *Set) Entries(name string) []Entry {
+	return s.entries[name]
+}
+
+// Validate checks up front that every selected artifact can be written:
+// files need an existing, writable parent directory and must not be a
+// directory themselves; directories must not be files and their nearest
+// existing ancestor must be writable.
+func (s *Set) Validate() error {
+	for _, sel := range s.selected() {
+		kind, path := sel.kind, sel.Path
+		if path == Stdout {
+			continue
+		}
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && kind.Dir && !info.IsDir():
+			return fmt.Errorf("output %s: %s is not a directory", sel.ID, path)
+		case err == nil && !kind.Dir && info.IsDir():
+			return fmt.Errorf("output %s: %s is a directory", sel.ID, path)
+		case err != nil && !errors.Is(err, os.ErrNotExist):
+			return fmt.Errorf("output %s: %w", sel.ID, err)
+		}
+
+		dir := filepath.Dir(path)
+		if kind.Dir {
+			dir = existingAncestor(path)
+		}
+		if err := checkWritable(dir); err != nil {
+			return fmt.Errorf("output %s: %w", sel.ID, err)
+		}
+	}
+	return nil
+}
+
+// Overwrites returns the ID of the selected file artifact that is the
+// existing file path, under any name, or "" if none is. Commands that read
+// an input while writing their outputs reject such a selection, since
+// creating the output truncates the input.
+func (s *Set) Overwrites(path string) string {
+	if path == Stdout {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	for _, sel := range s.selected() {
+		if sel.kind.Dir || sel.Path == Stdout {
+			continue
+		}
+		if out, err := os.Stat(sel.Path); err == nil && os.SameFile(info, out) {
+			return sel.ID
+		}
+	}
+	return ""
+}
+
+// Create creates every selected artifact in registry order: files are
+// created (or truncated) and directories made. Files are available from
+// File until Close.
+func (s *Set) Create() error {
+	for _, sel := range s.selected() {
+		if sel.kind.Dir {
+			if err := s.mkdirAll(sel.Path); err != nil {
+				return fmt.Errorf("failed to create output %s: %w", sel.ID, err)
+			}
+			continue
+		}
+		file, err := s.create(sel.Path)
+		if err != nil {
+			return fmt.Errorf("failed to create output %s: %w", sel.ID, err)
+		}
+		s.files[sel.ID] = file
+	}
+	return nil
+}
+
+// File returns the created file of the artifact or entry ID, or nil.
+func (s *Set) File(id string) *os.File {
+	return s.files[id]
+}
+
+// Discard closes and removes the file of the entry ID after Create and
+// drops the entry from the set, for an output that failed while the others
+// are kept.
+func (s *Set) Discard(id string) {
+	file := s.files[id]
+	if file == nil {
+		return
+	}
+	delete(s.files, id)
+	if file != s.stdout {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	s.created = slices.DeleteFunc(s.created, func(path string) bool { return path == file.Name() })
+	for name, entries := range s.entries {
+		s.entries[name] = slices.DeleteFunc(entries, func(entry Entry) bool { return entry.ID == id })
+	}
+}
+
+// CreateIn creates the file base inside the directory artifact name after
+// Create. It is closed and, on failure, removed together with the set.
+func (s *Set) CreateIn(name, base string) (*os.File, error) {
+	if !s.Has(name) || !s.dirs[s.Path(name)] {
+		return nil, fmt.Errorf("output %s is not a created directory", name)
+	}
+	file, err := s.create(filepath.Join(s.Path(name), base))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output %s: %w", name, err)
+	}
+	s.files[name+"/"+base] = file
+	return file, nil
+}
+
+// Close closes every created file. Unless complete is true, or if closing
+// fails, everything the set created is removed again, newest first;
+// directories only if they are empty by then.
+func (s *Set) Close(complete bool) error {
+	var err error
+	for _, file := range s.files {
+		if file == s.stdout {
+			continue
+		}
+		if closeErr := file.Close(); closeErr != nil && !errors.Is(closeErr, os.ErrClosed) && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", file.Name(), closeErr)
+		}
+	}
+	s.files = make(map[string]*os.File)
+	if !complete || err != nil {
+		s.Remove()
+	}
+	return err
+}
+
+// Remove deletes everything the set created, newest first.
+func (s *Set) Remove() {
+	for i := len(s.created) - 1; i >= 0; i-- {
+		os.Remove(s.created[i])
+	}
+	s.created = nil
+}
+
+// Paths returns the selected paths in registry order.
+func (s *Set) Paths() []string {
+	var paths []string
+	for _, sel := range s.selected() {
+		paths = append(paths, sel.Path)
+	}
+	return paths
+}
+
+// Usage describes the kinds for a flag's help text.
+func Usage(kinds []Kind) string {
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		usage := kind.Usage
+		if len(kind.Options) > 0 {
+			usage += "; options " + strings.Join(kind.Options, ", ")
+		}
+		if kind.Repeat {
+			usage += "; repeatable"
+		}
+		parts[i] = kind.Name + " (" + usage + ")"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (s *Set) create(path string) (*os.File, error) {
+	if path == Stdout {
+		s.stdout = os.Stdout
+		return s.stdout, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	s.created = append(s.created, path)
+	return file, nil
+}
+
+// mkdirAll creates path and its missing parents, recording each directory
+// it actually made.
+func (s *Set) mkdirAll(path string) error {
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		s.created = append(s.created, missing[i])
+	}
+	s.dirs[path] = true
+	return nil
+}
+
+func (s *Set) kind(name string) (Kind, bool) {
+	for _, kind := range s.kinds {
+		if kind.Name == name {
+			return kind, true
+		}
+	}
+	return Kind{}, false
+}
+
+func (s *Set) names() []string {
+	names := make([]string, len(s.kinds))
+	for i, kind := range s.kinds {
+		names[i] = kind.Name
+	}
+	return names
+}
+
+// selection is a selected entry together with its kind.
+type selection struct {
+	Entry
+	kind Kind
+}
+
+// selected returns the selected entries in registry order, those of one
+// kind in the order they were given.
+func (s *Set) selected() []selection {
+	var sels []selection
+	for _, kind := range s.kinds {
+		for _, entry := range s.entries[kind.Name] {
+			sels = append(sels, selection{Entry: entry, kind: kind})
+		}
+	}
+	return sels
+}
+
+func samePath(a, b string) bool {
+	if a == b {
+		return true
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			return path
+		}
+		path = filepath.Dir(path)
+	}
+}
+
+// checkWritable creates and removes a temporary file in dir.
+func checkWritable(dir string) error {
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("directory %s does not exist", dir)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	probe, err := os.CreateTemp(dir, ".sq-write-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
@@ -0,0 +1,286 @@
+package artifact_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/artifact"
+)
+
+var kinds = []artifact.Kind{
+	{Name: "main", Usage: "output WAV"},
+	{Name: "taps", Dir: true, Usage: "debug taps"},
+	{Name: "report", Usage: "report"},
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	s, err := artifact.Parse(" report = r.txt ,main=out.wav,taps=dir/", kinds)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if s.Path("main") != "out.wav" || s.Path("taps") != "dir" || s.Path("report") != "r.txt" {
+		t.Fatalf("paths = %q, %q, %q", s.Path("main"), s.Path("taps"), s.Path("report"))
+	}
+	// Registry order, not spec order.
+	if got := strings.Join(s.Paths(), ","); got != "out.wav,dir,r.txt" {
+		t.Fatalf("Paths() = %s, want out.wav,dir,r.txt", got)
+	}
+
+	empty, err := artifact.Parse("", kinds)
+	if err != nil || empty.Has("main") {
+		t.Fatalf("Parse(\"\") = %v, %v, want empty set", empty, err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec, want string
+	}{
+		{"out.wav", "use kind=path"},
+		{"stems=dir/", `unknown output "stems"`},
+		{"main=", "empty path"},
+		{"main=a.wav,main=b.wav", "more than once"},
+		{"main=a.wav,report=./a.wav", "both write"},
+		{"main=dir/", "must be a file"},
+		{"taps=-", "cannot be written to stdout"},
+	}
+	for _, tt := range tests {
+		_, err := artifact.Parse(tt.spec, kinds)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("Parse(%q) error = %v, want %q", tt.spec, err, tt.want)
+		}
+	}
+
+	s, _ := artifact.Parse("main=a.wav", kinds)
+	if err := s.Add("main", "b.wav"); err == nil {
+		t.Fatalf("Add() expected error for a second main output")
+	}
+}
+
+func TestParse_OptionsAndRepeat(t *testing.T) {
+	t.Parallel()
+
+	audio := []artifact.Kind{
+		{Name: "main", Options: []string{"stereo", "float32"}, Usage: "output WAV"},
+		{Name: "extra", Repeat: true, Options: []string{"stereo", "float32"}, Usage: "further WAV"},
+		{Name: "report", Usage: "report"},
+	}
+	s, err := artifact.Parse("extra:stereo=b.wav,main:float32=a.wav,extra: stereo :float32=c.wav", audio)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if main := s.Entries("main"); len(main) != 1 || main[0].Path != "a.wav" || strings.Join(main[0].Options, ",") != "float32" {
+		t.Fatalf("main entries = %+v", main)
+	}
+	extra := s.Entries("extra")
+	if len(extra) != 2 || extra[0].ID != "extra" || extra[1].ID != "extra#2" ||
+		strings.Join(extra[1].Options, ",") != "stereo,float32" {
+		t.Fatalf("extra entries = %+v", extra)
+	}
+	if got := strings.Join(s.Paths(), ","); got != "a.wav,b.wav,c.wav" {
+		t.Fatalf("Paths() = %s, want a.wav,b.wav,c.wav", got)
+	}
+
+	tests := []struct {
+		spec, want string
+	}{
+		{"main:mono=a.wav", `unknown option "mono"`},
+		{"report:stereo=r.txt", "takes no options"},
+		{"main=a.wav,main=b.wav", "more than once"},
+		{"extra=a.wav,extra:stereo=./a.wav", "both write"},
+	}
+	for _, tt := range tests {
+		_, err := artifact.Parse(tt.spec, audio)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("Parse(%q) error = %v, want %q", tt.spec, err, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		kind string
+		path string
+		ok   bool
+	}{
+		{"new file", "main", filepath.Join(dir, "out.wav"), true},
+		{"existing file", "main", file, true},
+		{"missing parent", "main", filepath.Join(dir, "missing", "out.wav"), false},
+		{"file is a directory", "main", dir, false},
+		{"nested new directory", "taps", filepath.Join(dir, "a", "b"), true},
+		{"directory is a file", "taps", file, false},
+		{"directory below a file", "taps", filepath.Join(file, "sub"), false},
+	}
+	for _, tt := range tests {
+		s, err := artifact.Parse("", kinds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Add(tt.kind, tt.path); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Validate(); (err == nil) != tt.ok {
+			t.Fatalf("%s: Validate() error = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestOverwrites(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.wav")
+	if err := os.Symlink(input, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"other file", "main=" + filepath.Join(dir, "out.wav"), ""},
+		{"same path", "main=" + input, "main"},
+		{"other spelling", "main=" + filepath.Join(dir, ".", "in.wav"), "main"},
+		{"symlink", "main=" + filepath.Join(dir, "out.wav") + ",report=" + link, "report"},
+		{"directory", "taps=" + dir, ""},
+		{"stdout", "main=-", ""},
+	}
+	for _, tt := range tests {
+		s, err := artifact.Parse(tt.spec, kinds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Overwrites(input); got != tt.want {
+			t.Fatalf("%s: Overwrites() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	s, err := artifact.Parse("main="+input, kinds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Overwrites(artifact.Stdout); got != "" {
+		t.Fatalf("Overwrites(stdin) = %q, want none", got)
+	}
+}
+
+func TestCreateClose(t *testing.T) {
+	t.Parallel()
+
+	for _, complete := range []bool{true, false} {
+		dir := t.TempDir()
+		spec := "main=" + filepath.Join(dir, "out.wav") +
+			",taps=" + filepath.Join(dir, "new", "taps") +
+			",report=" + filepath.Join(dir, "report.txt")
+		s, err := artifact.Parse(spec, kinds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Create(); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err := s.File("main").WriteString("data"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.CreateIn("taps", "tap.wav"); err != nil {
+			t.Fatalf("CreateIn() error = %v", err)
+		}
+		if _, err := s.CreateIn("report", "x"); err == nil {
+			t.Fatalf("CreateIn() expected error for a file output")
+		}
+		if err := s.Close(complete); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		paths := []string{"out.wav", "new/taps/tap.wav", "new/taps", "new", "report.txt"}
+		for _, path := range paths {
+			_, err := os.Stat(filepath.Join(dir, path))
+			if exists := err == nil; exists != complete {
+				t.Fatalf("complete = %v: %s exists = %v", complete, path, exists)
+			}
+		}
+		// The temporary directory itself was not created by the set.
+		if _, err := os.Stat(dir); err != nil {
+			t.Fatalf("removed pre-existing directory: %v", err)
+		}
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	repeated := []artifact.Kind{{Name: "main", Repeat: true, Usage: "output WAV"}}
+	s, err := artifact.Parse("main="+filepath.Join(dir, "a.wav")+",main="+filepath.Join(dir, "b.wav"), repeated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create(); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	s.Discard("main#2")
+	if s.File("main#2") != nil || len(s.Entries("main")) != 1 {
+		t.Fatalf("discarded entry still selected: %+v", s.Entries("main"))
+	}
+	if err := s.Close(true); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.wav")); err != nil {
+		t.Fatalf("kept output missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.wav")); err == nil {
+		t.Fatalf("discarded output still exists")
+	}
+}
+
+func TestStdout(t *testing.T) {
+	t.Parallel()
+
+	s, err := artifact.Parse("main=-,report="+filepath.Join(t.TempDir(), "r.txt"), kinds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.WritesStdout() {
+		t.Fatalf("WritesStdout() = false for main=-")
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err := s.Create(); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if s.File("main") != os.Stdout {
+		t.Fatalf("File(main) = %v, want os.Stdout", s.File("main"))
+	}
+	// A failed run removes the files but leaves standard output open.
+	if err := s.Close(false); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stdout.Stat(); err != nil {
+		t.Fatalf("Close() closed stdout: %v", err)
+	}
+
+	files, _ := artifact.Parse("main=out.wav", kinds)
+	if files.WritesStdout() {
+		t.Fatalf("WritesStdout() = true for a file output")
+	}
+}
@@ -0,0 +1,28 @@
+// Package assets holds the reference data embedded in the binary: head
+// related impulse responses for binaural rendering of the decoded quad
+// channels and high-shelf presets for the rear channels. The files under
+// data are written by gen; regenerate them with go generate.
+package assets
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+)
+
+//go:generate go run ./gen
+
+//go:embed data
+var data embed.FS
+
+func readJSON(name string, v any) error {
+	raw, err := data.ReadFile(path.Join("data", name))
+	if err != nil {
+		return fmt.Errorf("read embedded %s: %w", name, err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("parse embedded %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package assets_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/assets"
+)
+
+func TestHRIRs_EmbeddedFilesMatchIndex(t *testing.T) {
+	t.Parallel()
+
+	sets, err := assets.ListHRIRs()
+	if err != nil {
+		t.Fatalf("ListHRIRs() error = %v", err)
+	}
+	if len(sets) == 0 {
+		t.Fatalf("no embedded HRIR sets")
+	}
+	for _, set := range sets {
+		rates := map[int]bool{}
+		for _, file := range set.Files {
+			rates[file.SampleRate] = true
+			hrir, err := assets.LoadHRIR(set.Name, file.SampleRate)
+			if err != nil {
+				t.Fatalf("LoadHRIR(%q, %d) error = %v", set.Name, file.SampleRate, err)
+			}
+			for speaker := range hrir.Left {
+				if len(hrir.Left[speaker]) != file.Taps || len(hrir.Right[speaker]) != file.Taps {
+					t.Fatalf("%s at %d Hz: speaker %d has %d/%d taps, want %d",
+						set.Name, file.SampleRate, speaker, len(hrir.Left[speaker]), len(hrir.Right[speaker]), file.Taps)
+				}
+			}
+		}
+		if !rates[44100] || !rates[48000] {
+			t.Fatalf("%s: sample rates %v, want 44100 and 48000", set.Name, rates)
+		}
+	}
+}
+
+func TestLoadHRIR_NearEarLeads(t *testing.T) {
+	t.Parallel()
+
+	hrir, err := assets.LoadHRIR("spherical", 48000)
+	if err != nil {
+		t.Fatalf("LoadHRIR() error = %v", err)
+	}
+	// LF (speaker 0) is on the left: it must reach the left ear first and
+	// louder.
+	left, right := peak(hrir.Left[0]), peak(hrir.Right[0])
+	if left.at >= right.at || left.value <= right.value {
+		t.Fatalf("LF: left ear peak %g at %d, right ear %g at %d", left.value, left.at, right.value, right.at)
+	}
+}
+
+func TestLoadHRIR_ResamplesKeepingDCGain(t *testing.T) {
+	t.Parallel()
+
+	base, err := assets.LoadHRIR("spherical", 48000)
+	if err != nil {
+		t.Fatalf("LoadHRIR() error = %v", err)
+	}
+	hrir, err := assets.LoadHRIR("spherical", 96000)
+	if err != nil {
+		t.Fatalf("LoadHRIR() error = %v", err)
+	}
+	if hrir.SampleRate != 96000 || len(hrir.Left[0]) != 2*len(base.Left[0]) {
+		t.Fatalf("resampled to %d Hz with %d taps, want 96000 Hz with %d", hrir.SampleRate, len(hrir.Left[0]), 2*len(base.Left[0]))
+	}
+	for speaker := range hrir.Left {
+		if got, want := sum(hrir.Right[speaker]), sum(base.Right[speaker]); math.Abs(got-want) > 1e-3 {
+			t.Fatalf("speaker %d: DC gain %g, want %g", speaker, got, want)
+		}
+	}
+}
+
+func TestLoadHRIR_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := assets.LoadHRIR("kemar", 48000)
+	if err == nil || !strings.Contains(err.Error(), "spherical") {
+		t.Fatalf("LoadHRIR(unknown) error = %v, want one listing the sets", err)
+	}
+	if _, err := assets.LoadHRIR("spherical", 0); err == nil {
+		t.Fatalf("LoadHRIR(rate 0) expected error")
+	}
+}
+
+func TestEQPresets(t *testing.T) {
+	t.Parallel()
+
+	presets, err := assets.ListEQPresets()
+	if err != nil {
+		t.Fatalf("ListEQPresets() error = %v", err)
+	}
+	seen := map[string]bool{}
+	for _, preset := range presets {
+		if seen[preset.Name] {
+			t.Fatalf("duplicate preset %q", preset.Name)
+		}
+		seen[preset.Name] = true
+		if preset.FrequencyHz <= 0 || preset.Q <= 0 || math.Abs(preset.GainDB) > 12 {
+			t.Fatalf("preset %+v out of range", preset)
+		}
+	}
+	if !seen["flat"] {
+		t.Fatalf("missing flat preset")
+	}
+
+	if _, err := assets.LookupEQPreset("loud"); err == nil || !strings.Contains(err.Error(), "flat") {
+		t.Fatalf("LookupEQPreset(unknown) error = %v, want one listing the presets", err)
+	}
+}
+
+type peakSample struct {
+	at    int
+	value float64
+}
+
+func peak(ir []float64) peakSample {
+	var p peakSample
+	for i, v := range ir {
+		if math.Abs(v) > p.value {
+			p = peakSample{i, math.Abs(v)}
+		}
+	}
+	return p
+}
+
+func sum(ir []float64) float64 {
+	var s float64
+	for _, v := range ir {
+		s += v
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package assets
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// EQPreset is an embedded high-shelf correction for the rear channels.
+type EQPreset struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	FrequencyHz float64 `json:"frequency_hz"`
+	GainDB      float64 `json:"gain_db"`
+	Q           float64 `json:"q"`
+}
+
+// ListEQPresets returns the embedded EQ presets.
+func ListEQPresets() ([]EQPreset, error) {
+	var presets struct {
+		Presets []EQPreset `json:"presets"`
+	}
+	if err := readJSON("eq/presets.json", &presets); err != nil {
+		return nil, err
+	}
+	return presets.Presets, nil
+}
+
+// LookupEQPreset returns the EQ preset called name.
+func LookupEQPreset(name string) (EQPreset, error) {
+	presets, err := ListEQPresets()
+	if err != nil {
+		return EQPreset{}, err
+	}
+	names := make([]string, len(presets))
+	for i, preset := range presets {
+		if preset.Name == name {
+			return preset, nil
+		}
+		names[i] = preset.Name
+	}
+	return EQPreset{}, fmt.Errorf("unknown EQ preset %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Filter returns the preset's shelf at sampleRate. The corner frequency is
+// kept below 0.45 of the sample rate, so low-rate material still gets a
+// stable filter.
+func (p EQPreset) Filter(sampleRate float64) sqmath.Biquad {
+	return sqmath.NewHighShelf(sampleRate, math.Min(p.FrequencyHz, 0.45*sampleRate), p.GainDB, p.Q)
+}
@@ -0,0 +1,229 @@
+// Command gen writes the embedded HRIR sets and EQ presets of the assets
+// package. Run it with go generate in internal/assets; the output is
+// deterministic.
+//
+// The HRIRs come from the spherical head model of Brown and Duda ("A
+// structural model for binaural sound synthesis", 1998): a one-pole,
+// one-zero head shadow filter per ear and the interaural delay of a rigid
+// sphere. There is no pinna or torso model, so elevation and front/back
+// cues are weak; the sets are meant as a neutral headphone reference for
+// quad playback, not as a substitute for measured HRTFs.
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/cwbudde/go-sq-tool/internal/matrix"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+const (
+	speedOfSound = 343.0
+	// sincHalfWidth is the half length of the fractional delay kernel.
+	sincHalfWidth = 16
+	// level scales every response by -6 dB: the ipsilateral head shadow
+	// boosts highs by up to 6 dB, and the embedded float32 samples must stay
+	// within [-1, 1]. It also leaves headroom for four speakers summed into
+	// one ear.
+	level = 0.5
+)
+
+var sampleRates = []int{44100, 48000}
+
+// reflection is one early reflection of the room set, relative to the
+// direct sound of a speaker at azimuth.
+type reflection struct {
+	delay float64 // seconds after the direct sound
+	gain  float64
+	angle func(azimuth float64) float64
+}
+
+type set struct {
+	name        string
+	description string
+	headRadius  float64
+	duration    float64 // seconds
+	reflections []reflection
+}
+
+var sets = []set{
+	{
+		name:        "spherical",
+		description: "spherical head (8.75 cm radius), anechoic: head shadow and interaural delay only",
+		headRadius:  0.0875,
+		duration:    0.003,
+	},
+	{
+		name:        "spherical-room",
+		description: "spherical head in a small room: the anechoic set plus five early reflections for a more external image",
+		headRadius:  0.0875,
+		duration:    0.0125,
+		reflections: []reflection{
+			// Floor and ceiling arrive from the speaker's direction.
+			{0.0021, 0.45, func(a float64) float64 { return a }},
+			{0.0039, 0.35, func(a float64) float64 { return a }},
+			// Near side wall, from further out on the speaker's side.
+			{0.0052, 0.40, func(a float64) float64 { return math.Copysign(110, a) }},
+			// Front or back wall, mirrored front to back.
+			{0.0084, 0.30, func(a float64) float64 { return math.Copysign(180, a) - a }},
+			// Far side wall.
+			{0.0100, 0.20, func(a float64) float64 { return -math.Copysign(70, a) }},
+		},
+	},
+}
+
+type eqPreset struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	FrequencyHz float64 `json:"frequency_hz"`
+	GainDB      float64 `json:"gain_db"`
+	Q           float64 `json:"q"`
+}
+
+var eqPresets = []eqPreset{
+	{"flat", "no correction", 4000, 0, math.Sqrt2 / 2},
+	{"soft", "-2 dB above 4 kHz: tames surface noise, which SQ steers to the rears", 4000, -2, math.Sqrt2 / 2},
+	{"dark", "-4 dB above 3 kHz: for noisy transfers or bright rear speakers", 3000, -4, math.Sqrt2 / 2},
+	{"bright", "+2 dB above 6 kHz: for rear speakers with weak tweeters", 6000, 2, math.Sqrt2 / 2},
+}
+
+type hrirFile struct {
+	SampleRate int    `json:"sample_rate"`
+	Taps       int    `json:"taps"`
+	Path       string `json:"path"`
+}
+
+type hrirSet struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Files       []hrirFile `json:"files"`
+}
+
+func main() {
+	var index struct {
+		Sets []hrirSet `json:"sets"`
+	}
+	for _, s := range sets {
+		entry := hrirSet{Name: s.name, Description: s.description}
+		for _, rate := range sampleRates {
+			irs := s.render(float64(rate))
+			path := s.name + "-" + itoa(rate) + ".wav"
+			data := wav.NewAudioData(uint32(rate), irs)
+			if err := wav.WriteWAVWithOptions(filepath.Join("data", "hrir", path), data, len(irs), wav.WriteOptions{Format: wav.FormatFloat32}); err != nil {
+				log.Fatal(err)
+			}
+			entry.Files = append(entry.Files, hrirFile{SampleRate: rate, Taps: len(irs[0]), Path: path})
+		}
+		index.Sets = append(index.Sets, entry)
+	}
+	writeJSON(filepath.Join("data", "hrir", "index.json"), index)
+	writeJSON(filepath.Join("data", "eq", "presets.json"), struct {
+		Presets []eqPreset `json:"presets"`
+	}{eqPresets})
+}
+
+// render returns the impulse responses of the set at sampleRate, in the
+// channel order of the embedded files: LF left ear, LF right ear, RF left,
+// RF right, LB left, LB right, RB left, RB right.
+func (s set) render(sampleRate float64) [][]float64 {
+	taps := int(math.Ceil(s.duration * sampleRate))
+	var irs [][]float64
+	norm := 1.0
+	for _, r := range s.reflections {
+		norm += r.gain * r.gain
+	}
+	norm = level / math.Sqrt(norm)
+
+	for _, azimuth := range matrix.SpeakerAngles {
+		for _, ear := range []float64{-90, 90} {
+			ir := make([]float64, taps)
+			s.addPath(ir, sampleRate, azimuth, ear, 0, norm)
+			for _, r := range s.reflections {
+				s.addPath(ir, sampleRate, r.angle(azimuth), ear, r.delay, r.gain*norm)
+			}
+			fadeOut(ir, min(32, taps/4))
+			irs = append(irs, ir)
+		}
+	}
+	return irs
+}
+
+// addPath adds the response of one ear (at -90° or 90°) to a plane wave
+// from azimuth, delayed by delay seconds and scaled by gain.
+func (s set) addPath(ir []float64, sampleRate, azimuth, ear, delay, gain float64) {
+	// Angle between the source and the ear axis.
+	theta := math.Abs(wrap(azimuth-ear)) * math.Pi / 180
+	radiusTime := s.headRadius / speedOfSound
+
+	// Sphere delay, shifted so that it is never negative.
+	var sphereDelay float64
+	if theta < math.Pi/2 {
+		sphereDelay = -radiusTime * math.Cos(theta)
+	} else {
+		sphereDelay = radiusTime * (theta - math.Pi/2)
+	}
+	at := sincHalfWidth + (sphereDelay+radiusTime+delay)*sampleRate
+
+	// Fractional delay impulse.
+	impulse := make([]float64, len(ir))
+	for i := range impulse {
+		d := float64(i) - at
+		if math.Abs(d) < sincHalfWidth {
+			impulse[i] = sinc(d) * (0.42 + 0.5*math.Cos(math.Pi*d/sincHalfWidth) + 0.08*math.Cos(2*math.Pi*d/sincHalfWidth))
+		}
+	}
+
+	// Head shadow (alpha s + beta) / (s + beta), bilinear transform.
+	const alphaMin, thetaMin = 0.1, 150.0 * math.Pi / 180
+	alpha := (1 + alphaMin/2) + (1-alphaMin/2)*math.Cos(theta/thetaMin*math.Pi)
+	beta := 2 / radiusTime
+	k := 2 * sampleRate
+	b0, b1 := alpha*k+beta, beta-alpha*k
+	a0, a1 := k+beta, beta-k
+	var x1, y1 float64
+	for i, x := range impulse {
+		y := (b0*x + b1*x1 - a1*y1) / a0
+		x1, y1 = x, y
+		ir[i] += gain * y
+	}
+}
+
+func fadeOut(ir []float64, n int) {
+	for i := 0; i < n; i++ {
+		ir[len(ir)-n+i] *= 0.5 * (1 + math.Cos(math.Pi*float64(i+1)/float64(n)))
+	}
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+func wrap(angle float64) float64 {
+	angle = math.Mod(angle+180, 360)
+	if angle < 0 {
+		angle += 360
+	}
+	return angle - 180
+}
+
+func itoa(n int) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
+
+func writeJSON(path string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,125 @@
+package assets
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// hrirChannels is the channel count of an embedded HRIR file: a left and
+// right ear response for each of LF, RF, LB and RB, in that order.
+const hrirChannels = 8
+
+// HRIRInfo describes an embedded HRIR set.
+type HRIRInfo struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Files       []HRIRFile `json:"files"`
+}
+
+// HRIRFile is one embedded sample rate of an HRIR set.
+type HRIRFile struct {
+	SampleRate int    `json:"sample_rate"`
+	Taps       int    `json:"taps"`
+	Path       string `json:"path"`
+}
+
+// HRIR is an HRIR set at one sample rate. Left[i] and Right[i] are the
+// responses from speaker i (LF, RF, LB, RB) to the left and right ear.
+type HRIR struct {
+	Name       string
+	SampleRate int
+	Left       [4][]float64
+	Right      [4][]float64
+}
+
+// ListHRIRs returns the embedded HRIR sets.
+func ListHRIRs() ([]HRIRInfo, error) {
+	var index struct {
+		Sets []HRIRInfo `json:"sets"`
+	}
+	if err := readJSON("hrir/index.json", &index); err != nil {
+		return nil, err
+	}
+	return index.Sets, nil
+}
+
+// HRIRNames returns the names of the embedded HRIR sets.
+func HRIRNames() []string {
+	sets, _ := ListHRIRs()
+	names := make([]string, len(sets))
+	for i, set := range sets {
+		names[i] = set.Name
+	}
+	return names
+}
+
+// LoadHRIR returns the HRIR set called name at sampleRate. A set embedded at
+// that rate is returned as is; otherwise the nearest embedded rate is
+// resampled, keeping the DC gain of each response.
+func LoadHRIR(name string, sampleRate int) (*HRIR, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	sets, err := ListHRIRs()
+	if err != nil {
+		return nil, err
+	}
+	var set *HRIRInfo
+	for i := range sets {
+		if sets[i].Name == name {
+			set = &sets[i]
+		}
+	}
+	if set == nil {
+		return nil, fmt.Errorf("unknown HRIR set %q (available: %s)", name, strings.Join(HRIRNames(), ", "))
+	}
+	if len(set.Files) == 0 {
+		return nil, fmt.Errorf("HRIR set %q has no files", name)
+	}
+
+	files := append([]HRIRFile(nil), set.Files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return math.Abs(float64(files[i].SampleRate-sampleRate)) < math.Abs(float64(files[j].SampleRate-sampleRate))
+	})
+	file := files[0]
+	raw, err := data.ReadFile(path.Join("data", "hrir", file.Path))
+	if err != nil {
+		return nil, fmt.Errorf("read HRIR set %q: %w", name, err)
+	}
+	audio, err := wav.ReadWAVBytes(raw, hrirChannels)
+	if err != nil {
+		return nil, fmt.Errorf("read HRIR set %q: %w", name, err)
+	}
+	if int(audio.SampleRate) != file.SampleRate || audio.NumSamples != file.Taps {
+		return nil, fmt.Errorf("HRIR set %q: %s is %d taps at %d Hz, index says %d taps at %d Hz",
+			name, file.Path, audio.NumSamples, audio.SampleRate, file.Taps, file.SampleRate)
+	}
+
+	hrir := &HRIR{Name: name, SampleRate: sampleRate}
+	for speaker := range hrir.Left {
+		hrir.Left[speaker] = resampleIR(audio.Samples[2*speaker], file.SampleRate, sampleRate)
+		hrir.Right[speaker] = resampleIR(audio.Samples[2*speaker+1], file.SampleRate, sampleRate)
+	}
+	return hrir, nil
+}
+
+// resampleIR resamples an impulse response. Resampling keeps sample
+// amplitudes, so the response is scaled by from/to to keep its sum, the DC
+// gain, unchanged.
+func resampleIR(ir []float64, from, to int) []float64 {
+	if from == to {
+		return ir
+	}
+	out := sqmath.Resample(ir, from, to)
+	scale := float64(from) / float64(to)
+	for i := range out {
+		out[i] *= scale
+	}
+	return out
+}
@@ -0,0 +1,216 @@
+// Package audiofile reads audio inputs in any supported container into
+// wav.AudioData. WAV, RF64, CAF and AIFF are read natively; Ogg Vorbis is
+// decoded with github.com/jfreymuth/oggvorbis and MP3 with
+// github.com/hajimehoshi/go-mp3. FLAC is recognized but not decoded.
+package audiofile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// Format identifies an input container.
+type Format struct {
+	Name  string
+	Lossy bool
+}
+
+var (
+	// FormatWAV is RIFF/WAVE PCM or float.
+	FormatWAV = Format{Name: "WAV"}
+	// FormatRF64 is WAVE with 64-bit sizes (EBU Tech 3306).
+	FormatRF64 = Format{Name: "RF64"}
+	// FormatCAF is Apple Core Audio Format with linear PCM or float.
+	FormatCAF = Format{Name: "CAF"}
+	// FormatAIFF is AIFF or uncompressed AIFF-C.
+	FormatAIFF = Format{Name: "AIFF"}
+	// FormatOggVorbis is Vorbis audio in an Ogg container.
+	FormatOggVorbis = Format{Name: "Ogg Vorbis", Lossy: true}
+	// FormatMP3 is MPEG-1/2 Layer III.
+	FormatMP3 = Format{Name: "MP3", Lossy: true}
+	// FormatFLAC is Free Lossless Audio Codec, which is detected to report
+	// it clearly but cannot be decoded.
+	FormatFLAC = Format{Name: "FLAC"}
+)
+
+// extensions maps file extensions to the format Detect falls back to.
+// FLAC is left out so that directory scans skip .flac files.
+var extensions = map[string]Format{
+	".wav":  FormatWAV,
+	".wave": FormatWAV,
+	".rf64": FormatRF64,
+	".caf":  FormatCAF,
+	".aif":  FormatAIFF,
+	".aiff": FormatAIFF,
+	".aifc": FormatAIFF,
+	".ogg":  FormatOggVorbis,
+	".oga":  FormatOggVorbis,
+	".mp3":  FormatMP3,
+}
+
+// Streamed reports whether the format is read incrementally by wav.Reader
+// rather than decoded into memory.
+func (f Format) Streamed() bool {
+	return f == FormatWAV || f == FormatRF64
+}
+
+// MatchesExtension reports whether the extension of filename fits the
+// format: it names the format, one read the same way, or none Detect
+// knows.
+func (f Format) MatchesExtension(filename string) bool {
+	ext, ok := extensions[strings.ToLower(filepath.Ext(filename))]
+	return !ok || ext == f || ext.Streamed() && f.Streamed()
+}
+
+// SourceInfo describes a decoded input: its container, the codec of the
+// audio in it and, for uncompressed audio, the stored bit depth.
+type SourceInfo struct {
+	Format        Format
+	Codec         string
+	BitsPerSample int
+}
+
+// String returns a short description such as "WAV, 24-bit PCM".
+func (s SourceInfo) String() string {
+	if s.BitsPerSample == 0 {
+		return fmt.Sprintf("%s, %s", s.Format.Name, s.Codec)
+	}
+	return fmt.Sprintf("%s, %d-bit %s", s.Format.Name, s.BitsPerSample, s.Codec)
+}
+
+// sourceInfo describes data decoded from format.
+func sourceInfo(format Format, data *wav.AudioData) SourceInfo {
+	info := SourceInfo{Format: format, BitsPerSample: data.BitsPerSample}
+	switch {
+	case format == FormatOggVorbis:
+		info.Codec = "Vorbis"
+	case format == FormatMP3:
+		info.Codec = "MPEG Layer III"
+	case data.Float:
+		info.Codec = "float"
+	default:
+		info.Codec = "PCM"
+	}
+	return info
+}
+
+// HeaderSize is the number of leading bytes Detect inspects.
+const HeaderSize = 12
+
+// Detect identifies the container from the magic bytes in header, falling
+// back to the file extension when the bytes are inconclusive.
+func Detect(filename string, header []byte) (Format, error) {
+	switch {
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return FormatWAV, nil
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RF64")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return FormatRF64, nil
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("FORM")) &&
+		(bytes.Equal(header[8:12], []byte("AIFF")) || bytes.Equal(header[8:12], []byte("AIFC"))):
+		return FormatAIFF, nil
+	case bytes.HasPrefix(header, []byte("caff")):
+		return FormatCAF, nil
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return FormatFLAC, nil
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return FormatOggVorbis, nil
+	case bytes.HasPrefix(header, []byte("ID3")),
+		len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0:
+		return FormatMP3, nil
+	}
+
+	if format, ok := extensions[strings.ToLower(filepath.Ext(filename))]; ok {
+		return format, nil
+	}
+	return Format{}, fmt.Errorf("unrecognized audio format for %q (supported: WAV, RF64, CAF, AIFF, Ogg Vorbis, MP3)", filename)
+}
+
+// Open detects the format of filename from its contents and decodes it,
+// failing with a *wav.ChannelCountError when it has another channel count
+// than requested.
+func Open(filename string, channels int) (*wav.AudioData, SourceInfo, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, SourceInfo{}, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+
+	return OpenReader(file, filename, channels)
+}
+
+// OpenReader is Open for a stream; name is only consulted, by its
+// extension, when the leading bytes are inconclusive.
+func OpenReader(r io.Reader, name string, channels int) (*wav.AudioData, SourceInfo, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(HeaderSize)
+	format, err := Detect(name, header)
+	if err != nil {
+		return nil, SourceInfo{}, err
+	}
+
+	data, err := Decode(br, format, channels)
+	if err != nil {
+		return nil, SourceInfo{Format: format}, err
+	}
+	return data, sourceInfo(format, data), nil
+}
+
+// Decode reads a complete stream of the given format with the requested
+// channel count and returns it at the stream's native sample rate.
+func Decode(r io.Reader, format Format, channels int) (*wav.AudioData, error) {
+	switch format {
+	case FormatWAV, FormatRF64:
+		return wav.ReadWAVFromReader(r, channels)
+	case FormatCAF:
+		return wav.ReadCAFFromReader(r, channels)
+	case FormatAIFF:
+		return wav.ReadAIFFFromReader(r, channels)
+	case FormatFLAC:
+		return nil, fmt.Errorf("FLAC input is not supported; convert it to WAV first")
+	case FormatOggVorbis:
+		data, sourceChannels, err := decodeOggVorbis(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode Ogg Vorbis: %w", err)
+		}
+		return checkChannels(data, sourceChannels, channels)
+	case FormatMP3:
+		data, sourceChannels, err := decodeMP3(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode MP3: %w", err)
+		}
+		return checkChannels(data, sourceChannels, channels)
+	default:
+		return nil, fmt.Errorf("unsupported audio format %q", format.Name)
+	}
+}
+
+// deinterleave splits interleaved samples into [channel][frame].
+func deinterleave(interleaved []float64, channels int) [][]float64 {
+	numFrames := len(interleaved) / channels
+	samples := make([][]float64, channels)
+	for ch := range samples {
+		samples[ch] = make([]float64, numFrames)
+		for i := 0; i < numFrames; i++ {
+			samples[ch][i] = interleaved[i*channels+ch]
+		}
+	}
+	return samples
+}
+
+// checkChannels returns data decoded with sourceChannels channels if that
+// is the requested count, as the WAV readers do. Mono sources are not
+// duplicated here, so that callers handle them the same way in every
+// format.
+func checkChannels(data *wav.AudioData, sourceChannels, channels int) (*wav.AudioData, error) {
+	if sourceChannels != channels {
+		return nil, &wav.ChannelCountError{Want: channels, Got: sourceChannels}
+	}
+	return data, nil
+}
@@ -0,0 +1,270 @@
+package audiofile_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/audiofile"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// testdata/mono.ogg is the 1 s, 44.1 kHz mono test file from
+// github.com/jfreymuth/oggvorbis (MIT license). testdata/mono.mp3 is the
+// first 80 frames (about 2 s, 22.05 kHz mono MPEG-2) of the public domain
+// speech example of github.com/hajimehoshi/go-mp3.
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		filename string
+		header   []byte
+		want     audiofile.Format
+	}{
+		{"a.bin", []byte("RIFF\x00\x00\x00\x00WAVE"), audiofile.FormatWAV},
+		{"a.bin", []byte("OggS\x00\x02"), audiofile.FormatOggVorbis},
+		{"a.bin", []byte("ID3\x04\x00"), audiofile.FormatMP3},
+		{"a.bin", []byte{0xFF, 0xFB, 0x90, 0x00}, audiofile.FormatMP3},
+		{"track.MP3", nil, audiofile.FormatMP3},
+		{"track.ogg", []byte("junk"), audiofile.FormatOggVorbis},
+		{"track.wav", nil, audiofile.FormatWAV},
+		{"a.bin", []byte("caff\x00\x01\x00\x00desc"), audiofile.FormatCAF},
+		{"track.CAF", nil, audiofile.FormatCAF},
+		{"a.bin", []byte("RF64\xff\xff\xff\xffWAVE"), audiofile.FormatRF64},
+		{"a.wav", []byte("FORM\x00\x00\x00\x00AIFF"), audiofile.FormatAIFF},
+		{"a.bin", []byte("FORM\x00\x00\x00\x00AIFC"), audiofile.FormatAIFF},
+		{"track.aiff", nil, audiofile.FormatAIFF},
+		{"track.wav", []byte("fLaC\x00\x00\x00\x22"), audiofile.FormatFLAC},
+		// The contents win over the extension.
+		{"track.mp3", []byte("RIFF\x00\x00\x00\x00WAVE"), audiofile.FormatWAV},
+	}
+	for _, tt := range tests {
+		got, err := audiofile.Detect(tt.filename, tt.header)
+		if err != nil {
+			t.Fatalf("Detect(%q, %q) error = %v", tt.filename, tt.header, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Detect(%q, %q) = %v, want %v", tt.filename, tt.header, got, tt.want)
+		}
+	}
+
+	for _, name := range []string{"track.flac", "a.bin", "a.txt"} {
+		if _, err := audiofile.Detect(name, []byte("junk data!!!")); err == nil || !strings.Contains(err.Error(), "unrecognized audio format") {
+			t.Fatalf("Detect(%q, junk) error = %v, want unrecognized format", name, err)
+		}
+	}
+}
+
+// checkMono checks that the mono source path decodes to one channel of
+// frames samples and is rejected as stereo.
+func checkMono(t *testing.T, path string, frames int) (*wav.AudioData, audiofile.SourceInfo) {
+	t.Helper()
+
+	var channelErr *wav.ChannelCountError
+	if _, _, err := audiofile.Open(path, 2); !errors.As(err, &channelErr) || channelErr.Got != 1 {
+		t.Fatalf("Open(%s, 2) error = %v, want a channel count error", path, err)
+	}
+	data, info, err := audiofile.Open(path, 1)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if data.NumSamples != frames || len(data.Samples) != 1 {
+		t.Fatalf("got %d channels x %d samples, want 1 x %d", len(data.Samples), data.NumSamples, frames)
+	}
+	energy := 0.0
+	for _, v := range data.Samples[0] {
+		energy += v * v
+	}
+	if energy == 0 {
+		t.Fatalf("decoded signal is silent")
+	}
+	return data, info
+}
+
+func TestOpen_OggVorbisMono(t *testing.T) {
+	t.Parallel()
+
+	data, info := checkMono(t, filepath.Join("testdata", "mono.ogg"), 44100)
+	if data.SampleRate != 44100 {
+		t.Fatalf("SampleRate = %d, want 44100", data.SampleRate)
+	}
+	if info.Format != audiofile.FormatOggVorbis || !info.Format.Lossy || info.Codec != "Vorbis" || info.BitsPerSample != 0 {
+		t.Fatalf("source = %+v, want lossy Ogg Vorbis", info)
+	}
+}
+
+func TestOpen_MP3Mono(t *testing.T) {
+	t.Parallel()
+
+	// An MPEG-2 Layer III frame holds 576 samples.
+	data, info := checkMono(t, filepath.Join("testdata", "mono.mp3"), 80*576)
+	if data.SampleRate != 22050 {
+		t.Fatalf("SampleRate = %d, want 22050", data.SampleRate)
+	}
+	if info.Format != audiofile.FormatMP3 || !info.Format.Lossy || info.Codec != "MPEG Layer III" {
+		t.Fatalf("source = %+v, want lossy MP3", info)
+	}
+}
+
+func TestOpen_MP3Stereo(t *testing.T) {
+	t.Parallel()
+
+	// Ten silent MPEG-1 Layer III stereo frames (128 kbit/s, 44.1 kHz,
+	// zero side info) behind an ID3v2 tag whose body looks like a single
+	// channel frame header, which must be skipped.
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	stream := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x04\xff\xfb\x90\xc0"), bytes.Repeat(frame, 10)...)
+
+	data, _, err := audiofile.OpenReader(bytes.NewReader(stream), "stereo.mp3", 2)
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+	if data.NumSamples != 10*1152 || len(data.Samples) != 2 {
+		t.Fatalf("got %d channels x %d samples, want 2 x %d", len(data.Samples), data.NumSamples, 10*1152)
+	}
+	var channelErr *wav.ChannelCountError
+	if _, _, err := audiofile.OpenReader(bytes.NewReader(stream), "stereo.mp3", 1); !errors.As(err, &channelErr) || channelErr.Got != 2 {
+		t.Fatalf("OpenReader(stereo, 1) error = %v, want a channel count error", err)
+	}
+}
+
+func TestOpen_WAV(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "input.dat")
+	in := &wav.AudioData{
+		SampleRate: 48000,
+		Samples:    [][]float64{{0.5, -0.25, 0}, {0.125, 0, -0.5}},
+		NumSamples: 3,
+	}
+	if err := wav.WriteStereoFloat32WAV(filename, in); err != nil {
+		t.Fatalf("WriteStereoFloat32WAV() error = %v", err)
+	}
+
+	data, info, err := audiofile.Open(filename, 2)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := info.String(); got != "WAV, 32-bit float" {
+		t.Fatalf("source = %q, want WAV, 32-bit float", got)
+	}
+	for ch := range in.Samples {
+		for i, want := range in.Samples[ch] {
+			if math.Abs(data.Samples[ch][i]-want) > 1e-7 {
+				t.Fatalf("sample[%d][%d] = %v, want %v", ch, i, data.Samples[ch][i], want)
+			}
+		}
+	}
+}
+
+// aiffStereo16 is a 16-bit stereo AIFF file at 44.1 kHz with the frames
+// (0.5, -0.5) and (0.25, -0.25).
+func aiffStereo16() []byte {
+	comm := []byte{0, 2, 0, 0, 0, 2, 0, 16, 0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}
+	ssnd := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x40, 0x00, 0xc0, 0x00, 0x20, 0x00, 0xe0, 0x00}
+	var buf bytes.Buffer
+	buf.WriteString("FORM")
+	binary.Write(&buf, binary.BigEndian, uint32(4+8+len(comm)+8+len(ssnd)))
+	buf.WriteString("AIFFCOMM")
+	binary.Write(&buf, binary.BigEndian, uint32(len(comm)))
+	buf.Write(comm)
+	buf.WriteString("SSND")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ssnd)))
+	buf.Write(ssnd)
+	return buf.Bytes()
+}
+
+func TestOpen_DetectsContents(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	in := &wav.AudioData{
+		SampleRate: 44100,
+		Samples:    [][]float64{{0.5, 0.25}, {-0.5, -0.25}},
+		NumSamples: 2,
+	}
+	// Every file is misnamed; Open goes by the contents.
+	caf := filepath.Join(dir, "caf.wav")
+	if err := wav.WriteCAF(caf, in, 2, wav.WriteOptions{Format: wav.FormatPCM24}); err != nil {
+		t.Fatalf("WriteCAF() error = %v", err)
+	}
+	riff := filepath.Join(dir, "riff.caf")
+	if err := wav.WriteWAVWithOptions(riff, in, 2, wav.WriteOptions{Format: wav.FormatPCM16}); err != nil {
+		t.Fatalf("WriteWAVWithOptions() error = %v", err)
+	}
+	aiff := filepath.Join(dir, "aiff.wav")
+	if err := os.WriteFile(aiff, aiffStereo16(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{caf, "CAF, 24-bit PCM"},
+		{riff, "WAV, 16-bit PCM"},
+		{aiff, "AIFF, 16-bit PCM"},
+	} {
+		data, info, err := audiofile.Open(tt.path, 2)
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", tt.path, err)
+		}
+		if info.String() != tt.want {
+			t.Fatalf("Open(%s) source = %q, want %q", tt.path, info, tt.want)
+		}
+		if info.Format.MatchesExtension(tt.path) {
+			t.Fatalf("%s: MatchesExtension() = true for a misnamed file", tt.path)
+		}
+		for ch := range in.Samples {
+			for i, want := range in.Samples[ch] {
+				if math.Abs(data.Samples[ch][i]-want) > 1e-4 {
+					t.Fatalf("%s: sample[%d][%d] = %v, want %v", tt.path, ch, i, data.Samples[ch][i], want)
+				}
+			}
+		}
+	}
+
+	flac := filepath.Join(dir, "flac.wav")
+	if err := os.WriteFile(flac, []byte("fLaC\x00\x00\x00\x22 stream info"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, info, err := audiofile.Open(flac, 2); err == nil || info.Format != audiofile.FormatFLAC || !strings.Contains(err.Error(), "FLAC input is not supported") {
+		t.Fatalf("Open(FLAC) = %v, %v, want an unsupported FLAC error", info.Format, err)
+	}
+	unknown := filepath.Join(dir, "noise.bin")
+	if err := os.WriteFile(unknown, []byte("not audio at all"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := audiofile.Open(unknown, 2); err == nil || !strings.Contains(err.Error(), "unrecognized audio format") {
+		t.Fatalf("Open(unknown) error = %v, want unrecognized format", err)
+	}
+}
+
+func TestFormat_MatchesExtension(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format   audiofile.Format
+		filename string
+		want     bool
+	}{
+		{audiofile.FormatWAV, "a.WAV", true},
+		{audiofile.FormatRF64, "a.wav", true},
+		{audiofile.FormatAIFF, "a.aif", true},
+		{audiofile.FormatAIFF, "a.wav", false},
+		{audiofile.FormatFLAC, "a.wav", false},
+		{audiofile.FormatWAV, "a.dat", true},
+		{audiofile.FormatOggVorbis, "a.mp3", false},
+	}
+	for _, tt := range tests {
+		if got := tt.format.MatchesExtension(tt.filename); got != tt.want {
+			t.Fatalf("%s.MatchesExtension(%q) = %v, want %v", tt.format.Name, tt.filename, got, tt.want)
+		}
+	}
+}
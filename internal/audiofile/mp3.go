@@ -0,0 +1,67 @@
+package audiofile
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// decodeMP3 decodes MP3 at its native channel count. go-mp3 always
+// produces interleaved 16-bit little-endian stereo, upmixing mono streams,
+// so the channel count is taken from the first frame header and the copy
+// of a mono stream dropped again.
+func decodeMP3(r io.Reader) (*wav.AudioData, int, error) {
+	stream, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	decoder, err := mp3.NewDecoder(bytes.NewReader(stream))
+	if err != nil {
+		return nil, 0, err
+	}
+	pcm, err := io.ReadAll(decoder)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		v := int16(uint16(pcm[2*i]) | uint16(pcm[2*i+1])<<8)
+		samples[i] = wav.Int16ToFloat64(v)
+	}
+	channels := mp3Channels(stream)
+	return wav.NewAudioData(uint32(decoder.SampleRate()), deinterleave(samples, 2)[:channels]), channels, nil
+}
+
+// mp3Channels returns the channel count declared by the first Layer III
+// frame header of stream, after any ID3v2 tag: 1 for single channel mode,
+// otherwise 2.
+func mp3Channels(stream []byte) int {
+	start := 0
+	if len(stream) >= 10 && bytes.HasPrefix(stream, []byte("ID3")) {
+		// The tag size is a synchsafe integer of 7 bits per byte, without
+		// the header and the optional footer.
+		size := 0
+		for _, b := range stream[6:10] {
+			size = size<<7 | int(b&0x7f)
+		}
+		start = 10 + size
+		if stream[5]&0x10 != 0 {
+			start += 10
+		}
+	}
+	for i := start; i+4 <= len(stream); i++ {
+		h := stream[i : i+4]
+		if h[0] != 0xff || h[1]&0xe0 != 0xe0 ||
+			h[1]>>3&3 == 1 || h[1]>>1&3 != 1 || h[2]>>4 == 15 || h[2]>>2&3 == 3 {
+			continue
+		}
+		if h[3]>>6 == 3 {
+			return 1
+		}
+		return 2
+	}
+	return 2
+}
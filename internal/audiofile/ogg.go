@@ -0,0 +1,21 @@
+package audiofile
+
+import (
+	"io"
+
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/jfreymuth/oggvorbis"
+)
+
+func decodeOggVorbis(r io.Reader) (*wav.AudioData, int, error) {
+	interleaved, format, err := oggvorbis.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	samples := make([]float64, len(interleaved))
+	for i, v := range interleaved {
+		samples[i] = float64(v)
+	}
+	return wav.NewAudioData(uint32(format.SampleRate), deinterleave(samples, format.Channels)), format.Channels, nil
+}
@@ -0,0 +1,182 @@
+// Package batch records what a batch run did to every file in a manifest,
+// so that long unattended jobs can be audited and resumed, and what a run
+// did to a single output in a sidecar next to it.
+package batch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CurrentVersion is the manifest schema version written by Save.
+const CurrentVersion = 1
+
+// Manifest is the record of a batch run.
+type Manifest struct {
+	Version     int    `json:"version"`
+	Tool        string `json:"tool"`
+	ToolVersion string `json:"tool_version"`
+	// Start and End bound the run. End is updated whenever the manifest is
+	// saved, so an interrupted run shows how far it got.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitzero"`
+	// Album is the album loudness normalization of the run, if any.
+	Album *Album  `json:"album,omitempty"`
+	Files []Entry `json:"files"`
+}
+
+// Album records the loudness normalization applied to all files of a run.
+type Album struct {
+	TargetLUFS  float64 `json:"target_lufs"`
+	CeilingDBTP float64 `json:"ceiling_dbtp"`
+	// LoudnessLUFS and TruePeakDBTP are measured over all files; they are
+	// absent when all files are silent.
+	LoudnessLUFS *float64 `json:"loudness_lufs,omitempty"`
+	TruePeakDBTP *float64 `json:"true_peak_dbtp,omitempty"`
+	// GainDB is applied to every file; Limited reports that the ceiling
+	// reduced it below the difference to the target.
+	GainDB  float64 `json:"gain_db"`
+	Limited bool    `json:"limited,omitempty"`
+}
+
+// Loudness is the measurement of one file before the album gain, and the
+// gain applied to it. Silent files have no loudness or peak.
+type Loudness struct {
+	IntegratedLUFS *float64 `json:"integrated_lufs,omitempty"`
+	TruePeakDBTP   *float64 `json:"true_peak_dbtp,omitempty"`
+	AppliedGainDB  float64  `json:"applied_gain_db"`
+}
+
+// QA is the automatic check of one output for audible defects.
+type QA struct {
+	// Score is 100 without findings and drops with every finding.
+	Score    float64     `json:"score"`
+	Findings []QAFinding `json:"findings"`
+}
+
+// QAFinding is one suspected defect, located in the output.
+type QAFinding struct {
+	Kind            string  `json:"kind"`
+	Channel         string  `json:"channel"`
+	StartSeconds    float64 `json:"start_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Value measures the defect; its unit depends on the kind.
+	Value float64 `json:"value"`
+}
+
+// Entry is the result of one input file.
+type Entry struct {
+	Input        string `json:"input"`
+	InputSHA256  string `json:"input_sha256,omitempty"`
+	Output       string `json:"output"`
+	OutputSHA256 string `json:"output_sha256,omitempty"`
+	// Parameters are the effective decoder settings as logged.
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Duration is the length of the audio in seconds.
+	Duration float64   `json:"duration_seconds,omitempty"`
+	Start    time.Time `json:"start"`
+	// Elapsed is the processing time in seconds.
+	Elapsed        float64  `json:"elapsed_seconds"`
+	ClippedSamples int64    `json:"clipped_samples"`
+	Warnings       []string `json:"warnings,omitempty"`
+	// Loudness is set in album loudness normalization.
+	Loudness *Loudness `json:"loudness,omitempty"`
+	// QA is the automatic check of the output, if requested.
+	QA    *QA    `json:"qa,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Resumed marks an entry carried over from an earlier run's manifest
+	// instead of being decoded again.
+	Resumed bool `json:"resumed,omitempty"`
+}
+
+// OK reports whether the file was decoded successfully.
+func (e Entry) OK() bool {
+	return e.Error == ""
+}
+
+// Succeeded returns the successful entry for input, or false when there is
+// none.
+func (m Manifest) Succeeded(input string) (Entry, bool) {
+	for _, e := range m.Files {
+		if e.Input == input && e.OK() {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Save writes m as indented JSON. The file is replaced atomically, so a
+// crash never leaves a truncated manifest behind.
+func Save(path string, m Manifest) error {
+	if m.Version == 0 {
+		m.Version = CurrentVersion
+	}
+	if err := writeJSON(path, m); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON to path through a temporary file in
+// the same directory, renamed into place once complete.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Load reads a manifest written by Save. Manifests from newer schema
+// versions are rejected.
+func Load(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	if m.Version < 1 {
+		return Manifest{}, fmt.Errorf("manifest %s has no schema version", path)
+	}
+	if m.Version > CurrentVersion {
+		return Manifest{}, fmt.Errorf("manifest %s has schema version %d, newest supported is %d", path, m.Version, CurrentVersion)
+	}
+	return m, nil
+}
+
+// HashFile returns the hex SHA-256 of the file at path.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,76 @@
+package batch_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+)
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	start := time.Date(2024, 5, 1, 22, 0, 0, 0, time.UTC)
+	m := batch.Manifest{
+		Tool:  "go-sq-tool",
+		Start: start,
+		End:   start.Add(time.Hour),
+		Files: []batch.Entry{
+			{Input: "a.wav", Output: "out/a.wav", Parameters: map[string]string{"block_size": "1024"}, ClippedSamples: 3},
+			{Input: "b.wav", Output: "out/b.wav", Error: "unsupported format"},
+		},
+	}
+	if err := batch.Save(path, m); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := batch.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Version != batch.CurrentVersion || !loaded.End.Equal(m.End) || len(loaded.Files) != 2 {
+		t.Fatalf("Load() = %+v", loaded)
+	}
+	if e, ok := loaded.Succeeded("a.wav"); !ok || e.Parameters["block_size"] != "1024" || e.ClippedSamples != 3 {
+		t.Fatalf("Succeeded(a.wav) = %+v, %v", e, ok)
+	}
+	if _, ok := loaded.Succeeded("b.wav"); ok {
+		t.Fatalf("Succeeded(b.wav) = true for a failed file")
+	}
+
+	// Saving again replaces the file without leaving temporaries behind.
+	if err := batch.Save(path, loaded); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("directory holds %d entries, want 1 (%v)", len(entries), err)
+	}
+}
+
+func TestLoad_RejectsNewerVersion(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "files": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := batch.Load(path); err == nil {
+		t.Fatalf("Load() of version 99 expected error")
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "x")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := batch.HashFile(path)
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; err != nil || got != want {
+		t.Fatalf("HashFile() = %s, %v, want %s", got, err, want)
+	}
+}
@@ -0,0 +1,94 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SidecarVersion is the sidecar schema version written by SaveSidecar.
+const SidecarVersion = 1
+
+// Sidecar is the provenance record of one output file, kept next to it so
+// that the file can be traced back to the run that wrote it and checked for
+// changes since.
+type Sidecar struct {
+	Version     int    `json:"version"`
+	Tool        string `json:"tool"`
+	ToolVersion string `json:"tool_version"`
+	// CommandLine is the command line of the process as given. Command is
+	// the command that wrote the output, with its arguments and the flags
+	// set explicitly.
+	CommandLine []string          `json:"command_line"`
+	Command     string            `json:"command"`
+	Args        []string          `json:"args"`
+	Flags       map[string]string `json:"flags,omitempty"`
+	// Config is the configuration the command resolved from its flags,
+	// quality preset and profile, as logged.
+	Config map[string]string `json:"config,omitempty"`
+	Inputs []FileHash        `json:"inputs"`
+	Output FileHash          `json:"output"`
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end"`
+	// Elapsed is the processing time in seconds.
+	Elapsed  float64  `json:"elapsed_seconds"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// FileHash is a file and its SHA-256 as hex.
+type FileHash struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// SidecarPath returns the path of the sidecar of output: output with .json
+// appended.
+func SidecarPath(output string) string {
+	return output + ".json"
+}
+
+// SaveSidecar writes s as indented JSON. Like Save it replaces the file
+// atomically.
+func SaveSidecar(path string, s Sidecar) error {
+	if s.Version == 0 {
+		s.Version = SidecarVersion
+	}
+	if err := writeJSON(path, s); err != nil {
+		return fmt.Errorf("write sidecar: %w", err)
+	}
+	return nil
+}
+
+// LoadSidecar reads a sidecar written by SaveSidecar. Sidecars from newer
+// schema versions are rejected.
+func LoadSidecar(path string) (Sidecar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Sidecar{}, fmt.Errorf("read sidecar: %w", err)
+	}
+	var s Sidecar
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Sidecar{}, fmt.Errorf("parse sidecar %s: %w", path, err)
+	}
+	if s.Version < 1 {
+		return Sidecar{}, fmt.Errorf("sidecar %s has no schema version", path)
+	}
+	if s.Version > SidecarVersion {
+		return Sidecar{}, fmt.Errorf("sidecar %s has schema version %d, newest supported is %d", path, s.Version, SidecarVersion)
+	}
+	return s, nil
+}
+
+// VerifyOutput checks that the file at path still has the output hash
+// recorded in s.
+func (s Sidecar) VerifyOutput(path string) error {
+	hash, err := HashFile(path)
+	if err != nil {
+		return err
+	}
+	if hash != s.Output.SHA256 {
+		return fmt.Errorf("%s has SHA-256 %s, sidecar records %s", path, hash, s.Output.SHA256)
+	}
+	return nil
+}
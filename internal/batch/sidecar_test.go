@@ -0,0 +1,60 @@
+package batch_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/batch"
+)
+
+func TestSidecar_SaveLoadVerify(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.wav")
+	if err := os.WriteFile(output, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := batch.HashFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := batch.SidecarPath(output)
+	s := batch.Sidecar{
+		Tool:   "go-sq-tool",
+		Args:   []string{"in.wav", output},
+		Config: map[string]string{"block_size": "1024"},
+		Output: batch.FileHash{Path: output, SHA256: hash},
+	}
+	if err := batch.SaveSidecar(path, s); err != nil {
+		t.Fatalf("SaveSidecar() error = %v", err)
+	}
+	loaded, err := batch.LoadSidecar(path)
+	if err != nil {
+		t.Fatalf("LoadSidecar() error = %v", err)
+	}
+	if loaded.Version != batch.SidecarVersion || loaded.Config["block_size"] != "1024" || loaded.Output != s.Output {
+		t.Fatalf("LoadSidecar() = %+v", loaded)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 2 {
+		t.Fatalf("directory holds %d entries, want the output and its sidecar (%v)", len(entries), err)
+	}
+
+	if err := loaded.VerifyOutput(output); err != nil {
+		t.Fatalf("VerifyOutput() error = %v", err)
+	}
+	if err := os.WriteFile(output, []byte("abd"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.VerifyOutput(output); err == nil {
+		t.Fatal("VerifyOutput() accepted a changed output")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := batch.LoadSidecar(path); err == nil {
+		t.Fatal("LoadSidecar() of version 99 expected error")
+	}
+}
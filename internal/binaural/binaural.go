@@ -0,0 +1,78 @@
+// Package binaural renders the four decoded quad channels to headphone
+// stereo by convolving each speaker feed with the impulse responses from
+// its position to both ears.
+package binaural
+
+import (
+	"fmt"
+
+	"github.com/cwbudde/go-sq-tool/internal/assets"
+)
+
+// Renderer convolves LF, RF, LB and RB with an HRIR set. It keeps the tail
+// of each input channel, so a stream rendered in chunks equals the same
+// stream rendered at once. The convolution is direct: the embedded sets are
+// a few hundred taps long, short enough that an FFT would not pay off for
+// the chunk sizes of the pipeline.
+type Renderer struct {
+	hrir    *assets.HRIR
+	taps    int
+	history [4][]float64
+}
+
+// NewRenderer creates a renderer for hrir.
+func NewRenderer(hrir *assets.HRIR) (*Renderer, error) {
+	taps := len(hrir.Left[0])
+	for speaker := range hrir.Left {
+		if len(hrir.Left[speaker]) != taps || len(hrir.Right[speaker]) != taps {
+			return nil, fmt.Errorf("HRIR set %q: responses differ in length", hrir.Name)
+		}
+	}
+	if taps == 0 {
+		return nil, fmt.Errorf("HRIR set %q is empty", hrir.Name)
+	}
+	r := &Renderer{hrir: hrir, taps: taps}
+	for speaker := range r.history {
+		r.history[speaker] = make([]float64, taps-1)
+	}
+	return r, nil
+}
+
+// Process renders one chunk of LF, RF, LB, RB to left and right ear
+// signals of the same length.
+func (r *Renderer) Process(quad [][]float64) ([][]float64, error) {
+	if len(quad) != 4 {
+		return nil, fmt.Errorf("binaural rendering needs 4 channels, got %d", len(quad))
+	}
+	n := len(quad[0])
+	left := make([]float64, n)
+	right := make([]float64, n)
+	for speaker, in := range quad {
+		if len(in) != n {
+			return nil, fmt.Errorf("channel %d has %d frames, want %d", speaker, len(in), n)
+		}
+		// x holds the previous taps-1 frames followed by the chunk.
+		x := append(r.history[speaker], in...)
+		hl, hr := r.hrir.Left[speaker], r.hrir.Right[speaker]
+		for i := 0; i < n; i++ {
+			window := x[i : i+r.taps]
+			var l, rr float64
+			for k := 0; k < r.taps; k++ {
+				v := window[r.taps-1-k]
+				l += hl[k] * v
+				rr += hr[k] * v
+			}
+			left[i] += l
+			right[i] += rr
+		}
+		r.history[speaker] = append(r.history[speaker][:0], x[len(x)-(r.taps-1):]...)
+	}
+	return [][]float64{left, right}, nil
+}
+
+// Reset clears the convolution history.
+func (r *Renderer) Reset() {
+	for speaker := range r.history {
+		clear(r.history[speaker])
+	}
+}
@@ -0,0 +1,99 @@
+package binaural_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/assets"
+	"github.com/cwbudde/go-sq-tool/internal/binaural"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func newRenderer(t *testing.T) *binaural.Renderer {
+	t.Helper()
+	hrir, err := assets.LoadHRIR("spherical-room", 44100)
+	if err != nil {
+		t.Fatalf("LoadHRIR() error = %v", err)
+	}
+	r, err := binaural.NewRenderer(hrir)
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+	return r
+}
+
+func TestRenderer_ChunkedMatchesWhole(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, 3000, 0.2, 0.05)
+	whole, err := newRenderer(t).Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	r := newRenderer(t)
+	var chunked [2][]float64
+	// Chunks both shorter and longer than the responses.
+	for start, size := 0, 100; start < 3000; start, size = start+size, size*3 {
+		end := min(start+size, 3000)
+		chunk := make([][]float64, 4)
+		for ch := range chunk {
+			chunk[ch] = quad[ch][start:end]
+		}
+		out, err := r.Process(chunk)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		chunked[0] = append(chunked[0], out[0]...)
+		chunked[1] = append(chunked[1], out[1]...)
+	}
+
+	for ear := range chunked {
+		for i := range whole[ear] {
+			if math.Abs(chunked[ear][i]-whole[ear][i]) > 1e-12 {
+				t.Fatalf("ear %d frame %d = %v, want %v", ear, i, chunked[ear][i], whole[ear][i])
+			}
+		}
+	}
+}
+
+func TestRenderer_LeftSpeakerFavorsLeftEar(t *testing.T) {
+	t.Parallel()
+
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = make([]float64, 1024)
+	}
+	quad[2][0] = 1 // LB impulse
+	out, err := newRenderer(t).Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	first := func(x []float64) int {
+		for i, v := range x {
+			if math.Abs(v) > 0.05 {
+				return i
+			}
+		}
+		return len(x)
+	}
+	energy := func(x []float64) float64 {
+		var e float64
+		for _, v := range x {
+			e += v * v
+		}
+		return e
+	}
+	if first(out[0]) >= first(out[1]) || energy(out[0]) <= energy(out[1]) {
+		t.Fatalf("LB: left ear onset %d energy %g, right ear onset %d energy %g",
+			first(out[0]), energy(out[0]), first(out[1]), energy(out[1]))
+	}
+}
+
+func TestRenderer_RejectsChannelCount(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newRenderer(t).Process(make([][]float64, 2)); err == nil {
+		t.Fatalf("Process(2 channels) expected error")
+	}
+}
@@ -0,0 +1,440 @@
+// Package calibrate fits the tunable decoder parameters to recordings of a
+// hardware SQ decoder: given SQ inputs and what the hardware made of them,
+// it searches for the settings under which this decoder comes closest.
+//
+// Each reference is first time-aligned to a decode of its input by cross-
+// correlation, since captures start at arbitrary offsets and the hardware
+// has its own latency. Every candidate parameter set is then decoded,
+// level-matched to the references with one least-squares gain per pair and
+// scored by the energy of the residual relative to the reference. The
+// search is a coordinate descent over a fixed grid per parameter.
+package calibrate
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// Channels are the channels of a reference, in the order LF, RF, LB, RB.
+const Channels = 4
+
+// improvement is the smallest decrease of the residual in dB that makes a
+// candidate replace the current parameters, so that numerical noise does
+// not move the search.
+const improvement = 1e-3
+
+// Params are the decoder settings the search tunes.
+type Params struct {
+	// FrontBlend mixes each front channel into the other without delay, as
+	// the blend control of hardware decoders does (crossfeed amount, 0-1).
+	FrontBlend float64
+	// Window is the window of the phase shifter.
+	Window sqmath.WindowType
+	// Logic enables logic steering with the settings of the remaining
+	// fields, which are ignored without it.
+	Logic              bool
+	DominanceThreshold float64
+	MaxBoost           float64
+	MinGain            float64
+	AttackTime         float64
+	ReleaseTime        float64
+}
+
+// DefaultParams returns the settings the decoder uses without options.
+func DefaultParams() Params {
+	logic := decoder.DefaultLogicSteeringConfig()
+	return Params{
+		Window:             sqmath.WindowHann,
+		Logic:              logic.Enabled,
+		DominanceThreshold: logic.DominanceThreshold,
+		MaxBoost:           logic.MaxBoost,
+		MinGain:            logic.MinGain,
+		AttackTime:         logic.AttackTime,
+		ReleaseTime:        logic.ReleaseTime,
+	}
+}
+
+// NewDecoder returns a decoder with the settings of p. It fails if the
+// sample rate, block size or overlap is out of range.
+func (p Params) NewDecoder(sampleRate, blockSize, overlap int) (*sq.Decoder, error) {
+	options := []sq.Option{
+		sq.WithBlockSize(blockSize),
+		sq.WithOverlap(overlap),
+		sq.WithSampleRate(sampleRate),
+		sq.WithWindow(p.Window),
+	}
+	if p.Logic {
+		options = append(options, sq.WithLogicSteering(sq.LogicSteeringConfig{
+			AttackTime:         p.AttackTime,
+			ReleaseTime:        p.ReleaseTime,
+			DominanceThreshold: p.DominanceThreshold,
+			MaxBoost:           p.MaxBoost,
+			MinGain:            p.MinGain,
+		}))
+	}
+	d, err := sq.NewDecoder(options...)
+	if err != nil {
+		return nil, err
+	}
+	d.SetCrossfeed(p.FrontBlend, 0)
+	return d, nil
+}
+
+// String lists the settings of p.
+func (p Params) String() string {
+	s := fmt.Sprintf("front blend %.2f, window %s", p.FrontBlend, p.Window)
+	if !p.Logic {
+		return s + ", logic off"
+	}
+	return s + fmt.Sprintf(", logic on (dominance %.2f, max boost %.2f, min gain %.2f, attack %g s, release %g s)",
+		p.DominanceThreshold, p.MaxBoost, p.MinGain, p.AttackTime, p.ReleaseTime)
+}
+
+// dimension is one parameter of the search grid.
+type dimension struct {
+	// size is the number of grid values; set gives p the value i.
+	size int
+	set  func(p *Params, i int)
+	// logic marks the parameters that only matter with logic steering.
+	logic bool
+}
+
+func floats(logic bool, values []float64, field func(p *Params) *float64) dimension {
+	return dimension{size: len(values), logic: logic, set: func(p *Params, i int) { *field(p) = values[i] }}
+}
+
+// grid is the search space, in the order searched: the window, the front
+// blend and the logic steering settings. Logic steering itself is switched
+// by Search.
+var grid = []dimension{
+	{size: 4, set: func(p *Params, i int) {
+		p.Window = []sqmath.WindowType{sqmath.WindowHann, sqmath.WindowHamming, sqmath.WindowBlackman, sqmath.WindowRectangular}[i]
+	}},
+	floats(false, []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5}, func(p *Params) *float64 { return &p.FrontBlend }),
+	floats(true, []float64{0.45, 0.5, 0.55, 0.6, 0.65, 0.7}, func(p *Params) *float64 { return &p.DominanceThreshold }),
+	floats(true, []float64{1.2, 1.4, 1.6, 1.8, 2.0}, func(p *Params) *float64 { return &p.MaxBoost }),
+	floats(true, []float64{0.2, 0.3, 0.4, 0.5, 0.6}, func(p *Params) *float64 { return &p.MinGain }),
+	floats(true, []float64{0.002, 0.005, 0.01, 0.02, 0.05}, func(p *Params) *float64 { return &p.AttackTime }),
+	floats(true, []float64{0.05, 0.1, 0.2, 0.5, 1}, func(p *Params) *float64 { return &p.ReleaseTime }),
+}
+
+// Pair is an SQ input and the hardware decode of it.
+type Pair struct {
+	Name string
+	// Input holds LT and RT, Reference LF, RF, LB and RB.
+	Input, Reference [][]float64
+}
+
+// Config controls a search.
+type Config struct {
+	SampleRate, BlockSize, Overlap int
+	// MaxLag bounds the alignment offset in frames, either way.
+	MaxLag int
+	// Rounds bounds the passes over all parameters; the search ends
+	// earlier when a pass changes nothing.
+	Rounds int
+	// Start is where the search begins.
+	Start Params
+}
+
+// Alignment places a reference relative to the decode of its input:
+// Reference[ch][i] ≈ Gain · decoded[ch][i-Lag].
+type Alignment struct {
+	Lag  int
+	Gain float64
+}
+
+// Match is the residual after level matching, in dB relative to the
+// reference energy, per channel and in total. Lower is better.
+type Match struct {
+	Channels [Channels]float64
+	Total    float64
+}
+
+// Result is the outcome of Search.
+type Result struct {
+	Params Params
+	Match  Match
+	// Initial is the match of Config.Start.
+	Initial Match
+	// Alignments holds the alignment of every pair under Params.
+	Alignments []Alignment
+	// Evaluations is the number of parameter sets decoded.
+	Evaluations int
+}
+
+// Search looks for the parameters under which the decodes of the inputs of
+// pairs match their references best.
+func Search(pairs []Pair, config Config) (Result, error) {
+	if len(pairs) == 0 {
+		return Result{}, fmt.Errorf("no input/reference pairs")
+	}
+	if config.SampleRate <= 0 {
+		return Result{}, fmt.Errorf("sample rate must be positive, got %d", config.SampleRate)
+	}
+	if config.MaxLag < 0 {
+		return Result{}, fmt.Errorf("maximum lag must not be negative, got %d", config.MaxLag)
+	}
+	for _, pair := range pairs {
+		if len(pair.Input) != 2 || len(pair.Reference) != Channels {
+			return Result{}, fmt.Errorf("%s: need 2 input and %d reference channels, got %d and %d",
+				pair.Name, Channels, len(pair.Input), len(pair.Reference))
+		}
+	}
+
+	s := &search{pairs: pairs, config: config, lags: make([]int, len(pairs))}
+	// The lag does not depend on the parameters, so the pairs are aligned
+	// once under the start parameters.
+	for i, pair := range pairs {
+		decoded, err := s.decode(config.Start, pair)
+		if err != nil {
+			return Result{}, err
+		}
+		alignment, err := Align(pair.Reference, decoded, config.MaxLag)
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: %w", pair.Name, err)
+		}
+		s.lags[i] = alignment.Lag
+	}
+
+	initial, _, err := s.evaluate(config.Start)
+	if err != nil {
+		return Result{}, err
+	}
+	// Logic steering decides which other parameters matter, and switching
+	// it on with untuned settings can be worse than leaving it off, so the
+	// descent runs once with it off and once with it on.
+	var best Params
+	bestMatch := Match{Total: math.Inf(1)}
+	for _, logic := range []bool{false, true} {
+		start := config.Start
+		start.Logic = logic
+		p, match, err := s.descend(start, config.Rounds)
+		if err != nil {
+			return Result{}, err
+		}
+		if match.Total < bestMatch.Total {
+			best, bestMatch = p, match
+		}
+	}
+
+	_, gains, err := s.evaluate(best)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Params: best, Match: bestMatch, Initial: initial, Evaluations: s.evaluations}
+	for i, lag := range s.lags {
+		result.Alignments = append(result.Alignments, Alignment{Lag: lag, Gain: gains[i]})
+	}
+	return result, nil
+}
+
+// descend is the coordinate descent from start: each pass tries every grid
+// value of every parameter in turn and keeps what lowers the residual.
+func (s *search) descend(start Params, rounds int) (Params, Match, error) {
+	best := start
+	bestMatch, _, err := s.evaluate(best)
+	if err != nil {
+		return Params{}, Match{}, err
+	}
+	for round := 0; round < max(rounds, 1); round++ {
+		changed := false
+		for _, dim := range grid {
+			if dim.logic && !best.Logic {
+				continue
+			}
+			for i := 0; i < dim.size; i++ {
+				candidate := best
+				dim.set(&candidate, i)
+				if candidate == best {
+					continue
+				}
+				match, _, err := s.evaluate(candidate)
+				if err != nil {
+					return Params{}, Match{}, err
+				}
+				if match.Total < bestMatch.Total-improvement {
+					best, bestMatch, changed = candidate, match, true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return best, bestMatch, nil
+}
+
+// search is the state of a Search.
+type search struct {
+	pairs       []Pair
+	config      Config
+	lags        []int
+	evaluations int
+}
+
+func (s *search) decode(p Params, pair Pair) ([][]float64, error) {
+	d, err := p.NewDecoder(s.config.SampleRate, s.config.BlockSize, s.config.Overlap)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := d.Process(pair.Input)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pair.Name, err)
+	}
+	return decoded, nil
+}
+
+// evaluate decodes every pair with p and returns the match over all of
+// them and the level-matching gain of each.
+func (s *search) evaluate(p Params) (Match, []float64, error) {
+	s.evaluations++
+	var residual, energy [Channels]float64
+	gains := make([]float64, len(s.pairs))
+	for i, pair := range s.pairs {
+		decoded, err := s.decode(p, pair)
+		if err != nil {
+			return Match{}, nil, err
+		}
+		gains[i] = levelMatch(pair.Reference, decoded, s.lags[i])
+		for ch := range Channels {
+			r, e := residualEnergy(pair.Reference[ch], decoded[ch], s.lags[i], gains[i])
+			residual[ch] += r
+			energy[ch] += e
+		}
+	}
+	return newMatch(residual, energy), gains, nil
+}
+
+// Evaluate returns the match of decoded to reference under alignment.
+func Evaluate(reference, decoded [][]float64, alignment Alignment) Match {
+	var residual, energy [Channels]float64
+	for ch := range min(len(reference), len(decoded), Channels) {
+		residual[ch], energy[ch] = residualEnergy(reference[ch], decoded[ch], alignment.Lag, alignment.Gain)
+	}
+	return newMatch(residual, energy)
+}
+
+func newMatch(residual, energy [Channels]float64) Match {
+	var m Match
+	var totalResidual, totalEnergy float64
+	for ch := range Channels {
+		m.Channels[ch] = ratioDB(residual[ch], energy[ch])
+		totalResidual += residual[ch]
+		totalEnergy += energy[ch]
+	}
+	m.Total = ratioDB(totalResidual, totalEnergy)
+	return m
+}
+
+// ratioDB returns residual/energy in dB; a silent reference channel
+// matches perfectly only when the decode is silent too.
+func ratioDB(residual, energy float64) float64 {
+	switch {
+	case residual == 0:
+		return math.Inf(-1)
+	case energy == 0:
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(residual/energy)
+}
+
+// overlap returns the range of reference indices i for which decoded has
+// the sample i-lag.
+func overlap(reference, decoded []float64, lag int) (start, end int) {
+	return max(0, lag), min(len(reference), len(decoded)+lag)
+}
+
+// levelMatch returns the gain that minimizes the residual energy of
+// reference against decoded shifted by lag, over all channels.
+func levelMatch(reference, decoded [][]float64, lag int) float64 {
+	var cross, power float64
+	for ch := range reference {
+		start, end := overlap(reference[ch], decoded[ch], lag)
+		for i := start; i < end; i++ {
+			d := decoded[ch][i-lag]
+			cross += reference[ch][i] * d
+			power += d * d
+		}
+	}
+	if power == 0 {
+		return 0
+	}
+	return cross / power
+}
+
+// residualEnergy returns the energy of reference - gain·decoded(i-lag) and
+// of reference over their overlap.
+func residualEnergy(reference, decoded []float64, lag int, gain float64) (residual, energy float64) {
+	start, end := overlap(reference, decoded, lag)
+	for i := start; i < end; i++ {
+		e := reference[i] - gain*decoded[i-lag]
+		residual += e * e
+		energy += reference[i] * reference[i]
+	}
+	return residual, energy
+}
+
+// Align finds the lag within ±maxLag frames at which the cross-correlation
+// of reference and decoded, summed over the channels, peaks, and the
+// level-matching gain at that lag.
+func Align(reference, decoded [][]float64, maxLag int) (Alignment, error) {
+	if len(reference) != len(decoded) || len(reference) == 0 {
+		return Alignment{}, fmt.Errorf("channel mismatch: %d reference and %d decoded channels", len(reference), len(decoded))
+	}
+	n := 1
+	for n < len(reference[0])+len(decoded[0]) {
+		n *= 2
+	}
+	plan, err := algofft.NewPlan64(n)
+	if err != nil {
+		return Alignment{}, fmt.Errorf("alignment FFT: %w", err)
+	}
+	sum := make([]complex128, n)
+	ref := make([]complex128, n)
+	dec := make([]complex128, n)
+	for ch := range reference {
+		if err := transform(plan, ref, reference[ch]); err != nil {
+			return Alignment{}, err
+		}
+		if err := transform(plan, dec, decoded[ch]); err != nil {
+			return Alignment{}, err
+		}
+		for k := range sum {
+			sum[k] += ref[k] * cmplx.Conj(dec[k])
+		}
+	}
+	if err := plan.Inverse(sum, sum); err != nil {
+		return Alignment{}, fmt.Errorf("alignment FFT: %w", err)
+	}
+
+	// sum[k] is Σ reference[i]·decoded[i-k], with negative k wrapped.
+	maxLag = min(maxLag, n/2-1)
+	best, peak := 0, math.Inf(-1)
+	for lag := -maxLag; lag <= maxLag; lag++ {
+		if v := real(sum[(lag+n)%n]); v > peak {
+			best, peak = lag, v
+		}
+	}
+	return Alignment{Lag: best, Gain: levelMatch(reference, decoded, best)}, nil
+}
+
+// transform writes the FFT of x, zero-padded, to dst.
+func transform(plan *algofft.Plan[complex128], dst []complex128, x []float64) error {
+	for i := range dst {
+		dst[i] = 0
+	}
+	for i, v := range x {
+		dst[i] = complex(v, 0)
+	}
+	if err := plan.Forward(dst, dst); err != nil {
+		return fmt.Errorf("alignment FFT: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package calibrate_test
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/calibrate"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const rate = 8000
+
+func noise(r *rand.Rand, channels, frames int) [][]float64 {
+	out := make([][]float64, channels)
+	for ch := range out {
+		out[ch] = make([]float64, frames)
+		for i := range out[ch] {
+			out[ch][i] = 0.2 * (2*r.Float64() - 1)
+		}
+	}
+	return out
+}
+
+// program returns the SQ encode of quad noise bursts that move from
+// channel to channel every quarter second over a quiet bed, so that logic
+// steering has dominant sources to act on.
+func program(t *testing.T, seed int64) [][]float64 {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	quad := noise(r, 4, 2*rate)
+	for ch := range quad {
+		for i := range quad[ch] {
+			if (i/(rate/4))%4 != ch {
+				quad[ch][i] *= 0.05
+			}
+		}
+	}
+	stereo, err := encoder.NewSQEncoderWithParams(decoder.DefaultBlockSize, decoder.DefaultOverlap).Process(quad)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return stereo
+}
+
+// hardware decodes input with p and delays and scales the result as a
+// capture of a hardware decoder would be.
+func hardware(t *testing.T, p calibrate.Params, input [][]float64, lag int, gain float64) [][]float64 {
+	t.Helper()
+	d, err := p.NewDecoder(rate, decoder.DefaultBlockSize, decoder.DefaultOverlap)
+	if err != nil {
+		t.Fatalf("NewDecoder: %v", err)
+	}
+	decoded, err := d.Process(input)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return shift(decoded, lag, gain)
+}
+
+// shift returns gain·x delayed by lag frames (advanced for a negative lag).
+func shift(x [][]float64, lag int, gain float64) [][]float64 {
+	out := make([][]float64, len(x))
+	for ch := range x {
+		out[ch] = make([]float64, len(x[ch]))
+		for i := range out[ch] {
+			if j := i - lag; j >= 0 && j < len(x[ch]) {
+				out[ch][i] = gain * x[ch][j]
+			}
+		}
+	}
+	return out
+}
+
+func TestAlign(t *testing.T) {
+	t.Parallel()
+
+	decoded := noise(rand.New(rand.NewSource(1)), 4, 4000)
+	for _, lag := range []int{0, 123, -77} {
+		got, err := calibrate.Align(shift(decoded, lag, 0.5), decoded, 200)
+		if err != nil {
+			t.Fatalf("Align() error = %v", err)
+		}
+		if got.Lag != lag || math.Abs(got.Gain-0.5) > 1e-9 {
+			t.Fatalf("Align() = %+v, want lag %d, gain 0.5", got, lag)
+		}
+	}
+
+	// A lag outside the search range is not found.
+	if got, err := calibrate.Align(shift(decoded, 300, 1), decoded, 200); err != nil || got.Lag == 300 {
+		t.Fatalf("Align() beyond the range = %+v, %v", got, err)
+	}
+	if _, err := calibrate.Align(decoded[:2], decoded, 10); err == nil {
+		t.Fatal("Align() accepted a channel mismatch")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	r := rand.New(rand.NewSource(2))
+	decoded := noise(r, 4, 4000)
+	reference := shift(decoded, 10, 2)
+	// Disturb LB by noise 20 dB below the reference.
+	disturbance := noise(r, 1, 4000)[0]
+	for i := range reference[2] {
+		reference[2][i] += 0.2 * disturbance[i]
+	}
+
+	m := calibrate.Evaluate(reference, decoded, calibrate.Alignment{Lag: 10, Gain: 2})
+	for ch, got := range m.Channels {
+		if ch == 2 {
+			if math.Abs(got+20) > 0.5 {
+				t.Fatalf("LB match = %v dB, want -20", got)
+			}
+		} else if !math.IsInf(got, -1) {
+			t.Fatalf("channel %d match = %v dB, want a perfect match", ch, got)
+		}
+	}
+	if m.Total < -27 || m.Total > -25 {
+		t.Fatalf("total match = %v dB, want about -26 (one of four channels at -20)", m.Total)
+	}
+}
+
+func TestSearch_RecoversParameters(t *testing.T) {
+	t.Parallel()
+
+	want := calibrate.DefaultParams()
+	want.FrontBlend = 0.2
+	want.Window = sqmath.WindowBlackman
+	want.Logic = true
+	want.DominanceThreshold = 0.6
+	want.MaxBoost = 1.4
+
+	// Two captures with different offsets and levels.
+	var pairs []calibrate.Pair
+	for i, capture := range []struct {
+		lag  int
+		gain float64
+	}{{317, 0.7}, {-45, 1.3}} {
+		input := program(t, int64(10+i))
+		pairs = append(pairs, calibrate.Pair{
+			Name:      "capture",
+			Input:     input,
+			Reference: hardware(t, want, input, capture.lag, capture.gain),
+		})
+	}
+	result, err := calibrate.Search(pairs, calibrate.Config{
+		SampleRate: rate,
+		BlockSize:  decoder.DefaultBlockSize,
+		Overlap:    decoder.DefaultOverlap,
+		MaxLag:     rate / 10,
+		Rounds:     3,
+		Start:      calibrate.DefaultParams(),
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if result.Params != want {
+		t.Fatalf("Search() = %v, want %v", result.Params, want)
+	}
+	if result.Match.Total > -60 || result.Initial.Total < -30 {
+		t.Fatalf("match %v dB from %v dB, want below -60 from the start's poor match", result.Match.Total, result.Initial.Total)
+	}
+	for i, want := range []calibrate.Alignment{{Lag: 317, Gain: 0.7}, {Lag: -45, Gain: 1.3}} {
+		if got := result.Alignments[i]; got.Lag != want.Lag || math.Abs(got.Gain-want.Gain) > 1e-6 {
+			t.Fatalf("pair %d alignment = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestSearch_Errors(t *testing.T) {
+	t.Parallel()
+
+	config := calibrate.Config{SampleRate: rate, BlockSize: decoder.DefaultBlockSize, Overlap: decoder.DefaultOverlap, Start: calibrate.DefaultParams()}
+	if _, err := calibrate.Search(nil, config); err == nil {
+		t.Fatal("Search() without pairs succeeded")
+	}
+	stereo := noise(rand.New(rand.NewSource(3)), 2, 100)
+	if _, err := calibrate.Search([]calibrate.Pair{{Name: "x", Input: stereo, Reference: stereo}}, config); err == nil {
+		t.Fatal("Search() accepted a stereo reference")
+	}
+	quad := noise(rand.New(rand.NewSource(4)), 4, 100)
+	bad := config
+	bad.BlockSize = 1000
+	if _, err := calibrate.Search([]calibrate.Pair{{Name: "x", Input: stereo, Reference: quad}}, bad); err == nil || !strings.Contains(err.Error(), "block size 1000 is not a power of two") {
+		t.Fatalf("Search() with block size 1000 error = %v, want the block size rejected", err)
+	}
+}
@@ -0,0 +1,185 @@
+// Package compat checks SQ stereo (LT/RT) for stereo and mono
+// compatibility. Broadcasters reject masters whose LT/RT correlation dips
+// far below zero or whose mono fold-down loses much level, both signs of
+// anti-phase content. SQ produces them by design for back-center sources,
+// so an encode can fail the check on material that decodes perfectly.
+//
+// The check runs in short windows; Fix narrows the stereo image by
+// attenuating the side signal only where a window fails.
+package compat
+
+import (
+	"fmt"
+	"math"
+)
+
+// silenceFloor is the mean square below which a window counts as silent
+// and passes: the correlation of noise near the dither level is
+// meaningless.
+const silenceFloor = 1e-8
+
+// Config holds the limits of the check.
+type Config struct {
+	// WindowSeconds is the length of the analysis windows.
+	WindowSeconds float64
+	// MinCorrelation is the lowest acceptable zero-lag LT/RT correlation,
+	// in [-1, 1].
+	MinCorrelation float64
+	// MaxMonoLossDB is the largest acceptable level of the stereo pair
+	// above its mono fold-down (LT+RT)/2, in dB. Identical channels lose
+	// 0 dB, uncorrelated ones 3 dB.
+	MaxMonoLossDB float64
+	// FadeSeconds is the length of the ramps with which Fix enters and
+	// leaves a corrected window.
+	FadeSeconds float64
+}
+
+// DefaultConfig returns 400 ms windows, a correlation limit of -0.2 and a
+// mono loss limit of 6 dB.
+func DefaultConfig() Config {
+	return Config{
+		WindowSeconds:  0.4,
+		MinCorrelation: -0.2,
+		MaxMonoLossDB:  6,
+		FadeSeconds:    0.01,
+	}
+}
+
+func (c Config) validate() error {
+	if c.WindowSeconds <= 0 {
+		return fmt.Errorf("compatibility window must be positive, got %g s", c.WindowSeconds)
+	}
+	if c.MinCorrelation < -1 || c.MinCorrelation > 1 {
+		return fmt.Errorf("correlation limit %g out of range [-1, 1]", c.MinCorrelation)
+	}
+	if c.MaxMonoLossDB <= 0 {
+		return fmt.Errorf("mono loss limit must be positive, got %g dB", c.MaxMonoLossDB)
+	}
+	if c.FadeSeconds < 0 {
+		return fmt.Errorf("fade must not be negative, got %g s", c.FadeSeconds)
+	}
+	return nil
+}
+
+// Window is the measurement of frames [Start, End).
+type Window struct {
+	Start, End  int
+	Correlation float64
+	// MonoLossDB is +Inf when the fold-down cancels completely.
+	MonoLossDB float64
+	Silent     bool
+}
+
+// Fails reports whether w violates a limit of c.
+func (c Config) Fails(w Window) bool {
+	return !w.Silent && (w.Correlation < c.MinCorrelation || w.MonoLossDB > c.MaxMonoLossDB)
+}
+
+// sums accumulates the products of one window.
+type sums struct {
+	ll, rr, lr float64
+	n          int
+}
+
+func (s sums) window(start int) Window {
+	w := Window{Start: start, End: start + s.n}
+	if s.n == 0 || (s.ll+s.rr)/float64(2*s.n) < silenceFloor {
+		w.Silent = true
+		return w
+	}
+	if s.ll > 0 && s.rr > 0 {
+		w.Correlation = s.lr / math.Sqrt(s.ll*s.rr)
+	}
+	// Σ((l+r)/2)² = (Σl² + Σr² + 2Σlr) / 4
+	mono := (s.ll + s.rr + 2*s.lr) / 4
+	if mono <= 0 {
+		w.MonoLossDB = math.Inf(1)
+	} else {
+		w.MonoLossDB = 10 * math.Log10((s.ll+s.rr)/2/mono)
+	}
+	return w
+}
+
+// Scanner measures a stream of LT/RT samples window by window.
+type Scanner struct {
+	size    int
+	pos     int
+	current sums
+	windows []Window
+}
+
+// NewScanner returns a Scanner for audio at sampleRate.
+func NewScanner(sampleRate int, config Config) (*Scanner, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("sample rate must be positive, got %d", sampleRate)
+	}
+	return &Scanner{size: max(1, int(config.WindowSeconds*float64(sampleRate)))}, nil
+}
+
+// Add measures the next samples; lt and rt must have the same length.
+func (s *Scanner) Add(lt, rt []float64) {
+	for i := range lt {
+		l, r := lt[i], rt[i]
+		s.current.ll += l * l
+		s.current.rr += r * r
+		s.current.lr += l * r
+		s.current.n++
+		if s.current.n == s.size {
+			s.windows = append(s.windows, s.current.window(s.pos))
+			s.pos += s.size
+			s.current = sums{}
+		}
+	}
+}
+
+// Windows returns the measured windows. A trailing partial window counts
+// if it is at least half as long as the others.
+func (s *Scanner) Windows() []Window {
+	windows := s.windows
+	if s.current.n > 0 && (s.current.n >= s.size/2 || len(windows) == 0) {
+		windows = append(windows[:len(windows):len(windows)], s.current.window(s.pos))
+	}
+	return windows
+}
+
+// Scan measures lt and rt in windows of config.WindowSeconds.
+func Scan(lt, rt []float64, sampleRate int, config Config) ([]Window, error) {
+	if len(lt) != len(rt) {
+		return nil, fmt.Errorf("channels must have same length, got %d and %d", len(lt), len(rt))
+	}
+	s, err := NewScanner(sampleRate, config)
+	if err != nil {
+		return nil, err
+	}
+	s.Add(lt, rt)
+	return s.Windows(), nil
+}
+
+// Range is a run of consecutive failing windows with their worst values.
+type Range struct {
+	Start, End     int
+	MinCorrelation float64
+	MaxMonoLossDB  float64
+}
+
+// Failures merges the failing windows into ranges.
+func Failures(windows []Window, config Config) []Range {
+	var ranges []Range
+	for _, w := range windows {
+		if !config.Fails(w) {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].End == w.Start {
+			last := &ranges[n-1]
+			last.End = w.End
+			last.MinCorrelation = math.Min(last.MinCorrelation, w.Correlation)
+			last.MaxMonoLossDB = math.Max(last.MaxMonoLossDB, w.MonoLossDB)
+			continue
+		}
+		ranges = append(ranges, Range{Start: w.Start, End: w.End, MinCorrelation: w.Correlation, MaxMonoLossDB: w.MonoLossDB})
+	}
+	return ranges
+}
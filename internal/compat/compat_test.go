@@ -0,0 +1,193 @@
+package compat_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/compat"
+)
+
+const rate = 8000
+
+// segments returns LT/RT of consecutive one-second segments of a 440 Hz
+// tone; sign[i] is the polarity of RT in segment i relative to LT, and 0
+// leaves RT silent.
+func segments(sign ...float64) (lt, rt []float64) {
+	for _, s := range sign {
+		for i := 0; i < rate; i++ {
+			v := 0.5 * math.Sin(2*math.Pi*440*float64(i)/rate)
+			lt = append(lt, v)
+			rt = append(rt, s*v)
+		}
+	}
+	return lt, rt
+}
+
+func TestScan(t *testing.T) {
+	t.Parallel()
+
+	lt, rt := segments(1, -1, 0, 1)
+	config := compat.DefaultConfig()
+	config.WindowSeconds = 0.5
+	windows, err := compat.Scan(lt, rt, rate, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 8 {
+		t.Fatalf("Scan() = %d windows, want 8", len(windows))
+	}
+	for i, w := range windows {
+		var wantCorr, wantLoss float64
+		switch i / 2 {
+		case 0, 3: // in phase
+			wantCorr, wantLoss = 1, 0
+		case 1: // out of phase
+			wantCorr, wantLoss = -1, math.Inf(1)
+		case 2: // one channel: the fold-down halves the amplitude
+			wantCorr, wantLoss = 0, 10*math.Log10(2)
+		}
+		if math.Abs(w.Correlation-wantCorr) > 1e-9 {
+			t.Fatalf("window %d correlation = %g, want %g", i, w.Correlation, wantCorr)
+		}
+		if math.IsInf(wantLoss, 1) != math.IsInf(w.MonoLossDB, 1) || (!math.IsInf(wantLoss, 1) && math.Abs(w.MonoLossDB-wantLoss) > 1e-9) {
+			t.Fatalf("window %d mono loss = %g dB, want %g", i, w.MonoLossDB, wantLoss)
+		}
+		if w.Start != i*rate/2 || w.End != (i+1)*rate/2 {
+			t.Fatalf("window %d spans [%d, %d)", i, w.Start, w.End)
+		}
+	}
+
+	ranges := compat.Failures(windows, config)
+	if len(ranges) != 1 || ranges[0].Start != rate || ranges[0].End != 2*rate {
+		t.Fatalf("Failures() = %+v, want the out-of-phase second only", ranges)
+	}
+}
+
+func TestScan_Streaming(t *testing.T) {
+	t.Parallel()
+
+	lt, rt := segments(1, -1, 1)
+	want, err := compat.Scan(lt, rt, rate, compat.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := compat.NewScanner(rate, compat.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for start := 0; start < len(lt); start += 1000 {
+		end := min(start+1000, len(lt))
+		s.Add(lt[start:end], rt[start:end])
+	}
+	got := s.Windows()
+	if len(got) != len(want) {
+		t.Fatalf("streamed %d windows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("window %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestScan_Silence(t *testing.T) {
+	t.Parallel()
+
+	lt, rt := make([]float64, rate), make([]float64, rate)
+	for i := range lt {
+		lt[i], rt[i] = 1e-6, -1e-6
+	}
+	windows, err := compat.Scan(lt, rt, rate, compat.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges := compat.Failures(windows, compat.DefaultConfig()); len(ranges) != 0 {
+		t.Fatalf("anti-phase noise below the floor fails: %+v", ranges)
+	}
+}
+
+func TestFix(t *testing.T) {
+	t.Parallel()
+
+	// An out-of-phase second with some in-phase content of another tone,
+	// between two in-phase seconds.
+	lt, rt := segments(1, -1, 1)
+	for i := rate; i < 2*rate; i++ {
+		u := 0.15 * math.Sin(2*math.Pi*660*float64(i)/rate)
+		lt[i] += u
+		rt[i] += u
+	}
+	orig := append([]float64(nil), lt...)
+
+	config := compat.DefaultConfig()
+	corrections, err := compat.Fix(lt, rt, rate, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corrections) == 0 {
+		t.Fatalf("Fix() corrected nothing")
+	}
+	for _, c := range corrections {
+		if c.Start < rate || c.End > 2*rate {
+			t.Fatalf("Fix() corrected [%d, %d) outside the out-of-phase second", c.Start, c.End)
+		}
+		if c.Width <= 0 || c.Width >= 1 {
+			t.Fatalf("correction width = %g, want in (0, 1)", c.Width)
+		}
+	}
+
+	windows, err := compat.Scan(lt, rt, rate, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges := compat.Failures(windows, config); len(ranges) != 0 {
+		t.Fatalf("still failing after Fix(): %+v", ranges)
+	}
+	// The limit is met closely, not by collapsing to mono.
+	for _, w := range windows {
+		if w.Start >= rate && w.End <= 2*rate && w.Correlation > config.MinCorrelation+0.01 && w.MonoLossDB < config.MaxMonoLossDB-0.01 {
+			t.Fatalf("window [%d, %d) narrowed further than needed: correlation %g, mono loss %g dB",
+				w.Start, w.End, w.Correlation, w.MonoLossDB)
+		}
+	}
+	// Outside the fades the in-phase seconds are untouched.
+	fade := int(config.FadeSeconds * rate)
+	for i := range orig {
+		if (i < rate-fade || i >= 2*rate+fade) && lt[i] != orig[i] {
+			t.Fatalf("lt[%d] changed from %g to %g", i, orig[i], lt[i])
+		}
+	}
+}
+
+func TestFix_PureAntiPhase(t *testing.T) {
+	t.Parallel()
+
+	lt, rt := segments(-1)
+	corrections, err := compat.Fix(lt, rt, rate, compat.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range corrections {
+		if c.Width != 0 {
+			t.Fatalf("pure anti-phase width = %g, want 0", c.Width)
+		}
+	}
+	for i := range lt {
+		if lt[i] != 0 || rt[i] != 0 {
+			t.Fatalf("sample %d = %g, %g after removing pure anti-phase content", i, lt[i], rt[i])
+		}
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	config := compat.DefaultConfig()
+	config.MinCorrelation = -2
+	if _, err := compat.NewScanner(rate, config); err == nil {
+		t.Fatalf("correlation limit -2 accepted")
+	}
+	if _, err := compat.NewScanner(0, compat.DefaultConfig()); err == nil {
+		t.Fatalf("sample rate 0 accepted")
+	}
+}
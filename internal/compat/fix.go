@@ -0,0 +1,109 @@
+package compat
+
+import "math"
+
+// widthSteps is the number of bisection steps of the width search; 2^-30
+// is far below any audible difference.
+const widthSteps = 30
+
+// fixMargin is how far inside the limits, in correlation and in dB, Fix
+// aims, so that rounding does not fail the corrected window on a re-scan.
+const fixMargin = 1e-3
+
+// Correction is a window Fix narrowed, with its measurement before the
+// correction and the side gain applied.
+type Correction struct {
+	Window
+	Width float64
+}
+
+// Fix narrows the stereo image of lt and rt in place wherever a window
+// fails config: the side signal (LT-RT)/2 is scaled by the largest width in
+// [0, 1] with which the window passes, the mid signal (LT+RT)/2 is kept.
+// The width returns to 1 over config.FadeSeconds outside each corrected
+// window, so the windows themselves are corrected in full. A window of
+// pure anti-phase content has no mid signal and is silenced.
+func Fix(lt, rt []float64, sampleRate int, config Config) ([]Correction, error) {
+	windows, err := Scan(lt, rt, sampleRate, config)
+	if err != nil {
+		return nil, err
+	}
+	fade := int(config.FadeSeconds * float64(sampleRate))
+
+	var corrections []Correction
+	var gain []float64
+	for _, w := range windows {
+		if !config.Fails(w) {
+			continue
+		}
+		width := passingWidth(lt[w.Start:w.End], rt[w.Start:w.End], config)
+		corrections = append(corrections, Correction{Window: w, Width: width})
+		if gain == nil {
+			gain = make([]float64, len(lt))
+			for i := range gain {
+				gain[i] = 1
+			}
+		}
+		for i := max(0, w.Start-fade); i < min(len(gain), w.End+fade); i++ {
+			g := width
+			switch {
+			case i < w.Start:
+				g += (1 - width) * float64(w.Start-i) / float64(fade+1)
+			case i >= w.End:
+				g += (1 - width) * float64(i-w.End+1) / float64(fade+1)
+			}
+			gain[i] = math.Min(gain[i], g)
+		}
+	}
+
+	for i, g := range gain {
+		if g == 1 {
+			continue
+		}
+		mid := (lt[i] + rt[i]) / 2
+		side := g * (lt[i] - rt[i]) / 2
+		lt[i], rt[i] = mid+side, mid-side
+	}
+	return corrections, nil
+}
+
+// passingWidth returns the largest side gain with which lt and rt pass
+// config.
+func passingWidth(lt, rt []float64, config Config) float64 {
+	var mm, ss, ms float64
+	for i := range lt {
+		m, s := (lt[i]+rt[i])/2, (lt[i]-rt[i])/2
+		mm += m * m
+		ss += s * s
+		ms += m * s
+	}
+	if mm == 0 {
+		return 0
+	}
+	target := config
+	target.MinCorrelation += fixMargin
+	target.MaxMonoLossDB -= fixMargin
+	// With side gain g, l = m+g·s and r = m-g·s.
+	passes := func(g float64) bool {
+		win := sums{
+			ll: mm + g*g*ss + 2*g*ms,
+			rr: mm + g*g*ss - 2*g*ms,
+			lr: mm - g*g*ss,
+			n:  len(lt),
+		}
+		return !target.Fails(win.window(0))
+	}
+	if passes(1) {
+		return 1
+	}
+	lo, hi := 0.0, 1.0
+	for i := 0; i < widthSteps; i++ {
+		mid := (lo + hi) / 2
+		if passes(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
@@ -0,0 +1,213 @@
+// Package cue reads track lists for splitting a decoded album side: CD cue
+// sheets and a simple JSON list.
+package cue
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sheet is a parsed track list.
+type Sheet struct {
+	// Title and Performer describe the album.
+	Title     string
+	Performer string
+	// Files is the number of FILE entries of a cue sheet; a JSON list
+	// counts as one file.
+	Files  int
+	Tracks []Track
+}
+
+// Track is one track of a Sheet.
+type Track struct {
+	Number    int
+	Title     string
+	Performer string
+	// File is the index of the FILE entry the track belongs to.
+	File int
+	// Start is the position of INDEX 01 within the track's file.
+	Start time.Duration
+}
+
+// StartFrame returns the track start in sample frames at sampleRate.
+func (t Track) StartFrame(sampleRate int) int {
+	return int(math.Round(t.Start.Seconds() * float64(sampleRate)))
+}
+
+// Load reads a cue sheet, or a JSON track list when path ends in .json.
+func Load(path string) (*Sheet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read track list: %w", err)
+	}
+	defer f.Close()
+
+	var sheet *Sheet
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		sheet, err = ParseJSON(f)
+	} else {
+		sheet, err = Parse(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return sheet, nil
+}
+
+// Parse reads a cue sheet. Only the commands that describe tracks and
+// their positions are interpreted; INDEX 00 (pregap) and other commands
+// are ignored.
+func Parse(r io.Reader) (*Sheet, error) {
+	sheet := &Sheet{}
+	var track *Track
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		command, rest, _ := strings.Cut(line, " ")
+		command = strings.ToUpper(command)
+		rest = strings.TrimSpace(rest)
+
+		switch command {
+		case "FILE":
+			sheet.Files++
+			track = nil
+		case "TRACK":
+			numberField, _, _ := strings.Cut(rest, " ")
+			number, err := strconv.Atoi(numberField)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid track number %q", lineNo, numberField)
+			}
+			sheet.Tracks = append(sheet.Tracks, Track{Number: number, File: max(sheet.Files-1, 0), Start: -1})
+			track = &sheet.Tracks[len(sheet.Tracks)-1]
+		case "TITLE", "PERFORMER":
+			value := unquote(rest)
+			switch {
+			case track == nil && command == "TITLE":
+				sheet.Title = value
+			case track == nil:
+				sheet.Performer = value
+			case command == "TITLE":
+				track.Title = value
+			default:
+				track.Performer = value
+			}
+		case "INDEX":
+			numberField, timeField, _ := strings.Cut(rest, " ")
+			if track == nil {
+				return nil, fmt.Errorf("line %d: INDEX outside a TRACK", lineNo)
+			}
+			if numberField != "01" && numberField != "1" {
+				continue
+			}
+			start, err := parseTime(strings.TrimSpace(timeField))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			track.Start = start
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sheet.Files = max(sheet.Files, 1)
+	for _, t := range sheet.Tracks {
+		if t.Start < 0 {
+			return nil, fmt.Errorf("track %d has no INDEX 01", t.Number)
+		}
+	}
+	return sheet, sheet.validate()
+}
+
+// jsonSheet is the JSON track list: start times in seconds on the input.
+type jsonSheet struct {
+	Title     string `json:"title"`
+	Performer string `json:"performer"`
+	Tracks    []struct {
+		Title     string  `json:"title"`
+		Performer string  `json:"performer"`
+		Start     float64 `json:"start"`
+	} `json:"tracks"`
+}
+
+// ParseJSON reads a JSON track list of the form
+//
+//	{"title": "...", "performer": "...",
+//	 "tracks": [{"title": "...", "performer": "...", "start": 0}, ...]}
+//
+// with start times in seconds. Tracks are numbered in order from 1.
+func ParseJSON(r io.Reader) (*Sheet, error) {
+	var list jsonSheet
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&list); err != nil {
+		return nil, err
+	}
+	sheet := &Sheet{Title: list.Title, Performer: list.Performer, Files: 1}
+	for i, t := range list.Tracks {
+		if t.Start < 0 || math.IsNaN(t.Start) || math.IsInf(t.Start, 0) {
+			return nil, fmt.Errorf("track %d has invalid start %g", i+1, t.Start)
+		}
+		sheet.Tracks = append(sheet.Tracks, Track{
+			Number:    i + 1,
+			Title:     t.Title,
+			Performer: t.Performer,
+			Start:     time.Duration(math.Round(t.Start * float64(time.Second))),
+		})
+	}
+	return sheet, sheet.validate()
+}
+
+// validate checks that there are tracks and that they start in order
+// within every file.
+func (s *Sheet) validate() error {
+	if len(s.Tracks) == 0 {
+		return fmt.Errorf("no tracks")
+	}
+	for i := 1; i < len(s.Tracks); i++ {
+		prev, t := s.Tracks[i-1], s.Tracks[i]
+		if t.File == prev.File && t.Start <= prev.Start {
+			return fmt.Errorf("track %d starts at %v, not after track %d at %v", t.Number, t.Start, prev.Number, prev.Start)
+		}
+	}
+	return nil
+}
+
+// parseTime parses a cue time mm:ss:ff, with ff in CD frames of 1/75 s.
+func parseTime(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %q (want mm:ss:ff)", s)
+	}
+	var v [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid time %q (want mm:ss:ff)", s)
+		}
+		v[i] = n
+	}
+	if v[1] >= 60 || v[2] >= 75 {
+		return 0, fmt.Errorf("invalid time %q (seconds < 60, frames < 75)", s)
+	}
+	return time.Duration(v[0]*60+v[1])*time.Second + time.Duration(v[2])*time.Second/75, nil
+}
+
+// unquote strips the quotes of a cue string value.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
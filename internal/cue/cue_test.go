@@ -0,0 +1,97 @@
+package cue_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/cue"
+)
+
+const sideA = "\ufeffREM GENRE Rock\r\n" + `PERFORMER "The Quad Band"
+TITLE "Surround Sounds"
+FILE "side_a.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Opening"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Rear Guard"
+    PERFORMER "Guest"
+    INDEX 00 03:10:00
+    INDEX 01 03:12:37
+FILE "side_b.wav" WAVE
+  track 03 audio
+    title Closing
+    index 01 00:01:00
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	sheet, err := cue.Parse(strings.NewReader(sideA))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if sheet.Title != "Surround Sounds" || sheet.Performer != "The Quad Band" || sheet.Files != 2 || len(sheet.Tracks) != 3 {
+		t.Fatalf("Parse() = %+v", sheet)
+	}
+	want := []cue.Track{
+		{Number: 1, Title: "Opening", File: 0, Start: 0},
+		{Number: 2, Title: "Rear Guard", Performer: "Guest", File: 0, Start: 192*time.Second + 37*time.Second/75},
+		{Number: 3, Title: "Closing", File: 1, Start: time.Second},
+	}
+	for i, w := range want {
+		if got := sheet.Tracks[i]; got != w {
+			t.Fatalf("track %d = %+v, want %+v", i+1, got, w)
+		}
+	}
+	// 37 CD frames are 37*588 samples at 44.1 kHz.
+	if got := sheet.Tracks[1].StartFrame(44100); got != 192*44100+37*588 {
+		t.Fatalf("StartFrame(44100) = %d, want %d", got, 192*44100+37*588)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for name, sheet := range map[string]string{
+		"no tracks":     `FILE "a.wav" WAVE`,
+		"no index":      "TRACK 01 AUDIO\nTITLE x",
+		"bad time":      "TRACK 01 AUDIO\nINDEX 01 00:61:00",
+		"out of order":  "TRACK 01 AUDIO\nINDEX 01 01:00:00\nTRACK 02 AUDIO\nINDEX 01 00:30:00",
+		"index outside": "INDEX 01 00:00:00",
+	} {
+		if _, err := cue.Parse(strings.NewReader(sheet)); err == nil {
+			t.Fatalf("%s: Parse() expected error", name)
+		}
+	}
+}
+
+func TestLoad_JSON(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tracks.json")
+	list := `{"title": "Side A", "tracks": [{"title": "One", "start": 0}, {"title": "Two", "performer": "P", "start": 95.5}]}`
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := cue.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if sheet.Title != "Side A" || sheet.Files != 1 || len(sheet.Tracks) != 2 {
+		t.Fatalf("Load() = %+v", sheet)
+	}
+	if tr := sheet.Tracks[1]; tr.Number != 2 || tr.Performer != "P" || tr.StartFrame(48000) != 95.5*48000 {
+		t.Fatalf("track 2 = %+v", tr)
+	}
+
+	if _, err := cue.ParseJSON(strings.NewReader(`{"tracks": [{"start": 10}, {"start": 5}]}`)); err == nil {
+		t.Fatalf("ParseJSON(out of order) expected error")
+	}
+	if _, err := cue.ParseJSON(strings.NewReader(`{"tracks": [{"begin": 10}]}`)); err == nil {
+		t.Fatalf("ParseJSON(unknown field) expected error")
+	}
+}
@@ -0,0 +1,381 @@
+package decoder
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// AdaptiveConfig controls the experimental adaptive block size mode, which
+// decodes transient-dense regions with short blocks (less pre-echo in the
+// phase shifter) and steady regions with long ones (better separation in
+// the bass).
+type AdaptiveConfig struct {
+	// BlockSizes are the allowed block sizes in ascending order. The
+	// overlap of each is OverlapRatio times the block size.
+	BlockSizes   []int
+	OverlapRatio float64
+	// Thresholds are the transient densities in onsets per second at or
+	// above which BlockSizes[i] is chosen, in descending order and one
+	// fewer than BlockSizes; below the last one the longest block is used.
+	Thresholds []float64
+	// RegionSeconds is the length of an analysis region.
+	RegionSeconds float64
+	// Crossfade is the length in frames of the crossfade between the
+	// outputs of two block sizes, centered on a region boundary.
+	Crossfade int
+}
+
+// DefaultAdaptiveConfig returns half, once and twice blockSize with the
+// overlap ratio of blockSize and overlap, 2 s regions, thresholds of 4 and
+// 1 onsets per second and a crossfade of one long-block hop.
+func DefaultAdaptiveConfig(blockSize, overlap int) AdaptiveConfig {
+	return AdaptiveConfig{
+		BlockSizes:    []int{blockSize / 2, blockSize, blockSize * 2},
+		OverlapRatio:  float64(overlap) / float64(blockSize),
+		Thresholds:    []float64{4, 1},
+		RegionSeconds: 2,
+		Crossfade:     overlap * 2,
+	}
+}
+
+func (c AdaptiveConfig) validate() error {
+	if len(c.BlockSizes) == 0 || len(c.Thresholds) != len(c.BlockSizes)-1 {
+		return fmt.Errorf("adaptive mode needs n block sizes and n-1 thresholds, got %d and %d", len(c.BlockSizes), len(c.Thresholds))
+	}
+	if c.OverlapRatio <= 0 || c.OverlapRatio > 1 {
+		return fmt.Errorf("overlap ratio must be in (0, 1], got %g", c.OverlapRatio)
+	}
+	for i, size := range c.BlockSizes {
+		if size < 64 || size&(size-1) != 0 || (i > 0 && size <= c.BlockSizes[i-1]) {
+			return fmt.Errorf("adaptive block sizes must be ascending powers of 2 >= 64, got %v", c.BlockSizes)
+		}
+		if c.overlap(size) <= 0 {
+			return fmt.Errorf("overlap ratio %g gives no overlap for block size %d", c.OverlapRatio, size)
+		}
+	}
+	for i := 1; i < len(c.Thresholds); i++ {
+		if c.Thresholds[i] >= c.Thresholds[i-1] {
+			return fmt.Errorf("adaptive thresholds must be descending, got %v", c.Thresholds)
+		}
+	}
+	if c.RegionSeconds <= 0 {
+		return fmt.Errorf("region length must be > 0, got %g", c.RegionSeconds)
+	}
+	if c.Crossfade < 0 {
+		return fmt.Errorf("crossfade must be >= 0, got %d", c.Crossfade)
+	}
+	return nil
+}
+
+func (c AdaptiveConfig) overlap(blockSize int) int {
+	return int(float64(blockSize) * c.OverlapRatio)
+}
+
+// AdaptiveRegion is a stretch of input decoded with one block size.
+type AdaptiveRegion struct {
+	Start, End int
+	BlockSize  int
+	// Density is the transient density of the region in onsets per second.
+	Density float64
+}
+
+// Transient detection works on short frames of the first difference of
+// both channels, which emphasizes the high-frequency energy of attacks.
+const (
+	transientFrameSeconds = 0.005
+	// transientHistory is the number of frames the onset energy is compared
+	// against.
+	transientHistory = 8
+	// transientRatio is the energy jump over the recent average that counts
+	// as an onset (about 9 dB).
+	transientRatio = 8
+	// transientFloor is the frame energy per sample below which no onset is
+	// counted (-80 dBFS).
+	transientFloor = 1e-8
+)
+
+// TransientAnalyzer is the first pass of the adaptive mode. It counts
+// onsets per region of the input and plans a block size for each.
+type TransientAnalyzer struct {
+	config       AdaptiveConfig
+	sampleRate   int
+	frameLen     int
+	regionFrames int
+
+	prev       [2]float64
+	energy     float64
+	inFrame    int
+	history    []float64
+	onsets     int
+	regionPos  int
+	pos        int
+	lastOnset  bool
+	densities  []float64
+	regionEnds []int
+}
+
+// NewTransientAnalyzer returns an analyzer for input at sampleRate.
+func NewTransientAnalyzer(sampleRate int, config AdaptiveConfig) (*TransientAnalyzer, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("sample rate must be > 0, got %d", sampleRate)
+	}
+	// Regions are whole hops of the longest block, so boundaries fall on
+	// block starts of every size.
+	hop := config.overlap(config.BlockSizes[len(config.BlockSizes)-1])
+	regionFrames := max(1, int(math.Round(config.RegionSeconds*float64(sampleRate)/float64(hop)))) * hop
+	return &TransientAnalyzer{
+		config:       config,
+		sampleRate:   sampleRate,
+		frameLen:     max(16, int(transientFrameSeconds*float64(sampleRate))),
+		regionFrames: regionFrames,
+	}, nil
+}
+
+// Add analyzes the next stereo frames ([channel][frame]).
+func (a *TransientAnalyzer) Add(frames [][]float64) {
+	for i := range frames[0] {
+		for ch := range 2 {
+			d := frames[ch][i] - a.prev[ch]
+			a.prev[ch] = frames[ch][i]
+			a.energy += d * d
+		}
+		a.inFrame++
+		if a.inFrame == a.frameLen {
+			a.closeFrame()
+		}
+		a.pos++
+		a.regionPos++
+		if a.regionPos == a.regionFrames {
+			a.closeRegion()
+		}
+	}
+}
+
+// closeFrame counts an onset when the frame energy jumps over the recent
+// average; a sustained rise counts once.
+func (a *TransientAnalyzer) closeFrame() {
+	e := a.energy / float64(a.frameLen)
+	a.energy, a.inFrame = 0, 0
+
+	onset := false
+	if len(a.history) == transientHistory && e > transientFloor {
+		var mean float64
+		for _, h := range a.history {
+			mean += h
+		}
+		mean /= transientHistory
+		onset = e > transientRatio*mean
+	}
+	if onset && !a.lastOnset {
+		a.onsets++
+	}
+	a.lastOnset = onset
+
+	if len(a.history) == transientHistory {
+		a.history = a.history[1:]
+	}
+	a.history = append(a.history, e)
+}
+
+func (a *TransientAnalyzer) closeRegion() {
+	seconds := float64(a.regionPos) / float64(a.sampleRate)
+	a.densities = append(a.densities, float64(a.onsets)/seconds)
+	a.regionEnds = append(a.regionEnds, a.pos)
+	a.onsets, a.regionPos = 0, 0
+}
+
+// Plan returns the regions of the input analyzed so far with their block
+// sizes. Neighbouring regions with the same block size are merged; the
+// density of a merged region is the length-weighted average of its parts.
+func (a *TransientAnalyzer) Plan() []AdaptiveRegion {
+	densities, ends := a.densities, a.regionEnds
+	if a.regionPos > 0 {
+		seconds := float64(a.regionPos) / float64(a.sampleRate)
+		densities = append(slices.Clone(densities), float64(a.onsets)/seconds)
+		ends = append(slices.Clone(ends), a.pos)
+	}
+
+	var plan []AdaptiveRegion
+	start := 0
+	for i, density := range densities {
+		size := a.config.blockSizeFor(density)
+		if n := len(plan); n > 0 && plan[n-1].BlockSize == size {
+			last := &plan[n-1]
+			prev, cur := float64(last.End-last.Start), float64(ends[i]-start)
+			last.Density = (last.Density*prev + density*cur) / (prev + cur)
+			last.End = ends[i]
+		} else {
+			plan = append(plan, AdaptiveRegion{Start: start, End: ends[i], BlockSize: size, Density: density})
+		}
+		start = ends[i]
+	}
+	return plan
+}
+
+// blockSizeFor returns the block size for a transient density.
+func (c AdaptiveConfig) blockSizeFor(density float64) int {
+	for i, threshold := range c.Thresholds {
+		if density >= threshold {
+			return c.BlockSizes[i]
+		}
+	}
+	return c.BlockSizes[len(c.BlockSizes)-1]
+}
+
+// AdaptiveDecoder decodes with the block size of a plan's regions. Every
+// block size the plan uses has its own decoder, which runs over the whole
+// input: its state (logic steering envelopes, bass management filters) is
+// therefore settled when its region begins. The outputs are aligned to the
+// one of the longest block and crossfaded around each boundary, so that
+// the switch is free of discontinuities.
+type AdaptiveDecoder struct {
+	plan      []AdaptiveRegion
+	crossfade int
+	decoders  map[int]*SQDecoder
+	// shifts are the frames the input of each decoder is advanced by to
+	// align its output with the longest block's.
+	shifts    map[int]int
+	sizes     []int
+	hop       int
+	lookahead int
+	pos       int
+}
+
+// NewAdaptiveDecoder returns a decoder for plan. newDecoder creates a
+// decoder configured alike for a block size and overlap.
+func NewAdaptiveDecoder(plan []AdaptiveRegion, config AdaptiveConfig, newDecoder func(blockSize, overlap int) *SQDecoder) (*AdaptiveDecoder, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("empty adaptive plan")
+	}
+	a := &AdaptiveDecoder{
+		plan:      plan,
+		crossfade: config.Crossfade,
+		decoders:  make(map[int]*SQDecoder),
+		shifts:    make(map[int]int),
+	}
+	for i, region := range plan {
+		if i > 0 && region.Start != plan[i-1].End {
+			return nil, fmt.Errorf("adaptive region %d starts at %d, previous ends at %d", i, region.Start, plan[i-1].End)
+		}
+		if !slices.Contains(config.BlockSizes, region.BlockSize) {
+			return nil, fmt.Errorf("adaptive region %d has block size %d outside %v", i, region.BlockSize, config.BlockSizes)
+		}
+		if _, ok := a.decoders[region.BlockSize]; !ok {
+			a.decoders[region.BlockSize] = newDecoder(region.BlockSize, config.overlap(region.BlockSize))
+			a.sizes = append(a.sizes, region.BlockSize)
+		}
+	}
+	slices.Sort(a.sizes)
+
+	longest := a.decoders[a.sizes[len(a.sizes)-1]]
+	a.hop = longest.overlap
+	for _, size := range a.sizes {
+		d := a.decoders[size]
+		a.shifts[size] = longest.inputAdvance() - d.inputAdvance()
+		a.lookahead = max(a.lookahead, d.blockSize-d.overlap+a.shifts[size])
+	}
+	return a, nil
+}
+
+// inputAdvance returns how far the direct path of the output leads the
+// input: output frame i carries input frame i+inputAdvance.
+func (d *SQDecoder) inputAdvance() int {
+	if d.noOverlap() {
+		return 0
+	}
+	return d.overlap / 4
+}
+
+// Hop returns the segment alignment ProcessSegment needs: the overlap of
+// the longest block, a multiple of every other.
+func (a *AdaptiveDecoder) Hop() int {
+	return a.hop
+}
+
+// Lookahead returns the input ProcessSegment needs past numOutput.
+func (a *AdaptiveDecoder) Lookahead() int {
+	return a.lookahead
+}
+
+// ProcessSegment decodes the first numOutput samples of input and uses the
+// rest as lookahead, under the conditions of SQDecoder.ProcessSegment with
+// Hop as the overlap and Lookahead as the lookahead. The output is timed
+// like that of an SQDecoder with the longest block size.
+func (a *AdaptiveDecoder) ProcessSegment(input [][]float64, numOutput int) ([][]float64, error) {
+	if len(input) != 2 || len(input[1]) != len(input[0]) {
+		return nil, fmt.Errorf("input must have 2 channels of equal length")
+	}
+	outputs := make(map[int][][]float64, len(a.decoders))
+	for _, size := range a.sizes {
+		shifted := input
+		if shift := a.shifts[size]; shift > 0 {
+			shifted = make([][]float64, 2)
+			for ch := range shifted {
+				shifted[ch] = make([]float64, len(input[ch]))
+				if shift < len(input[ch]) {
+					copy(shifted[ch], input[ch][shift:])
+				}
+			}
+		}
+		out, err := a.decoders[size].ProcessSegment(shifted, numOutput)
+		if err != nil {
+			return nil, err
+		}
+		outputs[size] = out
+	}
+
+	output := make([][]float64, len(outputs[a.sizes[0]]))
+	for ch := range output {
+		output[ch] = make([]float64, numOutput)
+	}
+	region := 0
+	for i := range numOutput {
+		pos := a.pos + i
+		for region+1 < len(a.plan) && pos >= a.plan[region].End {
+			region++
+		}
+		cur := outputs[a.plan[region].BlockSize]
+		other, weight := a.crossfadePartner(region, pos)
+		for ch := range output {
+			v := cur[ch][i]
+			if other != 0 {
+				v = weight*v + (1-weight)*outputs[other][ch][i]
+			}
+			output[ch][i] = v
+		}
+	}
+	a.pos += numOutput
+	return output, nil
+}
+
+// crossfadePartner returns the block size of the neighbouring region whose
+// output is mixed in at pos and the weight of the current region, or 0 when
+// pos is outside a crossfade. The weight rises along a sine from 0.5 at the
+// boundary to 1 half a crossfade away.
+func (a *AdaptiveDecoder) crossfadePartner(region, pos int) (int, float64) {
+	half := float64(a.crossfade) / 2
+	if half == 0 {
+		return 0, 1
+	}
+	r := a.plan[region]
+	if region > 0 && float64(pos-r.Start) < half {
+		return a.plan[region-1].BlockSize, crossfadeWeight(float64(pos-r.Start)+0.5, half)
+	}
+	if region+1 < len(a.plan) && float64(r.End-pos) <= half {
+		return a.plan[region+1].BlockSize, crossfadeWeight(float64(r.End-pos)-0.5, half)
+	}
+	return 0, 1
+}
+
+// crossfadeWeight returns the weight of a region at distance d from its
+// boundary for a crossfade half length half.
+func crossfadeWeight(d, half float64) float64 {
+	return 0.5 + 0.5*math.Sin(math.Pi/2*math.Min(d/half, 1))
+}
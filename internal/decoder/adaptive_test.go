@@ -0,0 +1,215 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+// steadyThenClicks returns 1 s of steady tones followed by 1 s of the same
+// tones with a click every 50 ms.
+func steadyThenClicks(sampleRate int) [][]float64 {
+	n := 2 * sampleRate
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	clickEvery := sampleRate / 20
+	for i := range n {
+		ts := float64(i) / float64(sampleRate)
+		lt[i] = 0.3 * math.Sin(2*math.Pi*220*ts)
+		rt[i] = 0.3 * math.Sin(2*math.Pi*330*ts+1)
+		if i >= sampleRate {
+			if k := (i - sampleRate) % clickEvery; k < 64 {
+				click := 0.4 * math.Exp(-float64(k)/8) * math.Sin(2*math.Pi*float64(k)/7)
+				lt[i] += click
+				rt[i] -= click
+			}
+		}
+	}
+	return [][]float64{lt, rt}
+}
+
+func adaptiveTestConfig() decoder.AdaptiveConfig {
+	config := decoder.DefaultAdaptiveConfig(1024, 512)
+	config.RegionSeconds = 0.25
+	return config
+}
+
+func newLogicDecoder(sampleRate int) func(blockSize, overlap int) *decoder.SQDecoder {
+	return func(blockSize, overlap int) *decoder.SQDecoder {
+		d := decoder.NewSQDecoderWithParams(blockSize, overlap)
+		d.SetSampleRate(sampleRate)
+		d.EnableLogicSteering(true)
+		return d
+	}
+}
+
+func TestTransientAnalyzer_PlansShortBlocksForTransients(t *testing.T) {
+	t.Parallel()
+
+	const rate = 44100
+	config := adaptiveTestConfig()
+	analyzer, err := decoder.NewTransientAnalyzer(rate, config)
+	if err != nil {
+		t.Fatalf("NewTransientAnalyzer() error = %v", err)
+	}
+	input := steadyThenClicks(rate)
+	// Feeding in uneven chunks gives the same plan as one call.
+	for start := 0; start < len(input[0]); start += 1000 {
+		end := min(start+1000, len(input[0]))
+		analyzer.Add([][]float64{input[0][start:end], input[1][start:end]})
+	}
+	plan := analyzer.Plan()
+
+	// The analysis region holding the onset of the clicks may get a block
+	// size in between.
+	if len(plan) < 2 || len(plan) > 3 {
+		t.Fatalf("plan = %+v, want 2 or 3 regions", plan)
+	}
+	first, last := plan[0], plan[len(plan)-1]
+	if first.Start != 0 || last.End != len(input[0]) {
+		t.Fatalf("plan = %+v does not cover the input", plan)
+	}
+	for i := 1; i < len(plan); i++ {
+		if plan[i].Start != plan[i-1].End || plan[i].BlockSize >= plan[i-1].BlockSize {
+			t.Fatalf("plan = %+v, want contiguous regions of falling block size", plan)
+		}
+	}
+	if first.BlockSize != 2048 || first.Density != 0 || math.Abs(float64(first.End-rate)) > 0.25*rate {
+		t.Fatalf("steady region = %+v, want block 2048, no onsets, ending near %d", first, rate)
+	}
+	if last.BlockSize != 512 || last.Density < 15 || last.Density > 25 || math.Abs(float64(last.Start-rate)) > 0.25*rate {
+		t.Fatalf("click region = %+v, want block 512, about 20 onsets/s, starting near %d", last, rate)
+	}
+}
+
+func TestAdaptiveDecoder_ContinuousAcrossBlockSizeSwitch(t *testing.T) {
+	t.Parallel()
+
+	const rate = 44100
+	config := adaptiveTestConfig()
+	input := steadyThenClicks(rate)
+	n := len(input[0])
+	boundary := 19 * 2048 // 0.88 s, in the steady part before the clicks
+	plan := []decoder.AdaptiveRegion{
+		{Start: 0, End: boundary, BlockSize: 2048},
+		{Start: boundary, End: n, BlockSize: 512},
+	}
+
+	adaptive, err := decoder.NewAdaptiveDecoder(plan, config, newLogicDecoder(rate))
+	if err != nil {
+		t.Fatalf("NewAdaptiveDecoder() error = %v", err)
+	}
+	out, err := adaptive.ProcessSegment(input, n)
+	if err != nil {
+		t.Fatalf("ProcessSegment() error = %v", err)
+	}
+
+	// References: fixed block sizes, the short one aligned to the long one.
+	long, err := newLogicDecoder(rate)(2048, 1024).Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	shift := 1024/4 - 256/4
+	shifted := make([][]float64, 2)
+	for ch := range shifted {
+		shifted[ch] = make([]float64, n)
+		copy(shifted[ch], input[ch][shift:])
+	}
+	short, err := newLogicDecoder(rate)(512, 256).Process(shifted)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	half := config.Crossfade / 2
+	for ch := range out {
+		// Away from the crossfade the output is the decoder of the region.
+		for _, i := range []int{boundary - half - 1, boundary - 4*half, 0} {
+			if out[ch][i] != long[ch][i] {
+				t.Fatalf("ch %d frame %d = %g, want long-block %g", ch, i, out[ch][i], long[ch][i])
+			}
+		}
+		for _, i := range []int{boundary + half, boundary + 4*half, n - 1} {
+			if out[ch][i] != short[ch][i] {
+				t.Fatalf("ch %d frame %d = %g, want short-block %g", ch, i, out[ch][i], short[ch][i])
+			}
+		}
+
+		// Across the switch, the output moves no faster than the decoders
+		// do plus the slope of the crossfade between them; a hard switch
+		// would jump by their difference.
+		var maxStep, maxRefStep, maxDiff float64
+		for i := boundary - 2*half; i < boundary+2*half; i++ {
+			maxStep = math.Max(maxStep, math.Abs(out[ch][i+1]-out[ch][i]))
+			maxRefStep = math.Max(maxRefStep, math.Abs(long[ch][i+1]-long[ch][i]))
+			maxRefStep = math.Max(maxRefStep, math.Abs(short[ch][i+1]-short[ch][i]))
+			maxDiff = math.Max(maxDiff, math.Abs(long[ch][i]-short[ch][i]))
+		}
+		if limit := maxRefStep + maxDiff*math.Pi/(4*float64(half)) + 1e-12; maxStep > limit {
+			t.Fatalf("ch %d: max step %g across the switch, want <= %g (decoders %g, difference %g)", ch, maxStep, limit, maxRefStep, maxDiff)
+		}
+	}
+}
+
+func TestAdaptiveDecoder_SegmentsMatchSingleCall(t *testing.T) {
+	t.Parallel()
+
+	const rate = 44100
+	config := adaptiveTestConfig()
+	input := steadyThenClicks(rate)
+	n := len(input[0])
+	analyzer, err := decoder.NewTransientAnalyzer(rate, config)
+	if err != nil {
+		t.Fatalf("NewTransientAnalyzer() error = %v", err)
+	}
+	analyzer.Add(input)
+	plan := analyzer.Plan()
+
+	whole, err := decoder.NewAdaptiveDecoder(plan, config, newLogicDecoder(rate))
+	if err != nil {
+		t.Fatalf("NewAdaptiveDecoder() error = %v", err)
+	}
+	want, err := whole.ProcessSegment(input, n)
+	if err != nil {
+		t.Fatalf("ProcessSegment() error = %v", err)
+	}
+
+	segmented, err := decoder.NewAdaptiveDecoder(plan, config, newLogicDecoder(rate))
+	if err != nil {
+		t.Fatalf("NewAdaptiveDecoder() error = %v", err)
+	}
+	step := 5 * segmented.Hop()
+	for start := 0; start < n; start += step {
+		numOutput := min(step, n-start)
+		end := min(start+numOutput+segmented.Lookahead(), n)
+		got, err := segmented.ProcessSegment([][]float64{input[0][start:end], input[1][start:end]}, numOutput)
+		if err != nil {
+			t.Fatalf("ProcessSegment() error = %v", err)
+		}
+		for ch := range got {
+			for i, v := range got[ch] {
+				if v != want[ch][start+i] {
+					t.Fatalf("ch %d frame %d = %g, want %g", ch, start+i, v, want[ch][start+i])
+				}
+			}
+		}
+	}
+}
+
+func TestAdaptiveConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	bad := []decoder.AdaptiveConfig{
+		{BlockSizes: []int{512, 1024}, OverlapRatio: 0.5, Thresholds: []float64{4, 1}, RegionSeconds: 1},
+		{BlockSizes: []int{1024, 512}, OverlapRatio: 0.5, Thresholds: []float64{4}, RegionSeconds: 1},
+		{BlockSizes: []int{512, 1000}, OverlapRatio: 0.5, Thresholds: []float64{4}, RegionSeconds: 1},
+		{BlockSizes: []int{512, 1024, 2048}, OverlapRatio: 0.5, Thresholds: []float64{1, 4}, RegionSeconds: 1},
+		{BlockSizes: []int{512, 1024}, OverlapRatio: 0, Thresholds: []float64{4}, RegionSeconds: 1},
+		{BlockSizes: []int{512, 1024}, OverlapRatio: 0.5, Thresholds: []float64{4}},
+	}
+	for i, config := range bad {
+		if _, err := decoder.NewTransientAnalyzer(44100, config); err == nil {
+			t.Fatalf("config %d: NewTransientAnalyzer() expected error", i)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package decoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BackChannelMode selects how the decoded back channels are delivered.
+type BackChannelMode int
+
+const (
+	// BackChannelDiscrete outputs LF, RF, LB, RB (the default).
+	BackChannelDiscrete BackChannelMode = iota
+	// BackChannelSumDiff replaces LB/RB with LB+RB (mono rear) and LB-RB (rear width):
+	// output is LF, RF, LB+RB, LB-RB.
+	BackChannelSumDiff
+	// BackChannelDiscreteAndSumDiff appends the sum/difference channels to the
+	// discrete ones: output is LF, RF, LB, RB, LB+RB, LB-RB.
+	BackChannelDiscreteAndSumDiff
+)
+
+// ParseBackChannelMode converts a CLI name ("discrete", "sumdiff", "both")
+// into a BackChannelMode.
+func ParseBackChannelMode(name string) (BackChannelMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "discrete":
+		return BackChannelDiscrete, nil
+	case "sumdiff", "sum-diff":
+		return BackChannelSumDiff, nil
+	case "both":
+		return BackChannelDiscreteAndSumDiff, nil
+	default:
+		return BackChannelDiscrete, fmt.Errorf("unknown back channel mode %q (want discrete, sumdiff or both)", name)
+	}
+}
+
+// String returns the CLI name of the mode.
+func (m BackChannelMode) String() string {
+	switch m {
+	case BackChannelDiscrete:
+		return "discrete"
+	case BackChannelSumDiff:
+		return "sumdiff"
+	case BackChannelDiscreteAndSumDiff:
+		return "both"
+	default:
+		return fmt.Sprintf("BackChannelMode(%d)", int(m))
+	}
+}
+
+// Channels returns the number of output channels produced in this mode.
+func (m BackChannelMode) Channels() int {
+	if m == BackChannelDiscreteAndSumDiff {
+		return 6
+	}
+	return 4
+}
+
+// ChannelNames returns the output channel labels in order.
+func (m BackChannelMode) ChannelNames() []string {
+	switch m {
+	case BackChannelSumDiff:
+		return []string{"LF", "RF", "LB+RB", "LB-RB"}
+	case BackChannelDiscreteAndSumDiff:
+		return []string{"LF", "RF", "LB", "RB", "LB+RB", "LB-RB"}
+	default:
+		return []string{"LF", "RF", "LB", "RB"}
+	}
+}
+
+// SetBackChannelMode selects discrete or sum/difference back channel output.
+func (d *SQDecoder) SetBackChannelMode(mode BackChannelMode) {
+	d.backMode = mode
+}
+
+// BackChannelMode returns the configured back channel mode.
+func (d *SQDecoder) BackChannelMode() BackChannelMode {
+	return d.backMode
+}
+
+// applyBackChannelMode rearranges the discrete LF, RF, LB, RB output
+// according to mode.
+func applyBackChannelMode(output [][]float64, mode BackChannelMode) [][]float64 {
+	if mode == BackChannelDiscrete {
+		return output
+	}
+
+	lb, rb := output[2], output[3]
+	sum := make([]float64, len(lb))
+	diff := make([]float64, len(lb))
+	for i := range lb {
+		sum[i] = lb[i] + rb[i]
+		diff[i] = lb[i] - rb[i]
+	}
+
+	if mode == BackChannelSumDiff {
+		return [][]float64{output[0], output[1], sum, diff}
+	}
+	return append(output, sum, diff)
+}
@@ -0,0 +1,38 @@
+package decoder
+
+import "github.com/cwbudde/go-sq-tool/pkg/sqmath"
+
+// SetBassManagement folds the bass of the back channels into the fronts
+// for setups with small rear speakers: LB and RB are split by a 4th-order
+// Linkwitz-Riley crossover at crossoverHz, the low band is added to LF and
+// RF respectively and only the high band stays in the rears. The two bands
+// sum to a flat magnitude response, so the total bass level is unchanged.
+// A crossover of 0 disables bass management; crossovers near Nyquist are
+// limited to 0.45 times the sample rate.
+func (d *SQDecoder) SetBassManagement(crossoverHz float64) {
+	d.bassCrossover = max(crossoverHz, 0)
+	d.updateBassFilters()
+}
+
+func (d *SQDecoder) updateBassFilters() {
+	if d.bassCrossover <= 0 || d.sampleRate <= 0 {
+		return
+	}
+	freq := min(d.bassCrossover, 0.45*float64(d.sampleRate))
+	for side := range d.bassSplit {
+		d.bassSplit[side] = sqmath.NewCrossover(float64(d.sampleRate), freq)
+	}
+}
+
+// foldBackBass applies bass management to the discrete LF, RF, LB, RB
+// output in place. The crossover memory carries over to the next segment.
+func (d *SQDecoder) foldBackBass(output [][]float64) {
+	for side := range d.bassSplit {
+		front, back := output[side], output[2+side]
+		for i, v := range back {
+			low, high := d.bassSplit[side].Process(v)
+			front[i] += low
+			back[i] = high
+		}
+	}
+}
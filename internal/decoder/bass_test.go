@@ -0,0 +1,86 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+)
+
+// toneAmplitude returns the amplitude of the freq component of x[from:to].
+func toneAmplitude(x []float64, freq, sampleRate float64, from, to int) float64 {
+	var re, im float64
+	for i := from; i < to; i++ {
+		phase := 2.0 * math.Pi * freq * float64(i) / sampleRate
+		re += x[i] * math.Cos(phase)
+		im += x[i] * math.Sin(phase)
+	}
+	return 2.0 * math.Hypot(re, im) / float64(to-from)
+}
+
+func TestSQDecoder_BassManagement_FoldsBackBassForward(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sampleRate = 44100.0
+		n          = 2 * 44100
+		low        = 40.0
+		high       = 4000.0
+	)
+
+	// LB carries a bass and a treble tone, everything else is silent.
+	quad := [][]float64{make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)}
+	for i := range n {
+		ts := float64(i) / sampleRate
+		quad[2][i] = 0.3*math.Sin(2.0*math.Pi*low*ts) + 0.3*math.Sin(2.0*math.Pi*high*ts)
+	}
+	encoded, err := encoder.NewSQEncoder().Process(quad)
+	if err != nil {
+		t.Fatalf("encoder Process() error = %v", err)
+	}
+
+	decode := func(crossover float64) [][]float64 {
+		d := decoder.NewSQDecoder()
+		d.SetSampleRate(sampleRate)
+		d.SetBassManagement(crossover)
+		out, err := d.Process(encoded)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		return out
+	}
+	plain := decode(0)
+	managed := decode(120)
+
+	// Skip the filter settling time and an integer number of bass periods.
+	from, to := n/4, n/4+int(40*sampleRate/low)
+	amp := func(out [][]float64, ch int, freq float64) float64 {
+		return toneAmplitude(out[ch], freq, sampleRate, from, to)
+	}
+
+	lbLow := amp(plain, 2, low)
+	if lbLow < 0.1 {
+		t.Fatalf("plain LB %g Hz amplitude = %g, want the decoded tone", low, lbLow)
+	}
+	if got := amp(managed, 2, low); got > 0.05*lbLow {
+		t.Fatalf("managed LB %g Hz amplitude = %g, want < 5%% of %g", low, got, lbLow)
+	}
+
+	// The LF change at the bass frequency matches the bass removed from LB
+	// (up to the crossover droop at a third of its frequency).
+	diff := make([]float64, n)
+	for i := range diff {
+		diff[i] = managed[0][i] - plain[0][i]
+	}
+	if got := toneAmplitude(diff, low, sampleRate, from, to); math.Abs(got-lbLow) > 0.05*lbLow {
+		t.Fatalf("LF %g Hz gain = %g, want ~%g", low, got, lbLow)
+	}
+
+	for _, ch := range []int{0, 2} {
+		want := amp(plain, ch, high)
+		if got := amp(managed, ch, high); math.Abs(got-want) > 0.01*max(want, 0.01) {
+			t.Fatalf("channel %d %g Hz amplitude = %g, want %g", ch, high, got, want)
+		}
+	}
+}
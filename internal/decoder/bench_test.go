@@ -0,0 +1,181 @@
+package decoder_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const (
+	benchSampleRate = 44100
+	benchSeconds    = 60
+)
+
+// benchmarkInput returns 60 s of SQ-encoded quad test tones.
+func benchmarkInput(b *testing.B) [][]float64 {
+	b.Helper()
+
+	quad := testsignal.QuadTones(benchSampleRate, benchSeconds*benchSampleRate, 0.4, 0.05)
+	encoded, err := encoder.NewSQEncoder().Process(quad)
+	if err != nil {
+		b.Fatalf("Process() error = %v", err)
+	}
+	return encoded
+}
+
+// decodeModes are the decoder configurations compared by
+// BenchmarkSQDecoder_Process, alongside the frequency-domain matrix
+// prototype.
+var decodeModes = []struct {
+	name  string
+	setup func(*decoder.SQDecoder)
+}{
+	{"time-domain-matrix", func(*decoder.SQDecoder) {}},
+	{"time-domain-matrix+logic", func(d *decoder.SQDecoder) { d.EnableLogicSteering(true) }},
+	{"time-domain-matrix+float32", func(d *decoder.SQDecoder) { d.SetPrecision(sqmath.Precision32) }},
+}
+
+// fdMatrix is a prototype of the planned frequency-domain matrix at the
+// default block size and overlap: LT and RT are packed into one complex
+// FFT per block, the matrix is applied per bin, with the Hilbert filter as
+// a transfer function and the direct path as a delay, and one complex
+// inverse FFT yields LB and RB together, which are overlap-added. That is
+// two FFTs per block where the time-domain matrix needs four. It only
+// covers the plain SQ matrix without logic steering, and exists so that
+// the mode's PR and the current path are measured by the same harness.
+type fdMatrix struct {
+	blockSize, hop int
+	plan           *algofft.Plan[complex128]
+	// transfer is the response of the decoder's Hilbert filter, including
+	// its gain; delay moves the direct path to where the decoder reads it.
+	transfer, delay  []complex128
+	packed, spectrum []complex128
+}
+
+func newFDMatrix(blockSize, overlap int) (*fdMatrix, error) {
+	plan, err := algofft.NewPlan64(blockSize)
+	if err != nil {
+		return nil, err
+	}
+	impulse := make([]float64, blockSize)
+	impulse[0] = 1
+	response := sqmath.NewHilbertTransformer(blockSize, overlap).ProcessBlock(impulse)
+	m := &fdMatrix{
+		blockSize: blockSize,
+		hop:       overlap,
+		plan:      plan,
+		transfer:  make([]complex128, blockSize),
+		delay:     make([]complex128, blockSize),
+		packed:    make([]complex128, blockSize),
+		spectrum:  make([]complex128, blockSize),
+	}
+	for i, v := range response {
+		m.packed[i] = complex(v, 0)
+	}
+	if err := plan.Forward(m.transfer, m.packed); err != nil {
+		return nil, err
+	}
+	// The decoder reads the Hilbert output overlap/2 and the direct path
+	// overlap/4 samples ahead of the output position.
+	for k := range m.delay {
+		m.delay[k] = cmplx.Exp(complex(0, -2*math.Pi*float64(k*overlap/4)/float64(blockSize)))
+	}
+	return m, nil
+}
+
+// Process decodes input like decoder.SQDecoder.Process with the same block
+// size and overlap, except for the first overlap samples, where the decoder
+// reads the direct path before its first filtered segment.
+func (m *fdMatrix) Process(input [][]float64) [][]float64 {
+	n := len(input[0])
+	numBlocks := (n + m.hop - 1) / m.hop
+	acc := make([]complex128, (numBlocks+1)*m.hop+m.blockSize)
+	gain := complex(math.Sqrt2/2, 0)
+	for block := range numBlocks {
+		start := (block + 1) * m.hop
+		clear(m.packed)
+		for i := 0; i < m.hop && start+i < n; i++ {
+			m.packed[i] = complex(input[0][start+i], input[1][start+i])
+		}
+		if err := m.plan.Forward(m.spectrum, m.packed); err != nil {
+			panic(err)
+		}
+		// Unpack the spectra of LT and RT, apply the matrix and pack LB
+		// and RB into the real and imaginary part of one inverse FFT.
+		for k := range m.spectrum {
+			z, mirror := m.spectrum[k], cmplx.Conj(m.spectrum[(m.blockSize-k)%m.blockSize])
+			lt, rt := (z+mirror)/2, (z-mirror)/2i
+			lb := gain * (m.transfer[k]*lt - m.delay[k]*rt)
+			rb := gain * (m.delay[k]*lt - m.transfer[k]*rt)
+			m.packed[k] = lb + 1i*rb
+		}
+		if err := m.plan.Inverse(m.packed, m.packed); err != nil {
+			panic(err)
+		}
+		for i, v := range m.packed {
+			acc[start+i] += v
+		}
+	}
+
+	output := make([][]float64, 4)
+	for ch := range output {
+		output[ch] = make([]float64, n)
+	}
+	for t := range n {
+		if direct := t + m.hop/4; direct < n {
+			output[0][t], output[1][t] = input[0][direct], input[1][direct]
+		}
+		output[2][t], output[3][t] = real(acc[t+m.hop/2]), imag(acc[t+m.hop/2])
+	}
+	return output
+}
+
+func BenchmarkSQDecoder_Process(b *testing.B) {
+	input := benchmarkInput(b)
+
+	// The prototype must decode like the decoder before it is timed.
+	fd, err := newFDMatrix(decoder.DefaultBlockSize, decoder.DefaultOverlap)
+	if err != nil {
+		b.Fatalf("newFDMatrix() error = %v", err)
+	}
+	want, err := decoder.NewSQDecoder().Process(input)
+	if err != nil {
+		b.Fatal(err)
+	}
+	got := fd.Process(input)
+	for ch := range want {
+		for i := decoder.DefaultOverlap; i < len(want[ch]); i++ {
+			if math.Abs(got[ch][i]-want[ch][i]) > 1e-9 {
+				b.Fatalf("frequency-domain matrix channel %d differs at %d: %v, want %v", ch, i, got[ch][i], want[ch][i])
+			}
+		}
+	}
+	b.Run("frequency-domain-matrix", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(8 * len(input) * len(input[0])))
+		for b.Loop() {
+			fd.Process(input)
+		}
+	})
+
+	for _, mode := range decodeModes {
+		b.Run(mode.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(8 * len(input) * len(input[0])))
+			for b.Loop() {
+				d := decoder.NewSQDecoder()
+				d.SetSampleRate(benchSampleRate)
+				mode.setup(d)
+				if _, err := d.Process(input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package decoder
+
+import "fmt"
+
+// ProcessChunk and ProcessStream keep the input not decoded yet in
+// inputBufferL/R and the decoded frames not returned yet in chunkReady, one
+// slice per output channel; a nil chunkReady means no stream is running.
+
+// ChunkLatency returns the delay of the ProcessChunk output in frames:
+// output frame ChunkLatency+i is frame i of Process on the whole signal.
+// Pending input is decoded once a whole hop plus the blockSize-overlap
+// lookahead of its last block has arrived, so up to a hop-1 frames wait on
+// top of the lookahead.
+func (d *SQDecoder) ChunkLatency() int {
+	return d.blockSize - 1
+}
+
+// ProcessChunk decodes one chunk of a stream of arbitrary-length chunks
+// ([2][frames] LT, RT) and returns as many output frames, delayed by
+// ChunkLatency. Input is buffered until a whole number of hops can be
+// decoded through ProcessSegment, so the concatenated output of all chunks
+// and Flush equals Process on the concatenated input exactly, shifted by
+// ChunkLatency. The first chunk after a Flush (or of a new decoder) starts a
+// new stream; do not mix ProcessChunk with Process or ProcessSegment in
+// between.
+func (d *SQDecoder) ProcessChunk(input [][]float64) ([][]float64, error) {
+	input, err := d.validateInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if d.chunkReady == nil {
+		d.startChunks(d.ChunkLatency())
+	}
+	if err := d.bufferChunk(input); err != nil {
+		return nil, err
+	}
+
+	// At least frames are ready now: ChunkLatency covers the frames still
+	// held back in the input buffers.
+	frames := len(input[0])
+	if len(d.chunkReady[0]) < frames {
+		return nil, fmt.Errorf("ProcessChunk called on a ProcessStream stream")
+	}
+	output := make([][]float64, d.OutputChannels())
+	for ch := range output {
+		output[ch] = append([]float64(nil), d.chunkReady[ch][:frames]...)
+		d.chunkReady[ch] = append(d.chunkReady[ch][:0], d.chunkReady[ch][frames:]...)
+	}
+	return output, nil
+}
+
+// ProcessStream decodes one chunk of a stream of arbitrary-length chunks
+// ([2][frames] LT, RT) like ProcessChunk, but returns the output frames
+// decoded so far instead of a delayed frame for every input frame: nothing
+// until the first hop and its lookahead have arrived, then whole hops. The
+// concatenated output of all chunks and Flush equals Process on the
+// concatenated input exactly, with no latency to drop, while at most a
+// block of input is held back. The first chunk after a Flush (or of a new
+// decoder) starts a new stream; do not mix ProcessStream with ProcessChunk,
+// Process or ProcessSegment in between.
+func (d *SQDecoder) ProcessStream(input [][]float64) ([][]float64, error) {
+	input, err := d.validateInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if d.chunkReady == nil {
+		d.startChunks(0)
+	}
+	if err := d.bufferChunk(input); err != nil {
+		return nil, err
+	}
+
+	output := make([][]float64, d.OutputChannels())
+	for ch := range output {
+		output[ch], d.chunkReady[ch] = d.chunkReady[ch], []float64{}
+	}
+	return output, nil
+}
+
+// Flush ends the stream fed to ProcessChunk or ProcessStream: it decodes the
+// buffered input as the end of the signal and returns the output frames not
+// returned yet, ChunkLatency of them after ProcessChunk. Flush without a
+// running stream returns no frames.
+func (d *SQDecoder) Flush() ([][]float64, error) {
+	output := make([][]float64, d.OutputChannels())
+	if d.chunkReady == nil {
+		return output, nil
+	}
+	if n := len(d.inputBufferL); n > 0 {
+		if err := d.decodeChunks(n, n); err != nil {
+			return nil, err
+		}
+	}
+	copy(output, d.chunkReady)
+	d.chunkReady = nil
+	d.inputBufferL = d.inputBufferL[:0]
+	d.inputBufferR = d.inputBufferR[:0]
+	return output, nil
+}
+
+// bufferChunk appends input to the input buffers and decodes the whole hops
+// whose lookahead has arrived.
+func (d *SQDecoder) bufferChunk(input [][]float64) error {
+	d.inputBufferL = append(d.inputBufferL, input[0]...)
+	d.inputBufferR = append(d.inputBufferR, input[1]...)
+
+	lookahead := d.blockSize - d.overlap
+	if n := (len(d.inputBufferL) - lookahead) / d.overlap * d.overlap; n > 0 {
+		return d.decodeChunks(n, n+lookahead)
+	}
+	return nil
+}
+
+// startChunks resets the streaming state as Process does and primes the
+// output queue with latency frames of silence.
+func (d *SQDecoder) startChunks(latency int) {
+	for side := range d.bassSplit {
+		d.bassSplit[side].Reset()
+	}
+	d.resetCrossfeed()
+	d.resetRearLowpass()
+	d.resetHilbertCarry()
+	d.silentBlocks = 0
+	d.segmentBlock = 0
+	d.segmentFrames = 0
+	d.inputBufferL = d.inputBufferL[:0]
+	d.inputBufferR = d.inputBufferR[:0]
+	d.chunkReady = make([][]float64, d.OutputChannels())
+	for ch := range d.chunkReady {
+		d.chunkReady[ch] = make([]float64, latency)
+	}
+}
+
+// decodeChunks decodes the first numOutput buffered frames, reading up to
+// end frames, queues the output and drops the decoded frames from the
+// input buffers.
+func (d *SQDecoder) decodeChunks(numOutput, end int) error {
+	out, err := d.ProcessSegment([][]float64{d.inputBufferL[:end], d.inputBufferR[:end]}, numOutput)
+	if err != nil {
+		return err
+	}
+	for ch := range out {
+		d.chunkReady[ch] = append(d.chunkReady[ch], out[ch]...)
+	}
+	d.inputBufferL = append(d.inputBufferL[:0], d.inputBufferL[numOutput:]...)
+	d.inputBufferR = append(d.inputBufferR[:0], d.inputBufferR[numOutput:]...)
+	return nil
+}
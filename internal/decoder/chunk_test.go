@@ -0,0 +1,238 @@
+package decoder_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+// decodeChunks feeds input to d in chunks of size frames, flushes and
+// returns the concatenated output.
+func decodeChunks(t *testing.T, d *decoder.SQDecoder, input [][]float64, size int) [][]float64 {
+	t.Helper()
+
+	out := make([][]float64, d.OutputChannels())
+	n := len(input[0])
+	for start := 0; start < n; start += size {
+		end := min(start+size, n)
+		got, err := d.ProcessChunk([][]float64{input[0][start:end], input[1][start:end]})
+		if err != nil {
+			t.Fatalf("ProcessChunk() error = %v", err)
+		}
+		for ch := range out {
+			if len(got[ch]) != end-start {
+				t.Fatalf("ProcessChunk() returned %d frames for %d", len(got[ch]), end-start)
+			}
+			out[ch] = append(out[ch], got[ch]...)
+		}
+	}
+	rest, err := d.Flush()
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	for ch := range out {
+		out[ch] = append(out[ch], rest[ch]...)
+	}
+	return out
+}
+
+// decodeStream feeds input to d through ProcessStream in chunks of size
+// frames, flushes and returns the concatenated output.
+func decodeStream(t *testing.T, d *decoder.SQDecoder, input [][]float64, size int) [][]float64 {
+	t.Helper()
+
+	out := make([][]float64, d.OutputChannels())
+	n := len(input[0])
+	for start := 0; start < n; start += size {
+		end := min(start+size, n)
+		got, err := d.ProcessStream([][]float64{input[0][start:end], input[1][start:end]})
+		if err != nil {
+			t.Fatalf("ProcessStream() error = %v", err)
+		}
+		for ch := range out {
+			out[ch] = append(out[ch], got[ch]...)
+		}
+	}
+	rest, err := d.Flush()
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	for ch := range out {
+		out[ch] = append(out[ch], rest[ch]...)
+	}
+	return out
+}
+
+// checkDelayed fails unless got is want delayed by latency frames of
+// silence, bit for bit.
+func checkDelayed(t *testing.T, got, want [][]float64, latency int) {
+	t.Helper()
+
+	for ch := range want {
+		if len(got[ch]) != latency+len(want[ch]) {
+			t.Fatalf("channel %d: %d frames, want %d", ch, len(got[ch]), latency+len(want[ch]))
+		}
+		for i, v := range got[ch][:latency] {
+			if v != 0 {
+				t.Fatalf("channel %d latency frame %d = %v, want 0", ch, i, v)
+			}
+		}
+		for i, v := range got[ch][latency:] {
+			if v != want[ch][i] {
+				t.Fatalf("channel %d frame %d = %v, want %v", ch, i, v, want[ch][i])
+			}
+		}
+	}
+}
+
+func TestSQDecoder_ProcessChunkMatchesProcess(t *testing.T) {
+	t.Parallel()
+
+	input := slotInput(t, 12*stateOverlap+321)
+	want, err := newStateDecoder().Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	// 256 divides the overlap, the others do not.
+	for _, size := range []int{256, 1, 333, 700, 3000, len(input[0])} {
+		d := newStateDecoder()
+		if d.ChunkLatency() != stateBlockSize-1 {
+			t.Fatalf("ChunkLatency() = %d, want %d", d.ChunkLatency(), stateBlockSize-1)
+		}
+		checkDelayed(t, decodeChunks(t, d, input, size), want, d.ChunkLatency())
+	}
+}
+
+func TestSQDecoder_ProcessChunkNoOverlap(t *testing.T) {
+	t.Parallel()
+
+	input := slotInput(t, 9*stateBlockSize+77)
+	d := decoder.NewSQDecoderWithParams(stateBlockSize, stateBlockSize)
+	want, err := d.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	checkDelayed(t, decodeChunks(t, d, input, 333), want, d.ChunkLatency())
+}
+
+func TestSQDecoder_FlushStartsNewStream(t *testing.T) {
+	t.Parallel()
+
+	input := slotInput(t, 6*stateOverlap+100)
+	d := newStateDecoder()
+	first := decodeChunks(t, d, input, 500)
+	// The bass and crossfeed state of the first stream must not leak into
+	// the second.
+	second := decodeChunks(t, d, input, 500)
+	want, err := newStateDecoder().Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	checkDelayed(t, first, want, d.ChunkLatency())
+
+	// The logic envelopes carry over, as they do between Process calls.
+	d = newStateDecoder()
+	if _, err := d.Process(input); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	again, err := d.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	checkDelayed(t, second, again, d.ChunkLatency())
+
+	if rest, err := decoder.NewSQDecoder().Flush(); err != nil || len(rest[0]) != 0 {
+		t.Fatalf("Flush() without a stream = %d frames, %v", len(rest[0]), err)
+	}
+}
+
+func TestSQDecoder_ProcessChunkErrors(t *testing.T) {
+	t.Parallel()
+
+	d := decoder.NewSQDecoder()
+	if _, err := d.ProcessChunk([][]float64{make([]float64, 10)}); err == nil {
+		t.Fatal("ProcessChunk() accepted mono input")
+	}
+	if _, err := d.ProcessChunk([][]float64{make([]float64, 10), make([]float64, 9)}); err == nil {
+		t.Fatal("ProcessChunk() accepted unequal channels")
+	}
+}
+
+func TestSQDecoder_ProcessStreamMatchesProcess(t *testing.T) {
+	t.Parallel()
+
+	input := slotInput(t, 12*stateOverlap+321)
+	want, err := newStateDecoder().Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	n := len(input[0])
+	for _, size := range []int{256, 1, 333, 700, 3000, n} {
+		d := newStateDecoder()
+		got := make([][]float64, 4)
+		fed := 0
+		for start := 0; start < n; start += size {
+			end := min(start+size, n)
+			out, err := d.ProcessStream([][]float64{input[0][start:end], input[1][start:end]})
+			if err != nil {
+				t.Fatalf("ProcessStream() error = %v", err)
+			}
+			fed = end
+			for ch := range got {
+				got[ch] = append(got[ch], out[ch]...)
+			}
+			// Everything but the last block of input is decoded.
+			if held := fed - len(got[0]); held < 0 || held >= stateBlockSize {
+				t.Fatalf("chunk size %d: %d frames held back after %d, want fewer than %d", size, held, fed, stateBlockSize)
+			}
+		}
+		rest, err := d.Flush()
+		if err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		for ch := range got {
+			got[ch] = append(got[ch], rest[ch]...)
+		}
+		checkDelayed(t, got, want, 0)
+	}
+
+	d := newStateDecoder()
+	if _, err := d.ProcessStream([][]float64{input[0][:10], input[1][:10]}); err != nil {
+		t.Fatalf("ProcessStream() error = %v", err)
+	}
+	if _, err := d.ProcessChunk([][]float64{input[0][:10], input[1][:10]}); err == nil {
+		t.Fatal("ProcessChunk() continued a ProcessStream stream")
+	}
+}
+
+func TestSQDecoder_ChunksMatchProcessForEveryChannelCount(t *testing.T) {
+	t.Parallel()
+
+	input := slotInput(t, 6*stateOverlap+321)
+	tests := []struct {
+		name      string
+		configure func(d *decoder.SQDecoder)
+		channels  int
+	}{
+		{"sumdiff", func(d *decoder.SQDecoder) { d.SetBackChannelMode(decoder.BackChannelSumDiff) }, 4},
+		{"both", func(d *decoder.SQDecoder) { d.SetBackChannelMode(decoder.BackChannelDiscreteAndSumDiff) }, 6},
+		{"ambisonic-b", func(d *decoder.SQDecoder) { d.SetOutputLayout(decoder.LayoutAmbisonicB) }, 3},
+	}
+	for _, tt := range tests {
+		newDecoder := func() *decoder.SQDecoder {
+			d := newStateDecoder()
+			tt.configure(d)
+			return d
+		}
+		want, err := newDecoder().Process(input)
+		if err != nil {
+			t.Fatalf("%s: Process() error = %v", tt.name, err)
+		}
+		if len(want) != tt.channels {
+			t.Fatalf("%s: Process() returned %d channels, want %d", tt.name, len(want), tt.channels)
+		}
+		d := newDecoder()
+		checkDelayed(t, decodeChunks(t, d, input, 333), want, d.ChunkLatency())
+		checkDelayed(t, decodeStream(t, newDecoder(), input, 333), want, 0)
+	}
+}
@@ -0,0 +1,58 @@
+package decoder
+
+import "math"
+
+// MaxCrossfeedDelayMs bounds the crossfeed delay; the interaural delay of a
+// human head is below 0.7 ms.
+const MaxCrossfeedDelayMs = 5.0
+
+// crossfeed is the state of the front crossfeed.
+type crossfeed struct {
+	amount  float64
+	delayMs float64
+	// history[side] holds the last delay samples of LF and RF, oldest
+	// first.
+	history [2][]float64
+}
+
+// SetCrossfeed mixes a copy of each front channel, delayed by delayMs and
+// scaled by amount, into the other front, as a headphone crossfeed does:
+// the discrete LF and RF of an SQ decode otherwise sound unnaturally wide
+// on headphones. The sum is scaled by 1/(1+amount), which keeps a centered
+// source at its level at low frequencies. Only LF and RF are affected,
+// after bass management. amount is clamped to [0, 1], 0 disables
+// crossfeed; delayMs is clamped to [0, 5] and rounded to whole samples.
+func (d *SQDecoder) SetCrossfeed(amount, delayMs float64) {
+	d.crossfeed.amount = min(max(amount, 0), 1)
+	d.crossfeed.delayMs = min(max(delayMs, 0), MaxCrossfeedDelayMs)
+	d.resetCrossfeed()
+}
+
+// resetCrossfeed sizes the delay lines for the sample rate and clears them.
+func (d *SQDecoder) resetCrossfeed() {
+	delay := int(math.Round(d.crossfeed.delayMs * float64(d.sampleRate) / 1000))
+	for side := range d.crossfeed.history {
+		d.crossfeed.history[side] = make([]float64, delay)
+	}
+}
+
+// applyCrossfeed crossfeeds LF and RF of output in place. The delay lines
+// carry over to the next segment.
+func (d *SQDecoder) applyCrossfeed(output [][]float64) {
+	cf := &d.crossfeed
+	gain := 1 / (1 + cf.amount)
+	lf, rf := output[0], output[1]
+	delay := len(cf.history[0])
+	// Extend each channel by its history so the delayed sample i is at i.
+	delayed := [2][]float64{
+		append(append(make([]float64, 0, delay+len(lf)), cf.history[0]...), lf...),
+		append(append(make([]float64, 0, delay+len(rf)), cf.history[1]...), rf...),
+	}
+	for i := range lf {
+		lf[i] = gain * (lf[i] + cf.amount*delayed[1][i])
+		rf[i] = gain * (rf[i] + cf.amount*delayed[0][i])
+	}
+	for side := range cf.history {
+		copy(cf.history[side], delayed[side][len(delayed[side])-delay:])
+	}
+}
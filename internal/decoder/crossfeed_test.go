@@ -0,0 +1,112 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+func TestSQDecoder_Crossfeed_IncreasesFrontCorrelation(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sampleRate = 44100.0
+		n          = 44100
+	)
+
+	// Unrelated low tones in LT and RT decode to uncorrelated LF and RF.
+	input := [][]float64{make([]float64, n), make([]float64, n)}
+	for i := range n {
+		ts := float64(i) / sampleRate
+		input[0][i] = 0.4 * math.Sin(2.0*math.Pi*200*ts)
+		input[1][i] = 0.4 * math.Sin(2.0*math.Pi*310*ts)
+	}
+	decode := func(amount, delayMs float64) [][]float64 {
+		d := decoder.NewSQDecoder()
+		d.SetSampleRate(sampleRate)
+		d.SetCrossfeed(amount, delayMs)
+		out, err := d.Process(input)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		return out
+	}
+	from, to := n/4, 3*n/4
+	frontCorrelation := func(out [][]float64) float64 {
+		return correlation(out[0], out[1], from, to)
+	}
+
+	plain := decode(0, 0)
+	if got := frontCorrelation(plain); math.Abs(got) > 0.01 {
+		t.Fatalf("plain LF/RF correlation = %g, want ~0", got)
+	}
+
+	// Without delay LF and RF become a+b·x and b+a·x of equal-power
+	// uncorrelated a and b, correlated by 2x/(1+x²).
+	for _, amount := range []float64{0.2, 0.5, 1} {
+		want := 2 * amount / (1 + amount*amount)
+		if got := frontCorrelation(decode(amount, 0)); math.Abs(got-want) > 0.01 {
+			t.Fatalf("amount %g: LF/RF correlation = %g, want %g", amount, got, want)
+		}
+	}
+
+	// A delay well below the tone periods keeps most of the correlation,
+	// and more crossfeed correlates more.
+	prev := 0.0
+	for _, amount := range []float64{0.2, 0.5, 1} {
+		got := frontCorrelation(decode(amount, 0.3))
+		if got <= prev || got > 2*amount/(1+amount*amount) {
+			t.Fatalf("amount %g with 0.3 ms delay: LF/RF correlation = %g after %g", amount, got, prev)
+		}
+		prev = got
+	}
+	// The rears are not crossfed.
+	crossfed := decode(0.5, 0.3)
+	for ch := 2; ch < 4; ch++ {
+		for i := range crossfed[ch] {
+			if crossfed[ch][i] != plain[ch][i] {
+				t.Fatalf("channel %d sample %d = %g, want %g", ch, i, crossfed[ch][i], plain[ch][i])
+			}
+		}
+	}
+}
+
+func TestSQDecoder_Crossfeed_SegmentsMatchSingleCall(t *testing.T) {
+	t.Parallel()
+
+	const n = 8192
+	input := [][]float64{make([]float64, n), make([]float64, n)}
+	for i := range n {
+		input[0][i] = math.Sin(float64(i) * 0.05)
+		input[1][i] = math.Cos(float64(i) * 0.011)
+	}
+	const blockSize, overlap = 1024, 512
+	newDecoder := func() *decoder.SQDecoder {
+		d := decoder.NewSQDecoderWithParams(blockSize, overlap)
+		d.SetSampleRate(44100)
+		d.SetCrossfeed(0.4, 0.5)
+		return d
+	}
+	want, err := newDecoder().Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	segmented := newDecoder()
+	step := 3 * overlap
+	for start := 0; start < n; start += step {
+		numOutput := min(step, n-start)
+		end := min(start+numOutput+blockSize-overlap, n)
+		got, err := segmented.ProcessSegment([][]float64{input[0][start:end], input[1][start:end]}, numOutput)
+		if err != nil {
+			t.Fatalf("ProcessSegment() error = %v", err)
+		}
+		for ch := range got {
+			for i, v := range got[ch] {
+				if v != want[ch][start+i] {
+					t.Fatalf("ch %d frame %d = %g, want %g", ch, start+i, v, want[ch][start+i])
+				}
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package decoder
 
 import (
 	"fmt"
+	"log/slog"
 	"math"
 
 	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
@@ -16,21 +17,98 @@ const (
 
 // SQDecoder implements the SQ² (FFT-based) quadrophonic decoder
 type SQDecoder struct {
-	blockSize     int
-	overlap       int
-	initialDelay  int
-	sqrt2         float64
-	hilbertLeft   *sqmath.HilbertTransformer
-	hilbertRight  *sqmath.HilbertTransformer
+	blockSize    int
+	overlap      int
+	initialDelay int
+	sqrt2        float64
+	hilbertLeft  *sqmath.HilbertTransformer
+	hilbertRight *sqmath.HilbertTransformer
+	precision    sqmath.Precision
+	// hilbertScale is the gain of the built-in Hilbert filter; customFIR
+	// is set while a filter from SetHilbertFIR replaces it.
+	hilbertScale  float64
+	customFIR     bool
 	sampleRate    int
+	sanitize      bool
+	padUnequal    bool
+	tail          TailHandling
+	backMode      BackChannelMode
+	layout        OutputLayout
+	matrix        Matrix
 	logicConfig   LogicSteeringConfig
 	logicEnv      [4]float64
-	attackCoeff   float64
-	releaseCoeff  float64
-	inputBufferL  []float64
-	inputBufferR  []float64
-	outputBuffers [4][]float64
-	bufferPos     int
+	bassCrossover float64
+	bassSplit     [2]sqmath.Crossover
+	crossfeed     crossfeed
+	rearLowpass   rearLowpass
+	backSoftness  float64
+	silenceConfig SilenceSkipConfig
+	silenceLevel  float64
+	// silenceMinBlocks is MinDuration in blocks; silentBlocks counts the
+	// consecutive silent blocks up to the current one.
+	silenceMinBlocks int
+	silentBlocks     int
+	silenceStats     SilenceStats
+	steeredSamples   int
+	silentHilbert    []float64
+	attackCoeff      float64
+	releaseCoeff     float64
+	inputBufferL     []float64
+	inputBufferR     []float64
+	outputBuffers    [4][]float64
+	chunkReady       [][]float64
+	segmentBlock     int
+	monoInput        bool
+	segmentFrames    int
+	hookBefore       BlockHook
+	hookAfter        BlockHook
+	hookTap          BlockHook
+	tapBuffers       [4][]float64
+	// hilbertCarry holds the overlap-add accumulator of H(LT), H(RT) past
+	// the previous block's output; see overlapAddHilbert.
+	hilbertCarry [2][]float64
+	// logicAnalysis records the first pass of two-pass logic steering and
+	// logicPlan applies it; see logicplan.go.
+	logicAnalysis *LogicPlan
+	logicPlan     *LogicPlan
+}
+
+// ParamError is the error of ValidateParams. Param names the unusable
+// parameter, "block size" or "overlap".
+type ParamError struct {
+	Param  string
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return e.Reason
+}
+
+// ValidateParams checks the parameters of NewSQDecoderWithParams: blockSize
+// must be a power of two of at least 2 and overlap in [1, blockSize]. The
+// error is a *ParamError.
+func ValidateParams(blockSize, overlap int) error {
+	switch {
+	case blockSize < 2:
+		return &ParamError{Param: "block size", Reason: fmt.Sprintf("block size %d is below the minimum of 2", blockSize)}
+	case blockSize&(blockSize-1) != 0:
+		return &ParamError{Param: "block size", Reason: fmt.Sprintf("block size %d is not a power of two", blockSize)}
+	case overlap <= 0:
+		return &ParamError{Param: "overlap", Reason: fmt.Sprintf("overlap %d is not positive", overlap)}
+	case overlap > blockSize:
+		return &ParamError{Param: "overlap", Reason: fmt.Sprintf("overlap %d exceeds block size %d", overlap, blockSize)}
+	}
+	return nil
+}
+
+// NewSQDecoderWithParamsE is NewSQDecoderWithParams for parameters that
+// come from a user: it returns the error of ValidateParams instead of
+// panicking.
+func NewSQDecoderWithParamsE(blockSize, overlap int) (*SQDecoder, error) {
+	if err := ValidateParams(blockSize, overlap); err != nil {
+		return nil, err
+	}
+	return NewSQDecoderWithParams(blockSize, overlap), nil
 }
 
 // NewSQDecoder creates a new SQ decoder with FFT-based Hilbert transform
@@ -38,23 +116,37 @@ func NewSQDecoder() *SQDecoder {
 	return NewSQDecoderWithParams(DefaultBlockSize, DefaultOverlap)
 }
 
-// NewSQDecoderWithParams creates a new SQ decoder with custom parameters
+// NewSQDecoderWithParams creates a new SQ decoder with custom parameters.
+//
+// overlap == blockSize selects the no-overlap fast mode: contiguous blocks
+// are decoded without reading any input twice, roughly halving the FFT work.
+// The Hilbert filter keeps the length it has at half overlap, but the
+// samples near each block edge come from a circular convolution that wraps
+// around the block: a transient leaks into the far end of its block in the
+// phase-shifted path, which shows up as pre-echo and reduced separation in
+// the back channels on changing material. Steady material decodes close to
+// the default. The front channels pass through unshifted.
+//
+// It panics if ValidateParams rejects the parameters;
+// NewSQDecoderWithParamsE returns the error instead.
 func NewSQDecoderWithParams(blockSize, overlap int) *SQDecoder {
-	// Initial delay calculation from SQ² implementation
-	initialDelay := overlap + overlap/2
-
+	if err := ValidateParams(blockSize, overlap); err != nil {
+		panic(err)
+	}
 	decoder := &SQDecoder{
-		blockSize:    blockSize,
-		overlap:      overlap,
-		initialDelay: initialDelay,
-		sqrt2:        math.Sqrt(2.0) / 2.0, // ≈ 0.707
-		hilbertLeft:  sqmath.NewHilbertTransformer(blockSize, overlap),
-		hilbertRight: sqmath.NewHilbertTransformer(blockSize, overlap),
-		sampleRate:   44100,
-		logicConfig:  DefaultLogicSteeringConfig(),
-		inputBufferL: make([]float64, blockSize),
-		inputBufferR: make([]float64, blockSize),
-		bufferPos:    0,
+		blockSize:     blockSize,
+		overlap:       overlap,
+		initialDelay:  LatencyFor(blockSize, overlap),
+		sqrt2:         math.Sqrt(2.0) / 2.0, // ≈ 0.707
+		hilbertLeft:   sqmath.NewHilbertTransformer(blockSize, hilbertTaps(blockSize, overlap)),
+		hilbertRight:  sqmath.NewHilbertTransformer(blockSize, hilbertTaps(blockSize, overlap)),
+		hilbertScale:  sqmath.DefaultHilbertScale,
+		sampleRate:    44100,
+		logicConfig:   DefaultLogicSteeringConfig(),
+		silenceConfig: DefaultSilenceSkipConfig(),
+		silenceLevel:  -1,
+		inputBufferL:  make([]float64, 0, 2*blockSize),
+		inputBufferR:  make([]float64, 0, 2*blockSize),
 	}
 
 	// Initialize output buffers
@@ -63,6 +155,11 @@ func NewSQDecoderWithParams(blockSize, overlap int) *SQDecoder {
 	}
 
 	decoder.updateLogicCoefficients()
+	decoder.silentHilbert = make([]float64, blockSize)
+	if decoder.overlapAdd() {
+		n := overlap - overlap/2 + hilbertTaps(blockSize, overlap) - 1
+		decoder.hilbertCarry = [2][]float64{make([]float64, n), make([]float64, n)}
+	}
 
 	return decoder
 }
@@ -74,6 +171,10 @@ func (d *SQDecoder) SetSampleRate(sampleRate int) {
 	}
 	d.sampleRate = sampleRate
 	d.updateLogicCoefficients()
+	d.updateBassFilters()
+	d.updateSilenceThreshold()
+	d.resetCrossfeed()
+	d.updateRearLowpass()
 }
 
 // EnableLogicSteering toggles CBS-style logic steering.
@@ -81,6 +182,64 @@ func (d *SQDecoder) EnableLogicSteering(enabled bool) {
 	d.logicConfig.Enabled = enabled
 }
 
+// SetWindow selects the window applied to the Hilbert impulse response.
+func (d *SQDecoder) SetWindow(windowType sqmath.WindowType) {
+	taps := hilbertTaps(d.blockSize, d.overlap)
+	d.hilbertLeft = sqmath.NewHilbertTransformerWithWindow(d.blockSize, taps, windowType)
+	d.hilbertRight = sqmath.NewHilbertTransformerWithWindow(d.blockSize, taps, windowType)
+	d.customFIR = false
+	if d.hilbertScale != sqmath.DefaultHilbertScale {
+		d.hilbertLeft.SetScale(d.hilbertScale)
+		d.hilbertRight.SetScale(d.hilbertScale)
+	}
+	d.SetPrecision(d.precision)
+}
+
+// SetPrecision selects float64 (default) or float32 FFTs for the phase
+// shifter. Precision32 trades a deviation below -100 dBFS for half the FFT
+// memory; see sqmath.Precision32.
+func (d *SQDecoder) SetPrecision(precision sqmath.Precision) {
+	d.precision = precision
+	d.hilbertLeft.SetPrecision(precision)
+	d.hilbertRight.SetPrecision(precision)
+}
+
+// hilbertTaps returns the Hilbert filter length for a block size and
+// overlap. In no-overlap mode the filter cannot span the hop, so it keeps
+// the half-block length of the default configuration.
+func hilbertTaps(blockSize, overlap int) int {
+	if overlap == blockSize {
+		return blockSize / 2
+	}
+	return overlap
+}
+
+// noOverlap reports whether the decoder runs in no-overlap fast mode.
+func (d *SQDecoder) noOverlap() bool {
+	return d.overlap == d.blockSize
+}
+
+// overlapAdd reports whether the Hilbert path is computed by overlap-add,
+// which needs the hop and the filter tail to fit into a block.
+func (d *SQDecoder) overlapAdd() bool {
+	return 2*d.overlap <= d.blockSize
+}
+
+// SetSanitizeInput toggles replacing NaN/Inf input samples with 0 before
+// processing. Without sanitization a single non-finite sample propagates
+// through the FFT into every output sample of the blocks that contain it.
+func (d *SQDecoder) SetSanitizeInput(enabled bool) {
+	d.sanitize = enabled
+}
+
+// SetPadUnequalChannels toggles zero-extending the shorter input channel to
+// the length of the longer one instead of rejecting the input. Stitched
+// transfers sometimes end a few samples apart; the padding is logged at
+// info level. The default is strict.
+func (d *SQDecoder) SetPadUnequalChannels(enabled bool) {
+	d.padUnequal = enabled
+}
+
 // SetLogicSteeringConfig updates logic steering parameters.
 func (d *SQDecoder) SetLogicSteeringConfig(config LogicSteeringConfig) {
 	d.logicConfig = config
@@ -98,15 +257,81 @@ func (d *SQDecoder) updateLogicCoefficients() {
 // Process decodes stereo SQ-encoded audio to 4-channel quadrophonic
 // Input: [2][numSamples] - LT, RT (Left Total, Right Total)
 // Output: [4][numSamples] - LF, RF, LB, RB (Left Front, Right Front, Left Back, Right Back)
+// The back channels are rearranged according to SetBackChannelMode.
+// NaN/Inf input samples are only replaced when SetSanitizeInput(true) is set.
 func (d *SQDecoder) Process(input [][]float64) ([][]float64, error) {
+	input, err := d.validateInput(input)
+	if err != nil {
+		return nil, err
+	}
+	for side := range d.bassSplit {
+		d.bassSplit[side].Reset()
+	}
+	d.resetCrossfeed()
+	d.resetRearLowpass()
+	d.resetHilbertCarry()
+	d.silentBlocks = 0
+	return d.process(input, len(input[0]), 0), nil
+}
+
+// ProcessSegment decodes the first numOutput samples of input and uses the
+// remaining samples only as lookahead. Decoding a signal as consecutive
+// segments reproduces Process on the whole signal exactly, provided every
+// segment except the last starts on a multiple of the overlap, numOutput is
+// a multiple of the overlap, and input extends blockSize-overlap samples
+// past numOutput (or to the end of the signal). Block indices passed to
+// hooks continue across calls.
+func (d *SQDecoder) ProcessSegment(input [][]float64, numOutput int) ([][]float64, error) {
+	input, err := d.validateInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if numOutput < 0 || numOutput > len(input[0]) {
+		return nil, fmt.Errorf("numOutput %d out of range [0, %d]", numOutput, len(input[0]))
+	}
+
+	output := d.process(input, numOutput, d.segmentBlock)
+	d.segmentBlock += (numOutput + d.overlap - 1) / d.overlap
+	d.segmentFrames += numOutput
+	return output, nil
+}
+
+// validateInput checks the channel layout of input and returns it, with
+// the shorter channel zero-extended when SetPadUnequalChannels is on. It
+// also rejects a decoder whose block size or overlap is unusable, such as
+// the zero value, which would otherwise divide by zero.
+func (d *SQDecoder) validateInput(input [][]float64) ([][]float64, error) {
+	if err := ValidateParams(d.blockSize, d.overlap); err != nil {
+		return nil, fmt.Errorf("decoder not initialized: %w", err)
+	}
 	if len(input) != 2 {
 		return nil, fmt.Errorf("input must have 2 channels, got %d", len(input))
 	}
-
-	numSamples := len(input[0])
-	if len(input[1]) != numSamples {
+	lt, rt := input[0], input[1]
+	if len(lt) == len(rt) {
+		return input, nil
+	}
+	if !d.padUnequal {
 		return nil, fmt.Errorf("input channels must have same length")
 	}
+	slog.Info("zero-extending shorter input channel", "lt", len(lt), "rt", len(rt))
+	n := max(len(lt), len(rt))
+	padded := make([][]float64, 2)
+	for ch, samples := range input {
+		padded[ch] = samples
+		if len(samples) < n {
+			padded[ch] = make([]float64, n)
+			copy(padded[ch], samples)
+		}
+	}
+	return padded, nil
+}
+
+// process decodes numSamples output samples; input may be longer and is
+// zero-padded where shorter than a block. firstBlock offsets the block
+// index reported to hooks.
+func (d *SQDecoder) process(input [][]float64, numSamples, firstBlock int) [][]float64 {
+	numInput := len(input[0])
 
 	// Pad input to block boundaries
 	numBlocks := (numSamples + d.overlap - 1) / d.overlap
@@ -127,21 +352,62 @@ func (d *SQDecoder) Process(input [][]float64) ([][]float64, error) {
 
 		for i := 0; i < d.blockSize; i++ {
 			srcIdx := startIdx + i
-			if srcIdx < numSamples {
+			if srcIdx < numInput {
 				blockL[i] = input[0][srcIdx]
 				blockR[i] = input[1][srcIdx]
+			} else if d.tail != TailZeroPad {
+				// Pad from the block's own samples, which are the same
+				// however the input is segmented.
+				first := min(startIdx, numInput)
+				blockL[i] = padSample(input[0][first:numInput], srcIdx-first, d.tail)
+				blockR[i] = padSample(input[1][first:numInput], srcIdx-first, d.tail)
 			}
 			// else remains 0 (zero padding)
 		}
+		if d.sanitize {
+			sanitizeBlock(blockL)
+			sanitizeBlock(blockR)
+		}
+
+		// Apply Hilbert transform, unless the block is skipped as silent
+		silent := d.skipBlock(blockL, blockR)
+		phaseShiftedL, phaseShiftedR := d.silentHilbert, d.silentHilbert
+		if silent {
+			// Hooks may have written to the shared zero block.
+			clear(d.silentHilbert)
+			d.resetHilbertCarry()
+		} else if d.overlapAdd() {
+			phaseShiftedL = d.overlapAddHilbert(d.hilbertLeft, blockL, d.hilbertCarry[0])
+			phaseShiftedR = phaseShiftedL
+			if d.monoInput {
+				copy(d.hilbertCarry[1], d.hilbertCarry[0])
+			} else {
+				phaseShiftedR = d.overlapAddHilbert(d.hilbertRight, blockR, d.hilbertCarry[1])
+			}
+		} else {
+			phaseShiftedL = d.hilbertLeft.ProcessBlock(blockL)
+			phaseShiftedR = phaseShiftedL
+			if !d.monoInput {
+				phaseShiftedR = d.hilbertRight.ProcessBlock(blockR)
+			}
+		}
 
-		// Apply Hilbert transform
-		phaseShiftedL := d.hilbertLeft.ProcessBlock(blockL)
-		phaseShiftedR := d.hilbertRight.ProcessBlock(blockR)
+		if d.hookBefore != nil {
+			d.hookBefore(firstBlock+blockIdx, [][]float64{blockL, blockR, phaseShiftedL, phaseShiftedR})
+		}
 
 		// Apply SQ decode matrix
 		// Based on SQ² VSTDataModule.pas V2M_Process
 		outputOffset := d.overlap / 2
 		inputOffset := d.overlap / 4
+		if d.noOverlap() {
+			// The filter delay is half its length; the direct path is
+			// taken from the block itself.
+			outputOffset = hilbertTaps(d.blockSize, d.overlap) / 2
+			inputOffset = 0
+		}
+		blockOut := d.outputBuffers
+		count := 0
 
 		for i := 0; i < d.overlap; i++ {
 			outIdx := startIdx + i
@@ -155,6 +421,11 @@ func (d *SQDecoder) Process(input [][]float64) ([][]float64, error) {
 			}
 
 			phaseIdx := outputOffset + i
+			if d.noOverlap() {
+				// There is no next block to take the tail from; use the
+				// wrapped-around part of the circular convolution.
+				phaseIdx %= d.blockSize
+			}
 			if phaseIdx >= d.blockSize {
 				break
 			}
@@ -167,31 +438,114 @@ func (d *SQDecoder) Process(input [][]float64) ([][]float64, error) {
 
 			lt := blockL[inIdx]
 			rt := blockR[inIdx]
+			if silent {
+				lt, rt = 0, 0
+			}
 			hlt := phaseShiftedL[phaseIdx]
 			hrt := phaseShiftedR[phaseIdx]
 
-			lf := lt
-			rf := rt
-			lb := d.sqrt2*hlt - d.sqrt2*rt
-			rb := d.sqrt2*lt - d.sqrt2*hrt
+			var lf, rf, lb, rb float64
+			if d.matrix == MatrixQS {
+				lf, rf, lb, rb = decodeQS(lt, rt, hlt, hrt)
+			} else {
+				lf = lt
+				rf = rt
+				lb = d.sqrt2*hlt - d.sqrt2*rt
+				rb = d.sqrt2*lt - d.sqrt2*hrt
+			}
+			if d.backSoftness > 0 {
+				lb = softKnee(lb, d.backSoftness)
+				rb = softKnee(rb, d.backSoftness)
+			}
+
+			if d.hookTap != nil {
+				d.tapBuffers[0][i] = hlt
+				d.tapBuffers[1][i] = hrt
+				d.tapBuffers[2][i] = lb
+				d.tapBuffers[3][i] = rb
+			}
 
-			if d.logicConfig.Enabled {
+			switch {
+			case d.logicAnalysis != nil:
+				d.logicAnalysis.add(firstBlock*d.overlap+outIdx, lf, rf, lb, rb)
+			case !d.logicConfig.Enabled:
+			case d.logicPlan != nil:
+				lf, rf, lb, rb = d.applyLogicPlan(firstBlock*d.overlap+outIdx, lf, rf, lb, rb)
+			default:
 				lf, rf, lb, rb = d.applyLogicSteering(lf, rf, lb, rb)
 			}
 
-			output[0][outIdx] = lf
-			output[1][outIdx] = rf
-			output[2][outIdx] = lb
-			output[3][outIdx] = rb
+			blockOut[0][i] = lf
+			blockOut[1][i] = rf
+			blockOut[2][i] = lb
+			blockOut[3][i] = rb
+			count++
+		}
+
+		if d.hookTap != nil {
+			d.hookTap(firstBlock+blockIdx, [][]float64{
+				d.tapBuffers[0][:count],
+				d.tapBuffers[1][:count],
+				d.tapBuffers[2][:count],
+				d.tapBuffers[3][:count],
+			})
+		}
+
+		if d.hookAfter != nil {
+			d.hookAfter(firstBlock+blockIdx, [][]float64{
+				blockOut[0][:count],
+				blockOut[1][:count],
+				blockOut[2][:count],
+				blockOut[3][:count],
+			})
+		}
+
+		for ch := 0; ch < 4; ch++ {
+			copy(output[ch][startIdx:startIdx+count], blockOut[ch][:count])
 		}
 	}
 
-	return output, nil
+	if d.bassCrossover > 0 {
+		d.foldBackBass(output)
+	}
+	if d.rearLowpass.taps != nil {
+		d.applyRearLowpass(output)
+	}
+	if d.crossfeed.amount > 0 {
+		d.applyCrossfeed(output)
+	}
+	if d.layout == LayoutAmbisonicB {
+		return encodeAmbisonicB(output)
+	}
+	return applyBackChannelMode(output, d.backMode)
+}
+
+// sanitizeBlock replaces non-finite samples with 0 in place.
+func sanitizeBlock(block []float64) {
+	for i, v := range block {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			block[i] = 0
+		}
+	}
+}
+
+// LatencyFor returns the latency in samples of a decoder with the given
+// block size and overlap, as GetLatency reports it once constructed, so
+// hosts can announce it before creating one. None of the optional stages
+// (logic steering, bass management, crossfeed, silence skipping) adds
+// latency, except the rear low-pass of SetRearLowpass.
+func LatencyFor(blockSize, overlap int) int {
+	// Initial delay calculation from SQ² implementation
+	if overlap == blockSize {
+		return blockSize
+	}
+	return overlap + overlap/2
 }
 
-// GetLatency returns the decoder latency in samples
+// GetLatency returns the decoder latency in samples, including the group
+// delay of the rear low-pass.
 func (d *SQDecoder) GetLatency() int {
-	return d.initialDelay
+	return d.initialDelay + d.rearLowpass.delay()
 }
 
 // GetInfo returns information about the decoder configuration
@@ -200,6 +554,6 @@ func (d *SQDecoder) GetInfo() string {
 		"Block Size: %d samples\n"+
 		"Overlap: %d samples\n"+
 		"Latency: %d samples (%.2f ms @ 44.1kHz)",
-		d.blockSize, d.overlap, d.initialDelay,
-		float64(d.initialDelay)/44100.0*1000.0)
+		d.blockSize, d.overlap, d.GetLatency(),
+		float64(d.GetLatency())/44100.0*1000.0)
 }
@@ -1,10 +1,14 @@
 package decoder_test
 
 import (
+	"errors"
 	"math"
 	"testing"
 
 	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
 )
 
 func TestSQDecoder_Process_FrontChannelsShifted(t *testing.T) {
@@ -120,3 +124,327 @@ func TestSQDecoder_Process_Errors(t *testing.T) {
 		t.Fatalf("expected error for length mismatch")
 	}
 }
+
+func TestSQDecoder_Process_PadUnequalChannels(t *testing.T) {
+	t.Parallel()
+
+	const n = 4096
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	for i := range lt {
+		lt[i] = 0.5 * math.Sin(2.0*math.Pi*float64(i)/97.0)
+		rt[i] = 0.5 * math.Cos(2.0*math.Pi*float64(i)/131.0)
+	}
+	for i := n - 3; i < n; i++ {
+		rt[i] = 0
+	}
+
+	want, err := decoder.NewSQDecoderWithParams(1024, 512).Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	sqDec := decoder.NewSQDecoderWithParams(1024, 512)
+	sqDec.SetPadUnequalChannels(true)
+	got, err := sqDec.Process([][]float64{lt, rt[:n-3]})
+	if err != nil {
+		t.Fatalf("Process() with padding error = %v", err)
+	}
+	for ch := range want {
+		if len(got[ch]) != n {
+			t.Fatalf("channel %d has %d samples, want %d", ch, len(got[ch]), n)
+		}
+		for i := range want[ch] {
+			if got[ch][i] != want[ch][i] {
+				t.Fatalf("out[%d][%d] = %g, want %g as with explicit zeros", ch, i, got[ch][i], want[ch][i])
+			}
+		}
+	}
+}
+
+func TestSQDecoder_Process_SanitizeInput(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 8 * overlap
+	)
+
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	for i := 0; i < n; i++ {
+		lt[i] = 0.5 * math.Sin(2.0*math.Pi*float64(i)/97.0)
+		rt[i] = 0.5 * math.Cos(2.0*math.Pi*float64(i)/131.0)
+	}
+	lt[n/2] = math.NaN()
+	rt[n/2+7] = math.Inf(1)
+
+	raw := decoder.NewSQDecoderWithParams(blockSize, overlap)
+	outRaw, err := raw.Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	nonFinite := 0
+	for ch := 0; ch < 4; ch++ {
+		for _, v := range outRaw[ch] {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				nonFinite++
+			}
+		}
+	}
+	if nonFinite == 0 {
+		t.Fatalf("expected non-finite output without sanitization")
+	}
+
+	sqDec := decoder.NewSQDecoderWithParams(blockSize, overlap)
+	sqDec.SetSanitizeInput(true)
+	out, err := sqDec.Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	for ch := 0; ch < 4; ch++ {
+		for i, v := range out[ch] {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Fatalf("out[%d][%d] = %v, want finite", ch, i, v)
+			}
+		}
+	}
+	if !math.IsNaN(lt[n/2]) {
+		t.Fatalf("SetSanitizeInput modified the caller's input")
+	}
+}
+
+func TestSQDecoder_Process_BackChannelSumDiff(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 6 * overlap
+	)
+
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	for i := 0; i < n; i++ {
+		lt[i] = 0.5 * math.Sin(2.0*math.Pi*float64(i)/97.0)
+		rt[i] = 0.3 * math.Sin(2.0*math.Pi*float64(i)/61.0)
+	}
+
+	discrete, err := decoder.NewSQDecoderWithParams(blockSize, overlap).Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	for _, mode := range []decoder.BackChannelMode{decoder.BackChannelSumDiff, decoder.BackChannelDiscreteAndSumDiff} {
+		sqDec := decoder.NewSQDecoderWithParams(blockSize, overlap)
+		sqDec.SetBackChannelMode(mode)
+
+		out, err := sqDec.Process([][]float64{lt, rt})
+		if err != nil {
+			t.Fatalf("%v: Process() error = %v", mode, err)
+		}
+		if len(out) != mode.Channels() {
+			t.Fatalf("%v: channels = %d, want %d", mode, len(out), mode.Channels())
+		}
+
+		sumCh, diffCh := 2, 3
+		if mode == decoder.BackChannelDiscreteAndSumDiff {
+			sumCh, diffCh = 4, 5
+			for ch := 0; ch < 4; ch++ {
+				for i := 0; i < n; i++ {
+					if out[ch][i] != discrete[ch][i] {
+						t.Fatalf("%v: out[%d][%d] = %v, want %v", mode, ch, i, out[ch][i], discrete[ch][i])
+					}
+				}
+			}
+		}
+
+		for i := 0; i < n; i++ {
+			lb, rb := discrete[2][i], discrete[3][i]
+			if out[0][i] != discrete[0][i] || out[1][i] != discrete[1][i] {
+				t.Fatalf("%v: front channels changed at %d", mode, i)
+			}
+			if out[sumCh][i] != lb+rb {
+				t.Fatalf("%v: sum[%d] = %v, want %v", mode, i, out[sumCh][i], lb+rb)
+			}
+			if out[diffCh][i] != lb-rb {
+				t.Fatalf("%v: diff[%d] = %v, want %v", mode, i, out[diffCh][i], lb-rb)
+			}
+		}
+	}
+}
+
+func TestParseBackChannelMode(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]decoder.BackChannelMode{
+		"discrete": decoder.BackChannelDiscrete,
+		"SumDiff":  decoder.BackChannelSumDiff,
+		"both":     decoder.BackChannelDiscreteAndSumDiff,
+	} {
+		got, err := decoder.ParseBackChannelMode(name)
+		if err != nil || got != want {
+			t.Fatalf("ParseBackChannelMode(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := decoder.ParseBackChannelMode("surround"); err == nil {
+		t.Fatalf("ParseBackChannelMode(surround) error = nil, want error")
+	}
+}
+
+func TestSQDecoder_Process_NoOverlap(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		n         = 10 * blockSize
+		period    = 64.0
+	)
+
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	cosine := make([]float64, n)
+	for i := 0; i < n; i++ {
+		phase := 2.0 * math.Pi * float64(i) / period
+		lt[i] = 0.7 * math.Sin(phase)
+		rt[i] = 0.3 * math.Cos(2.0*math.Pi*float64(i)/131.0)
+		cosine[i] = math.Cos(phase)
+	}
+
+	sqDec := decoder.NewSQDecoderWithParams(blockSize, blockSize)
+	out, err := sqDec.Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// Contiguous blocks read no lookahead, so the fronts are unshifted.
+	for i := 0; i < n; i++ {
+		if out[0][i] != lt[i] || out[1][i] != rt[i] {
+			t.Fatalf("front[%d] = (%v, %v), want (%v, %v)", i, out[0][i], out[1][i], lt[i], rt[i])
+		}
+	}
+	if got := sqDec.GetLatency(); got != blockSize {
+		t.Fatalf("GetLatency() = %d, want %d", got, blockSize)
+	}
+
+	// The phase-shifted path must cover every sample of every block, not
+	// only the half reachable without wrapping around.
+	hilbert, err := decoder.NewHilbertExtractor(blockSize, blockSize).Process([][]float64{lt, make([]float64, n)})
+	if err != nil {
+		t.Fatalf("HilbertExtractor Process() error = %v", err)
+	}
+	for block := 1; block < n/blockSize-1; block++ {
+		from, to := block*blockSize, (block+1)*blockSize
+		if c := math.Abs(correlation(hilbert[0], cosine, from, to)); c < 0.99 {
+			t.Fatalf("block %d: |corr(H(LT), cos)| = %.4f, want > 0.99", block, c)
+		}
+	}
+}
+
+func TestSQDecoder_Process_Precision32(t *testing.T) {
+	t.Parallel()
+
+	// Near full scale, so that the tolerance is relative to 0 dBFS.
+	quad := testsignal.QuadTones(44100, 44100, 0.9, 0.05)
+	process := func(precision sqmath.Precision) [][]float64 {
+		enc := encoder.NewSQEncoder()
+		enc.SetPrecision(precision)
+		encoded, err := enc.Process(quad)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		dec := decoder.NewSQDecoder()
+		dec.EnableLogicSteering(true)
+		dec.SetPrecision(precision)
+		decoded, err := dec.Process(encoded)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return decoded
+	}
+
+	want := process(sqmath.Precision64)
+	got := process(sqmath.Precision32)
+	var maxErr float64
+	for ch := range want {
+		for i := range want[ch] {
+			maxErr = math.Max(maxErr, math.Abs(got[ch][i]-want[ch][i]))
+		}
+	}
+	// The documented tolerance of sqmath.Precision32: -100 dBFS.
+	if maxErr > 1e-5 || maxErr == 0 {
+		t.Fatalf("float32 deviates by %g, want (0, 1e-5]", maxErr)
+	}
+	t.Logf("max deviation %g (%.1f dBFS)", maxErr, 20*math.Log10(maxErr))
+}
+
+func TestLatencyFor(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range [][2]int{{1024, 512}, {2048, 1024}, {1024, 256}, {1024, 1024}, {4096, 2048}} {
+		d := decoder.NewSQDecoderWithParams(p[0], p[1])
+		d.EnableLogicSteering(true)
+		d.SetBassManagement(80)
+		if got, want := decoder.LatencyFor(p[0], p[1]), d.GetLatency(); got != want {
+			t.Fatalf("LatencyFor(%d, %d) = %d, GetLatency() = %d", p[0], p[1], got, want)
+		}
+	}
+	if got := decoder.LatencyFor(1024, 512); got != 768 {
+		t.Fatalf("LatencyFor(1024, 512) = %d, want 768", got)
+	}
+}
+
+func TestNewSQDecoderWithParamsE(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range [][2]int{{1000, 500}, {1024, 0}, {1024, 2048}, {0, 0}, {1, 1}} {
+		d, err := decoder.NewSQDecoderWithParamsE(p[0], p[1])
+		if err == nil || d != nil {
+			t.Fatalf("NewSQDecoderWithParamsE(%d, %d) = %v, %v; want an error", p[0], p[1], d, err)
+		}
+		var paramErr *decoder.ParamError
+		if !errors.As(err, &paramErr) {
+			t.Fatalf("NewSQDecoderWithParamsE(%d, %d) error %v is not a *ParamError", p[0], p[1], err)
+		}
+	}
+	for _, p := range [][2]int{{1024, 512}, {1024, 1024}, {64, 3}} {
+		if _, err := decoder.NewSQDecoderWithParamsE(p[0], p[1]); err != nil {
+			t.Fatalf("NewSQDecoderWithParamsE(%d, %d) error = %v", p[0], p[1], err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("NewSQDecoderWithParams(1000, 500) did not panic")
+		}
+	}()
+	decoder.NewSQDecoderWithParams(1000, 500)
+}
+
+func TestSQDecoder_ZeroValue(t *testing.T) {
+	t.Parallel()
+
+	// A decoder not made by a constructor holds block size and overlap 0.
+	var d decoder.SQDecoder
+	input := [][]float64{make([]float64, 100), make([]float64, 100)}
+	if _, err := d.Process(input); err == nil {
+		t.Fatalf("Process of a zero decoder succeeded; want an error")
+	}
+	if _, err := d.ProcessSegment(input, 64); err == nil {
+		t.Fatalf("ProcessSegment of a zero decoder succeeded; want an error")
+	}
+	if _, err := d.ProcessChunk(input); err == nil {
+		t.Fatalf("ProcessChunk of a zero decoder succeeded; want an error")
+	}
+	if _, err := d.ProcessStream(input); err == nil {
+		t.Fatalf("ProcessStream of a zero decoder succeeded; want an error")
+	}
+	if _, err := d.Flush(); err != nil {
+		t.Fatalf("Flush of a zero decoder error = %v", err)
+	}
+	state := decoder.NewSQDecoder().Snapshot()
+	if err := d.Restore(state); err == nil {
+		t.Fatalf("Restore into a zero decoder succeeded; want an error")
+	}
+}
@@ -0,0 +1,95 @@
+package decoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// LoadHilbertFIR reads Hilbert FIR coefficients from a text file; see
+// ParseHilbertFIR.
+func LoadHilbertFIR(path string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read Hilbert FIR: %w", err)
+	}
+	defer f.Close()
+	coeffs, err := ParseHilbertFIR(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return coeffs, nil
+}
+
+// ParseHilbertFIR reads whitespace-separated float coefficients, as written
+// by MATLAB's save -ascii or numpy.savetxt. Text from # to the end of a
+// line is a comment.
+func ParseHilbertFIR(r io.Reader) ([]float64, error) {
+	var coeffs []float64
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		for _, field := range strings.Fields(line) {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("line %d: invalid coefficient %q", lineNo, field)
+			}
+			coeffs = append(coeffs, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(coeffs) == 0 {
+		return nil, fmt.Errorf("no coefficients")
+	}
+	return coeffs, nil
+}
+
+// SetHilbertFIR replaces the built-in Hilbert filter of both channels with
+// the FIR coeffs, applied as given (no window, unity gain). The filter is
+// centered where the built-in one is, so a linear-phase design of odd
+// length keeps the timing of the decoder. It may have at most as many taps
+// as the built-in filter, FilterLength(blockSize, overlap), so that the
+// decoded samples are free of circular wrap-around. A later SetWindow
+// restores the built-in filter.
+func (d *SQDecoder) SetHilbertFIR(coeffs []float64) error {
+	taps := hilbertTaps(d.blockSize, d.overlap)
+	if len(coeffs) == 0 || len(coeffs) > taps {
+		return fmt.Errorf("Hilbert FIR has %d taps, block size %d with overlap %d allows 1 to %d", len(coeffs), d.blockSize, d.overlap, taps)
+	}
+	impulse := make([]float64, taps)
+	copy(impulse[taps/2-(len(coeffs)-1)/2:], coeffs)
+
+	left, err := sqmath.NewHilbertTransformerFromImpulse(d.blockSize, impulse)
+	if err != nil {
+		return err
+	}
+	right, err := sqmath.NewHilbertTransformerFromImpulse(d.blockSize, impulse)
+	if err != nil {
+		return err
+	}
+	d.hilbertLeft, d.hilbertRight = left, right
+	d.customFIR = true
+	d.SetPrecision(d.precision)
+	return nil
+}
+
+// SetHilbertScale sets the gain of the built-in Hilbert filter,
+// sqmath.DefaultHilbertScale by default. The phase-shifted path, and with it
+// the part of the back channels it feeds, scales linearly with it. It
+// carries over to a later SetWindow; a filter from SetHilbertFIR keeps unity
+// gain.
+func (d *SQDecoder) SetHilbertScale(scale float64) {
+	d.hilbertScale = scale
+	if !d.customFIR {
+		d.hilbertLeft.SetScale(scale)
+		d.hilbertRight.SetScale(scale)
+	}
+}
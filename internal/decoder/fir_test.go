@@ -0,0 +1,135 @@
+package decoder_test
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// writeHilbertFIR writes a 15-tap ideal Hilbert FIR, 2/(πn) for odd n, as
+// numpy.savetxt would.
+func writeHilbertFIR(t *testing.T) (string, []float64) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("# 15-tap Hilbert FIR\n")
+	var coeffs []float64
+	for n := -7; n <= 7; n++ {
+		v := 0.0
+		if n%2 != 0 {
+			v = 2 / (math.Pi * float64(n))
+		}
+		coeffs = append(coeffs, v)
+		fmt.Fprintf(&b, "%.18e\n", v)
+	}
+	path := filepath.Join(t.TempDir(), "hilbert.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, coeffs
+}
+
+func TestSQDecoder_SetHilbertFIR(t *testing.T) {
+	t.Parallel()
+
+	path, want := writeHilbertFIR(t)
+	coeffs, err := decoder.LoadHilbertFIR(path)
+	if err != nil {
+		t.Fatalf("LoadHilbertFIR() error = %v", err)
+	}
+	if len(coeffs) != len(want) {
+		t.Fatalf("LoadHilbertFIR() = %d coefficients, want %d", len(coeffs), len(want))
+	}
+	for i := range want {
+		if coeffs[i] != want[i] {
+			t.Fatalf("coefficient %d = %g, want %g", i, coeffs[i], want[i])
+		}
+	}
+
+	input := testsignal.QuadTones(44100, 8*512, 0.4, 0.05)[:2]
+	builtin, err := decoder.NewSQDecoderWithParams(1024, 512).Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	d := decoder.NewSQDecoderWithParams(1024, 512)
+	if err := d.SetHilbertFIR(coeffs); err != nil {
+		t.Fatalf("SetHilbertFIR() error = %v", err)
+	}
+	custom, err := d.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// The fronts pass through; the backs carry the custom phase shifter.
+	for ch := range 2 {
+		for i := range custom[ch] {
+			if custom[ch][i] != builtin[ch][i] {
+				t.Fatalf("front ch %d frame %d = %g, want %g", ch, i, custom[ch][i], builtin[ch][i])
+			}
+		}
+	}
+	for ch := 2; ch < 4; ch++ {
+		var diff float64
+		for i := range custom[ch] {
+			diff = math.Max(diff, math.Abs(custom[ch][i]-builtin[ch][i]))
+		}
+		if diff < 0.01 {
+			t.Fatalf("back ch %d: max difference to the built-in filter %g, want a different filter", ch, diff)
+		}
+	}
+
+	if err := d.SetHilbertFIR(make([]float64, 513)); err == nil {
+		t.Fatalf("SetHilbertFIR() with 513 taps at overlap 512 expected error")
+	}
+}
+
+func TestParseHilbertFIR_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, text := range []string{"", "# only a comment\n", "0.5 x 0.5", "0.5 NaN"} {
+		if _, err := decoder.ParseHilbertFIR(strings.NewReader(text)); err == nil {
+			t.Fatalf("ParseHilbertFIR(%q) expected error", text)
+		}
+	}
+}
+
+func TestSQDecoder_SetHilbertScale(t *testing.T) {
+	t.Parallel()
+
+	// shifted returns H(LT) of every block, in order.
+	shifted := func(scale float64, window bool) []float64 {
+		d := decoder.NewSQDecoderWithParams(1024, 512)
+		d.SetHilbertScale(scale)
+		if window {
+			// The scale carries over to the new filter.
+			d.SetWindow(sqmath.WindowBlackman)
+		}
+		var out []float64
+		d.SetBlockHook(decoder.HookBeforeMatrix, func(_ int, buffers [][]float64) {
+			out = append(out, buffers[2]...)
+		})
+		if _, err := d.Process(testsignal.QuadTones(44100, 4*512, 0.4, 0.05)[:2]); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		return out
+	}
+
+	for _, window := range []bool{false, true} {
+		want := shifted(sqmath.DefaultHilbertScale, window)
+		for _, scale := range []float64{0.9, 3.6} {
+			got := shifted(scale, window)
+			ratio := scale / sqmath.DefaultHilbertScale
+			for i := range want {
+				if math.Abs(got[i]-ratio*want[i]) > 1e-12 {
+					t.Fatalf("scale %g, window %t: H(LT)[%d] = %g, want %g", scale, window, i, got[i], ratio*want[i])
+				}
+			}
+		}
+	}
+}
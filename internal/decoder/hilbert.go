@@ -0,0 +1,44 @@
+package decoder
+
+// HilbertExtractor returns the 90°-shifted input channels H(LT), H(RT) that
+// the decoder feeds into its matrix, time-aligned with the decoded output.
+// It runs a private decoder and collects the HookIntermediate taps, so the
+// signals are exactly those the decoder uses.
+type HilbertExtractor struct {
+	decoder *SQDecoder
+	taps    [2][]float64
+}
+
+// NewHilbertExtractor creates an extractor with the given block size and
+// overlap.
+func NewHilbertExtractor(blockSize, overlap int) *HilbertExtractor {
+	e := &HilbertExtractor{decoder: NewSQDecoderWithParams(blockSize, overlap)}
+	e.decoder.SetBlockHook(HookIntermediate, func(_ int, buffers [][]float64) {
+		e.taps[0] = append(e.taps[0], buffers[0]...)
+		e.taps[1] = append(e.taps[1], buffers[1]...)
+	})
+	return e
+}
+
+// Process returns H(LT), H(RT) for a whole stereo signal.
+func (e *HilbertExtractor) Process(input [][]float64) ([][]float64, error) {
+	if _, err := e.decoder.Process(input); err != nil {
+		return nil, err
+	}
+	return e.collect(), nil
+}
+
+// ProcessSegment is the segmented form of Process with the same contract
+// as SQDecoder.ProcessSegment.
+func (e *HilbertExtractor) ProcessSegment(input [][]float64, numOutput int) ([][]float64, error) {
+	if _, err := e.decoder.ProcessSegment(input, numOutput); err != nil {
+		return nil, err
+	}
+	return e.collect(), nil
+}
+
+func (e *HilbertExtractor) collect() [][]float64 {
+	out := [][]float64{e.taps[0], e.taps[1]}
+	e.taps = [2][]float64{}
+	return out
+}
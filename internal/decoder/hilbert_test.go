@@ -0,0 +1,101 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+// correlation returns the normalized correlation of a and b over [from, to).
+func correlation(a, b []float64, from, to int) float64 {
+	var ab, aa, bb float64
+	for i := from; i < to; i++ {
+		ab += a[i] * b[i]
+		aa += a[i] * a[i]
+		bb += b[i] * b[i]
+	}
+	return ab / math.Sqrt(aa*bb)
+}
+
+func TestHilbertExtractor_SineBecomesQuadrature(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 16 * overlap
+		period    = 64.0
+	)
+
+	sine := make([]float64, n)
+	cosine := make([]float64, n)
+	for i := range sine {
+		phase := 2 * math.Pi * float64(i) / period
+		sine[i] = 0.5 * math.Sin(phase)
+		cosine[i] = math.Cos(phase)
+	}
+
+	out, err := decoder.NewHilbertExtractor(blockSize, overlap).Process([][]float64{sine, make([]float64, n)})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if len(out) != 2 || len(out[0]) != n || len(out[1]) != n {
+		t.Fatalf("output shape = %dx%d, want 2x%d", len(out), len(out[0]), n)
+	}
+
+	// Skip the edges where the transformer sees the zero padding.
+	from, to := blockSize, n-blockSize
+	if c := math.Abs(correlation(out[0], cosine, from, to)); c < 0.999 {
+		t.Fatalf("|corr(H(LT), cos)| = %.4f, want > 0.999", c)
+	}
+	if c := math.Abs(correlation(out[0], sine, from, to)); c > 0.01 {
+		t.Fatalf("|corr(H(LT), sin)| = %.4f, want ~0", c)
+	}
+	for i, v := range out[1] {
+		if v != 0 {
+			t.Fatalf("H(RT)[%d] = %v, want 0 for silent input", i, v)
+		}
+	}
+}
+
+func TestHilbertExtractor_ProcessSegmentMatchesProcess(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 6*overlap + 77
+	)
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	for i := range lt {
+		lt[i] = 0.5 * math.Sin(2.0*math.Pi*float64(i)/97.0)
+		rt[i] = 0.4 * math.Cos(2.0*math.Pi*float64(i)/131.0)
+	}
+
+	want, err := decoder.NewHilbertExtractor(blockSize, overlap).Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	e := decoder.NewHilbertExtractor(blockSize, overlap)
+	for start := 0; start < n; start += 2 * overlap {
+		numOutput := min(2*overlap, n-start)
+		end := min(start+numOutput+blockSize-overlap, n)
+		got, err := e.ProcessSegment([][]float64{lt[start:end], rt[start:end]}, numOutput)
+		if err != nil {
+			t.Fatalf("ProcessSegment() error = %v", err)
+		}
+		for ch := range got {
+			if len(got[ch]) != numOutput {
+				t.Fatalf("len(got[%d]) = %d, want %d", ch, len(got[ch]), numOutput)
+			}
+			for i, v := range got[ch] {
+				if v != want[ch][start+i] {
+					t.Fatalf("channel %d sample %d = %v, want %v", ch, start+i, v, want[ch][start+i])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package decoder
+
+// BlockStage selects where in the per-block pipeline a BlockHook runs.
+type BlockStage int
+
+const (
+	// HookBeforeMatrix runs after the Hilbert transform and before the
+	// decode matrix. Buffers are LT, RT, H(LT), H(RT), each blockSize long.
+	// With overlap-add, H(LT) and H(RT) only hold the samples the matrix
+	// reads, from overlap/2 on; the rest is 0.
+	HookBeforeMatrix BlockStage = iota
+	// HookAfterMatrix runs after the decode matrix and logic steering.
+	// Buffers are LF, RF, LB, RB holding the block's output samples
+	// (at most overlap long; shorter for the final block).
+	HookAfterMatrix
+	// HookIntermediate taps the signals between the Hilbert transform and
+	// logic steering, time-aligned with the output: H(LT), H(RT) and the
+	// pre-logic LB, RB, sized like the HookAfterMatrix buffers. The buffers
+	// are a copy; modifying them does not affect the output.
+	HookIntermediate
+)
+
+// BlockHook is invoked once per processed block with the block index and the
+// block buffers, which it may modify in place. The buffers are scratch
+// memory owned by the decoder: they are only valid for the duration of the
+// call and are reused for the next block, so a hook must copy anything it
+// wants to keep. Process calls the hook sequentially from the calling
+// goroutine, in block order.
+type BlockHook func(blockIdx int, buffers [][]float64)
+
+// SetBlockHook installs hook at the given stage; a nil hook removes it.
+func (d *SQDecoder) SetBlockHook(stage BlockStage, hook BlockHook) {
+	switch stage {
+	case HookBeforeMatrix:
+		d.hookBefore = hook
+	case HookAfterMatrix:
+		d.hookAfter = hook
+	case HookIntermediate:
+		d.hookTap = hook
+		if hook != nil && d.tapBuffers[0] == nil {
+			for i := range d.tapBuffers {
+				d.tapBuffers[i] = make([]float64, d.overlap)
+			}
+		}
+	}
+}
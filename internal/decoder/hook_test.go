@@ -0,0 +1,184 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+func TestSQDecoder_BlockHook_MutesEveryOtherBlock(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 8*overlap + 100
+	)
+
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	for i := 0; i < n; i++ {
+		lt[i] = 0.5 * math.Sin(2.0*math.Pi*float64(i)/97.0)
+		rt[i] = 0.4 * math.Cos(2.0*math.Pi*float64(i)/131.0)
+	}
+
+	reference, err := decoder.NewSQDecoderWithParams(blockSize, overlap).Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	sqDec := decoder.NewSQDecoderWithParams(blockSize, overlap)
+	calls := 0
+	sqDec.SetBlockHook(decoder.HookAfterMatrix, func(blockIdx int, buffers [][]float64) {
+		calls++
+		if len(buffers) != 4 {
+			t.Fatalf("len(buffers) = %d, want 4", len(buffers))
+		}
+		if blockIdx%2 == 0 {
+			return
+		}
+		for _, buf := range buffers {
+			for i := range buf {
+				buf[i] = 0
+			}
+		}
+	})
+
+	out, err := sqDec.Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if want := (n + overlap - 1) / overlap; calls != want {
+		t.Fatalf("hook calls = %d, want %d", calls, want)
+	}
+
+	for ch := 0; ch < 4; ch++ {
+		for i := 0; i < n; i++ {
+			want := reference[ch][i]
+			if (i/overlap)%2 == 1 {
+				want = 0
+			}
+			if out[ch][i] != want {
+				t.Fatalf("out[%d][%d] = %.15f, want %.15f", ch, i, out[ch][i], want)
+			}
+		}
+	}
+}
+
+func TestSQDecoder_BlockHook_BeforeMatrix(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 4 * overlap
+	)
+
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	for i := 0; i < n; i++ {
+		lt[i] = 0.5 * math.Sin(2.0*math.Pi*float64(i)/97.0)
+	}
+
+	sqDec := decoder.NewSQDecoderWithParams(blockSize, overlap)
+	sqDec.SetBlockHook(decoder.HookBeforeMatrix, func(blockIdx int, buffers [][]float64) {
+		if len(buffers) != 4 {
+			t.Fatalf("len(buffers) = %d, want 4", len(buffers))
+		}
+		for i, buf := range buffers {
+			if len(buf) != blockSize {
+				t.Fatalf("len(buffers[%d]) = %d, want %d", i, len(buf), blockSize)
+			}
+		}
+		// Silence LT and H(LT): every output must become zero.
+		for i := range buffers[0] {
+			buffers[0][i] = 0
+			buffers[2][i] = 0
+		}
+	})
+
+	out, err := sqDec.Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	for ch := 0; ch < 4; ch++ {
+		for i := 0; i < n; i++ {
+			if out[ch][i] != 0 {
+				t.Fatalf("out[%d][%d] = %v, want 0", ch, i, out[ch][i])
+			}
+		}
+	}
+}
+
+func TestSQDecoder_BlockHook_IntermediateMatchesProcessBlock(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 6*overlap + 77
+		known     = 2
+	)
+
+	lt := make([]float64, n)
+	rt := make([]float64, n)
+	for i := 0; i < n; i++ {
+		lt[i] = 0.5 * math.Sin(2.0*math.Pi*float64(i)/97.0)
+		rt[i] = 0.4 * math.Cos(2.0*math.Pi*float64(i)/131.0)
+	}
+
+	reference, err := decoder.NewSQDecoderWithParams(blockSize, overlap).Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var tapped []float64
+	total := 0
+	sqDec := decoder.NewSQDecoderWithParams(blockSize, overlap)
+	sqDec.SetBlockHook(decoder.HookIntermediate, func(blockIdx int, buffers [][]float64) {
+		if len(buffers) != 4 {
+			t.Fatalf("len(buffers) = %d, want 4", len(buffers))
+		}
+		total += len(buffers[0])
+		if blockIdx == known {
+			tapped = append([]float64(nil), buffers[0]...)
+		}
+		// Writes must not leak into the output.
+		for _, buf := range buffers {
+			for i := range buf {
+				buf[i] = 1
+			}
+		}
+	})
+
+	out, err := sqDec.Process([][]float64{lt, rt})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if total != n {
+		t.Fatalf("tapped %d samples, want %d", total, n)
+	}
+	for ch := 0; ch < 4; ch++ {
+		for i := 0; i < n; i++ {
+			if out[ch][i] != reference[ch][i] {
+				t.Fatalf("out[%d][%d] = %v, want %v", ch, i, out[ch][i], reference[ch][i])
+			}
+		}
+	}
+
+	// The tap is the linear convolution of LT with the Hilbert filter, which
+	// a block starting overlap-1 samples earlier yields free of wrap-around.
+	block := make([]float64, blockSize)
+	copy(block, lt[known*overlap+overlap/2-(overlap-1):])
+	want := sqmath.NewHilbertTransformer(blockSize, overlap).ProcessBlock(block)
+	if len(tapped) != overlap {
+		t.Fatalf("len(tapped) = %d, want %d", len(tapped), overlap)
+	}
+	for i := range tapped {
+		if math.Abs(tapped[i]-want[overlap-1+i]) > 1e-15 {
+			t.Fatalf("H(LT)[%d] = %v, want %v", i, tapped[i], want[overlap-1+i])
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package decoder
+
+import (
+	"fmt"
+	"math"
+)
+
+// OutputLayout selects the coordinate system of the decoded output.
+type OutputLayout int
+
+const (
+	// LayoutQuad outputs the four speaker feeds, arranged by the back
+	// channel mode (the default).
+	LayoutQuad OutputLayout = iota
+	// LayoutAmbisonicB outputs horizontal first-order ambisonics in FuMa
+	// B-format: W, X, Y. The quad is encoded as four corner speakers at
+	// ±45° and ±135°; the back channel mode does not apply. Experimental.
+	LayoutAmbisonicB
+)
+
+// String returns the name of the layout.
+func (l OutputLayout) String() string {
+	switch l {
+	case LayoutQuad:
+		return "quad"
+	case LayoutAmbisonicB:
+		return "ambisonic-b"
+	default:
+		return fmt.Sprintf("OutputLayout(%d)", int(l))
+	}
+}
+
+// SetOutputLayout selects speaker feeds or ambisonic B-format output.
+func (d *SQDecoder) SetOutputLayout(layout OutputLayout) {
+	d.layout = layout
+}
+
+// OutputLayout returns the configured output layout.
+func (d *SQDecoder) OutputLayout() OutputLayout {
+	return d.layout
+}
+
+// OutputChannels returns the number of channels the decoder outputs with
+// its layout and back channel mode.
+func (d *SQDecoder) OutputChannels() int {
+	if d.layout == LayoutAmbisonicB {
+		return 3
+	}
+	return d.backMode.Channels()
+}
+
+// OutputChannelNames returns the output channel labels in order.
+func (d *SQDecoder) OutputChannelNames() []string {
+	if d.layout == LayoutAmbisonicB {
+		return []string{"W", "X", "Y"}
+	}
+	return d.backMode.ChannelNames()
+}
+
+// cornerAzimuths are the azimuths of LF, RF, LB, RB in radians,
+// counterclockwise from front, so that positive Y points left.
+var cornerAzimuths = [4]float64{math.Pi / 4, -math.Pi / 4, 3 * math.Pi / 4, -3 * math.Pi / 4}
+
+// encodeAmbisonicB encodes the discrete LF, RF, LB, RB output as plane
+// waves from the corner speakers: W is the pressure sum scaled by 1/√2 as
+// FuMa specifies, X and Y the sums weighted by the cosine and sine of each
+// speaker's azimuth.
+func encodeAmbisonicB(output [][]float64) [][]float64 {
+	var gx, gy [4]float64
+	for ch, az := range cornerAzimuths {
+		gx[ch], gy[ch] = math.Cos(az), math.Sin(az)
+	}
+	n := len(output[0])
+	w := make([]float64, n)
+	x := make([]float64, n)
+	y := make([]float64, n)
+	for i := range n {
+		for ch := range 4 {
+			s := output[ch][i]
+			w[i] += s * math.Sqrt2 / 2
+			x[i] += s * gx[ch]
+			y[i] += s * gy[ch]
+		}
+	}
+	return [][]float64{w, x, y}
+}
@@ -0,0 +1,60 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+// TestOutputLayout_AmbisonicFrontCenter decodes a front-center phantom,
+// LT = RT, to B-format. The fronts decode equal, and the backs in antiphase
+// (RB = -LB), so the backs cancel in W and X: W = X = √2·LF, the ratio of a
+// source centered between speakers at ±45°. What SQ leaks to the backs
+// appears in Y alone, as Y = √2·LB.
+func TestOutputLayout_AmbisonicFrontCenter(t *testing.T) {
+	t.Parallel()
+
+	const n = 16384
+	sine := make([]float64, n)
+	for i := range sine {
+		sine[i] = 0.5 * math.Sin(2*math.Pi*float64(i)/100)
+	}
+	quad, err := decoder.NewSQDecoder().Process([][]float64{sine, sine})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	d := decoder.NewSQDecoder()
+	d.SetOutputLayout(decoder.LayoutAmbisonicB)
+	if got := d.OutputChannels(); got != 3 {
+		t.Fatalf("OutputChannels() = %d, want 3", got)
+	}
+	bformat, err := d.Process([][]float64{sine, sine})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if len(bformat) != 3 {
+		t.Fatalf("got %d channels, want 3", len(bformat))
+	}
+
+	var w2, x2, y2 float64
+	for i := range n {
+		w, x, y := bformat[0][i], bformat[1][i], bformat[2][i]
+		w2 += w * w
+		x2 += x * x
+		y2 += y * y
+		if want := math.Sqrt2 * quad[0][i]; math.Abs(w-want) > 1e-12 {
+			t.Fatalf("W[%d] = %g, want √2·LF = %g", i, w, want)
+		}
+		if math.Abs(x-w) > 1e-12 {
+			t.Fatalf("X[%d] = %g, want W = %g", i, x, w)
+		}
+		if want := math.Sqrt2 * quad[2][i]; math.Abs(y-want) > 1e-12 {
+			t.Fatalf("Y[%d] = %g, want √2·LB = %g", i, y, want)
+		}
+	}
+	if w2 == 0 {
+		t.Fatalf("W is silent")
+	}
+	t.Logf("X/W = %.3f, Y/W = %.3f", math.Sqrt(x2/w2), math.Sqrt(y2/w2))
+}
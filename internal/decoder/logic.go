@@ -12,6 +12,11 @@ type LogicSteeringConfig struct {
 	DominanceThreshold float64
 	MaxBoost           float64
 	MinGain            float64
+	// AxisMode steers the front/back and the left/right axis independently
+	// instead of toward the single dominant corner. DominanceThreshold then
+	// applies to the energy share of the stronger side of each axis: front
+	// or back, left or right.
+	AxisMode bool
 }
 
 // DefaultLogicSteeringConfig returns conservative logic steering defaults.
@@ -45,34 +50,27 @@ func (d *SQDecoder) applyLogicSteering(lf, rf, lb, rb float64) (float64, float64
 		}
 	}
 
-	maxIdx := 0
-	maxVal := d.logicEnv[0]
-	sum := d.logicEnv[0] + d.logicEnv[1] + d.logicEnv[2] + d.logicEnv[3] + logicEpsilon
-	for i := 1; i < 4; i++ {
-		if d.logicEnv[i] > maxVal {
-			maxVal = d.logicEnv[i]
-			maxIdx = i
-		}
-	}
-
-	dominance := maxVal / sum
-	if dominance <= d.logicConfig.DominanceThreshold {
+	gains, steered := d.logicGains(d.logicEnv)
+	if !steered {
 		return lf, rf, lb, rb
 	}
+	return d.steer(gains, lf, rf, lb, rb)
+}
 
-	intensity := (dominance - d.logicConfig.DominanceThreshold) / (1.0 - d.logicConfig.DominanceThreshold)
-	if intensity < 0 {
-		intensity = 0
-	} else if intensity > 1 {
-		intensity = 1
+// logicGains returns the steering gains for the channel envelopes env and
+// whether any channel is steered.
+func (d *SQDecoder) logicGains(env [4]float64) ([4]float64, bool) {
+	if d.logicConfig.AxisMode {
+		return d.axisGains(env)
 	}
+	return d.cornerGains(env)
+}
 
-	boost := 1.0 + (d.logicConfig.MaxBoost-1.0)*intensity
-	cut := 1.0 - (1.0-d.logicConfig.MinGain)*intensity
-
-	gains := [4]float64{cut, cut, cut, cut}
-	gains[maxIdx] = boost
-
+// steer applies gains to the outputs and rescales them to their energy
+// before steering.
+func (d *SQDecoder) steer(gains [4]float64, lf, rf, lb, rb float64) (float64, float64, float64, float64) {
+	d.steeredSamples++
+	energies := [4]float64{lf * lf, rf * rf, lb * lb, rb * rb}
 	out := [4]float64{
 		lf * gains[0],
 		rf * gains[1],
@@ -92,3 +90,70 @@ func (d *SQDecoder) applyLogicSteering(lf, rf, lb, rb float64) (float64, float64
 
 	return out[0], out[1], out[2], out[3]
 }
+
+// cornerGains boosts the channel holding the largest share of the envelope
+// energy and cuts the other three. It reports false when no channel dominates.
+func (d *SQDecoder) cornerGains(env [4]float64) ([4]float64, bool) {
+	maxIdx := 0
+	maxVal := env[0]
+	sum := env[0] + env[1] + env[2] + env[3] + logicEpsilon
+	for i := 1; i < 4; i++ {
+		if env[i] > maxVal {
+			maxVal = env[i]
+			maxIdx = i
+		}
+	}
+
+	dominance := maxVal / sum
+	if dominance <= d.logicConfig.DominanceThreshold {
+		return [4]float64{}, false
+	}
+	boost, cut := d.steeringGains(dominance)
+	gains := [4]float64{cut, cut, cut, cut}
+	gains[maxIdx] = boost
+	return gains, true
+}
+
+// axisGains steers the front/back and the left/right axis on their own: the
+// stronger side of an unbalanced axis is boosted and the other one cut. A
+// channel's gain is the product of its two axis gains, so a source centered
+// on one axis is only moved along the other. It reports false when neither
+// axis is unbalanced beyond the threshold.
+func (d *SQDecoder) axisGains(env [4]float64) ([4]float64, bool) {
+	sum := env[0] + env[1] + env[2] + env[3] + logicEpsilon
+	front, back, steeredFB := d.axisSideGains((env[0] + env[1]) / sum)
+	left, right, steeredLR := d.axisSideGains((env[0] + env[2]) / sum)
+	if !steeredFB && !steeredLR {
+		return [4]float64{}, false
+	}
+	return [4]float64{front * left, front * right, back * left, back * right}, true
+}
+
+// axisSideGains returns the gains of the two sides of an axis whose first
+// side holds the given share of the energy.
+func (d *SQDecoder) axisSideGains(share float64) (float64, float64, bool) {
+	dominance := max(share, 1-share)
+	if dominance <= d.logicConfig.DominanceThreshold {
+		return 1, 1, false
+	}
+	boost, cut := d.steeringGains(dominance)
+	if share < 0.5 {
+		return cut, boost, true
+	}
+	return boost, cut, true
+}
+
+// steeringGains maps a dominance above the threshold to the gains of the
+// dominant and the other channels.
+func (d *SQDecoder) steeringGains(dominance float64) (float64, float64) {
+	intensity := (dominance - d.logicConfig.DominanceThreshold) / (1.0 - d.logicConfig.DominanceThreshold)
+	if intensity < 0 {
+		intensity = 0
+	} else if intensity > 1 {
+		intensity = 1
+	}
+
+	boost := 1.0 + (d.logicConfig.MaxBoost-1.0)*intensity
+	cut := 1.0 - (1.0-d.logicConfig.MinGain)*intensity
+	return boost, cut
+}
@@ -55,3 +55,51 @@ func dominantRatio(out [][]float64, skip int) float64 {
 	}
 	return rf / (sum + eps)
 }
+
+func TestLogicSteering_AxisModeSteersCenterFrontForward(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 20 * overlap
+		skip      = 2 * overlap
+	)
+
+	// A center-front source is encoded equally into LT and RT.
+	m := make([]float64, n)
+	for i := range m {
+		m[i] = 0.5 * math.Sin(2.0*math.Pi*float64(i)/97.0)
+	}
+	decode := func(logic, axis bool) [4]float64 {
+		d := decoder.NewSQDecoderWithParams(blockSize, overlap)
+		d.SetSampleRate(44100)
+		config := decoder.DefaultLogicSteeringConfig()
+		config.Enabled = logic
+		config.AxisMode = axis
+		d.SetLogicSteeringConfig(config)
+		out, err := d.Process([][]float64{m, m})
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		var energy [4]float64
+		for ch := range out {
+			for _, v := range out[ch][skip:] {
+				energy[ch] += v * v
+			}
+		}
+		return energy
+	}
+	frontShare := func(e [4]float64) float64 {
+		return (e[0] + e[1]) / (e[0] + e[1] + e[2] + e[3])
+	}
+
+	basic, axis := decode(false, false), decode(true, true)
+	if frontShare(axis) <= frontShare(basic)*1.1 {
+		t.Fatalf("front energy share = %.4f, want > %.4f (basic %.4f)", frontShare(axis), frontShare(basic)*1.1, frontShare(basic))
+	}
+	// Steered forward, not pulled toward a front corner.
+	if math.Abs(axis[0]-axis[1]) > 1e-9*axis[0] || math.Abs(axis[2]-axis[3]) > 1e-9*axis[2] {
+		t.Fatalf("channel energies = %v, want LF = RF and LB = RB", axis)
+	}
+}
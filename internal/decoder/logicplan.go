@@ -0,0 +1,138 @@
+package decoder
+
+import "math"
+
+// Two-pass logic steering decodes a whole signal twice. The first pass
+// records the energy of the unsteered matrix output in frames of
+// logicPlanFrame samples. The envelopes are then smoothed forward and
+// backward with the attack time, which leaves them without delay, and the
+// second pass steers with the gains of those envelopes interpolated between
+// frame centers. A change of the dominant channel is thus followed as it
+// happens, where the causal envelopes lag by the attack time and the
+// release time lets the previous channel hang on.
+
+// logicPlanFrame is the length in samples of the frames the first pass
+// records.
+const logicPlanFrame = 64
+
+// LogicPlan is the non-causal logic steering of one signal, recorded by a
+// decoder after StartLogicAnalysis and applied by SetLogicPlan.
+type LogicPlan struct {
+	// energy holds the summed channel energies of every frame; samples is
+	// the number of samples recorded.
+	energy  [][4]float64
+	samples int
+	// gains are the steering gains of every frame and steered whether the
+	// frame is steered at all, computed by SetLogicPlan.
+	gains   [][4]float64
+	steered []bool
+}
+
+// StartLogicAnalysis makes the decoder record the first pass of two-pass
+// logic steering into the returned plan: it decodes without steering and
+// adds the energy of every output sample to the plan. Decode the whole
+// signal, with Process or ProcessSegment from its start, then pass the plan
+// to SetLogicPlan of a decoder with the same settings.
+func (d *SQDecoder) StartLogicAnalysis() *LogicPlan {
+	d.logicAnalysis = &LogicPlan{}
+	return d.logicAnalysis
+}
+
+// SetLogicPlan makes logic steering, when enabled, apply plan instead of
+// following the output causally. It ends a logic analysis of the decoder,
+// so the same decoder can decode the second pass after Reset. The gains
+// derive from the decoder's logic steering settings and sample rate, which
+// must be set before; a nil plan restores causal steering. The plan is not
+// part of Snapshot.
+func (d *SQDecoder) SetLogicPlan(plan *LogicPlan) {
+	d.logicAnalysis = nil
+	d.logicPlan = plan
+	if plan != nil {
+		d.planLogic(plan)
+	}
+}
+
+// Frames returns the number of frames of the plan and how many of them are
+// steered, for logging.
+func (p *LogicPlan) Frames() (frames, steered int) {
+	for _, s := range p.steered {
+		if s {
+			steered++
+		}
+	}
+	return len(p.energy), steered
+}
+
+// add records the outputs at sample position pos.
+func (p *LogicPlan) add(pos int, lf, rf, lb, rb float64) {
+	frame := pos / logicPlanFrame
+	for len(p.energy) <= frame {
+		p.energy = append(p.energy, [4]float64{})
+	}
+	e := &p.energy[frame]
+	e[0] += lf * lf
+	e[1] += rf * rf
+	e[2] += lb * lb
+	e[3] += rb * rb
+	p.samples = max(p.samples, pos+1)
+}
+
+// planLogic computes the gains of plan from its recorded energies.
+func (d *SQDecoder) planLogic(plan *LogicPlan) {
+	n := len(plan.energy)
+	env := make([][4]float64, n)
+	for i, e := range plan.energy {
+		frame := min(logicPlanFrame, plan.samples-i*logicPlanFrame)
+		for ch := range e {
+			env[i][ch] = e[ch] / float64(frame)
+		}
+	}
+
+	// A one-pole smoother run forward and then backward has no delay.
+	coeff := 0.0
+	if d.logicConfig.AttackTime > 0 && d.sampleRate > 0 {
+		coeff = math.Exp(-logicPlanFrame / (d.logicConfig.AttackTime * float64(d.sampleRate)))
+	}
+	for i := 1; i < n; i++ {
+		for ch := range env[i] {
+			env[i][ch] = coeff*env[i-1][ch] + (1-coeff)*env[i][ch]
+		}
+	}
+	for i := n - 2; i >= 0; i-- {
+		for ch := range env[i] {
+			env[i][ch] = coeff*env[i+1][ch] + (1-coeff)*env[i][ch]
+		}
+	}
+
+	plan.gains = make([][4]float64, n)
+	plan.steered = make([]bool, n)
+	for i := range env {
+		gains, steered := d.logicGains(env[i])
+		if !steered {
+			gains = [4]float64{1, 1, 1, 1}
+		}
+		plan.gains[i], plan.steered[i] = gains, steered
+	}
+}
+
+// applyLogicPlan steers the outputs at sample position pos with the gains of
+// the plan, interpolated linearly between the centers of its frames.
+func (d *SQDecoder) applyLogicPlan(pos int, lf, rf, lb, rb float64) (float64, float64, float64, float64) {
+	plan := d.logicPlan
+	n := len(plan.gains)
+	if n == 0 {
+		return lf, rf, lb, rb
+	}
+	x := (float64(pos) - logicPlanFrame/2) / logicPlanFrame
+	i := min(max(int(math.Floor(x)), 0), n-1)
+	next := min(i+1, n-1)
+	if !plan.steered[i] && !plan.steered[next] {
+		return lf, rf, lb, rb
+	}
+	t := min(max(x-float64(i), 0), 1)
+	var gains [4]float64
+	for ch := range gains {
+		gains[ch] = plan.gains[i][ch] + t*(plan.gains[next][ch]-plan.gains[i][ch])
+	}
+	return d.steer(gains, lf, rf, lb, rb)
+}
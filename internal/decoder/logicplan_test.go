@@ -0,0 +1,116 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+// TestLogicPlan_MovingSource follows a source moving from LF to RF. The
+// steering log is the level of every channel with steering relative to the
+// level without, in 5 ms windows around the move.
+func TestLogicPlan_MovingSource(t *testing.T) {
+	t.Parallel()
+
+	const sampleRate, slot, window = 44100, 22050, 220
+	stereo, err := encoder.NewSQEncoder().Process(testsignal.MovingSource(sampleRate, slot, 440, 0.5))
+	if err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+	newDecoder := func(logic bool) *decoder.SQDecoder {
+		d := decoder.NewSQDecoder()
+		d.SetSampleRate(sampleRate)
+		d.EnableLogicSteering(logic)
+		return d
+	}
+	unsteered, err := newDecoder(false).Process(stereo)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	single, err := newDecoder(true).Process(stereo)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	d := newDecoder(true)
+	plan := d.StartLogicAnalysis()
+	if _, err := d.Process(stereo); err != nil {
+		t.Fatalf("analysis Process() error = %v", err)
+	}
+	d.SetLogicPlan(plan)
+	d.Reset()
+	twoPass, err := d.Process(stereo)
+	if err != nil {
+		t.Fatalf("two-pass Process() error = %v", err)
+	}
+
+	steeringLog := func(out [][]float64) [][4]float64 {
+		var log [][4]float64
+		for start := slot - 100*sampleRate/1000; start < slot+300*sampleRate/1000; start += window {
+			var gains [4]float64
+			for ch := range gains {
+				var steered, plain float64
+				for i := start; i < start+window; i++ {
+					steered += out[ch][i] * out[ch][i]
+					plain += unsteered[ch][i] * unsteered[ch][i]
+				}
+				gains[ch] = 10 * math.Log10((steered+1e-20)/(plain+1e-20))
+			}
+			log = append(log, gains)
+		}
+		return log
+	}
+	// settle returns the time after the move from which RF stays boosted,
+	// and the largest gain step between windows.
+	settle := func(log [][4]float64) (float64, float64) {
+		settled, step := len(log), 0.0
+		for i := len(log) - 1; i >= 0 && log[i][1] > 0.5; i-- {
+			settled = i
+		}
+		for i := 1; i < len(log); i++ {
+			for ch := range log[i] {
+				step = max(step, math.Abs(log[i][ch]-log[i-1][ch]))
+			}
+		}
+		return float64(settled*window)/sampleRate - 0.1, step
+	}
+
+	singleSettle, singleStep := settle(steeringLog(single))
+	twoSettle, twoStep := settle(steeringLog(twoPass))
+	if twoSettle > 0.05 || twoSettle >= singleSettle {
+		t.Fatalf("RF steered %.0f ms after the move with two passes, %.0f ms with one; want within 50 ms and earlier",
+			1000*twoSettle, 1000*singleSettle)
+	}
+	if twoStep >= singleStep {
+		t.Fatalf("largest gain step %.2f dB with two passes, %.2f dB with one; want smoother", twoStep, singleStep)
+	}
+
+	// A first pass fed as a stream plans the same steering.
+	d = newDecoder(true)
+	plan = d.StartLogicAnalysis()
+	for start := 0; start < len(stereo[0]); start += 1000 {
+		end := min(start+1000, len(stereo[0]))
+		if _, err := d.ProcessStream([][]float64{stereo[0][start:end], stereo[1][start:end]}); err != nil {
+			t.Fatalf("ProcessStream() error = %v", err)
+		}
+	}
+	if _, err := d.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	d.SetLogicPlan(plan)
+	d.Reset()
+	streamed, err := d.Process(stereo)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	for ch := range streamed {
+		for i := range streamed[ch] {
+			if streamed[ch][i] != twoPass[ch][i] {
+				t.Fatalf("channel %d sample %d = %g with a streamed first pass, %g without", ch, i, streamed[ch][i], twoPass[ch][i])
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package decoder
+
+import "github.com/cwbudde/go-sq-tool/pkg/sqmath"
+
+// rearLowpass is the state of the rear low-pass.
+type rearLowpass struct {
+	cutoff float64
+	taps   []float64
+	// history[ch] holds the last samples of LF, RF, LB and RB, oldest
+	// first: the group delay of the filter for the front delay lines, one
+	// less than the filter length for the rears.
+	history [4][]float64
+}
+
+// SetRearLowpass band-limits LB and RB with a linear-phase FIR low-pass at
+// cutoffHz, as vintage SQ decoders rolled off their rear channels around
+// 10 kHz, which also hides the Hilbert inaccuracy near Nyquist. The
+// transition band is a fifth of the cutoff wide. LF and RF are delayed by
+// the group delay of the filter so the image stays aligned; the delay adds
+// to GetLatency. A cutoff of 0 disables the filter; cutoffs near Nyquist
+// are limited to 0.45 times the sample rate.
+func (d *SQDecoder) SetRearLowpass(cutoffHz float64) {
+	d.rearLowpass.cutoff = max(cutoffHz, 0)
+	d.updateRearLowpass()
+}
+
+// updateRearLowpass designs the filter for the sample rate and clears the
+// delay lines.
+func (d *SQDecoder) updateRearLowpass() {
+	lp := &d.rearLowpass
+	lp.taps = nil
+	if lp.cutoff > 0 && d.sampleRate > 0 {
+		freq := min(lp.cutoff, 0.45*float64(d.sampleRate))
+		lp.taps = sqmath.LowpassFIR(float64(d.sampleRate), freq, freq/5)
+	}
+	d.resetRearLowpass()
+}
+
+// resetRearLowpass clears the delay lines.
+func (d *SQDecoder) resetRearLowpass() {
+	lp := &d.rearLowpass
+	for ch := range lp.history {
+		lp.history[ch] = nil
+	}
+	if lp.taps == nil {
+		return
+	}
+	for ch := range lp.history {
+		n := len(lp.taps) - 1
+		if ch < 2 {
+			n = lp.delay()
+		}
+		lp.history[ch] = make([]float64, n)
+	}
+}
+
+// delay returns the group delay of the filter in samples, 0 when it is
+// disabled.
+func (lp *rearLowpass) delay() int {
+	return len(lp.taps) / 2
+}
+
+// applyRearLowpass filters LB and RB of output in place and delays LF and
+// RF to match. The delay lines carry over to the next segment.
+func (d *SQDecoder) applyRearLowpass(output [][]float64) {
+	lp := &d.rearLowpass
+	for ch := range lp.history {
+		x := output[ch]
+		history := lp.history[ch]
+		// Extend the channel by its history so that input sample i is at
+		// len(history)+i.
+		ext := append(append(make([]float64, 0, len(history)+len(x)), history...), x...)
+		if ch < 2 {
+			copy(x, ext)
+		} else {
+			for i := range x {
+				sum := 0.0
+				for k, h := range lp.taps {
+					sum += h * ext[i+len(history)-k]
+				}
+				x[i] = sum
+			}
+		}
+		copy(history, ext[len(ext)-len(history):])
+	}
+}
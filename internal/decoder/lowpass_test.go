@@ -0,0 +1,122 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+func TestSQDecoder_RearLowpass(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sampleRate = 44100
+		n          = 16384
+		cutoff     = 10000.0
+	)
+	decode := func(freq, cutoff float64) ([][]float64, int) {
+		input := [][]float64{make([]float64, n), make([]float64, n)}
+		for i := range n {
+			input[0][i] = 0.5 * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)
+			input[1][i] = 0.3 * math.Sin(2*math.Pi*freq*float64(i)/sampleRate+1)
+		}
+		d := decoder.NewSQDecoder()
+		d.SetSampleRate(sampleRate)
+		d.SetRearLowpass(cutoff)
+		out, err := d.Process(input)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		return out, d.GetLatency()
+	}
+	from, to := n/4, 3*n/4
+	rms := func(x []float64) float64 {
+		sum := 0.0
+		for _, v := range x[from:to] {
+			sum += v * v
+		}
+		return math.Sqrt(sum / float64(to-from))
+	}
+
+	for _, tt := range []struct {
+		freq, min, max float64
+	}{
+		{1000, 0.99, 1.01},
+		{5000, 0.99, 1.01},
+		{15000, 0, 1e-3},
+	} {
+		plain, latency := decode(tt.freq, 0)
+		filtered, filteredLatency := decode(tt.freq, cutoff)
+		delay := filteredLatency - latency
+		if delay <= 0 {
+			t.Fatalf("latency %d with the low-pass, %d without", filteredLatency, latency)
+		}
+
+		// The fronts are only delayed, by the group delay of the filter.
+		for ch := range 2 {
+			for i := delay; i < n; i++ {
+				if filtered[ch][i] != plain[ch][i-delay] {
+					t.Fatalf("%g Hz channel %d frame %d = %g, want %g", tt.freq, ch, i, filtered[ch][i], plain[ch][i-delay])
+				}
+			}
+		}
+		for ch := 2; ch < 4; ch++ {
+			gain := rms(filtered[ch]) / rms(plain[ch])
+			if gain < tt.min || gain > tt.max {
+				t.Fatalf("%g Hz channel %d gain = %g, want [%g, %g]", tt.freq, ch, gain, tt.min, tt.max)
+			}
+			if tt.freq > cutoff {
+				continue
+			}
+			// In the passband the rears are delayed like the fronts, so the
+			// image stays aligned.
+			peak := 0.0
+			for i := from; i < to; i++ {
+				peak = max(peak, math.Abs(filtered[ch][i]-plain[ch][i-delay]))
+			}
+			if peak > 0.01*rms(plain[ch]) {
+				t.Fatalf("%g Hz channel %d deviates by %g from the delayed rear", tt.freq, ch, peak)
+			}
+		}
+	}
+}
+
+func TestSQDecoder_RearLowpass_SegmentsMatchSingleCall(t *testing.T) {
+	t.Parallel()
+
+	const n = 8192
+	input := [][]float64{make([]float64, n), make([]float64, n)}
+	for i := range n {
+		input[0][i] = math.Sin(float64(i) * 0.05)
+		input[1][i] = math.Cos(float64(i) * 0.9)
+	}
+	const blockSize, overlap = 1024, 512
+	newDecoder := func() *decoder.SQDecoder {
+		d := decoder.NewSQDecoderWithParams(blockSize, overlap)
+		d.SetSampleRate(44100)
+		d.SetRearLowpass(8000)
+		return d
+	}
+	want, err := newDecoder().Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	segmented := newDecoder()
+	step := 3 * overlap
+	for start := 0; start < n; start += step {
+		numOutput := min(step, n-start)
+		end := min(start+numOutput+blockSize-overlap, n)
+		got, err := segmented.ProcessSegment([][]float64{input[0][start:end], input[1][start:end]}, numOutput)
+		if err != nil {
+			t.Fatalf("ProcessSegment() error = %v", err)
+		}
+		for ch := range got {
+			for i, v := range got[ch] {
+				if v != want[ch][start+i] {
+					t.Fatalf("ch %d frame %d = %g, want %g", ch, start+i, v, want[ch][start+i])
+				}
+			}
+		}
+	}
+}
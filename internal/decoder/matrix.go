@@ -0,0 +1,77 @@
+package decoder
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Matrix selects the matrix system whose decode equations the decoder
+// applies.
+type Matrix int
+
+const (
+	// MatrixSQ is the CBS SQ matrix (the default).
+	MatrixSQ Matrix = iota
+	// MatrixQS is the Sansui QS Regular Matrix.
+	MatrixQS
+)
+
+// QS mixes each channel into the totals at 22.5° from its own side:
+// qsCos = cos 22.5° ≈ 0.924 and qsSin = sin 22.5° ≈ 0.383.
+var (
+	qsCos = math.Cos(math.Pi / 8)
+	qsSin = math.Sin(math.Pi / 8)
+)
+
+// ParseMatrix converts a CLI name ("sq", "qs") into a Matrix.
+func ParseMatrix(name string) (Matrix, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "sq":
+		return MatrixSQ, nil
+	case "qs", "rm":
+		return MatrixQS, nil
+	default:
+		return MatrixSQ, fmt.Errorf("unknown matrix %q (want sq or qs)", name)
+	}
+}
+
+// String returns the CLI name of the matrix.
+func (m Matrix) String() string {
+	switch m {
+	case MatrixSQ:
+		return "sq"
+	case MatrixQS:
+		return "qs"
+	default:
+		return fmt.Sprintf("Matrix(%d)", int(m))
+	}
+}
+
+// SetMatrix selects the SQ or QS decode equations. Logic steering, bass
+// management and the other stages after the matrix apply to either.
+func (d *SQDecoder) SetMatrix(m Matrix) {
+	d.matrix = m
+}
+
+// Matrix returns the configured matrix system.
+func (d *SQDecoder) Matrix() Matrix {
+	return d.matrix
+}
+
+// decodeQS applies the QS decode matrix, the conjugate transpose of the
+// encode matrix: the fronts take both totals in phase, the backs both
+// totals shifted by -90°, each at cos 22.5° from its own side and sin 22.5°
+// from the other.
+//
+//	LF = 0.924·LT + 0.383·RT
+//	RF = 0.383·LT + 0.924·RT
+//	LB = -0.924·H(LT) + 0.383·H(RT)
+//	RB = -0.383·H(LT) + 0.924·H(RT)
+func decodeQS(lt, rt, hlt, hrt float64) (lf, rf, lb, rb float64) {
+	lf = qsCos*lt + qsSin*rt
+	rf = qsSin*lt + qsCos*rt
+	lb = -qsCos*hlt + qsSin*hrt
+	rb = -qsSin*hlt + qsCos*hrt
+	return lf, rf, lb, rb
+}
@@ -0,0 +1,106 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+)
+
+func TestParseMatrix(t *testing.T) {
+	t.Parallel()
+
+	for _, m := range []decoder.Matrix{decoder.MatrixSQ, decoder.MatrixQS} {
+		got, err := decoder.ParseMatrix(m.String())
+		if err != nil || got != m {
+			t.Fatalf("ParseMatrix(%q) = %v, %v; want %v", m.String(), got, err, m)
+		}
+	}
+	if _, err := decoder.ParseMatrix("cd4"); err == nil {
+		t.Fatalf("ParseMatrix(\"cd4\") error = nil, want error")
+	}
+}
+
+// TestMatrix_QSFrontBackSeparation encodes a tone in each channel with the
+// SQ and the QS matrix and decodes it with the same matrix. Both use a
+// windowed ideal Hilbert FIR of unity gain, so that the figures are those of
+// the matrices. A QS source leaks into the channel at the other end of its
+// side and the adjacent one at -3 dB, as an SQ front source does, and not
+// at all into the diagonal opposite. Its worst leak may be no worse than
+// SQ's.
+func TestMatrix_QSFrontBackSeparation(t *testing.T) {
+	t.Parallel()
+
+	const n, from, to = 16384, 2048, 14336
+	fir := make([]float64, 255)
+	for i := range fir {
+		k := i - len(fir)/2
+		if k%2 != 0 {
+			hann := 0.5 + 0.5*math.Cos(2*math.Pi*float64(k)/float64(len(fir)+1))
+			fir[i] = 2 / (math.Pi * float64(k)) * hann
+		}
+	}
+	// separation returns the level of every output relative to the
+	// source's own channel, in dB, for a 1 kHz tone in channel src.
+	separation := func(m decoder.Matrix, src int) [4]float64 {
+		quad := make([][]float64, 4)
+		for ch := range quad {
+			quad[ch] = make([]float64, n)
+		}
+		for i := range n {
+			quad[src][i] = 0.5 * math.Sin(2*math.Pi*1000*float64(i)/44100)
+		}
+		e := encoder.NewSQEncoder()
+		e.SetMatrix(m)
+		if err := e.SetHilbertFIR(fir); err != nil {
+			t.Fatalf("encoder SetHilbertFIR() error = %v", err)
+		}
+		stereo, err := e.Process(quad)
+		if err != nil {
+			t.Fatalf("encode error = %v", err)
+		}
+		d := decoder.NewSQDecoder()
+		d.SetMatrix(m)
+		if err := d.SetHilbertFIR(fir); err != nil {
+			t.Fatalf("decoder SetHilbertFIR() error = %v", err)
+		}
+		out, err := d.Process(stereo)
+		if err != nil {
+			t.Fatalf("decode error = %v", err)
+		}
+		var rms [4]float64
+		for ch := range out {
+			for _, v := range out[ch][from:to] {
+				rms[ch] += v * v
+			}
+		}
+		var db [4]float64
+		for ch := range db {
+			db[ch] = 10 * math.Log10((rms[ch]+1e-20)/rms[src])
+		}
+		return db
+	}
+
+	for src := range 4 {
+		sq := separation(decoder.MatrixSQ, src)
+		qs := separation(decoder.MatrixQS, src)
+		t.Logf("source %d: SQ %.1f dB, QS %.1f dB", src, sq, qs)
+		// The channel at the other end on the same side.
+		if other := src ^ 2; math.Abs(qs[other]+3) > 0.5 {
+			t.Fatalf("source %d: QS front/back leak %.2f dB, want -3 dB", src, qs[other])
+		}
+		if diagonal := 3 - src; qs[diagonal] > -30 {
+			t.Fatalf("source %d: QS diagonal leak %.2f dB, want below -30 dB", src, qs[diagonal])
+		}
+		worstSQ, worstQS := math.Inf(-1), math.Inf(-1)
+		for ch := range 4 {
+			if ch != src {
+				worstSQ, worstQS = max(worstSQ, sq[ch]), max(worstQS, qs[ch])
+			}
+		}
+		if worstQS > worstSQ+1 {
+			t.Fatalf("source %d: QS worst leak %.2f dB, SQ %.2f dB; want QS comparable", src, worstQS, worstSQ)
+		}
+	}
+}
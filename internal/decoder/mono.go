@@ -0,0 +1,18 @@
+package decoder
+
+import "fmt"
+
+// ProcessMonoSegment decodes a mono signal as if it were SQ stereo with
+// LT = RT = M, like ProcessSegment on [][]float64{M, M}, and returns the
+// same bit-identical four channels. Both totals then share one Hilbert
+// transform, which is computed once, and RB = -LB before logic steering:
+// the source decodes to the front center. The segmentation rules of
+// ProcessSegment apply.
+func (d *SQDecoder) ProcessMonoSegment(input [][]float64, numOutput int) ([][]float64, error) {
+	if len(input) != 1 {
+		return nil, fmt.Errorf("input must have 1 channel, got %d", len(input))
+	}
+	d.monoInput = true
+	defer func() { d.monoInput = false }()
+	return d.ProcessSegment([][]float64{input[0], input[0]}, numOutput)
+}
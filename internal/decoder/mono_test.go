@@ -0,0 +1,59 @@
+package decoder_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+func TestSQDecoder_ProcessMonoSegmentMatchesDuplicated(t *testing.T) {
+	t.Parallel()
+
+	mono := slotInput(t, 7*stateOverlap+123)[0]
+	n := len(mono)
+	for _, logic := range []bool{false, true} {
+		full := newStateDecoder()
+		full.EnableLogicSteering(logic)
+		want, err := full.Process([][]float64{mono, mono})
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+
+		// Segmented as the pipeline does it.
+		d := newStateDecoder()
+		d.EnableLogicSteering(logic)
+		got := make([][]float64, 4)
+		for start := 0; start < n; start += stateSegment {
+			numOutput := min(stateSegment, n-start)
+			end := min(start+numOutput+stateBlockSize-stateOverlap, n)
+			out, err := d.ProcessMonoSegment([][]float64{mono[start:end]}, numOutput)
+			if err != nil {
+				t.Fatalf("ProcessMonoSegment() error = %v", err)
+			}
+			for ch := range got {
+				got[ch] = append(got[ch], out[ch]...)
+			}
+		}
+		for ch := range want {
+			if len(got[ch]) != n {
+				t.Fatalf("channel %d: %d samples, want %d", ch, len(got[ch]), n)
+			}
+			for i := range want[ch] {
+				if got[ch][i] != want[ch][i] {
+					t.Fatalf("logic %v channel %d sample %d = %v, want %v", logic, ch, i, got[ch][i], want[ch][i])
+				}
+			}
+		}
+		if !logic {
+			for i := range got[2] {
+				if got[3][i] != -got[2][i] {
+					t.Fatalf("RB[%d] = %v, want -LB = %v", i, got[3][i], -got[2][i])
+				}
+			}
+		}
+	}
+
+	if _, err := decoder.NewSQDecoder().ProcessMonoSegment([][]float64{mono, mono}, n); err == nil {
+		t.Fatal("ProcessMonoSegment() accepted stereo input")
+	}
+}
@@ -0,0 +1,55 @@
+package decoder
+
+import "github.com/cwbudde/go-sq-tool/pkg/sqmath"
+
+// The phase-shifted path is a linear convolution computed by overlap-add.
+// Every block filters the hop of input that follows its first hop, with the
+// rest of the FFT block zeroed: the rectangular hop windows sum to exactly
+// one, and with the hop plus the filter tail fitting into the block the
+// circular convolution is free of wrap-around. The filter output is summed
+// into an accumulator at the hop and the block outputs the samples no later
+// segment contributes to. A steady sine therefore comes out as a steady
+// sine, where taking each block's output straight from its circular
+// convolution put wrapped-around samples into the first half of every hop
+// and modulated the back channels at the hop rate.
+//
+// In the default configuration a block outputs H for the input at
+// [overlap/2, overlap/2+overlap) of the block: the first half of that comes
+// from the accumulator of the previous blocks, the second half adds the
+// block's own segment at [overlap, 2·overlap). The no-overlap mode, and any
+// overlap above half the block size, keeps the circular convolution.
+
+// overlapAddHilbert filters the segment of block with ht, adds it to carry
+// and returns a block-sized buffer holding the finished samples at the
+// indices the decode matrix reads them from. carry is updated in place: it
+// holds the accumulator from the first sample the next block outputs on.
+func (d *SQDecoder) overlapAddHilbert(ht *sqmath.HilbertTransformer, block, carry []float64) []float64 {
+	hop := d.overlap
+	offset := hop / 2
+	lead := hop - offset
+
+	segment := make([]float64, d.blockSize)
+	copy(segment, block[hop:2*hop])
+	filtered := ht.ProcessBlock(segment)
+
+	phase := make([]float64, d.blockSize)
+	copy(phase[offset:hop], carry[:lead])
+	for i := range offset {
+		phase[hop+i] = filtered[i] + carry[lead+i]
+	}
+	for i := range carry {
+		next := 0.0
+		if hop+i < len(carry) {
+			next = carry[hop+i]
+		}
+		carry[i] = filtered[offset+i] + next
+	}
+	return phase
+}
+
+// resetHilbertCarry clears the overlap-add accumulator.
+func (d *SQDecoder) resetHilbertCarry() {
+	for ch := range d.hilbertCarry {
+		clear(d.hilbertCarry[ch])
+	}
+}
@@ -0,0 +1,47 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+func TestSQDecoder_OverlapAdd_SteadySine(t *testing.T) {
+	t.Parallel()
+
+	const n, sampleRate = 1 << 15, 44100.0
+	for _, freq := range []float64{100, 1234.5, 12000} {
+		lt := make([]float64, n)
+		for i := range lt {
+			lt[i] = 0.5 * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)
+		}
+		d := decoder.NewSQDecoder()
+		d.EnableLogicSteering(false)
+		out, err := d.Process([][]float64{lt, make([]float64, n)})
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+
+		// With RT silent, LB is the phase-shifted LT alone. Fit a sine of
+		// the input frequency to it away from the edges: any modulation at
+		// the hop rate is left in the residual.
+		lb := out[2][n/4 : 3*n/4]
+		var ss, cc, sc, ys, yc float64
+		for i, v := range lb {
+			phase := 2 * math.Pi * freq * float64(n/4+i) / sampleRate
+			s, c := math.Sin(phase), math.Cos(phase)
+			ss, cc, sc, ys, yc = ss+s*s, cc+c*c, sc+s*c, ys+v*s, yc+v*c
+		}
+		det := ss*cc - sc*sc
+		a, b := (ys*cc-yc*sc)/det, (yc*ss-ys*sc)/det
+		amplitude, residual := math.Hypot(a, b), 0.0
+		for i, v := range lb {
+			phase := 2 * math.Pi * freq * float64(n/4+i) / sampleRate
+			residual = max(residual, math.Abs(v-a*math.Sin(phase)-b*math.Cos(phase)))
+		}
+		if amplitude == 0 || residual > 1e-9*amplitude {
+			t.Fatalf("%g Hz: LB amplitude %g, residual %g; want a clean sine", freq, amplitude, residual)
+		}
+	}
+}
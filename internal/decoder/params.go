@@ -0,0 +1,34 @@
+package decoder
+
+import "math"
+
+// referenceSampleRate is the rate DefaultBlockSize is tuned for.
+const referenceSampleRate = 44100
+
+// minRecommendedBlockSize keeps the Hilbert filter usable at low rates.
+const minRecommendedBlockSize = 256
+
+// RecommendedParams returns a block size and overlap that keep the Hilbert
+// impulse response at roughly the same duration as DefaultBlockSize at
+// 44.1 kHz. The block size is the power of two nearest (on a log scale) to
+// the rate-scaled default, so 44.1/48 kHz use 1024, 88.2/96 kHz use 2048 and
+// 176.4/192 kHz use 4096. Overlap is half the block size. Non-positive rates
+// return the defaults.
+func RecommendedParams(sampleRate int) (blockSize, overlap int) {
+	if sampleRate <= 0 {
+		return DefaultBlockSize, DefaultOverlap
+	}
+
+	ratio := float64(sampleRate) / referenceSampleRate
+	blockSize = DefaultBlockSize
+	scale := 1.0
+	for ratio >= math.Sqrt2*scale {
+		blockSize *= 2
+		scale *= 2
+	}
+	for ratio < scale/math.Sqrt2 && blockSize > minRecommendedBlockSize {
+		blockSize /= 2
+		scale /= 2
+	}
+	return blockSize, blockSize / 2
+}
@@ -0,0 +1,34 @@
+package decoder_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+func TestRecommendedParams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sampleRate int
+		blockSize  int
+	}{
+		{0, decoder.DefaultBlockSize},
+		{8000, 256},
+		{22050, 512},
+		{32000, 1024},
+		{44100, 1024},
+		{48000, 1024},
+		{88200, 2048},
+		{96000, 2048},
+		{176400, 4096},
+		{192000, 4096},
+	}
+	for _, tt := range tests {
+		blockSize, overlap := decoder.RecommendedParams(tt.sampleRate)
+		if blockSize != tt.blockSize || overlap != tt.blockSize/2 {
+			t.Fatalf("RecommendedParams(%d) = (%d, %d), want (%d, %d)",
+				tt.sampleRate, blockSize, overlap, tt.blockSize, tt.blockSize/2)
+		}
+	}
+}
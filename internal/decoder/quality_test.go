@@ -0,0 +1,76 @@
+package decoder_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+func TestQualities_ResolveAtSupportedRates(t *testing.T) {
+	t.Parallel()
+
+	rates := []int{8000, 11025, 16000, 22050, 32000, 44100, 48000, 88200, 96000, 176400, 192000}
+	for _, rate := range rates {
+		prevLatency := 0
+		for _, q := range decoder.Qualities() {
+			blockSize, overlap := q.Resolve(rate)
+			if blockSize < 64 || blockSize > 1<<16 || blockSize&(blockSize-1) != 0 {
+				t.Fatalf("%s at %d Hz: block size %d is not a power of 2 in [64, 65536]", q.Name, rate, blockSize)
+			}
+			if overlap <= 0 || overlap > blockSize || blockSize%overlap != 0 {
+				t.Fatalf("%s at %d Hz: invalid overlap %d for block size %d", q.Name, rate, overlap, blockSize)
+			}
+			if _, err := sqmath.ParseWindowType(string(q.Window)); err != nil {
+				t.Fatalf("%s: %v", q.Name, err)
+			}
+
+			// Presets are ordered by quality, which costs latency.
+			latency := decoder.LatencyFor(blockSize, overlap)
+			if latency <= prevLatency {
+				t.Fatalf("%s at %d Hz: latency %d not above the previous preset's %d", q.Name, rate, latency, prevLatency)
+			}
+			prevLatency = latency
+
+			d := decoder.NewSQDecoderWithParams(blockSize, overlap)
+			d.SetSampleRate(rate)
+			d.SetWindow(q.Window)
+			stereo := testsignal.QuadTones(rate, 3*blockSize, 0.4, 0.05)[:2]
+			out, err := d.Process(stereo)
+			if err != nil {
+				t.Fatalf("%s at %d Hz: Process() error = %v", q.Name, rate, err)
+			}
+			if len(out) != 4 || len(out[0]) != len(stereo[0]) {
+				t.Fatalf("%s at %d Hz: got %d channels of %d frames", q.Name, rate, len(out), len(out[0]))
+			}
+		}
+	}
+}
+
+func TestLookupQuality(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range decoder.QualityNames() {
+		q, err := decoder.LookupQuality(name)
+		if err != nil || q.Name != name {
+			t.Fatalf("LookupQuality(%q) = %q, %v", name, q.Name, err)
+		}
+	}
+	if q, _ := decoder.LookupQuality(" Best "); q.Name != "best" {
+		t.Fatalf("LookupQuality(\" Best \") = %q, want best", q.Name)
+	}
+	if _, err := decoder.LookupQuality("ultra"); err == nil {
+		t.Fatalf("LookupQuality(ultra) expected error")
+	}
+
+	// The default preset is the decoder's recommendation.
+	q, _ := decoder.LookupQuality("default")
+	for _, rate := range []int{22050, 44100, 96000} {
+		gotBlock, gotOverlap := q.Resolve(rate)
+		wantBlock, wantOverlap := decoder.RecommendedParams(rate)
+		if gotBlock != wantBlock || gotOverlap != wantOverlap {
+			t.Fatalf("default at %d Hz = %d/%d, want %d/%d", rate, gotBlock, gotOverlap, wantBlock, wantOverlap)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package decoder
+
+import "math"
+
+// ProcessResult is the output of ProcessWithResult together with
+// diagnostics gathered while decoding.
+type ProcessResult struct {
+	// Output is what Process returns.
+	Output [][]float64
+	// Peaks holds the largest absolute sample of every output channel.
+	Peaks []float64
+	// Clipped counts the samples of every output channel beyond ±1.
+	Clipped []int
+	// SteeringEngaged reports whether logic steering changed the gains of
+	// any sample; SteeredSamples counts those samples.
+	SteeringEngaged bool
+	SteeredSamples  int
+	// Blocks is the number of blocks decoded and SkippedBlocks how many of
+	// them were skipped as silent.
+	Blocks        int
+	SkippedBlocks int
+}
+
+// ProcessWithResult decodes input like Process and also reports the output
+// peaks, clipping, logic steering activity and block counts of the call,
+// which otherwise take separate passes over the output or hooks.
+func (d *SQDecoder) ProcessWithResult(input [][]float64) (*ProcessResult, error) {
+	stats := d.silenceStats
+	d.steeredSamples = 0
+	output, err := d.Process(input)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProcessResult{
+		Output:          output,
+		Peaks:           make([]float64, len(output)),
+		Clipped:         make([]int, len(output)),
+		SteeringEngaged: d.steeredSamples > 0,
+		SteeredSamples:  d.steeredSamples,
+		Blocks:          d.silenceStats.Blocks - stats.Blocks,
+		SkippedBlocks:   d.silenceStats.SkippedBlocks - stats.SkippedBlocks,
+	}
+	for ch, samples := range output {
+		for _, v := range samples {
+			v = math.Abs(v)
+			result.Peaks[ch] = max(result.Peaks[ch], v)
+			if v > 1 {
+				result.Clipped[ch]++
+			}
+		}
+	}
+	return result, nil
+}
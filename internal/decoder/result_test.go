@@ -0,0 +1,83 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func TestSQDecoder_ProcessWithResult(t *testing.T) {
+	t.Parallel()
+
+	const n = 10000
+	quad := testsignal.QuadTones(44100, n, 0.6, 0.05)
+	encoded, err := encoder.NewSQEncoder().Process(quad)
+	if err != nil {
+		t.Fatalf("encoder Process() error = %v", err)
+	}
+
+	want, err := decoder.NewSQDecoder().Process(encoded)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	result, err := decoder.NewSQDecoder().ProcessWithResult(encoded)
+	if err != nil {
+		t.Fatalf("ProcessWithResult() error = %v", err)
+	}
+
+	for ch := range want {
+		var peak float64
+		clipped := 0
+		for i, v := range want[ch] {
+			if result.Output[ch][i] != v {
+				t.Fatalf("channel %d sample %d = %g, want %g as from Process", ch, i, result.Output[ch][i], v)
+			}
+			peak = math.Max(peak, math.Abs(v))
+			if math.Abs(v) > 1 {
+				clipped++
+			}
+		}
+		if result.Peaks[ch] != peak || result.Clipped[ch] != clipped {
+			t.Fatalf("channel %d: peak %g, clipped %d; want %g, %d", ch, result.Peaks[ch], result.Clipped[ch], peak, clipped)
+		}
+	}
+	if wantBlocks := (n + decoder.DefaultOverlap - 1) / decoder.DefaultOverlap; result.Blocks != wantBlocks {
+		t.Fatalf("Blocks = %d, want %d", result.Blocks, wantBlocks)
+	}
+	if result.SteeringEngaged || result.SteeredSamples != 0 {
+		t.Fatalf("steering engaged without logic steering: %d samples", result.SteeredSamples)
+	}
+}
+
+func TestSQDecoder_ProcessWithResult_Steering(t *testing.T) {
+	t.Parallel()
+
+	// LB alone dominates, so logic steering engages.
+	quad := testsignal.Isolate(testsignal.QuadTones(44100, 8192, 0.5, 0), 2)
+	encoded, err := encoder.NewSQEncoder().Process(quad)
+	if err != nil {
+		t.Fatalf("encoder Process() error = %v", err)
+	}
+	d := decoder.NewSQDecoder()
+	d.SetSampleRate(44100)
+	d.EnableLogicSteering(true)
+	result, err := d.ProcessWithResult(encoded)
+	if err != nil {
+		t.Fatalf("ProcessWithResult() error = %v", err)
+	}
+	if !result.SteeringEngaged || result.SteeredSamples == 0 || result.SteeredSamples > 8192 {
+		t.Fatalf("SteeringEngaged = %t with %d steered samples, want engaged", result.SteeringEngaged, result.SteeredSamples)
+	}
+
+	// The counters cover one call only.
+	again, err := d.ProcessWithResult(encoded)
+	if err != nil {
+		t.Fatalf("ProcessWithResult() error = %v", err)
+	}
+	if again.Blocks != result.Blocks {
+		t.Fatalf("second call Blocks = %d, want %d", again.Blocks, result.Blocks)
+	}
+}
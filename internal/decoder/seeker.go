@@ -0,0 +1,208 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+)
+
+// FrameReaderAt reads input frames ([channel][frame]) at arbitrary
+// positions, returning io.EOF when fewer than len(dst[0]) frames remain;
+// *wav.ReaderAt satisfies it.
+type FrameReaderAt interface {
+	ReadFramesAt(dst [][]float64, frame int) (int, error)
+}
+
+// SeekerConfig controls the pages a DecodeSeeker decodes and caches.
+type SeekerConfig struct {
+	// PageBlocks is the size of a decoded page in hops (overlaps). Every
+	// page is decoded on its own, so a sample's value does not depend on
+	// which reads came before.
+	PageBlocks int
+	// Preroll is how much input, in seconds, is decoded ahead of a page to
+	// settle the decoder state. It is only spent when the decoder carries
+	// state from block to block (logic steering, bass management,
+	// crossfeed, rear low-pass or silence skipping); otherwise pages decode
+	// exactly from two blocks ahead, which fill the Hilbert overlap-add.
+	Preroll float64
+	// CachePages is the number of most recently used pages kept.
+	CachePages int
+}
+
+// DefaultSeekerConfig returns pages of 16 hops, a 2 s preroll (ten release
+// time constants of the default logic steering) and a cache of 8 pages.
+func DefaultSeekerConfig() SeekerConfig {
+	return SeekerConfig{PageBlocks: 16, Preroll: 2, CachePages: 8}
+}
+
+// DecodeSeeker gives random access to the decoded output of a seekable
+// input, for players that jump around in a file. It decodes the pages a
+// read touches on demand, each from the nearest earlier block boundary, and
+// caches the most recent ones. Overlapping reads return identical samples.
+//
+// Without block-to-block state the output is exactly that of decoding the
+// whole input at once. With logic steering, bass management or silence
+// skipping the state at a page start is rebuilt from the preroll instead of
+// the whole history, which leaves small differences that decay with the
+// preroll length.
+type DecodeSeeker struct {
+	mu         sync.Mutex
+	src        FrameReaderAt
+	numFrames  int
+	newDecoder func() *SQDecoder
+	channels   int
+	pageFrames int
+	preroll    int
+	lookahead  int
+	cacheSize  int
+	pages      map[int][][]float64
+	// recent lists the cached pages, least recently used first.
+	recent []int
+}
+
+// NewDecodeSeeker returns a DecodeSeeker over the first numFrames stereo
+// frames of src. newDecoder creates a configured decoder for every page
+// that has to be decoded; all of them must be configured alike.
+func NewDecodeSeeker(src FrameReaderAt, numFrames int, newDecoder func() *SQDecoder, config SeekerConfig) (*DecodeSeeker, error) {
+	if numFrames < 0 {
+		return nil, fmt.Errorf("frame count must be >= 0, got %d", numFrames)
+	}
+	if config.PageBlocks <= 0 {
+		return nil, fmt.Errorf("page size must be > 0 blocks, got %d", config.PageBlocks)
+	}
+	if config.Preroll < 0 {
+		return nil, fmt.Errorf("preroll must be >= 0, got %g", config.Preroll)
+	}
+	if config.CachePages <= 0 {
+		return nil, fmt.Errorf("cache size must be > 0 pages, got %d", config.CachePages)
+	}
+
+	d := newDecoder()
+	if d.overlap <= 0 || d.overlap > d.blockSize {
+		return nil, fmt.Errorf("invalid decoder overlap %d for block size %d", d.overlap, d.blockSize)
+	}
+	preroll := 0
+	if d.overlapAdd() {
+		preroll = 2 * d.overlap
+	}
+	if d.stateful() {
+		blocks := math.Ceil(config.Preroll * float64(d.sampleRate) / float64(d.overlap))
+		preroll = max(int(blocks)*d.overlap, preroll)
+	}
+	return &DecodeSeeker{
+		src:        src,
+		numFrames:  numFrames,
+		newDecoder: newDecoder,
+		channels:   d.OutputChannels(),
+		pageFrames: config.PageBlocks * d.overlap,
+		preroll:    preroll,
+		lookahead:  d.blockSize - d.overlap,
+		cacheSize:  config.CachePages,
+		pages:      make(map[int][][]float64),
+	}, nil
+}
+
+// stateful reports whether the output of a block depends on earlier blocks.
+func (d *SQDecoder) stateful() bool {
+	return d.logicConfig.Enabled || d.bassCrossover > 0 || d.crossfeed.amount > 0 || d.rearLowpass.taps != nil || d.silenceConfig.Enabled
+}
+
+// NumFrames returns the number of decoded frames.
+func (s *DecodeSeeker) NumFrames() int {
+	return s.numFrames
+}
+
+// Channels returns the number of decoded channels.
+func (s *DecodeSeeker) Channels() int {
+	return s.channels
+}
+
+// ReadAt fills dst ([channel][frame]) with the decoded frames starting at
+// frame and returns the number of frames read. Like io.ReaderAt it returns
+// io.EOF when fewer than len(dst[0]) frames remain. It is safe for
+// concurrent use.
+func (s *DecodeSeeker) ReadAt(dst [][]float64, frame int) (int, error) {
+	if len(dst) != s.channels {
+		return 0, fmt.Errorf("destination must have %d channels, got %d", s.channels, len(dst))
+	}
+	if frame < 0 {
+		return 0, fmt.Errorf("negative frame %d", frame)
+	}
+	if frame >= s.numFrames {
+		return 0, io.EOF
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := min(len(dst[0]), s.numFrames-frame)
+	for pos := frame; pos < frame+n; {
+		index := pos / s.pageFrames
+		page, err := s.page(index)
+		if err != nil {
+			return pos - frame, err
+		}
+		offset := pos - index*s.pageFrames
+		count := min(len(page[0])-offset, frame+n-pos)
+		for ch := range dst {
+			copy(dst[ch][pos-frame:], page[ch][offset:offset+count])
+		}
+		pos += count
+	}
+	if n < len(dst[0]) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// page returns the decoded page index from the cache or decodes it.
+func (s *DecodeSeeker) page(index int) ([][]float64, error) {
+	if page, ok := s.pages[index]; ok {
+		i := slices.Index(s.recent, index)
+		s.recent = append(slices.Delete(s.recent, i, i+1), index)
+		return page, nil
+	}
+
+	page, err := s.decodePage(index)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.recent) == s.cacheSize {
+		delete(s.pages, s.recent[0])
+		s.recent = slices.Delete(s.recent, 0, 1)
+	}
+	s.pages[index] = page
+	s.recent = append(s.recent, index)
+	return page, nil
+}
+
+// decodePage decodes page index with a fresh decoder, starting preroll
+// frames early. Pages and preroll are whole hops, so the blocks line up
+// with those of a decode of the whole input.
+func (s *DecodeSeeker) decodePage(index int) ([][]float64, error) {
+	start := index * s.pageFrames
+	end := min(start+s.pageFrames, s.numFrames)
+	from := max(start-s.preroll, 0)
+	to := min(end+s.lookahead, s.numFrames)
+
+	input := [][]float64{make([]float64, to-from), make([]float64, to-from)}
+	n, err := s.src.ReadFramesAt(input, from)
+	if err != nil && !(errors.Is(err, io.EOF) && n == to-from) {
+		return nil, fmt.Errorf("read input frames %d-%d: %w", from, to, err)
+	}
+	if n < to-from {
+		return nil, fmt.Errorf("read input frames %d-%d: short read of %d frames", from, to, n)
+	}
+
+	output, err := s.newDecoder().ProcessSegment(input, end-from)
+	if err != nil {
+		return nil, err
+	}
+	for ch := range output {
+		output[ch] = output[ch][start-from:]
+	}
+	return output, nil
+}
@@ -0,0 +1,163 @@
+package decoder_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// seekerInput writes SQ stereo test material to a float32 WAV and returns
+// a random access reader over it together with the samples as stored.
+func seekerInput(t *testing.T, numSamples int) (*wav.ReaderAt, [][]float64) {
+	t.Helper()
+
+	stereo := testsignal.QuadTones(44100, numSamples, 0.4, 0.05)[:2]
+	var buf bytes.Buffer
+	data := &wav.AudioData{SampleRate: 44100, Samples: stereo, NumSamples: numSamples}
+	if err := wav.WriteWAVWithOptionsToWriter(&buf, data, 2, wav.WriteOptions{Format: wav.FormatFloat32}); err != nil {
+		t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+	whole, err := wav.ReadWAVBytes(buf.Bytes(), 2)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+	r, err := wav.NewReaderAt(bytes.NewReader(buf.Bytes()), 2)
+	if err != nil {
+		t.Fatalf("NewReaderAt() error = %v", err)
+	}
+	return r, whole.Samples
+}
+
+// readRange reads frames [start, start+n) from s.
+func readRange(t *testing.T, s *decoder.DecodeSeeker, start, n int) [][]float64 {
+	t.Helper()
+
+	dst := make([][]float64, s.Channels())
+	for ch := range dst {
+		dst[ch] = make([]float64, n)
+	}
+	got, err := s.ReadAt(dst, start)
+	if want := min(n, s.NumFrames()-start); got != want || (err != nil) != (want < n) {
+		t.Fatalf("ReadAt(%d, %d frames) = %d, %v, want %d frames", start, n, got, err, want)
+	}
+	for ch := range dst {
+		dst[ch] = dst[ch][:got]
+	}
+	return dst
+}
+
+func TestDecodeSeeker_MatchesFullDecode(t *testing.T) {
+	t.Parallel()
+
+	const numSamples = 40*512 + 211
+	src, stereo := seekerInput(t, numSamples)
+	newDecoder := func() *decoder.SQDecoder {
+		d := decoder.NewSQDecoder()
+		d.SetTailHandling(decoder.TailMirror)
+		return d
+	}
+	want, err := newDecoder().Process(stereo)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	config := decoder.DefaultSeekerConfig()
+	config.PageBlocks, config.CachePages = 4, 2
+	s, err := decoder.NewDecodeSeeker(src, src.NumFrames(), newDecoder, config)
+	if err != nil {
+		t.Fatalf("NewDecodeSeeker() error = %v", err)
+	}
+
+	// Random ranges, including ones crossing pages and reaching past the
+	// end, decode exactly like the whole file.
+	rng := rand.New(rand.NewSource(1))
+	for range 50 {
+		start := rng.Intn(numSamples)
+		got := readRange(t, s, start, 1+rng.Intn(5000))
+		for ch := range got {
+			for i, v := range got[ch] {
+				if w := want[ch][start+i]; v != w {
+					t.Fatalf("ch %d frame %d: got %v, want %v", ch, start+i, v, w)
+				}
+			}
+		}
+	}
+
+	if _, err := s.ReadAt(make([][]float64, 4), numSamples); !errors.Is(err, io.EOF) {
+		t.Fatalf("ReadAt(end) error = %v, want io.EOF", err)
+	}
+	if _, err := s.ReadAt(make([][]float64, 2), 0); err == nil {
+		t.Fatalf("ReadAt(2 channels) expected error")
+	}
+}
+
+func TestDecodeSeeker_LogicSteering(t *testing.T) {
+	t.Parallel()
+
+	const numSamples = 6 * 44100
+	src, stereo := seekerInput(t, numSamples)
+	newDecoder := func() *decoder.SQDecoder {
+		d := decoder.NewSQDecoder()
+		d.EnableLogicSteering(true)
+		return d
+	}
+	want, err := newDecoder().Process(stereo)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// A single cached page forces every read to decode again.
+	config := decoder.DefaultSeekerConfig()
+	config.CachePages = 1
+	s, err := decoder.NewDecodeSeeker(src, numSamples, newDecoder, config)
+	if err != nil {
+		t.Fatalf("NewDecodeSeeker() error = %v", err)
+	}
+
+	// Overlapping reads return identical samples however they are reached.
+	const start, n = 5*44100 + 1000, 20000
+	first := readRange(t, s, start, n)
+	readRange(t, s, 0, 100)
+	second := readRange(t, s, start+n/2, n)
+	for ch := range first {
+		for i := n / 2; i < n; i++ {
+			if first[ch][i] != second[ch][i-n/2] {
+				t.Fatalf("ch %d frame %d: %v, then %v", ch, start+i, first[ch][i], second[ch][i-n/2])
+			}
+		}
+	}
+
+	// The preroll settles the steering envelopes close to their state in a
+	// decode of the whole file.
+	var maxDiff float64
+	for ch := range first {
+		for i, v := range first[ch] {
+			maxDiff = math.Max(maxDiff, math.Abs(v-want[ch][start+i]))
+		}
+	}
+	if maxDiff > 1e-3 {
+		t.Fatalf("max difference to the full decode = %g, want <= 1e-3", maxDiff)
+	}
+}
+
+func TestNewDecodeSeeker_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	src, _ := seekerInput(t, 1000)
+	for _, config := range []decoder.SeekerConfig{
+		{PageBlocks: 0, Preroll: 1, CachePages: 1},
+		{PageBlocks: 1, Preroll: -1, CachePages: 1},
+		{PageBlocks: 1, Preroll: 1, CachePages: 0},
+	} {
+		if _, err := decoder.NewDecodeSeeker(src, 1000, decoder.NewSQDecoder, config); err == nil {
+			t.Fatalf("NewDecodeSeeker(%+v) expected error", config)
+		}
+	}
+}
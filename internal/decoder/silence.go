@@ -0,0 +1,80 @@
+package decoder
+
+import "math"
+
+// SilenceSkipConfig controls skipping the FFT path on silent input.
+type SilenceSkipConfig struct {
+	Enabled bool
+	// ThresholdDB is the level in dBFS at or below which a sample counts as
+	// silent.
+	ThresholdDB float64
+	// MinDuration is how long, in seconds, the input has to be silent
+	// before blocks are skipped, so that short pauses in the program are
+	// decoded normally.
+	MinDuration float64
+}
+
+// DefaultSilenceSkipConfig returns the silence skip defaults (disabled).
+func DefaultSilenceSkipConfig() SilenceSkipConfig {
+	return SilenceSkipConfig{
+		Enabled:     false,
+		ThresholdDB: -120,
+		MinDuration: 1.0,
+	}
+}
+
+// SilenceStats counts the blocks processed and skipped as silent since the
+// decoder was created.
+type SilenceStats struct {
+	Blocks        int
+	SkippedBlocks int
+}
+
+// SetSilenceSkip configures the silence detector. A block is skipped when
+// every input sample its FFT would read is at or below the threshold and
+// the input has been silent for at least MinDuration. Skipped blocks output
+// exact zeros; the logic steering envelopes decay exactly as if the zeros
+// had been processed. Blocks are otherwise independent, so the first block
+// after a gap is decoded as usual. Below -100 dBFS the output differs from
+// the full path by less than the silent input itself.
+func (d *SQDecoder) SetSilenceSkip(config SilenceSkipConfig) {
+	d.silenceConfig = config
+	d.updateSilenceThreshold()
+}
+
+// SilenceStats returns the silence skip counters.
+func (d *SQDecoder) SilenceStats() SilenceStats {
+	return d.silenceStats
+}
+
+func (d *SQDecoder) updateSilenceThreshold() {
+	d.silenceLevel = -1
+	d.silenceMinBlocks = 0
+	if !d.silenceConfig.Enabled {
+		return
+	}
+	d.silenceLevel = math.Pow(10, d.silenceConfig.ThresholdDB/20)
+	minFrames := d.silenceConfig.MinDuration * float64(d.sampleRate)
+	d.silenceMinBlocks = max(int(math.Ceil(minFrames/float64(d.overlap))), 1)
+}
+
+// skipBlock updates the silent run with the block's input window and
+// reports whether the block is skipped.
+func (d *SQDecoder) skipBlock(blockL, blockR []float64) bool {
+	d.silenceStats.Blocks++
+	if d.silenceLevel < 0 {
+		return false
+	}
+	for i := range blockL {
+		if math.Abs(blockL[i]) > d.silenceLevel || math.Abs(blockR[i]) > d.silenceLevel {
+			d.silentBlocks = 0
+			return false
+		}
+	}
+	d.silentBlocks++
+	if d.silentBlocks < d.silenceMinBlocks {
+		return false
+	}
+	d.silenceStats.SkippedBlocks++
+	return true
+}
@@ -0,0 +1,100 @@
+package decoder_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+)
+
+// gapInput returns SQ-encoded stereo of a front-left tone that stops for a
+// 3 s gap holding only a -130 dBFS noise floor.
+func gapInput(t *testing.T, sampleRate int) [][]float64 {
+	t.Helper()
+
+	n := 5 * sampleRate
+	gapStart, gapEnd := sampleRate+123, 4*sampleRate+77
+	rng := rand.New(rand.NewSource(1))
+	floor := math.Pow(10, -130.0/20)
+	lf := make([]float64, n)
+	for i := range lf {
+		if i >= gapStart && i < gapEnd {
+			lf[i] = floor * (2*rng.Float64() - 1)
+			continue
+		}
+		lf[i] = 0.5 * math.Sin(2.0*math.Pi*440.0*float64(i)/float64(sampleRate))
+	}
+	silent := make([]float64, n)
+	encoded, err := encoder.NewSQEncoder().Process([][]float64{lf, silent, silent, silent})
+	if err != nil {
+		t.Fatalf("encoder Process() error = %v", err)
+	}
+	return encoded
+}
+
+func newSilenceDecoder(skip bool) *decoder.SQDecoder {
+	d := decoder.NewSQDecoder()
+	d.SetSampleRate(48000)
+	d.EnableLogicSteering(true)
+	d.SetSilenceSkip(decoder.SilenceSkipConfig{Enabled: skip, ThresholdDB: -120, MinDuration: 0.5})
+	return d
+}
+
+func TestSQDecoder_SilenceSkip_MatchesFullPath(t *testing.T) {
+	t.Parallel()
+
+	input := gapInput(t, 48000)
+	want, err := newSilenceDecoder(false).Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	skipping := newSilenceDecoder(true)
+	got, err := skipping.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	stats := skipping.SilenceStats()
+	// The 3 s gap minus the 0.5 s detection time and the block windows
+	// reaching into the tone.
+	if stats.SkippedBlocks < 230 || stats.Blocks != (len(input[0])+511)/512 {
+		t.Fatalf("SilenceStats() = %+v, want ~240 of %d blocks skipped", stats, (len(input[0])+511)/512)
+	}
+
+	maxDiff := 0.0
+	zeros := 0
+	for ch := range want {
+		for i := range want[ch] {
+			maxDiff = max(maxDiff, math.Abs(got[ch][i]-want[ch][i]))
+			if got[ch][i] == 0 {
+				zeros++
+			}
+		}
+	}
+	if db := 20 * math.Log10(maxDiff); db > -100 {
+		t.Fatalf("max deviation from the full path = %.1f dBFS, want < -100", db)
+	}
+	if zeros < 4*stats.SkippedBlocks*512 {
+		t.Fatalf("%d exact zeros, want at least %d for the skipped blocks", zeros, 4*stats.SkippedBlocks*512)
+	}
+}
+
+func TestSQDecoder_SilenceSkip_SegmentsMatchProcess(t *testing.T) {
+	t.Parallel()
+
+	input := gapInput(t, 48000)
+	want, err := newSilenceDecoder(true).Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	got := decodeSegments(t, newSilenceDecoder(true), input, 0)
+	for ch := range want {
+		for i := range want[ch] {
+			if got[ch][i] != want[ch][i] {
+				t.Fatalf("channel %d sample %d = %v, want %v", ch, i, got[ch][i], want[ch][i])
+			}
+		}
+	}
+}
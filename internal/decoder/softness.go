@@ -0,0 +1,26 @@
+package decoder
+
+import "math"
+
+// SetBackChannelSoftness (experimental) passes LB and RB through a soft
+// knee right after the decode matrix, which rounds off the peaks the
+// sqrt(2)/2 terms produce on transients. Levels up to the knee at
+// 1-amount of full scale pass unchanged; above it the level approaches
+// full scale along a tanh curve that joins the linear part without a kink.
+// amount is clamped to [0, 1]; 0 (the default) bypasses the knee and
+// reproduces the linear matrix exactly. The fronts are not affected, and
+// unlike a limiter the knee has no memory and adds no latency.
+func (d *SQDecoder) SetBackChannelSoftness(amount float64) {
+	d.backSoftness = min(max(amount, 0), 1)
+}
+
+// softKnee applies the knee of SetBackChannelSoftness for amount to v.
+func softKnee(v, amount float64) float64 {
+	knee := 1 - amount
+	level := math.Abs(v)
+	if level <= knee {
+		return v
+	}
+	soft := knee + amount*math.Tanh((level-knee)/amount)
+	return math.Copysign(soft, v)
+}
@@ -0,0 +1,75 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+func TestSQDecoder_BackChannelSoftness(t *testing.T) {
+	t.Parallel()
+
+	const n = 16384
+	// Opposite-phase bursts in LT and RT decode mostly to the backs, with
+	// peaks of about 0.64 in LB and RB.
+	input := [][]float64{make([]float64, n), make([]float64, n)}
+	for i := range n {
+		v := 0.9 * math.Sin(2*math.Pi*440*float64(i)/44100)
+		if (i/2048)%2 == 1 {
+			v *= 0.2
+		}
+		input[0][i] = v
+		input[1][i] = -v
+	}
+	decode := func(set bool, amount float64) [][]float64 {
+		d := decoder.NewSQDecoder()
+		if set {
+			d.SetBackChannelSoftness(amount)
+		}
+		out, err := d.Process(input)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		return out
+	}
+	peak := func(samples []float64) float64 {
+		p := 0.0
+		for _, v := range samples {
+			p = max(p, math.Abs(v))
+		}
+		return p
+	}
+
+	plain := decode(false, 0)
+	linear := decode(true, 0)
+	for ch := range plain {
+		for i := range plain[ch] {
+			if linear[ch][i] != plain[ch][i] {
+				t.Fatalf("amount 0: channel %d sample %d = %g, want %g", ch, i, linear[ch][i], plain[ch][i])
+			}
+		}
+	}
+
+	prev := peak(plain[2])
+	for _, amount := range []float64{0.5, 1} {
+		soft := decode(true, amount)
+		for ch := 2; ch < 4; ch++ {
+			if got := peak(soft[ch]); got >= peak(plain[ch]) {
+				t.Fatalf("amount %g: channel %d peak %g, want below %g", amount, ch, got, peak(plain[ch]))
+			}
+		}
+		if got := peak(soft[2]); got >= prev {
+			t.Fatalf("amount %g: LB peak %g, want below %g of the smaller amount", amount, got, prev)
+		}
+		prev = peak(soft[2])
+		// The fronts pass unchanged.
+		for ch := range 2 {
+			for i := range soft[ch] {
+				if soft[ch][i] != plain[ch][i] {
+					t.Fatalf("amount %g: channel %d sample %d = %g, want %g", amount, ch, i, soft[ch][i], plain[ch][i])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// stateMagic and stateVersion identify a serialized decoder state.
+const (
+	stateMagic   = "SQDS"
+	stateVersion = 6
+	// stateSize is the size without the crossfeed and rear low-pass delay
+	// lines and the Hilbert overlap-add accumulator.
+	stateSize = 4 + 1 + 4 + 4 + 4 + 1 + 8 + 8 + 8 + 2*8*8 + 8 + 8 + 8 + 8 + 4 + 8 + 4*8
+)
+
+// Snapshot serializes the streaming state that ProcessSegment carries from
+// one call to the next: the output position, the block index, the bass
+// management filter memory, the length of the current silent run, the
+// crossfeed and rear low-pass delay lines, the overlap-add accumulator of
+// the Hilbert path and the logic steering envelopes. Blocks read their
+// whole input window from the current segment, so there is no input state
+// to save.
+//
+// To resume after a snapshot, create a decoder with the same settings, call
+// Restore and continue ProcessSegment with input starting at Position. The
+// output then matches an uninterrupted run exactly.
+func (d *SQDecoder) Snapshot() []byte {
+	delay := len(d.crossfeed.history[0])
+	buf := make([]byte, 0, stateSize+2*8*delay+d.rearLowpassStateSize()+d.hilbertCarryStateSize())
+	buf = append(buf, stateMagic...)
+	buf = append(buf, stateVersion)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(d.blockSize))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(d.overlap))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(d.sampleRate))
+	logic := byte(0)
+	if d.logicConfig.Enabled {
+		logic = 1
+	}
+	buf = append(buf, logic)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(d.segmentFrames))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(d.segmentBlock))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(d.bassCrossover))
+	for side := range d.bassSplit {
+		for _, v := range d.bassSplit[side].State() {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(d.silenceLevel))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(d.silenceMinBlocks))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(d.silentBlocks))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(d.crossfeed.amount))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(delay))
+	for side := range d.crossfeed.history {
+		for _, v := range d.crossfeed.history[side] {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(d.rearLowpass.cutoff))
+	for _, history := range d.rearLowpass.history {
+		for _, v := range history {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+	}
+	for _, carry := range d.hilbertCarry {
+		for _, v := range carry {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+	}
+	for _, env := range d.logicEnv {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(env))
+	}
+	return buf
+}
+
+// Restore loads a state produced by Snapshot. It fails if the snapshot was
+// taken with a different block size, overlap, sample rate, bass crossover,
+// silence skip, crossfeed, rear low-pass or logic steering setting, since
+// continuing would not reproduce the original run.
+func (d *SQDecoder) Restore(state []byte) error {
+	delay := len(d.crossfeed.history[0])
+	if len(state) != stateSize+2*8*delay+d.rearLowpassStateSize()+d.hilbertCarryStateSize() || string(state[:4]) != stateMagic {
+		return fmt.Errorf("not a decoder state")
+	}
+	if state[4] != stateVersion {
+		return fmt.Errorf("unsupported decoder state version %d", state[4])
+	}
+	if err := ValidateParams(d.blockSize, d.overlap); err != nil {
+		return fmt.Errorf("decoder not initialized: %w", err)
+	}
+	p := state[5:]
+	blockSize := int(binary.LittleEndian.Uint32(p[0:]))
+	overlap := int(binary.LittleEndian.Uint32(p[4:]))
+	sampleRate := int(binary.LittleEndian.Uint32(p[8:]))
+	logic := p[12] == 1
+	if blockSize != d.blockSize || overlap != d.overlap {
+		return fmt.Errorf("state is for block size %d, overlap %d; decoder uses %d, %d", blockSize, overlap, d.blockSize, d.overlap)
+	}
+	if sampleRate != d.sampleRate || logic != d.logicConfig.Enabled {
+		return fmt.Errorf("state is for sample rate %d, logic %t; decoder uses %d, %t", sampleRate, logic, d.sampleRate, d.logicConfig.Enabled)
+	}
+
+	p = p[13:]
+	frames := binary.LittleEndian.Uint64(p[0:])
+	block := binary.LittleEndian.Uint64(p[8:])
+	if frames > math.MaxInt32*uint64(overlap) || block > frames/uint64(overlap)+1 {
+		return fmt.Errorf("invalid decoder state position %d (block %d)", frames, block)
+	}
+	p = p[16:]
+	if crossover := math.Float64frombits(binary.LittleEndian.Uint64(p)); crossover != d.bassCrossover {
+		return fmt.Errorf("state is for bass crossover %g Hz; decoder uses %g Hz", crossover, d.bassCrossover)
+	}
+	p = p[8:]
+	var bass [2][8]float64
+	for side := range bass {
+		for i := range bass[side] {
+			bass[side][i] = math.Float64frombits(binary.LittleEndian.Uint64(p))
+			p = p[8:]
+		}
+	}
+	level := math.Float64frombits(binary.LittleEndian.Uint64(p))
+	minBlocks := binary.LittleEndian.Uint64(p[8:])
+	if level != d.silenceLevel || minBlocks != uint64(d.silenceMinBlocks) {
+		return fmt.Errorf("state is for a different silence skip setting")
+	}
+	silentBlocks := binary.LittleEndian.Uint64(p[16:])
+	if silentBlocks > block {
+		return fmt.Errorf("invalid silent run of %d blocks at block %d", silentBlocks, block)
+	}
+	p = p[24:]
+	amount := math.Float64frombits(binary.LittleEndian.Uint64(p))
+	if amount != d.crossfeed.amount || int(binary.LittleEndian.Uint32(p[8:])) != delay {
+		return fmt.Errorf("state is for a different crossfeed setting")
+	}
+	p = p[12:]
+	var history [2][]float64
+	for side := range history {
+		history[side] = make([]float64, delay)
+		for i := range history[side] {
+			history[side][i] = math.Float64frombits(binary.LittleEndian.Uint64(p))
+			p = p[8:]
+		}
+	}
+	if cutoff := math.Float64frombits(binary.LittleEndian.Uint64(p)); cutoff != d.rearLowpass.cutoff {
+		return fmt.Errorf("state is for rear low-pass %g Hz; decoder uses %g Hz", cutoff, d.rearLowpass.cutoff)
+	}
+	p = p[8:]
+	var rear [4][]float64
+	for ch := range rear {
+		rear[ch] = make([]float64, len(d.rearLowpass.history[ch]))
+		for i := range rear[ch] {
+			rear[ch][i] = math.Float64frombits(binary.LittleEndian.Uint64(p))
+			p = p[8:]
+		}
+	}
+	var carry [2][]float64
+	for ch := range carry {
+		carry[ch] = make([]float64, len(d.hilbertCarry[ch]))
+		for i := range carry[ch] {
+			carry[ch][i] = math.Float64frombits(binary.LittleEndian.Uint64(p))
+			p = p[8:]
+		}
+	}
+	var env [4]float64
+	for i := range env {
+		env[i] = math.Float64frombits(binary.LittleEndian.Uint64(p[8*i:]))
+		if math.IsNaN(env[i]) || env[i] < 0 {
+			return fmt.Errorf("invalid logic envelope %v", env[i])
+		}
+	}
+
+	d.segmentFrames = int(frames)
+	d.segmentBlock = int(block)
+	d.logicEnv = env
+	d.silentBlocks = int(silentBlocks)
+	for side := range bass {
+		d.bassSplit[side].SetState(bass[side])
+	}
+	d.crossfeed.history = history
+	d.rearLowpass.history = rear
+	d.hilbertCarry = carry
+	return nil
+}
+
+// rearLowpassStateSize returns the size of the rear low-pass delay lines in
+// a snapshot.
+func (d *SQDecoder) rearLowpassStateSize() int {
+	n := 0
+	for _, history := range d.rearLowpass.history {
+		n += 8 * len(history)
+	}
+	return n
+}
+
+// Reset returns the decoder to the state of a new one while keeping its
+// configuration, so that one instance can decode many signals: after Reset
+// the output is bit-identical to that of a newly constructed decoder set up
+// alike. It clears the position and block index, the logic steering
+// envelopes, the bass management, crossfeed, rear low-pass and Hilbert
+// overlap-add memory, the silent run, the silence and steering statistics
+// and a running ProcessChunk or ProcessStream stream. Hooks and a logic
+// plan stay installed.
+func (d *SQDecoder) Reset() {
+	for side := range d.bassSplit {
+		d.bassSplit[side].Reset()
+	}
+	d.resetCrossfeed()
+	d.resetRearLowpass()
+	d.resetHilbertCarry()
+	d.logicEnv = [4]float64{}
+	d.silentBlocks = 0
+	d.silenceStats = SilenceStats{}
+	d.steeredSamples = 0
+	d.segmentBlock = 0
+	d.segmentFrames = 0
+	d.inputBufferL = d.inputBufferL[:0]
+	d.inputBufferR = d.inputBufferR[:0]
+	for ch := range d.outputBuffers {
+		clear(d.outputBuffers[ch])
+	}
+	d.chunkReady = nil
+}
+
+// hilbertCarryStateSize returns the size of the Hilbert overlap-add
+// accumulator in a snapshot.
+func (d *SQDecoder) hilbertCarryStateSize() int {
+	return 2 * 8 * len(d.hilbertCarry[0])
+}
+
+// Position returns the number of output frames produced by ProcessSegment
+// so far, which is also where the next segment's input starts.
+func (d *SQDecoder) Position() int {
+	return d.segmentFrames
+}
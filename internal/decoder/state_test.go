@@ -0,0 +1,223 @@
+package decoder_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+const (
+	stateBlockSize = 1024
+	stateOverlap   = 512
+	stateSegment   = 4 * stateOverlap
+)
+
+func newStateDecoder() *decoder.SQDecoder {
+	d := decoder.NewSQDecoderWithParams(stateBlockSize, stateOverlap)
+	d.SetSampleRate(48000)
+	d.EnableLogicSteering(true)
+	d.SetBassManagement(120)
+	d.SetCrossfeed(0.3, 0.3)
+	d.SetRearLowpass(10000)
+	return d
+}
+
+// decodeSegments feeds input to d segment by segment, starting at d's
+// position, and returns the output. It stops early after maxSegments
+// segments when maxSegments > 0, as if the process had been killed.
+func decodeSegments(t *testing.T, d *decoder.SQDecoder, input [][]float64, maxSegments int) [][]float64 {
+	t.Helper()
+
+	n := len(input[0])
+	out := make([][]float64, 4)
+	for segments := 0; d.Position() < n; segments++ {
+		if maxSegments > 0 && segments == maxSegments {
+			break
+		}
+		start := d.Position()
+		numOutput := min(stateSegment, n-start)
+		end := min(start+numOutput+stateBlockSize-stateOverlap, n)
+		got, err := d.ProcessSegment([][]float64{input[0][start:end], input[1][start:end]}, numOutput)
+		if err != nil {
+			t.Fatalf("ProcessSegment() error = %v", err)
+		}
+		for ch := range out {
+			out[ch] = append(out[ch], got[ch]...)
+		}
+	}
+	return out
+}
+
+// slotInput encodes a sweep moving through the four channels in turn, so
+// the logic envelopes are still releasing at every checkpoint.
+func slotInput(t *testing.T, n int) [][]float64 {
+	t.Helper()
+
+	quad := testsignal.QuadSweepSlots(48000, (n+3)/4, 100, 4000, 0.5)
+	for ch := range quad {
+		quad[ch] = quad[ch][:n]
+	}
+	encoded, err := encoder.NewSQEncoderWithParams(stateBlockSize, stateOverlap).Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	return encoded
+}
+
+func TestSQDecoder_SnapshotResumeMatchesUninterrupted(t *testing.T) {
+	t.Parallel()
+
+	input := slotInput(t, 20*stateSegment+321)
+	want := decodeSegments(t, newStateDecoder(), input, 0)
+
+	// Run until the checkpoint, snapshot, and abandon the decoder.
+	killed := newStateDecoder()
+	head := decodeSegments(t, killed, input, 7)
+	state := killed.Snapshot()
+	position := killed.Position()
+	if position != 7*stateSegment {
+		t.Fatalf("Position() = %d, want %d", position, 7*stateSegment)
+	}
+
+	resumed := newStateDecoder()
+	if err := resumed.Restore(state); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if resumed.Position() != position {
+		t.Fatalf("restored Position() = %d, want %d", resumed.Position(), position)
+	}
+	tail := decodeSegments(t, resumed, input, 0)
+
+	for ch := range want {
+		got := append(append([]float64(nil), head[ch]...), tail[ch]...)
+		if len(got) != len(want[ch]) {
+			t.Fatalf("channel %d: %d samples, want %d", ch, len(got), len(want[ch]))
+		}
+		for i := position; i < len(got); i++ {
+			if got[i] != want[ch][i] {
+				t.Fatalf("channel %d sample %d = %v, want %v", ch, i, got[i], want[ch][i])
+			}
+		}
+	}
+
+	// The envelopes are part of the state: resuming with them cleared
+	// changes the logic-steered output.
+	cleared := append([]byte(nil), state...)
+	clear(cleared[len(cleared)-32:])
+	reset := newStateDecoder()
+	if err := reset.Restore(cleared); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	other := decodeSegments(t, reset, input, 1)
+	differs := false
+	for ch := range other {
+		for i, v := range other[ch] {
+			differs = differs || v != want[ch][position+i]
+		}
+	}
+	if !differs {
+		t.Fatalf("resuming without envelopes reproduced the output; envelopes not captured")
+	}
+}
+
+func TestSQDecoder_ResetMatchesNewDecoder(t *testing.T) {
+	t.Parallel()
+
+	input := slotInput(t, 6*stateSegment+123)
+	want := decodeSegments(t, newStateDecoder(), input, 0)
+	wantWhole, err := newStateDecoder().Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	d := newStateDecoder()
+	first := decodeSegments(t, d, input, 0)
+	// Leave a chunked stream running as well.
+	if _, err := d.ProcessChunk([][]float64{input[0][:1000], input[1][:1000]}); err != nil {
+		t.Fatalf("ProcessChunk() error = %v", err)
+	}
+	d.Reset()
+	second := decodeSegments(t, d, input, 0)
+	d.Reset()
+	whole, err := d.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	for ch := range want {
+		for i := range want[ch] {
+			if first[ch][i] != want[ch][i] || second[ch][i] != want[ch][i] || whole[ch][i] != wantWhole[ch][i] {
+				t.Fatalf("channel %d sample %d = %v, %v, %v after Reset, want %v, %v",
+					ch, i, first[ch][i], second[ch][i], whole[ch][i], want[ch][i], wantWhole[ch][i])
+			}
+		}
+	}
+
+	// Without Reset, the logic envelopes carry over into the next run.
+	again, err := d.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	differs := false
+	for ch := range again {
+		for i, v := range again[ch] {
+			differs = differs || v != wantWhole[ch][i]
+		}
+	}
+	if !differs {
+		t.Fatalf("a second Process without Reset reproduced the output; test does not exercise Reset")
+	}
+}
+
+func TestSQDecoder_RestoreRejectsMismatchedState(t *testing.T) {
+	t.Parallel()
+
+	state := newStateDecoder().Snapshot()
+
+	tests := []struct {
+		name  string
+		setup func() *decoder.SQDecoder
+		state []byte
+	}{
+		{"block size", func() *decoder.SQDecoder { return decoder.NewSQDecoderWithParams(2048, stateOverlap) }, state},
+		{"sample rate", func() *decoder.SQDecoder {
+			d := newStateDecoder()
+			d.SetSampleRate(44100)
+			return d
+		}, state},
+		{"bass crossover", func() *decoder.SQDecoder {
+			d := newStateDecoder()
+			d.SetBassManagement(80)
+			return d
+		}, state},
+		{"crossfeed", func() *decoder.SQDecoder {
+			d := newStateDecoder()
+			d.SetCrossfeed(0.3, 0.5)
+			return d
+		}, state},
+		{"rear low-pass", func() *decoder.SQDecoder {
+			d := newStateDecoder()
+			d.SetRearLowpass(8000)
+			return d
+		}, state},
+		{"silence skip", func() *decoder.SQDecoder {
+			d := newStateDecoder()
+			d.SetSilenceSkip(decoder.SilenceSkipConfig{Enabled: true, ThresholdDB: -120, MinDuration: 1})
+			return d
+		}, state},
+		{"logic", func() *decoder.SQDecoder {
+			d := newStateDecoder()
+			d.EnableLogicSteering(false)
+			return d
+		}, state},
+		{"truncated", newStateDecoder, state[:len(state)-1]},
+		{"encoder state", newStateDecoder, append([]byte("SQES"), state[4:]...)},
+	}
+	for _, tt := range tests {
+		if err := tt.setup().Restore(tt.state); err == nil {
+			t.Fatalf("%s: Restore() error = nil, want error", tt.name)
+		}
+	}
+}
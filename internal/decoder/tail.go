@@ -0,0 +1,89 @@
+package decoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TailHandling selects how the decoder pads the blocks that reach past the
+// end of the signal. Mirror and Hold extrapolate the signal and reduce the
+// edge error for content that changes slowly over the phase shifter
+// length, such as bass or a fade-out; for busy material, which no padding
+// predicts, zero padding keeps the error lowest.
+type TailHandling int
+
+const (
+	// TailZeroPad pads with zeros (the default). The signal stops abruptly,
+	// and the phase-shifted path rings with the step before the end.
+	TailZeroPad TailHandling = iota
+	// TailMirror pads with the signal point-reflected about its last sample,
+	// which continues the waveform with the same level and slope.
+	TailMirror
+	// TailHold repeats the last sample.
+	TailHold
+)
+
+// ParseTailHandling converts a CLI name ("zero", "mirror", "hold") into a
+// TailHandling.
+func ParseTailHandling(name string) (TailHandling, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "zero", "zeropad":
+		return TailZeroPad, nil
+	case "mirror":
+		return TailMirror, nil
+	case "hold":
+		return TailHold, nil
+	default:
+		return TailZeroPad, fmt.Errorf("unknown tail handling %q (want zero, mirror or hold)", name)
+	}
+}
+
+// String returns the CLI name of the mode.
+func (t TailHandling) String() string {
+	switch t {
+	case TailZeroPad:
+		return "zero"
+	case TailMirror:
+		return "mirror"
+	case TailHold:
+		return "hold"
+	default:
+		return fmt.Sprintf("TailHandling(%d)", int(t))
+	}
+}
+
+// SetTailHandling selects how the last blocks are padded past the end of
+// the input. The output length always equals the input length; the padding
+// only affects the output samples whose phase shifter taps reach past the
+// end. In
+// segmented processing only the final segment, the one without lookahead,
+// is padded.
+func (d *SQDecoder) SetTailHandling(mode TailHandling) {
+	d.tail = mode
+}
+
+// TailHandling returns the configured tail handling.
+func (d *SQDecoder) TailHandling() TailHandling {
+	return d.tail
+}
+
+// padSample returns the sample at index i >= len(x) of x extended past its
+// end according to mode.
+func padSample(x []float64, i int, mode TailHandling) float64 {
+	n := len(x)
+	if n == 0 {
+		return 0
+	}
+	switch mode {
+	case TailHold:
+		return x[n-1]
+	case TailMirror:
+		// Point reflection about the last sample, which continues both the
+		// level and the slope. Past the length of x, the samples of the
+		// block, the padding holds the value reflected from x[0].
+		k := min(i-(n-1), n-1)
+		return 2*x[n-1] - x[n-1-k]
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,90 @@
+package decoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+)
+
+// tone returns SQ stereo of a 40 Hz tone, LT and RT at different phases so
+// that both the direct and the phase-shifted paths carry it. Padding can
+// only extrapolate content that changes slowly over the phase shifter
+// length, such as bass.
+func tone(numSamples int) [][]float64 {
+	stereo := [][]float64{make([]float64, numSamples), make([]float64, numSamples)}
+	for i := range stereo[0] {
+		phase := 2 * math.Pi * 40 * float64(i) / 44100
+		stereo[0][i] = 0.5 * math.Sin(phase)
+		stereo[1][i] = 0.3 * math.Sin(phase+1)
+	}
+	return stereo
+}
+
+func TestSQDecoder_TailHandling(t *testing.T) {
+	t.Parallel()
+
+	const numSamples = 20*512 + 301
+	// The reference is the decode of the tone going on past the end.
+	long := decodeWith(t, decoder.TailZeroPad, tone(numSamples+4096))
+
+	tailError := func(mode decoder.TailHandling) float64 {
+		got := decodeWith(t, mode, tone(numSamples))
+		if len(got[0]) != numSamples {
+			t.Fatalf("%v: %d frames, want %d", mode, len(got[0]), numSamples)
+		}
+		var sum float64
+		for ch := range got {
+			for i := numSamples - 1024; i < numSamples; i++ {
+				d := got[ch][i] - long[ch][i]
+				sum += d * d
+			}
+		}
+		return math.Sqrt(sum / (4 * 1024))
+	}
+
+	zero, mirror, hold := tailError(decoder.TailZeroPad), tailError(decoder.TailMirror), tailError(decoder.TailHold)
+	t.Logf("tail RMS error: zero %.4g, mirror %.4g, hold %.4g", zero, mirror, hold)
+	if mirror >= zero/4 {
+		t.Fatalf("mirror error %.4g not below a quarter of the zero-pad error %.4g", mirror, zero)
+	}
+	if hold >= zero/2 {
+		t.Fatalf("hold error %.4g not below half the zero-pad error %.4g", hold, zero)
+	}
+
+	// Away from the end the modes decode identically.
+	head := decodeWith(t, decoder.TailMirror, tone(numSamples))
+	for ch := range head {
+		for i := range numSamples - 2048 {
+			if head[ch][i] != long[ch][i] {
+				t.Fatalf("ch %d sample %d: %v, want %v", ch, i, head[ch][i], long[ch][i])
+			}
+		}
+	}
+}
+
+func decodeWith(t *testing.T, mode decoder.TailHandling, input [][]float64) [][]float64 {
+	t.Helper()
+
+	d := decoder.NewSQDecoder()
+	d.SetTailHandling(mode)
+	out, err := d.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	return out
+}
+
+func TestParseTailHandling(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []decoder.TailHandling{decoder.TailZeroPad, decoder.TailMirror, decoder.TailHold} {
+		got, err := decoder.ParseTailHandling(mode.String())
+		if err != nil || got != mode {
+			t.Fatalf("ParseTailHandling(%q) = %v, %v", mode.String(), got, err)
+		}
+	}
+	if _, err := decoder.ParseTailHandling("wrap"); err == nil {
+		t.Fatalf("ParseTailHandling(wrap) expected error")
+	}
+}
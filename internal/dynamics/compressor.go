@@ -0,0 +1,299 @@
+// Package dynamics provides level processing for decoded multichannel audio.
+package dynamics
+
+import (
+	"fmt"
+	"math"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+)
+
+// DefaultCompressorBlockSize is the default FFT size of the compressor.
+const DefaultCompressorBlockSize = 2048
+
+// numBands is the number of compressor bands.
+const numBands = 3
+
+const levelEpsilon = 1e-20
+
+// CompressorConfig controls the multiband compressor. The same settings
+// apply to all three bands.
+type CompressorConfig struct {
+	// Crossovers are the low/mid and mid/high band edges in Hz. They must
+	// be at least an octave apart.
+	Crossovers [2]float64
+	// ThresholdDB is the band RMS level in dBFS above which gain reduction
+	// starts.
+	ThresholdDB float64
+	// Ratio is the compression ratio above the threshold (>= 1).
+	Ratio float64
+	// AttackMs and ReleaseMs are the gain smoothing time constants.
+	AttackMs  float64
+	ReleaseMs float64
+	// BlockSize is the FFT size (power of 2); 0 means
+	// DefaultCompressorBlockSize.
+	BlockSize int
+}
+
+// DefaultCompressorConfig returns gentle mastering settings.
+func DefaultCompressorConfig() CompressorConfig {
+	return CompressorConfig{
+		Crossovers:  [2]float64{200, 2000},
+		ThresholdDB: -20,
+		Ratio:       3,
+		AttackMs:    10,
+		ReleaseMs:   150,
+	}
+}
+
+// Validate reports whether the configuration is usable.
+func (c CompressorConfig) Validate() error {
+	if c.Crossovers[0] <= 0 || c.Crossovers[1] < 2*c.Crossovers[0] {
+		return fmt.Errorf("crossovers must be positive and at least an octave apart, got %v", c.Crossovers)
+	}
+	if c.Ratio < 1 {
+		return fmt.Errorf("ratio must be >= 1, got %g", c.Ratio)
+	}
+	if c.AttackMs < 0 || c.ReleaseMs < 0 {
+		return fmt.Errorf("attack and release must be >= 0, got %g/%g ms", c.AttackMs, c.ReleaseMs)
+	}
+	if c.BlockSize != 0 && (c.BlockSize < 64 || c.BlockSize&(c.BlockSize-1) != 0) {
+		return fmt.Errorf("block size must be a power of 2 >= 64, got %d", c.BlockSize)
+	}
+	return nil
+}
+
+// Compressor is a three-band compressor for multichannel audio. Bands are
+// split in the frequency domain of a 50%-overlapped FFT with complementary
+// zero-phase crossovers, so with unity gain the output reproduces the input
+// exactly. The band levels are detected jointly over all channels (the
+// loudest channel wins) and every channel receives the same per-band gain,
+// which preserves inter-channel phase and level relationships and
+// therefore the quad image.
+type Compressor struct {
+	config     CompressorConfig
+	blockSize  int
+	hop        int
+	plan       *algofft.Plan[complex128]
+	window     []float64
+	levelNorm  float64
+	bandWeight [numBands][]float64
+	attack     float64
+	release    float64
+
+	channels int
+	gainDB   [numBands]float64
+	tail     [][]float64
+	started  bool
+}
+
+// NewCompressor creates a compressor for the given sample rate.
+func NewCompressor(config CompressorConfig, sampleRate int) (*Compressor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("sample rate must be > 0, got %d", sampleRate)
+	}
+	blockSize := config.BlockSize
+	if blockSize == 0 {
+		blockSize = DefaultCompressorBlockSize
+	}
+	plan, err := algofft.NewPlan64(blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Compressor{
+		config:    config,
+		blockSize: blockSize,
+		hop:       blockSize / 2,
+		plan:      plan,
+		window:    make([]float64, blockSize),
+	}
+
+	// Periodic Hann: overlapping windows at a hop of blockSize/2 sum to 1,
+	// so analysis windowing alone reconstructs the signal.
+	sumSquares := 0.0
+	for i := range c.window {
+		c.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(blockSize))
+		sumSquares += c.window[i] * c.window[i]
+	}
+	c.levelNorm = float64(blockSize) * sumSquares
+
+	for b := range c.bandWeight {
+		c.bandWeight[b] = make([]float64, blockSize)
+	}
+	for k := 0; k < blockSize; k++ {
+		f := float64(min(k, blockSize-k)) * float64(sampleRate) / float64(blockSize)
+		low := 1 - crossoverHigh(f, config.Crossovers[0])
+		high := crossoverHigh(f, config.Crossovers[1])
+		c.bandWeight[0][k] = low
+		c.bandWeight[1][k] = math.Max(1-low-high, 0)
+		c.bandWeight[2][k] = high
+	}
+
+	c.attack = smoothingCoeff(config.AttackMs, c.hop, sampleRate)
+	c.release = smoothingCoeff(config.ReleaseMs, c.hop, sampleRate)
+	return c, nil
+}
+
+// crossoverHigh is the high-side weight of a crossover at fc: a raised
+// cosine over one octave centered on fc in log frequency. The low side is
+// its complement, so the bands always sum to one.
+func crossoverHigh(f, fc float64) float64 {
+	if f <= 0 {
+		return 0
+	}
+	t := math.Log2(f/fc) + 0.5
+	switch {
+	case t <= 0:
+		return 0
+	case t >= 1:
+		return 1
+	default:
+		return 0.5 - 0.5*math.Cos(math.Pi*t)
+	}
+}
+
+func smoothingCoeff(ms float64, hop, sampleRate int) float64 {
+	if ms <= 0 {
+		return 0
+	}
+	return math.Exp(-float64(hop) / (ms / 1000 * float64(sampleRate)))
+}
+
+// Lookahead returns the number of input frames past the output that
+// ProcessSegment needs.
+func (c *Compressor) Lookahead() int {
+	return c.hop
+}
+
+// Reset clears the gain state so the next call starts a new signal.
+func (c *Compressor) Reset() {
+	c.gainDB = [numBands]float64{}
+	c.tail = nil
+	c.started = false
+}
+
+// Process compresses a whole signal ([channel][frame]). The output is
+// time-aligned with the input.
+func (c *Compressor) Process(input [][]float64) ([][]float64, error) {
+	c.Reset()
+	if len(input) == 0 {
+		return nil, fmt.Errorf("input must have at least 1 channel")
+	}
+	return c.ProcessSegment(input, len(input[0]))
+}
+
+// ProcessSegment compresses the first numOutput frames of input and uses
+// the remaining frames only as lookahead. Consecutive segments reproduce
+// Process on the whole signal exactly, provided numOutput is a multiple of
+// BlockSize/2 for every segment except the last and input extends
+// Lookahead frames past numOutput (or to the end of the signal).
+func (c *Compressor) ProcessSegment(input [][]float64, numOutput int) ([][]float64, error) {
+	if len(input) == 0 {
+		return nil, fmt.Errorf("input must have at least 1 channel")
+	}
+	for ch := range input {
+		if len(input[ch]) != len(input[0]) {
+			return nil, fmt.Errorf("input channels must have same length")
+		}
+	}
+	if numOutput < 0 || numOutput > len(input[0]) {
+		return nil, fmt.Errorf("numOutput %d out of range [0, %d]", numOutput, len(input[0]))
+	}
+	if c.started && len(input) != c.channels {
+		return nil, fmt.Errorf("input must have %d channels, got %d", c.channels, len(input))
+	}
+
+	if !c.started {
+		// The first frame straddles the signal start.
+		c.channels = len(input)
+		c.tail = c.frame(input, -c.hop)
+		for ch := range c.tail {
+			c.tail[ch] = c.tail[ch][c.hop:]
+		}
+		c.started = true
+	}
+
+	output := make([][]float64, len(input))
+	for ch := range output {
+		output[ch] = make([]float64, numOutput)
+	}
+	for start := 0; start < numOutput; start += c.hop {
+		frame := c.frame(input, start)
+		count := min(c.hop, numOutput-start)
+		for ch := range output {
+			for i := 0; i < count; i++ {
+				output[ch][start+i] = c.tail[ch][i] + frame[ch][i]
+			}
+			c.tail[ch] = frame[ch][c.hop:]
+		}
+	}
+	return output, nil
+}
+
+// frame windows input[start:start+blockSize] (zero outside the input),
+// applies the band gains and returns the time-domain result per channel.
+func (c *Compressor) frame(input [][]float64, start int) [][]float64 {
+	spectra := make([][]complex128, len(input))
+	var levels [numBands]float64
+	buf := make([]complex128, c.blockSize)
+	for ch := range input {
+		for i := range buf {
+			v := 0.0
+			if idx := start + i; idx >= 0 && idx < len(input[ch]) {
+				v = input[ch][idx] * c.window[i]
+			}
+			buf[i] = complex(v, 0)
+		}
+		spectrum := make([]complex128, c.blockSize)
+		if err := c.plan.Forward(spectrum, buf); err != nil {
+			panic(err)
+		}
+		spectra[ch] = spectrum
+
+		for b := range levels {
+			energy := 0.0
+			for k, x := range spectrum {
+				energy += c.bandWeight[b][k] * (real(x)*real(x) + imag(x)*imag(x))
+			}
+			levels[b] = math.Max(levels[b], energy)
+		}
+	}
+
+	var bandGain [numBands]float64
+	for b := range levels {
+		levelDB := 10 * math.Log10(levels[b]/c.levelNorm+levelEpsilon)
+		target := 0.0
+		if over := levelDB - c.config.ThresholdDB; over > 0 {
+			target = -over * (1 - 1/c.config.Ratio)
+		}
+		coeff := c.release
+		if target < c.gainDB[b] {
+			coeff = c.attack
+		}
+		c.gainDB[b] = target + (c.gainDB[b]-target)*coeff
+		bandGain[b] = math.Pow(10, c.gainDB[b]/20)
+	}
+
+	out := make([][]float64, len(input))
+	for ch, spectrum := range spectra {
+		for k := range spectrum {
+			g := 0.0
+			for b := range bandGain {
+				g += c.bandWeight[b][k] * bandGain[b]
+			}
+			spectrum[k] *= complex(g, 0)
+		}
+		if err := c.plan.Inverse(buf, spectrum); err != nil {
+			panic(err)
+		}
+		out[ch] = make([]float64, c.blockSize)
+		for i := range out[ch] {
+			out[ch][i] = real(buf[i])
+		}
+	}
+	return out
+}
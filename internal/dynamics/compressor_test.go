@@ -0,0 +1,202 @@
+package dynamics_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/dynamics"
+)
+
+const sampleRate = 44100
+
+func sine(freq, amplitude float64, numSamples int) []float64 {
+	out := make([]float64, numSamples)
+	for i := range out {
+		out[i] = amplitude * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)
+	}
+	return out
+}
+
+func add(a, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range out {
+		out[i] = a[i] + b[i]
+	}
+	return out
+}
+
+func scale(a []float64, g float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range out {
+		out[i] = a[i] * g
+	}
+	return out
+}
+
+// amplitude returns the amplitude of the freq component of signal[from:to].
+func amplitude(signal []float64, freq float64, from, to int) float64 {
+	var re, im float64
+	for i := from; i < to; i++ {
+		phase := 2 * math.Pi * freq * float64(i) / sampleRate
+		re += signal[i] * math.Cos(phase)
+		im += signal[i] * math.Sin(phase)
+	}
+	return 2 * math.Hypot(re, im) / float64(to-from)
+}
+
+func TestCompressor_LoudBandAttenuatedQuietBandPasses(t *testing.T) {
+	t.Parallel()
+
+	const numSamples = 2 * sampleRate
+	// -3 dBFS RMS at 100 Hz (low band), -43 dBFS RMS at 5 kHz (high band).
+	signal := add(sine(100, 1.0, numSamples), sine(5000, 0.01, numSamples))
+	input := [][]float64{signal, signal, signal, signal}
+
+	cfg := dynamics.DefaultCompressorConfig()
+	cfg.ThresholdDB = -20
+	cfg.Ratio = 4
+	comp, err := dynamics.NewCompressor(cfg, sampleRate)
+	if err != nil {
+		t.Fatalf("NewCompressor() error = %v", err)
+	}
+	output, err := comp.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// Measure one second in the settled middle of the signal.
+	from, to := sampleRate/2, sampleRate/2+sampleRate
+	loudDB := 20 * math.Log10(amplitude(output[0], 100, from, to)/1.0)
+	quietDB := 20 * math.Log10(amplitude(output[0], 5000, from, to)/0.01)
+
+	// (−3 − (−20)) · (1 − 1/4) ≈ 12.8 dB of reduction.
+	if loudDB > -11 || loudDB < -14 {
+		t.Fatalf("loud band gain = %.2f dB, want about -12.8 dB", loudDB)
+	}
+	if math.Abs(quietDB) > 0.1 {
+		t.Fatalf("quiet band gain = %.2f dB, want 0 dB", quietDB)
+	}
+}
+
+func TestCompressor_LinkedGainPreservesCorrelation(t *testing.T) {
+	t.Parallel()
+
+	const numSamples = sampleRate
+	loud := add(sine(100, 0.8, numSamples), sine(1000, 0.5, numSamples))
+	// Channel 1 is a quiet copy of channel 0 that would not be compressed
+	// on its own; channels 2 and 3 carry unrelated content.
+	input := [][]float64{
+		loud,
+		scale(loud, 0.05),
+		sine(8000, 0.02, numSamples),
+		scale(loud, -0.5),
+	}
+
+	comp, err := dynamics.NewCompressor(dynamics.DefaultCompressorConfig(), sampleRate)
+	if err != nil {
+		t.Fatalf("NewCompressor() error = %v", err)
+	}
+	output, err := comp.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if amplitude(output[0], 100, 0, numSamples) > 0.5*0.8 {
+		t.Fatalf("loud channel was not compressed")
+	}
+	for _, tc := range []struct {
+		ch   int
+		gain float64
+	}{{1, 0.05}, {3, -0.5}} {
+		for i := range output[0] {
+			if d := output[tc.ch][i] - tc.gain*output[0][i]; math.Abs(d) > 1e-12 {
+				t.Fatalf("channel %d sample %d differs from %g x channel 0 by %g", tc.ch, i, tc.gain, d)
+			}
+		}
+	}
+}
+
+func TestCompressor_UnityGainIsTransparent(t *testing.T) {
+	t.Parallel()
+
+	const numSamples = 10000
+	signal := add(sine(60, 0.05, numSamples), sine(3000, 0.05, numSamples))
+	cfg := dynamics.DefaultCompressorConfig()
+	cfg.ThresholdDB = 0
+	comp, err := dynamics.NewCompressor(cfg, sampleRate)
+	if err != nil {
+		t.Fatalf("NewCompressor() error = %v", err)
+	}
+	output, err := comp.Process([][]float64{signal})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	for i := range signal {
+		if d := math.Abs(output[0][i] - signal[i]); d > 1e-12 {
+			t.Fatalf("sample %d differs by %g", i, d)
+		}
+	}
+}
+
+func TestCompressor_ProcessSegmentMatchesProcess(t *testing.T) {
+	t.Parallel()
+
+	const numSamples = 20000
+	signal := add(sine(100, 0.9, numSamples), sine(2500, 0.3, numSamples))
+	input := [][]float64{signal, scale(signal, 0.5)}
+
+	whole, err := dynamics.NewCompressor(dynamics.DefaultCompressorConfig(), sampleRate)
+	if err != nil {
+		t.Fatalf("NewCompressor() error = %v", err)
+	}
+	want, err := whole.Process(input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	comp, err := dynamics.NewCompressor(dynamics.DefaultCompressorConfig(), sampleRate)
+	if err != nil {
+		t.Fatalf("NewCompressor() error = %v", err)
+	}
+	const segment = 4096
+	for start := 0; start < numSamples; start += segment {
+		numOutput := min(segment, numSamples-start)
+		end := min(start+numOutput+comp.Lookahead(), numSamples)
+		got, err := comp.ProcessSegment([][]float64{input[0][start:end], input[1][start:end]}, numOutput)
+		if err != nil {
+			t.Fatalf("ProcessSegment() error = %v", err)
+		}
+		for ch := range got {
+			for i, v := range got[ch] {
+				if v != want[ch][start+i] {
+					t.Fatalf("channel %d sample %d = %v, want %v", ch, start+i, v, want[ch][start+i])
+				}
+			}
+		}
+	}
+}
+
+func TestCompressorConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		modify func(*dynamics.CompressorConfig)
+	}{
+		{"crossovers too close", func(c *dynamics.CompressorConfig) { c.Crossovers = [2]float64{500, 800} }},
+		{"zero crossover", func(c *dynamics.CompressorConfig) { c.Crossovers[0] = 0 }},
+		{"ratio below one", func(c *dynamics.CompressorConfig) { c.Ratio = 0.5 }},
+		{"negative attack", func(c *dynamics.CompressorConfig) { c.AttackMs = -1 }},
+		{"block size not power of 2", func(c *dynamics.CompressorConfig) { c.BlockSize = 1000 }},
+	}
+	for _, tt := range tests {
+		cfg := dynamics.DefaultCompressorConfig()
+		tt.modify(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s: Validate() error = nil, want error", tt.name)
+		}
+	}
+	if err := dynamics.DefaultCompressorConfig().Validate(); err != nil {
+		t.Fatalf("default Validate() error = %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package encoder_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func BenchmarkSQEncoder_Process(b *testing.B) {
+	const sampleRate = 44100
+	quad := testsignal.QuadTones(sampleRate, 60*sampleRate, 0.4, 0.05)
+	b.ReportAllocs()
+	b.SetBytes(int64(8 * len(quad) * len(quad[0])))
+	for b.Loop() {
+		if _, err := encoder.NewSQEncoder().Process(quad); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
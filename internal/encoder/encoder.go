@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
 	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
 )
 
@@ -22,6 +23,21 @@ type SQEncoder struct {
 	sqrt2        float64
 	hilbertLB    *sqmath.HilbertTransformer
 	hilbertRB    *sqmath.HilbertTransformer
+	precision    sqmath.Precision
+	// hilbertScale and customFIR are as in the decoder; see
+	// SetHilbertScale.
+	hilbertScale float64
+	customFIR    bool
+	matrix       decoder.Matrix
+	// outputBuffers is per-block scratch for the matrix output.
+	outputBuffers [2][]float64
+	hookBefore    BlockHook
+	hookAfter     BlockHook
+	segmentBlock  int
+	segmentFrames int
+	outputScale   float64
+	measure       bool
+	energy        EnergyStats
 }
 
 // NewSQEncoder creates a new SQ encoder with FFT-based Hilbert transform
@@ -29,35 +45,100 @@ func NewSQEncoder() *SQEncoder {
 	return NewSQEncoderWithParams(DefaultBlockSize, DefaultOverlap)
 }
 
-// NewSQEncoderWithParams creates a new SQ encoder with custom parameters
+// NewSQEncoderWithParams creates a new SQ encoder with custom parameters.
+// overlap == blockSize selects the no-overlap fast mode with the same
+// trade-offs as the decoder's (see decoder.NewSQDecoderWithParams).
 func NewSQEncoderWithParams(blockSize, overlap int) *SQEncoder {
-	initialDelay := overlap + overlap/2
-
 	return &SQEncoder{
 		blockSize:    blockSize,
 		overlap:      overlap,
-		initialDelay: initialDelay,
+		initialDelay: LatencyFor(blockSize, overlap),
 		sqrt2:        math.Sqrt(2.0) / 2.0, // ≈ 0.707
-		hilbertLB:    sqmath.NewHilbertTransformer(blockSize, overlap),
-		hilbertRB:    sqmath.NewHilbertTransformer(blockSize, overlap),
+		hilbertLB:    sqmath.NewHilbertTransformer(blockSize, hilbertTaps(blockSize, overlap)),
+		hilbertRB:    sqmath.NewHilbertTransformer(blockSize, hilbertTaps(blockSize, overlap)),
+		hilbertScale: sqmath.DefaultHilbertScale,
+		outputBuffers: [2][]float64{
+			make([]float64, blockSize),
+			make([]float64, blockSize),
+		},
+		outputScale: 1,
+	}
+}
+
+// SetWindow selects the window applied to the Hilbert impulse response.
+func (e *SQEncoder) SetWindow(windowType sqmath.WindowType) {
+	taps := hilbertTaps(e.blockSize, e.overlap)
+	e.hilbertLB = sqmath.NewHilbertTransformerWithWindow(e.blockSize, taps, windowType)
+	e.hilbertRB = sqmath.NewHilbertTransformerWithWindow(e.blockSize, taps, windowType)
+	e.customFIR = false
+	if e.hilbertScale != sqmath.DefaultHilbertScale {
+		e.hilbertLB.SetScale(e.hilbertScale)
+		e.hilbertRB.SetScale(e.hilbertScale)
+	}
+	e.SetPrecision(e.precision)
+}
+
+// SetPrecision selects float64 (default) or float32 FFTs for the phase
+// shifter, as in the decoder (see decoder.SQDecoder.SetPrecision).
+func (e *SQEncoder) SetPrecision(precision sqmath.Precision) {
+	e.precision = precision
+	e.hilbertLB.SetPrecision(precision)
+	e.hilbertRB.SetPrecision(precision)
+}
+
+// hilbertTaps returns the Hilbert filter length; in no-overlap mode it keeps
+// the half-block length of the default configuration.
+func hilbertTaps(blockSize, overlap int) int {
+	if overlap == blockSize {
+		return blockSize / 2
 	}
+	return overlap
 }
 
 // Process encodes 4-channel quadrophonic audio to stereo SQ
 // Input: [4][numSamples] - LF, RF, LB, RB (Left Front, Right Front, Left Back, Right Back)
 // Output: [2][numSamples] - LT, RT (Left Total, Right Total)
 func (e *SQEncoder) Process(input [][]float64) ([][]float64, error) {
-	if len(input) != 4 {
-		return nil, fmt.Errorf("input must have 4 channels, got %d", len(input))
+	if err := e.validateInput(input); err != nil {
+		return nil, err
+	}
+	return e.process(input, len(input[0]), 0), nil
+}
+
+// ProcessSegment encodes the first numOutput samples of input and uses the
+// remaining samples only as lookahead. The segmentation rules are the same
+// as for SQDecoder.ProcessSegment.
+func (e *SQEncoder) ProcessSegment(input [][]float64, numOutput int) ([][]float64, error) {
+	if err := e.validateInput(input); err != nil {
+		return nil, err
 	}
+	if numOutput < 0 || numOutput > len(input[0]) {
+		return nil, fmt.Errorf("numOutput %d out of range [0, %d]", numOutput, len(input[0]))
+	}
+
+	output := e.process(input, numOutput, e.segmentBlock)
+	e.segmentBlock += (numOutput + e.overlap - 1) / e.overlap
+	e.segmentFrames += numOutput
+	return output, nil
+}
 
-	numSamples := len(input[0])
+func (e *SQEncoder) validateInput(input [][]float64) error {
+	if err := decoder.ValidateParams(e.blockSize, e.overlap); err != nil {
+		return fmt.Errorf("encoder not initialized: %w", err)
+	}
+	if len(input) != 4 {
+		return fmt.Errorf("input must have 4 channels, got %d", len(input))
+	}
 	for i := 1; i < 4; i++ {
-		if len(input[i]) != numSamples {
-			return nil, fmt.Errorf("input channels must have same length")
+		if len(input[i]) != len(input[0]) {
+			return fmt.Errorf("input channels must have same length")
 		}
 	}
+	return nil
+}
 
+func (e *SQEncoder) process(input [][]float64, numSamples, firstBlock int) [][]float64 {
+	numInput := len(input[0])
 	numBlocks := (numSamples + e.overlap - 1) / e.overlap
 
 	output := make([][]float64, 2)
@@ -75,7 +156,7 @@ func (e *SQEncoder) Process(input [][]float64) ([][]float64, error) {
 
 		for i := 0; i < e.blockSize; i++ {
 			srcIdx := startIdx + i
-			if srcIdx < numSamples {
+			if srcIdx < numInput {
 				blockLF[i] = input[0][srcIdx]
 				blockRF[i] = input[1][srcIdx]
 				blockLB[i] = input[2][srcIdx]
@@ -86,8 +167,19 @@ func (e *SQEncoder) Process(input [][]float64) ([][]float64, error) {
 		phaseShiftedLB := e.hilbertLB.ProcessBlock(blockLB)
 		phaseShiftedRB := e.hilbertRB.ProcessBlock(blockRB)
 
+		if e.hookBefore != nil {
+			e.hookBefore(firstBlock+blockIdx, [][]float64{blockLF, blockRF, blockLB, blockRB, phaseShiftedLB, phaseShiftedRB})
+		}
+
 		outputOffset := e.overlap / 2
 		inputOffset := e.overlap / 4
+		noOverlap := e.overlap == e.blockSize
+		if noOverlap {
+			outputOffset = hilbertTaps(e.blockSize, e.overlap) / 2
+			inputOffset = 0
+		}
+		blockOut := e.outputBuffers
+		count := 0
 
 		for i := 0; i < e.overlap; i++ {
 			outIdx := startIdx + i
@@ -101,6 +193,9 @@ func (e *SQEncoder) Process(input [][]float64) ([][]float64, error) {
 			}
 
 			phaseIdx := outputOffset + i
+			if noOverlap {
+				phaseIdx %= e.blockSize
+			}
 			if phaseIdx >= e.blockSize {
 				break
 			}
@@ -112,15 +207,33 @@ func (e *SQEncoder) Process(input [][]float64) ([][]float64, error) {
 			hlb := phaseShiftedLB[phaseIdx]
 			hrb := phaseShiftedRB[phaseIdx]
 
-			// SQ Encode Matrix:
-			// LT = LF + sqrt(2)/2 * RB - sqrt(2)/2 * H(LB)
-			// RT = RF - sqrt(2)/2 * LB + sqrt(2)/2 * H(RB)
-			output[0][outIdx] = lf + e.sqrt2*rb - e.sqrt2*hlb
-			output[1][outIdx] = rf - e.sqrt2*lb + e.sqrt2*hrb
+			var lt, rt float64
+			if e.matrix == decoder.MatrixQS {
+				lt, rt = encodeQS(lf, rf, hlb, hrb)
+			} else {
+				// SQ Encode Matrix:
+				// LT = LF + sqrt(2)/2 * RB - sqrt(2)/2 * H(LB)
+				// RT = RF - sqrt(2)/2 * LB + sqrt(2)/2 * H(RB)
+				lt = lf + e.sqrt2*rb - e.sqrt2*hlb
+				rt = rf - e.sqrt2*lb + e.sqrt2*hrb
+			}
+			blockOut[0][i] = e.outputScale * lt
+			blockOut[1][i] = e.outputScale * rt
+			count++
 		}
+
+		if e.hookAfter != nil {
+			e.hookAfter(firstBlock+blockIdx, [][]float64{blockOut[0][:count], blockOut[1][:count]})
+		}
+
+		copy(output[0][startIdx:startIdx+count], blockOut[0][:count])
+		copy(output[1][startIdx:startIdx+count], blockOut[1][:count])
 	}
 
-	return output, nil
+	if e.measure {
+		e.measureEnergy(input, output, numSamples)
+	}
+	return output
 }
 
 // GetLatency returns the encoder latency in samples
@@ -128,6 +241,15 @@ func (e *SQEncoder) GetLatency() int {
 	return e.initialDelay
 }
 
+// LatencyFor returns the latency in samples of an encoder with the given
+// parameters, the value its GetLatency reports. It matches the decoder's.
+func LatencyFor(blockSize, overlap int) int {
+	if overlap == blockSize {
+		return blockSize
+	}
+	return overlap + overlap/2
+}
+
 // GetInfo returns information about the encoder configuration
 func (e *SQEncoder) GetInfo() string {
 	return fmt.Sprintf("SQ Encoder (FFT-based)\n"+
@@ -97,3 +97,53 @@ func TestSQEncoder_Process_Errors(t *testing.T) {
 		t.Fatalf("expected error for length mismatch")
 	}
 }
+
+func TestSQEncoder_ProcessSegment_MatchesProcess(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 9*overlap + 77
+		chunk     = 2 * overlap
+		lookahead = blockSize - overlap
+	)
+
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = make([]float64, n)
+		for i := 0; i < n; i++ {
+			quad[ch][i] = 0.3 * math.Sin(2.0*math.Pi*float64(i)/float64(40+25*ch))
+		}
+	}
+
+	want, err := encoder.NewSQEncoderWithParams(blockSize, overlap).Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	sqEnc := encoder.NewSQEncoderWithParams(blockSize, overlap)
+	for start := 0; start < n; start += chunk {
+		numOutput := min(chunk, n-start)
+		end := min(start+numOutput+lookahead, n)
+		segment := make([][]float64, 4)
+		for ch := range segment {
+			segment[ch] = quad[ch][start:end]
+		}
+
+		got, err := sqEnc.ProcessSegment(segment, numOutput)
+		if err != nil {
+			t.Fatalf("ProcessSegment() error = %v", err)
+		}
+		for ch := 0; ch < 2; ch++ {
+			if len(got[ch]) != numOutput {
+				t.Fatalf("len(got[%d]) = %d, want %d", ch, len(got[ch]), numOutput)
+			}
+			for i := range got[ch] {
+				if got[ch][i] != want[ch][start+i] {
+					t.Fatalf("segment at %d: out[%d][%d] = %v, want %v", start, ch, i, got[ch][i], want[ch][start+i])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package encoder
+
+import "math"
+
+// EnergyStats is what an encoder measured with SetEnergyMeasurement since it
+// was enabled.
+type EnergyStats struct {
+	// Input and Output are the sums of the squared samples of all input
+	// and output channels, over the encoded frames.
+	Input, Output float64
+	// Peak is the largest absolute output sample.
+	Peak float64
+}
+
+// GainDB returns the ratio of output to input energy in dB, 0 for silent
+// input. The SQ matrix conserves the energy of uncorrelated channels, but
+// correlated back channels can add up to several dB in the stereo.
+func (s EnergyStats) GainDB() float64 {
+	if s.Input == 0 || s.Output == 0 {
+		return 0
+	}
+	return 10 * math.Log10(s.Output/s.Input)
+}
+
+// PeakDB returns the output peak in dBFS.
+func (s EnergyStats) PeakDB() float64 {
+	return 20 * math.Log10(s.Peak)
+}
+
+// SetEnergyMeasurement toggles measuring the input and output energy and
+// the output peak of every encoded frame, and clears the measurement.
+func (e *SQEncoder) SetEnergyMeasurement(enabled bool) {
+	e.measure = enabled
+	e.energy = EnergyStats{}
+}
+
+// Energy returns the measurement of SetEnergyMeasurement.
+func (e *SQEncoder) Energy() EnergyStats {
+	return e.energy
+}
+
+// SetOutputScale scales the encoded output by scale, for example to keep
+// it below a ceiling (see HeadroomScale). The energy measurement covers the
+// scaled output. The default is 1.
+func (e *SQEncoder) SetOutputScale(scale float64) {
+	e.outputScale = scale
+}
+
+// HeadroomScale returns the output scale that brings an output peak of
+// peak down to ceilingDB dBFS, or 1 if it is already below.
+func HeadroomScale(peak, ceilingDB float64) float64 {
+	ceiling := math.Pow(10, ceilingDB/20)
+	if peak <= ceiling {
+		return 1
+	}
+	return ceiling / peak
+}
+
+// measureEnergy adds the first numSamples frames of input and output to the
+// measurement.
+func (e *SQEncoder) measureEnergy(input, output [][]float64, numSamples int) {
+	for _, ch := range input {
+		for _, v := range ch[:min(numSamples, len(ch))] {
+			e.energy.Input += v * v
+		}
+	}
+	for _, ch := range output {
+		for _, v := range ch[:numSamples] {
+			e.energy.Output += v * v
+			e.energy.Peak = max(e.energy.Peak, math.Abs(v))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package encoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+)
+
+// fullScaleQuad returns n frames of a full-scale 1 kHz sine at 48 kHz in all
+// four channels, which the SQ matrix sums to more than full scale.
+func fullScaleQuad(n int) [][]float64 {
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = make([]float64, n)
+		for i := range n {
+			quad[ch][i] = math.Sin(2 * math.Pi * 1000 * float64(i) / 48000)
+		}
+	}
+	return quad
+}
+
+func TestSQEncoder_EnergyMeasurement(t *testing.T) {
+	t.Parallel()
+
+	const n = 16384
+	quad := fullScaleQuad(n)
+	e := encoder.NewSQEncoder()
+	e.SetEnergyMeasurement(true)
+	stereo, err := e.Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var input, output, peak float64
+	for _, ch := range quad {
+		for _, v := range ch {
+			input += v * v
+		}
+	}
+	for _, ch := range stereo {
+		for _, v := range ch {
+			output += v * v
+			peak = max(peak, math.Abs(v))
+		}
+	}
+	got := e.Energy()
+	if math.Abs(got.Input-input) > 1e-9*input || math.Abs(got.Output-output) > 1e-9*output || got.Peak != peak {
+		t.Fatalf("Energy() = %+v, want input %g, output %g, peak %g", got, input, output, peak)
+	}
+	if got.Peak <= 1 {
+		t.Fatalf("full-scale quad encoded to a peak of %g, want an overload", got.Peak)
+	}
+	if want := 10 * math.Log10(output/input); math.Abs(got.GainDB()-want) > 1e-9 {
+		t.Fatalf("GainDB() = %g, want %g", got.GainDB(), want)
+	}
+
+	// Re-enabling the measurement clears it.
+	e.SetEnergyMeasurement(true)
+	if e.Energy() != (encoder.EnergyStats{}) {
+		t.Fatalf("Energy() = %+v after reset, want zero", e.Energy())
+	}
+}
+
+func TestSQEncoder_HeadroomKeepsFullScaleWithinCeiling(t *testing.T) {
+	t.Parallel()
+
+	const (
+		n         = 16384
+		ceilingDB = -1.0
+	)
+	quad := fullScaleQuad(n)
+	measure := encoder.NewSQEncoder()
+	measure.SetEnergyMeasurement(true)
+	plain, err := measure.Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	scale := encoder.HeadroomScale(measure.Energy().Peak, ceilingDB)
+	if scale >= 1 {
+		t.Fatalf("HeadroomScale(%g, %g) = %g, want attenuation", measure.Energy().Peak, ceilingDB, scale)
+	}
+
+	e := encoder.NewSQEncoder()
+	e.SetEnergyMeasurement(true)
+	e.SetOutputScale(scale)
+	stereo, err := e.Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	ceiling := math.Pow(10, ceilingDB/20)
+	if peak := e.Energy().Peak; peak > ceiling*(1+1e-12) {
+		t.Fatalf("peak = %g (%.2f dBFS), want at most %g", peak, e.Energy().PeakDB(), ceiling)
+	}
+	for ch := range stereo {
+		for i, v := range stereo[ch] {
+			if math.Abs(v-scale*plain[ch][i]) > 1e-12 {
+				t.Fatalf("channel %d frame %d = %g, want %g", ch, i, v, scale*plain[ch][i])
+			}
+		}
+	}
+	if got := encoder.HeadroomScale(0.5, ceilingDB); got != 1 {
+		t.Fatalf("HeadroomScale(0.5, %g) = %g, want 1", ceilingDB, got)
+	}
+}
@@ -0,0 +1,33 @@
+package encoder
+
+// BlockStage selects where in the per-block pipeline a BlockHook runs.
+type BlockStage int
+
+const (
+	// HookBeforeMatrix runs after the Hilbert transform and before the
+	// encode matrix. Buffers are LF, RF, LB, RB, H(LB), H(RB), each
+	// blockSize long.
+	HookBeforeMatrix BlockStage = iota
+	// HookAfterMatrix runs after the encode matrix. Buffers are LT, RT
+	// holding the block's output samples (at most overlap long; shorter for
+	// the final block).
+	HookAfterMatrix
+)
+
+// BlockHook is invoked once per processed block with the block index and the
+// block buffers, which it may modify in place. The buffers are scratch
+// memory owned by the encoder: they are only valid for the duration of the
+// call and are reused for the next block, so a hook must copy anything it
+// wants to keep. Process calls the hook sequentially from the calling
+// goroutine, in block order.
+type BlockHook func(blockIdx int, buffers [][]float64)
+
+// SetBlockHook installs hook at the given stage; a nil hook removes it.
+func (e *SQEncoder) SetBlockHook(stage BlockStage, hook BlockHook) {
+	switch stage {
+	case HookBeforeMatrix:
+		e.hookBefore = hook
+	case HookAfterMatrix:
+		e.hookAfter = hook
+	}
+}
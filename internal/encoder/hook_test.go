@@ -0,0 +1,69 @@
+package encoder_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+)
+
+func TestSQEncoder_BlockHook_MutesEveryOtherBlock(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		n         = 6*overlap + 37
+	)
+
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = make([]float64, n)
+		for i := 0; i < n; i++ {
+			quad[ch][i] = 0.3 * math.Sin(2.0*math.Pi*float64(i)/float64(50+20*ch))
+		}
+	}
+
+	reference, err := encoder.NewSQEncoderWithParams(blockSize, overlap).Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	sqEnc := encoder.NewSQEncoderWithParams(blockSize, overlap)
+	before := 0
+	sqEnc.SetBlockHook(encoder.HookBeforeMatrix, func(blockIdx int, buffers [][]float64) {
+		before++
+		if len(buffers) != 6 {
+			t.Fatalf("len(buffers) = %d, want 6", len(buffers))
+		}
+	})
+	sqEnc.SetBlockHook(encoder.HookAfterMatrix, func(blockIdx int, buffers [][]float64) {
+		if blockIdx%2 == 0 {
+			return
+		}
+		for _, buf := range buffers {
+			for i := range buf {
+				buf[i] = 0
+			}
+		}
+	})
+
+	out, err := sqEnc.Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if want := (n + overlap - 1) / overlap; before != want {
+		t.Fatalf("before-matrix hook calls = %d, want %d", before, want)
+	}
+	for ch := 0; ch < 2; ch++ {
+		for i := 0; i < n; i++ {
+			want := reference[ch][i]
+			if (i/overlap)%2 == 1 {
+				want = 0
+			}
+			if out[ch][i] != want {
+				t.Fatalf("out[%d][%d] = %.15f, want %.15f", ch, i, out[ch][i], want)
+			}
+		}
+	}
+}
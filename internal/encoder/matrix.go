@@ -0,0 +1,75 @@
+package encoder
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// QS mixes each channel into the totals at 22.5° from its own side, as in
+// the decoder.
+var (
+	qsCos = math.Cos(math.Pi / 8)
+	qsSin = math.Sin(math.Pi / 8)
+)
+
+// SetMatrix selects the SQ or QS encode equations, to match a decoder set
+// to the same matrix.
+func (e *SQEncoder) SetMatrix(m decoder.Matrix) {
+	e.matrix = m
+}
+
+// Matrix returns the configured matrix system.
+func (e *SQEncoder) Matrix() decoder.Matrix {
+	return e.matrix
+}
+
+// SetHilbertFIR replaces the built-in Hilbert filter of both back channels
+// with the FIR coeffs, applied as given (no window, unity gain) and
+// centered like the built-in one, as decoder.SQDecoder.SetHilbertFIR does.
+// A later SetWindow restores the built-in filter.
+func (e *SQEncoder) SetHilbertFIR(coeffs []float64) error {
+	taps := hilbertTaps(e.blockSize, e.overlap)
+	if len(coeffs) == 0 || len(coeffs) > taps {
+		return fmt.Errorf("Hilbert FIR has %d taps, block size %d with overlap %d allows 1 to %d", len(coeffs), e.blockSize, e.overlap, taps)
+	}
+	impulse := make([]float64, taps)
+	copy(impulse[taps/2-(len(coeffs)-1)/2:], coeffs)
+
+	lb, err := sqmath.NewHilbertTransformerFromImpulse(e.blockSize, impulse)
+	if err != nil {
+		return err
+	}
+	rb, err := sqmath.NewHilbertTransformerFromImpulse(e.blockSize, impulse)
+	if err != nil {
+		return err
+	}
+	e.hilbertLB, e.hilbertRB = lb, rb
+	e.customFIR = true
+	e.SetPrecision(e.precision)
+	return nil
+}
+
+// SetHilbertScale sets the gain of the built-in Hilbert filter of the back
+// channels, as decoder.SQDecoder.SetHilbertScale does.
+func (e *SQEncoder) SetHilbertScale(scale float64) {
+	e.hilbertScale = scale
+	if !e.customFIR {
+		e.hilbertLB.SetScale(scale)
+		e.hilbertRB.SetScale(scale)
+	}
+}
+
+// encodeQS applies the QS Regular Matrix: the fronts enter both totals in
+// phase, the backs both totals shifted by +90° into LT and -90° into RT,
+// each at cos 22.5° into its own side and sin 22.5° into the other.
+//
+//	LT = 0.924·LF + 0.383·RF + 0.924·H(LB) + 0.383·H(RB)
+//	RT = 0.383·LF + 0.924·RF - 0.383·H(LB) - 0.924·H(RB)
+func encodeQS(lf, rf, hlb, hrb float64) (lt, rt float64) {
+	lt = qsCos*lf + qsSin*rf + qsCos*hlb + qsSin*hrb
+	rt = qsSin*lf + qsCos*rf - qsSin*hlb - qsCos*hrb
+	return lt, rt
+}
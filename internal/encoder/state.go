@@ -0,0 +1,72 @@
+package encoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// stateMagic and stateVersion identify a serialized encoder state.
+const (
+	stateMagic   = "SQES"
+	stateVersion = 1
+	stateSize    = 4 + 1 + 4 + 4 + 8 + 8
+)
+
+// Snapshot serializes the streaming state that ProcessSegment carries from
+// one call to the next: the output position and the block index. Resuming
+// works as for SQDecoder.Snapshot.
+func (e *SQEncoder) Snapshot() []byte {
+	buf := make([]byte, 0, stateSize)
+	buf = append(buf, stateMagic...)
+	buf = append(buf, stateVersion)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(e.blockSize))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(e.overlap))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(e.segmentFrames))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(e.segmentBlock))
+	return buf
+}
+
+// Restore loads a state produced by Snapshot. It fails if the snapshot was
+// taken with a different block size or overlap.
+func (e *SQEncoder) Restore(state []byte) error {
+	if len(state) != stateSize || string(state[:4]) != stateMagic {
+		return fmt.Errorf("not an encoder state")
+	}
+	if state[4] != stateVersion {
+		return fmt.Errorf("unsupported encoder state version %d", state[4])
+	}
+	p := state[5:]
+	blockSize := int(binary.LittleEndian.Uint32(p[0:]))
+	overlap := int(binary.LittleEndian.Uint32(p[4:]))
+	if blockSize != e.blockSize || overlap != e.overlap {
+		return fmt.Errorf("state is for block size %d, overlap %d; encoder uses %d, %d", blockSize, overlap, e.blockSize, e.overlap)
+	}
+
+	frames := binary.LittleEndian.Uint64(p[8:])
+	block := binary.LittleEndian.Uint64(p[16:])
+	if frames > math.MaxInt32*uint64(overlap) || block > frames/uint64(overlap)+1 {
+		return fmt.Errorf("invalid encoder state position %d (block %d)", frames, block)
+	}
+	e.segmentFrames = int(frames)
+	e.segmentBlock = int(block)
+	return nil
+}
+
+// Reset returns the encoder to the state of a new one while keeping its
+// configuration, as SQDecoder.Reset does: it clears the position, the
+// block index and the energy measurement.
+func (e *SQEncoder) Reset() {
+	e.segmentBlock = 0
+	e.segmentFrames = 0
+	e.energy = EnergyStats{}
+	for ch := range e.outputBuffers {
+		clear(e.outputBuffers[ch])
+	}
+}
+
+// Position returns the number of output frames produced by ProcessSegment
+// so far, which is also where the next segment's input starts.
+func (e *SQEncoder) Position() int {
+	return e.segmentFrames
+}
@@ -0,0 +1,109 @@
+package encoder_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func TestSQEncoder_SnapshotResumeMatchesUninterrupted(t *testing.T) {
+	t.Parallel()
+
+	const (
+		blockSize = 1024
+		overlap   = 512
+		segment   = 4 * overlap
+	)
+	quad := testsignal.QuadTones(44100, 12*segment+99, 0.4, 0.05)
+	n := len(quad[0])
+
+	// encodeSegments continues e from its position, stopping after
+	// maxSegments segments when maxSegments > 0.
+	encodeSegments := func(e *encoder.SQEncoder, maxSegments int) [][]float64 {
+		out := make([][]float64, 2)
+		for segments := 0; e.Position() < n; segments++ {
+			if maxSegments > 0 && segments == maxSegments {
+				break
+			}
+			start := e.Position()
+			numOutput := min(segment, n-start)
+			end := min(start+numOutput+blockSize-overlap, n)
+			in := make([][]float64, 4)
+			for ch := range in {
+				in[ch] = quad[ch][start:end]
+			}
+			got, err := e.ProcessSegment(in, numOutput)
+			if err != nil {
+				t.Fatalf("ProcessSegment() error = %v", err)
+			}
+			for ch := range out {
+				out[ch] = append(out[ch], got[ch]...)
+			}
+		}
+		return out
+	}
+
+	want := encodeSegments(encoder.NewSQEncoderWithParams(blockSize, overlap), 0)
+
+	killed := encoder.NewSQEncoderWithParams(blockSize, overlap)
+	head := encodeSegments(killed, 5)
+	state := killed.Snapshot()
+
+	resumed := encoder.NewSQEncoderWithParams(blockSize, overlap)
+	if err := resumed.Restore(state); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	tail := encodeSegments(resumed, 0)
+
+	for ch := range want {
+		got := append(append([]float64(nil), head[ch]...), tail[ch]...)
+		if len(got) != n {
+			t.Fatalf("channel %d: %d samples, want %d", ch, len(got), n)
+		}
+		for i := range got {
+			if got[i] != want[ch][i] {
+				t.Fatalf("channel %d sample %d = %v, want %v", ch, i, got[i], want[ch][i])
+			}
+		}
+	}
+
+	if err := encoder.NewSQEncoderWithParams(2*blockSize, overlap).Restore(state); err == nil {
+		t.Fatalf("Restore() with different block size error = nil, want error")
+	}
+}
+
+func TestSQEncoder_ResetMatchesNewEncoder(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, 5000, 0.4, 0.05)
+	newEncoder := func() *encoder.SQEncoder {
+		e := encoder.NewSQEncoder()
+		e.SetEnergyMeasurement(true)
+		return e
+	}
+	fresh := newEncoder()
+	want, err := fresh.ProcessSegment(quad, 2048)
+	if err != nil {
+		t.Fatalf("ProcessSegment() error = %v", err)
+	}
+
+	e := newEncoder()
+	for run := range 2 {
+		got, err := e.ProcessSegment(quad, 2048)
+		if err != nil {
+			t.Fatalf("ProcessSegment() error = %v", err)
+		}
+		for ch := range want {
+			for i := range want[ch] {
+				if got[ch][i] != want[ch][i] {
+					t.Fatalf("run %d channel %d sample %d = %v, want %v", run, ch, i, got[ch][i], want[ch][i])
+				}
+			}
+		}
+		if e.Position() != 2048 || e.Energy() != fresh.Energy() {
+			t.Fatalf("run %d: Position() = %d, Energy() = %+v, want 2048, %+v", run, e.Position(), e.Energy(), fresh.Energy())
+		}
+		e.Reset()
+	}
+}
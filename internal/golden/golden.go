@@ -0,0 +1,236 @@
+// Package golden generates deterministic encode/decode test vectors at a
+// pinned configuration and reduces their outputs to compact fingerprints.
+// The fingerprints stored in testdata/fingerprints.json are the reference
+// for the regression test; after an intentional algorithm change they are
+// regenerated with `go-sq-tool gen-vectors`.
+package golden
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+)
+
+// DefaultPath is the fingerprint file relative to the repository root.
+const DefaultPath = "internal/golden/testdata/fingerprints.json"
+
+// RMSTolerance is the largest absolute per-block RMS difference Compare
+// accepts. It only absorbs floating-point noise from reordered arithmetic.
+const RMSTolerance = 1e-9
+
+// Config is the pinned processing configuration of the vectors.
+type Config struct {
+	SampleRate int `json:"sampleRate"`
+	NumSamples int `json:"numSamples"`
+	BlockSize  int `json:"blockSize"`
+	Overlap    int `json:"overlap"`
+	// RMSBlock is the fingerprint block length in samples.
+	RMSBlock int `json:"rmsBlock"`
+}
+
+// DefaultConfig returns the configuration the stored fingerprints use.
+func DefaultConfig() Config {
+	return Config{
+		SampleRate: 44100,
+		NumSamples: 32768,
+		BlockSize:  decoder.DefaultBlockSize,
+		Overlap:    decoder.DefaultOverlap,
+		RMSBlock:   4096,
+	}
+}
+
+// Fingerprint summarizes one processed signal.
+type Fingerprint struct {
+	// Name identifies the vector and stage, e.g. "quad-tones/decoded".
+	Name     string `json:"name"`
+	Channels int    `json:"channels"`
+	Frames   int    `json:"frames"`
+	// BlockRMS is the RMS of each RMSBlock-sample block per channel.
+	BlockRMS [][]float64 `json:"blockRMS"`
+	// SHA256 is the hash of the signal as interleaved little-endian 16-bit
+	// PCM, which catches changes the RMS cannot see (such as polarity).
+	SHA256 string `json:"sha256"`
+}
+
+// Set is the content of a fingerprint file.
+type Set struct {
+	Config       Config        `json:"config"`
+	Fingerprints []Fingerprint `json:"fingerprints"`
+}
+
+// vector is a deterministic quad input.
+type vector struct {
+	name  string
+	input func(Config) [][]float64
+}
+
+var vectors = []vector{
+	{"quad-tones", func(c Config) [][]float64 {
+		return testsignal.QuadTones(c.SampleRate, c.NumSamples, 0.4, 0.05)
+	}},
+	{"isolated-lb", func(c Config) [][]float64 {
+		return testsignal.Isolate(testsignal.QuadTones(c.SampleRate, c.NumSamples, 0.5, 0), 2)
+	}},
+	{"sweep-slots", func(c Config) [][]float64 {
+		return testsignal.QuadSweepSlots(c.SampleRate, c.NumSamples/4, 20, 20000, 0.5)
+	}},
+}
+
+// Generate encodes every vector, decodes the result with and without logic
+// steering and fingerprints the three outputs.
+func Generate(config Config) (Set, error) {
+	set := Set{Config: config}
+	for _, v := range vectors {
+		sqEncoder, err := sq.NewEncoder(sq.WithBlockSize(config.BlockSize), sq.WithOverlap(config.Overlap))
+		if err != nil {
+			return Set{}, err
+		}
+		encoded, err := sqEncoder.Process(v.input(config))
+		if err != nil {
+			return Set{}, fmt.Errorf("%s: encoding failed: %w", v.name, err)
+		}
+		set.Fingerprints = append(set.Fingerprints, fingerprint(v.name+"/encoded", encoded, config.RMSBlock))
+
+		for _, logic := range []bool{false, true} {
+			options := []sq.Option{
+				sq.WithBlockSize(config.BlockSize),
+				sq.WithOverlap(config.Overlap),
+				sq.WithSampleRate(config.SampleRate),
+			}
+			if logic {
+				options = append(options, sq.WithLogicSteering(sq.DefaultLogicSteeringConfig()))
+			}
+			sqDecoder, err := sq.NewDecoder(options...)
+			if err != nil {
+				return Set{}, err
+			}
+			decoded, err := sqDecoder.Process(encoded)
+			if err != nil {
+				return Set{}, fmt.Errorf("%s: decoding failed: %w", v.name, err)
+			}
+			name := v.name + "/decoded"
+			if logic {
+				name += "-logic"
+			}
+			set.Fingerprints = append(set.Fingerprints, fingerprint(name, decoded, config.RMSBlock))
+		}
+	}
+	return set, nil
+}
+
+func fingerprint(name string, samples [][]float64, rmsBlock int) Fingerprint {
+	fp := Fingerprint{Name: name, Channels: len(samples)}
+	if len(samples) > 0 {
+		fp.Frames = len(samples[0])
+	}
+
+	fp.BlockRMS = make([][]float64, len(samples))
+	for ch, signal := range samples {
+		for start := 0; start < len(signal); start += rmsBlock {
+			block := signal[start:min(start+rmsBlock, len(signal))]
+			sum := 0.0
+			for _, v := range block {
+				sum += v * v
+			}
+			fp.BlockRMS[ch] = append(fp.BlockRMS[ch], math.Sqrt(sum/float64(len(block))))
+		}
+	}
+
+	hash := sha256.New()
+	frame := make([]byte, 2*len(samples))
+	for i := 0; i < fp.Frames; i++ {
+		for ch := range samples {
+			binary.LittleEndian.PutUint16(frame[2*ch:], uint16(wav.Float64ToInt16(samples[ch][i])))
+		}
+		hash.Write(frame)
+	}
+	fp.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return fp
+}
+
+// Compare returns one message per difference between the reference set
+// want and the regenerated set got; nil means they match.
+func Compare(want, got Set) []string {
+	if want.Config != got.Config {
+		return []string{fmt.Sprintf("config %+v, want %+v", got.Config, want.Config)}
+	}
+
+	var diffs []string
+	byName := make(map[string]Fingerprint, len(got.Fingerprints))
+	for _, fp := range got.Fingerprints {
+		byName[fp.Name] = fp
+	}
+	for _, w := range want.Fingerprints {
+		g, ok := byName[w.Name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: missing", w.Name))
+			continue
+		}
+		delete(byName, w.Name)
+		diffs = append(diffs, compareFingerprint(w, g)...)
+	}
+	for _, fp := range got.Fingerprints {
+		if _, ok := byName[fp.Name]; ok {
+			diffs = append(diffs, fmt.Sprintf("%s: not in reference", fp.Name))
+		}
+	}
+	return diffs
+}
+
+func compareFingerprint(want, got Fingerprint) []string {
+	if got.Channels != want.Channels || got.Frames != want.Frames || len(got.BlockRMS) != len(want.BlockRMS) {
+		return []string{fmt.Sprintf("%s: shape %dx%d, want %dx%d", want.Name, got.Channels, got.Frames, want.Channels, want.Frames)}
+	}
+
+	var diffs []string
+	for ch := range want.BlockRMS {
+		if len(got.BlockRMS[ch]) != len(want.BlockRMS[ch]) {
+			diffs = append(diffs, fmt.Sprintf("%s: channel %d has %d RMS blocks, want %d", want.Name, ch, len(got.BlockRMS[ch]), len(want.BlockRMS[ch])))
+			continue
+		}
+		for i, w := range want.BlockRMS[ch] {
+			if d := got.BlockRMS[ch][i] - w; math.Abs(d) > RMSTolerance {
+				diffs = append(diffs, fmt.Sprintf("%s: channel %d block %d RMS %.9f, want %.9f", want.Name, ch, i, got.BlockRMS[ch][i], w))
+				break
+			}
+		}
+	}
+	if got.SHA256 != want.SHA256 {
+		diffs = append(diffs, fmt.Sprintf("%s: sample hash %s, want %s", want.Name, got.SHA256, want.SHA256))
+	}
+	return diffs
+}
+
+// Load reads a fingerprint file.
+func Load(path string) (Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Set{}, fmt.Errorf("failed to read fingerprints: %w", err)
+	}
+	var set Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return Set{}, fmt.Errorf("failed to parse fingerprints: %w", err)
+	}
+	return set, nil
+}
+
+// Save writes set as indented JSON.
+func Save(path string, set Set) error {
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fingerprints: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write fingerprints: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package golden_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/golden"
+)
+
+// TestRegression regenerates the vectors and compares them against the
+// stored reference. If an algorithm change is intentional, update the
+// reference with `go run . gen-vectors` from the repository root and
+// commit the new file together with the change.
+func TestRegression(t *testing.T) {
+	t.Parallel()
+
+	want, err := golden.Load(filepath.Join("testdata", "fingerprints.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got, err := golden.Generate(golden.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if diffs := golden.Compare(want, got); len(diffs) > 0 {
+		t.Fatalf("output differs from golden fingerprints (run `go run . gen-vectors` if intended):\n%s", strings.Join(diffs, "\n"))
+	}
+}
+
+func TestCompare_DetectsChanges(t *testing.T) {
+	t.Parallel()
+
+	config := golden.DefaultConfig()
+	config.NumSamples = 4096
+	config.RMSBlock = 1024
+	want, err := golden.Generate(config)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*golden.Set)
+		match  string
+	}{
+		{"rms", func(s *golden.Set) { s.Fingerprints[1].BlockRMS[2][1] += 1e-6 }, "block 1 RMS"},
+		{"hash", func(s *golden.Set) { s.Fingerprints[0].SHA256 = "00" }, "sample hash"},
+		{"missing", func(s *golden.Set) { s.Fingerprints = s.Fingerprints[1:] }, "missing"},
+		{"config", func(s *golden.Set) { s.Config.Overlap = 256 }, "config"},
+	}
+	for _, tt := range tests {
+		got, err := golden.Generate(config)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		tt.modify(&got)
+		diffs := golden.Compare(want, got)
+		if len(diffs) != 1 || !strings.Contains(diffs[0], tt.match) {
+			t.Fatalf("%s: Compare() = %q, want one diff containing %q", tt.name, diffs, tt.match)
+		}
+	}
+
+	got, err := golden.Generate(config)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	got.Fingerprints[0].BlockRMS[0][0] += golden.RMSTolerance / 2
+	if diffs := golden.Compare(want, got); diffs != nil {
+		t.Fatalf("Compare() within tolerance = %q, want nil", diffs)
+	}
+}
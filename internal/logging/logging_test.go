@@ -0,0 +1,130 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/logging"
+)
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want slog.Level
+	}{
+		{"error", slog.LevelError},
+		{"warn", slog.LevelWarn},
+		{"WARNING", slog.LevelWarn},
+		{"info", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+	}
+	for _, tt := range tests {
+		got, err := logging.ParseLevel(tt.name)
+		if err != nil {
+			t.Fatalf("ParseLevel(%q) error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("ParseLevel(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, err := logging.ParseLevel("verbose"); err == nil {
+		t.Fatalf("ParseLevel(verbose) error = nil, want error")
+	}
+}
+
+func TestNew_LevelFiltering(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		level slog.Level
+		want  []string
+	}{
+		{slog.LevelError, []string{"e"}},
+		{slog.LevelWarn, []string{"w", "e"}},
+		{slog.LevelInfo, []string{"i", "w", "e"}},
+		{slog.LevelDebug, []string{"d", "i", "w", "e"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		logger, err := logging.New(&buf, tt.level, logging.FormatJSON)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		logger.Debug("d")
+		logger.Info("i")
+		logger.Warn("w")
+		logger.Error("e")
+
+		var got []string
+		dec := json.NewDecoder(&buf)
+		for dec.More() {
+			var record struct {
+				Msg string `json:"msg"`
+			}
+			if err := dec.Decode(&record); err != nil {
+				t.Fatalf("decode record: %v", err)
+			}
+			got = append(got, record.Msg)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Fatalf("level %v: records = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestNew_TextFormat(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, slog.LevelInfo, logging.FormatText)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	logger.Info("decoded", "path", "in.wav", "frames", 42)
+
+	line := buf.String()
+	for _, want := range []string{"level=INFO", "msg=decoded", "path=in.wav", "frames=42"} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("output %q does not contain %q", line, want)
+		}
+	}
+	if _, err := logging.New(&buf, slog.LevelInfo, "xml"); err == nil {
+		t.Fatalf("New(xml) error = nil, want error")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	next, err := logging.New(&buf, slog.LevelWarn, logging.FormatText)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	rec := logging.NewRecorder(next.Handler(), slog.LevelInfo)
+	logger := slog.New(rec).With("file", "a.wav")
+	logger.Debug("d")
+	logger.Info("input", "frames", 42)
+	logger.Warn("output clipped", "samples", 7)
+
+	// The wrapped handler keeps its own level.
+	if out := buf.String(); strings.Contains(out, "msg=input") || !strings.Contains(out, "msg=\"output clipped\"") {
+		t.Fatalf("forwarded output = %q, want only the warning", out)
+	}
+	records := rec.Records()
+	if len(records) != 2 || records[0].Message != "input" || records[1].Message != "output clipped" {
+		t.Fatalf("Records() = %v, want input and output clipped", records)
+	}
+	attrs := rec.Find("output clipped")
+	if attrs["samples"].Int64() != 7 || attrs["file"].String() != "a.wav" {
+		t.Fatalf("Find(output clipped) = %v", attrs)
+	}
+	if rec.Find("missing") != nil {
+		t.Fatalf("Find(missing) != nil")
+	}
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Recorder is a slog.Handler that keeps every record at or above its level
+// and passes the records the wrapped handler accepts on to it. The batch
+// command and --sidecar use it to collect the diagnostics of a file for
+// their records whatever the console log level.
+type Recorder struct {
+	next  slog.Handler
+	level slog.Level
+	attrs []slog.Attr
+	state *recorderState
+}
+
+type recorderState struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+// NewRecorder returns a Recorder keeping records at or above level and
+// forwarding to next.
+func NewRecorder(next slog.Handler, level slog.Level) *Recorder {
+	return &Recorder{next: next, level: level, state: &recorderState{}}
+}
+
+// Enabled implements slog.Handler.
+func (r *Recorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= r.level || r.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (r *Recorder) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= r.level {
+		kept := record.Clone()
+		kept.AddAttrs(r.attrs...)
+		r.state.mu.Lock()
+		r.state.records = append(r.state.records, kept)
+		r.state.mu.Unlock()
+	}
+	if r.next.Enabled(ctx, record.Level) {
+		return r.next.Handle(ctx, record)
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler. Groups are not tracked; recorded
+// attributes are flat.
+func (r *Recorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Recorder{
+		next:  r.next.WithAttrs(attrs),
+		level: r.level,
+		attrs: append(append([]slog.Attr(nil), r.attrs...), attrs...),
+		state: r.state,
+	}
+}
+
+// WithGroup implements slog.Handler.
+func (r *Recorder) WithGroup(name string) slog.Handler {
+	return &Recorder{next: r.next.WithGroup(name), level: r.level, attrs: r.attrs, state: r.state}
+}
+
+// Records returns the records kept so far, oldest first.
+func (r *Recorder) Records() []slog.Record {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return append([]slog.Record(nil), r.state.records...)
+}
+
+// Find returns the attributes of the first kept record with the given
+// message, or nil.
+func (r *Recorder) Find(msg string) map[string]slog.Value {
+	for _, record := range r.Records() {
+		if record.Message == msg {
+			attrs := make(map[string]slog.Value, record.NumAttrs())
+			record.Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a.Value
+				return true
+			})
+			return attrs
+		}
+	}
+	return nil
+}
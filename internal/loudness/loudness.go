@@ -0,0 +1,199 @@
+// Package loudness measures integrated loudness and true peak after ITU-R
+// BS.1770-4 and derives the gain that brings a set of files, such as the
+// tracks of an album, to a common target.
+package loudness
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const (
+	// stepSeconds is the hop of the gating blocks, which overlap by 75%.
+	stepSeconds = 0.1
+	// blockSteps is the length of a gating block (400 ms) in steps.
+	blockSteps = 4
+	// absoluteGate is the absolute gating threshold in LUFS.
+	absoluteGate = -70.0
+	// relativeGate is the relative gating threshold in LU below the
+	// loudness of the blocks above the absolute gate.
+	relativeGate = -10.0
+)
+
+// SurroundWeight is the BS.1770 channel weight of the surround channels.
+const SurroundWeight = 1.41
+
+// QuadWeights returns the channel weights of a decoded quad output (LF, RF,
+// LB, RB) with channels channels: the backs count as surrounds, and any
+// channels past the fourth, which are sums of the others, are ignored.
+func QuadWeights(channels int) []float64 {
+	weights := make([]float64, channels)
+	for ch := range weights {
+		switch {
+		case ch < 2:
+			weights[ch] = 1
+		case ch < 4:
+			weights[ch] = SurroundWeight
+		}
+	}
+	return weights
+}
+
+// Meter accumulates the BS.1770 measurement of one signal.
+type Meter struct {
+	weights []float64
+	filters [][2]sqmath.Biquad
+	peaks   []truePeak
+	// stepFrames is the length of a step; fill frames of the current one
+	// have been summed into energy.
+	stepFrames int
+	fill       int
+	energy     float64
+	// steps holds the weighted K-filtered energy of every complete step.
+	steps []float64
+}
+
+// NewMeter returns a meter for a signal at sampleRate with one weight per
+// channel.
+func NewMeter(sampleRate int, weights []float64) (*Meter, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("sample rate must be > 0, got %d", sampleRate)
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("at least one channel weight is required")
+	}
+	m := &Meter{
+		weights:    weights,
+		filters:    make([][2]sqmath.Biquad, len(weights)),
+		peaks:      make([]truePeak, len(weights)),
+		stepFrames: max(int(math.Round(stepSeconds*float64(sampleRate))), 1),
+	}
+	for ch := range m.filters {
+		m.filters[ch] = sqmath.NewKWeighting(float64(sampleRate))
+	}
+	return m, nil
+}
+
+// Add measures the next frames ([channel][frame]), one slice per weight.
+func (m *Meter) Add(frames [][]float64) error {
+	if len(frames) != len(m.weights) {
+		return fmt.Errorf("expected %d channels, got %d", len(m.weights), len(frames))
+	}
+	for i := range frames[0] {
+		for ch, x := range frames {
+			m.peaks[ch].add(x[i])
+			if m.weights[ch] == 0 {
+				continue
+			}
+			f := &m.filters[ch]
+			y := f[1].Process(f[0].Process(x[i]))
+			m.energy += m.weights[ch] * y * y
+		}
+		m.fill++
+		if m.fill == m.stepFrames {
+			m.steps = append(m.steps, m.energy)
+			m.energy, m.fill = 0, 0
+		}
+	}
+	return nil
+}
+
+// blocks returns the mean-square energy of every complete gating block.
+func (m *Meter) blocks() []float64 {
+	if len(m.steps) < blockSteps {
+		return nil
+	}
+	blocks := make([]float64, len(m.steps)-blockSteps+1)
+	for i := range blocks {
+		var sum float64
+		for _, e := range m.steps[i : i+blockSteps] {
+			sum += e
+		}
+		blocks[i] = sum / float64(blockSteps*m.stepFrames)
+	}
+	return blocks
+}
+
+// Integrated returns the gated integrated loudness in LUFS, or -Inf when no
+// block is above the absolute gate.
+func (m *Meter) Integrated() float64 {
+	return gatedLoudness(m.blocks())
+}
+
+// TruePeak returns the largest true peak of all channels in dBTP, or -Inf
+// for silence.
+func (m *Meter) TruePeak() float64 {
+	var peak float64
+	for _, p := range m.peaks {
+		peak = max(peak, p.peak)
+	}
+	return 20 * math.Log10(peak)
+}
+
+// blockLoudness returns the loudness of a block energy in LUFS.
+func blockLoudness(energy float64) float64 {
+	return -0.691 + 10*math.Log10(energy)
+}
+
+// gatedLoudness applies the absolute and the relative gate to blocks and
+// returns the loudness of the mean of the remaining ones.
+func gatedLoudness(blocks []float64) float64 {
+	mean := func(threshold float64) float64 {
+		var sum float64
+		n := 0
+		for _, e := range blocks {
+			if blockLoudness(e) > threshold {
+				sum += e
+				n++
+			}
+		}
+		if n == 0 {
+			return 0
+		}
+		return sum / float64(n)
+	}
+	absolute := mean(absoluteGate)
+	if absolute == 0 {
+		return math.Inf(-1)
+	}
+	relative := blockLoudness(absolute) + relativeGate
+	return blockLoudness(mean(max(absoluteGate, relative)))
+}
+
+// Album is the normalization of a set of files to a common target.
+type Album struct {
+	// LoudnessLUFS is the integrated loudness of all files together, gated
+	// as one programme, and TruePeakDBTP their largest true peak.
+	LoudnessLUFS float64
+	TruePeakDBTP float64
+	// GainDB is the gain applied to every file: the difference to the
+	// target, reduced so that the true peak stays at or below the ceiling.
+	GainDB float64
+	// Limited reports whether the ceiling reduced the gain.
+	Limited bool
+}
+
+// AlbumGain returns the single gain that brings the files measured by
+// meters to targetLUFS as a whole without their true peak exceeding
+// ceilingDBTP. Keeping one gain for all files preserves their loudness
+// relative to each other. A silent album gets no gain.
+func AlbumGain(meters []*Meter, targetLUFS, ceilingDBTP float64) Album {
+	var blocks []float64
+	album := Album{TruePeakDBTP: math.Inf(-1)}
+	for _, m := range meters {
+		blocks = append(blocks, m.blocks()...)
+		album.TruePeakDBTP = max(album.TruePeakDBTP, m.TruePeak())
+	}
+	album.LoudnessLUFS = gatedLoudness(blocks)
+	if math.IsInf(album.LoudnessLUFS, -1) {
+		return album
+	}
+	album.GainDB = targetLUFS - album.LoudnessLUFS
+	if headroom := ceilingDBTP - album.TruePeakDBTP; album.GainDB > headroom {
+		album.GainDB = headroom
+		album.Limited = true
+	}
+	return album
+}
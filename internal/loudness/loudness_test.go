@@ -0,0 +1,132 @@
+package loudness_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/loudness"
+)
+
+const rate = 48000
+
+// sine returns seconds of a freq Hz sine of peak amplitude level, starting
+// at phase.
+func sine(freq, level, phase, seconds float64) []float64 {
+	x := make([]float64, int(seconds*rate))
+	for i := range x {
+		x[i] = level * math.Sin(2*math.Pi*freq*float64(i)/rate+phase)
+	}
+	return x
+}
+
+// measure returns a meter over a stereo signal with left and right.
+func measure(t *testing.T, left, right []float64) *loudness.Meter {
+	t.Helper()
+	m, err := loudness.NewMeter(rate, []float64{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Feed in uneven chunks; the result must not depend on them.
+	for start := 0; start < len(left); start += 7001 {
+		end := min(start+7001, len(left))
+		if err := m.Add([][]float64{left[start:end], right[start:end]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestMeter_IntegratedSine(t *testing.T) {
+	t.Parallel()
+
+	// BS.1770: a 0 dBFS 997 Hz sine in one channel reads -3.01 LUFS.
+	silent := make([]float64, 5*rate)
+	for _, level := range []float64{1, 0.5, 0.1} {
+		m := measure(t, sine(997, level, 0, 5), silent)
+		want := -3.01 + 20*math.Log10(level)
+		if got := m.Integrated(); math.Abs(got-want) > 0.05 {
+			t.Fatalf("level %g: Integrated() = %.3f LUFS, want %.3f", level, got, want)
+		}
+	}
+	// In both channels it reads 0 LUFS.
+	both := sine(997, 1, 0, 5)
+	if got := measure(t, both, both).Integrated(); math.Abs(got) > 0.05 {
+		t.Fatalf("stereo 0 dBFS sine: Integrated() = %.3f LUFS, want 0", got)
+	}
+}
+
+func TestMeter_Gating(t *testing.T) {
+	t.Parallel()
+
+	if got := measure(t, make([]float64, rate), make([]float64, rate)).Integrated(); !math.IsInf(got, -1) {
+		t.Fatalf("silence: Integrated() = %g, want -Inf", got)
+	}
+	// A quiet passage 30 dB down is below the relative gate: of the 400 ms
+	// blocks only the 37 within the loud passage and the three straddling
+	// the transition, with 3/4, 1/2 and 1/4 of its energy, count.
+	loud := sine(997, 0.5, 0, 4)
+	quiet := sine(997, 0.5*math.Pow(10, -30.0/20), 0, 4)
+	m := measure(t, append(loud, quiet...), make([]float64, 8*rate))
+	want := -3.01 + 20*math.Log10(0.5) + 10*math.Log10((37+0.75+0.5+0.25)/40)
+	if got := m.Integrated(); math.Abs(got-want) > 0.1 {
+		t.Fatalf("loud + quiet: Integrated() = %.3f LUFS, want %.3f", got, want)
+	}
+}
+
+func TestMeter_TruePeak(t *testing.T) {
+	t.Parallel()
+
+	// A full-scale sine at a quarter of the sample rate, sampled 45° off
+	// its peaks: the samples reach only -3 dBFS, the waveform 0 dBTP.
+	x := sine(rate/4, 1, math.Pi/4, 1)
+	sampled := 0.0
+	for _, v := range x {
+		sampled = max(sampled, math.Abs(v))
+	}
+	if math.Abs(20*math.Log10(sampled)+3.01) > 0.01 {
+		t.Fatalf("sample peak = %g, test signal broken", sampled)
+	}
+	if got := measure(t, x, x).TruePeak(); math.Abs(got) > 0.3 {
+		t.Fatalf("TruePeak() = %.2f dBTP, want ~0", got)
+	}
+}
+
+func TestAlbumGain(t *testing.T) {
+	t.Parallel()
+
+	silent := make([]float64, 4*rate)
+	track := func(offsetDB float64) *loudness.Meter {
+		return measure(t, sine(997, 0.25*math.Pow(10, offsetDB/20), 0, 4), silent)
+	}
+	reference := -3.01 + 20*math.Log10(0.25)
+
+	// Tracks 0 and -6 dB: album loudness is that of their mean power, the
+	// gain brings it to the target and keeps the 6 dB between them.
+	album := loudness.AlbumGain([]*loudness.Meter{track(0), track(-6)}, -18, -1)
+	wantLoudness := reference + 10*math.Log10((1+math.Pow(10, -0.6))/2)
+	if math.Abs(album.LoudnessLUFS-wantLoudness) > 0.05 {
+		t.Fatalf("album loudness = %.3f LUFS, want %.3f", album.LoudnessLUFS, wantLoudness)
+	}
+	if math.Abs(album.GainDB-(-18-wantLoudness)) > 0.05 || album.Limited {
+		t.Fatalf("album gain = %.3f dB (limited %t), want %.3f", album.GainDB, album.Limited, -18-wantLoudness)
+	}
+
+	// A track 20 dB down falls below the relative gate of the album and
+	// does not pull the gain up.
+	album = loudness.AlbumGain([]*loudness.Meter{track(0), track(-20)}, -18, -1)
+	if math.Abs(album.LoudnessLUFS-reference) > 0.05 {
+		t.Fatalf("album loudness with a quiet track = %.3f LUFS, want %.3f", album.LoudnessLUFS, reference)
+	}
+
+	// A target of 0 LUFS would push the -12 dBTP peak above the -1 dBTP
+	// ceiling, so the gain is limited to 11 dB.
+	album = loudness.AlbumGain([]*loudness.Meter{track(0)}, 0, -1)
+	peak := 20 * math.Log10(0.25)
+	if !album.Limited || math.Abs(album.GainDB-(-1-peak)) > 0.05 {
+		t.Fatalf("limited album gain = %.3f dB (limited %t), want %.3f", album.GainDB, album.Limited, -1-peak)
+	}
+
+	if album := loudness.AlbumGain([]*loudness.Meter{measure(t, silent, silent)}, -18, -1); album.GainDB != 0 {
+		t.Fatalf("silent album gain = %g dB, want 0", album.GainDB)
+	}
+}
@@ -0,0 +1,62 @@
+package loudness
+
+import "math"
+
+const (
+	// oversampling is the true-peak oversampling factor of BS.1770 for
+	// 48 kHz input.
+	oversampling = 4
+	// peakTaps is the interpolator length per phase.
+	peakTaps = 12
+)
+
+// peakKernel holds the polyphase interpolator over the last peakTaps
+// inputs, newest first: peakKernel[p] yields the point (p+1)/oversampling
+// of a sample before input peakTaps/2-1. It is a Blackman-windowed sinc,
+// normalized to unity gain per phase.
+var peakKernel = func() [oversampling - 1][peakTaps]float64 {
+	var kernel [oversampling - 1][peakTaps]float64
+	const center = peakTaps/2 - 1
+	for p := range kernel {
+		var sum float64
+		for k := range kernel[p] {
+			// Distance of input k (newest first) from the interpolated
+			// point, in input samples.
+			d := float64(k-center) - float64(p+1)/oversampling
+			u := d / (peakTaps / 2)
+			w := 0.42 + 0.5*math.Cos(math.Pi*u) + 0.08*math.Cos(2*math.Pi*u)
+			v := w
+			if d != 0 {
+				v *= math.Sin(math.Pi*d) / (math.Pi * d)
+			}
+			kernel[p][k] = v
+			sum += v
+		}
+		for k := range kernel[p] {
+			kernel[p][k] /= sum
+		}
+	}
+	return kernel
+}()
+
+// truePeak tracks the largest absolute value of one channel oversampled by
+// oversampling, which catches the inter-sample peaks a DAC reconstructs.
+type truePeak struct {
+	// history holds the last peakTaps inputs, newest at pos.
+	history [peakTaps]float64
+	pos     int
+	peak    float64
+}
+
+func (t *truePeak) add(x float64) {
+	t.pos = (t.pos + peakTaps - 1) % peakTaps
+	t.history[t.pos] = x
+	t.peak = max(t.peak, math.Abs(x))
+	for p := range peakKernel {
+		var y float64
+		for k, h := range peakKernel[p] {
+			y += h * t.history[(t.pos+k)%peakTaps]
+		}
+		t.peak = max(t.peak, math.Abs(y))
+	}
+}
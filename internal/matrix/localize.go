@@ -0,0 +1,130 @@
+package matrix
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// SpeakerAngles are the nominal playback angles of LF, RF, LB and RB in
+// degrees: 0 is center front, positive angles are to the right and ±180 is
+// center back.
+var SpeakerAngles = [4]float64{-45, 45, -135, 135}
+
+// Localization is the analytic position of a source in an encoded LT/RT
+// pair, assuming a perfect 90° shifter.
+type Localization struct {
+	// LT and RT are the encoded phasors of the source.
+	LT, RT complex128
+	// Angle is the playback angle in degrees (see SpeakerAngles) of the
+	// constant-power panned source the matrix encodes most like LT/RT.
+	Angle float64
+	// Deviation is the distance in degrees on the Scheiber sphere between
+	// LT/RT and that panned source; 0 means the source is encoded exactly
+	// as a pan to Angle, larger values mean the position is ambiguous.
+	Deviation float64
+}
+
+// PanGains returns the LF, RF, LB, RB gains that place a source at angle
+// degrees with a constant-power pan between the two adjacent speakers.
+func PanGains(angle float64) [4]float64 {
+	angle = wrapAngle(angle)
+	// The speakers in playback order around the listener, clockwise from
+	// LB, so that every angle falls between two consecutive entries.
+	ring := [5]int{2, 0, 1, 3, 2}
+	var gains [4]float64
+	for i := 0; i < 4; i++ {
+		from := SpeakerAngles[ring[i]]
+		if angle < from || angle > from+90 {
+			continue
+		}
+		x := (angle - from) / 90 * math.Pi / 2
+		gains[ring[i]] = math.Cos(x)
+		gains[ring[i+1]] = math.Sin(x)
+		return gains
+	}
+	// Between RB (135°) and LB (-135° = 225°) across center back.
+	if angle < 0 {
+		angle += 360
+	}
+	x := (angle - 135) / 90 * math.Pi / 2
+	gains[3] = math.Cos(x)
+	gains[2] = math.Sin(x)
+	return gains
+}
+
+// Localize encodes a source with the LF, RF, LB, RB gains through encode
+// ([2][4]) and finds its nominal playback angle. The angle is that of the
+// panned source (PanGains) whose LT/RT amplitude ratio and phase difference
+// are closest to the source's, searched in 0.1° steps.
+func Localize(encode Coefficients, gains []float64) (Localization, error) {
+	if len(encode) != 2 {
+		return Localization{}, fmt.Errorf("encode matrix has %d rows, want 2", len(encode))
+	}
+	if len(gains) != 4 {
+		return Localization{}, fmt.Errorf("got %d gains, want 4 (LF, RF, LB, RB)", len(gains))
+	}
+	lt, rt, err := encodePhasors(encode, gains)
+	if err != nil {
+		return Localization{}, err
+	}
+	target, ok := sphere(lt, rt)
+	if !ok {
+		return Localization{}, fmt.Errorf("source encodes to silence")
+	}
+
+	best := Localization{LT: lt, RT: rt, Deviation: math.Inf(1)}
+	for step := 0; step < 3600; step++ {
+		angle := float64(step)/10 - 180
+		pan := PanGains(angle)
+		plt, prt, _ := encodePhasors(encode, pan[:])
+		point, ok := sphere(plt, prt)
+		if !ok {
+			continue
+		}
+		dot := target[0]*point[0] + target[1]*point[1] + target[2]*point[2]
+		deviation := math.Acos(math.Max(-1, math.Min(1, dot))) * 180 / math.Pi
+		if deviation < best.Deviation {
+			best.Angle = angle
+			best.Deviation = deviation
+		}
+	}
+	return best, nil
+}
+
+func encodePhasors(encode Coefficients, gains []float64) (lt, rt complex128, err error) {
+	for i, row := range encode {
+		if len(row) != len(gains) {
+			return 0, 0, fmt.Errorf("row %d has %d columns, want %d", i, len(row), len(gains))
+		}
+	}
+	for ch, g := range gains {
+		lt += encode[0][ch] * complex(g, 0)
+		rt += encode[1][ch] * complex(g, 0)
+	}
+	return lt, rt, nil
+}
+
+// sphere returns the normalized Stokes parameters of the pair: the LT/RT
+// balance, the in-phase and the quadrature correlation. Every coherent
+// LT/RT pair lies on the unit (Scheiber) sphere; the overall level and
+// phase drop out.
+func sphere(lt, rt complex128) ([3]float64, bool) {
+	lp := real(lt)*real(lt) + imag(lt)*imag(lt)
+	rp := real(rt)*real(rt) + imag(rt)*imag(rt)
+	power := lp + rp
+	if power < 1e-24 {
+		return [3]float64{}, false
+	}
+	cross := lt * cmplx.Conj(rt)
+	return [3]float64{(lp - rp) / power, 2 * real(cross) / power, 2 * imag(cross) / power}, true
+}
+
+// wrapAngle maps degrees into [-180, 180).
+func wrapAngle(angle float64) float64 {
+	angle = math.Mod(angle+180, 360)
+	if angle < 0 {
+		angle += 360
+	}
+	return angle - 180
+}
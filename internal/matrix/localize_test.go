@@ -0,0 +1,46 @@
+package matrix_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/matrix"
+)
+
+func TestLocalize_LFIsFrontLeft(t *testing.T) {
+	t.Parallel()
+
+	loc, err := matrix.Localize(matrix.SQ().Encode, []float64{1, 0, 0, 0})
+	if err != nil {
+		t.Fatalf("Localize() error = %v", err)
+	}
+	if loc.Angle != matrix.SpeakerAngles[0] || loc.Deviation > 1e-6 {
+		t.Fatalf("LF localizes to %g° (deviation %g°), want %g°", loc.Angle, loc.Deviation, matrix.SpeakerAngles[0])
+	}
+	if loc.LT != 1 || loc.RT != 0 {
+		t.Fatalf("LT, RT = %v, %v, want 1, 0", loc.LT, loc.RT)
+	}
+}
+
+func TestLocalize_PannedSources(t *testing.T) {
+	t.Parallel()
+
+	sq := matrix.SQ().Encode
+	for _, angle := range []float64{-180, -135, -100, -45, 0, 30, 45, 90, 135, 170} {
+		gains := matrix.PanGains(angle)
+		loc, err := matrix.Localize(sq, gains[:])
+		if err != nil {
+			t.Fatalf("Localize(%g°) error = %v", angle, err)
+		}
+		if math.Abs(loc.Angle-angle) > 0.05 || loc.Deviation > 1e-6 {
+			t.Fatalf("source at %g° localizes to %g° (deviation %g°)", angle, loc.Angle, loc.Deviation)
+		}
+	}
+
+	if _, err := matrix.Localize(sq, []float64{0, 0, 0, 0}); err == nil {
+		t.Fatalf("Localize() expected error for a silent source")
+	}
+	if _, err := matrix.Localize(sq, []float64{1, 0}); err == nil {
+		t.Fatalf("Localize() expected error for two gains")
+	}
+}
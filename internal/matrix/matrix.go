@@ -0,0 +1,202 @@
+package matrix
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strings"
+)
+
+// Coefficients is a complex mixing matrix indexed [output][input].
+// A real entry is a plain gain; an imaginary entry denotes a Hilbert-shifted
+// (90°) term, so an entry of j·g contributes g·H(x) under an ideal shifter.
+type Coefficients [][]complex128
+
+// Preset bundles the encode and decode matrices of a matrix system.
+type Preset struct {
+	Name        string
+	Description string
+	// Encode maps LF, RF, LB, RB to LT, RT ([2][4]).
+	Encode Coefficients
+	// Decode maps LT, RT to LF, RF, LB, RB ([4][2]).
+	Decode Coefficients
+}
+
+// SQ returns the basic CBS SQ matrix as implemented by the encoder and
+// decoder packages (no logic steering).
+func SQ() Preset {
+	a := complex(math.Sqrt(2.0)/2.0, 0)
+	ja := complex(0, math.Sqrt(2.0)/2.0)
+	return Preset{
+		Name:        "sq",
+		Description: "CBS SQ basic matrix (LF/RF discrete, backs via ±90° terms)",
+		Encode: Coefficients{
+			// LT = LF + 0.707·RB - 0.707·H(LB)
+			{1, 0, -ja, a},
+			// RT = RF - 0.707·LB + 0.707·H(RB)
+			{0, 1, -a, ja},
+		},
+		Decode: Coefficients{
+			// LF = LT
+			{1, 0},
+			// RF = RT
+			{0, 1},
+			// LB = 0.707·H(LT) - 0.707·RT
+			{ja, -a},
+			// RB = 0.707·LT - 0.707·H(RT)
+			{a, -ja},
+		},
+	}
+}
+
+// QS returns the Sansui QS Regular Matrix as implemented by the encoder and
+// decoder packages with decoder.MatrixQS (no Vario-Matrix steering).
+func QS() Preset {
+	c := math.Cos(math.Pi / 8)
+	s := math.Sin(math.Pi / 8)
+	rc, rs := complex(c, 0), complex(s, 0)
+	jc, js := complex(0, c), complex(0, s)
+	return Preset{
+		Name:        "qs",
+		Description: "Sansui QS regular matrix (all channels at 22.5°, backs via ±90° terms)",
+		Encode: Coefficients{
+			// LT = 0.924·LF + 0.383·RF + 0.924·H(LB) + 0.383·H(RB)
+			{rc, rs, jc, js},
+			// RT = 0.383·LF + 0.924·RF - 0.383·H(LB) - 0.924·H(RB)
+			{rs, rc, -js, -jc},
+		},
+		Decode: Coefficients{
+			// LF = 0.924·LT + 0.383·RT
+			{rc, rs},
+			// RF = 0.383·LT + 0.924·RT
+			{rs, rc},
+			// LB = -0.924·H(LT) + 0.383·H(RT)
+			{-jc, js},
+			// RB = -0.383·H(LT) + 0.924·H(RT)
+			{-js, jc},
+		},
+	}
+}
+
+// Presets returns all known matrix presets.
+func Presets() []Preset {
+	return []Preset{SQ(), QS()}
+}
+
+// Lookup returns the preset with the given (case-insensitive) name.
+func Lookup(name string) (Preset, error) {
+	names := make([]string, 0, len(Presets()))
+	for _, preset := range Presets() {
+		if strings.EqualFold(preset.Name, name) {
+			return preset, nil
+		}
+		names = append(names, preset.Name)
+	}
+	return Preset{}, fmt.Errorf("unknown matrix %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Multiply returns the matrix product a·b.
+func Multiply(a, b Coefficients) (Coefficients, error) {
+	if len(a) == 0 || len(b) == 0 {
+		return nil, fmt.Errorf("empty matrix")
+	}
+	inner := len(b)
+	cols := len(b[0])
+	for i, row := range a {
+		if len(row) != inner {
+			return nil, fmt.Errorf("row %d has %d columns, want %d", i, len(row), inner)
+		}
+	}
+	for i, row := range b {
+		if len(row) != cols {
+			return nil, fmt.Errorf("row %d has %d columns, want %d", i, len(row), cols)
+		}
+	}
+
+	out := make(Coefficients, len(a))
+	for i := range a {
+		out[i] = make([]complex128, cols)
+		for j := 0; j < cols; j++ {
+			var sum complex128
+			for k := 0; k < inner; k++ {
+				sum += a[i][k] * b[k][j]
+			}
+			out[i][j] = sum
+		}
+	}
+	return out, nil
+}
+
+// SingularValues returns the singular values of c in descending order.
+// Matrix systems map two channels to four or back, so c may have at most
+// two rows or two columns; its singular values are then the square roots
+// of the eigenvalues of the 2x2 (or 1x1) Gram matrix.
+func SingularValues(c Coefficients) ([]float64, error) {
+	if len(c) == 0 || len(c[0]) == 0 {
+		return nil, fmt.Errorf("empty matrix")
+	}
+	for i, row := range c {
+		if len(row) != len(c[0]) {
+			return nil, fmt.Errorf("row %d has %d columns, want %d", i, len(row), len(c[0]))
+		}
+	}
+
+	// Gram matrix of the smaller dimension: c^H·c over columns, or c·c^H
+	// over rows.
+	k := min(len(c), len(c[0]))
+	if k > 2 {
+		return nil, fmt.Errorf("%dx%d matrix: singular values need at most 2 rows or columns", len(c), len(c[0]))
+	}
+	gram := func(i, j int) complex128 {
+		var sum complex128
+		if k == len(c[0]) {
+			for _, row := range c {
+				sum += cmplx.Conj(row[i]) * row[j]
+			}
+		} else {
+			for col := range c[0] {
+				sum += c[i][col] * cmplx.Conj(c[j][col])
+			}
+		}
+		return sum
+	}
+
+	if k == 1 {
+		return []float64{math.Sqrt(real(gram(0, 0)))}, nil
+	}
+	// Eigenvalues of the Hermitian [[p, q], [q*, r]].
+	p, r, q := real(gram(0, 0)), real(gram(1, 1)), gram(0, 1)
+	mean := (p + r) / 2
+	radius := math.Hypot((p-r)/2, cmplx.Abs(q))
+	return []float64{math.Sqrt(mean + radius), math.Sqrt(max(mean-radius, 0))}, nil
+}
+
+// ConditionNumber returns the ratio of the largest to the smallest
+// singular value of c: the worst-case amplification of a relative error in
+// the input, such as noise in one direction of the stereo pair, relative to
+// the signal. It is 1 for a matrix whose columns are orthogonal with equal
+// norm and +Inf for a singular one.
+func ConditionNumber(c Coefficients) (float64, error) {
+	sv, err := SingularValues(c)
+	if err != nil {
+		return 0, err
+	}
+	if sv[len(sv)-1] == 0 {
+		return math.Inf(1), nil
+	}
+	return sv[0] / sv[len(sv)-1], nil
+}
+
+// FormatCoefficient renders a coefficient with a "j" suffix on the
+// Hilbert-shifted part, e.g. "1.000", "-0.707j" or "0.500+0.500j".
+func FormatCoefficient(c complex128) string {
+	re, im := real(c), imag(c)
+	switch {
+	case im == 0:
+		return fmt.Sprintf("%.3f", re)
+	case re == 0:
+		return fmt.Sprintf("%.3fj", im)
+	default:
+		return fmt.Sprintf("%.3f%+.3fj", re, im)
+	}
+}
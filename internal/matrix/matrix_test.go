@@ -0,0 +1,130 @@
+package matrix_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/matrix"
+)
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	preset, err := matrix.Lookup("SQ")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if preset.Name != "sq" {
+		t.Fatalf("Name = %q, want sq", preset.Name)
+	}
+	if _, err := matrix.Lookup("nope"); err == nil {
+		t.Fatalf("Lookup() expected error for unknown matrix")
+	}
+}
+
+func TestMultiply_SQFrontPassThrough(t *testing.T) {
+	t.Parallel()
+
+	sq := matrix.SQ()
+	product, err := matrix.Multiply(sq.Decode, sq.Encode)
+	if err != nil {
+		t.Fatalf("Multiply() error = %v", err)
+	}
+	if len(product) != 4 || len(product[0]) != 4 {
+		t.Fatalf("product shape = %dx%d, want 4x4", len(product), len(product[0]))
+	}
+
+	// Each channel comes back at unity gain with an ideal shifter.
+	for i := 0; i < 4; i++ {
+		if got := cmplx.Abs(product[i][i]); got < 1-1e-12 || got > 1+1e-12 {
+			t.Fatalf("|product[%d][%d]| = %.12f, want 1", i, i, got)
+		}
+	}
+
+	if _, err := matrix.Multiply(sq.Encode, sq.Encode); err == nil {
+		t.Fatalf("Multiply() expected error for mismatched shapes")
+	}
+}
+
+// TestMultiply_QSRoundTrip checks the QS preset against the textbook
+// figures: every channel returns at unity gain, the neighbours at -3 dB
+// and the diagonal opposite not at all.
+func TestMultiply_QSRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	qs := matrix.QS()
+	product, err := matrix.Multiply(qs.Decode, qs.Encode)
+	if err != nil {
+		t.Fatalf("Multiply() error = %v", err)
+	}
+	for out := range 4 {
+		for in := range 4 {
+			want := math.Sqrt(0.5)
+			switch {
+			case out == in:
+				want = 1
+			case out == 3-in:
+				want = 0
+			}
+			if got := cmplx.Abs(product[out][in]); math.Abs(got-want) > 1e-12 {
+				t.Fatalf("|product[%d][%d]| = %.12f, want %.12f", out, in, got, want)
+			}
+		}
+	}
+}
+
+func TestFormatCoefficient(t *testing.T) {
+	t.Parallel()
+
+	tests := map[complex128]string{
+		1:                   "1.000",
+		complex(0, -0.5):    "-0.500j",
+		complex(0.25, 0.75): "0.250+0.750j",
+	}
+	for in, want := range tests {
+		if got := matrix.FormatCoefficient(in); got != want {
+			t.Fatalf("FormatCoefficient(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestConditionNumber(t *testing.T) {
+	t.Parallel()
+
+	// The SQ decode matrix has orthogonal columns of norm sqrt(2):
+	// |1|² + |0.707j|² + |0.707|² = 2 and the cross terms of the back rows,
+	// (0.707j)*·(-0.707) + 0.707*·(-0.707j), cancel.
+	sq := matrix.SQ()
+	sv, err := matrix.SingularValues(sq.Decode)
+	if err != nil {
+		t.Fatalf("SingularValues() error = %v", err)
+	}
+	for i, v := range sv {
+		if math.Abs(v-math.Sqrt2) > 1e-12 {
+			t.Fatalf("singular value %d = %.15f, want sqrt(2)", i, v)
+		}
+	}
+	if got, err := matrix.ConditionNumber(sq.Decode); err != nil || math.Abs(got-1) > 1e-12 {
+		t.Fatalf("ConditionNumber(SQ decode) = %.15f, %v, want 1", got, err)
+	}
+
+	// [[1, 1], [0, 1]] has Gram matrix [[1, 1], [1, 2]] with eigenvalues
+	// (3 ± sqrt(5))/2, so its condition number is (3 + sqrt(5))/2.
+	shear := matrix.Coefficients{{1, 1}, {0, 1}}
+	if got, _ := matrix.ConditionNumber(shear); math.Abs(got-(3+math.Sqrt(5))/2) > 1e-12 {
+		t.Fatalf("ConditionNumber(shear) = %.15f, want %.15f", got, (3+math.Sqrt(5))/2)
+	}
+
+	// The transpose has the same singular values.
+	if got, _ := matrix.ConditionNumber(sq.Encode); math.Abs(got-1) > 1e-12 {
+		t.Fatalf("ConditionNumber(SQ encode) = %.15f, want 1", got)
+	}
+
+	if got, _ := matrix.ConditionNumber(matrix.Coefficients{{1, 1}, {1, 1}}); !math.IsInf(got, 1) {
+		t.Fatalf("ConditionNumber(singular) = %g, want +Inf", got)
+	}
+	if _, err := matrix.ConditionNumber(matrix.Coefficients{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}); err == nil {
+		t.Fatalf("ConditionNumber(3x3) expected error")
+	}
+}
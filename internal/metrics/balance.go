@@ -0,0 +1,46 @@
+package metrics
+
+import "math"
+
+// BalanceReport holds the level balance of a quad channel set.
+type BalanceReport struct {
+	// ChannelRMS is the RMS of LF, RF, LB and RB.
+	ChannelRMS [4]float64
+	// LeftRightDB is the level of LF+LB over RF+RB in dB; positive means
+	// the left side is louder.
+	LeftRightDB float64
+	// FrontBackDB is the level of LF+RF over LB+RB in dB; positive means
+	// the front is louder.
+	FrontBackDB float64
+}
+
+// Balance returns the left/right and front/back RMS ratios of a 4-channel
+// set (LF, RF, LB, RB); channels beyond the fourth are ignored and missing
+// ones count as silent. The sides are compared by their total energy, so a
+// quiet back pair does not skew the left/right ratio. A silent side gives
+// ±Inf, two silent sides 0 dB.
+func Balance(channels [][]float64) BalanceReport {
+	var report BalanceReport
+	var energy [4]float64
+	for ch := range min(len(channels), 4) {
+		report.ChannelRMS[ch] = rms(channels[ch])
+		energy[ch] = report.ChannelRMS[ch] * report.ChannelRMS[ch]
+	}
+	report.LeftRightDB = balanceDB(energy[0]+energy[2], energy[1]+energy[3])
+	report.FrontBackDB = balanceDB(energy[0]+energy[1], energy[2]+energy[3])
+	return report
+}
+
+// balanceDB returns the ratio of two energies in dB.
+func balanceDB(a, b float64) float64 {
+	const floor = separationEpsilon * separationEpsilon
+	switch {
+	case a <= floor && b <= floor:
+		return 0
+	case b <= floor:
+		return math.Inf(1)
+	case a <= floor:
+		return math.Inf(-1)
+	}
+	return 10 * math.Log10(a/b)
+}
@@ -0,0 +1,45 @@
+package metrics_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func TestBalance_LeftBias(t *testing.T) {
+	t.Parallel()
+
+	// Equal tones on all four channels, the left ones 3 dB hotter.
+	quad := testsignal.QuadTones(44100, 44100, 0.3, 0)
+	gain := math.Pow(10, 3.0/20)
+	for _, ch := range []int{0, 2} {
+		for i := range quad[ch] {
+			quad[ch][i] *= gain
+		}
+	}
+
+	report := metrics.Balance(quad)
+	if math.Abs(report.LeftRightDB-3) > 0.05 {
+		t.Fatalf("LeftRightDB = %.3f, want 3", report.LeftRightDB)
+	}
+	if math.Abs(report.FrontBackDB) > 0.05 {
+		t.Fatalf("FrontBackDB = %.3f, want 0", report.FrontBackDB)
+	}
+}
+
+func TestBalance_SilentSides(t *testing.T) {
+	t.Parallel()
+
+	front := [][]float64{{1, -1}, {1, -1}, {0, 0}, {0, 0}}
+	if got := metrics.Balance(front); got.LeftRightDB != 0 || !math.IsInf(got.FrontBackDB, 1) {
+		t.Fatalf("Balance(front only) = %+v, want 0 dB and +Inf", got)
+	}
+	if got := metrics.Balance(make([][]float64, 4)); got.LeftRightDB != 0 || got.FrontBackDB != 0 {
+		t.Fatalf("Balance(silence) = %+v, want 0 dB", got)
+	}
+	if got := metrics.Balance([][]float64{{0, 0}, {1, 1}}); !math.IsInf(got.LeftRightDB, -1) {
+		t.Fatalf("Balance(right only) LeftRightDB = %v, want -Inf", got.LeftRightDB)
+	}
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import "math"
+
+// Band is a frequency band in Hz.
+type Band struct {
+	Low, Center, High float64
+}
+
+// OctaveBands returns the octave bands centered on 31.25 Hz·2^k below the
+// Nyquist frequency of sampleRate; the top band is cut at Nyquist.
+func OctaveBands(sampleRate int) []Band {
+	nyquist := float64(sampleRate) / 2
+	var bands []Band
+	for center := 31.25; center < nyquist; center *= 2 {
+		bands = append(bands, Band{Low: center / math.Sqrt2, Center: center, High: math.Min(center*math.Sqrt2, nyquist)})
+	}
+	return bands
+}
+
+// BandSeparation returns the separation in dB of the target channel in each
+// band, as ChannelSeparation measures it with the band as FMin/FMax. Every
+// channel is transformed once for all bands, zero-padded to a power of 2.
+// options.SampleRate must be set; its FMin and FMax are ignored.
+func BandSeparation(decoded [][]float64, target int, bands []Band, options SeparationOptions) []float64 {
+	seps := make([]float64, len(bands))
+	if target < 0 || target >= len(decoded) || options.SampleRate <= 0 {
+		return seps
+	}
+
+	// rms[ch][band]
+	rms := make([][]float64, len(decoded))
+	for ch, samples := range decoded {
+		rms[ch] = make([]float64, len(bands))
+		nfft := 1
+		for nfft < len(samples) {
+			nfft *= 2
+		}
+		power := powerSpectrum(samples, options.AnalysisWindow, nfft)
+		binHz := float64(options.SampleRate) / float64(nfft)
+		for b, band := range bands {
+			sum := 0.0
+			for k, p := range power {
+				if f := float64(k) * binHz; f >= band.Low && f < band.High {
+					sum += p
+				}
+			}
+			rms[ch][b] = math.Sqrt(sum)
+		}
+	}
+
+	for b := range bands {
+		var leak float64
+		leakCount := 0
+		for ch := range decoded {
+			if ch == target {
+				continue
+			}
+			if options.LeakMode == LeakModeAvg {
+				leak += rms[ch][b]
+				leakCount++
+			} else {
+				leak = math.Max(leak, rms[ch][b])
+			}
+		}
+		if leakCount > 0 {
+			leak /= float64(leakCount)
+		}
+		seps[b] = separationDB(rms[target][b], leak)
+	}
+	return seps
+}
+
+// SeparationOverTime returns the separation in dB of the target channel in
+// consecutive windows of windowFrames; a last partial window counts if it
+// is at least half as long.
+func SeparationOverTime(decoded [][]float64, target, windowFrames int, options SeparationOptions) []float64 {
+	if target < 0 || target >= len(decoded) || windowFrames <= 0 {
+		return nil
+	}
+	var seps []float64
+	n := len(decoded[target])
+	for start := 0; start < n; start += windowFrames {
+		end := min(start+windowFrames, n)
+		if end-start < windowFrames/2 && start > 0 {
+			break
+		}
+		window := make([][]float64, len(decoded))
+		for ch := range decoded {
+			window[ch] = decoded[ch][start:end]
+		}
+		seps = append(seps, ChannelSeparation(window, target, options).SeparationDB)
+	}
+	return seps
+}
+
+// Crosstalk returns the level in dB of every channel of decoded relative
+// to the target channel: 0 for the target, -Inf for a silent channel.
+// Band limits in options apply.
+func Crosstalk(decoded [][]float64, target int, options SeparationOptions) []float64 {
+	levels := make([]float64, len(decoded))
+	if target < 0 || target >= len(decoded) {
+		return levels
+	}
+	targetRMS := rmsWithOptions(decoded[target], options)
+	for ch, samples := range decoded {
+		switch r := rmsWithOptions(samples, options); {
+		case ch == target:
+			levels[ch] = 0
+		case r <= separationEpsilon:
+			levels[ch] = math.Inf(-1)
+		case targetRMS <= separationEpsilon:
+			levels[ch] = math.Inf(1)
+		default:
+			levels[ch] = 20 * math.Log10(r/targetRMS)
+		}
+	}
+	return levels
+}
@@ -0,0 +1,102 @@
+package metrics_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+)
+
+func TestOctaveBands(t *testing.T) {
+	t.Parallel()
+
+	bands := metrics.OctaveBands(44100)
+	if len(bands) != 10 {
+		t.Fatalf("OctaveBands(44100) = %d bands, want 10 (31.25 Hz to 16 kHz)", len(bands))
+	}
+	if c := bands[5].Center; c != 1000 {
+		t.Fatalf("band 5 center = %g, want 1000", c)
+	}
+	for i := 1; i < len(bands); i++ {
+		if math.Abs(bands[i].Low-bands[i-1].High) > 1e-9 {
+			t.Fatalf("band %d starts at %g, previous ends at %g", i, bands[i].Low, bands[i-1].High)
+		}
+	}
+	if top := bands[len(bands)-1].High; top != 22050 {
+		t.Fatalf("top band ends at %g, want Nyquist", top)
+	}
+}
+
+// tone returns n samples of a sine with the given amplitude and frequency.
+func tone(n, sampleRate int, amp, freq float64) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = amp * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate))
+	}
+	return out
+}
+
+func TestBandSeparation(t *testing.T) {
+	t.Parallel()
+
+	// The target has 125 Hz and 1 kHz; the leak only 125 Hz, 20 dB down.
+	// Both tones fall on FFT bins of the power-of-2 length, so nothing
+	// leaks across bands.
+	const rate, n = 8192, 8192
+	target := tone(n, rate, 1, 125)
+	for i, v := range tone(n, rate, 1, 1000) {
+		target[i] += v
+	}
+	decoded := [][]float64{target, tone(n, rate, 0.1, 125), make([]float64, n), make([]float64, n)}
+
+	bands := metrics.OctaveBands(rate)
+	seps := metrics.BandSeparation(decoded, 0, bands, metrics.SeparationOptions{SampleRate: rate})
+	for i, band := range bands {
+		switch band.Center {
+		case 125:
+			if math.Abs(seps[i]-20) > 1e-6 {
+				t.Fatalf("125 Hz band separation = %g, want 20", seps[i])
+			}
+		case 1000:
+			if !math.IsInf(seps[i], 1) {
+				t.Fatalf("1 kHz band separation = %g, want +Inf", seps[i])
+			}
+		default:
+			if seps[i] != 0 {
+				t.Fatalf("empty %g Hz band separation = %g, want 0", band.Center, seps[i])
+			}
+		}
+	}
+}
+
+func TestSeparationOverTime(t *testing.T) {
+	t.Parallel()
+
+	// The leak is 20 dB down in the first second and 40 dB in the second.
+	const rate = 8000
+	target := tone(2*rate, rate, 1, 500)
+	leak := tone(2*rate, rate, 0.1, 500)
+	for i := rate; i < 2*rate; i++ {
+		leak[i] /= 10
+	}
+	decoded := [][]float64{target, leak}
+
+	seps := metrics.SeparationOverTime(decoded, 0, rate, metrics.SeparationOptions{})
+	if len(seps) != 2 || math.Abs(seps[0]-20) > 1e-6 || math.Abs(seps[1]-40) > 1e-6 {
+		t.Fatalf("SeparationOverTime() = %v, want [20 40]", seps)
+	}
+	// A trailing window shorter than half is dropped.
+	if got := metrics.SeparationOverTime(decoded, 0, rate*9/10, metrics.SeparationOptions{}); len(got) != 2 {
+		t.Fatalf("SeparationOverTime() with 0.9 s windows = %d windows, want 2", len(got))
+	}
+}
+
+func TestCrosstalk(t *testing.T) {
+	t.Parallel()
+
+	decoded := [][]float64{{1, -1}, {0.1, -0.1}, {0, 0}, {0.5, -0.5}}
+	got := metrics.Crosstalk(decoded, 0, metrics.SeparationOptions{})
+	if got[0] != 0 || math.Abs(got[1]+20) > 1e-9 || !math.IsInf(got[2], -1) || math.Abs(got[3]+20*math.Log10(2)) > 1e-9 {
+		t.Fatalf("Crosstalk() = %v, want [0 -20 -Inf -6.02]", got)
+	}
+}
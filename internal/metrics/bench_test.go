@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+func BenchmarkBandRMS(b *testing.B) {
+	const sampleRate = 44100
+	samples := testsignal.QuadTones(sampleRate, 5*sampleRate, 0.4, 0.05)[0]
+	for _, window := range []sqmath.WindowType{sqmath.WindowRectangular, sqmath.WindowHann} {
+		b.Run(string(window), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(8 * len(samples)))
+			for b.Loop() {
+				bandRMS(samples, sampleRate, 80, 8000, window)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import "math"
+
+// DefaultImageWindow is the image report window length in seconds.
+const DefaultImageWindow = 10.0
+
+// ImageWindow describes the sound image of a decoded LF, RF, LB, RB signal
+// over one report window.
+type ImageWindow struct {
+	// Start and Duration locate the window in seconds.
+	Start    float64
+	Duration float64
+	// LeftRight is the energy centroid from -100 (all left) to +100 (all
+	// right); FrontBack runs from -100 (all back) to +100 (all front).
+	LeftRight float64
+	FrontBack float64
+	// Width is the inter-channel decorrelation in percent: 0 for a single
+	// source panned anywhere, 100 for unrelated content in every channel.
+	Width float64
+	// RMS is the overall level of the window, averaged over the channels.
+	RMS float64
+}
+
+// imagePairs lists the channel pairs whose correlation makes up the width.
+var imagePairs = [6][2]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}
+
+// ImageAnalyzer accumulates the image report of a decoded quad stream. Add
+// takes the output in arbitrary chunks; window boundaries do not need to
+// line up with them.
+type ImageAnalyzer struct {
+	windowFrames int
+	sampleRate   int
+	windows      []ImageWindow
+	frames       int
+	started      int
+	// energy is the per-channel sum of squares of the current window and
+	// cross the sum of products for each of imagePairs.
+	energy [4]float64
+	cross  [6]float64
+}
+
+// NewImageAnalyzer returns an analyzer for windows of windowSeconds. Values
+// <= 0 select DefaultImageWindow.
+func NewImageAnalyzer(sampleRate int, windowSeconds float64) *ImageAnalyzer {
+	if windowSeconds <= 0 {
+		windowSeconds = DefaultImageWindow
+	}
+	return &ImageAnalyzer{
+		windowFrames: max(int(windowSeconds*float64(sampleRate)), 1),
+		sampleRate:   max(sampleRate, 1),
+	}
+}
+
+// Add analyzes the next frames of LF, RF, LB, RB ([channel][frame]).
+// Further channels are ignored.
+func (a *ImageAnalyzer) Add(frames [][]float64) {
+	if len(frames) < 4 {
+		return
+	}
+	for i := range frames[0] {
+		for ch := range a.energy {
+			v := frames[ch][i]
+			a.energy[ch] += v * v
+		}
+		for p, pair := range imagePairs {
+			a.cross[p] += frames[pair[0]][i] * frames[pair[1]][i]
+		}
+		a.frames++
+		if a.frames-a.started == a.windowFrames {
+			a.closeWindow()
+		}
+	}
+}
+
+// Windows returns the report, including a final partial window.
+func (a *ImageAnalyzer) Windows() []ImageWindow {
+	if a.frames > a.started {
+		a.closeWindow()
+	}
+	return a.windows
+}
+
+func (a *ImageAnalyzer) closeWindow() {
+	n := a.frames - a.started
+	w := ImageWindow{
+		Start:    float64(a.started) / float64(a.sampleRate),
+		Duration: float64(n) / float64(a.sampleRate),
+	}
+	lf, rf, lb, rb := a.energy[0], a.energy[1], a.energy[2], a.energy[3]
+	total := lf + rf + lb + rb
+	if total > 0 {
+		w.LeftRight = 100 * (rf + rb - lf - lb) / total
+		w.FrontBack = 100 * (lf + rf - lb - rb) / total
+		w.RMS = math.Sqrt(total / float64(4*n))
+	}
+
+	// Weight each pair by its geometric mean energy, so that near-silent
+	// channels do not dominate the width.
+	var weighted, weights float64
+	for p, pair := range imagePairs {
+		weight := math.Sqrt(a.energy[pair[0]] * a.energy[pair[1]])
+		if weight <= separationEpsilon {
+			continue
+		}
+		weighted += weight * (1 - math.Abs(a.cross[p])/weight)
+		weights += weight
+	}
+	if weights > 0 {
+		w.Width = 100 * weighted / weights
+	}
+
+	a.windows = append(a.windows, w)
+	a.started = a.frames
+	a.energy = [4]float64{}
+	a.cross = [6]float64{}
+}
+
+// ImageReport returns the image report of a decoded LF, RF, LB, RB signal.
+func ImageReport(decoded [][]float64, sampleRate int, windowSeconds float64) []ImageWindow {
+	a := NewImageAnalyzer(sampleRate, windowSeconds)
+	a.Add(decoded)
+	return a.Windows()
+}
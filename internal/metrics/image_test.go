@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+)
+
+const imageRate = 1000
+
+func noise(rng *rand.Rand, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = rng.Float64()*2 - 1
+	}
+	return out
+}
+
+// panned places one source in the quad field with the given channel gains.
+func panned(source []float64, gains [4]float64) [][]float64 {
+	out := make([][]float64, 4)
+	for ch := range out {
+		out[ch] = make([]float64, len(source))
+		for i, v := range source {
+			out[ch][i] = gains[ch] * v
+		}
+	}
+	return out
+}
+
+func TestImageReport_PannedSources(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	source := noise(rng, 10*imageRate)
+	c, s := math.Cos(math.Pi/8), math.Sin(math.Pi/8)
+
+	tests := []struct {
+		name                 string
+		gains                [4]float64
+		leftRight, frontBack float64
+	}{
+		{"LF", [4]float64{1, 0, 0, 0}, -100, 100},
+		{"RB", [4]float64{0, 0, 0, 1}, 100, -100},
+		{"center front", [4]float64{1, 1, 0, 0}, 0, 100},
+		{"center", [4]float64{1, 1, 1, 1}, 0, 0},
+		// Constant power pan a quarter of the way from LF to RF.
+		{"LF-RF 22.5deg", [4]float64{c, s, 0, 0}, -100 * math.Cos(math.Pi/4), 100},
+		{"left side", [4]float64{0.5, 0, -0.5, 0}, -100, 0},
+	}
+	for _, tt := range tests {
+		windows := metrics.ImageReport(panned(source, tt.gains), imageRate, 0)
+		if len(windows) != 1 {
+			t.Fatalf("%s: %d windows, want 1", tt.name, len(windows))
+		}
+		w := windows[0]
+		if math.Abs(w.LeftRight-tt.leftRight) > 1e-9 || math.Abs(w.FrontBack-tt.frontBack) > 1e-9 {
+			t.Fatalf("%s: centroid (%g, %g), want (%g, %g)", tt.name, w.LeftRight, w.FrontBack, tt.leftRight, tt.frontBack)
+		}
+		// A single source is fully correlated, whatever its polarity.
+		if w.Width > 1e-9 {
+			t.Fatalf("%s: width = %g, want 0", tt.name, w.Width)
+		}
+	}
+}
+
+func TestImageReport_UncorrelatedIsWide(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(2))
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = noise(rng, 10*imageRate)
+	}
+	w := metrics.ImageReport(quad, imageRate, 0)[0]
+	if math.Abs(w.LeftRight) > 3 || math.Abs(w.FrontBack) > 3 {
+		t.Fatalf("centroid (%g, %g), want ~(0, 0)", w.LeftRight, w.FrontBack)
+	}
+	if w.Width < 95 {
+		t.Fatalf("width = %g, want ~100", w.Width)
+	}
+}
+
+func TestImageAnalyzer_WindowsAndChunks(t *testing.T) {
+	t.Parallel()
+
+	// 25 s moving from LF to RB after 15 s.
+	rng := rand.New(rand.NewSource(3))
+	source := noise(rng, 25*imageRate)
+	quad := panned(source, [4]float64{1, 0, 0, 1})
+	for i := range source {
+		if i < 15*imageRate {
+			quad[3][i] = 0
+		} else {
+			quad[0][i] = 0
+		}
+	}
+
+	whole := metrics.ImageReport(quad, imageRate, 10)
+	if len(whole) != 3 {
+		t.Fatalf("%d windows, want 3", len(whole))
+	}
+	if whole[2].Start != 20 || whole[2].Duration != 5 {
+		t.Fatalf("last window at %gs for %gs, want 20s for 5s", whole[2].Start, whole[2].Duration)
+	}
+	if math.Abs(whole[0].LeftRight+100) > 1e-9 || math.Abs(whole[2].LeftRight-100) > 1e-9 {
+		t.Fatalf("left-right = %g .. %g, want -100 .. 100", whole[0].LeftRight, whole[2].LeftRight)
+	}
+	if lr := whole[1].LeftRight; math.Abs(lr) > 10 {
+		t.Fatalf("middle window left-right = %g, want ~0", lr)
+	}
+
+	a := metrics.NewImageAnalyzer(imageRate, 10)
+	for start := 0; start < len(source); start += 777 {
+		end := min(start+777, len(source))
+		chunk := make([][]float64, 4)
+		for ch := range chunk {
+			chunk[ch] = quad[ch][start:end]
+		}
+		a.Add(chunk)
+	}
+	chunked := a.Windows()
+	for i := range whole {
+		if math.Abs(chunked[i].LeftRight-whole[i].LeftRight) > 1e-9 || math.Abs(chunked[i].Width-whole[i].Width) > 1e-9 {
+			t.Fatalf("window %d: chunked %+v, want %+v", i, chunked[i], whole[i])
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package metrics
+
+import (
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+)
+
+// RoundTripLatency returns the latency in samples of an encode -> decode
+// chain with the given block size and overlap: the sum of the encoder's and
+// the decoder's GetLatency. It is the figure to compensate for when the
+// decoded audio has to stay in sync with a picture or another track.
+func RoundTripLatency(blockSize, overlap int) int {
+	return encoder.LatencyFor(blockSize, overlap) + decoder.LatencyFor(blockSize, overlap)
+}
@@ -0,0 +1,20 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+)
+
+func TestRoundTripLatency(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range [][2]int{{1024, 512}, {2048, 512}, {4096, 4096}, {64, 3}} {
+		want := encoder.NewSQEncoderWithParams(p[0], p[1]).GetLatency() + decoder.NewSQDecoderWithParams(p[0], p[1]).GetLatency()
+		if got := metrics.RoundTripLatency(p[0], p[1]); got != want {
+			t.Fatalf("RoundTripLatency(%d, %d) = %d, want %d", p[0], p[1], got, want)
+		}
+	}
+}
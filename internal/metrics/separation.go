@@ -4,6 +4,7 @@ import (
 	"math"
 
 	algofft "github.com/MeKo-Christian/algo-fft"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
 )
 
 const separationEpsilon = 1e-12
@@ -28,6 +29,9 @@ type SeparationOptions struct {
 	SampleRate int
 	FMin       float64
 	FMax       float64
+	// AnalysisWindow is applied before the band-limited FFT to reduce
+	// spectral leakage across the band edges. Empty means rectangular.
+	AnalysisWindow sqmath.WindowType
 }
 
 // ChannelSeparation computes RMS-based separation for a target channel.
@@ -84,6 +88,28 @@ func ChannelPairSeparation(decoded [][]float64, target, leak int, options Separa
 	}
 }
 
+// FrontBackSeparation returns the front-to-back separation in dB of a
+// decoded LF, RF, LB, RB signal: the RMS of the front pair over the RMS of
+// the back pair. Left and right are discrete in SQ, so this is the figure SQ
+// decoders are traditionally specified by. Measure it on a front-panned
+// source; for a back-panned source the negated value is the back-to-front
+// separation. Band limits in options apply; LeakMode is not used.
+func FrontBackSeparation(decoded [][]float64, options SeparationOptions) float64 {
+	if len(decoded) < 4 {
+		return 0
+	}
+	pairRMS := func(a, b []float64) float64 {
+		ra, rb := rmsWithOptions(a, options), rmsWithOptions(b, options)
+		return math.Sqrt((ra*ra + rb*rb) / 2)
+	}
+	front := pairRMS(decoded[0], decoded[1])
+	back := pairRMS(decoded[2], decoded[3])
+	if back > separationEpsilon && front <= separationEpsilon {
+		return math.Inf(-1)
+	}
+	return separationDB(front, back)
+}
+
 func separationDB(targetRMS, leakRMS float64) float64 {
 	if leakRMS > separationEpsilon && targetRMS > separationEpsilon {
 		return 20.0 * math.Log10(targetRMS/leakRMS)
@@ -101,7 +127,7 @@ func rmsWithOptions(samples []float64, options SeparationOptions) float64 {
 	if options.SampleRate <= 0 {
 		return 0
 	}
-	return bandRMS(samples, options.SampleRate, options.FMin, options.FMax)
+	return bandRMS(samples, options.SampleRate, options.FMin, options.FMax, options.AnalysisWindow)
 }
 
 func rms(samples []float64) float64 {
@@ -115,9 +141,11 @@ func rms(samples []float64) float64 {
 	return math.Sqrt(sum / float64(len(samples)))
 }
 
-func bandRMS(samples []float64, sampleRate int, fmin, fmax float64) float64 {
-	n := len(samples)
-	if n == 0 || sampleRate <= 0 {
+// bandRMS returns the RMS of the spectral content between fmin and fmax.
+// The power is normalized by the window energy, so a broadband stationary
+// signal measures the same RMS with any window.
+func bandRMS(samples []float64, sampleRate int, fmin, fmax float64, windowType sqmath.WindowType) float64 {
+	if len(samples) == 0 || sampleRate <= 0 {
 		return 0
 	}
 	if fmin < 0 {
@@ -131,34 +159,106 @@ func bandRMS(samples []float64, sampleRate int, fmin, fmax float64) float64 {
 		return 0
 	}
 
+	power := powerSpectrum(samples, windowType, len(samples))
+	sumPow := 0.0
+	nFloat := float64(len(samples))
+	for k, p := range power {
+		freqHz := float64(k) * float64(sampleRate) / nFloat
+		if freqHz >= fmin && freqHz <= fmax {
+			sumPow += p
+		}
+	}
+	return math.Sqrt(sumPow)
+}
+
+// powerSpectrum returns the one-sided power of samples per bin of an FFT of
+// size n >= len(samples), bins 0 to n/2, windowed and normalized so that
+// the bins sum to the mean square of a stationary signal. Zero-padding to
+// a larger n only interpolates the spectrum. It returns nil when the FFT
+// cannot be planned.
+func powerSpectrum(samples []float64, windowType sqmath.WindowType, n int) []float64 {
 	plan, err := algofft.NewPlan64(n)
 	if err != nil {
-		return 0
+		return nil
+	}
+
+	if windowType == "" {
+		windowType = sqmath.WindowRectangular
+	}
+	window, err := sqmath.Window(windowType, len(samples))
+	if err != nil {
+		return nil
+	}
+	windowPower := 0.0
+	for _, w := range window {
+		windowPower += w * w
+	}
+	if windowPower == 0 {
+		return nil
 	}
 
 	input := make([]complex128, n)
 	for i, v := range samples {
-		input[i] = complex(v, 0)
+		input[i] = complex(v*window[i], 0)
 	}
 	freq := make([]complex128, n)
 	if err := plan.Forward(freq, input); err != nil {
-		return 0
+		return nil
 	}
 
-	sumPow := 0.0
-	nFloat := float64(n)
-	for k := 0; k <= n/2; k++ {
-		freqHz := float64(k) * float64(sampleRate) / nFloat
-		if freqHz < fmin || freqHz > fmax {
-			continue
-		}
-		power := real(freq[k])*real(freq[k]) + imag(freq[k])*imag(freq[k])
+	power := make([]float64, n/2+1)
+	scale := 1 / (float64(n) * windowPower)
+	for k := range power {
+		p := (real(freq[k])*real(freq[k]) + imag(freq[k])*imag(freq[k])) * scale
 		if k == 0 || k == n/2 {
-			sumPow += power
+			power[k] = p
 		} else {
-			sumPow += 2.0 * power
+			power[k] = 2 * p
 		}
 	}
+	return power
+}
 
-	return math.Sqrt(sumPow / (nFloat * nFloat))
+// RecoverySeparation measures how well recovered reproduces original. The
+// recovered signal is split into its least-squares projection onto
+// original (the target) and the residual (the leak), so gain errors do not
+// count as crosstalk but leakage that cancels or inverts the target does.
+// recovered[i] is compared against original[i+shift].
+func RecoverySeparation(original, recovered []float64, shift int) SeparationResult {
+	var cross, origEnergy, recEnergy float64
+	n := 0
+	for i := 0; i+shift < len(original) && i < len(recovered); i++ {
+		if i+shift < 0 {
+			continue
+		}
+		o := original[i+shift]
+		r := recovered[i]
+		cross += o * r
+		origEnergy += o * o
+		recEnergy += r * r
+		n++
+	}
+	if n == 0 || origEnergy <= 0 {
+		return SeparationResult{}
+	}
+
+	gain := cross / origEnergy
+	targetEnergy := 0.0
+	if gain > 0 {
+		// Inverted output is not a recovery of the original.
+		targetEnergy = gain * cross
+	}
+	leakEnergy := math.Max(recEnergy-targetEnergy, 0)
+	targetRMS := math.Sqrt(targetEnergy / float64(n))
+	leakRMS := math.Sqrt(leakEnergy / float64(n))
+
+	sep := separationDB(targetRMS, leakRMS)
+	if targetRMS <= separationEpsilon && leakRMS > separationEpsilon {
+		sep = math.Inf(-1)
+	}
+	return SeparationResult{
+		TargetRMS:    targetRMS,
+		LeakRMS:      leakRMS,
+		SeparationDB: sep,
+	}
 }
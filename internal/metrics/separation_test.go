@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
 )
 
 func TestChannelSeparation(t *testing.T) {
@@ -30,3 +31,114 @@ func TestChannelSeparation(t *testing.T) {
 		t.Fatalf("SeparationDB = %.9f, want 20.0", result.SeparationDB)
 	}
 }
+
+func TestChannelPairSeparation_AnalysisWindowNearBandEdge(t *testing.T) {
+	t.Parallel()
+
+	const (
+		n          = 8192
+		sampleRate = 48000
+		amplitude  = 0.5
+		fmin       = 500.0
+		fmax       = 1050.0
+	)
+
+	tone := func(freq float64) [][]float64 {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = amplitude * math.Sin(2.0*math.Pi*freq*float64(i)/sampleRate)
+		}
+		return [][]float64{x, make([]float64, n)}
+	}
+	measure := func(decoded [][]float64, window sqmath.WindowType) float64 {
+		options := metrics.SeparationOptions{SampleRate: sampleRate, FMin: fmin, FMax: fmax, AnalysisWindow: window}
+		return metrics.ChannelPairSeparation(decoded, 0, 1, options).TargetRMS
+	}
+
+	// A tone just inside the upper band edge, between FFT bins.
+	wantRMS := amplitude / math.Sqrt2
+	inBand := tone(1030.7)
+	rectErr := math.Abs(measure(inBand, "") - wantRMS)
+	hannErr := math.Abs(measure(inBand, sqmath.WindowHann) - wantRMS)
+	if hannErr >= rectErr {
+		t.Fatalf("in-band error: hann %.3g, rect %.3g; want hann smaller", hannErr, rectErr)
+	}
+	if hannErr > 1e-4 {
+		t.Fatalf("in-band error with hann = %.3g, want <= 1e-4", hannErr)
+	}
+
+	// A tone just outside the band edge should barely register.
+	outOfBand := tone(1070)
+	rectLeak := measure(outOfBand, "")
+	hannLeak := measure(outOfBand, sqmath.WindowHann)
+	if hannLeak*10 >= rectLeak {
+		t.Fatalf("out-of-band leak: hann %.3g, rect %.3g; want hann at least 10x smaller", hannLeak, rectLeak)
+	}
+}
+
+func TestRecoverySeparation(t *testing.T) {
+	t.Parallel()
+
+	original := []float64{0, 1, 0, -1, 0, 1, 0, -1}
+	// A quarter-period-shifted copy is orthogonal to original.
+	orthogonal := []float64{1, 0, -1, 0, 1, 0, -1, 0}
+
+	scaled := make([]float64, len(original))
+	inverted := make([]float64, len(original))
+	leaky := make([]float64, len(original))
+	for i := range original {
+		scaled[i] = 0.5 * original[i]
+		inverted[i] = -original[i]
+		leaky[i] = original[i] + 0.1*orthogonal[i]
+	}
+
+	if got := metrics.RecoverySeparation(original, scaled, 0).SeparationDB; !math.IsInf(got, 1) {
+		t.Fatalf("scaled: SeparationDB = %v, want +Inf", got)
+	}
+	if got := metrics.RecoverySeparation(original, inverted, 0).SeparationDB; !math.IsInf(got, -1) {
+		t.Fatalf("inverted: SeparationDB = %v, want -Inf", got)
+	}
+	if got := metrics.RecoverySeparation(original, leaky, 0).SeparationDB; math.Abs(got-20.0) > 1e-9 {
+		t.Fatalf("leaky: SeparationDB = %.9f, want 20.0", got)
+	}
+	// recovered[i] is compared against original[i+shift].
+	if got := metrics.RecoverySeparation(original, original[1:], 1).SeparationDB; !math.IsInf(got, 1) {
+		t.Fatalf("shifted: SeparationDB = %v, want +Inf", got)
+	}
+}
+
+func TestFrontBackSeparation(t *testing.T) {
+	t.Parallel()
+
+	const n = 4800
+	tone := func(amplitude float64) []float64 {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = amplitude * math.Sin(2.0*math.Pi*float64(i)/48.0)
+		}
+		return x
+	}
+	options := metrics.SeparationOptions{}
+
+	// Front-only decode with a -40 dB residue in the back pair.
+	frontOnly := [][]float64{tone(1), tone(1), tone(0.01), tone(0.01)}
+	if got := metrics.FrontBackSeparation(frontOnly, options); math.Abs(got-40) > 1e-9 {
+		t.Fatalf("FrontBackSeparation(front only) = %.9f, want 40", got)
+	}
+	// One front channel against one back channel is the same ratio.
+	single := [][]float64{tone(1), tone(0), tone(0.01), tone(0)}
+	if got := metrics.FrontBackSeparation(single, options); math.Abs(got-40) > 1e-9 {
+		t.Fatalf("FrontBackSeparation(LF only) = %.9f, want 40", got)
+	}
+	silentBack := [][]float64{tone(1), tone(1), tone(0), tone(0)}
+	if got := metrics.FrontBackSeparation(silentBack, options); !math.IsInf(got, 1) {
+		t.Fatalf("FrontBackSeparation(silent back) = %v, want +Inf", got)
+	}
+	backOnly := [][]float64{tone(0.01), tone(0.01), tone(1), tone(1)}
+	if got := metrics.FrontBackSeparation(backOnly, options); math.Abs(got+40) > 1e-9 {
+		t.Fatalf("FrontBackSeparation(back only) = %.9f, want -40", got)
+	}
+	if got := metrics.FrontBackSeparation(frontOnly[:2], options); got != 0 {
+		t.Fatalf("FrontBackSeparation(2 channels) = %v, want 0", got)
+	}
+}
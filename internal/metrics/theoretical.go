@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"fmt"
+	"math/cmplx"
+
+	"github.com/cwbudde/go-sq-tool/internal/matrix"
+)
+
+// TheoreticalSeparation computes the ideal crosstalk of an encode -> decode
+// chain assuming a perfect 90° shifter. The result is indexed
+// [source][output] and holds the separation in dB between the source's own
+// output channel and the given output: 0 on the diagonal, +Inf where no
+// signal reaches the output.
+func TheoreticalSeparation(encodeMatrix, decodeMatrix matrix.Coefficients) ([][]float64, error) {
+	product, err := matrix.Multiply(decodeMatrix, encodeMatrix)
+	if err != nil {
+		return nil, fmt.Errorf("combine matrices: %w", err)
+	}
+	if len(product) != len(product[0]) {
+		return nil, fmt.Errorf("decode·encode must be square, got %dx%d", len(product), len(product[0]))
+	}
+
+	n := len(product)
+	result := make([][]float64, n)
+	for src := 0; src < n; src++ {
+		result[src] = make([]float64, n)
+		target := cmplx.Abs(product[src][src])
+		for out := 0; out < n; out++ {
+			leak := cmplx.Abs(product[out][src])
+			if out == src {
+				result[src][out] = 0
+				continue
+			}
+			result[src][out] = separationDB(target, leak)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package metrics_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/matrix"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+)
+
+func TestTheoreticalSeparation_SQ(t *testing.T) {
+	t.Parallel()
+
+	sq := matrix.SQ()
+	sep, err := metrics.TheoreticalSeparation(sq.Encode, sq.Decode)
+	if err != nil {
+		t.Fatalf("TheoreticalSeparation() error = %v", err)
+	}
+
+	const (
+		lf = 0
+		rf = 1
+		lb = 2
+		rb = 3
+	)
+	threeDB := 20.0 * math.Log10(math.Sqrt2)
+
+	// Published basic SQ figures: discrete front and back L/R pairs, 3 dB
+	// between adjacent front/back channels.
+	tests := []struct {
+		name     string
+		src, out int
+		want     float64
+	}{
+		{"LF->RF", lf, rf, math.Inf(1)},
+		{"RF->LF", rf, lf, math.Inf(1)},
+		{"LB->RB", lb, rb, math.Inf(1)},
+		{"RB->LB", rb, lb, math.Inf(1)},
+		{"LF->LB", lf, lb, threeDB},
+		{"LF->RB", lf, rb, threeDB},
+		{"LB->LF", lb, lf, threeDB},
+		{"RB->RF", rb, rf, threeDB},
+		{"LF->LF", lf, lf, 0},
+	}
+	for _, tt := range tests {
+		got := sep[tt.src][tt.out]
+		if math.IsInf(tt.want, 1) {
+			if !math.IsInf(got, 1) {
+				t.Errorf("%s = %.6f dB, want +Inf", tt.name, got)
+			}
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s = %.6f dB, want %.6f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTheoreticalSeparation_ShapeMismatch(t *testing.T) {
+	t.Parallel()
+
+	sq := matrix.SQ()
+	if _, err := metrics.TheoreticalSeparation(sq.Decode, sq.Decode); err == nil {
+		t.Fatalf("expected error for mismatched matrix shapes")
+	}
+}
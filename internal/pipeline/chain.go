@@ -0,0 +1,65 @@
+package pipeline
+
+// Chain returns a Processor that feeds the output of first into second,
+// where second needs secondLookahead frames past its output. Run the chain
+// with a Config whose Lookahead is the sum of both stages' lookaheads.
+//
+// The output is identical to running first over the whole signal and then
+// second over that result, provided secondLookahead and every non-final
+// numOutput are multiples of both stages' hop sizes.
+func Chain(first, second Processor, secondLookahead int) Processor {
+	var (
+		started  bool
+		buffered [][]float64
+	)
+	return func(input [][]float64, numOutput int) ([][]float64, error) {
+		frames := 0
+		if len(input) > 0 {
+			frames = len(input[0])
+		}
+
+		// The first stage has already produced secondLookahead frames past
+		// the previous output, so it continues that far into input.
+		skip, want := secondLookahead, numOutput
+		if !started {
+			skip, want = 0, numOutput+secondLookahead
+			started = true
+		}
+
+		// Zero-pad to the requested length like the stages do at the end of
+		// the signal.
+		firstIn := make([][]float64, len(input))
+		for ch := range input {
+			firstIn[ch] = make([]float64, max(frames-skip, want))
+			if frames > skip {
+				copy(firstIn[ch], input[ch][skip:])
+			}
+		}
+		out, err := first(firstIn, want)
+		if err != nil {
+			return nil, err
+		}
+
+		// A short input marks the end of the signal. Whatever the first
+		// stage rings out past it is discarded, because second would see
+		// zeros there when run over the whole signal.
+		if buffered == nil {
+			buffered = make([][]float64, len(out))
+		}
+		for ch := range out {
+			if end := frames - skip; end < len(out[ch]) {
+				clear(out[ch][max(end, 0):])
+			}
+			buffered[ch] = append(buffered[ch], out[ch]...)
+		}
+
+		result, err := second(buffered, numOutput)
+		if err != nil {
+			return nil, err
+		}
+		for ch := range buffered {
+			buffered[ch] = append([]float64(nil), buffered[ch][numOutput:]...)
+		}
+		return result, nil
+	}
+}
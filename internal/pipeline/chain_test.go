@@ -0,0 +1,76 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/dynamics"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+// captureWriter returns a float32 writer for 4 channels over buf.
+func captureWriter(tb testing.TB, buf *bytes.Buffer, numFrames int) *wav.Writer {
+	tb.Helper()
+
+	w, err := wav.NewWriter(buf, 44100, 4, numFrames, wav.WriteOptions{Format: wav.FormatFloat32})
+	if err != nil {
+		tb.Fatalf("NewWriter() error = %v", err)
+	}
+	return w
+}
+
+func TestChain_MatchesSequentialStages(t *testing.T) {
+	t.Parallel()
+
+	numSamples := 20*overlap + 123
+	quad := testsignal.QuadTones(44100, numSamples, 0.9, 0.05)
+	stereo := quad[:2]
+
+	newCompressor := func() *dynamics.Compressor {
+		c, err := dynamics.NewCompressor(dynamics.DefaultCompressorConfig(), 44100)
+		if err != nil {
+			t.Fatalf("NewCompressor() error = %v", err)
+		}
+		return c
+	}
+
+	decoded, err := newDecoder().Process(stereo)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	want, err := newCompressor().Process(decoded)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	for _, chunkFrames := range []int{2 * overlap, 16 * overlap} {
+		comp := newCompressor()
+		process := pipeline.Chain(newDecoder().ProcessSegment, comp.ProcessSegment, comp.Lookahead())
+		cfg := pipeline.Config{ChunkFrames: chunkFrames, Lookahead: blockSize - overlap + comp.Lookahead()}
+
+		var buf bytes.Buffer
+		w := captureWriter(t, &buf, numSamples)
+		if err := pipeline.Run(context.Background(), pipeline.NewSliceSource(stereo), 2, w, process, cfg); err != nil {
+			t.Fatalf("chunk %d: Run() error = %v", chunkFrames, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		got, err := wav.ReadWAVBytes(buf.Bytes(), 4)
+		if err != nil {
+			t.Fatalf("ReadWAVBytes() error = %v", err)
+		}
+		for ch := range want {
+			for i, v := range want[ch] {
+				if d := math.Abs(got.Samples[ch][i] - float64(float32(v))); d > 1e-6 {
+					t.Fatalf("chunk %d: channel %d sample %d differs by %g", chunkFrames, ch, i, d)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FanoutSink writes one stream to several sinks, such as the same output in
+// different formats. A sink whose write fails is dropped and its error
+// kept, so that the others still receive the whole stream; in strict mode
+// the first failure ends the stream instead.
+type FanoutSink struct {
+	sinks  []Sink
+	errs   []error
+	strict bool
+}
+
+// NewFanoutSink returns a Sink writing every frame to each of sinks.
+func NewFanoutSink(sinks []Sink, strict bool) (*FanoutSink, error) {
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("at least one sink is required")
+	}
+	return &FanoutSink{sinks: sinks, errs: make([]error, len(sinks)), strict: strict}, nil
+}
+
+// WriteFrames implements Sink. Every remaining sink receives the same
+// frames, which it must not modify. It fails on the first failing sink in
+// strict mode and otherwise only once every sink has failed.
+func (f *FanoutSink) WriteFrames(frames [][]float64) error {
+	remaining := 0
+	for i, sink := range f.sinks {
+		if f.errs[i] != nil {
+			continue
+		}
+		if err := sink.WriteFrames(frames); err != nil {
+			f.errs[i] = err
+			if f.strict {
+				return err
+			}
+			continue
+		}
+		remaining++
+	}
+	if remaining == 0 {
+		return errors.Join(f.errs...)
+	}
+	return nil
+}
+
+// Err returns the error that dropped sink i, or nil while it still
+// receives the stream.
+func (f *FanoutSink) Err(i int) error {
+	return f.errs[i]
+}
@@ -0,0 +1,100 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+// failingSink accepts frames until it has seen limit of them.
+type failingSink struct {
+	memorySink
+	limit int
+}
+
+var errSinkFull = errors.New("sink full")
+
+func (f *failingSink) WriteFrames(frames [][]float64) error {
+	if len(f.frames) > 0 && len(f.frames[0])+len(frames[0]) > f.limit {
+		return errSinkFull
+	}
+	return f.memorySink.WriteFrames(frames)
+}
+
+func TestFanoutSink_SinksReceiveIdenticalFrames(t *testing.T) {
+	t.Parallel()
+
+	numSamples := 20*overlap + 33
+	stereo := testsignal.QuadTones(44100, numSamples, 0.6, 0.05)[:2]
+	sinks := []*memorySink{{}, {}, {}}
+	fan, err := pipeline.NewFanoutSink([]pipeline.Sink{sinks[0], sinks[1], sinks[2]}, false)
+	if err != nil {
+		t.Fatalf("NewFanoutSink() error = %v", err)
+	}
+	cfg := pipeline.Config{ChunkFrames: 4 * overlap, Lookahead: blockSize - overlap}
+	if err := pipeline.Run(context.Background(), pipeline.NewSliceSource(stereo), 2, fan, newDecoder().ProcessSegment, cfg); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := sinks[0].frames
+	if len(want) != 4 || len(want[0]) != numSamples {
+		t.Fatalf("sink 0 got %d channels, want 4 of %d frames", len(want), numSamples)
+	}
+	for s, sink := range sinks[1:] {
+		for ch := range want {
+			for i := range want[ch] {
+				if sink.frames[ch][i] != want[ch][i] {
+					t.Fatalf("sink %d channel %d frame %d = %v, want %v", s+1, ch, i, sink.frames[ch][i], want[ch][i])
+				}
+			}
+		}
+	}
+}
+
+func TestFanoutSink_FailureKeepsOthers(t *testing.T) {
+	t.Parallel()
+
+	chunk := [][]float64{{1, 2, 3}, {4, 5, 6}}
+	good := &memorySink{}
+	bad := &failingSink{limit: 4}
+	fan, err := pipeline.NewFanoutSink([]pipeline.Sink{bad, good}, false)
+	if err != nil {
+		t.Fatalf("NewFanoutSink() error = %v", err)
+	}
+	for range 3 {
+		if err := fan.WriteFrames(chunk); err != nil {
+			t.Fatalf("WriteFrames() error = %v, want the failing sink dropped", err)
+		}
+	}
+	if !errors.Is(fan.Err(0), errSinkFull) || fan.Err(1) != nil {
+		t.Fatalf("Err() = %v, %v, want sink full and nil", fan.Err(0), fan.Err(1))
+	}
+	if len(good.frames[0]) != 9 || len(bad.frames[0]) != 3 {
+		t.Fatalf("got %d and %d frames, want 9 and 3", len(good.frames[0]), len(bad.frames[0]))
+	}
+
+	// Once every sink has failed, the stream fails.
+	only, _ := pipeline.NewFanoutSink([]pipeline.Sink{&failingSink{limit: 4}}, false)
+	only.WriteFrames(chunk)
+	if err := only.WriteFrames(chunk); !errors.Is(err, errSinkFull) {
+		t.Fatalf("WriteFrames() with no sink left error = %v, want sink full", err)
+	}
+}
+
+func TestFanoutSink_Strict(t *testing.T) {
+	t.Parallel()
+
+	chunk := [][]float64{{1, 2, 3}}
+	good := &memorySink{}
+	fan, _ := pipeline.NewFanoutSink([]pipeline.Sink{&failingSink{limit: 4}, good}, true)
+	fan.WriteFrames(chunk)
+	if err := fan.WriteFrames(chunk); !errors.Is(err, errSinkFull) {
+		t.Fatalf("strict WriteFrames() error = %v, want sink full", err)
+	}
+	if _, err := pipeline.NewFanoutSink(nil, false); err == nil {
+		t.Fatalf("NewFanoutSink() without sinks error = nil, want error")
+	}
+}
@@ -0,0 +1,26 @@
+package pipeline
+
+import "github.com/cwbudde/go-sq-tool/pkg/sqmath"
+
+// FilterProcessor returns process with filter applied to output channels
+// first and up. Each channel gets its own copy of filter, so the filter
+// state carries over from one call to the next.
+func FilterProcessor(process Processor, first int, filter sqmath.Biquad) Processor {
+	var filters []sqmath.Biquad
+	return func(input [][]float64, numOutput int) ([][]float64, error) {
+		output, err := process(input, numOutput)
+		if err != nil {
+			return nil, err
+		}
+		for ch := first; ch < len(output); ch++ {
+			for len(filters) <= ch-first {
+				filters = append(filters, filter)
+			}
+			f := &filters[ch-first]
+			for i, v := range output[ch] {
+				output[ch][i] = f.Process(v)
+			}
+		}
+		return output, nil
+	}
+}
@@ -0,0 +1,56 @@
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+func TestFilterProcessor_FiltersRearChannelsAcrossCalls(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, 1000, 0.2, 0.05)
+	shelf := sqmath.NewHighShelf(44100, 4000, -6, sqmath.ButterworthQ)
+	passThrough := func(input [][]float64, numOutput int) ([][]float64, error) {
+		output := make([][]float64, len(input))
+		for ch := range input {
+			output[ch] = append([]float64(nil), input[ch][:numOutput]...)
+		}
+		return output, nil
+	}
+	process := pipeline.FilterProcessor(passThrough, 2, shelf)
+
+	got := make([][]float64, 4)
+	for start := 0; start < 1000; start += 300 {
+		end := min(start+300, 1000)
+		chunk := make([][]float64, 4)
+		for ch := range chunk {
+			chunk[ch] = quad[ch][start:end]
+		}
+		output, err := process(chunk, end-start)
+		if err != nil {
+			t.Fatalf("process() error = %v", err)
+		}
+		for ch := range got {
+			got[ch] = append(got[ch], output[ch]...)
+		}
+	}
+
+	for ch := range got {
+		want := quad[ch]
+		if ch >= 2 {
+			f := shelf
+			want = make([]float64, len(quad[ch]))
+			for i, v := range quad[ch] {
+				want[i] = f.Process(v)
+			}
+		}
+		for i := range want {
+			if got[ch][i] != want[i] {
+				t.Fatalf("channel %d frame %d = %v, want %v", ch, i, got[ch][i], want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package pipeline
+
+import "github.com/cwbudde/go-sq-tool/internal/wav"
+
+// gainSource scales the frames of a Source as they are read.
+type gainSource struct {
+	src Source
+	db  float64
+}
+
+// GainSource returns src with every frame scaled by db decibels. The gain is
+// applied before the frames reach the processor, so it also applies to the
+// lookahead the processor sees.
+func GainSource(src Source, db float64) Source {
+	if db == 0 {
+		return src
+	}
+	return &gainSource{src: src, db: db}
+}
+
+// ReadFrames implements Source.
+func (g *gainSource) ReadFrames(dst [][]float64) (int, error) {
+	n, err := g.src.ReadFrames(dst)
+	if n > 0 {
+		frames := make([][]float64, len(dst))
+		for ch := range dst {
+			frames[ch] = dst[ch][:n]
+		}
+		wav.ApplyGain(frames, g.db)
+	}
+	return n, err
+}
+
+// GainProcessor returns process with its output scaled by db decibels.
+func GainProcessor(process Processor, db float64) Processor {
+	if db == 0 {
+		return process
+	}
+	return func(input [][]float64, numOutput int) ([][]float64, error) {
+		output, err := process(input, numOutput)
+		if err != nil {
+			return nil, err
+		}
+		wav.ApplyGain(output, db)
+		return output, nil
+	}
+}
@@ -0,0 +1,53 @@
+package pipeline_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func TestGain_InputGainScalesOutput(t *testing.T) {
+	t.Parallel()
+
+	numSamples := 20*overlap + 123
+	stereo := testsignal.QuadTones(44100, numSamples, 0.2, 0.05)[:2]
+	cfg := pipeline.Config{ChunkFrames: 4 * overlap, Lookahead: blockSize - overlap}
+
+	run := func(inputDB, outputDB float64) [][]float64 {
+		// The decoder is linear without logic steering.
+		d := newDecoder()
+		d.EnableLogicSteering(false)
+		src := pipeline.GainSource(pipeline.NewSliceSource(stereo), inputDB)
+		var sink memorySink
+		if err := pipeline.Run(context.Background(), src, 2, &sink, pipeline.GainProcessor(d.ProcessSegment, outputDB), cfg); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		return sink.frames
+	}
+
+	plain := run(0, 0)
+	boosted := run(6, 0)
+	restored := run(6, -6)
+
+	gain := math.Pow(10, 6.0/20)
+	var plainEnergy, boostedEnergy float64
+	for ch := range plain {
+		for i := range plain[ch] {
+			if math.Abs(boosted[ch][i]-gain*plain[ch][i]) > 1e-12 {
+				t.Fatalf("channel %d frame %d = %v, want %v", ch, i, boosted[ch][i], gain*plain[ch][i])
+			}
+			if math.Abs(restored[ch][i]-plain[ch][i]) > 1e-12 {
+				t.Fatalf("+6/-6 dB: channel %d frame %d = %v, want %v", ch, i, restored[ch][i], plain[ch][i])
+			}
+			plainEnergy += plain[ch][i] * plain[ch][i]
+			boostedEnergy += boosted[ch][i] * boosted[ch][i]
+		}
+	}
+	// +6 dB is a factor of 1.995 in amplitude.
+	if ratio := math.Sqrt(boostedEnergy / plainEnergy); math.Abs(ratio-2) > 0.01 {
+		t.Fatalf("level ratio = %.4f, want ~2", ratio)
+	}
+}
@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ConcatSource plays several sources back to back as one continuous
+// stream, so that stateful processors carry over from one to the next.
+type ConcatSource struct {
+	sources []Source
+}
+
+// NewConcatSource returns a Source reading each of sources to io.EOF in
+// order. All sources must have the same channel count.
+func NewConcatSource(sources ...Source) *ConcatSource {
+	return &ConcatSource{sources: sources}
+}
+
+// ReadFrames implements Source. A read never spans two sources; the next
+// call continues with the following one.
+func (c *ConcatSource) ReadFrames(dst [][]float64) (int, error) {
+	for len(c.sources) > 0 {
+		n, err := c.sources[0].ReadFrames(dst)
+		if errors.Is(err, io.EOF) {
+			c.sources = c.sources[1:]
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+	return 0, io.EOF
+}
+
+// SplitSink distributes a continuous stream over several sinks: the first
+// frames[0] frames go to sinks[0], the next frames[1] to sinks[1] and so on.
+type SplitSink struct {
+	sinks   []Sink
+	frames  []int
+	written int
+}
+
+// NewSplitSink returns a Sink splitting its input at the given frame
+// counts.
+func NewSplitSink(sinks []Sink, frames []int) (*SplitSink, error) {
+	if len(sinks) != len(frames) {
+		return nil, fmt.Errorf("got %d sinks for %d frame counts", len(sinks), len(frames))
+	}
+	for i, n := range frames {
+		if n < 0 {
+			return nil, fmt.Errorf("frame count %d is negative: %d", i, n)
+		}
+	}
+	return &SplitSink{sinks: sinks, frames: frames}, nil
+}
+
+// WriteFrames implements Sink. Writing more frames than the counts add up
+// to is an error.
+func (s *SplitSink) WriteFrames(frames [][]float64) error {
+	if len(frames) == 0 {
+		return nil
+	}
+	frames = append([][]float64(nil), frames...)
+	for len(frames[0]) > 0 {
+		for len(s.sinks) > 0 && s.written == s.frames[0] {
+			s.sinks, s.frames, s.written = s.sinks[1:], s.frames[1:], 0
+		}
+		if len(s.sinks) == 0 {
+			return fmt.Errorf("%d frames past the end of the last sink", len(frames[0]))
+		}
+
+		n := min(len(frames[0]), s.frames[0]-s.written)
+		part := make([][]float64, len(frames))
+		for ch := range frames {
+			part[ch] = frames[ch][:n]
+			frames[ch] = frames[ch][n:]
+		}
+		if err := s.sinks[0].WriteFrames(part); err != nil {
+			return err
+		}
+		s.written += n
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+// memorySink collects the frames written to it.
+type memorySink struct {
+	frames [][]float64
+}
+
+func (m *memorySink) WriteFrames(frames [][]float64) error {
+	if m.frames == nil {
+		m.frames = make([][]float64, len(frames))
+	}
+	for ch := range frames {
+		m.frames[ch] = append(m.frames[ch], frames[ch]...)
+	}
+	return nil
+}
+
+func TestJoin_SplitInputMatchesWhole(t *testing.T) {
+	t.Parallel()
+
+	numSamples := 30*overlap + 77
+	stereo := testsignal.QuadTones(44100, numSamples, 0.6, 0.05)[:2]
+	want, err := newDecoder().Process(stereo)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// Split off the middle of a chunk and not on a block boundary.
+	cut := 13*overlap + 201
+	first := [][]float64{stereo[0][:cut], stereo[1][:cut]}
+	second := [][]float64{stereo[0][cut:], stereo[1][cut:]}
+	sides := []*memorySink{{}, {}}
+	sink, err := pipeline.NewSplitSink([]pipeline.Sink{sides[0], sides[1]}, []int{cut, numSamples - cut})
+	if err != nil {
+		t.Fatalf("NewSplitSink() error = %v", err)
+	}
+
+	src := pipeline.NewConcatSource(pipeline.NewSliceSource(first), pipeline.NewSliceSource(second))
+	cfg := pipeline.Config{ChunkFrames: 4 * overlap, Lookahead: blockSize - overlap}
+	if err := pipeline.Run(context.Background(), src, 2, sink, newDecoder().ProcessSegment, cfg); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for side, wantLen := range []int{cut, numSamples - cut} {
+		if got := len(sides[side].frames[0]); got != wantLen {
+			t.Fatalf("side %d: %d frames, want %d", side, got, wantLen)
+		}
+	}
+	for ch := range want {
+		joined := append(append([]float64(nil), sides[0].frames[ch]...), sides[1].frames[ch]...)
+		for i := range want[ch] {
+			if joined[i] != want[ch][i] {
+				t.Fatalf("channel %d frame %d = %v, want %v", ch, i, joined[i], want[ch][i])
+			}
+		}
+	}
+}
+
+func TestSplitSink_RejectsExtraFrames(t *testing.T) {
+	t.Parallel()
+
+	sink, err := pipeline.NewSplitSink([]pipeline.Sink{&memorySink{}}, []int{3})
+	if err != nil {
+		t.Fatalf("NewSplitSink() error = %v", err)
+	}
+	if err := sink.WriteFrames([][]float64{{1, 2, 3, 4}}); err == nil {
+		t.Fatalf("WriteFrames() past the end error = nil, want error")
+	}
+	if _, err := pipeline.NewSplitSink([]pipeline.Sink{&memorySink{}}, nil); err == nil {
+		t.Fatalf("NewSplitSink() with mismatched counts error = nil, want error")
+	}
+}
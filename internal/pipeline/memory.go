@@ -0,0 +1,23 @@
+package pipeline
+
+// sampleBytes is the size of one float64 sample.
+const sampleBytes = 8
+
+// MemoryBytes returns an upper bound on the sample memory Run holds at once
+// with cfg. Each of the two queues holds QueueDepth chunks, and each stage
+// holds one more while it works on it: QueueDepth+2 input and QueueDepth+2
+// output chunks. The worker's pending input reaches two chunks plus the
+// lookahead, and it copies the unconsumed remainder of up to one chunk
+// plus lookahead before releasing the old one. Slice growth slack and the
+// processor's own state are not included.
+func (c Config) MemoryBytes(inChannels, outChannels int) int64 {
+	depth := c.QueueDepth
+	if depth <= 0 {
+		depth = DefaultQueueDepth
+	}
+	chunk := int64(c.ChunkFrames)
+	lookahead := int64(c.Lookahead)
+	inFrames := int64(depth+2)*chunk + (2*chunk + lookahead) + (chunk + lookahead)
+	outFrames := int64(depth+2) * chunk
+	return sampleBytes * (inFrames*int64(inChannels) + outFrames*int64(outChannels))
+}
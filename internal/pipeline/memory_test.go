@@ -0,0 +1,86 @@
+package pipeline_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+)
+
+// countingSource serves frames of silence up to total and tracks how many
+// frames were read but not yet written, together with countingSink.
+type countingSource struct {
+	mu                  sync.Mutex
+	read, written, peak int
+	total               int
+}
+
+func (s *countingSource) ReadFrames(dst [][]float64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(len(dst[0]), s.total-s.read)
+	if n == 0 {
+		return 0, io.EOF
+	}
+	s.read += n
+	// A chunk is held from the moment it is allocated for reading.
+	s.peak = max(s.peak, s.read-s.written+len(dst[0])-n)
+	return n, nil
+}
+
+type countingSink struct{ src *countingSource }
+
+func (w countingSink) WriteFrames(frames [][]float64) error {
+	// A slow writer lets the queues fill up.
+	time.Sleep(200 * time.Microsecond)
+	w.src.mu.Lock()
+	defer w.src.mu.Unlock()
+	w.src.written += len(frames[0])
+	return nil
+}
+
+func TestConfig_MemoryBytes(t *testing.T) {
+	t.Parallel()
+
+	cfg := pipeline.Config{ChunkFrames: 1000, Lookahead: 300, QueueDepth: 3}
+	// 5 input chunks queued or in a stage, 2000+300 pending and a 1300
+	// frame remainder copy; 5 output chunks.
+	want := int64(8 * ((5000+2300+1300)*2 + 5000*4))
+	if got := cfg.MemoryBytes(2, 4); got != want {
+		t.Fatalf("MemoryBytes(2, 4) = %d, want %d", got, want)
+	}
+	defaults := cfg
+	defaults.QueueDepth = pipeline.DefaultQueueDepth
+	cfg.QueueDepth = 0
+	if got, want := cfg.MemoryBytes(1, 1), defaults.MemoryBytes(1, 1); got != want {
+		t.Fatalf("QueueDepth 0 estimate %d, want the default depth's %d", got, want)
+	}
+}
+
+func TestConfig_MemoryBytesBoundsRun(t *testing.T) {
+	t.Parallel()
+
+	for _, depth := range []int{1, 4} {
+		cfg := pipeline.Config{ChunkFrames: 256, Lookahead: 512, QueueDepth: depth}
+		src := &countingSource{total: 200 * cfg.ChunkFrames}
+		pass := func(input [][]float64, numOutput int) ([][]float64, error) {
+			return [][]float64{append([]float64(nil), input[0][:numOutput]...)}, nil
+		}
+		if err := pipeline.Run(context.Background(), src, 1, countingSink{src}, pass, cfg); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		// With one channel in and out, every frame between reading and
+		// writing is one sample of the estimate.
+		bound := int(cfg.MemoryBytes(1, 1) / 8)
+		if src.peak > bound {
+			t.Fatalf("depth %d: %d frames in flight, estimate %d", depth, src.peak, bound)
+		}
+		// The input and output queues are full with a slow writer.
+		if src.peak < 2*depth*cfg.ChunkFrames {
+			t.Fatalf("depth %d: only %d frames in flight, queues not filled", depth, src.peak)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package pipeline overlaps WAV reading, block processing and WAV writing
+// so that file I/O and DSP run concurrently instead of back to back.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultChunkBlocks is the default chunk size in units of the hop size.
+	DefaultChunkBlocks = 16
+	// DefaultQueueDepth is the default number of chunks buffered between stages.
+	DefaultQueueDepth = 4
+)
+
+// Source yields input frames. ReadFrames fills up to len(dst[0]) frames of
+// dst ([channel][frame]) and returns io.EOF once exhausted; *wav.Reader
+// satisfies it.
+type Source interface {
+	ReadFrames(dst [][]float64) (int, error)
+}
+
+// Sink consumes output frames ([channel][frame]) in order; *wav.Writer
+// satisfies it.
+type Sink interface {
+	WriteFrames(frames [][]float64) error
+}
+
+// SliceSource serves frames from signals already held in memory, such as
+// decoded lossy input.
+type SliceSource struct {
+	samples [][]float64
+	pos     int
+}
+
+// NewSliceSource returns a Source over samples ([channel][frame]).
+func NewSliceSource(samples [][]float64) *SliceSource {
+	return &SliceSource{samples: samples}
+}
+
+// ReadFrames implements Source.
+func (s *SliceSource) ReadFrames(dst [][]float64) (int, error) {
+	if len(dst) != len(s.samples) {
+		return 0, fmt.Errorf("destination must have %d channels, got %d", len(s.samples), len(dst))
+	}
+	if len(s.samples) == 0 || s.pos >= len(s.samples[0]) {
+		return 0, io.EOF
+	}
+	n := 0
+	for ch := range dst {
+		n = copy(dst[ch], s.samples[ch][s.pos:])
+	}
+	s.pos += n
+	return n, nil
+}
+
+// Processor converts the first numOutput frames of input ([channel][frame])
+// into output frames, using the rest of input as lookahead. The decoder and
+// encoder ProcessSegment methods satisfy this signature.
+type Processor func(input [][]float64, numOutput int) ([][]float64, error)
+
+// Config controls chunking and buffering.
+type Config struct {
+	// ChunkFrames is the number of frames per chunk. It must be a multiple
+	// of the processor hop size so that segment boundaries line up with
+	// block boundaries.
+	ChunkFrames int
+	// Lookahead is the number of frames past a chunk the processor needs
+	// (blockSize-overlap for the SQ decoder and encoder).
+	Lookahead int
+	// QueueDepth bounds the number of chunks in flight between stages and
+	// therefore the memory footprint.
+	QueueDepth int
+	// Logger receives a debug record with the frame and chunk counts when
+	// the run ends; nil disables logging.
+	Logger *slog.Logger
+}
+
+// DefaultConfig returns a configuration for a processor with the given
+// block size and overlap (hop).
+func DefaultConfig(blockSize, overlap int) Config {
+	return Config{
+		ChunkFrames: overlap * DefaultChunkBlocks,
+		Lookahead:   max(blockSize-overlap, 0),
+		QueueDepth:  DefaultQueueDepth,
+	}
+}
+
+// Run streams every frame of src through process into w. A reader goroutine
+// decodes chunks, a single worker processes them in order (the processors
+// carry state from block to block), and a writer goroutine drains the
+// results. Channel capacities bound memory to roughly QueueDepth chunks per
+// stage. The output is identical to processing the whole signal at once.
+//
+// When ctx is cancelled every stage stops after its current chunk and Run
+// returns ctx.Err(); w is left unclosed. On success Run does not close w
+// either, so the caller can finish the stream with w.Close.
+func Run(ctx context.Context, src Source, inChannels int, w Sink, process Processor, cfg Config) error {
+	if cfg.ChunkFrames <= 0 {
+		return fmt.Errorf("chunk size must be > 0, got %d", cfg.ChunkFrames)
+	}
+	if cfg.Lookahead < 0 {
+		return fmt.Errorf("lookahead must be >= 0, got %d", cfg.Lookahead)
+	}
+	if cfg.QueueDepth <= 0 {
+		cfg.QueueDepth = DefaultQueueDepth
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	var written, numChunks int
+	chunks := make(chan [][]float64, cfg.QueueDepth)
+	results := make(chan [][]float64, cfg.QueueDepth)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		defer close(chunks)
+		if err := readChunks(ctx, src, inChannels, cfg.ChunkFrames, chunks); err != nil {
+			fail(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(results)
+		if err := processChunks(ctx, chunks, results, process, cfg); err != nil {
+			fail(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		written, numChunks, err = writeChunks(ctx, w, results)
+		if err != nil {
+			fail(err)
+		}
+	}()
+	wg.Wait()
+
+	if cfg.Logger != nil {
+		cfg.Logger.Debug("pipeline finished",
+			"frames", written,
+			"chunks", numChunks,
+			"chunk_frames", cfg.ChunkFrames,
+			"duration", time.Since(start),
+			"error", firstErr)
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	// The stages may have exited cleanly just as the parent was cancelled.
+	return ctx.Err()
+}
+
+func readChunks(ctx context.Context, src Source, channels, chunkFrames int, out chan<- [][]float64) error {
+	for {
+		chunk := make([][]float64, channels)
+		for ch := range chunk {
+			chunk[ch] = make([]float64, chunkFrames)
+		}
+
+		n, err := src.ReadFrames(chunk)
+		if n > 0 {
+			for ch := range chunk {
+				chunk[ch] = chunk[ch][:n]
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+	}
+}
+
+func processChunks(ctx context.Context, in <-chan [][]float64, out chan<- [][]float64, process Processor, cfg Config) error {
+	var pending [][]float64
+
+	emit := func(numOutput int) error {
+		inputFrames := min(len(pending[0]), numOutput+cfg.Lookahead)
+		input := make([][]float64, len(pending))
+		for ch := range pending {
+			input[ch] = pending[ch][:inputFrames]
+		}
+
+		result, err := process(input, numOutput)
+		if err != nil {
+			return fmt.Errorf("process: %w", err)
+		}
+
+		// Copy the remainder so the consumed frames can be collected.
+		for ch := range pending {
+			pending[ch] = append([]float64(nil), pending[ch][numOutput:]...)
+		}
+
+		select {
+		case out <- result:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for {
+		select {
+		case chunk, ok := <-in:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// End of input: flush the tail, zero-padded by the processor.
+				for pending != nil && len(pending[0]) > 0 {
+					if err := emit(min(cfg.ChunkFrames, len(pending[0]))); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			if pending == nil {
+				pending = make([][]float64, len(chunk))
+			}
+			for ch := range chunk {
+				pending[ch] = append(pending[ch], chunk[ch]...)
+			}
+			for len(pending[0]) >= cfg.ChunkFrames+cfg.Lookahead {
+				if err := emit(cfg.ChunkFrames); err != nil {
+					return err
+				}
+			}
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func writeChunks(ctx context.Context, w Sink, in <-chan [][]float64) (frames, chunks int, err error) {
+	for {
+		select {
+		case result, ok := <-in:
+			if !ok {
+				return frames, chunks, nil
+			}
+			if err := w.WriteFrames(result); err != nil {
+				return frames, chunks, fmt.Errorf("write output: %w", err)
+			}
+			frames += len(result[0])
+			chunks++
+		case <-ctx.Done():
+			return frames, chunks, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+)
+
+const (
+	blockSize = 1024
+	overlap   = 512
+)
+
+// stereoWAV returns a float32 WAV payload with the first two test tones.
+func stereoWAV(tb testing.TB, numSamples int) []byte {
+	tb.Helper()
+
+	quad := testsignal.QuadTones(44100, numSamples, 0.4, 0.05)
+	var buf bytes.Buffer
+	data := &wav.AudioData{SampleRate: 44100, Samples: quad[:2], NumSamples: numSamples}
+	if err := wav.WriteWAVWithOptionsToWriter(&buf, data, 2, wav.WriteOptions{Format: wav.FormatFloat32}); err != nil {
+		tb.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newDecoder() *decoder.SQDecoder {
+	d := decoder.NewSQDecoderWithParams(blockSize, overlap)
+	d.EnableLogicSteering(true)
+	return d
+}
+
+func sequential(tb testing.TB, in io.Reader, out io.Writer) {
+	tb.Helper()
+
+	audio, err := wav.ReadWAVFromReader(in, 2)
+	if err != nil {
+		tb.Fatalf("ReadWAVFromReader() error = %v", err)
+	}
+	decoded, err := newDecoder().Process(audio.Samples)
+	if err != nil {
+		tb.Fatalf("Process() error = %v", err)
+	}
+	data := &wav.AudioData{SampleRate: audio.SampleRate, Samples: decoded, NumSamples: audio.NumSamples}
+	if err := wav.WriteWAVWithOptionsToWriter(out, data, 4, wav.WriteOptions{}); err != nil {
+		tb.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+}
+
+func pipelined(ctx context.Context, in io.Reader, out io.Writer, cfg pipeline.Config) error {
+	r, err := wav.NewReader(in, 2)
+	if err != nil {
+		return err
+	}
+	w, err := wav.NewWriter(out, r.SampleRate(), 4, r.NumFrames(), wav.WriteOptions{})
+	if err != nil {
+		return err
+	}
+	if err := pipeline.Run(ctx, r, 2, w, newDecoder().ProcessSegment, cfg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func TestRun_MatchesSequential(t *testing.T) {
+	t.Parallel()
+
+	input := stereoWAV(t, 20*overlap+123)
+
+	var want bytes.Buffer
+	sequential(t, bytes.NewReader(input), &want)
+
+	configs := []pipeline.Config{
+		pipeline.DefaultConfig(blockSize, overlap),
+		{ChunkFrames: overlap, Lookahead: blockSize - overlap, QueueDepth: 1},
+		{ChunkFrames: 3 * overlap, Lookahead: blockSize - overlap, QueueDepth: 2},
+	}
+	for _, cfg := range configs {
+		var got bytes.Buffer
+		if err := pipelined(context.Background(), bytes.NewReader(input), &got, cfg); err != nil {
+			t.Fatalf("chunk %d: pipelined() error = %v", cfg.ChunkFrames, err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Fatalf("chunk %d: pipelined output differs from sequential output", cfg.ChunkFrames)
+		}
+	}
+}
+
+// blockingReader serves a prefix and then blocks until the context is done.
+type blockingReader struct {
+	ctx    context.Context
+	prefix *bytes.Reader
+	served chan struct{}
+}
+
+func (b *blockingReader) Read(p []byte) (int, error) {
+	if b.prefix.Len() > 0 {
+		return b.prefix.Read(p)
+	}
+	select {
+	case b.served <- struct{}{}:
+	default:
+	}
+	<-b.ctx.Done()
+	return 0, b.ctx.Err()
+}
+
+func TestRun_Cancel(t *testing.T) {
+	t.Parallel()
+
+	input := stereoWAV(t, 40*overlap)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := &blockingReader{ctx: ctx, prefix: bytes.NewReader(input[:len(input)/2]), served: make(chan struct{}, 1)}
+	done := make(chan error, 1)
+	go func() {
+		done <- pipelined(ctx, in, io.Discard, pipeline.Config{ChunkFrames: overlap, Lookahead: blockSize - overlap})
+	}()
+
+	<-in.served
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("pipelined() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("pipeline did not stop after cancel")
+	}
+}
+
+// throttledReader simulates a slow disk by sleeping in proportion to the
+// number of bytes read. Delays are accumulated and slept in steps of at
+// least a millisecond to stay accurate despite timer granularity.
+type throttledReader struct {
+	r           io.Reader
+	perKilobyte time.Duration
+	debt        time.Duration
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	t.debt += time.Duration(n) * t.perKilobyte / 1024
+	if t.debt >= time.Millisecond || err != nil {
+		time.Sleep(t.debt)
+		t.debt = 0
+	}
+	return n, err
+}
+
+func benchmarkInput(b *testing.B, throttled bool) func() io.Reader {
+	b.Helper()
+
+	input := stereoWAV(b, 44100*5)
+	return func() io.Reader {
+		var r io.Reader = bytes.NewReader(input)
+		if throttled {
+			r = &throttledReader{r: r, perKilobyte: 50 * time.Microsecond}
+		}
+		return r
+	}
+}
+
+func BenchmarkSequential_Memory(b *testing.B) {
+	open := benchmarkInput(b, false)
+	for b.Loop() {
+		sequential(b, open(), io.Discard)
+	}
+}
+
+func BenchmarkPipelined_Memory(b *testing.B) {
+	open := benchmarkInput(b, false)
+	for b.Loop() {
+		if err := pipelined(context.Background(), open(), io.Discard, pipeline.DefaultConfig(blockSize, overlap)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSequential_Throttled(b *testing.B) {
+	open := benchmarkInput(b, true)
+	for b.Loop() {
+		sequential(b, open(), io.Discard)
+	}
+}
+
+func BenchmarkPipelined_Throttled(b *testing.B) {
+	open := benchmarkInput(b, true)
+	for b.Loop() {
+		if err := pipelined(context.Background(), open(), io.Discard, pipeline.DefaultConfig(blockSize, overlap)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRun_SliceSource(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, 7*overlap+5, 0.4, 0.05)
+	want, err := newDecoder().Process(quad[:2])
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := wav.NewWriter(&buf, 44100, 4, len(quad[0]), wav.WriteOptions{Format: wav.FormatFloat32})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	cfg := pipeline.Config{ChunkFrames: 2 * overlap, Lookahead: blockSize - overlap}
+	if err := pipeline.Run(context.Background(), pipeline.NewSliceSource(quad[:2]), 2, w, newDecoder().ProcessSegment, cfg); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, err := wav.ReadWAVBytes(buf.Bytes(), 4)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+	for ch := range want {
+		for i := range want[ch] {
+			if got.Samples[ch][i] != float64(float32(want[ch][i])) {
+				t.Fatalf("out[%d][%d] = %v, want %v", ch, i, got.Samples[ch][i], float32(want[ch][i]))
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package pipeline
+
+import "fmt"
+
+// Stream adapts a Processor to push-style hosts that hand over blocks of
+// arbitrary size and expect the same number of frames back, such as audio
+// callbacks and the C library. Frames are buffered until a whole hop plus
+// lookahead is available, so the output is delayed by Latency frames.
+type Stream struct {
+	process    Processor
+	inChannels int
+	hop        int
+	lookahead  int
+	pending    [][]float64
+	ready      [][]float64
+}
+
+// NewStream returns a Stream over process, which consumes inChannels and
+// produces outChannels channels in steps of hop frames with lookahead
+// frames past each step.
+func NewStream(process Processor, inChannels, outChannels, hop, lookahead int) (*Stream, error) {
+	if inChannels <= 0 || outChannels <= 0 {
+		return nil, fmt.Errorf("channel counts must be > 0, got %d in, %d out", inChannels, outChannels)
+	}
+	if hop <= 0 {
+		return nil, fmt.Errorf("hop must be > 0, got %d", hop)
+	}
+	if lookahead < 0 {
+		return nil, fmt.Errorf("lookahead must be >= 0, got %d", lookahead)
+	}
+	s := &Stream{
+		process:    process,
+		inChannels: inChannels,
+		hop:        hop,
+		lookahead:  lookahead,
+		pending:    make([][]float64, inChannels),
+		ready:      make([][]float64, outChannels),
+	}
+	for ch := range s.ready {
+		s.ready[ch] = make([]float64, s.Latency())
+	}
+	return s, nil
+}
+
+// Latency returns the delay of the output in frames: output frame
+// Latency+i is frame i of processing the whole signal at once. Push
+// Latency frames of silence after the signal to drain the stream.
+func (s *Stream) Latency() int {
+	return s.lookahead + s.hop - 1
+}
+
+// Process pushes input ([channel][frame]) and returns as many output frames
+// as it was given.
+func (s *Stream) Process(input [][]float64) ([][]float64, error) {
+	if len(input) != s.inChannels {
+		return nil, fmt.Errorf("input must have %d channels, got %d", s.inChannels, len(input))
+	}
+	frames := len(input[0])
+	for ch := range input {
+		if len(input[ch]) != frames {
+			return nil, fmt.Errorf("input channels must have same length")
+		}
+		s.pending[ch] = append(s.pending[ch], input[ch]...)
+	}
+
+	// Process all whole hops at once; the processor carries its state over
+	// from call to call.
+	if n := (len(s.pending[0]) - s.lookahead) / s.hop * s.hop; n > 0 {
+		segment := make([][]float64, s.inChannels)
+		for ch := range segment {
+			segment[ch] = s.pending[ch][:n+s.lookahead]
+		}
+		out, err := s.process(segment, n)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) != len(s.ready) {
+			return nil, fmt.Errorf("processor returned %d channels, want %d", len(out), len(s.ready))
+		}
+		for ch := range s.pending {
+			s.pending[ch] = append(s.pending[ch][:0], s.pending[ch][n:]...)
+		}
+		for ch := range out {
+			s.ready[ch] = append(s.ready[ch], out[ch]...)
+		}
+	}
+
+	// At least frames are ready now: Latency covers the frames still held
+	// back in pending.
+	output := make([][]float64, len(s.ready))
+	for ch := range output {
+		output[ch] = append([]float64(nil), s.ready[ch][:frames]...)
+		s.ready[ch] = append(s.ready[ch][:0], s.ready[ch][frames:]...)
+	}
+	return output, nil
+}
@@ -0,0 +1,84 @@
+package pipeline_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func TestStream_MatchesProcess(t *testing.T) {
+	t.Parallel()
+
+	numSamples := 10*overlap + 77
+	stereo := testsignal.QuadTones(44100, numSamples, 0.4, 0.05)[:2]
+	want, err := newDecoder().Process(stereo)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	s, err := pipeline.NewStream(newDecoder().ProcessSegment, 2, 4, overlap, blockSize-overlap)
+	if err != nil {
+		t.Fatalf("NewStream() error = %v", err)
+	}
+	latency := s.Latency()
+	if latency != blockSize-1 {
+		t.Fatalf("Latency() = %d, want %d", latency, blockSize-1)
+	}
+
+	// Push the signal and the drain in blocks of random size, as an audio
+	// host would.
+	total := numSamples + latency
+	padded := [][]float64{make([]float64, total), make([]float64, total)}
+	copy(padded[0], stereo[0])
+	copy(padded[1], stereo[1])
+	got := make([][]float64, 4)
+	rng := rand.New(rand.NewSource(1))
+	for pos := 0; pos < total; {
+		n := min(rng.Intn(700), total-pos)
+		out, err := s.Process([][]float64{padded[0][pos : pos+n], padded[1][pos : pos+n]})
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		if len(out) != 4 || len(out[0]) != n {
+			t.Fatalf("Process(%d frames) returned %d channels of %d frames", n, len(out), len(out[0]))
+		}
+		for ch := range out {
+			got[ch] = append(got[ch], out[ch]...)
+		}
+		pos += n
+	}
+
+	for ch := range want {
+		for i := range latency {
+			if got[ch][i] != 0 {
+				t.Fatalf("ch %d frame %d: %v before the latency, want 0", ch, i, got[ch][i])
+			}
+		}
+		for i, w := range want[ch] {
+			if g := got[ch][latency+i]; g != w {
+				t.Fatalf("ch %d frame %d: got %v, want %v", ch, i, g, w)
+			}
+		}
+	}
+}
+
+func TestNewStream_Invalid(t *testing.T) {
+	t.Parallel()
+
+	process := newDecoder().ProcessSegment
+	for _, tt := range []struct {
+		name                string
+		in, out, hop, ahead int
+	}{
+		{"no input channels", 0, 4, overlap, 0},
+		{"no output channels", 2, 0, overlap, 0},
+		{"zero hop", 2, 4, 0, 0},
+		{"negative lookahead", 2, 4, overlap, -1},
+	} {
+		if _, err := pipeline.NewStream(process, tt.in, tt.out, tt.hop, tt.ahead); err == nil {
+			t.Fatalf("%s: expected error", tt.name)
+		}
+	}
+}
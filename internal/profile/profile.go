@@ -0,0 +1,126 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// CurrentVersion is the profile schema version written by Save.
+const CurrentVersion = 1
+
+// Profile is a saved set of processing options keyed by flag name.
+// Options missing from an older profile keep their flag defaults when the
+// profile is applied, so new flags never break existing profiles.
+type Profile struct {
+	Version int               `json:"version"`
+	Command string            `json:"command"`
+	Options map[string]string `json:"options"`
+}
+
+// FromFlags captures the effective value of every flag in fs except the
+// excluded names.
+func FromFlags(command string, fs *pflag.FlagSet, exclude ...string) Profile {
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[name] = true
+	}
+
+	p := Profile{
+		Version: CurrentVersion,
+		Command: command,
+		Options: make(map[string]string),
+	}
+	fs.VisitAll(func(f *pflag.Flag) {
+		if skip[f.Name] {
+			return
+		}
+		p.Options[f.Name] = f.Value.String()
+	})
+	return p
+}
+
+// Apply sets the profile's options as flag values. Flags the user set
+// explicitly on the command line keep their values; options naming flags
+// that fs does not define are ignored.
+func (p Profile) Apply(fs *pflag.FlagSet) error {
+	names := make([]string, 0, len(p.Options))
+	for name := range p.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+		if err := fs.Set(name, p.Options[name]); err != nil {
+			return fmt.Errorf("profile option %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Path resolves a profile name to a file path. Values that look like paths
+// (containing a separator or ending in .json) are used as-is; bare names map
+// to <user config dir>/go-sq-tool/profiles/<name>.json.
+func Path(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("empty profile name")
+	}
+	if strings.ContainsRune(name, os.PathSeparator) || strings.ContainsRune(name, '/') || strings.HasSuffix(name, ".json") {
+		return name, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate profiles directory: %w", err)
+	}
+	return filepath.Join(configDir, "go-sq-tool", "profiles", name+".json"), nil
+}
+
+// Save writes p as indented JSON, creating parent directories as needed.
+func Save(path string, p Profile) error {
+	if p.Version == 0 {
+		p.Version = CurrentVersion
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode profile: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create profile directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write profile: %w", err)
+	}
+	return nil
+}
+
+// Load reads a profile written by Save. Profiles from newer schema versions
+// are rejected.
+func Load(path string) (Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, fmt.Errorf("read profile: %w", err)
+	}
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Profile{}, fmt.Errorf("parse profile %s: %w", path, err)
+	}
+	if p.Version < 1 {
+		return Profile{}, fmt.Errorf("profile %s has no schema version", path)
+	}
+	if p.Version > CurrentVersion {
+		return Profile{}, fmt.Errorf("profile %s has schema version %d, newest supported is %d", path, p.Version, CurrentVersion)
+	}
+	if p.Options == nil {
+		p.Options = make(map[string]string)
+	}
+	return p, nil
+}
@@ -0,0 +1,146 @@
+package profile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/profile"
+	"github.com/spf13/pflag"
+)
+
+type testFlags struct {
+	fs        *pflag.FlagSet
+	blockSize int
+	logic     bool
+	window    string
+}
+
+func newTestFlags(t *testing.T, args ...string) *testFlags {
+	t.Helper()
+
+	f := &testFlags{fs: pflag.NewFlagSet("test", pflag.ContinueOnError)}
+	f.fs.IntVar(&f.blockSize, "block-size", 1024, "")
+	f.fs.BoolVar(&f.logic, "logic", false, "")
+	f.fs.StringVar(&f.window, "window", "hann", "")
+	f.fs.String("profile", "", "")
+	if err := f.fs.Parse(args); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return f
+}
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "album.json")
+	src := newTestFlags(t, "--block-size", "2048", "--logic", "--profile", "album")
+
+	saved := profile.FromFlags("decode", src.fs, "profile")
+	if _, ok := saved.Options["profile"]; ok {
+		t.Fatalf("excluded flag was saved")
+	}
+	if err := profile.Save(path, saved); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := profile.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Version != profile.CurrentVersion || loaded.Command != "decode" {
+		t.Fatalf("loaded header = %d/%q, want %d/decode", loaded.Version, loaded.Command, profile.CurrentVersion)
+	}
+
+	dst := newTestFlags(t)
+	if err := loaded.Apply(dst.fs); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if dst.blockSize != 2048 || !dst.logic || dst.window != "hann" {
+		t.Fatalf("applied = %d/%v/%q, want 2048/true/hann", dst.blockSize, dst.logic, dst.window)
+	}
+}
+
+func TestApply_ExplicitFlagsOverrideProfile(t *testing.T) {
+	t.Parallel()
+
+	p := profile.Profile{
+		Version: profile.CurrentVersion,
+		Options: map[string]string{"block-size": "4096", "logic": "true"},
+	}
+
+	dst := newTestFlags(t, "--block-size", "512")
+	if err := p.Apply(dst.fs); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if dst.blockSize != 512 {
+		t.Fatalf("block-size = %d, want explicit 512", dst.blockSize)
+	}
+	if !dst.logic {
+		t.Fatalf("logic = false, want true from profile")
+	}
+}
+
+func TestLoad_OldProfileKeepsDefaultsForNewFields(t *testing.T) {
+	t.Parallel()
+
+	// A profile written before the "window" flag existed, carrying an
+	// option for a flag that has since been removed.
+	path := filepath.Join(t.TempDir(), "old.json")
+	old := `{"version": 1, "command": "decode", "options": {"block-size": "2048", "removed-flag": "x"}}`
+	if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	loaded, err := profile.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	dst := newTestFlags(t)
+	if err := loaded.Apply(dst.fs); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if dst.blockSize != 2048 || dst.window != "hann" {
+		t.Fatalf("applied = %d/%q, want 2048/hann", dst.blockSize, dst.window)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cases := map[string]string{
+		"future.json":      `{"version": 99, "options": {}}`,
+		"unversioned.json": `{"options": {}}`,
+		"garbage.json":     `{`,
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if _, err := profile.Load(path); err == nil {
+			t.Fatalf("Load(%s) expected error", name)
+		}
+	}
+
+	p := profile.Profile{Version: 1, Options: map[string]string{"block-size": "abc"}}
+	if err := p.Apply(newTestFlags(t).fs); err == nil {
+		t.Fatalf("Apply() expected error for invalid value")
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+
+	if got, _ := profile.Path("dir/album.json"); got != "dir/album.json" {
+		t.Fatalf("Path() = %q, want explicit path unchanged", got)
+	}
+	got, err := profile.Path("album")
+	if err != nil {
+		t.Skipf("no user config dir: %v", err)
+	}
+	if filepath.Base(got) != "album.json" || filepath.Base(filepath.Dir(got)) != "profiles" {
+		t.Fatalf("Path() = %q, want .../profiles/album.json", got)
+	}
+}
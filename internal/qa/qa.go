@@ -0,0 +1,512 @@
+// Package qa scans decoded audio for defects a listener would notice, so
+// that large batch runs can be checked without listening to every file:
+// periodic artifacts at the block rate of the decoder, sudden gain jumps
+// of the logic steering, runs of clipped samples and dropouts. Each
+// detector reports timestamped findings; the score summarizes them to
+// rank files for listening and is not a measurement.
+package qa
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// Kind names a detector.
+type Kind string
+
+const (
+	// BlockRate is a periodic modulation of the envelope at the hop rate
+	// of the decoder, heard as a buzz or flutter of the block processing.
+	BlockRate Kind = "block-rate"
+	// GainJump is a sudden level step of one channel while the total level
+	// stays the same, as from abrupt logic steering.
+	GainJump Kind = "gain-jump"
+	// Clipping is a run of identical consecutive samples at full scale, the
+	// flat top of a clipped waveform.
+	Clipping Kind = "clipping"
+	// Dropout is a short gap of near silence inside a signal.
+	Dropout Kind = "dropout"
+)
+
+// penalties are the score points each finding of a kind costs.
+var penalties = map[Kind]float64{
+	BlockRate: 15,
+	Dropout:   10,
+	Clipping:  5,
+	GainJump:  2,
+}
+
+const (
+	// frameSeconds is the length of the level frames of the gain jump
+	// detector and of the context around a dropout.
+	frameSeconds = 0.01
+	// mergeSeconds is the largest gap between two findings of the same
+	// kind and channel that are reported as one.
+	mergeSeconds = 0.1
+	// totalStepDB is the largest change of the total level at which a
+	// channel step still counts as a redistribution rather than a change
+	// of the programme.
+	totalStepDB = 3
+	// envelopeSteps is the number of envelope samples per hop of the
+	// block-rate detector.
+	envelopeSteps = 8
+	// silentEnvelope is the mean envelope below which a block-rate window
+	// is not analysed.
+	silentEnvelope = 1e-4
+	// energyFloor bounds the levels in dB from below.
+	energyFloor = 1e-12
+)
+
+// Config holds the thresholds of the detectors.
+type Config struct {
+	// Hop is the hop size of the decoder in frames. The block-rate
+	// detector is disabled when it is 0.
+	Hop int
+	// WindowSeconds is the length of the block-rate analysis windows.
+	WindowSeconds float64
+	// BlockRateDB is how far the hop-rate line of the envelope spectrum
+	// must stand above its neighbourhood, and MinModulation the smallest
+	// relative depth of that modulation worth reporting.
+	BlockRateDB   float64
+	MinModulation float64
+	// JumpDB is the smallest level step of a channel within two level
+	// frames that counts as a gain jump.
+	JumpDB float64
+	// ClipLevel is the magnitude at which a sample counts as clipped, and
+	// MinClipRun the number of consecutive identical clipped samples
+	// reported. A waveform merely peaking near full scale has no such runs.
+	ClipLevel  float64
+	MinClipRun int
+	// DropoutDBFS is the level at or below which samples count as a gap.
+	// Gaps between MinDropout and MaxDropout seconds long with at least
+	// ContextDBFS on both sides are dropouts. ContextDBFS is also the
+	// level a channel must reach for a gain jump.
+	DropoutDBFS float64
+	MinDropout  float64
+	MaxDropout  float64
+	ContextDBFS float64
+}
+
+// DefaultConfig returns the thresholds for a decoder with the given hop
+// size.
+func DefaultConfig(hop int) Config {
+	return Config{
+		Hop:           hop,
+		WindowSeconds: 2,
+		BlockRateDB:   12,
+		MinModulation: 0.01,
+		JumpDB:        12,
+		ClipLevel:     0.999,
+		MinClipRun:    3,
+		DropoutDBFS:   -80,
+		MinDropout:    0.002,
+		MaxDropout:    0.5,
+		ContextDBFS:   -40,
+	}
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.Hop < 0:
+		return fmt.Errorf("hop size must not be negative, got %d", c.Hop)
+	case c.WindowSeconds <= 0:
+		return fmt.Errorf("QA window must be positive, got %g s", c.WindowSeconds)
+	case c.ClipLevel <= 0:
+		return fmt.Errorf("clip level must be positive, got %g", c.ClipLevel)
+	case c.MinClipRun < 1:
+		return fmt.Errorf("clip run must be at least 1 sample, got %d", c.MinClipRun)
+	case c.MinDropout <= 0 || c.MaxDropout < c.MinDropout:
+		return fmt.Errorf("invalid dropout length range %g to %g s", c.MinDropout, c.MaxDropout)
+	}
+	return nil
+}
+
+// Finding is one suspected defect.
+type Finding struct {
+	Kind    Kind
+	Channel int
+	// Start and Duration locate the defect in seconds.
+	Start, Duration float64
+	// Value measures the defect: the prominence of the hop-rate line in dB
+	// (BlockRate), the largest level step in dB (GainJump), the number of
+	// clipped samples (Clipping) or the level in dBFS around the gap
+	// (Dropout).
+	Value float64
+}
+
+// Report is the result of a scan.
+type Report struct {
+	// Score is 100 for a file without findings and drops with every
+	// finding, by kind, to at least 0.
+	Score    float64
+	Findings []Finding
+}
+
+// Analyzer scans a stream of frames.
+type Analyzer struct {
+	config     Config
+	sampleRate float64
+	channels   []channelState
+	pos        int
+	findings   []Finding
+
+	// frameLen samples make a level frame; frame holds the energy of the
+	// current one per channel and levels that of the last two complete ones.
+	frameLen int
+	frameN   int
+	frame    []float64
+	levels   [2][]float64
+	numLevel int
+
+	// envLen samples make an envelope sample, windowLen envelope samples a
+	// block-rate window.
+	envLen    int
+	windowLen int
+
+	clipLevel    float64
+	dropoutLevel float64
+	contextPower float64
+	minDropout   int
+	maxDropout   int
+}
+
+// channelState is the per-channel state of the detectors.
+type channelState struct {
+	// clipValue is the sample value of the current clipped run.
+	clipStart, clipRun int
+	clipValue          float64
+
+	quietStart, quietRun int
+	// before is the mean square of the frameLen samples before the current
+	// gap; recent holds the last frameLen squares for it.
+	before  float64
+	recent  []float64
+	recentN int
+	recentS float64
+	// pending is a gap waiting for the context after it.
+	pending *gap
+
+	envSum   float64
+	envN     int
+	envStart int
+	envelope []float64
+}
+
+type gap struct {
+	start, length int
+	before        float64
+	after         float64
+	afterN        int
+}
+
+// NewAnalyzer returns an Analyzer for channels channels at sampleRate.
+func NewAnalyzer(sampleRate, channels int, config Config) (*Analyzer, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("sample rate must be positive, got %d", sampleRate)
+	}
+	if channels <= 0 {
+		return nil, fmt.Errorf("channels must be positive, got %d", channels)
+	}
+	rate := float64(sampleRate)
+	a := &Analyzer{
+		config:       config,
+		sampleRate:   rate,
+		channels:     make([]channelState, channels),
+		frameLen:     max(1, int(frameSeconds*rate)),
+		frame:        make([]float64, channels),
+		levels:       [2][]float64{make([]float64, channels), make([]float64, channels)},
+		clipLevel:    config.ClipLevel,
+		dropoutLevel: math.Pow(10, config.DropoutDBFS/20),
+		contextPower: math.Pow(10, config.ContextDBFS/10),
+		minDropout:   max(1, int(math.Round(config.MinDropout*rate))),
+		maxDropout:   int(math.Round(config.MaxDropout * rate)),
+	}
+	if config.Hop > 0 {
+		a.envLen = max(1, config.Hop/envelopeSteps)
+		a.windowLen = max(1, int(config.WindowSeconds*rate)/a.envLen)
+	}
+	for ch := range a.channels {
+		a.channels[ch].recent = make([]float64, a.frameLen)
+	}
+	return a, nil
+}
+
+// Add scans the next frames ([channel][frame]).
+func (a *Analyzer) Add(frames [][]float64) error {
+	if len(frames) != len(a.channels) {
+		return fmt.Errorf("expected %d channels, got %d", len(a.channels), len(frames))
+	}
+	for i := range frames[0] {
+		for ch, x := range frames {
+			v := x[i]
+			a.frame[ch] += v * v
+			s := &a.channels[ch]
+			a.clipping(ch, s, v)
+			a.dropout(ch, s, v)
+			if a.envLen > 0 {
+				a.blockRate(ch, s, v)
+			}
+		}
+		a.pos++
+		if a.frameN++; a.frameN == a.frameLen {
+			a.gainJump()
+			a.frameN = 0
+		}
+	}
+	return nil
+}
+
+// Report returns the findings so far, sorted by time, and the score. Runs
+// still open at the end of the stream are included.
+func (a *Analyzer) Report() Report {
+	findings := slices.Clone(a.findings)
+	for ch := range a.channels {
+		s := &a.channels[ch]
+		if s.clipRun >= a.config.MinClipRun {
+			findings = a.merge(findings, a.clipFinding(ch, s))
+		}
+		if a.envLen > 0 && len(s.envelope) >= a.windowLen/2 {
+			if f, ok := a.blockRateWindow(ch, s); ok {
+				findings = a.merge(findings, f)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+
+	score := 100.0
+	for _, f := range findings {
+		score -= penalties[f.Kind]
+	}
+	return Report{Score: max(score, 0), Findings: findings}
+}
+
+// seconds converts a frame position to seconds.
+func (a *Analyzer) seconds(frames int) float64 {
+	return float64(frames) / a.sampleRate
+}
+
+// add records f, merging it into the last finding of the same kind and
+// channel when they are less than mergeSeconds apart.
+func (a *Analyzer) add(f Finding) {
+	a.findings = a.merge(a.findings, f)
+}
+
+func (a *Analyzer) merge(findings []Finding, f Finding) []Finding {
+	for i := len(findings) - 1; i >= 0; i-- {
+		last := &findings[i]
+		if last.Kind != f.Kind || last.Channel != f.Channel {
+			continue
+		}
+		if f.Start-(last.Start+last.Duration) >= mergeSeconds {
+			break
+		}
+		end := max(last.Start+last.Duration, f.Start+f.Duration)
+		last.Duration = end - last.Start
+		switch f.Kind {
+		case Clipping:
+			last.Value += f.Value
+		case Dropout:
+			last.Value = min(last.Value, f.Value)
+		default:
+			last.Value = max(last.Value, f.Value)
+		}
+		return findings
+	}
+	return append(findings, f)
+}
+
+func (a *Analyzer) clipping(ch int, s *channelState, v float64) {
+	if s.clipRun > 0 && v == s.clipValue {
+		s.clipRun++
+		return
+	}
+	if s.clipRun >= a.config.MinClipRun {
+		a.add(a.clipFinding(ch, s))
+	}
+	s.clipRun = 0
+	if math.Abs(v) >= a.clipLevel {
+		s.clipStart, s.clipRun, s.clipValue = a.pos, 1, v
+	}
+}
+
+func (a *Analyzer) clipFinding(ch int, s *channelState) Finding {
+	return Finding{
+		Kind:     Clipping,
+		Channel:  ch,
+		Start:    a.seconds(s.clipStart),
+		Duration: a.seconds(s.clipRun),
+		Value:    float64(s.clipRun),
+	}
+}
+
+func (a *Analyzer) dropout(ch int, s *channelState, v float64) {
+	power := v * v
+	if p := s.pending; p != nil {
+		p.after += power
+		if p.afterN++; p.afterN == a.frameLen {
+			after := p.after / float64(a.frameLen)
+			if after >= a.contextPower {
+				a.add(Finding{
+					Kind:     Dropout,
+					Channel:  ch,
+					Start:    a.seconds(p.start),
+					Duration: a.seconds(p.length),
+					Value:    10 * math.Log10(max(min(p.before, after), energyFloor)),
+				})
+			}
+			s.pending = nil
+		}
+	}
+
+	if math.Abs(v) <= a.dropoutLevel {
+		if s.quietRun == 0 {
+			s.quietStart = a.pos
+			s.before = s.recentS / float64(a.frameLen)
+			if s.recentN < a.frameLen {
+				// Not enough signal before the gap to judge it.
+				s.before = 0
+			}
+		}
+		s.quietRun++
+	} else {
+		if s.quietRun >= a.minDropout && s.quietRun <= a.maxDropout && s.before >= a.contextPower {
+			s.pending = &gap{start: s.quietStart, length: s.quietRun, before: s.before, after: power, afterN: 1}
+		}
+		s.quietRun = 0
+	}
+
+	// Keep the sum of the last frameLen squares for the context before a
+	// gap.
+	i := s.recentN % a.frameLen
+	s.recentS += power - s.recent[i]
+	s.recent[i] = power
+	s.recentN++
+}
+
+// gainJump compares the level frame that just ended with the one two
+// frames earlier, so that a step anywhere within the middle frame shows in
+// full.
+func (a *Analyzer) gainJump() {
+	n := float64(a.frameLen)
+	if a.numLevel == 2 {
+		var total, earlierTotal float64
+		for ch := range a.channels {
+			total += a.frame[ch] / n
+			earlierTotal += a.levels[0][ch]
+		}
+		totalStep := 10 * math.Log10(max(total, energyFloor)/max(earlierTotal, energyFloor))
+		if math.Abs(totalStep) < totalStepDB {
+			for ch := range a.channels {
+				now, earlier := a.frame[ch]/n, a.levels[0][ch]
+				louder, quieter := max(now, earlier), min(now, earlier)
+				if louder < a.contextPower || quieter <= a.dropoutLevel*a.dropoutLevel {
+					continue
+				}
+				if step := 10 * math.Log10(louder/quieter); step >= a.config.JumpDB {
+					a.add(Finding{
+						Kind:     GainJump,
+						Channel:  ch,
+						Start:    a.seconds(a.pos - 2*a.frameLen),
+						Duration: a.seconds(2 * a.frameLen),
+						Value:    step,
+					})
+				}
+			}
+		}
+	}
+
+	a.levels[0], a.levels[1] = a.levels[1], a.levels[0]
+	for ch := range a.channels {
+		a.levels[1][ch] = a.frame[ch] / n
+		a.frame[ch] = 0
+	}
+	a.numLevel = min(a.numLevel+1, 2)
+}
+
+func (a *Analyzer) blockRate(ch int, s *channelState, v float64) {
+	s.envSum += v * v
+	if s.envN++; s.envN < a.envLen {
+		return
+	}
+	if len(s.envelope) == 0 {
+		s.envStart = a.pos + 1 - a.envLen
+	}
+	s.envelope = append(s.envelope, math.Sqrt(s.envSum/float64(a.envLen)))
+	s.envSum, s.envN = 0, 0
+	if len(s.envelope) == a.windowLen {
+		if f, ok := a.blockRateWindow(ch, s); ok {
+			a.add(f)
+		}
+		s.envelope = s.envelope[:0]
+	}
+}
+
+// blockRateWindow looks for the hop-rate line in the spectrum of the
+// envelope window of s: its Hann-windowed magnitude at the hop rate
+// against the median of the bins around it, outside the main lobe.
+func (a *Analyzer) blockRateWindow(ch int, s *channelState) (Finding, bool) {
+	env := s.envelope
+	n := len(env)
+	weights := make([]float64, n)
+	var weightSum, mean float64
+	for i := range env {
+		weights[i] = 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(n))
+		weightSum += weights[i]
+		mean += weights[i] * env[i]
+	}
+	mean /= weightSum
+	if mean < silentEnvelope {
+		return Finding{}, false
+	}
+
+	magnitude := func(freq float64) float64 {
+		var re, im float64
+		for i, e := range env {
+			phase := 2 * math.Pi * freq * float64(i)
+			re += weights[i] * (e - mean) * math.Cos(phase)
+			im -= weights[i] * (e - mean) * math.Sin(phase)
+		}
+		return math.Hypot(re, im)
+	}
+	// The hop rate in cycles per envelope sample, and the bin spacing.
+	hop := float64(a.envLen) / float64(a.config.Hop)
+	bin := 1 / float64(n)
+	line := magnitude(hop)
+	var neighbours []float64
+	for k := 3; k <= 8; k++ {
+		neighbours = append(neighbours, magnitude(hop-float64(k)*bin), magnitude(hop+float64(k)*bin))
+	}
+	slices.Sort(neighbours)
+	reference := (neighbours[len(neighbours)/2-1] + neighbours[len(neighbours)/2]) / 2
+
+	// A modulation m·(1 + d·cos) gives a line of m·d/2 times the weights.
+	depth := 2 * line / (mean * weightSum)
+	prominence := 20 * math.Log10(max(line, energyFloor)/max(reference, energyFloor))
+	if depth < a.config.MinModulation || prominence < a.config.BlockRateDB {
+		return Finding{}, false
+	}
+	return Finding{
+		Kind:     BlockRate,
+		Channel:  ch,
+		Start:    a.seconds(s.envStart),
+		Duration: a.seconds(n * a.envLen),
+		Value:    prominence,
+	}, true
+}
+
+// Analyze scans frames ([channel][frame]) at once.
+func Analyze(frames [][]float64, sampleRate int, config Config) (Report, error) {
+	a, err := NewAnalyzer(sampleRate, len(frames), config)
+	if err != nil {
+		return Report{}, err
+	}
+	if err := a.Add(frames); err != nil {
+		return Report{}, err
+	}
+	return a.Report(), nil
+}
@@ -0,0 +1,202 @@
+package qa_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/qa"
+)
+
+const (
+	rate = 48000
+	hop  = 1024
+)
+
+// noise returns seconds of uniform noise at the given peak level.
+func noise(seed int64, seconds, level float64) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	out := make([]float64, int(seconds*rate))
+	for i := range out {
+		out[i] = level * (2*r.Float64() - 1)
+	}
+	return out
+}
+
+func analyze(t *testing.T, frames ...[]float64) qa.Report {
+	t.Helper()
+	report, err := qa.Analyze(frames, rate, qa.DefaultConfig(hop))
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	return report
+}
+
+// only fails unless every finding of report is of kind, on channel, and
+// there is at least one.
+func only(t *testing.T, report qa.Report, kind qa.Kind, channel int) []qa.Finding {
+	t.Helper()
+	if len(report.Findings) == 0 {
+		t.Fatalf("no findings, want %s", kind)
+	}
+	for _, f := range report.Findings {
+		if f.Kind != kind || f.Channel != channel {
+			t.Fatalf("finding %+v, want only %s on channel %d", f, kind, channel)
+		}
+	}
+	if report.Score >= 100 {
+		t.Fatalf("score = %v with findings", report.Score)
+	}
+	return report.Findings
+}
+
+func clean(t *testing.T, report qa.Report) {
+	t.Helper()
+	if len(report.Findings) != 0 || report.Score != 100 {
+		t.Fatalf("findings %+v, score %v, want none", report.Findings, report.Score)
+	}
+}
+
+func TestBlockRate(t *testing.T) {
+	t.Parallel()
+
+	// Noise modulated by 10% at the hop rate, on the second channel only.
+	modulated := noise(1, 4, 0.3)
+	for i := range modulated {
+		modulated[i] *= 1 + 0.1*math.Sin(2*math.Pi*float64(i)/hop)
+	}
+	findings := only(t, analyze(t, noise(2, 4, 0.3), modulated), qa.BlockRate, 1)
+	if len(findings) != 1 || findings[0].Start != 0 || findings[0].Duration < 3.9 {
+		t.Fatalf("findings = %+v, want one over the whole file", findings)
+	}
+	if findings[0].Value < 12 {
+		t.Fatalf("prominence = %v dB, want at least 12", findings[0].Value)
+	}
+
+	// The same modulation at another rate is not the block rate.
+	other := noise(1, 4, 0.3)
+	for i := range other {
+		other[i] *= 1 + 0.1*math.Sin(2*math.Pi*5*float64(i)/rate)
+	}
+	clean(t, analyze(t, other))
+
+	// Without a hop size the detector is off.
+	report, err := qa.Analyze([][]float64{modulated}, rate, qa.DefaultConfig(0))
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	clean(t, report)
+}
+
+func TestGainJump(t *testing.T) {
+	t.Parallel()
+
+	// Steering moves the signal from the first channel to the second at
+	// one second with the total level unchanged.
+	source := noise(3, 2, 0.5)
+	front := make([]float64, len(source))
+	back := make([]float64, len(source))
+	for i, v := range source {
+		if i < rate {
+			front[i], back[i] = v, 0.02*v
+		} else {
+			front[i], back[i] = 0.02*v, v
+		}
+	}
+	findings := analyze(t, front, back).Findings
+	if len(findings) != 2 {
+		t.Fatalf("findings = %+v, want a jump on each channel", findings)
+	}
+	for ch, f := range findings {
+		if f.Kind != qa.GainJump || math.Abs(f.Start+f.Duration/2-1) > 0.02 || f.Value < 30 {
+			t.Fatalf("channel %d finding = %+v, want a 34 dB jump at 1 s", ch, f)
+		}
+	}
+
+	// A programme onset raises every channel and is no jump.
+	onset := noise(4, 2, 0.5)
+	for i := range rate {
+		onset[i] *= 0.01
+	}
+	clean(t, analyze(t, onset, onset))
+}
+
+func TestClipping(t *testing.T) {
+	t.Parallel()
+
+	sine := func(amplitude float64) []float64 {
+		out := make([]float64, rate)
+		for i := range out {
+			v := amplitude * math.Sin(2*math.Pi*100*float64(i)/rate)
+			out[i] = max(-1, min(1, v))
+		}
+		return out
+	}
+	findings := only(t, analyze(t, sine(0.9), sine(1.5)), qa.Clipping, 1)
+	// 100 cycles of two flat tops each, merged into one finding.
+	if len(findings) != 1 || findings[0].Value < 100*2*100 {
+		t.Fatalf("findings = %+v, want one run of clipped tops", findings)
+	}
+	clean(t, analyze(t, sine(0.9)))
+}
+
+func TestDropout(t *testing.T) {
+	t.Parallel()
+
+	signal := noise(5, 1, 0.3)
+	for i := rate / 2; i < rate/2+rate/200; i++ {
+		signal[i] = 0
+	}
+	findings := only(t, analyze(t, signal), qa.Dropout, 0)
+	if len(findings) != 1 || math.Abs(findings[0].Start-0.5) > 1e-3 || math.Abs(findings[0].Duration-0.005) > 1e-3 {
+		t.Fatalf("findings = %+v, want a 5 ms gap at 0.5 s", findings)
+	}
+
+	// A fade to silence at the end is no dropout, nor is a pause longer
+	// than MaxDropout.
+	faded := noise(6, 2, 0.3)
+	for i := rate / 2; i < 11*rate/10; i++ {
+		faded[i] = 0
+	}
+	for i := 3 * rate / 2; i < len(faded); i++ {
+		faded[i] = 0
+	}
+	clean(t, analyze(t, faded))
+}
+
+func TestAnalyzer_Streaming(t *testing.T) {
+	t.Parallel()
+
+	signal := noise(7, 1, 0.3)
+	for i := rate / 2; i < rate/2+rate/100; i++ {
+		signal[i] = 0
+	}
+	signal[rate/4], signal[rate/4+1], signal[rate/4+2] = 1, 1, 1
+	want := analyze(t, signal)
+
+	a, err := qa.NewAnalyzer(rate, 1, qa.DefaultConfig(hop))
+	if err != nil {
+		t.Fatalf("NewAnalyzer() error = %v", err)
+	}
+	for i := 0; i < len(signal); i += 1000 {
+		if err := a.Add([][]float64{signal[i:min(i+1000, len(signal))]}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	got := a.Report()
+	if len(got.Findings) != 2 || len(want.Findings) != 2 || got.Score != want.Score {
+		t.Fatalf("streamed %+v, at once %+v, want the clipping and the dropout", got, want)
+	}
+	for i := range got.Findings {
+		if got.Findings[i] != want.Findings[i] {
+			t.Fatalf("finding %d streamed %+v, at once %+v", i, got.Findings[i], want.Findings[i])
+		}
+	}
+
+	if err := a.Add([][]float64{signal, signal}); err == nil {
+		t.Fatal("Add() with the wrong channel count succeeded")
+	}
+	if _, err := qa.NewAnalyzer(rate, 1, qa.Config{}); err == nil {
+		t.Fatal("NewAnalyzer() accepted an empty config")
+	}
+}
@@ -0,0 +1,323 @@
+// Package qualify measures the channel separation of an encode -> decode
+// round trip with band-limited pink noise and scores it against the
+// thresholds of a JSON profile.
+//
+// Every channel is driven alone, once per octave band, with pink noise
+// confined to that band. The leak into each other channel is measured in
+// the same band, so the result does not depend on where a tone happens to
+// fall, and every source/leak pair gets one figure per band.
+package qualify
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// MaxSeparationDB caps the measured separation: a leak more than this far
+// below its source counts as this much, which keeps a pair without any
+// leak finite in the scorecard.
+const MaxSeparationDB = 120
+
+// ChannelNames are the channels of a scorecard in order.
+var ChannelNames = [4]string{"LF", "RF", "LB", "RB"}
+
+//go:embed profiles/*.json
+var builtinProfiles embed.FS
+
+// Profile configures a qualification run and its pass/fail thresholds.
+type Profile struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// SampleRate and Duration set the length of every noise burst.
+	SampleRate int     `json:"sample_rate"`
+	Duration   float64 `json:"duration_seconds"`
+	// LevelDB is the RMS level of the noise in dBFS.
+	LevelDB float64 `json:"level_dbfs"`
+	// BlockSize and Overlap default to the decoder defaults.
+	BlockSize int `json:"block_size,omitempty"`
+	Overlap   int `json:"overlap,omitempty"`
+	// Matrix is "sq" (the default) or "qs".
+	Matrix string `json:"matrix,omitempty"`
+	// MinBandHz and MaxBandHz select the octave bands, by center
+	// frequency, that are measured.
+	MinBandHz  float64     `json:"min_band_hz"`
+	MaxBandHz  float64     `json:"max_band_hz"`
+	Thresholds []Threshold `json:"thresholds"`
+}
+
+// Threshold is the minimum separation of the leak of one source channel
+// into another, in every measured band from MinHz to MaxHz (by center
+// frequency; zero means no limit).
+type Threshold struct {
+	Source string  `json:"source"`
+	Leak   string  `json:"leak"`
+	MinDB  float64 `json:"min_db"`
+	MinHz  float64 `json:"min_hz,omitempty"`
+	MaxHz  float64 `json:"max_hz,omitempty"`
+}
+
+// applies reports whether the threshold covers the band centered on freq.
+func (t Threshold) applies(freq float64) bool {
+	return freq >= t.MinHz && (t.MaxHz <= 0 || freq <= t.MaxHz)
+}
+
+// BuiltinProfiles returns the names of the embedded profiles.
+func BuiltinProfiles() []string {
+	entries, err := builtinProfiles.ReadDir("profiles")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return names
+}
+
+// DefaultProfile returns the embedded default profile.
+func DefaultProfile() Profile {
+	p, err := loadBuiltin("default")
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// LoadProfile reads the profile file at path or, if there is no such
+// file, the embedded profile of that name, with or without ".json".
+func LoadProfile(path string) (Profile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		name := strings.TrimSuffix(path, ".json")
+		if slices.Contains(BuiltinProfiles(), name) {
+			return loadBuiltin(name)
+		}
+		return Profile{}, fmt.Errorf("no profile file %q and no built-in profile of that name (built-in: %s)", path, strings.Join(BuiltinProfiles(), ", "))
+	}
+	if err != nil {
+		return Profile{}, fmt.Errorf("failed to read profile: %w", err)
+	}
+	return parseProfile(data)
+}
+
+func loadBuiltin(name string) (Profile, error) {
+	data, err := builtinProfiles.ReadFile("profiles/" + name + ".json")
+	if err != nil {
+		return Profile{}, err
+	}
+	return parseProfile(data)
+}
+
+// parseProfile decodes and validates a profile; unknown fields are errors
+// so that a misspelt threshold does not pass silently.
+func parseProfile(data []byte) (Profile, error) {
+	var p Profile
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return Profile{}, fmt.Errorf("invalid profile: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return Profile{}, fmt.Errorf("invalid profile %q: %w", p.Name, err)
+	}
+	return p, nil
+}
+
+// Validate checks the settings and thresholds of p.
+func (p Profile) Validate() error {
+	if p.SampleRate <= 0 || p.Duration <= 0 {
+		return fmt.Errorf("sample_rate and duration_seconds must be positive")
+	}
+	if p.LevelDB > 0 {
+		return fmt.Errorf("level_dbfs must be at most 0, got %g", p.LevelDB)
+	}
+	if _, err := decoder.ParseMatrix(p.Matrix); err != nil {
+		return err
+	}
+	blockSize, overlap := p.blockParams()
+	if err := decoder.ValidateParams(blockSize, overlap); err != nil {
+		return err
+	}
+	if len(p.bands()) == 0 {
+		return fmt.Errorf("no octave band between %g and %g Hz", p.MinBandHz, p.MaxBandHz)
+	}
+	for _, t := range p.Thresholds {
+		src, leak := channelIndex(t.Source), channelIndex(t.Leak)
+		if src < 0 || leak < 0 || src == leak {
+			return fmt.Errorf("threshold %s -> %s: want two different channels of %s", t.Source, t.Leak, strings.Join(ChannelNames[:], ", "))
+		}
+	}
+	return nil
+}
+
+// blockParams returns the block size and overlap, defaulted.
+func (p Profile) blockParams() (int, int) {
+	blockSize, overlap := p.BlockSize, p.Overlap
+	if blockSize == 0 {
+		blockSize = decoder.DefaultBlockSize
+	}
+	if overlap == 0 {
+		overlap = blockSize * decoder.DefaultOverlap / decoder.DefaultBlockSize
+	}
+	return blockSize, overlap
+}
+
+// bands returns the measured octave bands.
+func (p Profile) bands() []metrics.Band {
+	var bands []metrics.Band
+	for _, b := range metrics.OctaveBands(p.SampleRate) {
+		if b.Center >= p.MinBandHz && (p.MaxBandHz <= 0 || b.Center <= p.MaxBandHz) {
+			bands = append(bands, b)
+		}
+	}
+	return bands
+}
+
+func channelIndex(name string) int {
+	for i, n := range ChannelNames {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Scorecard is the outcome of a qualification run.
+type Scorecard struct {
+	Profile string `json:"profile"`
+	// BandsHz are the center frequencies of the measured octave bands.
+	BandsHz []float64 `json:"bands_hz"`
+	// Separation[source][leak][band] is the level of source's own output
+	// over the level of leak's output in dB, with source driven alone.
+	// Entries of a source with itself are zero.
+	Separation [4][4][]float64 `json:"separation_db"`
+	Failures   []Failure       `json:"failures"`
+}
+
+// Failure is a band in which a pair misses its threshold.
+type Failure struct {
+	Source       string  `json:"source"`
+	Leak         string  `json:"leak"`
+	BandHz       float64 `json:"band_hz"`
+	SeparationDB float64 `json:"separation_db"`
+	MinDB        float64 `json:"min_db"`
+}
+
+// String describes the failure for reports.
+func (f Failure) String() string {
+	return fmt.Sprintf("%s -> %s at %g Hz: %.1f dB < %.1f dB", f.Source, f.Leak, f.BandHz, f.SeparationDB, f.MinDB)
+}
+
+// Passed reports whether every threshold was met.
+func (s Scorecard) Passed() bool {
+	return len(s.Failures) == 0
+}
+
+// Run measures every channel and band of p and scores the result.
+func Run(p Profile) (Scorecard, error) {
+	if err := p.Validate(); err != nil {
+		return Scorecard{}, err
+	}
+	m, _ := decoder.ParseMatrix(p.Matrix)
+	blockSize, overlap := p.blockParams()
+	options := []sq.Option{
+		sq.WithBlockSize(blockSize),
+		sq.WithOverlap(overlap),
+		sq.WithMatrix(m),
+		sq.WithSampleRate(p.SampleRate),
+	}
+	enc, err := sq.NewEncoder(options...)
+	if err != nil {
+		return Scorecard{}, err
+	}
+	dec, err := sq.NewDecoder(options...)
+	if err != nil {
+		return Scorecard{}, err
+	}
+
+	bands := p.bands()
+	card := Scorecard{Profile: p.Name}
+	for _, band := range bands {
+		card.BandsHz = append(card.BandsHz, band.Center)
+	}
+	numSamples := int(p.Duration * float64(p.SampleRate))
+	// The measurement leaves out the first block, where the decoder sees
+	// the zero padding before the burst, and is a power of 2 long for a
+	// fast FFT.
+	window := 1
+	for 2*window <= numSamples-2*blockSize {
+		window *= 2
+	}
+	if window < 2*blockSize {
+		return Scorecard{}, fmt.Errorf("duration of %d samples is too short for block size %d", numSamples, blockSize)
+	}
+	level := math.Pow(10, p.LevelDB/20)
+	for src := range 4 {
+		for leak := range 4 {
+			card.Separation[src][leak] = make([]float64, len(bands))
+		}
+	}
+
+	for b, band := range bands {
+		for src := range 4 {
+			quad := make([][]float64, 4)
+			for ch := range quad {
+				quad[ch] = make([]float64, numSamples)
+			}
+			quad[src] = testsignal.PinkNoise(p.SampleRate, numSamples, band.Low, band.High, level, int64(1+b*4+src))
+
+			enc.Reset()
+			dec.Reset()
+			stereo, err := enc.Process(quad)
+			if err != nil {
+				return Scorecard{}, fmt.Errorf("encoding failed: %w", err)
+			}
+			decoded, err := dec.Process(stereo)
+			if err != nil {
+				return Scorecard{}, fmt.Errorf("decoding failed: %w", err)
+			}
+			for ch := range decoded {
+				decoded[ch] = decoded[ch][blockSize : blockSize+window]
+			}
+
+			options := metrics.SeparationOptions{
+				SampleRate:     p.SampleRate,
+				FMin:           band.Low,
+				FMax:           band.High,
+				AnalysisWindow: sqmath.WindowHann,
+			}
+			for leak, db := range metrics.Crosstalk(decoded, src, options) {
+				if leak != src {
+					card.Separation[src][leak][b] = min(-db, MaxSeparationDB)
+				}
+			}
+		}
+	}
+
+	for _, t := range p.Thresholds {
+		src, leak := channelIndex(t.Source), channelIndex(t.Leak)
+		for b, band := range bands {
+			if sep := card.Separation[src][leak][b]; t.applies(band.Center) && sep < t.MinDB {
+				card.Failures = append(card.Failures, Failure{
+					Source:       ChannelNames[src],
+					Leak:         ChannelNames[leak],
+					BandHz:       band.Center,
+					SeparationDB: sep,
+					MinDB:        t.MinDB,
+				})
+			}
+		}
+	}
+	return card, nil
+}
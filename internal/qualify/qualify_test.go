@@ -0,0 +1,98 @@
+package qualify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/qualify"
+)
+
+func TestRun_DefaultProfilePasses(t *testing.T) {
+	t.Parallel()
+
+	card, err := qualify.Run(qualify.DefaultProfile())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !card.Passed() {
+		t.Fatalf("default profile failed: %v", card.Failures)
+	}
+	if len(card.BandsHz) != 9 || card.BandsHz[0] != 62.5 || card.BandsHz[8] != 16000 {
+		t.Fatalf("bands = %v, want the 9 octaves from 62.5 Hz to 16 kHz", card.BandsHz)
+	}
+	for src := range 4 {
+		for leak := range 4 {
+			if got := len(card.Separation[src][leak]); got != len(card.BandsHz) {
+				t.Fatalf("%d -> %d: %d bands, want %d", src, leak, got, len(card.BandsHz))
+			}
+		}
+	}
+	// SQ feeds a front source into the diagonally opposite back channel
+	// at -3 dB.
+	for _, sep := range card.Separation[0][3] {
+		if sep < 2 || sep > 4 {
+			t.Fatalf("LF -> RB = %.2f dB, want about 3", sep)
+		}
+	}
+}
+
+func TestRun_ReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	p := qualify.DefaultProfile()
+	p.MinBandHz, p.MaxBandHz = 500, 2000
+	p.Thresholds = []qualify.Threshold{
+		{Source: "LF", Leak: "RB", MinDB: 10, MinHz: 1000},
+		{Source: "LF", Leak: "RF", MinDB: 30},
+	}
+	card, err := qualify.Run(p)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// The 1 and 2 kHz bands miss the LF -> RB threshold; 500 Hz is not
+	// checked.
+	if len(card.Failures) != 2 {
+		t.Fatalf("failures = %v, want LF -> RB at 1000 and 2000 Hz", card.Failures)
+	}
+	for i, f := range card.Failures {
+		if f.Source != "LF" || f.Leak != "RB" || f.BandHz != []float64{1000, 2000}[i] {
+			t.Fatalf("failure %d = %v", i, f)
+		}
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"default", "default.json"} {
+		p, err := qualify.LoadProfile(name)
+		if err != nil || p.Name != "default" {
+			t.Fatalf("LoadProfile(%q) = %q, %v; want the built-in default", name, p.Name, err)
+		}
+	}
+
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		json  string
+		valid bool
+	}{
+		{`{"name": "x", "sample_rate": 48000, "duration_seconds": 0.5, "level_dbfs": -20, "min_band_hz": 1000, "max_band_hz": 1000, "thresholds": [{"source": "lb", "leak": "LF", "min_db": 3}]}`, true},
+		{`{"name": "x", "sample_rate": 48000, "duration_seconds": 0.5, "level_dbfs": -20, "min_band_hz": 1000, "max_band_hz": 1000, "treshold": []}`, false},
+		{`{"name": "x", "sample_rate": 48000, "duration_seconds": 0.5, "level_dbfs": -20, "min_band_hz": 1000, "max_band_hz": 1000, "thresholds": [{"source": "LF", "leak": "LF", "min_db": 3}]}`, false},
+		{`{"name": "x", "sample_rate": 48000, "duration_seconds": 0.5, "level_dbfs": -20, "min_band_hz": 1000, "max_band_hz": 900}`, false},
+		{`{"name": "x", "sample_rate": 48000, "duration_seconds": 0.5, "level_dbfs": -20, "block_size": 1000, "min_band_hz": 1000, "max_band_hz": 1000}`, false},
+	} {
+		path := filepath.Join(dir, "profile.json")
+		if err := os.WriteFile(path, []byte(tc.json), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := qualify.LoadProfile(path)
+		if (err == nil) != tc.valid {
+			t.Fatalf("LoadProfile(%s) error = %v, want valid %t", tc.json, err, tc.valid)
+		}
+	}
+	if _, err := qualify.LoadProfile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatalf("LoadProfile of a missing file succeeded")
+	}
+}
@@ -0,0 +1,149 @@
+// Package repair detects and corrects the two most common faults of SQ
+// stereo rips: swapped LT/RT channels and one channel with inverted
+// polarity. Both turn an otherwise fine decode into a mirrored or phasey
+// image.
+package repair
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// polarityThreshold is the bass correlation below which an inverted
+	// channel is suspected.
+	polarityThreshold = -0.2
+	// swapThreshold is the side coherence below which swapped channels are
+	// suspected. Side coherence is diluted by overlapping sources, so even
+	// small negative values are meaningful.
+	swapThreshold = -0.05
+	// polarityCertain and swapCertain are the statistic magnitudes at which
+	// a finding is reported with full confidence.
+	polarityCertain = 0.6
+	swapCertain     = 0.3
+)
+
+// Finding is the verdict on one fault.
+type Finding struct {
+	Suspected bool
+	// Confidence in [0, 1] of the verdict, suspected or not. It grows with
+	// the distance of the statistic from 0; material without bass or side
+	// images gives a low confidence either way.
+	Confidence float64
+}
+
+// Report is the result of Detect.
+type Report struct {
+	// BassCorrelation is the LT/RT correlation below 150 Hz, see
+	// BassCorrelation.
+	BassCorrelation float64
+	// SideCoherence is the side image consistency, see SideCoherence.
+	SideCoherence float64
+	// Inverted is the verdict on an inverted channel, Swapped the verdict
+	// on swapped LT/RT.
+	Inverted Finding
+	Swapped  Finding
+}
+
+// Detect checks stereo (LT, RT) for swapped channels and an inverted
+// channel. The two statistics are independent: a swap leaves the bass
+// correlation unchanged and a polarity inversion leaves the side coherence
+// unchanged, so both faults can be detected at once.
+func Detect(stereo [][]float64, sampleRate int) (Report, error) {
+	if len(stereo) != 2 {
+		return Report{}, fmt.Errorf("detection needs 2 channels, got %d", len(stereo))
+	}
+	side, err := SideCoherence(stereo[0], stereo[1], sampleRate)
+	if err != nil {
+		return Report{}, err
+	}
+	bass := BassCorrelation(stereo[0], stereo[1], sampleRate)
+	return Report{
+		BassCorrelation: bass,
+		SideCoherence:   side,
+		Inverted:        finding(bass, polarityThreshold, polarityCertain),
+		Swapped:         finding(side, swapThreshold, swapCertain),
+	}, nil
+}
+
+func finding(value, threshold, certain float64) Finding {
+	return Finding{
+		Suspected:  value < threshold,
+		Confidence: math.Min(math.Abs(value)/certain, 1),
+	}
+}
+
+// Correction is a set of fixes for a stereo pair.
+type Correction struct {
+	// Swap exchanges LT and RT.
+	Swap bool
+	// Invert inverts RT (after Swap). Which channel was inverted cannot be
+	// told from the signal; inverting the other one instead only inverts
+	// the whole decode.
+	Invert bool
+}
+
+// Correction returns the fixes for the suspected faults.
+func (r Report) Correction() Correction {
+	return Correction{Swap: r.Swapped.Suspected, Invert: r.Inverted.Suspected}
+}
+
+// IsZero reports whether c changes nothing.
+func (c Correction) IsZero() bool {
+	return c == Correction{}
+}
+
+func (c Correction) String() string {
+	var fixes []string
+	if c.Swap {
+		fixes = append(fixes, "swap LT/RT")
+	}
+	if c.Invert {
+		fixes = append(fixes, "invert RT")
+	}
+	if len(fixes) == 0 {
+		return "none"
+	}
+	return strings.Join(fixes, ", ")
+}
+
+// Apply applies c to stereo in place.
+func Apply(stereo [][]float64, c Correction) error {
+	if len(stereo) != 2 {
+		return fmt.Errorf("correction needs 2 channels, got %d", len(stereo))
+	}
+	if c.Swap {
+		stereo[0], stereo[1] = stereo[1], stereo[0]
+	}
+	if c.Invert {
+		for i, v := range stereo[1] {
+			stereo[1][i] = -v
+		}
+	}
+	return nil
+}
+
+// PairCorrelation is the bass correlation of two quad channels.
+type PairCorrelation struct {
+	A, B        int
+	Correlation float64
+}
+
+// InvertedQuadPairs checks discrete LF, RF, LB, RB for an inverted channel
+// and returns the suspect pairs. Like LT and RT, the front pair and the
+// back pair of a quad mix share their bass in phase; an anti-phase pair
+// usually means that one of its channels was inverted on the way.
+func InvertedQuadPairs(quad [][]float64, sampleRate int) ([]PairCorrelation, error) {
+	if len(quad) != 4 {
+		return nil, fmt.Errorf("quad check needs 4 channels, got %d", len(quad))
+	}
+	var suspect []PairCorrelation
+	for _, pair := range [][2]int{{0, 1}, {2, 3}} {
+		corr := BassCorrelation(quad[pair[0]], quad[pair[1]], sampleRate)
+		if corr < polarityThreshold {
+			suspect = append(suspect, PairCorrelation{A: pair[0], B: pair[1], Correlation: corr})
+		}
+	}
+	return suspect, nil
+}
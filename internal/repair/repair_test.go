@@ -0,0 +1,251 @@
+package repair_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+	"github.com/cwbudde/go-sq-tool/internal/repair"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const (
+	sampleRate = 44100
+	numSamples = 1 << 17
+)
+
+// hilbert returns the 90° shifted x, computed over the whole signal. The
+// test material needs the full-level quadrature of a real SQ record.
+func hilbert(t *testing.T, x []float64) []float64 {
+	t.Helper()
+	plan, err := algofft.NewPlan64(len(x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]complex128, len(x))
+	for i, v := range x {
+		buf[i] = complex(v, 0)
+	}
+	spec := make([]complex128, len(x))
+	if err := plan.Forward(spec, buf); err != nil {
+		t.Fatal(err)
+	}
+	spec[0], spec[len(x)/2] = 0, 0
+	for k := 1; k < len(x)/2; k++ {
+		spec[k] *= -1i
+		spec[len(x)-k] *= 1i
+	}
+	if err := plan.Inverse(buf, spec); err != nil {
+		t.Fatal(err)
+	}
+	out := make([]float64, len(x))
+	for i := range out {
+		out[i] = real(buf[i])
+	}
+	return out
+}
+
+// encode applies the SQ encode matrix to LF, RF, LB, RB.
+func encode(t *testing.T, quad [][]float64) [][]float64 {
+	t.Helper()
+	hlb, hrb := hilbert(t, quad[2]), hilbert(t, quad[3])
+	a := math.Sqrt2 / 2
+	stereo := [][]float64{make([]float64, numSamples), make([]float64, numSamples)}
+	for i := range stereo[0] {
+		stereo[0][i] = quad[0][i] + a*quad[3][i] - a*hlb[i]
+		stereo[1][i] = quad[1][i] - a*quad[2][i] + a*hrb[i]
+	}
+	return stereo
+}
+
+// harmonics returns a tone at f0 with partials up to 6 kHz.
+func harmonics(f0, phase float64) []float64 {
+	out := make([]float64, numSamples)
+	for h := 1.0; f0*h < 6000; h++ {
+		for i := range out {
+			out[i] += 0.1 / h * math.Sin(2*math.Pi*f0*h*float64(i)/sampleRate+phase*h)
+		}
+	}
+	return out
+}
+
+func bass(seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	lp := sqmath.NewLowpass(sampleRate, 100, sqmath.ButterworthQ)
+	out := make([]float64, numSamples)
+	for i := range out {
+		out[i] = lp.Process(0.5 * rng.NormFloat64())
+	}
+	return out
+}
+
+// mix is a quad mix with centered bass, a center front voice and one
+// instrument on each side, shared by its front and back speaker.
+func mix(t *testing.T) [][]float64 {
+	t.Helper()
+	low, voice := bass(1), harmonics(470, 0.3)
+	left, right := harmonics(200, 1.1), harmonics(310, 2.2)
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = make([]float64, numSamples)
+	}
+	for i := range quad[0] {
+		quad[0][i] = low[i] + voice[i] + 0.7*left[i]
+		quad[1][i] = low[i] + voice[i] + 0.7*right[i]
+		quad[2][i] = 0.7 * left[i]
+		quad[3][i] = 0.7 * right[i]
+	}
+	return encode(t, quad)
+}
+
+func negate(x []float64) []float64 {
+	out := make([]float64, len(x))
+	for i, v := range x {
+		out[i] = -v
+	}
+	return out
+}
+
+func TestBassCorrelation(t *testing.T) {
+	t.Parallel()
+
+	low := bass(2)
+	if got := repair.BassCorrelation(low, low, sampleRate); math.Abs(got-1) > 1e-9 {
+		t.Fatalf("in phase = %v, want 1", got)
+	}
+	if got := repair.BassCorrelation(low, negate(low), sampleRate); math.Abs(got+1) > 1e-9 {
+		t.Fatalf("anti-phase = %v, want -1", got)
+	}
+	if got := repair.BassCorrelation(low, make([]float64, len(low)), sampleRate); got != 0 {
+		t.Fatalf("against silence = %v, want 0", got)
+	}
+	// Unrelated bass lines do not correlate.
+	if got := repair.BassCorrelation(low, bass(3), sampleRate); math.Abs(got) > 0.1 {
+		t.Fatalf("independent = %v, want about 0", got)
+	}
+}
+
+func TestSideCoherence(t *testing.T) {
+	t.Parallel()
+
+	tone := harmonics(250, 0)
+	silent := make([]float64, numSamples)
+	coherence := func(quad [][]float64, swap, invert bool) float64 {
+		stereo := encode(t, quad)
+		if err := repair.Apply(stereo, repair.Correction{Swap: swap, Invert: invert}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repair.SideCoherence(stereo[0], stereo[1], sampleRate)
+		if err != nil {
+			t.Fatalf("SideCoherence() error = %v", err)
+		}
+		return got
+	}
+
+	for _, tt := range []struct {
+		name string
+		quad [][]float64
+	}{
+		{"left side", [][]float64{tone, silent, tone, silent}},
+		{"right side", [][]float64{silent, tone, silent, tone}},
+	} {
+		plain := coherence(tt.quad, false, false)
+		if plain < 0.5 {
+			t.Fatalf("%s: %v, want > 0.5", tt.name, plain)
+		}
+		if swapped := coherence(tt.quad, true, false); math.Abs(swapped+plain) > 1e-9 {
+			t.Fatalf("%s swapped: %v, want %v", tt.name, swapped, -plain)
+		}
+		if inverted := coherence(tt.quad, false, true); math.Abs(inverted-plain) > 1e-9 {
+			t.Fatalf("%s inverted: %v, want %v", tt.name, inverted, plain)
+		}
+	}
+
+	// Front, back and diagonal sources carry no channel order information.
+	for name, quad := range map[string][][]float64{
+		"center front": {tone, tone, silent, silent},
+		"left back":    {silent, silent, tone, silent},
+		"diagonal":     {tone, silent, silent, tone},
+	} {
+		if got := coherence(quad, false, false); math.Abs(got) > 1e-6 {
+			t.Fatalf("%s: %v, want 0", name, got)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	original := mix(t)
+	for _, fault := range []repair.Correction{
+		{},
+		{Swap: true},
+		{Invert: true},
+		{Swap: true, Invert: true},
+	} {
+		// Apply works in place.
+		stereo := [][]float64{append([]float64(nil), original[0]...), append([]float64(nil), original[1]...)}
+		if err := repair.Apply(stereo, fault); err != nil {
+			t.Fatal(err)
+		}
+		report, err := repair.Detect(stereo, sampleRate)
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		// The correction of each fault is the fault itself, up to the
+		// polarity of the whole pair.
+		if got := report.Correction(); got != fault {
+			t.Fatalf("fault %v: detected %v (report %+v)", fault, got, report)
+		}
+		if report.Inverted.Confidence < 0.9 || report.Swapped.Confidence < 0.5 {
+			t.Fatalf("fault %v: low confidence %+v", fault, report)
+		}
+
+		if err := repair.Apply(stereo, report.Correction()); err != nil {
+			t.Fatal(err)
+		}
+		fixed, err := repair.Detect(stereo, sampleRate)
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if !fixed.Correction().IsZero() {
+			t.Fatalf("fault %v: still detected %v after fixing", fault, fixed.Correction())
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	stereo := [][]float64{{1, 2}, {3, 4}}
+	if err := repair.Apply(stereo, repair.Correction{Swap: true, Invert: true}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if stereo[0][0] != 3 || stereo[0][1] != 4 || stereo[1][0] != -1 || stereo[1][1] != -2 {
+		t.Fatalf("Apply() = %v, want [[3 4] [-1 -2]]", stereo)
+	}
+	if err := repair.Apply([][]float64{{1}}, repair.Correction{Swap: true}); err == nil {
+		t.Fatalf("Apply(mono) expected error")
+	}
+	if got := (repair.Correction{Swap: true, Invert: true}).String(); got != "swap LT/RT, invert RT" {
+		t.Fatalf("String() = %q", got)
+	}
+}
+
+func TestInvertedQuadPairs(t *testing.T) {
+	t.Parallel()
+
+	front, back := bass(4), bass(5)
+	quad := [][]float64{front, front, back, negate(back)}
+	pairs, err := repair.InvertedQuadPairs(quad, sampleRate)
+	if err != nil {
+		t.Fatalf("InvertedQuadPairs() error = %v", err)
+	}
+	if len(pairs) != 1 || pairs[0].A != 2 || pairs[0].B != 3 || pairs[0].Correlation > -0.99 {
+		t.Fatalf("InvertedQuadPairs() = %+v, want the back pair", pairs)
+	}
+	if _, err := repair.InvertedQuadPairs(quad[:2], sampleRate); err == nil {
+		t.Fatalf("InvertedQuadPairs(stereo) expected error")
+	}
+}
@@ -0,0 +1,117 @@
+package repair
+
+import (
+	"fmt"
+	"math"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const (
+	// bassCutoff is the upper edge of the band BassCorrelation looks at.
+	bassCutoff = 150.0
+	// sideLow and sideHigh bound the band SideCoherence looks at: above
+	// the mostly centered bass and below the range where cartridge phase
+	// errors dominate.
+	sideLow, sideHigh = 150.0, 6000.0
+	// sideFFTSize is the STFT size of SideCoherence.
+	sideFFTSize = 2048
+)
+
+// BassCorrelation returns the zero-lag correlation of a and b below 150 Hz,
+// in [-1, 1]. SQ keeps bass in phase: of the four speakers only center back
+// is encoded anti-phase, and records avoid anti-phase bass, which the
+// cutting lathe turns into vertical groove modulation. A strongly negative
+// value therefore points to an inverted channel. Silence yields 0.
+func BassCorrelation(a, b []float64, sampleRate int) float64 {
+	n := min(len(a), len(b))
+	if n == 0 || sampleRate <= 0 {
+		return 0
+	}
+	freq := math.Min(bassCutoff, 0.45*float64(sampleRate))
+	// 4th-order Linkwitz-Riley low-pass, the same on both channels.
+	var filters [2][2]sqmath.Biquad
+	for ch := range filters {
+		for i := range filters[ch] {
+			filters[ch][i] = sqmath.NewLowpass(float64(sampleRate), freq, sqmath.ButterworthQ)
+		}
+	}
+	var ab, aa, bb float64
+	for i := 0; i < n; i++ {
+		x := filters[0][1].Process(filters[0][0].Process(a[i]))
+		y := filters[1][1].Process(filters[1][0].Process(b[i]))
+		ab += x * y
+		aa += x * x
+		bb += y * y
+	}
+	if aa == 0 || bb == 0 {
+		return 0
+	}
+	return ab / math.Sqrt(aa*bb)
+}
+
+// SideCoherence measures in which channel order the quadrature content of
+// lt and rt is consistent with SQ, in [-1, 1].
+//
+// A source shared by the front and back speakers of one side, such as a
+// pan from LF to LB, reaches LT and RT with a cross-spectrum LT·conj(RT)
+// whose phase lies in the first or third quadrant: the back contributes an
+// in-phase term to one channel and a 90° term to the other. Swapping LT and
+// RT conjugates the cross-spectrum and moves it to the second or fourth
+// quadrant, where no SQ position lies. Front, back and diagonal sources have
+// a phase of 0°, ±90° or 180° and do not contribute, and neither does a
+// polarity inversion, which only adds 180°.
+//
+// The result is the magnitude-weighted mean of sin(2φ) over the STFT bins
+// between 150 Hz and 6 kHz: positive for SQ order, negative for swapped
+// channels, near 0 when the material has no side images or its sources
+// overlap too much in time and frequency to tell.
+func SideCoherence(lt, rt []float64, sampleRate int) (float64, error) {
+	if sampleRate <= 0 {
+		return 0, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	plan, err := algofft.NewPlan64(sideFFTSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create FFT plan: %w", err)
+	}
+	window, err := sqmath.Window(sqmath.WindowHann, sideFFTSize)
+	if err != nil {
+		return 0, err
+	}
+	lo := int(math.Ceil(sideLow * sideFFTSize / float64(sampleRate)))
+	hi := min(int(sideHigh*sideFFTSize/float64(sampleRate)), sideFFTSize/2)
+
+	frameL := make([]complex128, sideFFTSize)
+	frameR := make([]complex128, sideFFTSize)
+	specL := make([]complex128, sideFFTSize)
+	specR := make([]complex128, sideFFTSize)
+	n := min(len(lt), len(rt))
+	var sum, weight float64
+	for start := 0; start+sideFFTSize <= n; start += sideFFTSize / 2 {
+		for i := range frameL {
+			frameL[i] = complex(lt[start+i]*window[i], 0)
+			frameR[i] = complex(rt[start+i]*window[i], 0)
+		}
+		if err := plan.Forward(specL, frameL); err != nil {
+			return 0, err
+		}
+		if err := plan.Forward(specR, frameR); err != nil {
+			return 0, err
+		}
+		for k := lo; k < hi; k++ {
+			c := specL[k] * complex(real(specR[k]), -imag(specR[k]))
+			m := math.Hypot(real(c), imag(c))
+			if m == 0 {
+				continue
+			}
+			// sin(2φ)·|c| = 2·Re·Im / |c|
+			sum += 2 * real(c) * imag(c) / m
+			weight += m
+		}
+	}
+	if weight == 0 {
+		return 0, nil
+	}
+	return sum / weight, nil
+}
@@ -0,0 +1,88 @@
+// Package report renders separation measurements as a single
+// self-contained HTML file: the tables as HTML, the charts as inline SVG,
+// with no scripts or external resources, so it can be mailed or archived
+// and opened anywhere.
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+)
+
+//go:embed report.html.tmpl
+var pageSource string
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"db":      FormatDB,
+	"rms":     func(v float64) string { return fmt.Sprintf("%.6f", v) },
+	"hz":      formatHz,
+	"heatmap": heatmap,
+	"bands":   bandChart,
+	"time":    timeChart,
+}).Parse(pageSource))
+
+// Analysis is the content of a report.
+type Analysis struct {
+	Title     string
+	Input     string
+	Generated time.Time
+	// Config lists the processing parameters in display order.
+	Config []Param
+	// Channels names the decoded channels, LF RF LB RB.
+	Channels []string
+	// Separation is the result of ChannelSeparation for each channel
+	// encoded and decoded on its own.
+	Separation []metrics.SeparationResult
+	Pairs      []Pair
+	// Crosstalk[source][output] is the level of each output relative to
+	// the source's own channel in dB, from metrics.Crosstalk.
+	Crosstalk [][]float64
+	// BandSeparation[source][band] is the separation of each channel per
+	// band in dB.
+	Bands          []metrics.Band
+	BandSeparation [][]float64
+	// TimeSeparation[source][window] is the separation of each channel in
+	// consecutive windows of TimeWindow seconds.
+	TimeWindow     float64
+	TimeSeparation [][]float64
+	InputBalance   metrics.BalanceReport
+	DecodedBalance metrics.BalanceReport
+}
+
+// Param is a named setting shown in the configuration table.
+type Param struct {
+	Name, Value string
+}
+
+// Pair is the separation of one channel from its neighbour.
+type Pair struct {
+	Name         string
+	SeparationDB float64
+}
+
+// WriteHTML renders a as an HTML page to w.
+func WriteHTML(w io.Writer, a Analysis) error {
+	if err := page.Execute(w, a); err != nil {
+		return fmt.Errorf("render report: %w", err)
+	}
+	return nil
+}
+
+// FormatDB formats a level in dB with two decimals, or as +Inf / -Inf.
+func FormatDB(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return fmt.Sprintf("%.2f", v)
+}
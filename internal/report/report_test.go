@@ -0,0 +1,72 @@
+package report_test
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/report"
+)
+
+func TestWriteHTML(t *testing.T) {
+	t.Parallel()
+
+	a := report.Analysis{
+		Title:     "Separation analysis",
+		Input:     "<quad>.wav",
+		Generated: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Config:    []report.Param{{Name: "block_size", Value: "1024"}},
+		Channels:  []string{"LF", "RF", "LB", "RB"},
+		Separation: []metrics.SeparationResult{
+			{TargetRMS: 0.5, LeakRMS: 0.35, SeparationDB: 3.01},
+			{TargetRMS: 0.5, LeakRMS: 0.35, SeparationDB: 3.02},
+			{TargetRMS: 0.5, LeakRMS: 0.35, SeparationDB: 3.03},
+			{TargetRMS: 0.5, LeakRMS: 0, SeparationDB: math.Inf(1)},
+		},
+		Pairs: []report.Pair{{Name: "LB->RB", SeparationDB: 68.21}},
+		Crosstalk: [][]float64{
+			{0, math.Inf(-1), -3.01, -3.01},
+			{math.Inf(-1), 0, -3.01, -3.01},
+			{-3.01, -3.01, 0, -68.21},
+			{-3.01, -3.01, -68.21, 0},
+		},
+		Bands:          metrics.OctaveBands(8000),
+		BandSeparation: [][]float64{{1, 2, 3, 4, 5, 6, 7}, {1, 2, 3, 4, 5, 6, 7}, {1, 2, 3, 4, 5, 6, 7}, {1, 2, 3, 4, 5, 6, 42.5}},
+		TimeWindow:     1,
+		TimeSeparation: [][]float64{{3, 3}, {3, 3}, {3, 3}, {math.Inf(1), 3}},
+		DecodedBalance: metrics.BalanceReport{LeftRightDB: 0.25, FrontBackDB: 2.99},
+	}
+	var buf bytes.Buffer
+	if err := report.WriteHTML(&buf, a); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"&lt;quad&gt;.wav", // text is escaped, "+" as &#43;
+		"block_size", "1024",
+		"3.02", "&#43;Inf", "68.21", "-68.21", "-Inf", "42.50", "2.99",
+		"<svg", "<polyline",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("report lacks %q", want)
+		}
+	}
+	for _, external := range []string{"<script", "src=", "http://", "https://"} {
+		if strings.Contains(strings.ReplaceAll(html, `xmlns="http://www.w3.org/2000/svg"`, ""), external) {
+			t.Fatalf("report contains %q; it must be self-contained", external)
+		}
+	}
+}
+
+func TestWriteHTML_Empty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := report.WriteHTML(&buf, report.Analysis{Title: "empty"}); err != nil {
+		t.Fatalf("WriteHTML() of an empty analysis error = %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+)
+
+// Chart geometry in SVG user units.
+const (
+	chartWidth   = 640
+	chartHeight  = 300
+	chartLeft    = 56
+	chartRight   = 96
+	chartTop     = 16
+	chartBottom  = 40
+	heatmapCell  = 64
+	heatmapFloor = -60.0
+)
+
+// seriesColors are the line colors of the channels, in order.
+var seriesColors = []string{"#1f77b4", "#d62728", "#2ca02c", "#9467bd", "#ff7f0e", "#8c564b"}
+
+// bandChart plots the separation per band of every channel over the band
+// center frequencies.
+func bandChart(bands []metrics.Band, values [][]float64, names []string) template.HTML {
+	labels := make([]string, len(bands))
+	for i, band := range bands {
+		labels[i] = formatHz(band.Center)
+	}
+	return lineChart(labels, values, names, "Frequency (Hz)")
+}
+
+// timeChart plots the separation of every channel over time.
+func timeChart(window float64, values [][]float64, names []string) template.HTML {
+	n := 0
+	for _, v := range values {
+		n = max(n, len(v))
+	}
+	labels := make([]string, n)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("%g", float64(i)*window)
+	}
+	return lineChart(labels, values, names, "Time (s)")
+}
+
+// lineChart draws one polyline per series over evenly spaced x positions.
+// Infinite values are drawn at the top of the scale, as open circles.
+func lineChart(labels []string, values [][]float64, names []string, xTitle string) template.HTML {
+	if len(labels) == 0 {
+		return ""
+	}
+	lo, hi := dbRange(values)
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	x := func(i int) float64 {
+		if len(labels) == 1 {
+			return chartLeft + plotW/2
+		}
+		return chartLeft + plotW*float64(i)/float64(len(labels)-1)
+	}
+	y := func(v float64) float64 {
+		v = math.Max(math.Min(v, hi), lo)
+		return chartTop + plotH*(hi-v)/(hi-lo)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img">`,
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	step := gridStep(hi - lo)
+	for v := lo; v <= hi+1e-9; v += step {
+		fmt.Fprintf(&b, `<line x1="%d" x2="%.1f" y1="%.1f" y2="%.1f" stroke="#ddd"/>`, chartLeft, chartLeft+plotW, y(v), y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" font-size="11" text-anchor="end">%g</text>`, chartLeft-6, y(v)+4, v)
+	}
+	labelEvery := max(1, len(labels)/12)
+	for i, label := range labels {
+		if i%labelEvery != 0 {
+			continue
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="11" text-anchor="middle">%s</text>`,
+			x(i), chartTop+plotH+16, template.HTMLEscapeString(label))
+	}
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" font-size="12" text-anchor="middle">%s</text>`,
+		chartLeft+plotW/2, chartHeight-4, template.HTMLEscapeString(xTitle))
+	fmt.Fprintf(&b, `<text x="14" y="%.1f" font-size="12" text-anchor="middle" transform="rotate(-90 14 %.1f)">Separation (dB)</text>`,
+		chartTop+plotH/2, chartTop+plotH/2)
+
+	for s, series := range values {
+		color := seriesColors[s%len(seriesColors)]
+		var points []string
+		for i, v := range series {
+			if math.IsNaN(v) {
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), y(v)))
+			if math.IsInf(v, 0) {
+				fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="none" stroke="%s"/>`, x(i), y(v), color)
+			}
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(points, " "))
+		if s < len(names) {
+			ly := chartTop + 8 + 18*s
+			fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%d" y2="%d" stroke="%s" stroke-width="2"/>`,
+				chartWidth-chartRight+12, chartWidth-chartRight+32, ly, ly, color)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12">%s</text>`,
+				chartWidth-chartRight+38, ly+4, template.HTMLEscapeString(names[s]))
+		}
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// dbRange returns a scale in multiples of 10 dB covering the finite values
+// and 0 dB, with room above the largest for infinite values.
+func dbRange(values [][]float64) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, series := range values {
+		for _, v := range series {
+			if !math.IsInf(v, 0) && !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	if math.IsInf(lo, 1) {
+		return 0, 60
+	}
+	lo = math.Min(math.Floor(lo/10)*10, 0)
+	hi = math.Ceil(hi/10)*10 + 10
+	return lo, hi
+}
+
+// gridStep returns the spacing of the horizontal grid lines for a range.
+func gridStep(span float64) float64 {
+	step := 10.0
+	for span/step > 8 {
+		step *= 2
+	}
+	return step
+}
+
+// heatmap draws the crosstalk matrix, one row per source and one column
+// per output, shaded from white at heatmapFloor dB and below to dark red at
+// 0 dB.
+func heatmap(levels [][]float64, names []string) template.HTML {
+	const labelW = 48
+	size := len(names)
+	width := labelW + heatmapCell*size
+	height := labelW + heatmapCell*len(levels)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img">`,
+		width, height, width, height)
+	for i, name := range names {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12" text-anchor="middle">%s</text>`,
+			labelW+heatmapCell*i+heatmapCell/2, labelW-10, template.HTMLEscapeString(name))
+	}
+	for src, row := range levels {
+		top := labelW + heatmapCell*src
+		if src < size {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12" text-anchor="end">%s</text>`,
+				labelW-8, top+heatmapCell/2+4, template.HTMLEscapeString(names[src]))
+		}
+		for out, v := range row {
+			left := labelW + heatmapCell*out
+			shade := 0.0
+			if !math.IsNaN(v) {
+				shade = math.Max(0, math.Min(1, 1-v/heatmapFloor))
+			}
+			r := 255 - int(116*shade)
+			gb := 255 - int(255*shade)
+			text := "#000"
+			if shade > 0.6 {
+				text = "#fff"
+			}
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="rgb(%d,%d,%d)" stroke="#fff"/>`,
+				left, top, heatmapCell, heatmapCell, r, gb, gb)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12" text-anchor="middle" fill="%s">%s</text>`,
+				left+heatmapCell/2, top+heatmapCell/2+4, text, FormatDB(v))
+		}
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// formatHz formats a band center: 31, 63, 125, ..., 1k, 2k, 16k.
+func formatHz(f float64) string {
+	if f >= 1000 {
+		return fmt.Sprintf("%gk", math.Round(f/100)/10)
+	}
+	return fmt.Sprintf("%.0f", math.Round(f))
+}
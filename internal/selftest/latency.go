@@ -0,0 +1,134 @@
+package selftest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+)
+
+// latencyPushFrames is the block size MeasureLatency pushes through the
+// streams; it is deliberately not a divisor of any block size.
+const latencyPushFrames = 441
+
+// ChannelLatency is where an impulse in one quad channel comes out of the
+// same channel after encode -> decode, as an offset in frames from the
+// impulse position.
+type ChannelLatency struct {
+	// Offline is the offset in the output of Process on the whole signal,
+	// as the CLI writes it. It is negative where the output leads.
+	Offline int
+	// Stream is the offset through a pipeline.Stream per codec, as a
+	// real-time host such as the C library sees it.
+	Stream int
+}
+
+// LatencyReport is the outcome of MeasureLatency.
+type LatencyReport struct {
+	Config Config
+	// Theoretical is the sum of the encoder's and the decoder's
+	// GetLatency.
+	Theoretical int
+	// StreamDelay is the buffering delay the two streams add.
+	StreamDelay int
+	// Channels holds the measurement for LF, RF, LB, RB.
+	Channels [4]ChannelLatency
+}
+
+// MeasureLatency places a single impulse in each quad channel in turn,
+// surrounded by silence, runs it through encode -> decode with config and
+// locates the peak of the same output channel, once for offline and once
+// for streamed processing.
+func MeasureLatency(config Config) (LatencyReport, error) {
+	report := LatencyReport{
+		Config:      config,
+		Theoretical: metrics.RoundTripLatency(config.BlockSize, config.Overlap),
+	}
+
+	// Silence of several blocks on both sides keeps the impulse response
+	// clear of the edges.
+	pos := 4 * config.BlockSize
+	frames := 8 * config.BlockSize
+	for ch := range 4 {
+		quad := make([][]float64, 4)
+		for i := range quad {
+			quad[i] = make([]float64, frames)
+		}
+		quad[ch][pos] = 1
+
+		decoded, err := roundTrip(quad, config)
+		if err != nil {
+			return LatencyReport{}, err
+		}
+		report.Channels[ch].Offline = peakIndex(decoded[ch]) - pos
+
+		streamed, delay, err := streamRoundTrip(quad, config)
+		if err != nil {
+			return LatencyReport{}, err
+		}
+		report.StreamDelay = delay
+		report.Channels[ch].Stream = peakIndex(streamed[ch]) - pos
+	}
+	return report, nil
+}
+
+// streamRoundTrip pushes quad followed by enough silence to drain it
+// through an encoder and a decoder stream in blocks of latencyPushFrames
+// and returns the decoded output and the combined stream delay.
+func streamRoundTrip(quad [][]float64, config Config) ([][]float64, int, error) {
+	sqEncoder, err := newEncoder(config)
+	if err != nil {
+		return nil, 0, err
+	}
+	sqDecoder, err := newDecoder(config, DefaultSampleRate)
+	if err != nil {
+		return nil, 0, err
+	}
+	lookahead := config.BlockSize - config.Overlap
+	enc, err := pipeline.NewStream(sqEncoder.ProcessSegment, 4, 2, config.Overlap, lookahead)
+	if err != nil {
+		return nil, 0, err
+	}
+	dec, err := pipeline.NewStream(sqDecoder.ProcessSegment, 2, 4, config.Overlap, lookahead)
+	if err != nil {
+		return nil, 0, err
+	}
+	delay := enc.Latency() + dec.Latency()
+
+	total := len(quad[0]) + delay
+	output := make([][]float64, 4)
+	for start := 0; start < total; start += latencyPushFrames {
+		n := min(latencyPushFrames, total-start)
+		block := make([][]float64, 4)
+		for ch := range block {
+			block[ch] = make([]float64, n)
+			if start < len(quad[ch]) {
+				copy(block[ch], quad[ch][start:])
+			}
+		}
+		encoded, err := enc.Process(block)
+		if err != nil {
+			return nil, 0, fmt.Errorf("encoding failed: %w", err)
+		}
+		decoded, err := dec.Process(encoded)
+		if err != nil {
+			return nil, 0, fmt.Errorf("decoding failed: %w", err)
+		}
+		for ch := range output {
+			output[ch] = append(output[ch], decoded[ch]...)
+		}
+	}
+	return output, delay, nil
+}
+
+// peakIndex returns the index of the largest magnitude in x.
+func peakIndex(x []float64) int {
+	peak := 0
+	for i, v := range x {
+		if math.Abs(v) > math.Abs(x[peak]) {
+			peak = i
+		}
+	}
+	return peak
+}
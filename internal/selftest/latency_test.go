@@ -0,0 +1,53 @@
+package selftest_test
+
+import (
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/selftest"
+)
+
+func TestMeasureLatency(t *testing.T) {
+	t.Parallel()
+
+	for _, config := range []selftest.Config{
+		{Name: "default", BlockSize: 1024, Overlap: 512},
+		{Name: "quarter", BlockSize: 1024, Overlap: 256},
+		{Name: "no-overlap", BlockSize: 1024, Overlap: 1024},
+	} {
+		report, err := selftest.MeasureLatency(config)
+		if err != nil {
+			t.Fatalf("%s: MeasureLatency() error = %v", config.Name, err)
+		}
+		// Encoder and decoder each advance their direct path by a quarter
+		// of the overlap, except in no-overlap mode; the streams add their
+		// buffering on top.
+		advance := config.Overlap / 2
+		if config.Overlap == config.BlockSize {
+			advance = 0
+		}
+		for ch, got := range report.Channels {
+			if got.Offline != -advance {
+				t.Fatalf("%s: channel %d offline offset = %d, want %d", config.Name, ch, got.Offline, -advance)
+			}
+			if got.Stream != report.StreamDelay-advance {
+				t.Fatalf("%s: channel %d stream offset = %d, want %d", config.Name, ch, got.Stream, report.StreamDelay-advance)
+			}
+		}
+	}
+}
+
+func TestMeasureLatency_NoOverlapMatchesTheoretical(t *testing.T) {
+	t.Parallel()
+
+	// Without overlap the theoretical latency is one block per codec, and a
+	// stream delays by one block less a frame.
+	report, err := selftest.MeasureLatency(selftest.Config{Name: "no-overlap", BlockSize: 1024, Overlap: 1024})
+	if err != nil {
+		t.Fatalf("MeasureLatency() error = %v", err)
+	}
+	for ch, got := range report.Channels {
+		if diff := got.Stream - report.Theoretical; diff < -2 || diff > 0 {
+			t.Fatalf("channel %d measured latency %d, theoretical %d", ch, got.Stream, report.Theoretical)
+		}
+	}
+}
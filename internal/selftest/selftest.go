@@ -0,0 +1,231 @@
+package selftest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const (
+	// DefaultSampleRate used for the synthesized test signal.
+	DefaultSampleRate = 44100
+	// DefaultDuration of the synthesized test signal in seconds.
+	DefaultDuration = 2.0
+)
+
+// Config is a single encode -> decode parameter set to check.
+type Config struct {
+	Name      string
+	BlockSize int
+	Overlap   int
+	// Window is the phase shifter window; empty keeps the codec default.
+	Window sqmath.WindowType
+}
+
+// Thresholds are the pass/fail limits applied to every config.
+type Thresholds struct {
+	// FrontPairDB is the minimum LF<->RF separation in dB.
+	FrontPairDB float64
+	// BackPairDB is the minimum LB<->RB separation in dB.
+	BackPairDB float64
+}
+
+// DefaultConfigs returns the default and one alternate parameter set.
+func DefaultConfigs() []Config {
+	return []Config{
+		{Name: "default", BlockSize: decoder.DefaultBlockSize, Overlap: decoder.DefaultOverlap},
+		{Name: "alternate", BlockSize: 2 * decoder.DefaultBlockSize, Overlap: 2 * decoder.DefaultOverlap},
+	}
+}
+
+// PresetConfigs returns one config per decoder quality preset, resolved at
+// DefaultSampleRate.
+func PresetConfigs() []Config {
+	var configs []Config
+	for _, q := range decoder.Qualities() {
+		blockSize, overlap := q.Resolve(DefaultSampleRate)
+		configs = append(configs, Config{Name: q.Name, BlockSize: blockSize, Overlap: overlap, Window: q.Window})
+	}
+	return configs
+}
+
+// DefaultThresholds returns the built-in self-test limits.
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		FrontPairDB: 30.0,
+		BackPairDB:  12.0,
+	}
+}
+
+// ConfigResult holds the measurements for one config.
+type ConfigResult struct {
+	Config Config
+	// Separation is the per-channel (LF, RF, LB, RB) separation against
+	// the strongest leak, measured on isolated channels.
+	Separation [4]float64
+	// PairSeparation is LF->RF, RF->LF, LB->RB, RB->LB in dB.
+	PairSeparation [4]float64
+	// SNR is the full-mix round-trip signal-to-noise ratio in dB.
+	SNR float64
+	// DecodeSpeed is the decoding speed of the full mix as a multiple of
+	// real time.
+	DecodeSpeed float64
+	Failures    []string
+}
+
+// Passed reports whether all checks for this config passed.
+func (r ConfigResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Report is the outcome of a self-test run.
+type Report struct {
+	Thresholds Thresholds
+	Results    []ConfigResult
+}
+
+// Passed reports whether every config passed.
+func (r Report) Passed() bool {
+	for _, res := range r.Results {
+		if !res.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Run synthesizes the standard quad test signal, runs encode -> decode for
+// every config and checks the results against the thresholds.
+func Run(configs []Config, thresholds Thresholds) (Report, error) {
+	numSamples := int(DefaultDuration * DefaultSampleRate)
+	quad := testsignal.QuadTones(DefaultSampleRate, numSamples, 0.6, 0.05)
+
+	report := Report{Thresholds: thresholds}
+	for _, config := range configs {
+		result, err := runConfig(quad, config, thresholds)
+		if err != nil {
+			return Report{}, fmt.Errorf("config %s: %w", config.Name, err)
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+func runConfig(quad [][]float64, config Config, thresholds Thresholds) (ConfigResult, error) {
+	result := ConfigResult{Config: config}
+	options := metrics.SeparationOptions{LeakMode: metrics.LeakModeMax}
+	pairs := [4]int{1, 0, 3, 2}
+
+	for ch := range 4 {
+		decoded, err := roundTrip(testsignal.Isolate(quad, ch), config)
+		if err != nil {
+			return ConfigResult{}, err
+		}
+		result.Separation[ch] = metrics.ChannelSeparation(decoded, ch, options).SeparationDB
+		result.PairSeparation[ch] = metrics.ChannelPairSeparation(decoded, ch, pairs[ch], options).SeparationDB
+	}
+
+	encoded, err := encode(quad, config)
+	if err != nil {
+		return ConfigResult{}, err
+	}
+	start := time.Now()
+	decoded, err := decode(encoded, config, DefaultSampleRate)
+	if err != nil {
+		return ConfigResult{}, err
+	}
+	result.DecodeSpeed = float64(len(quad[0])) / DefaultSampleRate / time.Since(start).Seconds()
+	// Front channels pass through encoder and decoder with an overall
+	// advance of overlap/2 samples.
+	result.SNR = roundTripSNR(quad, decoded, config.Overlap/2)
+
+	for i, name := range []string{"LF->RF", "RF->LF"} {
+		if !(result.PairSeparation[i] > thresholds.FrontPairDB) {
+			result.Failures = append(result.Failures,
+				fmt.Sprintf("%s separation %.2f dB <= %.2f dB", name, result.PairSeparation[i], thresholds.FrontPairDB))
+		}
+	}
+	for i, name := range []string{"LB->RB", "RB->LB"} {
+		if !(result.PairSeparation[i+2] > thresholds.BackPairDB) {
+			result.Failures = append(result.Failures,
+				fmt.Sprintf("%s separation %.2f dB <= %.2f dB", name, result.PairSeparation[i+2], thresholds.BackPairDB))
+		}
+	}
+	if math.IsNaN(result.SNR) || math.IsInf(result.SNR, 0) {
+		result.Failures = append(result.Failures, fmt.Sprintf("round-trip SNR %v is not finite", result.SNR))
+	}
+
+	return result, nil
+}
+
+func roundTrip(quad [][]float64, config Config) ([][]float64, error) {
+	encoded, err := encode(quad, config)
+	if err != nil {
+		return nil, err
+	}
+	return decode(encoded, config, DefaultSampleRate)
+}
+
+// newEncoder and newDecoder build the codecs of config.
+func newEncoder(config Config) (*sq.Encoder, error) {
+	return sq.NewEncoder(sq.WithBlockSize(config.BlockSize), sq.WithOverlap(config.Overlap), sq.WithWindow(config.Window))
+}
+
+func newDecoder(config Config, sampleRate int) (*sq.Decoder, error) {
+	return sq.NewDecoder(
+		sq.WithBlockSize(config.BlockSize),
+		sq.WithOverlap(config.Overlap),
+		sq.WithWindow(config.Window),
+		sq.WithSampleRate(sampleRate))
+}
+
+func encode(quad [][]float64, config Config) ([][]float64, error) {
+	sqEncoder, err := newEncoder(config)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := sqEncoder.Process(quad)
+	if err != nil {
+		return nil, fmt.Errorf("encoding failed: %w", err)
+	}
+	return encoded, nil
+}
+
+func decode(encoded [][]float64, config Config, sampleRate int) ([][]float64, error) {
+	sqDecoder, err := newDecoder(config, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := sqDecoder.Process(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding failed: %w", err)
+	}
+	return decoded, nil
+}
+
+// roundTripSNR compares decoded[n] against original[n+shift] over all
+// channels and returns the ratio of signal to error energy in dB.
+func roundTripSNR(original, decoded [][]float64, shift int) float64 {
+	signal := 0.0
+	noise := 0.0
+	for ch := range original {
+		for i := 0; i+shift < len(original[ch]) && i < len(decoded[ch]); i++ {
+			want := original[ch][i+shift]
+			diff := decoded[ch][i] - want
+			signal += want * want
+			noise += diff * diff
+		}
+	}
+	if noise == 0 {
+		return math.Inf(1)
+	}
+	return 10.0 * math.Log10(signal/noise)
+}
@@ -0,0 +1,50 @@
+package selftest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/selftest"
+)
+
+func TestRun_DefaultConfigsPass(t *testing.T) {
+	t.Parallel()
+
+	report, err := selftest.Run(selftest.DefaultConfigs(), selftest.DefaultThresholds())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, want := len(report.Results), len(selftest.DefaultConfigs()); got != want {
+		t.Fatalf("len(Results) = %d, want %d", got, want)
+	}
+	for _, res := range report.Results {
+		if !res.Passed() {
+			t.Errorf("config %s failed: %v", res.Config.Name, res.Failures)
+		}
+		if math.IsNaN(res.SNR) || math.IsInf(res.SNR, 0) {
+			t.Errorf("config %s SNR = %v, want finite", res.Config.Name, res.SNR)
+		}
+	}
+	if !report.Passed() {
+		t.Fatalf("report.Passed() = false, want true")
+	}
+}
+
+func TestRun_ImpossibleThresholdsFail(t *testing.T) {
+	t.Parallel()
+
+	thresholds := selftest.Thresholds{
+		FrontPairDB: math.Inf(1),
+		BackPairDB:  math.Inf(1),
+	}
+	report, err := selftest.Run(selftest.DefaultConfigs()[:1], thresholds)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Passed() {
+		t.Fatalf("report.Passed() = true, want false")
+	}
+	if got := len(report.Results[0].Failures); got != 4 {
+		t.Fatalf("len(Failures) = %d, want 4: %v", got, report.Results[0].Failures)
+	}
+}
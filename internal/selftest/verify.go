@@ -0,0 +1,75 @@
+package selftest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+)
+
+// DefaultVerifyThresholdDB is the per-channel recovery separation below
+// which Verify warns. Typical quad material recovers at about +3 dB (front)
+// and -3 dB (back) through the plain SQ matrix; content the matrix cannot
+// represent, such as anti-phase components, falls well below.
+const DefaultVerifyThresholdDB = -6.0
+
+// verifySilenceDB is the level below the loudest original channel at which a
+// channel counts as silent and is not checked.
+const verifySilenceDB = 60.0
+
+var channelNames = [4]string{"LF", "RF", "LB", "RB"}
+
+// Verification is the outcome of Verify.
+type Verification struct {
+	// Active marks the channels with content in the original.
+	Active [4]bool
+	// Separation is the per-channel (LF, RF, LB, RB) recovery separation
+	// in dB, see metrics.RecoverySeparation. Only active channels are
+	// measured.
+	Separation [4]float64
+	Warnings   []string
+}
+
+// Passed reports whether every active channel was recovered above the
+// threshold.
+func (v Verification) Passed() bool {
+	return len(v.Warnings) == 0
+}
+
+// Verify decodes encoded with config and measures how well each channel of
+// original is recovered. Channels whose recovery separation is below
+// thresholdDB produce a warning.
+func Verify(original, encoded [][]float64, config Config, sampleRate int, thresholdDB float64) (Verification, error) {
+	if len(original) != 4 {
+		return Verification{}, fmt.Errorf("original must have 4 channels, got %d", len(original))
+	}
+	decoded, err := decode(encoded, config, sampleRate)
+	if err != nil {
+		return Verification{}, err
+	}
+
+	var levels [4]float64
+	loudest := 0.0
+	for ch := range 4 {
+		levels[ch] = metrics.ChannelSeparation(original, ch, metrics.SeparationOptions{}).TargetRMS
+		loudest = math.Max(loudest, levels[ch])
+	}
+	silence := loudest * math.Pow(10, -verifySilenceDB/20)
+
+	var v Verification
+	// Front channels pass through encoder and decoder with an overall
+	// advance of overlap/2 samples.
+	shift := config.Overlap / 2
+	for ch := range 4 {
+		if levels[ch] <= silence {
+			continue
+		}
+		v.Active[ch] = true
+		v.Separation[ch] = metrics.RecoverySeparation(original[ch], decoded[ch], shift).SeparationDB
+		if !(v.Separation[ch] >= thresholdDB) {
+			v.Warnings = append(v.Warnings,
+				fmt.Sprintf("%s recovered at %.2f dB separation (< %.2f dB)", channelNames[ch], v.Separation[ch], thresholdDB))
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,79 @@
+package selftest_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/selftest"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func verify(t *testing.T, quad [][]float64) selftest.Verification {
+	t.Helper()
+
+	config := selftest.DefaultConfigs()[0]
+	encoded, err := encoder.NewSQEncoderWithParams(config.BlockSize, config.Overlap).Process(quad)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	v, err := selftest.Verify(quad, encoded, config, selftest.DefaultSampleRate, selftest.DefaultVerifyThresholdDB)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	return v
+}
+
+func TestVerify_FrontOnly(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(selftest.DefaultSampleRate, selftest.DefaultSampleRate, 0.5, 0)
+	quad[2] = make([]float64, len(quad[2]))
+	quad[3] = make([]float64, len(quad[3]))
+
+	v := verify(t, quad)
+	if !v.Passed() {
+		t.Fatalf("Passed() = false, warnings %v", v.Warnings)
+	}
+	if v.Active != [4]bool{true, true, false, false} {
+		t.Fatalf("Active = %v, want fronts only", v.Active)
+	}
+	for ch := 0; ch < 2; ch++ {
+		if v.Separation[ch] < 40 {
+			t.Fatalf("Separation[%d] = %.2f dB, want >= 40", ch, v.Separation[ch])
+		}
+	}
+}
+
+func TestVerify_QuadTonesPass(t *testing.T) {
+	t.Parallel()
+
+	v := verify(t, testsignal.QuadTones(selftest.DefaultSampleRate, selftest.DefaultSampleRate, 0.5, 0.05))
+	if !v.Passed() {
+		t.Fatalf("Passed() = false, warnings %v", v.Warnings)
+	}
+}
+
+func TestVerify_AntiPhaseWarns(t *testing.T) {
+	t.Parallel()
+
+	// The same tone in every channel with the right side inverted: the SQ
+	// matrix cancels it in the recovered RB.
+	n := selftest.DefaultSampleRate
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		v := 0.5 * math.Sin(2.0*math.Pi*440.0*float64(i)/float64(selftest.DefaultSampleRate))
+		quad[0][i], quad[1][i], quad[2][i], quad[3][i] = v, -v, v, -v
+	}
+
+	v := verify(t, quad)
+	if v.Passed() {
+		t.Fatalf("Passed() = true for anti-phase content, separation %v", v.Separation)
+	}
+	if v.Separation[3] >= selftest.DefaultVerifyThresholdDB {
+		t.Fatalf("RB separation = %.2f dB, want below threshold", v.Separation[3])
+	}
+}
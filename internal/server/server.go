@@ -0,0 +1,407 @@
+// Package server exposes the SQ decoder and encoder over HTTP.
+//
+// POST /decode and POST /encode accept a WAV file either as the raw request
+// body or as the first file part of a multipart/form-data body, and stream
+// the processed WAV back. GET /healthz reports liveness. Processing uses the
+// streaming WAV reader/writer and pipeline, so memory per request is bounded
+// by a few chunks regardless of the file length.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// DefaultMaxBodyBytes is the default request body limit (1 GiB).
+const DefaultMaxBodyBytes = 1 << 30
+
+// Error codes returned in the JSON error body.
+const (
+	CodeInvalidParameter = "invalid_parameter"
+	CodeInvalidInput     = "invalid_input"
+	CodeTooLarge         = "request_too_large"
+	CodeBusy             = "server_busy"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeProcessingFailed = "processing_failed"
+)
+
+// Config controls request limits.
+type Config struct {
+	// MaxBodyBytes caps the request body size; 0 means DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// MaxConcurrent caps the number of requests processed at once; further
+	// requests are rejected with 503. 0 means runtime.NumCPU().
+	MaxConcurrent int
+	// Logger receives one record per request with its method, path,
+	// status, duration and processing parameters: info for successful
+	// requests, warn for client errors and error for processing failures.
+	// nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Server handles decode/encode requests.
+type Server struct {
+	maxBodyBytes int64
+	slots        chan struct{}
+	logger       *slog.Logger
+	mux          *http.ServeMux
+}
+
+// ErrorResponse is the JSON body of every error response.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail carries a stable machine-readable code and a message.
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// New creates a Server with the given configuration.
+func New(cfg Config) *Server {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = runtime.NumCPU()
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	s := &Server{
+		maxBodyBytes: cfg.MaxBodyBytes,
+		slots:        make(chan struct{}, cfg.MaxConcurrent),
+		logger:       cfg.Logger,
+		mux:          http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/decode", func(w http.ResponseWriter, r *http.Request) {
+		s.handleProcess(w, r, 2, newDecodeProcessor)
+	})
+	s.mux.HandleFunc("/encode", func(w http.ResponseWriter, r *http.Request) {
+		s.handleProcess(w, r, 4, newEncodeProcessor)
+	})
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rw := &responseWriter{ResponseWriter: w}
+	// Deferred so aborted requests are logged while the panic unwinds.
+	defer func() {
+		level := slog.LevelInfo
+		switch {
+		case rw.err != nil:
+			level = slog.LevelError
+		case rw.status >= 400:
+			level = slog.LevelWarn
+		}
+		attrs := append([]slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rw.statusCode()),
+			slog.Duration("duration", time.Since(start)),
+		}, rw.attrs...)
+		if rw.err != nil {
+			attrs = append(attrs, slog.String("error", rw.err.Error()))
+		}
+		s.logger.LogAttrs(r.Context(), level, "request", attrs...)
+	}()
+	s.mux.ServeHTTP(rw, r)
+}
+
+// responseWriter records the status and the log attributes of a request.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	attrs  []slog.Attr
+	err    error
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// addLogAttrs attaches attrs to the request's log record.
+func addLogAttrs(w http.ResponseWriter, attrs ...slog.Attr) {
+	if rw, ok := w.(*responseWriter); ok {
+		rw.attrs = append(rw.attrs, attrs...)
+	}
+}
+
+// setLogError marks the request as failed after the response started.
+func setLogError(w http.ResponseWriter, err error) {
+	if rw, ok := w.(*responseWriter); ok {
+		rw.err = err
+	}
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.WriteString(w, `{"status":"ok"}`+"\n")
+}
+
+// params holds the query parameters shared by /decode and /encode.
+type params struct {
+	blockSize  int
+	overlap    int
+	window     sqmath.WindowType
+	logic      bool
+	format     wav.SampleFormat
+	sampleRate int
+}
+
+func parseParams(r *http.Request) (params, error) {
+	q := r.URL.Query()
+	p := params{
+		blockSize: sq.DefaultBlockSize,
+		overlap:   sq.DefaultOverlap,
+		window:    sqmath.WindowHann,
+		format:    wav.FormatPCM16,
+	}
+
+	var err error
+	if v := q.Get("block_size"); v != "" {
+		if p.blockSize, err = strconv.Atoi(v); err != nil {
+			return p, fmt.Errorf("block_size: %w", err)
+		}
+	}
+	if v := q.Get("overlap"); v != "" {
+		if p.overlap, err = strconv.Atoi(v); err != nil {
+			return p, fmt.Errorf("overlap: %w", err)
+		}
+	}
+	if p.blockSize < 64 || p.blockSize > 1<<16 || p.blockSize&(p.blockSize-1) != 0 {
+		return p, fmt.Errorf("block_size must be a power of 2 in [64, 65536], got %d", p.blockSize)
+	}
+	if p.overlap <= 0 || p.overlap > p.blockSize {
+		return p, fmt.Errorf("overlap must be in [1, %d], got %d", p.blockSize, p.overlap)
+	}
+	if v := q.Get("window"); v != "" {
+		if p.window, err = sqmath.ParseWindowType(v); err != nil {
+			return p, err
+		}
+	}
+	if v := q.Get("logic"); v != "" {
+		if p.logic, err = strconv.ParseBool(v); err != nil {
+			return p, fmt.Errorf("logic: %w", err)
+		}
+	}
+	switch v := q.Get("format"); v {
+	case "", "pcm16":
+	case "pcm8":
+		p.format = wav.FormatPCM8
+	case "pcm24":
+		p.format = wav.FormatPCM24
+	case "float32":
+		p.format = wav.FormatFloat32
+	default:
+		return p, fmt.Errorf("format must be pcm16, pcm8, pcm24 or float32, got %q", v)
+	}
+	return p, nil
+}
+
+// processorFactory builds a configured processor and returns it together
+// with its output channel count.
+type processorFactory func(p params) (pipeline.Processor, int, error)
+
+func newDecodeProcessor(p params) (pipeline.Processor, int, error) {
+	options := []sq.Option{
+		sq.WithBlockSize(p.blockSize),
+		sq.WithOverlap(p.overlap),
+		sq.WithWindow(p.window),
+		sq.WithSampleRate(p.sampleRate),
+	}
+	if p.logic {
+		options = append(options, sq.WithLogicSteering(sq.DefaultLogicSteeringConfig()))
+	}
+	d, err := sq.NewDecoder(options...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return d.ProcessSegment, d.OutputChannels(), nil
+}
+
+func newEncodeProcessor(p params) (pipeline.Processor, int, error) {
+	e, err := sq.NewEncoder(sq.WithBlockSize(p.blockSize), sq.WithOverlap(p.overlap), sq.WithWindow(p.window))
+	if err != nil {
+		return nil, 0, err
+	}
+	return e.ProcessSegment, 2, nil
+}
+
+func (s *Server) handleProcess(w http.ResponseWriter, r *http.Request, inChannels int, factory processorFactory) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "use POST")
+		return
+	}
+
+	p, err := parseParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	addLogAttrs(w,
+		slog.Int("block_size", p.blockSize),
+		slog.Int("overlap", p.overlap),
+		slog.String("window", string(p.window)),
+		slog.Bool("logic", p.logic),
+		slog.String("format", p.format.String()),
+	)
+
+	if r.ContentLength > s.maxBodyBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge,
+			fmt.Sprintf("request body exceeds %d bytes", s.maxBodyBytes))
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusServiceUnavailable, CodeBusy, "too many concurrent requests")
+		return
+	}
+
+	body, err := s.inputStream(w, r)
+	if err != nil {
+		writeInputError(w, err)
+		return
+	}
+
+	reader, err := wav.NewReader(body, inChannels)
+	if err != nil {
+		writeInputError(w, err)
+		return
+	}
+	if reader.NumFrames() < 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidInput, "WAV data size is not set; send a finished file")
+		return
+	}
+	if int64(reader.NumFrames())*int64(inChannels) > s.maxBodyBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge,
+			fmt.Sprintf("declared audio data exceeds %d bytes", s.maxBodyBytes))
+		return
+	}
+
+	p.sampleRate = int(reader.SampleRate())
+	addLogAttrs(w,
+		slog.Int("sample_rate", p.sampleRate),
+		slog.Int("frames", reader.NumFrames()),
+	)
+	process, outChannels, err := factory(p)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	writer, err := wav.NewWriter(w, reader.SampleRate(), outChannels, reader.NumFrames(), wav.WriteOptions{Format: p.format})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeProcessingFailed, err.Error())
+		return
+	}
+
+	cfg := pipeline.DefaultConfig(p.blockSize, p.overlap)
+	cfg.Logger = s.logger
+	err = pipeline.Run(r.Context(), reader, inChannels, writer, process, cfg)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		// The status line may already be on the wire; abort the connection
+		// so the client cannot mistake a truncated WAV for a complete one.
+		if errors.Is(err, context.Canceled) {
+			addLogAttrs(w, slog.Bool("canceled", true))
+		} else {
+			setLogError(w, err)
+		}
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// inputStream returns the WAV payload: the raw body, or the first file part
+// of a multipart/form-data body. The body is capped at maxBodyBytes.
+func (s *Server) inputStream(w http.ResponseWriter, r *http.Request) (io.Reader, error) {
+	// MaxBytesReader closes the connection on overflow only when it sees
+	// the server's own ResponseWriter.
+	if rw, ok := w.(*responseWriter); ok {
+		w = rw.ResponseWriter
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("multipart body has no file part")
+			}
+			return nil, err
+		}
+		if part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+func writeInputError(w http.ResponseWriter, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge,
+			fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, CodeInvalidInput, err.Error())
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorDetail{Code: code, Message: message}})
+}
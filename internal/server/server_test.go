@@ -0,0 +1,299 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/server"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const numSamples = 10*512 + 33
+
+func newServer(cfg server.Config) *server.Server {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.DiscardHandler)
+	}
+	return server.New(cfg)
+}
+
+func wavBytes(t *testing.T, samples [][]float64) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	data := &wav.AudioData{SampleRate: 44100, Samples: samples, NumSamples: len(samples[0])}
+	if err := wav.WriteWAVWithOptionsToWriter(&buf, data, len(samples), wav.WriteOptions{Format: wav.FormatFloat32}); err != nil {
+		t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) server.ErrorDetail {
+	t.Helper()
+
+	var body server.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode error body: %v", err)
+	}
+	return body.Error
+}
+
+func TestServer_Decode(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, numSamples, 0.4, 0.05)
+	input := wavBytes(t, quad[:2])
+
+	// Expected output: whole-signal decode with the same parameters.
+	audio, err := wav.ReadWAVBytes(input, 2)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+	d := decoder.NewSQDecoderWithParams(2048, 1024)
+	d.SetWindow(sqmath.WindowBlackman)
+	d.SetSampleRate(44100)
+	d.EnableLogicSteering(true)
+	decoded, err := d.Process(audio.Samples)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	var want bytes.Buffer
+	if err := wav.WriteWAVWithOptionsToWriter(&want, &wav.AudioData{SampleRate: 44100, Samples: decoded, NumSamples: numSamples}, 4, wav.WriteOptions{}); err != nil {
+		t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/decode?block_size=2048&overlap=1024&window=blackman&logic=true", bytes.NewReader(input))
+	rec := httptest.NewRecorder()
+	newServer(server.Config{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %q)", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "audio/wav" {
+		t.Fatalf("Content-Type = %q, want audio/wav", got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), want.Bytes()) {
+		t.Fatalf("response body differs from whole-file decode")
+	}
+}
+
+func TestServer_EncodeMultipart(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, numSamples, 0.4, 0.05)
+	input := wavBytes(t, quad)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("note", "ignored"); err != nil {
+		t.Fatal(err)
+	}
+	part, err := mw.CreateFormFile("file", "quad.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(input); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/encode?format=float32", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	newServer(server.Config{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %q)", rec.Code, rec.Body.String())
+	}
+
+	got, err := wav.ReadWAVBytes(rec.Body.Bytes(), 2)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+	audio, err := wav.ReadWAVBytes(input, 4)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+	want, err := encoder.NewSQEncoder().Process(audio.Samples)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	for ch := 0; ch < 2; ch++ {
+		for i := 0; i < numSamples; i++ {
+			if got.Samples[ch][i] != float64(float32(want[ch][i])) {
+				t.Fatalf("out[%d][%d] = %v, want %v", ch, i, got.Samples[ch][i], float32(want[ch][i]))
+			}
+		}
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, numSamples, 0.4, 0.05)
+	stereo := wavBytes(t, quad[:2])
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   []byte
+		cfg    server.Config
+		status int
+		code   string
+	}{
+		{"bad block size", http.MethodPost, "/decode?block_size=1000", stereo, server.Config{}, http.StatusBadRequest, server.CodeInvalidParameter},
+		{"bad window", http.MethodPost, "/encode?window=kaiser", stereo, server.Config{}, http.StatusBadRequest, server.CodeInvalidParameter},
+		{"not a wav", http.MethodPost, "/decode", []byte("hello world"), server.Config{}, http.StatusBadRequest, server.CodeInvalidInput},
+		{"wrong channels", http.MethodPost, "/encode", stereo, server.Config{}, http.StatusBadRequest, server.CodeInvalidInput},
+		{"too large", http.MethodPost, "/decode", stereo, server.Config{MaxBodyBytes: 1024}, http.StatusRequestEntityTooLarge, server.CodeTooLarge},
+		{"wrong method", http.MethodGet, "/decode", nil, server.Config{}, http.StatusMethodNotAllowed, server.CodeMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, bytes.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		newServer(tt.cfg).ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if got := decodeError(t, rec); got.Code != tt.code {
+			t.Fatalf("%s: code = %q, want %q (message %q)", tt.name, got.Code, tt.code, got.Message)
+		}
+	}
+}
+
+func TestServer_ConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, numSamples, 0.4, 0.05)
+	stereo := wavBytes(t, quad[:2])
+
+	ts := httptest.NewServer(newServer(server.Config{MaxConcurrent: 1}))
+	defer ts.Close()
+
+	// Occupy the only slot with a request whose body never finishes.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() {
+		resp, err := http.Post(ts.URL+"/decode", "audio/wav", pr)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	if _, err := pw.Write(stereo[:100]); err != nil {
+		t.Fatalf("write partial body: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Post(ts.URL+"/decode", "audio/wav", bytes.NewReader(stereo))
+		if err != nil {
+			t.Fatalf("POST error = %v", err)
+		}
+		status := resp.StatusCode
+		var body server.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if status == http.StatusServiceUnavailable {
+			if body.Error.Code != server.CodeBusy {
+				t.Fatalf("code = %q, want %q", body.Error.Code, server.CodeBusy)
+			}
+			return
+		}
+		// The first request may not have reached the handler yet.
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %d, want 503 while the slot is occupied", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_Healthz(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	newServer(server.Config{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Body.String(); got != "{\"status\":\"ok\"}\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestServer_RequestLog(t *testing.T) {
+	t.Parallel()
+
+	quad := testsignal.QuadTones(44100, numSamples, 0.4, 0.05)
+	stereo := wavBytes(t, quad[:2])
+
+	requests := []struct {
+		target string
+		body   []byte
+	}{
+		{"/decode?block_size=2048&overlap=1024&format=float32", stereo},
+		{"/decode?block_size=1000", stereo},
+	}
+
+	tests := []struct {
+		level  slog.Level
+		status []float64
+	}{
+		{slog.LevelInfo, []float64{http.StatusOK, http.StatusBadRequest}},
+		{slog.LevelWarn, []float64{http.StatusBadRequest}},
+		{slog.LevelError, nil},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		srv := newServer(server.Config{Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: tt.level}))})
+		for _, req := range requests {
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, req.target, bytes.NewReader(req.body)))
+		}
+
+		var records []map[string]any
+		dec := json.NewDecoder(&buf)
+		for dec.More() {
+			var record map[string]any
+			if err := dec.Decode(&record); err != nil {
+				t.Fatalf("decode record: %v", err)
+			}
+			records = append(records, record)
+		}
+		if len(records) != len(tt.status) {
+			t.Fatalf("level %v: %d records, want %d: %v", tt.level, len(records), len(tt.status), records)
+		}
+		for i, record := range records {
+			if record["msg"] != "request" || record["path"] != "/decode" || record["status"] != tt.status[i] {
+				t.Fatalf("level %v: record %d = %v", tt.level, i, record)
+			}
+			if _, ok := record["duration"]; !ok {
+				t.Fatalf("level %v: record %d has no duration", tt.level, i)
+			}
+			if tt.status[i] != http.StatusOK {
+				continue
+			}
+			want := map[string]any{"block_size": 2048.0, "overlap": 1024.0, "format": "32-bit IEEE float", "sample_rate": 44100.0, "frames": float64(numSamples)}
+			for key, value := range want {
+				if record[key] != value {
+					t.Fatalf("record[%q] = %v, want %v", key, record[key], value)
+				}
+			}
+		}
+	}
+}
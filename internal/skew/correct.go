@@ -0,0 +1,147 @@
+package skew
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+)
+
+// halfTaps is half the length of the fractional delay filters.
+const halfTaps = 32
+
+// CorrectSource returns src, a 2-channel Source, with a skew of RT against
+// LT (in seconds, positive when RT lags) removed: LT is delayed and RT
+// advanced by half of it each, through windowed-sinc interpolators, so the
+// pair stays centered on its original timing and keeps its length. A skew
+// of 0 returns src.
+func CorrectSource(src pipeline.Source, skew float64, sampleRate int) pipeline.Source {
+	if skew == 0 {
+		return src
+	}
+	d := skew * float64(sampleRate) / 2
+	return NewDelaySource(src, []float64{d, -d})
+}
+
+// delaySource delays each channel of a Source by a fractional number of
+// samples.
+type delaySource struct {
+	src pipeline.Source
+	// shifts and filters split each delay into whole samples and a
+	// fractional interpolator: output i is the dot product of the filter
+	// with the input from i-shift-halfTaps+1 on.
+	shifts  []int
+	filters [][]float64
+	// ahead is the number of input frames past an output frame it needs.
+	ahead int
+	// buf holds the input from frame base on; total is the input length
+	// once src is exhausted, -1 before.
+	buf   [][]float64
+	base  int
+	total int
+	pos   int
+	read  [][]float64
+}
+
+// NewDelaySource returns src with channel ch delayed by delays[ch] samples,
+// which may be fractional or negative, through windowed-sinc
+// interpolators. Input before the first and past the last frame counts as
+// silence; the output has as many frames as src. A channel whose delay is a
+// whole number of samples is shifted exactly.
+func NewDelaySource(src pipeline.Source, delays []float64) pipeline.Source {
+	s := &delaySource{src: src, total: -1}
+	for _, delay := range delays {
+		shift := int(math.Floor(delay))
+		s.shifts = append(s.shifts, shift)
+		s.filters = append(s.filters, fractionalDelay(delay-float64(shift)))
+		s.ahead = max(s.ahead, halfTaps-shift)
+		s.buf = append(s.buf, nil)
+		s.read = append(s.read, make([]float64, 4096))
+	}
+	return s
+}
+
+// fractionalDelay returns the Blackman-windowed sinc interpolator that
+// delays by frac in [0, 1) samples, normalized to unity gain at DC. Tap j
+// weighs the input halfTaps-1-j samples before the integer position.
+func fractionalDelay(frac float64) []float64 {
+	taps := make([]float64, 2*halfTaps)
+	if frac == 0 {
+		taps[halfTaps-1] = 1
+		return taps
+	}
+	sum := 0.0
+	for j := range taps {
+		x := float64(halfTaps-1-j) - frac
+		w := 0.42 + 0.5*math.Cos(math.Pi*x/halfTaps) + 0.08*math.Cos(2*math.Pi*x/halfTaps)
+		taps[j] = math.Sin(math.Pi*x) / (math.Pi * x) * w
+		sum += taps[j]
+	}
+	for j := range taps {
+		taps[j] /= sum
+	}
+	return taps
+}
+
+// ReadFrames implements pipeline.Source.
+func (s *delaySource) ReadFrames(dst [][]float64) (int, error) {
+	if len(dst) != len(s.shifts) {
+		return 0, fmt.Errorf("destination must have %d channels, got %d", len(s.shifts), len(dst))
+	}
+	want := len(dst[0])
+	// Read until the input reaches ahead frames past the last output.
+	for s.total < 0 && s.base+len(s.buf[0]) < s.pos+want+s.ahead {
+		n, err := s.src.ReadFrames(s.read)
+		for ch := range s.buf {
+			s.buf[ch] = append(s.buf[ch], s.read[ch][:n]...)
+		}
+		if errors.Is(err, io.EOF) {
+			s.total = s.base + len(s.buf[0])
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if s.total >= 0 {
+		want = min(want, s.total-s.pos)
+		if want <= 0 {
+			return 0, io.EOF
+		}
+	}
+
+	for ch, taps := range s.filters {
+		for i := range want {
+			first := s.pos + i - s.shifts[ch] - halfTaps + 1
+			sum := 0.0
+			for j, tap := range taps {
+				sum += tap * s.input(ch, first+j)
+			}
+			dst[ch][i] = sum
+		}
+	}
+	s.pos += want
+
+	// Drop the input no later output reaches back to.
+	keep := s.pos
+	for _, shift := range s.shifts {
+		keep = min(keep, s.pos-shift-halfTaps+1)
+	}
+	if drop := keep - s.base; drop > 0 && drop <= len(s.buf[0]) {
+		for ch := range s.buf {
+			s.buf[ch] = append(s.buf[ch][:0], s.buf[ch][drop:]...)
+		}
+		s.base = keep
+	}
+	return want, nil
+}
+
+// input returns input frame i of channel ch, 0 outside the signal.
+func (s *delaySource) input(ch, i int) float64 {
+	if i < s.base || i >= s.base+len(s.buf[ch]) {
+		return 0
+	}
+	return s.buf[ch][i-s.base]
+}
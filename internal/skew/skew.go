@@ -0,0 +1,212 @@
+// Package skew estimates and removes the time offset between the two
+// channels of a stereo capture.
+//
+// Azimuth error of a tape head or a cartridge delays one channel against
+// the other by a few to a few hundred microseconds. The delay is a phase
+// error that grows with frequency, and the SQ decoder, which relies on the
+// phase relation of LT and RT, loses separation from the midrange up.
+package skew
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const (
+	// MaxSkew is the largest offset, in seconds, that Estimate searches
+	// for.
+	MaxSkew = 1e-3
+	// MinFreq and MaxFreq bound the band the estimate uses: below it hum
+	// and rumble dominate, above it the tape or stylus noise.
+	MinFreq = 300.0
+	MaxFreq = 12000.0
+	// MinCorrelation is the normalized correlation below which LT and RT
+	// are too unrelated for a reliable estimate.
+	MinCorrelation = 0.1
+
+	// frameSize is the FFT size of the cross-spectrum, in samples.
+	frameSize = 8192
+	// upsample is the interpolation factor of the cross-correlation.
+	upsample = 16
+)
+
+// Estimator averages the cross-spectrum of LT and RT over Hann-windowed
+// frames, half a frame apart, of a signal fed in pieces of any length.
+type Estimator struct {
+	sampleRate int
+	plan       *algofft.Plan[complex128]
+	window     []float64
+	pending    [2][]float64
+	// cross is the summed LT·conj(RT) spectrum; powerL and powerR are
+	// the summed power of each channel, all within the band.
+	cross          []complex128
+	powerL, powerR float64
+	frames         int
+}
+
+// NewEstimator returns an Estimator for a signal at sampleRate.
+func NewEstimator(sampleRate int) (*Estimator, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("sample rate must be positive, got %d", sampleRate)
+	}
+	plan, err := algofft.NewPlan64(frameSize)
+	if err != nil {
+		return nil, err
+	}
+	window, err := sqmath.Window(sqmath.WindowHann, frameSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Estimator{
+		sampleRate: sampleRate,
+		plan:       plan,
+		window:     window,
+		cross:      make([]complex128, frameSize/2+1),
+	}, nil
+}
+
+// Add feeds the next frames of LT (frames[0]) and RT (frames[1]).
+func (e *Estimator) Add(frames [][]float64) {
+	e.pending[0] = append(e.pending[0], frames[0]...)
+	e.pending[1] = append(e.pending[1], frames[1]...)
+	for len(e.pending[0]) >= frameSize {
+		e.addFrame(e.pending[0][:frameSize], e.pending[1][:frameSize])
+		for ch := range e.pending {
+			e.pending[ch] = append(e.pending[ch][:0], e.pending[ch][frameSize/2:]...)
+		}
+	}
+}
+
+// addFrame adds the spectra of one frame of each channel.
+func (e *Estimator) addFrame(lt, rt []float64) {
+	var spectra [2][]complex128
+	for ch, samples := range [][]float64{lt, rt} {
+		input := make([]complex128, frameSize)
+		for i, v := range samples {
+			input[i] = complex(v*e.window[i], 0)
+		}
+		spectra[ch] = make([]complex128, frameSize)
+		if err := e.plan.Forward(spectra[ch], input); err != nil {
+			panic(err)
+		}
+	}
+	low, high := e.band()
+	for k := low; k <= high; k++ {
+		l, r := spectra[0][k], spectra[1][k]
+		e.cross[k] += l * complex(real(r), -imag(r))
+		e.powerL += real(l)*real(l) + imag(l)*imag(l)
+		e.powerR += real(r)*real(r) + imag(r)*imag(r)
+	}
+	e.frames++
+}
+
+// band returns the first and last bin of the band the estimate uses.
+func (e *Estimator) band() (int, int) {
+	binHz := float64(e.sampleRate) / frameSize
+	low := max(int(math.Ceil(MinFreq/binHz)), 1)
+	high := min(int(MaxFreq/binHz), frameSize/2-1)
+	return low, high
+}
+
+// Skew returns the offset of RT against LT in seconds, positive when RT
+// lags. It is the peak of the envelope of the cross-correlation, which,
+// unlike the correlation itself, does not depend on the phase relation of
+// the two channels: the 90° components of the backs in an SQ signal locate
+// the offset as well as the in-phase fronts. It fails if less than one
+// frame was added or the channels are too unrelated.
+func (e *Estimator) Skew() (float64, error) {
+	if e.frames == 0 {
+		return 0, fmt.Errorf("need at least %d frames to estimate the skew", frameSize)
+	}
+	if e.powerL == 0 || e.powerR == 0 {
+		return 0, fmt.Errorf("cannot estimate the skew of a silent channel")
+	}
+
+	// The analytic cross-correlation, interpolated by zero-padding the
+	// spectrum: only the positive frequencies, at their own bins.
+	size := frameSize * upsample
+	plan, err := algofft.NewPlan64(size)
+	if err != nil {
+		return 0, err
+	}
+	spectrum := make([]complex128, size)
+	copy(spectrum, e.cross)
+	correlation := make([]complex128, size)
+	if err := plan.Inverse(correlation, spectrum); err != nil {
+		return 0, err
+	}
+	envelope := func(lag int) float64 {
+		return cmplx.Abs(correlation[(lag+size)%size])
+	}
+
+	maxLag := int(math.Ceil(MaxSkew * float64(e.sampleRate) * upsample))
+	peak := -maxLag
+	for lag := -maxLag; lag <= maxLag; lag++ {
+		if envelope(lag) > envelope(peak) {
+			peak = lag
+		}
+	}
+	// Inverse scales by 1/size; by Cauchy-Schwarz the peak is at most
+	// sqrt(powerL·powerR).
+	if coefficient := envelope(peak) * float64(size) / math.Sqrt(e.powerL*e.powerR); coefficient < MinCorrelation {
+		return 0, fmt.Errorf("LT and RT correlate too weakly (%.3f) to estimate the skew", coefficient)
+	}
+
+	// Parabolic interpolation between the neighbours of the peak.
+	lag := float64(peak)
+	if prev, next := envelope(peak-1), envelope(peak+1); prev+next < 2*envelope(peak) {
+		lag += 0.5 * (prev - next) / (prev - 2*envelope(peak) + next)
+	}
+	// The correlation of LT against RT peaks at minus the delay of RT.
+	return e.refine(-lag / upsample / float64(e.sampleRate)), nil
+}
+
+// refine returns the skew near coarse that best fits the phase of the
+// cross-spectrum. With RT delayed by d the phase at ω is φ0 + ω·d, where
+// the constant φ0 depends on how the channels relate, so a line of free
+// offset is fitted, weighted by magnitude, to the phase left after
+// removing coarse. The envelope peak is broad, and the phase locates the
+// delay more precisely.
+func (e *Estimator) refine(coarse float64) float64 {
+	low, high := e.band()
+	binOmega := 2 * math.Pi * float64(e.sampleRate) / frameSize
+	residual := make([]complex128, high+1)
+	var sum complex128
+	for k := low; k <= high; k++ {
+		residual[k] = e.cross[k] * cmplx.Exp(complex(0, -float64(k)*binOmega*coarse))
+		sum += residual[k]
+	}
+	// Centering the phases on their mean keeps them clear of the wrap at
+	// ±π whatever φ0 is.
+	rotate := cmplx.Exp(complex(0, -cmplx.Phase(sum)))
+	var sw, sx, sy, sxx, sxy float64
+	for k := low; k <= high; k++ {
+		w := cmplx.Abs(residual[k])
+		x := float64(k) * binOmega
+		y := cmplx.Phase(residual[k] * rotate)
+		sw += w
+		sx += w * x
+		sy += w * y
+		sxx += w * x * x
+		sxy += w * x * y
+	}
+	if det := sw*sxx - sx*sx; det > 0 {
+		return coarse + (sw*sxy-sx*sy)/det
+	}
+	return coarse
+}
+
+// Estimate returns the skew of rt against lt at sampleRate, as
+// Estimator.Skew.
+func Estimate(lt, rt []float64, sampleRate int) (float64, error) {
+	e, err := NewEstimator(sampleRate)
+	if err != nil {
+		return 0, err
+	}
+	e.Add([][]float64{lt, rt})
+	return e.Skew()
+}
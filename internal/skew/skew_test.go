@@ -0,0 +1,221 @@
+package skew_test
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/metrics"
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/internal/skew"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+const (
+	sampleRate = 44100
+	// n is the length of the test signals, about 3 s; a power of 2 keeps
+	// the band-limited separation measurement fast.
+	n = 1 << 17
+)
+
+// encodeNoise returns the SQ encoding of independent white noise in the
+// channels of quad selected by active, plus, with center, a source panned
+// center front, as music usually has.
+func encodeNoise(t *testing.T, n int, center bool, active ...int) [][]float64 {
+	t.Helper()
+	rng := rand.New(rand.NewSource(int64(len(active)*10 + active[0])))
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = make([]float64, n)
+	}
+	for _, ch := range active {
+		for i := range quad[ch] {
+			quad[ch][i] = 0.2 * rng.NormFloat64()
+		}
+	}
+	if center {
+		for i := range n {
+			v := 0.2 * rng.NormFloat64()
+			quad[0][i] += v
+			quad[1][i] += v
+		}
+	}
+	stereo, err := encoder.NewSQEncoder().Process(quad)
+	if err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+	return stereo
+}
+
+// readAll reads src to the end in blocks of size frames.
+func readAll(t *testing.T, src pipeline.Source, channels, size int) [][]float64 {
+	t.Helper()
+	out := make([][]float64, channels)
+	buf := make([][]float64, channels)
+	for ch := range buf {
+		buf[ch] = make([]float64, size)
+	}
+	for {
+		n, err := src.ReadFrames(buf)
+		for ch := range out {
+			out[ch] = append(out[ch], buf[ch][:n]...)
+		}
+		if errors.Is(err, io.EOF) {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("ReadFrames error = %v", err)
+		}
+	}
+}
+
+// applySkew delays RT against LT by skew seconds.
+func applySkew(t *testing.T, stereo [][]float64, skewSeconds float64) [][]float64 {
+	t.Helper()
+	src := skew.NewDelaySource(pipeline.NewSliceSource(stereo), []float64{0, skewSeconds * sampleRate})
+	return readAll(t, src, 2, 1000)
+}
+
+func TestDelaySource(t *testing.T) {
+	t.Parallel()
+
+	const n = 5000
+	signal := make([]float64, n)
+	for i := range signal {
+		signal[i] = math.Sin(2*math.Pi*1000*float64(i)/sampleRate) + 0.5*math.Sin(2*math.Pi*5000*float64(i)/sampleRate)
+	}
+
+	// Whole-sample delays shift exactly, also across block boundaries.
+	src := skew.NewDelaySource(pipeline.NewSliceSource([][]float64{signal, signal}), []float64{3, -2})
+	out := readAll(t, src, 2, 7)
+	if len(out[0]) != n {
+		t.Fatalf("delayed %d frames, want %d", len(out[0]), n)
+	}
+	for i := range n {
+		want0, want1 := 0.0, 0.0
+		if i >= 3 {
+			want0 = signal[i-3]
+		}
+		if i+2 < n {
+			want1 = signal[i+2]
+		}
+		if out[0][i] != want0 || out[1][i] != want1 {
+			t.Fatalf("frame %d = %g, %g; want %g, %g", i, out[0][i], out[1][i], want0, want1)
+		}
+	}
+
+	// A fractional delay matches the analytic signal away from the edges,
+	// whatever the block size it is read in.
+	const delay = 1.37
+	whole := readAll(t, skew.NewDelaySource(pipeline.NewSliceSource([][]float64{signal}), []float64{delay}), 1, n)
+	blocks := readAll(t, skew.NewDelaySource(pipeline.NewSliceSource([][]float64{signal}), []float64{delay}), 1, 333)
+	for i := 100; i < n-100; i++ {
+		x := float64(i) - delay
+		want := math.Sin(2*math.Pi*1000*x/sampleRate) + 0.5*math.Sin(2*math.Pi*5000*x/sampleRate)
+		if math.Abs(whole[0][i]-want) > 1e-3 {
+			t.Fatalf("frame %d = %g, want %g", i, whole[0][i], want)
+		}
+		if blocks[0][i] != whole[0][i] {
+			t.Fatalf("frame %d read in blocks = %g, whole = %g", i, blocks[0][i], whole[0][i])
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	t.Parallel()
+
+	stereo := encodeNoise(t, n, true, 0, 1, 2, 3)
+	for _, want := range []float64{0, 31e-6, -85e-6, 400e-6} {
+		got, err := skew.Estimate(applySkew(t, stereo, want)[0], applySkew(t, stereo, want)[1], sampleRate)
+		if err != nil {
+			t.Fatalf("Estimate(%g) error = %v", want, err)
+		}
+		if math.Abs(got-want) > 1e-6 {
+			t.Fatalf("Estimate = %.2f µs, want %.2f µs", got*1e6, want*1e6)
+		}
+	}
+
+	// Channels in quadrature, as a source panned center back is in LT and
+	// RT, locate the offset as well.
+	rng := rand.New(rand.NewSource(1))
+	quadrature := [][]float64{make([]float64, sampleRate), make([]float64, sampleRate)}
+	for range 200 {
+		freq, phase := 300+11000*rng.Float64(), 2*math.Pi*rng.Float64()
+		for i := range sampleRate {
+			x := 2*math.Pi*freq*float64(i)/sampleRate + phase
+			quadrature[0][i] += 0.01 * math.Sin(x)
+			quadrature[1][i] += 0.01 * math.Cos(x)
+		}
+	}
+	if got, err := skew.Estimate(applySkew(t, quadrature, 50e-6)[0], applySkew(t, quadrature, 50e-6)[1], sampleRate); err != nil || math.Abs(got-50e-6) > 1e-6 {
+		t.Fatalf("Estimate of channels in quadrature = %g µs, %v; want 50 µs", got*1e6, err)
+	}
+
+	lt, rt := make([]float64, sampleRate), make([]float64, sampleRate)
+	for i := range lt {
+		lt[i], rt[i] = rng.NormFloat64(), rng.NormFloat64()
+	}
+	if _, err := skew.Estimate(lt, rt, sampleRate); err == nil {
+		t.Fatalf("Estimate of unrelated channels succeeded")
+	}
+	if _, err := skew.Estimate(lt[:100], rt[:100], sampleRate); err == nil {
+		t.Fatalf("Estimate of 100 frames succeeded")
+	}
+}
+
+func TestCorrectSource_RecoversSeparation(t *testing.T) {
+	t.Parallel()
+
+	const applied = 120e-6
+	options := metrics.SeparationOptions{
+		SampleRate:     sampleRate,
+		FMin:           200,
+		FMax:           15000,
+		AnalysisWindow: sqmath.WindowHann,
+	}
+	decode := func(stereo [][]float64) [][]float64 {
+		out, err := decoder.NewSQDecoder().Process(stereo)
+		if err != nil {
+			t.Fatalf("decode error = %v", err)
+		}
+		return out
+	}
+
+	// The estimate gets more material than the measurement: its error
+	// falls with the length, and where the backs separate by more than
+	// 70 dB a microsecond counts.
+	mix := encodeNoise(t, 8*n, true, 0, 1, 2, 3)
+	skewed := applySkew(t, mix, applied)
+	estimate, err := skew.Estimate(skewed[0], skewed[1], sampleRate)
+	if err != nil {
+		t.Fatalf("Estimate error = %v", err)
+	}
+
+	degraded := false
+	for src := range 4 {
+		clean := encodeNoise(t, n, false, src)
+		skewedSrc := applySkew(t, clean, applied)
+		corrected := readAll(t, skew.CorrectSource(pipeline.NewSliceSource(skewedSrc), estimate, sampleRate), 2, 4096)
+		want, bad, got := decode(clean), decode(skewedSrc), decode(corrected)
+		for leak := range 4 {
+			if leak == src {
+				continue
+			}
+			w := metrics.ChannelPairSeparation(want, src, leak, options).SeparationDB
+			b := metrics.ChannelPairSeparation(bad, src, leak, options).SeparationDB
+			g := metrics.ChannelPairSeparation(got, src, leak, options).SeparationDB
+			if math.Abs(g-w) > 1 && !(math.IsInf(g, 1) && math.IsInf(w, 1)) {
+				t.Fatalf("source %d, leak %d: corrected separation %.1f dB, clean %.1f dB", src, leak, g, w)
+			}
+			degraded = degraded || b < w-10
+		}
+	}
+	// The backs cancel in each other only with LT and RT aligned.
+	if !degraded {
+		t.Fatalf("a skew of %g µs degraded no separation figure by 10 dB", applied*1e6)
+	}
+}
@@ -0,0 +1,215 @@
+package testsignal
+
+import (
+	"math"
+	"math/rand"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+)
+
+// QuadToneFrequencies are the per-channel tone frequencies (LF, RF, LB, RB)
+// of the standard quad test signal.
+var QuadToneFrequencies = [4]float64{100.0, 200.0, 400.0, 800.0}
+
+// QuadTones synthesizes the standard 4-channel test signal: one sine tone per
+// channel at QuadToneFrequencies plus low-level white noise. The noise is
+// seeded so the output is deterministic.
+func QuadTones(sampleRate, numSamples int, toneLevel, noiseLevel float64) [][]float64 {
+	samples := make([][]float64, 4)
+	for ch := range 4 {
+		samples[ch] = make([]float64, numSamples)
+	}
+	if sampleRate <= 0 {
+		return samples
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := range numSamples {
+		t := float64(i) / float64(sampleRate)
+		for ch := range 4 {
+			tone := toneLevel * math.Sin(2.0*math.Pi*QuadToneFrequencies[ch]*t)
+			noise := noiseLevel * (rng.Float64()*2.0 - 1.0)
+			samples[ch][i] = tone + noise
+		}
+	}
+
+	return samples
+}
+
+// Isolate returns a copy of quad with every channel except ch silenced.
+func Isolate(quad [][]float64, ch int) [][]float64 {
+	isolated := make([][]float64, len(quad))
+	for i := range quad {
+		isolated[i] = make([]float64, len(quad[i]))
+	}
+	if ch >= 0 && ch < len(quad) {
+		copy(isolated[ch], quad[ch])
+	}
+	return isolated
+}
+
+// sweepFadeSeconds is the raised-cosine fade applied at both ends of each
+// sweep and reference tone to avoid clicks at the boundaries.
+const sweepFadeSeconds = 0.005
+
+// LogSweep synthesizes a logarithmic (exponential) sine sweep from fStart to
+// fEnd Hz over numSamples samples with short fades at both ends.
+func LogSweep(sampleRate, numSamples int, fStart, fEnd, level float64) []float64 {
+	sweep := make([]float64, numSamples)
+	if sampleRate <= 0 || numSamples <= 0 || fStart <= 0 || fEnd <= 0 {
+		return sweep
+	}
+
+	duration := float64(numSamples) / float64(sampleRate)
+	rate := math.Log(fEnd / fStart)
+	fade := fadeLength(sampleRate, numSamples)
+
+	for i := range numSamples {
+		t := float64(i) / float64(sampleRate)
+		var phase float64
+		if rate == 0 {
+			phase = 2.0 * math.Pi * fStart * t
+		} else {
+			phase = 2.0 * math.Pi * fStart * duration / rate * (math.Exp(t/duration*rate) - 1.0)
+		}
+		sweep[i] = level * fadeGain(i, numSamples, fade) * math.Sin(phase)
+	}
+	return sweep
+}
+
+// ReferenceTone synthesizes a sine tone of freq Hz with peak amplitude
+// level over numSamples samples, such as the 1 kHz line-up tone at the head
+// of a tape. It has the same short fades as LogSweep.
+func ReferenceTone(sampleRate, numSamples int, freq, level float64) []float64 {
+	tone := make([]float64, numSamples)
+	if sampleRate <= 0 || numSamples <= 0 {
+		return tone
+	}
+
+	fade := fadeLength(sampleRate, numSamples)
+	for i := range numSamples {
+		t := float64(i) / float64(sampleRate)
+		tone[i] = level * fadeGain(i, numSamples, fade) * math.Sin(2.0*math.Pi*freq*t)
+	}
+	return tone
+}
+
+// PinkNoise synthesizes pink noise, whose power falls by 3 dB per octave,
+// limited to the band from low to high Hz, with RMS level before the same
+// short fades as LogSweep. It is built in the frequency domain with
+// 1/sqrt(f) magnitudes and random phases drawn from seed, so the output is
+// deterministic and its spectrum is exactly zero outside the band.
+func PinkNoise(sampleRate, numSamples int, low, high, level float64, seed int64) []float64 {
+	noise := make([]float64, numSamples)
+	if sampleRate <= 0 || numSamples <= 0 {
+		return noise
+	}
+	plan, err := algofft.NewPlan64(numSamples)
+	if err != nil {
+		return noise
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	spectrum := make([]complex128, numSamples)
+	binHz := float64(sampleRate) / float64(numSamples)
+	// Bins 1 to below Nyquist, mirrored so that the signal is real.
+	for k := 1; 2*k < numSamples; k++ {
+		phase := 2 * math.Pi * rng.Float64()
+		if f := float64(k) * binHz; f >= low && f < high {
+			v := complex(math.Cos(phase), math.Sin(phase)) / complex(math.Sqrt(f), 0)
+			spectrum[k] = v
+			spectrum[numSamples-k] = complex(real(v), -imag(v))
+		}
+	}
+	if err := plan.Inverse(spectrum, spectrum); err != nil {
+		return noise
+	}
+
+	sum := 0.0
+	for i, v := range spectrum {
+		noise[i] = real(v)
+		sum += noise[i] * noise[i]
+	}
+	if sum == 0 {
+		return noise
+	}
+	gain := level / math.Sqrt(sum/float64(numSamples))
+	fade := fadeLength(sampleRate, numSamples)
+	for i := range noise {
+		noise[i] *= gain * fadeGain(i, numSamples, fade)
+	}
+	return noise
+}
+
+// fadeLength returns the fade of a signal of numSamples samples:
+// sweepFadeSeconds, at most half the signal.
+func fadeLength(sampleRate, numSamples int) int {
+	return min(int(sweepFadeSeconds*float64(sampleRate)), numSamples/2)
+}
+
+// fadeGain returns the raised-cosine fade gain of sample i of n with fades
+// of fade samples at both ends.
+func fadeGain(i, n, fade int) float64 {
+	if i < fade {
+		return 0.5 * (1.0 - math.Cos(math.Pi*float64(i)/float64(fade)))
+	}
+	if j := n - 1 - i; j < fade {
+		return 0.5 * (1.0 - math.Cos(math.Pi*float64(j)/float64(fade)))
+	}
+	return 1
+}
+
+// QuadSweepSlots synthesizes a 4-channel calibration signal in which each
+// channel (LF, RF, LB, RB) carries a log sweep in its own time slot of
+// slotSamples samples while the other channels are silent.
+func QuadSweepSlots(sampleRate, slotSamples int, fStart, fEnd, level float64) [][]float64 {
+	samples := make([][]float64, 4)
+	for ch := range 4 {
+		samples[ch] = make([]float64, 4*slotSamples)
+	}
+
+	sweep := LogSweep(sampleRate, slotSamples, fStart, fEnd, level)
+	for ch := range 4 {
+		copy(samples[ch][ch*slotSamples:], sweep)
+	}
+	return samples
+}
+
+// MovingSourceCorners is the order in which MovingSource visits the
+// channels: around the room clockwise from left front.
+var MovingSourceCorners = [4]int{0, 1, 3, 2}
+
+// MovingSource synthesizes a 4-channel signal of one sine tone that moves
+// around the room, LF, RF, RB, LB, staying slotSamples samples in each
+// corner. It pans between corners with equal power over sweepFadeSeconds
+// centered on the slot boundaries, to test how steering follows a source.
+func MovingSource(sampleRate, slotSamples int, freq, level float64) [][]float64 {
+	samples := make([][]float64, 4)
+	for ch := range 4 {
+		samples[ch] = make([]float64, 4*slotSamples)
+	}
+	if sampleRate <= 0 || slotSamples <= 0 {
+		return samples
+	}
+
+	fade := min(int(sweepFadeSeconds*float64(sampleRate)), slotSamples)
+	for i := range 4 * slotSamples {
+		tone := level * math.Sin(2.0*math.Pi*freq*float64(i)/float64(sampleRate))
+		slot := i / slotSamples
+		// The pan position between this corner and the next, reaching the
+		// next one fade/2 samples after the boundary.
+		pan := 0.0
+		if d := i - (slot+1)*slotSamples + fade/2; d >= 0 && slot < 3 {
+			pan = float64(d) / float64(fade)
+		}
+		if d := i - slot*slotSamples; d < fade/2 && slot > 0 {
+			slot--
+			pan = float64(d+fade/2) / float64(fade)
+		}
+		samples[MovingSourceCorners[slot]][i] += tone * math.Cos(pan*math.Pi/2)
+		if pan > 0 {
+			samples[MovingSourceCorners[slot+1]][i] += tone * math.Sin(pan*math.Pi/2)
+		}
+	}
+	return samples
+}
@@ -0,0 +1,163 @@
+package testsignal_test
+
+import (
+	"math"
+	"testing"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+func TestQuadTones_Deterministic(t *testing.T) {
+	t.Parallel()
+
+	a := testsignal.QuadTones(44100, 1000, 0.6, 0.05)
+	b := testsignal.QuadTones(44100, 1000, 0.6, 0.05)
+	for ch := range 4 {
+		for i := range a[ch] {
+			if a[ch][i] != b[ch][i] {
+				t.Fatalf("sample[%d][%d] differs between runs: %v vs %v", ch, i, a[ch][i], b[ch][i])
+			}
+		}
+	}
+}
+
+func TestQuadTones_NoNoiseIsPureTone(t *testing.T) {
+	t.Parallel()
+
+	const rate = 8000
+	quad := testsignal.QuadTones(rate, 200, 0.5, 0)
+	for ch := range 4 {
+		for i := range quad[ch] {
+			want := 0.5 * math.Sin(2.0*math.Pi*testsignal.QuadToneFrequencies[ch]*float64(i)/rate)
+			if math.Abs(quad[ch][i]-want) > 1e-12 {
+				t.Fatalf("sample[%d][%d] = %.12f, want %.12f", ch, i, quad[ch][i], want)
+			}
+		}
+	}
+}
+
+func TestIsolate(t *testing.T) {
+	t.Parallel()
+
+	quad := [][]float64{{1, 1}, {2, 2}, {3, 3}, {4, 4}}
+	isolated := testsignal.Isolate(quad, 2)
+	for ch := range 4 {
+		for i := range isolated[ch] {
+			want := 0.0
+			if ch == 2 {
+				want = 3
+			}
+			if isolated[ch][i] != want {
+				t.Fatalf("isolated[%d][%d] = %v, want %v", ch, i, isolated[ch][i], want)
+			}
+		}
+	}
+	if quad[0][0] != 1 {
+		t.Fatalf("Isolate modified its input")
+	}
+}
+
+func TestQuadSweepSlots_EnergyConfinedToSlot(t *testing.T) {
+	t.Parallel()
+
+	const (
+		rate = 44100
+		slot = rate / 2
+	)
+	quad := testsignal.QuadSweepSlots(rate, slot, 20, 20000, 0.5)
+
+	for ch := range 4 {
+		if got := len(quad[ch]); got != 4*slot {
+			t.Fatalf("len(quad[%d]) = %d, want %d", ch, got, 4*slot)
+		}
+		for s := range 4 {
+			energy := 0.0
+			for _, v := range quad[ch][s*slot : (s+1)*slot] {
+				energy += v * v
+			}
+			if s == ch {
+				// A sine of amplitude 0.5 has mean power 0.125.
+				if energy < 0.1*float64(slot) {
+					t.Fatalf("channel %d slot %d energy = %.3f, want most of the sweep", ch, s, energy)
+				}
+				continue
+			}
+			if energy != 0 {
+				t.Fatalf("channel %d slot %d energy = %g, want 0", ch, s, energy)
+			}
+		}
+	}
+}
+
+func TestLogSweep_FrequencyRises(t *testing.T) {
+	t.Parallel()
+
+	const rate = 48000
+	sweep := testsignal.LogSweep(rate, rate, 100, 10000, 1.0)
+
+	crossings := func(from, to int) int {
+		n := 0
+		for i := from + 1; i < to; i++ {
+			if (sweep[i-1] < 0) != (sweep[i] < 0) {
+				n++
+			}
+		}
+		return n
+	}
+	early := crossings(0, rate/10)
+	late := crossings(rate-rate/10, rate)
+	if late <= 10*early {
+		t.Fatalf("zero crossings early=%d late=%d, want a rising frequency", early, late)
+	}
+}
+
+func TestPinkNoise(t *testing.T) {
+	t.Parallel()
+
+	const sampleRate, n = 44100, 1 << 16
+	noise := testsignal.PinkNoise(sampleRate, n, 100, 12800, 0.1, 1)
+	sum := 0.0
+	for _, v := range noise {
+		sum += v * v
+	}
+	if rms := math.Sqrt(sum / n); math.Abs(rms-0.1) > 0.002 {
+		t.Fatalf("RMS = %g, want 0.1", rms)
+	}
+	again := testsignal.PinkNoise(sampleRate, n, 100, 12800, 0.1, 1)
+	other := testsignal.PinkNoise(sampleRate, n, 100, 12800, 0.1, 2)
+	if again[n/2] != noise[n/2] || other[n/2] == noise[n/2] {
+		t.Fatalf("noise is not determined by the seed")
+	}
+
+	// Pink noise carries the same power in every octave and none outside
+	// the band.
+	plan, err := algofft.NewPlan64(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spectrum := make([]complex128, n)
+	in := make([]complex128, n)
+	for i, v := range noise {
+		in[i] = complex(v, 0)
+	}
+	if err := plan.Forward(spectrum, in); err != nil {
+		t.Fatal(err)
+	}
+	power := func(low, high float64) float64 {
+		p := 0.0
+		for k := 1; k < n/2; k++ {
+			if f := float64(k) * sampleRate / n; f >= low && f < high {
+				p += real(spectrum[k])*real(spectrum[k]) + imag(spectrum[k])*imag(spectrum[k])
+			}
+		}
+		return p
+	}
+	low, high, total := power(100, 200), power(6400, 12800), power(0, sampleRate/2)
+	if db := 10 * math.Log10(high/low); math.Abs(db) > 0.5 {
+		t.Fatalf("top octave is %.2f dB relative to the bottom one, want 0", db)
+	}
+	if out := power(0, 90) + power(14000, sampleRate/2); out > 1e-3*total {
+		t.Fatalf("%.2g of the power lies outside the band", out/total)
+	}
+}
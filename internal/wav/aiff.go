@@ -0,0 +1,186 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Audio Interchange File Format (AIFF) files are a "FORM" container of type
+// AIFF or AIFC with big-endian chunk sizes, each chunk padded to an even
+// length. The COMM chunk describes the audio, with the sample rate as an
+// 80-bit extended float; the SSND chunk holds the samples after an offset
+// and block size. Of AIFF-C, only the uncompressed types are supported.
+
+const (
+	// aiffCommSize and aifcCommSize are the minimum COMM chunk sizes of
+	// AIFF and AIFF-C, which adds the compression type.
+	aiffCommSize = 18
+	aifcCommSize = 22
+	// aiffSSNDHeaderSize is the offset and block size leading the SSND
+	// chunk.
+	aiffSSNDHeaderSize = 8
+)
+
+// aifcTypes maps the supported AIFF-C compression types to the flags of
+// the equivalent linear PCM description.
+var aifcTypes = map[string]uint32{
+	"NONE": 0,
+	"twos": 0,
+	"sowt": cafFlagLittleEndian,
+	"fl32": cafFlagFloat,
+	"FL32": cafFlagFloat,
+	"fl64": cafFlagFloat,
+	"FL64": cafFlagFloat,
+}
+
+// ReadAIFF reads an AIFF or AIFF-C file with a specific channel count.
+func ReadAIFF(filename string, channels int) (*AudioData, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open AIFF file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadAIFFFromReader(file, channels)
+}
+
+// ReadAIFFFromReader reads an AIFF or AIFF-C stream with a specific channel
+// count.
+func ReadAIFFFromReader(r io.Reader, channels int) (*AudioData, error) {
+	data, err := readAIFF(bufio.NewReader(r), channels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AIFF: %w", err)
+	}
+	return data, nil
+}
+
+func readAIFF(br *bufio.Reader, channels int) (*AudioData, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, fmt.Errorf("read file header: %w", err)
+	}
+	formType := string(header[8:12])
+	if string(header[0:4]) != "FORM" || (formType != "AIFF" && formType != "AIFC") {
+		return nil, fmt.Errorf("not an AIFF file")
+	}
+
+	var desc *cafDesc
+	var numFrames int64
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(br, chunk[:]); err != nil {
+			return nil, fmt.Errorf("no SSND chunk found: %w", err)
+		}
+		id, size := string(chunk[0:4]), int64(binary.BigEndian.Uint32(chunk[4:]))
+		switch id {
+		case "COMM":
+			d, frames, err := readCommChunk(br, size, formType == "AIFC")
+			if err != nil {
+				return nil, err
+			}
+			if int(d.channels) != channels {
+				return nil, &ChannelCountError{Want: channels, Got: int(d.channels)}
+			}
+			desc, numFrames = d, frames
+		case "SSND":
+			if desc == nil {
+				return nil, fmt.Errorf("SSND chunk before COMM chunk")
+			}
+			if size < aiffSSNDHeaderSize {
+				return nil, fmt.Errorf("invalid SSND chunk size %d", size)
+			}
+			var ssnd [aiffSSNDHeaderSize]byte
+			if _, err := io.ReadFull(br, ssnd[:]); err != nil {
+				return nil, fmt.Errorf("read SSND header: %w", err)
+			}
+			offset := int64(binary.BigEndian.Uint32(ssnd[0:]))
+			if _, err := io.CopyN(io.Discard, br, offset); err != nil {
+				return nil, fmt.Errorf("skip SSND offset: %w", err)
+			}
+			dataSize := min(size-aiffSSNDHeaderSize-offset, numFrames*int64(desc.bytesPerPacket))
+			if dataSize < 0 {
+				return nil, fmt.Errorf("invalid SSND offset %d", offset)
+			}
+			return readPCMData(br, desc, dataSize)
+		default:
+			if _, err := io.CopyN(io.Discard, br, size+size%2); err != nil {
+				return nil, fmt.Errorf("skip %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// readCommChunk reads a COMM chunk of size bytes and returns it as a linear
+// PCM description and the frame count.
+func readCommChunk(br *bufio.Reader, size int64, aifc bool) (*cafDesc, int64, error) {
+	minSize := int64(aiffCommSize)
+	if aifc {
+		minSize = aifcCommSize
+	}
+	if size < minSize {
+		return nil, 0, fmt.Errorf("invalid COMM chunk size %d", size)
+	}
+	payload := make([]byte, size+size%2)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return nil, 0, fmt.Errorf("read COMM chunk: %w", err)
+	}
+
+	channels := uint32(binary.BigEndian.Uint16(payload[0:]))
+	numFrames := int64(binary.BigEndian.Uint32(payload[2:]))
+	bits := uint32(binary.BigEndian.Uint16(payload[6:]))
+	var flags uint32
+	if aifc {
+		compression := string(payload[18:22])
+		f, ok := aifcTypes[compression]
+		if !ok {
+			return nil, 0, fmt.Errorf("unsupported AIFF-C compression %q (only uncompressed PCM and float)", compression)
+		}
+		flags = f
+		switch compression {
+		case "fl32", "FL32":
+			bits = 32
+		case "fl64", "FL64":
+			bits = 64
+		}
+	}
+	// Samples are stored left-justified in whole bytes, so a 20-bit file
+	// reads like a 24-bit one.
+	bits = (bits + 7) / 8 * 8
+
+	desc := &cafDesc{
+		sampleRate:      extendedToFloat64(payload[8:18]),
+		formatID:        "lpcm",
+		flags:           flags,
+		bytesPerPacket:  channels * bits / 8,
+		framesPerPacket: 1,
+		channels:        channels,
+		bits:            bits,
+	}
+	if err := desc.validate(); err != nil {
+		return nil, 0, err
+	}
+	return desc, numFrames, nil
+}
+
+// extendedToFloat64 converts an 80-bit IEEE 754 extended precision number,
+// as used for the AIFF sample rate, to a float64.
+func extendedToFloat64(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:]))
+	mantissa := binary.BigEndian.Uint64(b[2:])
+	sign := 1.0
+	if exponent&0x8000 != 0 {
+		sign = -1
+		exponent &= 0x7fff
+	}
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	if exponent == 0x7fff {
+		return math.NaN()
+	}
+	return sign * math.Ldexp(float64(mantissa), exponent-16383-63)
+}
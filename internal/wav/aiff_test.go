@@ -0,0 +1,128 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// aiffFile builds an AIFF stream, or AIFF-C for a non-empty compression
+// type: a chunk the reader has to skip, a COMM chunk and an SSND chunk with
+// a 2-byte offset ahead of the samples.
+func aiffFile(compression string, bits, channels, frames int, samples []byte) []byte {
+	comm := binary.BigEndian.AppendUint16(nil, uint16(channels))
+	comm = binary.BigEndian.AppendUint32(comm, uint32(frames))
+	comm = binary.BigEndian.AppendUint16(comm, uint16(bits))
+	// 44100 as an 80-bit extended float.
+	comm = append(comm, 0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0)
+	formType := "AIFF"
+	if compression != "" {
+		formType = "AIFC"
+		comm = append(comm, compression...)
+		comm = append(comm, 0, 0) // empty compression name, padded
+	}
+	ssnd := append([]byte{0, 0, 0, 2, 0, 0, 0, 0, 0xee, 0xee}, samples...)
+
+	var body bytes.Buffer
+	body.WriteString(formType)
+	chunk := func(id string, payload []byte) {
+		body.WriteString(id)
+		binary.Write(&body, binary.BigEndian, uint32(len(payload)))
+		body.Write(payload)
+		if len(payload)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	chunk("NAME", []byte("abc"))
+	chunk("COMM", comm)
+	chunk("SSND", ssnd)
+
+	var buf bytes.Buffer
+	buf.WriteString("FORM")
+	binary.Write(&buf, binary.BigEndian, uint32(body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+func TestReadAIFF_Synthesized(t *testing.T) {
+	t.Parallel()
+
+	// Big-endian 16-bit, two channels.
+	pcm := []byte{0x40, 0x00, 0xc0, 0x00, 0x7f, 0xff, 0x80, 0x00}
+	floats := binary.BigEndian.AppendUint32(nil, math.Float32bits(0.25))
+	floats = binary.BigEndian.AppendUint32(floats, math.Float32bits(-0.75))
+	tests := []struct {
+		name        string
+		compression string
+		bits        int
+		samples     []byte
+		want        [][]float64
+		float       bool
+	}{
+		{"AIFF", "", 16, pcm, [][]float64{{0.5, 32767.0 / 32768}, {-0.5, -1}}, false},
+		{"twos", "twos", 16, pcm, [][]float64{{0.5, 32767.0 / 32768}, {-0.5, -1}}, false},
+		{"sowt", "sowt", 16, []byte{0x00, 0x40, 0x00, 0xc0, 0xff, 0x7f, 0x00, 0x80}, [][]float64{{0.5, 32767.0 / 32768}, {-0.5, -1}}, false},
+		{"fl32", "fl32", 32, floats, [][]float64{{0.25}, {-0.75}}, true},
+		// 20-bit samples are stored left-justified in 3 bytes.
+		{"20-bit", "", 20, []byte{0x40, 0x00, 0x00, 0xc0, 0x00, 0x00}, [][]float64{{0.5}, {-0.5}}, false},
+	}
+	for _, tt := range tests {
+		frames := len(tt.want[0])
+		data, err := ReadAIFFFromReader(bytes.NewReader(aiffFile(tt.compression, tt.bits, 2, frames, tt.samples)), 2)
+		if err != nil {
+			t.Fatalf("%s: ReadAIFFFromReader() error = %v", tt.name, err)
+		}
+		if data.SampleRate != 44100 || data.NumSamples != frames || data.Float != tt.float {
+			t.Fatalf("%s: got %d Hz, %d frames, float %v, want 44100 Hz, %d frames, float %v",
+				tt.name, data.SampleRate, data.NumSamples, data.Float, frames, tt.float)
+		}
+		for ch := range tt.want {
+			for i, want := range tt.want[ch] {
+				if got := data.Samples[ch][i]; got != want {
+					t.Fatalf("%s: channel %d sample %d = %v, want %v", tt.name, ch, i, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestReadAIFF_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name, want string
+		file       []byte
+		channels   int
+	}{
+		{"channel mismatch", "input must have 4 channels", aiffFile("", 16, 2, 0, nil), 4},
+		{"compressed", `unsupported AIFF-C compression "ulaw"`, aiffFile("ulaw", 8, 2, 0, nil), 2},
+		{"truncated data", "read sample data", aiffFile("", 16, 1, 4, make([]byte, 8))[:72], 1},
+		{"not AIFF", "not an AIFF file", []byte("RIFF\x00\x00\x00\x00WAVE"), 2},
+	}
+	for _, tt := range tests {
+		_, err := ReadAIFFFromReader(bytes.NewReader(tt.file), tt.channels)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestExtendedToFloat64(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		b    []byte
+		want float64
+	}{
+		{[]byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}, 44100},
+		{[]byte{0x40, 0x0e, 0xbb, 0x80, 0, 0, 0, 0, 0, 0}, 48000},
+		{[]byte{0x40, 0x0b, 0xfa, 0, 0, 0, 0, 0, 0, 0}, 8000},
+		{make([]byte, 10), 0},
+	} {
+		if got := extendedToFloat64(tt.b); got != tt.want {
+			t.Fatalf("extendedToFloat64(% x) = %v, want %v", tt.b, got, tt.want)
+		}
+	}
+}
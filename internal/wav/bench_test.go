@@ -0,0 +1,47 @@
+package wav
+
+import (
+	"io"
+	"math"
+	"testing"
+)
+
+func BenchmarkWriter(b *testing.B) {
+	const (
+		channels   = 4
+		numFrames  = 10 * 44100
+		chunkFrame = 8192
+	)
+	samples := make([][]float64, channels)
+	for ch := range samples {
+		samples[ch] = make([]float64, numFrames)
+		for i := range samples[ch] {
+			samples[ch][i] = 0.5 * math.Sin(2*math.Pi*float64((ch+1)*i)/441)
+		}
+	}
+
+	for _, format := range []SampleFormat{FormatPCM16, FormatFloat32} {
+		b.Run(format.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(8 * channels * numFrames))
+			chunk := make([][]float64, channels)
+			for b.Loop() {
+				w, err := NewWriter(io.Discard, 44100, channels, numFrames, WriteOptions{Format: format})
+				if err != nil {
+					b.Fatal(err)
+				}
+				for start := 0; start < numFrames; start += chunkFrame {
+					for ch := range chunk {
+						chunk[ch] = samples[ch][start:min(start+chunkFrame, numFrames)]
+					}
+					if err := w.WriteFrames(chunk); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := w.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Core Audio Format (CAF) files start with a "caff" header followed by
+// chunks with big-endian 64-bit sizes. The desc chunk, which comes first,
+// describes the audio; the data chunk holds the samples after a 4-byte edit
+// count and, as the last chunk, may declare size -1 to run to the end of
+// the file. Only linear PCM is supported.
+
+const (
+	// cafFlagFloat and cafFlagLittleEndian are the linear PCM format
+	// flags of the desc chunk.
+	cafFlagFloat        = 1 << 0
+	cafFlagLittleEndian = 1 << 1
+	// cafDescSize is the size of the desc chunk payload.
+	cafDescSize = 32
+	// cafEditCountSize is the size of the edit count leading the data
+	// chunk.
+	cafEditCountSize = 4
+)
+
+// cafDesc is the audio description of a CAF file.
+type cafDesc struct {
+	sampleRate      float64
+	formatID        string
+	flags           uint32
+	bytesPerPacket  uint32
+	framesPerPacket uint32
+	channels        uint32
+	bits            uint32
+}
+
+func (d *cafDesc) float() bool {
+	return d.flags&cafFlagFloat != 0
+}
+
+func (d *cafDesc) order() binary.ByteOrder {
+	if d.flags&cafFlagLittleEndian != 0 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// validate checks that the description is linear PCM the reader can decode.
+func (d *cafDesc) validate() error {
+	if d.formatID != "lpcm" {
+		return fmt.Errorf("unsupported CAF format %q (only linear PCM)", d.formatID)
+	}
+	if d.channels == 0 {
+		return fmt.Errorf("invalid channel count 0")
+	}
+	if !(d.sampleRate >= 1 && d.sampleRate <= math.MaxUint32) {
+		return fmt.Errorf("invalid sample rate %g", d.sampleRate)
+	}
+	if d.float() {
+		if d.bits != 32 && d.bits != 64 {
+			return fmt.Errorf("unsupported float bit depth %d", d.bits)
+		}
+	} else {
+		switch d.bits {
+		case 8, 16, 24, 32:
+		default:
+			return fmt.Errorf("unsupported PCM bit depth %d", d.bits)
+		}
+	}
+	if d.framesPerPacket != 1 || d.bytesPerPacket != d.channels*d.bits/8 {
+		return fmt.Errorf("invalid packet layout of %d bytes, %d frames for %d channels of %d-bit samples",
+			d.bytesPerPacket, d.framesPerPacket, d.channels, d.bits)
+	}
+	return nil
+}
+
+// sample decodes one sample of b.
+func (d *cafDesc) sample(b []byte) float64 {
+	order := d.order()
+	if d.float() {
+		if d.bits == 64 {
+			return clampUnit(math.Float64frombits(order.Uint64(b)))
+		}
+		return Float32ToFloat64(math.Float32frombits(order.Uint32(b)))
+	}
+	switch d.bits {
+	case 8:
+		return float64(int8(b[0])) / 128.0
+	case 16:
+		return Int16ToFloat64(int16(order.Uint16(b)))
+	case 24:
+		if order == binary.BigEndian {
+			return Int24ToFloat64(int32(b[0])<<16 | int32(b[1])<<8 | int32(b[2]))
+		}
+		return Int24ToFloat64(int32(b[2])<<16 | int32(b[1])<<8 | int32(b[0]))
+	default:
+		return Int32ToFloat64(int32(order.Uint32(b)))
+	}
+}
+
+// ReadCAF reads a CAF file with a specific channel count.
+func ReadCAF(filename string, channels int) (*AudioData, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CAF file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadCAFFromReader(file, channels)
+}
+
+// ReadCAFFromReader reads a CAF stream with a specific channel count.
+func ReadCAFFromReader(r io.Reader, channels int) (*AudioData, error) {
+	data, err := readCAF(bufio.NewReader(r), channels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CAF: %w", err)
+	}
+	return data, nil
+}
+
+func readCAF(br *bufio.Reader, channels int) (*AudioData, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, fmt.Errorf("read file header: %w", err)
+	}
+	if string(header[0:4]) != "caff" {
+		return nil, fmt.Errorf("not a CAF file")
+	}
+	if version := binary.BigEndian.Uint16(header[4:]); version != 1 {
+		return nil, fmt.Errorf("unsupported CAF version %d", version)
+	}
+
+	var desc *cafDesc
+	for {
+		var chunk [12]byte
+		if _, err := io.ReadFull(br, chunk[:]); err != nil {
+			return nil, fmt.Errorf("no data chunk found: %w", err)
+		}
+		id, size := string(chunk[0:4]), int64(binary.BigEndian.Uint64(chunk[4:]))
+		switch {
+		case id == "desc":
+			if size != cafDescSize {
+				return nil, fmt.Errorf("invalid desc chunk size %d", size)
+			}
+			var payload [cafDescSize]byte
+			if _, err := io.ReadFull(br, payload[:]); err != nil {
+				return nil, fmt.Errorf("read desc chunk: %w", err)
+			}
+			desc = &cafDesc{
+				sampleRate:      math.Float64frombits(binary.BigEndian.Uint64(payload[0:])),
+				formatID:        string(payload[8:12]),
+				flags:           binary.BigEndian.Uint32(payload[12:]),
+				bytesPerPacket:  binary.BigEndian.Uint32(payload[16:]),
+				framesPerPacket: binary.BigEndian.Uint32(payload[20:]),
+				channels:        binary.BigEndian.Uint32(payload[24:]),
+				bits:            binary.BigEndian.Uint32(payload[28:]),
+			}
+			if err := desc.validate(); err != nil {
+				return nil, err
+			}
+			if int(desc.channels) != channels {
+				return nil, &ChannelCountError{Want: channels, Got: int(desc.channels)}
+			}
+		case id == "data":
+			if desc == nil {
+				return nil, fmt.Errorf("data chunk before desc chunk")
+			}
+			if size != -1 && size < cafEditCountSize {
+				return nil, fmt.Errorf("invalid data chunk size %d", size)
+			}
+			return readCAFData(br, desc, size)
+		case size < 0:
+			return nil, fmt.Errorf("invalid %q chunk size %d", id, size)
+		default:
+			if _, err := io.CopyN(io.Discard, br, size); err != nil {
+				return nil, fmt.Errorf("skip %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// readCAFData reads the samples of a data chunk of size bytes, or up to the
+// end of the stream for size -1.
+func readCAFData(br *bufio.Reader, desc *cafDesc, size int64) (*AudioData, error) {
+	if _, err := io.CopyN(io.Discard, br, cafEditCountSize); err != nil {
+		return nil, fmt.Errorf("read edit count: %w", err)
+	}
+	if size >= 0 {
+		size -= cafEditCountSize
+	}
+	return readPCMData(br, desc, size)
+}
+
+// readPCMData reads size bytes of interleaved samples as described by desc,
+// or up to the end of the stream for size -1, allocating as the data
+// actually arrives.
+func readPCMData(br *bufio.Reader, desc *cafDesc, size int64) (*AudioData, error) {
+	frameSize := int(desc.bytesPerPacket)
+	remaining := int64(-1)
+	if size >= 0 {
+		remaining = size / int64(frameSize)
+	}
+
+	channels := int(desc.channels)
+	samples := make([][]float64, channels)
+	buf := make([]byte, readChunkFrames*frameSize)
+	sampleSize := frameSize / channels
+	for remaining != 0 {
+		want := readChunkFrames
+		if remaining > 0 {
+			want = int(min(remaining, readChunkFrames))
+		}
+		n, err := io.ReadFull(br, buf[:want*frameSize])
+		frames := n / frameSize
+		for i := range frames {
+			frame := buf[i*frameSize:]
+			for ch := range samples {
+				samples[ch] = append(samples[ch], desc.sample(frame[ch*sampleSize:]))
+			}
+		}
+		if remaining > 0 {
+			remaining -= int64(frames)
+		}
+		if size < 0 && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read sample data: %w", err)
+		}
+	}
+
+	numFrames := 0
+	if channels > 0 {
+		numFrames = len(samples[0])
+	}
+	for ch := range samples {
+		if samples[ch] == nil {
+			samples[ch] = []float64{}
+		}
+	}
+	return &AudioData{
+		SampleRate:    uint32(math.Round(desc.sampleRate)),
+		Samples:       samples,
+		NumSamples:    numFrames,
+		BitsPerSample: int(desc.bits),
+		Float:         desc.float(),
+	}, nil
+}
+
+// WriteCAF writes audio data with the given channel count and sample format
+// to a CAF file.
+func WriteCAF(filename string, data *AudioData, channels int, options WriteOptions) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create CAF file: %w", err)
+	}
+	defer file.Close()
+
+	return WriteCAFToWriter(file, data, channels, options)
+}
+
+// WriteCAFToWriter writes audio data with the given channel count and sample
+// format to a CAF stream.
+func WriteCAFToWriter(w io.Writer, data *AudioData, channels int, options WriteOptions) error {
+	if err := data.validateChannels(channels); err != nil {
+		return err
+	}
+
+	writer, err := NewCAFWriter(w, data.SampleRate, channels, data.Frames(), options)
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteFrames(data.Samples); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// CAFWriter encodes sample frames to a CAF stream incrementally, in
+// big-endian byte order. Like Writer it needs the frame count up front.
+// Of the WriteOptions only Format and ErrorOnClip apply; CAF has no place
+// for the RIFF chunk layout, INFO fields or loop points.
+type CAFWriter struct {
+	bw          *bufio.Writer
+	channels    int
+	format      SampleFormat
+	numFrames   int
+	written     int
+	errorOnClip bool
+	clipped     int
+	closed      bool
+}
+
+// NewCAFWriter writes the CAF header, the desc chunk and the data chunk
+// header. Frames are then appended with WriteFrames, and Close finishes the
+// stream.
+func NewCAFWriter(w io.Writer, sampleRate uint32, channels, numFrames int, options WriteOptions) (*CAFWriter, error) {
+	if channels <= 0 {
+		return nil, fmt.Errorf("channels must be > 0, got %d", channels)
+	}
+	if numFrames < 0 {
+		return nil, fmt.Errorf("NumSamples must be >= 0")
+	}
+	var flags, bits uint32
+	switch options.Format {
+	case FormatPCM16:
+		bits = 16
+	case FormatFloat32:
+		flags, bits = cafFlagFloat, 32
+	case FormatPCM8:
+		bits = 8
+	case FormatPCM24:
+		bits = 24
+	default:
+		return nil, fmt.Errorf("unsupported sample format %d", options.Format)
+	}
+	frameSize := uint32(channels) * bits / 8
+
+	var header [8 + 12 + cafDescSize + 12 + cafEditCountSize]byte
+	copy(header[0:], "caff")
+	binary.BigEndian.PutUint16(header[4:], 1) // version; flags stay 0
+	copy(header[8:], "desc")
+	binary.BigEndian.PutUint64(header[12:], cafDescSize)
+	desc := header[20:]
+	binary.BigEndian.PutUint64(desc[0:], math.Float64bits(float64(sampleRate)))
+	copy(desc[8:], "lpcm")
+	binary.BigEndian.PutUint32(desc[12:], flags)
+	binary.BigEndian.PutUint32(desc[16:], frameSize)
+	binary.BigEndian.PutUint32(desc[20:], 1)
+	binary.BigEndian.PutUint32(desc[24:], uint32(channels))
+	binary.BigEndian.PutUint32(desc[28:], bits)
+	data := header[20+cafDescSize:]
+	copy(data[0:], "data")
+	binary.BigEndian.PutUint64(data[4:], uint64(cafEditCountSize)+uint64(numFrames)*uint64(frameSize))
+	// The edit count that follows stays 0.
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header[:]); err != nil {
+		return nil, fmt.Errorf("failed to write CAF header: %w", err)
+	}
+	return &CAFWriter{
+		bw:          bw,
+		channels:    channels,
+		format:      options.Format,
+		numFrames:   numFrames,
+		errorOnClip: options.ErrorOnClip,
+	}, nil
+}
+
+// WriteFrames appends the frames in samples ([channel][frame]) to the data
+// chunk. All channels must have the same length.
+func (w *CAFWriter) WriteFrames(samples [][]float64) error {
+	if w.closed {
+		return fmt.Errorf("write to closed CAF writer")
+	}
+	if len(samples) != w.channels {
+		return fmt.Errorf("output must have %d channels, got %d", w.channels, len(samples))
+	}
+	n := len(samples[0])
+	for ch := 1; ch < w.channels; ch++ {
+		if len(samples[ch]) != n {
+			return fmt.Errorf("channel %d has %d samples, want %d", ch, len(samples[ch]), n)
+		}
+	}
+	if w.written+n > w.numFrames {
+		return fmt.Errorf("writing %d frames exceeds declared length %d", w.written+n, w.numFrames)
+	}
+
+	var b [4]byte
+	for i := 0; i < n; i++ {
+		for ch := 0; ch < w.channels; ch++ {
+			v := samples[ch][i]
+			if v > 1 || v < -1 {
+				if w.errorOnClip {
+					return fmt.Errorf("%w: channel %d, frame %d is %g", ErrClipped, ch, w.written+i, v)
+				}
+				w.clipped++
+			}
+			var sample []byte
+			switch w.format {
+			case FormatPCM16:
+				binary.BigEndian.PutUint16(b[:], uint16(Float64ToInt16(v)))
+				sample = b[:2]
+			case FormatFloat32:
+				binary.BigEndian.PutUint32(b[:], math.Float32bits(Float64ToFloat32(v)))
+				sample = b[:4]
+			case FormatPCM8:
+				b[0] = byte(Float64ToUint8(v) - 128)
+				sample = b[:1]
+			case FormatPCM24:
+				binary.BigEndian.PutUint32(b[:], uint32(Float64ToInt24(v))<<8)
+				sample = b[:3]
+			}
+			if _, err := w.bw.Write(sample); err != nil {
+				return fmt.Errorf("failed to write sample data: %w", err)
+			}
+		}
+	}
+	w.written += n
+	return nil
+}
+
+// Clipped returns the number of samples written so far that lay outside
+// [-1, 1] and were clamped.
+func (w *CAFWriter) Clipped() int {
+	return w.clipped
+}
+
+// Close flushes the stream. It fails if fewer frames were written than
+// declared. Close does not close the underlying io.Writer.
+func (w *CAFWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.written != w.numFrames {
+		return fmt.Errorf("wrote %d frames, declared %d", w.written, w.numFrames)
+	}
+	if err := w.bw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush CAF data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCAF_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	in := &AudioData{
+		SampleRate: 48000,
+		Samples: [][]float64{
+			{0.0, 0.5, -0.5, 1.0, -1.0, 0.25, -0.25},
+			{0.1, -0.1, 0.9, -0.9, 0.0, 0.75, -0.75},
+			{0.3, 0.2, 0.1, 0.0, -0.1, -0.2, -0.3},
+		},
+		NumSamples: 7,
+	}
+	tests := []struct {
+		format    SampleFormat
+		tolerance float64
+	}{
+		{FormatPCM16, 2.0 / 32768},
+		{FormatFloat32, 1e-7},
+		{FormatPCM8, 2.0 / 128},
+	}
+	for _, tt := range tests {
+		filename := filepath.Join(t.TempDir(), "out.caf")
+		if err := WriteCAF(filename, in, 3, WriteOptions{Format: tt.format}); err != nil {
+			t.Fatalf("%s: WriteCAF() error = %v", tt.format, err)
+		}
+		out, err := ReadCAF(filename, 3)
+		if err != nil {
+			t.Fatalf("%s: ReadCAF() error = %v", tt.format, err)
+		}
+		if out.SampleRate != in.SampleRate || out.NumSamples != in.NumSamples {
+			t.Fatalf("%s: got %d Hz, %d frames, want %d Hz, %d frames", tt.format, out.SampleRate, out.NumSamples, in.SampleRate, in.NumSamples)
+		}
+		for ch := range in.Samples {
+			for i, want := range in.Samples[ch] {
+				if got := out.Samples[ch][i]; math.Abs(got-want) > tt.tolerance {
+					t.Fatalf("%s: channel %d sample %d = %v, want %v", tt.format, ch, i, got, want)
+				}
+			}
+		}
+	}
+}
+
+// cafFile builds a CAF stream: a desc chunk for the given flags and bit
+// depth, a chunk the reader has to skip and a data chunk of the given size.
+func cafFile(flags, bits, channels uint32, dataSize int64, samples []byte) []byte {
+	var buf bytes.Buffer
+	chunk := func(id string, size int64) {
+		buf.WriteString(id)
+		binary.Write(&buf, binary.BigEndian, size)
+	}
+	buf.WriteString("caff")
+	binary.Write(&buf, binary.BigEndian, [2]uint16{1, 0})
+	chunk("desc", 32)
+	binary.Write(&buf, binary.BigEndian, 44100.0)
+	buf.WriteString("lpcm")
+	binary.Write(&buf, binary.BigEndian, [5]uint32{flags, channels * bits / 8, 1, channels, bits})
+	chunk("free", 3)
+	buf.Write([]byte{0, 0, 0})
+	chunk("data", dataSize)
+	binary.Write(&buf, binary.BigEndian, uint32(0))
+	buf.Write(samples)
+	return buf.Bytes()
+}
+
+func TestReadCAF_Synthesized(t *testing.T) {
+	t.Parallel()
+
+	// Little-endian 24-bit, two channels, data running to the end.
+	samples := []byte{
+		0x00, 0x00, 0x40, 0x00, 0x00, 0xc0, // 0.5, -0.5
+		0xff, 0xff, 0x7f, 0x00, 0x00, 0x80, // max, -1
+	}
+	data, err := ReadCAFFromReader(bytes.NewReader(cafFile(cafFlagLittleEndian, 24, 2, -1, samples)), 2)
+	if err != nil {
+		t.Fatalf("ReadCAFFromReader() error = %v", err)
+	}
+	want := [][]float64{{0.5, 8388607.0 / 8388608}, {-0.5, -1}}
+	if data.SampleRate != 44100 || data.NumSamples != 2 {
+		t.Fatalf("got %d Hz, %d frames, want 44100 Hz, 2 frames", data.SampleRate, data.NumSamples)
+	}
+	for ch := range want {
+		for i := range want[ch] {
+			if data.Samples[ch][i] != want[ch][i] {
+				t.Fatalf("channel %d sample %d = %v, want %v", ch, i, data.Samples[ch][i], want[ch][i])
+			}
+		}
+	}
+
+	// Big-endian 64-bit float with an explicit data size.
+	float := make([]byte, 16)
+	binary.BigEndian.PutUint64(float, math.Float64bits(0.25))
+	binary.BigEndian.PutUint64(float[8:], math.Float64bits(-0.75))
+	data, err = ReadCAFFromReader(bytes.NewReader(cafFile(cafFlagFloat, 64, 1, 4+16, float)), 1)
+	if err != nil {
+		t.Fatalf("ReadCAFFromReader() float64 error = %v", err)
+	}
+	if data.NumSamples != 2 || data.Samples[0][0] != 0.25 || data.Samples[0][1] != -0.75 {
+		t.Fatalf("float64 samples = %v", data.Samples)
+	}
+}
+
+func TestReadCAF_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name, want string
+		file       []byte
+		channels   int
+	}{
+		{"channel mismatch", "input must have 4 channels", cafFile(0, 16, 2, -1, nil), 4},
+		{"bit depth", "unsupported PCM bit depth 12", cafFile(0, 12, 2, -1, nil), 2},
+		{"truncated data", "read sample data", cafFile(0, 16, 1, 4+8, []byte{0, 1}), 1},
+		{"not CAF", "not a CAF file", []byte("RIFF\x00\x00\x00\x00WAVE"), 2},
+	}
+	for _, tt := range tests {
+		_, err := ReadCAFFromReader(bytes.NewReader(tt.file), tt.channels)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package wav
+
+import "math"
+
+// Sample conversions used by the WAV reader and writer. Integer formats are
+// decoded by dividing by 2^(bits-1), so the most negative code maps to
+// exactly -1.0. Encoding scales by 2^(bits-1)-1 and rounds, so ±1.0 map to
+// the symmetric codes. Out-of-range input is clamped and NaN/Inf become
+// silence.
+
+// Float64ToUint8 converts v to 8-bit unsigned PCM, where 128 is silence.
+func Float64ToUint8(v float64) uint8 {
+	v = sanitize(v)
+	if v >= 1.0 {
+		return 255
+	}
+	if v <= -1.0 {
+		return 0
+	}
+	return uint8(math.Round(v*127.0) + 128)
+}
+
+// Uint8ToFloat64 converts an 8-bit unsigned PCM sample to [-1.0, 1.0).
+func Uint8ToFloat64(v uint8) float64 {
+	return float64(int(v)-128) / 128.0
+}
+
+// Float64ToInt16 converts v to 16-bit PCM.
+func Float64ToInt16(v float64) int16 {
+	v = sanitize(v)
+	if v >= 1.0 {
+		return 32767
+	}
+	if v <= -1.0 {
+		return -32768
+	}
+	return int16(math.Round(v * 32767.0))
+}
+
+// Int16ToFloat64 converts a 16-bit PCM sample to [-1.0, 1.0).
+func Int16ToFloat64(v int16) float64 {
+	return float64(v) / 32768.0
+}
+
+// Float64ToInt24 converts v to 24-bit PCM, returned sign-extended in an
+// int32.
+func Float64ToInt24(v float64) int32 {
+	v = sanitize(v)
+	if v >= 1.0 {
+		return 8388607
+	}
+	if v <= -1.0 {
+		return -8388608
+	}
+	return int32(math.Round(v * 8388607.0))
+}
+
+// Int24ToFloat64 converts a sign-extended 24-bit PCM sample to [-1.0, 1.0).
+// Bits above the low 24 are ignored.
+func Int24ToFloat64(v int32) float64 {
+	v = v << 8 >> 8
+	return float64(v) / 8388608.0
+}
+
+// Float64ToInt32 converts v to 32-bit PCM.
+func Float64ToInt32(v float64) int32 {
+	v = sanitize(v)
+	if v >= 1.0 {
+		return math.MaxInt32
+	}
+	if v <= -1.0 {
+		return math.MinInt32
+	}
+	return int32(math.Round(v * math.MaxInt32))
+}
+
+// Int32ToFloat64 converts a 32-bit PCM sample to [-1.0, 1.0).
+func Int32ToFloat64(v int32) float64 {
+	return float64(v) / 2147483648.0
+}
+
+// Float64ToFloat32 converts v to a 32-bit float clamped to [-1.0, 1.0].
+// The clamp comes first, so +Inf and -Inf are written as 1 and -1; NaN is
+// written as 0.
+func Float64ToFloat32(v float64) float32 {
+	if v > 1.0 {
+		return 1.0
+	}
+	if v < -1.0 {
+		return -1.0
+	}
+	return float32(sanitize(v))
+}
+
+// Float32ToFloat64 converts a 32-bit float sample, clamping to [-1.0, 1.0]
+// so that out-of-range or invalid values in a file cannot propagate.
+func Float32ToFloat64(v float32) float64 {
+	return clampUnit(float64(v))
+}
+
+// clampUnit clamps v to [-1.0, 1.0] and maps NaN/Inf to 0.
+func clampUnit(v float64) float64 {
+	v = sanitize(v)
+	if v > 1.0 {
+		return 1.0
+	}
+	if v < -1.0 {
+		return -1.0
+	}
+	return v
+}
+
+func sanitize(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
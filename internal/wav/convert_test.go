@@ -0,0 +1,216 @@
+package wav
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat64ToInt16(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   float64
+		want int16
+	}{
+		{0, 0},
+		{0.5, 16384},
+		{-0.5, -16384},
+		{1.0, 32767},
+		{-1.0, -32768},
+		{1.5, 32767},
+		{-1.5, -32768},
+		{math.NaN(), 0},
+		{math.Inf(1), 0},
+		{math.Inf(-1), 0},
+	}
+	for _, tt := range tests {
+		if got := Float64ToInt16(tt.in); got != tt.want {
+			t.Fatalf("Float64ToInt16(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInt16ToFloat64(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   int16
+		want float64
+	}{
+		{0, 0},
+		{16384, 0.5},
+		{-16384, -0.5},
+		{-32768, -1.0},
+		{32767, 32767.0 / 32768.0},
+	}
+	for _, tt := range tests {
+		if got := Int16ToFloat64(tt.in); got != tt.want {
+			t.Fatalf("Int16ToFloat64(%d) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFloat64ToInt24(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   float64
+		want int32
+	}{
+		{0, 0},
+		{0.5, 4194304},
+		{-0.5, -4194304},
+		{1.0, 8388607},
+		{-1.0, -8388608},
+		{2.0, 8388607},
+		{-2.0, -8388608},
+		{math.NaN(), 0},
+	}
+	for _, tt := range tests {
+		if got := Float64ToInt24(tt.in); got != tt.want {
+			t.Fatalf("Float64ToInt24(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInt24ToFloat64(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   int32
+		want float64
+	}{
+		{0, 0},
+		{4194304, 0.5},
+		{-8388608, -1.0},
+		{8388607, 8388607.0 / 8388608.0},
+		// Only the low 24 bits are significant.
+		{0x00800000, -1.0},
+	}
+	for _, tt := range tests {
+		if got := Int24ToFloat64(tt.in); got != tt.want {
+			t.Fatalf("Int24ToFloat64(%d) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFloat64ToInt32(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   float64
+		want int32
+	}{
+		{0, 0},
+		{0.5, 1073741824},
+		{-0.5, -1073741824},
+		{1.0, math.MaxInt32},
+		{-1.0, math.MinInt32},
+		{3.0, math.MaxInt32},
+		{-3.0, math.MinInt32},
+		{math.Inf(1), 0},
+	}
+	for _, tt := range tests {
+		if got := Float64ToInt32(tt.in); got != tt.want {
+			t.Fatalf("Float64ToInt32(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	if got := Int32ToFloat64(math.MinInt32); got != -1.0 {
+		t.Fatalf("Int32ToFloat64(MinInt32) = %v, want -1", got)
+	}
+	if got := Int32ToFloat64(1073741824); got != 0.5 {
+		t.Fatalf("Int32ToFloat64(2^30) = %v, want 0.5", got)
+	}
+}
+
+func TestFloat32Conversions(t *testing.T) {
+	t.Parallel()
+
+	// Writing clamps infinities to full scale; reading maps them to 0 like
+	// NaN.
+	tests := []struct {
+		in    float64
+		write float32
+		read  float64
+	}{
+		{0.25, 0.25, 0.25},
+		{-0.75, -0.75, -0.75},
+		{1.0, 1.0, 1.0},
+		{1.25, 1.0, 1.0},
+		{-1.25, -1.0, -1.0},
+		{math.NaN(), 0, 0},
+		{math.Inf(1), 1.0, 0},
+		{math.Inf(-1), -1.0, 0},
+	}
+	for _, tt := range tests {
+		if got := Float64ToFloat32(tt.in); got != tt.write {
+			t.Fatalf("Float64ToFloat32(%v) = %v, want %v", tt.in, got, tt.write)
+		}
+		if got := Float32ToFloat64(float32(tt.in)); got != tt.read {
+			t.Fatalf("Float32ToFloat64(%v) = %v, want %v", float32(tt.in), got, tt.read)
+		}
+	}
+}
+
+func TestUint8Conversions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   float64
+		want uint8
+	}{
+		{0, 128},
+		{0.5, 192},
+		{-0.5, 64},
+		{1.0, 255},
+		{-1.0, 0},
+		{1.1, 255},
+		{-1.1, 0},
+		{math.NaN(), 128},
+	}
+	for _, tt := range tests {
+		if got := Float64ToUint8(tt.in); got != tt.want {
+			t.Fatalf("Float64ToUint8(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	if got := Uint8ToFloat64(0); got != -1.0 {
+		t.Fatalf("Uint8ToFloat64(0) = %v, want -1", got)
+	}
+	if got := Uint8ToFloat64(192); got != 0.5 {
+		t.Fatalf("Uint8ToFloat64(192) = %v, want 0.5", got)
+	}
+}
+
+// TestInt16RoundTrip checks that every 16-bit code survives decode/encode
+// except the asymmetric extremes, which stay within one LSB.
+func TestInt16RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for v := math.MinInt16; v <= math.MaxInt16; v++ {
+		got := Float64ToInt16(Int16ToFloat64(int16(v)))
+		if d := int(got) - v; d < -1 || d > 1 {
+			t.Fatalf("round trip %d -> %d", v, got)
+		}
+	}
+}
+
+func TestApplyGain(t *testing.T) {
+	t.Parallel()
+
+	samples := [][]float64{{0.25, -0.5}, {0.1, 0}}
+	ApplyGain(samples, 20*math.Log10(2))
+	want := [][]float64{{0.5, -1.0}, {0.2, 0}}
+	for ch := range want {
+		for i := range want[ch] {
+			if math.Abs(samples[ch][i]-want[ch][i]) > 1e-12 {
+				t.Fatalf("samples[%d][%d] = %v, want %v", ch, i, samples[ch][i], want[ch][i])
+			}
+		}
+	}
+
+	before := samples[0][1]
+	ApplyGain(samples, 0)
+	if samples[0][1] != before {
+		t.Fatalf("0 dB changed %v to %v", before, samples[0][1])
+	}
+}
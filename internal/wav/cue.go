@@ -0,0 +1,239 @@
+package wav
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Chunk IDs of cue points and their labels.
+const (
+	ChunkCue = "cue "
+	// listAdtl is the list type of the associated data list, a LIST chunk
+	// whose labl subchunks name the cue points.
+	listAdtl  = "adtl"
+	chunkLabl = "labl"
+)
+
+const cuePointSize = 24
+
+// Cue is a labeled cue point, such as a track boundary. Position is in
+// sample frames.
+type Cue struct {
+	Position uint32
+	Label    string
+}
+
+// cuePoint is a point of the cue chunk; id links it to its labl subchunk.
+type cuePoint struct {
+	id       uint32
+	position uint32
+}
+
+// markers are the loop points and cue points of a WAV stream, gathered from
+// the chunks around the data chunk.
+type markers struct {
+	loops  []Loop
+	points []cuePoint
+	labels map[uint32]string
+}
+
+// read parses a chunk of id and chunkSize bytes whose header has just been
+// read, including its pad byte, if it holds markers: an smpl, cue or LIST
+// chunk. It reports whether it consumed the chunk. A LIST chunk other than
+// the associated data list is skipped.
+func (m *markers) read(br *bufio.Reader, id string, chunkSize uint32) (bool, error) {
+	switch id {
+	case ChunkSmpl:
+		loops, err := readSmplChunk(br, chunkSize)
+		if err != nil {
+			return true, err
+		}
+		if m.loops == nil {
+			m.loops = loops
+		}
+	case ChunkCue:
+		points, err := readCueChunk(br, chunkSize)
+		if err != nil {
+			return true, err
+		}
+		if m.points == nil {
+			m.points = points
+		}
+	case ChunkList:
+		if err := m.readList(br, chunkSize); err != nil {
+			return true, err
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// readList parses the labels of an associated data list and skips any
+// other list.
+func (m *markers) readList(br *bufio.Reader, chunkSize uint32) error {
+	skip := int64(chunkSize) + int64(chunkSize%2)
+	if chunkSize >= 4 {
+		var listType [4]byte
+		if _, err := io.ReadFull(br, listType[:]); err != nil {
+			return fmt.Errorf("read LIST type: %w", err)
+		}
+		skip -= 4
+		if string(listType[:]) == listAdtl {
+			data, err := io.ReadAll(io.LimitReader(br, skip))
+			if err != nil {
+				return fmt.Errorf("read adtl list: %w", err)
+			}
+			if int64(len(data)) < int64(chunkSize)-4 {
+				return fmt.Errorf("read adtl list: %w", io.ErrUnexpectedEOF)
+			}
+			if m.labels == nil {
+				m.labels = parseAdtlList(data)
+			}
+			return nil
+		}
+	}
+	if _, err := io.CopyN(io.Discard, br, skip); err != nil {
+		return fmt.Errorf("skip LIST chunk: %w", err)
+	}
+	return nil
+}
+
+// cues returns the cue points in chunk order with their labels.
+func (m *markers) cues() []Cue {
+	if len(m.points) == 0 {
+		return nil
+	}
+	cues := make([]Cue, len(m.points))
+	for i, p := range m.points {
+		cues[i] = Cue{Position: p.position, Label: m.labels[p.id]}
+	}
+	return cues
+}
+
+// readCueChunk parses the points of a cue chunk of chunkSize bytes and
+// skips the rest of the chunk, including its pad byte. The position of a
+// point is its sample offset; the fields locating it in a wave list, which
+// nothing writes any more, are ignored.
+func readCueChunk(br *bufio.Reader, chunkSize uint32) ([]cuePoint, error) {
+	if chunkSize < 4 {
+		return nil, fmt.Errorf("invalid cue chunk size %d", chunkSize)
+	}
+	var count [4]byte
+	if _, err := io.ReadFull(br, count[:]); err != nil {
+		return nil, fmt.Errorf("read cue point count: %w", err)
+	}
+	numPoints := binary.LittleEndian.Uint32(count[:])
+	if uint64(numPoints)*cuePointSize > uint64(chunkSize-4) {
+		return nil, fmt.Errorf("cue chunk of %d bytes cannot hold %d points", chunkSize, numPoints)
+	}
+
+	points := make([]cuePoint, 0, min(numPoints, 64))
+	var buf [cuePointSize]byte
+	for range numPoints {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			return nil, fmt.Errorf("read cue point: %w", err)
+		}
+		points = append(points, cuePoint{
+			id:       binary.LittleEndian.Uint32(buf[0:]),
+			position: binary.LittleEndian.Uint32(buf[20:]),
+		})
+	}
+
+	remaining := int64(chunkSize) - 4 - int64(numPoints)*cuePointSize + int64(chunkSize%2)
+	if remaining > 0 {
+		if _, err := io.CopyN(io.Discard, br, remaining); err != nil {
+			return nil, fmt.Errorf("skip cue chunk: %w", err)
+		}
+	}
+	return points, nil
+}
+
+// parseAdtlList returns the labl texts of an associated data list payload
+// after "adtl", keyed by cue point ID. Notes and other subchunks are
+// skipped, as is a truncated last subchunk.
+func parseAdtlList(data []byte) map[uint32]string {
+	labels := make(map[uint32]string)
+	for len(data) >= 8 {
+		id := string(data[0:4])
+		n := binary.LittleEndian.Uint32(data[4:8])
+		data = data[8:]
+		if uint64(n) > uint64(len(data)) {
+			break
+		}
+		if id == chunkLabl && n >= 4 {
+			labels[binary.LittleEndian.Uint32(data)] = strings.TrimRight(string(data[4:n]), "\x00")
+		}
+		data = data[min(int(n+n%2), len(data)):]
+	}
+	return labels
+}
+
+// cuePayload builds a cue chunk holding cues, with IDs numbered from 1 in
+// order.
+func cuePayload(cues []Cue) []byte {
+	payload := binary.LittleEndian.AppendUint32(nil, uint32(len(cues)))
+	for i, cue := range cues {
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(i+1))
+		payload = binary.LittleEndian.AppendUint32(payload, cue.Position)
+		payload = append(payload, ChunkData...)
+		payload = binary.LittleEndian.AppendUint32(payload, 0) // chunk start
+		payload = binary.LittleEndian.AppendUint32(payload, 0) // block start
+		payload = binary.LittleEndian.AppendUint32(payload, cue.Position)
+	}
+	return payload
+}
+
+// adtlListPayload builds the LIST/adtl payload labeling cues with the IDs
+// of cuePayload, or nil when no cue has a label.
+func adtlListPayload(cues []Cue) []byte {
+	var payload []byte
+	for i, cue := range cues {
+		if cue.Label == "" {
+			continue
+		}
+		if payload == nil {
+			payload = []byte(listAdtl)
+		}
+		label := strings.ReplaceAll(cue.Label, "\x00", "") + "\x00"
+		payload = append(payload, chunkLabl...)
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(4+len(label)))
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(i+1))
+		payload = append(payload, label...)
+		if len(label)%2 == 1 {
+			payload = append(payload, 0)
+		}
+	}
+	return payload
+}
+
+// ScanCues walks every chunk of a WAV stream like ScanLoops and returns the
+// cue points with their labels, or nil if there are none. r is left at an
+// unspecified position.
+func ScanCues(r io.ReadSeeker) ([]Cue, error) {
+	var m markers
+	err := scanChunks(r, func(id string, size uint32, body io.Reader) (bool, error) {
+		if id != ChunkCue && id != ChunkList {
+			return false, nil
+		}
+		// Buffer the chunk alone, with a pad byte for an odd size, so that
+		// m.read cannot run into the next chunk.
+		buf := bytes.NewBuffer(make([]byte, 0, min(size+1, 1<<16)))
+		if _, err := io.CopyN(buf, body, int64(size)); err != nil {
+			return true, fmt.Errorf("read chunk %q: %w", id, err)
+		}
+		if size%2 == 1 {
+			buf.WriteByte(0)
+		}
+		_, err := m.read(bufio.NewReader(buf), id, size)
+		return err != nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.cues(), nil
+}
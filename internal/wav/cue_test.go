@@ -0,0 +1,43 @@
+package wav
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestCueRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	in := loopAudio()
+	in.Cues = []Cue{{Position: 0, Label: "Side A"}, {Position: 3, Label: "Track 2"}}
+	for _, layout := range []ChunkLayout{LayoutMinimal, LayoutStandard, LayoutTrailing} {
+		var buf bytes.Buffer
+		if err := WriteWAVWithOptionsToWriter(&buf, in, 2, WriteOptions{Layout: layout}); err != nil {
+			t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+		}
+
+		out, err := ReadWAVBytes(buf.Bytes(), 2)
+		if err != nil {
+			t.Fatalf("ReadWAVBytes() error = %v", err)
+		}
+		if !slices.Equal(out.Cues, in.Cues) {
+			t.Fatalf("Cues = %+v, want %+v", out.Cues, in.Cues)
+		}
+		if !slices.Equal(out.Loops, in.Loops) {
+			t.Fatalf("Loops = %+v, want %+v", out.Loops, in.Loops)
+		}
+
+		scanned, err := ScanCues(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ScanCues() error = %v", err)
+		}
+		if !slices.Equal(scanned, in.Cues) {
+			t.Fatalf("ScanCues() = %+v, want %+v", scanned, in.Cues)
+		}
+		// The labels' LIST chunk is not mistaken for an INFO list.
+		if tags, err := ScanInfo(bytes.NewReader(buf.Bytes())); err != nil || (tags != nil) != slices.Contains(layout, ChunkList) {
+			t.Fatalf("ScanInfo() = %v, %v with layout %v", tags, err, layout)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// headerOnlyFixtures are malformed files whose header is valid; only
+// reading the samples fails.
+var headerOnlyFixtures = map[string]bool{
+	"huge_data_size.wav": true,
+	"truncated_data.wav": true,
+}
+
+func malformedFixtures(tb testing.TB) map[string][]byte {
+	tb.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "malformed", "*.wav"))
+	if err != nil {
+		tb.Fatalf("Glob() error = %v", err)
+	}
+	if len(paths) == 0 {
+		tb.Fatalf("no fixtures in testdata/malformed")
+	}
+	fixtures := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatalf("ReadFile(%s) error = %v", path, err)
+		}
+		fixtures[filepath.Base(path)] = data
+	}
+	return fixtures
+}
+
+// validSeeds returns well-formed files in every supported sample format.
+func validSeeds(tb testing.TB) [][]byte {
+	tb.Helper()
+
+	in := &AudioData{
+		SampleRate: 44100,
+		Samples: [][]float64{
+			{0.0, 0.5, -0.5, 1.0, -1.0},
+			{0.25, -0.25, 0.75, -0.75, 0.0},
+		},
+		NumSamples: 5,
+	}
+	var seeds [][]byte
+	for _, format := range []SampleFormat{FormatPCM16, FormatFloat32, FormatPCM8} {
+		for _, layout := range []ChunkLayout{LayoutMinimal, LayoutStandard, LayoutTrailing} {
+			var buf bytes.Buffer
+			if err := WriteWAVWithOptionsToWriter(&buf, in, 2, WriteOptions{Format: format, Layout: layout}); err != nil {
+				tb.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+			}
+			seeds = append(seeds, buf.Bytes())
+		}
+	}
+	// A file written to a pipe, with the RIFF and data sizes left at
+	// 0xFFFFFFFF.
+	streaming := bytes.Clone(seeds[0])
+	binary.LittleEndian.PutUint32(streaming[4:], 0xffffffff)
+	binary.LittleEndian.PutUint32(streaming[bytes.Index(streaming, []byte("data"))+4:], 0xffffffff)
+	return append(seeds, streaming)
+}
+
+func TestReadWAVBytes_MalformedFixtures(t *testing.T) {
+	t.Parallel()
+
+	for name, data := range malformedFixtures(t) {
+		if _, err := ReadWAVBytes(data, 2); err == nil {
+			t.Errorf("%s: ReadWAVBytes() error = nil, want error", name)
+		}
+		_, err := ReadInfo(bytes.NewReader(data))
+		if headerOnlyFixtures[name] {
+			if err != nil {
+				t.Errorf("%s: ReadInfo() error = %v, want nil", name, err)
+			}
+		} else if err == nil {
+			t.Errorf("%s: ReadInfo() error = nil, want error", name)
+		}
+	}
+}
+
+func TestReadInfo(t *testing.T) {
+	t.Parallel()
+
+	seeds := validSeeds(t)
+	info, err := ReadInfo(bytes.NewReader(seeds[3]))
+	if err != nil {
+		t.Fatalf("ReadInfo() error = %v", err)
+	}
+	want := Info{SampleRate: 44100, Channels: 2, BitsPerSample: 32, Float: true, NumFrames: 5}
+	if info != want {
+		t.Fatalf("ReadInfo() = %+v, want %+v", info, want)
+	}
+
+	info, err = ReadInfo(bytes.NewReader(seeds[len(seeds)-1]))
+	if err != nil || info.NumFrames != -1 {
+		t.Fatalf("ReadInfo(streaming) = %+v, %v, want NumFrames -1", info, err)
+	}
+}
+
+func FuzzReadWAVBytes(f *testing.F) {
+	for _, seed := range validSeeds(f) {
+		f.Add(seed, uint8(2))
+	}
+	for _, data := range malformedFixtures(f) {
+		f.Add(data, uint8(2))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, channels uint8) {
+		audio, err := ReadWAVBytes(data, int(channels))
+		if err != nil {
+			return
+		}
+		want := int(channels)
+		if want == AnyChannels {
+			info, err := ReadInfo(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("ReadInfo() error = %v after a successful read", err)
+			}
+			want = info.Channels
+		}
+		if len(audio.Samples) != want {
+			t.Fatalf("len(Samples) = %d, want %d", len(audio.Samples), want)
+		}
+		// Every frame takes at least one byte per channel, so the decoded
+		// size is bounded by the input size.
+		if audio.NumSamples*want > len(data) {
+			t.Fatalf("NumSamples = %d exceeds input of %d bytes", audio.NumSamples, len(data))
+		}
+		for ch, samples := range audio.Samples {
+			if len(samples) != audio.NumSamples {
+				t.Fatalf("len(Samples[%d]) = %d, want %d", ch, len(samples), audio.NumSamples)
+			}
+			for i, v := range samples {
+				if math.IsNaN(v) || v < -1.0 || v > 1.0 {
+					t.Fatalf("Samples[%d][%d] = %v, want finite in [-1, 1]", ch, i, v)
+				}
+			}
+		}
+	})
+}
+
+func FuzzReadInfo(f *testing.F) {
+	for _, seed := range validSeeds(f) {
+		f.Add(seed)
+	}
+	for _, data := range malformedFixtures(f) {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := ReadInfo(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		// NumFrames is -1 for a data chunk of unknown size.
+		if info.Channels <= 0 || info.SampleRate == 0 || info.NumFrames < -1 {
+			t.Fatalf("ReadInfo() = %+v, want positive channels and rate and a known or unknown (-1) length", info)
+		}
+	})
+}
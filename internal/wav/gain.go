@@ -0,0 +1,23 @@
+package wav
+
+import "math"
+
+// DBToGain converts a level change in dB to a linear amplitude factor.
+func DBToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// ApplyGain scales samples ([channel][frame]) by db decibels in place. A
+// gain of 0 dB leaves the samples untouched. Samples are not clamped; the
+// writer clips what ends up outside [-1, 1].
+func ApplyGain(samples [][]float64, db float64) {
+	if db == 0 {
+		return
+	}
+	gain := DBToGain(db)
+	for _, ch := range samples {
+		for i := range ch {
+			ch[i] *= gain
+		}
+	}
+}
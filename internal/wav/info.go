@@ -0,0 +1,109 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Common IDs of LIST/INFO text fields.
+const (
+	InfoTitle    = "INAM"
+	InfoArtist   = "IART"
+	InfoAlbum    = "IPRD"
+	InfoTrack    = "ITRK"
+	InfoComment  = "ICMT"
+	InfoSoftware = "ISFT"
+)
+
+// InfoTags are the text fields of a LIST/INFO chunk keyed by their
+// four-character ID.
+type InfoTags map[string]string
+
+// infoListPayload builds a LIST/INFO payload with the software name
+// followed by tags in ID order. A software tag in tags replaces the default.
+func infoListPayload(tags InfoTags) []byte {
+	software := "go-sq-tool"
+	if v, ok := tags[InfoSoftware]; ok {
+		software = v
+	}
+	ids := make([]string, 0, len(tags))
+	for id := range tags {
+		if id != InfoSoftware && len(id) == 4 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	payload := []byte("INFO")
+	payload = appendInfoField(payload, InfoSoftware, software)
+	for _, id := range ids {
+		payload = appendInfoField(payload, id, tags[id])
+	}
+	return payload
+}
+
+// appendInfoField appends a NUL-terminated, word-aligned text field.
+func appendInfoField(payload []byte, id, value string) []byte {
+	value = strings.ReplaceAll(value, "\x00", "") + "\x00"
+	payload = append(payload, id...)
+	payload = binary.LittleEndian.AppendUint32(payload, uint32(len(value)))
+	payload = append(payload, value...)
+	if len(value)%2 == 1 {
+		payload = append(payload, 0)
+	}
+	return payload
+}
+
+// ScanInfo walks every chunk of a WAV stream like ScanLoops and returns the
+// text fields of the first LIST/INFO chunk, or nil if there is none. r is
+// left at an unspecified position.
+func ScanInfo(r io.ReadSeeker) (InfoTags, error) {
+	var tags InfoTags
+	err := scanChunks(r, func(id string, size uint32, body io.Reader) (bool, error) {
+		if id != ChunkList || size < 4 {
+			return false, nil
+		}
+		var listType [4]byte
+		if _, err := io.ReadFull(body, listType[:]); err != nil {
+			// A list cut off before its type ends the scan.
+			return true, nil
+		}
+		if string(listType[:]) != "INFO" {
+			return false, nil
+		}
+		// Read what the stream holds rather than allocate the declared
+		// size, which a damaged header may put at up to 4 GiB.
+		data, err := io.ReadAll(io.LimitReader(body, int64(size)-4))
+		if err == nil && int64(len(data)) < int64(size)-4 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return true, fmt.Errorf("read INFO list: %w", err)
+		}
+		tags, err = parseInfoList(data)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// parseInfoList parses the fields of a LIST/INFO payload after "INFO".
+func parseInfoList(data []byte) (InfoTags, error) {
+	tags := make(InfoTags)
+	for len(data) >= 8 {
+		id := string(data[0:4])
+		n := binary.LittleEndian.Uint32(data[4:8])
+		data = data[8:]
+		if uint64(n) > uint64(len(data)) {
+			return nil, fmt.Errorf("INFO field %q of %d bytes exceeds the list", id, n)
+		}
+		tags[id] = strings.TrimRight(string(data[:n]), "\x00")
+		data = data[min(int(n+n%2), len(data)):]
+	}
+	return tags, nil
+}
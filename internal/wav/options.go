@@ -0,0 +1,142 @@
+package wav
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SampleFormat selects how samples are encoded in the data chunk.
+type SampleFormat int
+
+const (
+	// FormatPCM16 writes 16-bit signed PCM.
+	FormatPCM16 SampleFormat = iota
+	// FormatFloat32 writes 32-bit IEEE float.
+	FormatFloat32
+	// FormatPCM8 writes 8-bit unsigned PCM (biased by 128).
+	FormatPCM8
+	// FormatPCM24 writes 24-bit signed PCM, packed in three bytes.
+	FormatPCM24
+)
+
+// ParseBitDepth converts a CLI bit depth ("8", "16", "24" or "32f") into a
+// SampleFormat.
+func ParseBitDepth(name string) (SampleFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "8":
+		return FormatPCM8, nil
+	case "16":
+		return FormatPCM16, nil
+	case "24":
+		return FormatPCM24, nil
+	case "32f":
+		return FormatFloat32, nil
+	default:
+		return FormatPCM16, fmt.Errorf("unsupported bit depth %q (want 8, 16, 24 or 32f)", name)
+	}
+}
+
+// String describes the format for user-facing output.
+func (f SampleFormat) String() string {
+	switch f {
+	case FormatPCM16:
+		return "16-bit PCM"
+	case FormatFloat32:
+		return "32-bit IEEE float"
+	case FormatPCM8:
+		return "8-bit unsigned PCM"
+	case FormatPCM24:
+		return "24-bit PCM"
+	default:
+		return fmt.Sprintf("SampleFormat(%d)", int(f))
+	}
+}
+
+// WriteOptions controls how audio data is written.
+type WriteOptions struct {
+	Format SampleFormat
+	// Layout selects chunk order and optional chunks; nil means LayoutMinimal.
+	Layout ChunkLayout
+	// Loops are written as an smpl chunk after every other chunk; none is
+	// written when empty.
+	Loops []Loop
+	// Cues are written as a cue chunk, followed by a LIST/adtl chunk with
+	// their labels, after the chunks of the layout.
+	Cues []Cue
+	// ErrorOnClip makes the writer fail with ErrClipped on the first sample
+	// outside [-1, 1] instead of clamping it.
+	ErrorOnClip bool
+	// Info adds text fields to the LIST/INFO chunk. When the layout has no
+	// LIST chunk, one is appended after the data so that the fields are not
+	// lost.
+	Info InfoTags
+}
+
+// Chunk IDs understood by the writers.
+const (
+	ChunkFmt  = "fmt "
+	ChunkFact = "fact"
+	ChunkList = "LIST"
+	ChunkData = "data"
+)
+
+// ChunkLayout lists the chunks a writer emits, in order. It must contain
+// "fmt " and "data" exactly once with "fmt " first; "fact" and "LIST" (an
+// INFO list naming the software and any WriteOptions.Info fields) are
+// optional.
+type ChunkLayout []string
+
+var (
+	// LayoutMinimal emits only fmt followed directly by data. This is the
+	// default and suits players that reject intervening chunks.
+	LayoutMinimal = ChunkLayout{ChunkFmt, ChunkData}
+	// LayoutStandard emits fmt, fact, LIST and then data.
+	LayoutStandard = ChunkLayout{ChunkFmt, ChunkFact, ChunkList, ChunkData}
+	// LayoutTrailing keeps fmt directly before data and appends fact and
+	// LIST after the audio.
+	LayoutTrailing = ChunkLayout{ChunkFmt, ChunkData, ChunkFact, ChunkList}
+)
+
+var namedLayouts = []struct {
+	name   string
+	layout ChunkLayout
+}{
+	{"minimal", LayoutMinimal},
+	{"standard", LayoutStandard},
+	{"trailing", LayoutTrailing},
+}
+
+// ParseChunkLayout returns the named layout (minimal, standard or trailing).
+func ParseChunkLayout(name string) (ChunkLayout, error) {
+	names := make([]string, 0, len(namedLayouts))
+	for _, named := range namedLayouts {
+		if strings.EqualFold(named.name, name) {
+			return named.layout, nil
+		}
+		names = append(names, named.name)
+	}
+	return nil, fmt.Errorf("unknown chunk layout %q (use %s)", name, strings.Join(names, ", "))
+}
+
+// Validate checks that the layout can be written.
+func (l ChunkLayout) Validate() error {
+	seen := make(map[string]bool, len(l))
+	for _, id := range l {
+		switch id {
+		case ChunkFmt, ChunkFact, ChunkList, ChunkData:
+		default:
+			return fmt.Errorf("unsupported chunk %q in layout", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicate chunk %q in layout", id)
+		}
+		if id == ChunkData && !seen[ChunkFmt] {
+			return fmt.Errorf("chunk layout must place %q before %q", ChunkFmt, ChunkData)
+		}
+		seen[id] = true
+	}
+	if !seen[ChunkFmt] || !seen[ChunkData] {
+		return fmt.Errorf("chunk layout must contain %q and %q", ChunkFmt, ChunkData)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package wav
+
+import "fmt"
+
+// RemapChannels reorders the channels of data in place so that output
+// channel i is input channel order[i]. Only the channel slices move; the
+// samples are neither copied nor re-interleaved. order must be a
+// permutation of the channel indices.
+func RemapChannels(data *AudioData, order []int) error {
+	if len(order) != len(data.Samples) {
+		return fmt.Errorf("channel order has %d entries, audio has %d channels", len(order), len(data.Samples))
+	}
+	seen := make([]bool, len(order))
+	for _, ch := range order {
+		if ch < 0 || ch >= len(order) {
+			return fmt.Errorf("channel %d out of range [0, %d)", ch, len(order))
+		}
+		if seen[ch] {
+			return fmt.Errorf("channel %d appears twice in order %v", ch, order)
+		}
+		seen[ch] = true
+	}
+
+	samples := make([][]float64, len(order))
+	for i, ch := range order {
+		samples[i] = data.Samples[ch]
+	}
+	copy(data.Samples, samples)
+	return nil
+}
@@ -0,0 +1,124 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ChunkSmpl is the sampler chunk carrying loop points.
+const ChunkSmpl = "smpl"
+
+// Loop types of the smpl chunk.
+const (
+	LoopForward     uint32 = 0
+	LoopAlternating uint32 = 1
+	LoopBackward    uint32 = 2
+)
+
+const (
+	smplHeaderSize = 36
+	smplLoopSize   = 24
+	// smplUnityNote is MIDI middle C, written when no other value is known.
+	smplUnityNote = 60
+)
+
+// Loop is a loop point of the smpl chunk. Positions are in sample frames.
+type Loop struct {
+	// ID is the cue point ID; 0 when the loop has no cue point.
+	ID uint32
+	// Type is LoopForward, LoopAlternating or LoopBackward.
+	Type uint32
+	// Start and End are the first and last frame of the loop (inclusive).
+	Start uint32
+	End   uint32
+	// Fraction is the fine-tuning of the end point in units of 1/2^32
+	// frame.
+	Fraction uint32
+	// PlayCount is the number of repetitions; 0 loops indefinitely.
+	PlayCount uint32
+}
+
+// readSmplChunk parses the loops of an smpl chunk of chunkSize bytes and
+// skips the rest of the chunk, including its pad byte. Only the loops are
+// kept; the MIDI and SMPTE fields and sampler-specific data are dropped.
+func readSmplChunk(br *bufio.Reader, chunkSize uint32) ([]Loop, error) {
+	if chunkSize < smplHeaderSize {
+		return nil, fmt.Errorf("invalid smpl chunk size %d", chunkSize)
+	}
+	var header [smplHeaderSize]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, fmt.Errorf("read smpl header: %w", err)
+	}
+	numLoops := binary.LittleEndian.Uint32(header[28:])
+	if uint64(numLoops)*smplLoopSize > uint64(chunkSize-smplHeaderSize) {
+		return nil, fmt.Errorf("smpl chunk of %d bytes cannot hold %d loops", chunkSize, numLoops)
+	}
+
+	loops := make([]Loop, 0, min(numLoops, 64))
+	var buf [smplLoopSize]byte
+	for range numLoops {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			return nil, fmt.Errorf("read smpl loop: %w", err)
+		}
+		loops = append(loops, Loop{
+			ID:        binary.LittleEndian.Uint32(buf[0:]),
+			Type:      binary.LittleEndian.Uint32(buf[4:]),
+			Start:     binary.LittleEndian.Uint32(buf[8:]),
+			End:       binary.LittleEndian.Uint32(buf[12:]),
+			Fraction:  binary.LittleEndian.Uint32(buf[16:]),
+			PlayCount: binary.LittleEndian.Uint32(buf[20:]),
+		})
+	}
+
+	remaining := int64(chunkSize) - smplHeaderSize - int64(numLoops)*smplLoopSize + int64(chunkSize%2)
+	if remaining > 0 {
+		if _, err := io.CopyN(io.Discard, br, remaining); err != nil {
+			return nil, fmt.Errorf("skip smpl sampler data: %w", err)
+		}
+	}
+	return loops, nil
+}
+
+// smplPayload builds an smpl chunk holding loops, with the sample period
+// derived from sampleRate and the remaining header fields at their
+// defaults.
+func smplPayload(loops []Loop, sampleRate uint32) []byte {
+	payload := make([]byte, smplHeaderSize+smplLoopSize*len(loops))
+	if sampleRate > 0 {
+		binary.LittleEndian.PutUint32(payload[8:], uint32(1e9/float64(sampleRate)+0.5))
+	}
+	binary.LittleEndian.PutUint32(payload[12:], smplUnityNote)
+	binary.LittleEndian.PutUint32(payload[28:], uint32(len(loops)))
+	for i, loop := range loops {
+		p := payload[smplHeaderSize+i*smplLoopSize:]
+		binary.LittleEndian.PutUint32(p[0:], loop.ID)
+		binary.LittleEndian.PutUint32(p[4:], loop.Type)
+		binary.LittleEndian.PutUint32(p[8:], loop.Start)
+		binary.LittleEndian.PutUint32(p[12:], loop.End)
+		binary.LittleEndian.PutUint32(p[16:], loop.Fraction)
+		binary.LittleEndian.PutUint32(p[20:], loop.PlayCount)
+	}
+	return payload
+}
+
+// ScanLoops walks every chunk of a WAV stream, seeking over their contents,
+// and returns the loops of the first smpl chunk, or nil if there is none.
+// Unlike Reader it also finds an smpl chunk that follows the data chunk
+// before any samples are read. r is left at an unspecified position.
+func ScanLoops(r io.ReadSeeker) ([]Loop, error) {
+	var loops []Loop
+	err := scanChunks(r, func(id string, size uint32, body io.Reader) (bool, error) {
+		if id != ChunkSmpl {
+			return false, nil
+		}
+		var err error
+		loops, err = readSmplChunk(bufio.NewReaderSize(body, smplHeaderSize+smplLoopSize), size)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loops, nil
+}
@@ -0,0 +1,107 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+func loopAudio() *AudioData {
+	return &AudioData{
+		SampleRate: 44100,
+		Samples: [][]float64{
+			{0.0, 0.5, -0.5, 0.25, -0.25},
+			{0.1, -0.1, 0.2, -0.2, 0.3},
+		},
+		NumSamples: 5,
+		Loops:      []Loop{{ID: 1, Type: LoopAlternating, Start: 1, End: 3, PlayCount: 2}},
+	}
+}
+
+func TestSmplLoopRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	in := loopAudio()
+	for _, layout := range []ChunkLayout{LayoutMinimal, LayoutStandard, LayoutTrailing} {
+		var buf bytes.Buffer
+		if err := WriteWAVWithOptionsToWriter(&buf, in, 2, WriteOptions{Layout: layout}); err != nil {
+			t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+		}
+
+		riffSize := binary.LittleEndian.Uint32(buf.Bytes()[4:])
+		if int(riffSize) != buf.Len()-8 {
+			t.Fatalf("RIFF size = %d, want %d", riffSize, buf.Len()-8)
+		}
+
+		out, err := ReadWAVBytes(buf.Bytes(), 2)
+		if err != nil {
+			t.Fatalf("ReadWAVBytes() error = %v", err)
+		}
+		if !slices.Equal(out.Loops, in.Loops) {
+			t.Fatalf("Loops = %+v, want %+v", out.Loops, in.Loops)
+		}
+
+		scanned, err := ScanLoops(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("ScanLoops() error = %v", err)
+		}
+		if !slices.Equal(scanned, in.Loops) {
+			t.Fatalf("ScanLoops() = %+v, want %+v", scanned, in.Loops)
+		}
+	}
+}
+
+// TestSmplBeforeData checks that an smpl chunk ahead of the data chunk,
+// with trailing sampler data, is available right after NewReader.
+func TestSmplBeforeData(t *testing.T) {
+	t.Parallel()
+
+	in := loopAudio()
+	payload := append(smplPayload(in.Loops, in.SampleRate), 1, 2, 3)
+	binary.LittleEndian.PutUint32(payload[32:], 3) // sampler data size
+
+	var buf bytes.Buffer
+	data := *in
+	data.Loops = nil
+	if err := WriteWAVWithOptionsToWriter(&buf, &data, 2, WriteOptions{}); err != nil {
+		t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+	// Insert the smpl chunk between fmt (ending at 36) and data.
+	var chunk bytes.Buffer
+	if err := writeChunk(&chunk, ChunkSmpl, payload); err != nil {
+		t.Fatalf("writeChunk() error = %v", err)
+	}
+	file := slices.Concat(buf.Bytes()[:36], chunk.Bytes(), buf.Bytes()[36:])
+	binary.LittleEndian.PutUint32(file[4:], uint32(len(file)-8))
+
+	r, err := NewReader(bytes.NewReader(file), 2)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if !slices.Equal(r.Loops(), in.Loops) {
+		t.Fatalf("Loops() = %+v, want %+v", r.Loops(), in.Loops)
+	}
+	out, err := ReadWAVBytes(file, 2)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+	if d := out.Samples[1][4] - in.Samples[1][4]; d < -1.0/32768 || d > 1.0/32768 {
+		t.Fatalf("sample after smpl chunk = %v, want %v", out.Samples[1][4], in.Samples[1][4])
+	}
+}
+
+func TestSmplLoopCountExceedsChunk(t *testing.T) {
+	t.Parallel()
+
+	payload := smplPayload([]Loop{{Start: 0, End: 1}}, 44100)
+	binary.LittleEndian.PutUint32(payload[28:], 1000)
+	var chunk bytes.Buffer
+	if err := writeChunk(&chunk, ChunkSmpl, payload); err != nil {
+		t.Fatalf("writeChunk() error = %v", err)
+	}
+	file := slices.Concat([]byte("RIFF\x00\x00\x00\x00WAVE"), chunk.Bytes())
+	if _, err := ScanLoops(bytes.NewReader(file)); err == nil {
+		t.Fatalf("ScanLoops() error = nil, want error")
+	}
+}
@@ -0,0 +1,758 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// Reader decodes the sample frames of a WAV stream incrementally, so large
+// files can be processed without holding the whole signal in memory.
+type Reader struct {
+	br        *bufio.Reader
+	format    wavFormat
+	channels  int
+	numFrames int
+	remaining int
+	padByte   bool
+	markers   markers
+}
+
+// ChannelCountError is returned by the readers when the input has another
+// channel count than requested.
+type ChannelCountError struct {
+	Want, Got int
+}
+
+func (e *ChannelCountError) Error() string {
+	return fmt.Sprintf("input must have %d channels, got %d channels", e.Want, e.Got)
+}
+
+// AnyChannels makes NewReader accept a stream of any channel count.
+const AnyChannels = 0
+
+// NewReader parses the WAV header up to the start of the data chunk and
+// returns a Reader positioned at the first sample frame. With AnyChannels
+// the stream may have any number of channels; Info reports it.
+func NewReader(r io.Reader, channels int) (*Reader, error) {
+	br := bufio.NewReader(r)
+	f, dataSize, marks, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if channels == AnyChannels {
+		channels = int(f.numChannels)
+	}
+	if int(f.numChannels) != channels {
+		return nil, &ChannelCountError{Want: channels, Got: int(f.numChannels)}
+	}
+
+	return &Reader{
+		br:        br,
+		format:    *f,
+		channels:  channels,
+		numFrames: f.frames(dataSize),
+		remaining: f.frames(dataSize),
+		padByte:   dataSize != unknownDataSize && dataSize%2 == 1,
+		markers:   marks,
+	}, nil
+}
+
+// Info describes a WAV stream as declared by its header.
+type Info struct {
+	SampleRate    uint32
+	Channels      int
+	BitsPerSample int
+	// Float is true for IEEE float samples, false for integer PCM.
+	Float     bool
+	NumFrames int
+}
+
+// ReadInfo parses the WAV header up to the start of the data chunk and
+// returns the declared format without reading any samples.
+func ReadInfo(r io.Reader) (Info, error) {
+	f, dataSize, _, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		return Info{}, err
+	}
+	return f.info(f.frames(dataSize)), nil
+}
+
+func (f *wavFormat) info(numFrames int) Info {
+	return Info{
+		SampleRate:    f.sampleRate,
+		Channels:      int(f.numChannels),
+		BitsPerSample: int(f.bitsPerSample),
+		Float:         f.audioFormat == 3,
+		NumFrames:     numFrames,
+	}
+}
+
+const (
+	// rf64SizeMarker is the 32-bit size of an RF64 chunk whose actual size
+	// is in the ds64 chunk. Writers that cannot seek back, such as ffmpeg
+	// writing to a pipe, leave it in the data chunk of plain RIFF files as
+	// well.
+	rf64SizeMarker = 0xffffffff
+	// unknownDataSize is the data size readHeader returns for a data chunk
+	// that runs to the end of the stream.
+	unknownDataSize = math.MaxUint64
+)
+
+// frames returns the number of frames in dataSize bytes, or -1 for
+// unknownDataSize.
+func (f *wavFormat) frames(dataSize uint64) int {
+	if dataSize == unknownDataSize {
+		return -1
+	}
+	return int(dataSize / uint64(f.blockAlign))
+}
+
+// isWAVEHeader reports whether the 12-byte file header is that of a RIFF or
+// RF64 WAVE file.
+func isWAVEHeader(header [12]byte) bool {
+	id := string(header[0:4])
+	return (id == "RIFF" || id == "RF64") && string(header[8:12]) == "WAVE"
+}
+
+// scanChunks walks the chunks of a WAV stream from its start, seeking over
+// their contents, and calls visit with the ID and declared size of every
+// chunk and a reader of its body, the pad byte of an odd size included.
+// visit may read any part of the body and returns true to end the walk. A
+// truncated or missing chunk header ends the walk without error.
+func scanChunks(r io.ReadSeeker, visit func(id string, size uint32, body io.Reader) (bool, error)) error {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("read RIFF header: %w", err)
+	}
+	if !isWAVEHeader(header) {
+		return fmt.Errorf("not a RIFF WAVE file")
+	}
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:])
+		body := &io.LimitedReader{R: r, N: int64(size) + int64(size%2)}
+		if done, err := visit(id, size, body); done || err != nil {
+			return err
+		}
+		if _, err := r.Seek(body.N, io.SeekCurrent); err != nil {
+			return fmt.Errorf("skip chunk %q: %w", id, err)
+		}
+	}
+}
+
+// readHeader reads the RIFF or RF64 header and chunks up to the data chunk
+// and returns the validated format, the data chunk size and the loop and
+// cue points preceding the data. br is left at the first data byte.
+func readHeader(br *bufio.Reader) (*wavFormat, uint64, markers, error) {
+	var riff [4]byte
+	if _, err := io.ReadFull(br, riff[:]); err != nil {
+		return nil, 0, markers{}, fmt.Errorf("read RIFF header: %w", err)
+	}
+	if string(riff[:]) != "RIFF" && string(riff[:]) != "RF64" {
+		return nil, 0, markers{}, fmt.Errorf("not a RIFF file")
+	}
+
+	var _riffSize uint32
+	if err := binary.Read(br, binary.LittleEndian, &_riffSize); err != nil {
+		return nil, 0, markers{}, fmt.Errorf("read RIFF size: %w", err)
+	}
+
+	var wave [4]byte
+	if _, err := io.ReadFull(br, wave[:]); err != nil {
+		return nil, 0, markers{}, fmt.Errorf("read WAVE header: %w", err)
+	}
+	if string(wave[:]) != "WAVE" {
+		return nil, 0, markers{}, fmt.Errorf("not a WAVE file")
+	}
+
+	var fmtChunk *wavFormat
+	var marks markers
+	// ds64DataSize is the data size of the ds64 chunk leading an RF64 file.
+	ds64DataSize := uint64(unknownDataSize)
+	for {
+		var chunkID [4]byte
+		if _, err := io.ReadFull(br, chunkID[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, 0, markers{}, fmt.Errorf("read chunk id: %w", err)
+		}
+		var chunkSize uint32
+		if err := binary.Read(br, binary.LittleEndian, &chunkSize); err != nil {
+			return nil, 0, markers{}, fmt.Errorf("read chunk size: %w", err)
+		}
+
+		switch string(chunkID[:]) {
+		case "fmt ":
+			if fmtChunk != nil {
+				return nil, 0, markers{}, fmt.Errorf("duplicate fmt chunk")
+			}
+			f, err := readFmtChunk(br, chunkSize)
+			if err != nil {
+				return nil, 0, markers{}, err
+			}
+			fmtChunk = f
+
+		case "data":
+			if fmtChunk == nil {
+				return nil, 0, markers{}, fmt.Errorf("data chunk before fmt chunk")
+			}
+			if err := fmtChunk.validate(); err != nil {
+				return nil, 0, markers{}, err
+			}
+			dataSize := uint64(chunkSize)
+			if chunkSize == rf64SizeMarker {
+				dataSize = unknownDataSize
+				if string(riff[:]) == "RF64" {
+					dataSize = ds64DataSize
+				}
+			}
+			if dataSize != unknownDataSize && dataSize%uint64(fmtChunk.blockAlign) != 0 {
+				return nil, 0, markers{}, fmt.Errorf("data chunk not aligned to block size")
+			}
+			return fmtChunk, dataSize, marks, nil
+
+		case "ds64":
+			// The 64-bit RIFF and data sizes, the sample count and a table
+			// of other large chunks, which is skipped.
+			if chunkSize < 24 {
+				return nil, 0, markers{}, fmt.Errorf("invalid ds64 chunk size %d", chunkSize)
+			}
+			var sizes [24]byte
+			if _, err := io.ReadFull(br, sizes[:]); err != nil {
+				return nil, 0, markers{}, fmt.Errorf("read ds64 chunk: %w", err)
+			}
+			ds64DataSize = binary.LittleEndian.Uint64(sizes[8:])
+			if _, err := io.CopyN(io.Discard, br, int64(chunkSize)-24+int64(chunkSize%2)); err != nil {
+				return nil, 0, markers{}, fmt.Errorf("skip ds64 table: %w", err)
+			}
+
+		case ChunkSmpl, ChunkCue, ChunkList:
+			if _, err := marks.read(br, string(chunkID[:]), chunkSize); err != nil {
+				return nil, 0, markers{}, err
+			}
+
+		default:
+			// Skip unknown chunk (plus pad byte if needed).
+			if _, err := io.CopyN(io.Discard, br, int64(chunkSize)); err != nil {
+				return nil, 0, markers{}, fmt.Errorf("skip chunk %q: %w", string(chunkID[:]), err)
+			}
+			if chunkSize%2 == 1 {
+				if _, err := br.ReadByte(); err != nil {
+					return nil, 0, markers{}, fmt.Errorf("read pad byte: %w", err)
+				}
+			}
+		}
+	}
+
+	return nil, 0, markers{}, fmt.Errorf("no data chunk found")
+}
+
+// formatExtensible is the WAVE_FORMAT_EXTENSIBLE format code, whose fmt
+// extension of extensibleSize bytes names the actual sample format.
+const (
+	formatExtensible = 0xfffe
+	extensibleSize   = 24
+)
+
+func readFmtChunk(br *bufio.Reader, chunkSize uint32) (*wavFormat, error) {
+	if chunkSize < 16 {
+		return nil, fmt.Errorf("invalid fmt chunk size %d", chunkSize)
+	}
+	f := &wavFormat{}
+	if err := binary.Read(br, binary.LittleEndian, &f.audioFormat); err != nil {
+		return nil, fmt.Errorf("read audio format: %w", err)
+	}
+	if err := binary.Read(br, binary.LittleEndian, &f.numChannels); err != nil {
+		return nil, fmt.Errorf("read num channels: %w", err)
+	}
+	if err := binary.Read(br, binary.LittleEndian, &f.sampleRate); err != nil {
+		return nil, fmt.Errorf("read sample rate: %w", err)
+	}
+	if err := binary.Read(br, binary.LittleEndian, &f.byteRate); err != nil {
+		return nil, fmt.Errorf("read byte rate: %w", err)
+	}
+	if err := binary.Read(br, binary.LittleEndian, &f.blockAlign); err != nil {
+		return nil, fmt.Errorf("read block align: %w", err)
+	}
+	if err := binary.Read(br, binary.LittleEndian, &f.bitsPerSample); err != nil {
+		return nil, fmt.Errorf("read bits per sample: %w", err)
+	}
+
+	remaining := int64(chunkSize) - 16 + int64(chunkSize%2)
+	if f.audioFormat == formatExtensible && remaining >= extensibleSize {
+		// cbSize, valid bits and channel mask precede the sub-format GUID,
+		// whose first two bytes are the plain format code.
+		var ext [extensibleSize]byte
+		if _, err := io.ReadFull(br, ext[:]); err != nil {
+			return nil, fmt.Errorf("read fmt extension: %w", err)
+		}
+		f.audioFormat = binary.LittleEndian.Uint16(ext[8:])
+		remaining -= extensibleSize
+	}
+
+	// Skip the extension and the pad byte of an odd-sized chunk.
+	if remaining > 0 {
+		if _, err := io.CopyN(io.Discard, br, remaining); err != nil {
+			return nil, fmt.Errorf("skip fmt extension: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// validate checks that the format is one the Reader can decode and that the
+// declared frame layout is consistent with it.
+func (f *wavFormat) validate() error {
+	if f.numChannels == 0 {
+		return fmt.Errorf("invalid channel count 0")
+	}
+	if f.sampleRate == 0 {
+		return fmt.Errorf("invalid sample rate 0")
+	}
+
+	switch f.audioFormat {
+	case 1: // PCM
+		switch f.bitsPerSample {
+		case 8, 16, 24, 32:
+		default:
+			return fmt.Errorf("unsupported PCM bit depth %d (supported: 8, 16, 24 and 32)", f.bitsPerSample)
+		}
+	case 3: // IEEE float
+		if f.bitsPerSample != 32 && f.bitsPerSample != 64 {
+			return fmt.Errorf("unsupported IEEE float bit depth %d", f.bitsPerSample)
+		}
+	default:
+		return fmt.Errorf("unsupported WAV audio format %d", f.audioFormat)
+	}
+
+	// ReadFrames consumes channels*bits/8 bytes per frame; any other
+	// blockAlign would desynchronize it from the declared data size.
+	if want := uint32(f.numChannels) * uint32(f.bitsPerSample/8); uint32(f.blockAlign) != want {
+		return fmt.Errorf("invalid blockAlign=%d for %d channels of %d-bit samples", f.blockAlign, f.numChannels, f.bitsPerSample)
+	}
+	return nil
+}
+
+// SampleRate returns the sample rate declared in the fmt chunk.
+func (r *Reader) SampleRate() uint32 {
+	return r.format.sampleRate
+}
+
+// Info returns the format declared by the header.
+func (r *Reader) Info() Info {
+	return r.format.info(r.numFrames)
+}
+
+// NumFrames returns the total number of sample frames in the data chunk,
+// or -1 when the header leaves the size open and the data runs to the end
+// of the stream.
+func (r *Reader) NumFrames() int {
+	return r.numFrames
+}
+
+// Loops returns the loop points of the smpl chunk. An smpl chunk after the
+// data chunk is only seen once ReadFrames has consumed all frames.
+func (r *Reader) Loops() []Loop {
+	return r.markers.loops
+}
+
+// Cues returns the cue points with their labels. Like Loops it only sees
+// chunks after the data chunk once ReadFrames has consumed all frames.
+func (r *Reader) Cues() []Cue {
+	return r.markers.cues()
+}
+
+// ReadFrames decodes up to len(dst[0]) frames into dst ([channel][frame])
+// and returns the number of frames read. It returns 0, io.EOF once the data
+// chunk is exhausted.
+func (r *Reader) ReadFrames(dst [][]float64) (int, error) {
+	if len(dst) != r.channels {
+		return 0, fmt.Errorf("destination must have %d channels, got %d", r.channels, len(dst))
+	}
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	if r.remaining < 0 {
+		return r.readToEnd(dst)
+	}
+
+	n := min(len(dst[0]), r.remaining)
+	for i := 0; i < n; i++ {
+		for ch := 0; ch < r.channels; ch++ {
+			v, err := r.readSample()
+			if err != nil {
+				return i, err
+			}
+			dst[ch][i] = v
+		}
+	}
+	r.remaining -= n
+
+	// Chunks are word-aligned; if size is odd, a pad byte follows.
+	if r.remaining == 0 && r.padByte {
+		if _, err := r.br.ReadByte(); err != nil {
+			return n, fmt.Errorf("read data pad byte: %w", err)
+		}
+	}
+	if r.remaining == 0 {
+		r.readTrailingMarkers()
+	}
+
+	return n, nil
+}
+
+// readToEnd is ReadFrames for a data chunk of unknown size, which ends with
+// the stream.
+func (r *Reader) readToEnd(dst [][]float64) (int, error) {
+	for i := range dst[0] {
+		if _, err := r.br.Peek(1); err == io.EOF {
+			r.remaining = 0
+			if i == 0 {
+				return 0, io.EOF
+			}
+			return i, nil
+		}
+		for ch := 0; ch < r.channels; ch++ {
+			v, err := r.readSample()
+			if err != nil {
+				return i, err
+			}
+			dst[ch][i] = v
+		}
+	}
+	return len(dst[0]), nil
+}
+
+// readTrailingMarkers reads the smpl, cue and LIST/adtl chunks after the
+// data chunk. Trailing chunks are metadata, so a truncated or malformed
+// tail ends the search instead of failing the read.
+func (r *Reader) readTrailingMarkers() {
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r.br, header[:]); err != nil {
+			return
+		}
+		size := binary.LittleEndian.Uint32(header[4:])
+		handled, err := r.markers.read(r.br, string(header[0:4]), size)
+		if err != nil {
+			return
+		}
+		if handled {
+			continue
+		}
+		if _, err := io.CopyN(io.Discard, r.br, int64(size)+int64(size%2)); err != nil {
+			return
+		}
+	}
+}
+
+func (r *Reader) readSample() (float64, error) {
+	switch r.format.audioFormat {
+	case 1: // PCM
+		switch r.format.bitsPerSample {
+		case 8:
+			// 8-bit WAV is unsigned with 128 as the zero level.
+			b, err := r.br.ReadByte()
+			if err != nil {
+				return 0, fmt.Errorf("read PCM8 sample: %w", err)
+			}
+			return Uint8ToFloat64(b), nil
+		case 24:
+			v, err := readPCM24Sample(r.br)
+			if err != nil {
+				return 0, fmt.Errorf("read PCM24 sample: %w", err)
+			}
+			return Int24ToFloat64(v), nil
+		case 32:
+			var v int32
+			if err := binary.Read(r.br, binary.LittleEndian, &v); err != nil {
+				return 0, fmt.Errorf("read PCM32 sample: %w", err)
+			}
+			return Int32ToFloat64(v), nil
+		}
+
+		var v int16
+		if err := binary.Read(r.br, binary.LittleEndian, &v); err != nil {
+			return 0, fmt.Errorf("read PCM16 sample: %w", err)
+		}
+		return Int16ToFloat64(v), nil
+
+	default: // IEEE float
+		if r.format.bitsPerSample == 64 {
+			var v float64
+			if err := binary.Read(r.br, binary.LittleEndian, &v); err != nil {
+				return 0, fmt.Errorf("read float64 sample: %w", err)
+			}
+			return clampUnit(v), nil
+		}
+		var v float32
+		if err := binary.Read(r.br, binary.LittleEndian, &v); err != nil {
+			return 0, fmt.Errorf("read float32 sample: %w", err)
+		}
+		return Float32ToFloat64(v), nil
+	}
+}
+
+// ErrClipped is returned by writers with WriteOptions.ErrorOnClip set when
+// a sample lies outside [-1, 1].
+var ErrClipped = errors.New("sample clipped")
+
+// Writer encodes sample frames to a WAV stream incrementally. The frame
+// count must be known up front because the chunk sizes precede the data.
+type Writer struct {
+	bw          *bufio.Writer
+	channels    int
+	format      SampleFormat
+	numFrames   int
+	written     int
+	dataSize    uint32
+	trailing    []string
+	payloads    map[string][]byte
+	cue         []byte
+	adtl        []byte
+	smpl        []byte
+	errorOnClip bool
+	clipped     int
+	closed      bool
+}
+
+// NewWriter writes the RIFF header and every chunk that precedes the data
+// chunk in options.Layout. Frames are then appended with WriteFrames, and
+// Close finishes the stream.
+func NewWriter(w io.Writer, sampleRate uint32, channels, numFrames int, options WriteOptions) (*Writer, error) {
+	if channels <= 0 {
+		return nil, fmt.Errorf("channels must be > 0, got %d", channels)
+	}
+	if numFrames < 0 {
+		return nil, fmt.Errorf("NumSamples must be >= 0")
+	}
+
+	layout := options.Layout
+	if layout == nil {
+		layout = LayoutMinimal
+	}
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	if len(options.Info) > 0 && !slices.Contains(layout, ChunkList) {
+		layout = append(slices.Clone(layout), ChunkList)
+	}
+
+	var audioFormat, bitsPerSample uint16
+	switch options.Format {
+	case FormatPCM16:
+		audioFormat = 1 // PCM
+		bitsPerSample = 16
+	case FormatFloat32:
+		audioFormat = 3 // IEEE float
+		bitsPerSample = 32
+	case FormatPCM8:
+		audioFormat = 1 // PCM
+		bitsPerSample = 8
+	case FormatPCM24:
+		audioFormat = 1 // PCM
+		bitsPerSample = 24
+	default:
+		return nil, fmt.Errorf("unsupported sample format %d", options.Format)
+	}
+
+	numChannels := uint16(channels)
+	blockAlign := numChannels * (bitsPerSample / 8)
+	byteRate := sampleRate * uint32(blockAlign)
+	dataSize := uint32(numFrames) * uint32(blockAlign)
+
+	// Build the non-data chunk payloads up front so the RIFF size is known
+	// before anything is written.
+	payloads := make(map[string][]byte, len(layout))
+	riffSize := uint32(4) // "WAVE"
+	for _, id := range layout {
+		var payload []byte
+		switch id {
+		case ChunkFmt:
+			payload = make([]byte, 16)
+			binary.LittleEndian.PutUint16(payload[0:], audioFormat)
+			binary.LittleEndian.PutUint16(payload[2:], numChannels)
+			binary.LittleEndian.PutUint32(payload[4:], sampleRate)
+			binary.LittleEndian.PutUint32(payload[8:], byteRate)
+			binary.LittleEndian.PutUint16(payload[12:], blockAlign)
+			binary.LittleEndian.PutUint16(payload[14:], bitsPerSample)
+		case ChunkFact:
+			payload = make([]byte, 4)
+			binary.LittleEndian.PutUint32(payload, uint32(numFrames))
+		case ChunkList:
+			payload = infoListPayload(options.Info)
+		case ChunkData:
+			riffSize += 8 + dataSize + dataSize%2
+			continue
+		}
+		payloads[id] = payload
+		riffSize += 8 + uint32(len(payload)) + uint32(len(payload)%2)
+	}
+	var cue, adtl []byte
+	if len(options.Cues) > 0 {
+		cue = cuePayload(options.Cues)
+		riffSize += 8 + uint32(len(cue))
+		if adtl = adtlListPayload(options.Cues); adtl != nil {
+			riffSize += 8 + uint32(len(adtl))
+		}
+	}
+	var smpl []byte
+	if len(options.Loops) > 0 {
+		smpl = smplPayload(options.Loops, sampleRate)
+		riffSize += 8 + uint32(len(smpl))
+	}
+
+	bw := bufio.NewWriter(w)
+
+	// RIFF header
+	if err := writeString(bw, "RIFF"); err != nil {
+		return nil, fmt.Errorf("failed to write RIFF header: %w", err)
+	}
+	if err := binary.Write(bw, binary.LittleEndian, riffSize); err != nil {
+		return nil, fmt.Errorf("failed to write file size: %w", err)
+	}
+	if err := writeString(bw, "WAVE"); err != nil {
+		return nil, fmt.Errorf("failed to write WAVE header: %w", err)
+	}
+
+	var trailing []string
+	for i, id := range layout {
+		if id == ChunkData {
+			if err := writeString(bw, "data"); err != nil {
+				return nil, fmt.Errorf("failed to write data chunk ID: %w", err)
+			}
+			if err := binary.Write(bw, binary.LittleEndian, dataSize); err != nil {
+				return nil, fmt.Errorf("failed to write data size: %w", err)
+			}
+			trailing = layout[i+1:]
+			break
+		}
+		if err := writeChunk(bw, id, payloads[id]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Writer{
+		bw:          bw,
+		channels:    channels,
+		format:      options.Format,
+		numFrames:   numFrames,
+		dataSize:    dataSize,
+		trailing:    trailing,
+		payloads:    payloads,
+		cue:         cue,
+		adtl:        adtl,
+		smpl:        smpl,
+		errorOnClip: options.ErrorOnClip,
+	}, nil
+}
+
+// WriteFrames appends the frames in samples ([channel][frame]) to the data
+// chunk. All channels must have the same length.
+func (w *Writer) WriteFrames(samples [][]float64) error {
+	if w.closed {
+		return fmt.Errorf("write to closed WAV writer")
+	}
+	if len(samples) != w.channels {
+		return fmt.Errorf("output must have %d channels, got %d", w.channels, len(samples))
+	}
+	n := len(samples[0])
+	for ch := 1; ch < w.channels; ch++ {
+		if len(samples[ch]) != n {
+			return fmt.Errorf("channel %d has %d samples, want %d", ch, len(samples[ch]), n)
+		}
+	}
+	if w.written+n > w.numFrames {
+		return fmt.Errorf("writing %d frames exceeds declared length %d", w.written+n, w.numFrames)
+	}
+
+	var pcm24 [3]byte
+	for i := 0; i < n; i++ {
+		for ch := 0; ch < w.channels; ch++ {
+			if v := samples[ch][i]; v > 1 || v < -1 {
+				if w.errorOnClip {
+					return fmt.Errorf("%w: channel %d, frame %d is %g", ErrClipped, ch, w.written+i, v)
+				}
+				w.clipped++
+			}
+			var err error
+			switch w.format {
+			case FormatPCM16:
+				err = binary.Write(w.bw, binary.LittleEndian, Float64ToInt16(samples[ch][i]))
+			case FormatFloat32:
+				err = binary.Write(w.bw, binary.LittleEndian, Float64ToFloat32(samples[ch][i]))
+			case FormatPCM8:
+				err = w.bw.WriteByte(Float64ToUint8(samples[ch][i]))
+			case FormatPCM24:
+				v := Float64ToInt24(samples[ch][i])
+				pcm24 = [3]byte{byte(v), byte(v >> 8), byte(v >> 16)}
+				_, err = w.bw.Write(pcm24[:])
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write sample data: %w", err)
+			}
+		}
+	}
+	w.written += n
+	return nil
+}
+
+// Clipped returns the number of samples written so far that lay outside
+// [-1, 1] and were clamped.
+func (w *Writer) Clipped() int {
+	return w.clipped
+}
+
+// Close writes the data pad byte, any chunks that follow the data chunk, the
+// cue and LIST/adtl chunks and the smpl chunk, then flushes. It fails if
+// fewer frames were written than declared. Close does not close the
+// underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.written != w.numFrames {
+		return fmt.Errorf("wrote %d frames, declared %d", w.written, w.numFrames)
+	}
+	if w.dataSize%2 == 1 {
+		if _, err := w.bw.Write([]byte{0}); err != nil {
+			return fmt.Errorf("failed to write data pad byte: %w", err)
+		}
+	}
+	for _, id := range w.trailing {
+		if err := writeChunk(w.bw, id, w.payloads[id]); err != nil {
+			return err
+		}
+	}
+	if w.cue != nil {
+		if err := writeChunk(w.bw, ChunkCue, w.cue); err != nil {
+			return err
+		}
+	}
+	if w.adtl != nil {
+		if err := writeChunk(w.bw, ChunkList, w.adtl); err != nil {
+			return err
+		}
+	}
+	if w.smpl != nil {
+		if err := writeChunk(w.bw, ChunkSmpl, w.smpl); err != nil {
+			return err
+		}
+	}
+
+	if err := w.bw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush WAV data: %w", err)
+	}
+	return nil
+}
@@ -1,12 +1,11 @@
 package wav
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
-	"math"
 	"os"
 )
 
@@ -15,6 +14,59 @@ type AudioData struct {
 	SampleRate uint32
 	Samples    [][]float64 // [channel][sample]
 	NumSamples int
+	// Loops are the loop points of the smpl chunk, if any.
+	Loops []Loop
+	// Cues are the cue points with their labels, if any.
+	Cues []Cue
+	// BitsPerSample and Float describe the sample format the data was
+	// read from; both are zero for decoded lossy sources.
+	BitsPerSample int
+	Float         bool
+}
+
+// NewAudioData returns samples at sampleRate as AudioData, with NumSamples
+// taken from the samples.
+func NewAudioData(sampleRate uint32, samples [][]float64) *AudioData {
+	data := &AudioData{SampleRate: sampleRate, Samples: samples}
+	data.NumSamples = data.Frames()
+	return data
+}
+
+// Frames returns the length of the sample slices, which the writers write
+// whatever NumSamples says; Validate checks that the two agree.
+func (a *AudioData) Frames() int {
+	if len(a.Samples) == 0 {
+		return 0
+	}
+	return len(a.Samples[0])
+}
+
+// Validate checks that a has at least one channel, that all channels have
+// the same length and that NumSamples is that length. A stage that changes
+// the length, such as latency compensation or resampling, and leaves
+// NumSamples behind is caught here before a header is written.
+func (a *AudioData) Validate() error {
+	if len(a.Samples) == 0 {
+		return fmt.Errorf("audio data has no channels")
+	}
+	frames := a.Frames()
+	for ch, samples := range a.Samples {
+		if len(samples) != frames {
+			return fmt.Errorf("channel %d has %d samples, channel 0 has %d", ch, len(samples), frames)
+		}
+	}
+	if a.NumSamples != frames {
+		return fmt.Errorf("NumSamples is %d, but the channels have %d samples", a.NumSamples, frames)
+	}
+	return nil
+}
+
+// validateChannels checks a with Validate for writing as channels channels.
+func (a *AudioData) validateChannels(channels int) error {
+	if len(a.Samples) != channels {
+		return fmt.Errorf("output must have %d channels, got %d", channels, len(a.Samples))
+	}
+	return a.Validate()
 }
 
 // ReadWAV reads a stereo WAV file and returns the audio data
@@ -22,7 +74,8 @@ func ReadWAV(filename string) (*AudioData, error) {
 	return ReadWAVChannels(filename, 2)
 }
 
-// ReadWAVFromReader reads a WAV stream with a specific channel count.
+// ReadWAVFromReader reads a WAV stream with a specific channel count, or
+// any with AnyChannels.
 func ReadWAVFromReader(r io.Reader, channels int) (*AudioData, error) {
 	audioData, err := readWAV(r, channels)
 	if err != nil {
@@ -31,7 +84,8 @@ func ReadWAVFromReader(r io.Reader, channels int) (*AudioData, error) {
 	return audioData, nil
 }
 
-// ReadWAVBytes reads a WAV payload with a specific channel count.
+// ReadWAVBytes reads a WAV payload with a specific channel count, or any
+// with AnyChannels.
 func ReadWAVBytes(data []byte, channels int) (*AudioData, error) {
 	return ReadWAVFromReader(bytes.NewReader(data), channels)
 }
@@ -49,238 +103,94 @@ func ReadWAVChannels(filename string, channels int) (*AudioData, error) {
 
 // WriteWAV writes 4-channel audio data to a WAV file
 func WriteWAV(filename string, data *AudioData) error {
-	return writeWAVPCM16(filename, data, 4)
+	return WriteWAVWithOptions(filename, data, 4, WriteOptions{Format: FormatPCM16})
 }
 
 // WriteStereoWAV writes 2-channel audio data to a WAV file
 func WriteStereoWAV(filename string, data *AudioData) error {
-	return writeWAVPCM16(filename, data, 2)
-}
-
-func writeWAVPCM16(filename string, data *AudioData, channels int) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create WAV file: %w", err)
-	}
-	defer file.Close()
-
-	return writeWAVPCM16ToWriter(file, data, channels)
+	return WriteWAVWithOptions(filename, data, 2, WriteOptions{Format: FormatPCM16})
 }
 
 // WriteWAVToWriter writes 4-channel audio data to a WAV stream in 16-bit PCM.
 func WriteWAVToWriter(w io.Writer, data *AudioData) error {
-	return writeWAVPCM16ToWriter(w, data, 4)
+	return WriteWAVWithOptionsToWriter(w, data, 4, WriteOptions{Format: FormatPCM16})
 }
 
 // WriteStereoWAVToWriter writes 2-channel audio data to a WAV stream in 16-bit PCM.
 func WriteStereoWAVToWriter(w io.Writer, data *AudioData) error {
-	return writeWAVPCM16ToWriter(w, data, 2)
-}
-
-func writeWAVPCM16ToWriter(w io.Writer, data *AudioData, channels int) error {
-	if len(data.Samples) != channels {
-		return fmt.Errorf("output must have %d channels, got %d", channels, len(data.Samples))
-	}
-	if data.NumSamples < 0 {
-		return fmt.Errorf("NumSamples must be >= 0")
-	}
-	for ch := 0; ch < channels; ch++ {
-		if len(data.Samples[ch]) < data.NumSamples {
-			return fmt.Errorf("channel %d has %d samples, want at least %d", ch, len(data.Samples[ch]), data.NumSamples)
-		}
-	}
-
-	bw := bufio.NewWriter(w)
-
-	numChannels := uint16(channels)
-	bitsPerSample := uint16(16)
-	blockAlign := numChannels * (bitsPerSample / 8)
-	byteRate := data.SampleRate * uint32(blockAlign)
-	audioFormat := uint16(1) // PCM
-	dataSize := uint32(data.NumSamples) * uint32(blockAlign)
-
-	// RIFF header
-	if err := writeString(bw, "RIFF"); err != nil {
-		return fmt.Errorf("failed to write RIFF header: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, uint32(36+dataSize)); err != nil {
-		return fmt.Errorf("failed to write file size: %w", err)
-	}
-	if err := writeString(bw, "WAVE"); err != nil {
-		return fmt.Errorf("failed to write WAVE header: %w", err)
-	}
-
-	// fmt chunk
-	if err := writeString(bw, "fmt "); err != nil {
-		return fmt.Errorf("failed to write fmt chunk ID: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, uint32(16)); err != nil {
-		return fmt.Errorf("failed to write fmt chunk size: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, audioFormat); err != nil {
-		return fmt.Errorf("failed to write audio format: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, numChannels); err != nil {
-		return fmt.Errorf("failed to write num channels: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, data.SampleRate); err != nil {
-		return fmt.Errorf("failed to write sample rate: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, byteRate); err != nil {
-		return fmt.Errorf("failed to write byte rate: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, blockAlign); err != nil {
-		return fmt.Errorf("failed to write block align: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, bitsPerSample); err != nil {
-		return fmt.Errorf("failed to write bits per sample: %w", err)
-	}
-
-	// data chunk
-	if err := writeString(bw, "data"); err != nil {
-		return fmt.Errorf("failed to write data chunk ID: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, dataSize); err != nil {
-		return fmt.Errorf("failed to write data size: %w", err)
-	}
-
-	// Interleaved PCM16 samples
-	for i := 0; i < data.NumSamples; i++ {
-		for ch := 0; ch < channels; ch++ {
-			sample := floatToPCM16(data.Samples[ch][i])
-			if err := binary.Write(bw, binary.LittleEndian, sample); err != nil {
-				return fmt.Errorf("failed to write sample data: %w", err)
-			}
-		}
-	}
-	if err := bw.Flush(); err != nil {
-		return fmt.Errorf("failed to flush WAV data: %w", err)
-	}
-
-	return nil
+	return WriteWAVWithOptionsToWriter(w, data, 2, WriteOptions{Format: FormatPCM16})
 }
 
 // WriteFloat32WAV writes 4-channel audio data to a WAV file in 32-bit IEEE float format
 func WriteFloat32WAV(filename string, data *AudioData) error {
-	return writeWAVFloat32(filename, data, 4)
+	return WriteWAVWithOptions(filename, data, 4, WriteOptions{Format: FormatFloat32})
 }
 
 // WriteStereoFloat32WAV writes 2-channel audio data to a WAV file in 32-bit IEEE float format
 func WriteStereoFloat32WAV(filename string, data *AudioData) error {
-	return writeWAVFloat32(filename, data, 2)
-}
-
-func writeWAVFloat32(filename string, data *AudioData, channels int) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create WAV file: %w", err)
-	}
-	defer file.Close()
-
-	return writeWAVFloat32ToWriter(file, data, channels)
+	return WriteWAVWithOptions(filename, data, 2, WriteOptions{Format: FormatFloat32})
 }
 
 // WriteFloat32WAVToWriter writes 4-channel audio data to a WAV stream in 32-bit IEEE float format.
 func WriteFloat32WAVToWriter(w io.Writer, data *AudioData) error {
-	return writeWAVFloat32ToWriter(w, data, 4)
+	return WriteWAVWithOptionsToWriter(w, data, 4, WriteOptions{Format: FormatFloat32})
 }
 
 // WriteStereoFloat32WAVToWriter writes 2-channel audio data to a WAV stream in 32-bit IEEE float format.
 func WriteStereoFloat32WAVToWriter(w io.Writer, data *AudioData) error {
-	return writeWAVFloat32ToWriter(w, data, 2)
+	return WriteWAVWithOptionsToWriter(w, data, 2, WriteOptions{Format: FormatFloat32})
 }
 
-func writeWAVFloat32ToWriter(w io.Writer, data *AudioData, channels int) error {
-	if len(data.Samples) != channels {
-		return fmt.Errorf("output must have %d channels, got %d", channels, len(data.Samples))
-	}
-	if data.NumSamples < 0 {
-		return fmt.Errorf("NumSamples must be >= 0")
-	}
-	for ch := 0; ch < channels; ch++ {
-		if len(data.Samples[ch]) < data.NumSamples {
-			return fmt.Errorf("channel %d has %d samples, want at least %d", ch, len(data.Samples[ch]), data.NumSamples)
-		}
+// WriteWAVWithOptions writes audio data with the given channel count, sample
+// format and chunk layout to a WAV file.
+func WriteWAVWithOptions(filename string, data *AudioData, channels int, options WriteOptions) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create WAV file: %w", err)
 	}
+	defer file.Close()
 
-	bw := bufio.NewWriter(w)
-
-	numChannels := uint16(channels)
-	bitsPerSample := uint16(32)
-	byteRate := data.SampleRate * uint32(numChannels) * uint32(bitsPerSample/8)
-	blockAlign := numChannels * (bitsPerSample / 8)
-	audioFormat := uint16(3) // IEEE float
-	dataSize := uint32(data.NumSamples) * uint32(numChannels) * uint32(bitsPerSample/8)
+	return WriteWAVWithOptionsToWriter(file, data, channels, options)
+}
 
-	// Write RIFF header
-	if err := writeString(bw, "RIFF"); err != nil {
-		return fmt.Errorf("failed to write RIFF header: %w", err)
-	}
-	// File size - 8 (will be updated at the end if needed)
-	if err := binary.Write(bw, binary.LittleEndian, uint32(36+dataSize)); err != nil {
-		return fmt.Errorf("failed to write file size: %w", err)
-	}
-	if err := writeString(bw, "WAVE"); err != nil {
-		return fmt.Errorf("failed to write WAVE header: %w", err)
+// WriteWAVWithOptionsToWriter writes audio data with the given channel count,
+// sample format and chunk layout to a WAV stream.
+func WriteWAVWithOptionsToWriter(w io.Writer, data *AudioData, channels int, options WriteOptions) error {
+	if err := data.validateChannels(channels); err != nil {
+		return err
 	}
 
-	// Write fmt chunk
-	if err := writeString(bw, "fmt "); err != nil {
-		return fmt.Errorf("failed to write fmt chunk ID: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, uint32(16)); err != nil { // fmt chunk size
-		return fmt.Errorf("failed to write fmt chunk size: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, audioFormat); err != nil {
-		return fmt.Errorf("failed to write audio format: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, numChannels); err != nil {
-		return fmt.Errorf("failed to write num channels: %w", err)
+	if options.Loops == nil {
+		options.Loops = data.Loops
 	}
-	if err := binary.Write(bw, binary.LittleEndian, data.SampleRate); err != nil {
-		return fmt.Errorf("failed to write sample rate: %w", err)
+	if options.Cues == nil {
+		options.Cues = data.Cues
 	}
-	if err := binary.Write(bw, binary.LittleEndian, byteRate); err != nil {
-		return fmt.Errorf("failed to write byte rate: %w", err)
-	}
-	if err := binary.Write(bw, binary.LittleEndian, blockAlign); err != nil {
-		return fmt.Errorf("failed to write block align: %w", err)
+	writer, err := NewWriter(w, data.SampleRate, channels, data.Frames(), options)
+	if err != nil {
+		return err
 	}
-	if err := binary.Write(bw, binary.LittleEndian, bitsPerSample); err != nil {
-		return fmt.Errorf("failed to write bits per sample: %w", err)
+	if err := writer.WriteFrames(data.Samples); err != nil {
+		return err
 	}
+	return writer.Close()
+}
 
-	// Write data chunk
-	if err := writeString(bw, "data"); err != nil {
-		return fmt.Errorf("failed to write data chunk ID: %w", err)
+func writeChunk(w io.Writer, id string, payload []byte) error {
+	if err := writeString(w, id); err != nil {
+		return fmt.Errorf("failed to write %q chunk ID: %w", id, err)
 	}
-	if err := binary.Write(bw, binary.LittleEndian, dataSize); err != nil {
-		return fmt.Errorf("failed to write data size: %w", err)
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(payload))); err != nil {
+		return fmt.Errorf("failed to write %q chunk size: %w", id, err)
 	}
-
-	// Write interleaved float32 samples
-	for i := 0; i < data.NumSamples; i++ {
-		for ch := 0; ch < channels; ch++ {
-			val := data.Samples[ch][i]
-			// Clamp to [-1.0, 1.0] to prevent invalid float values
-			if val > 1.0 {
-				val = 1.0
-			} else if val < -1.0 {
-				val = -1.0
-			} else if math.IsNaN(val) || math.IsInf(val, 0) {
-				val = 0.0
-			}
-
-			if err := binary.Write(bw, binary.LittleEndian, float32(val)); err != nil {
-				return fmt.Errorf("failed to write sample data: %w", err)
-			}
-		}
+	if _, err := w.Write(payload); err != nil {
+		return fmt.Errorf("failed to write %q chunk: %w", id, err)
 	}
-
-	if err := bw.Flush(); err != nil {
-		return fmt.Errorf("failed to flush WAV data: %w", err)
+	if len(payload)%2 == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return fmt.Errorf("failed to write %q pad byte: %w", id, err)
+		}
 	}
-
 	return nil
 }
 
@@ -299,192 +209,54 @@ type wavFormat struct {
 	bitsPerSample uint16
 }
 
-func readWAV(r io.Reader, expectedChannels int) (*AudioData, error) {
-	br := bufio.NewReader(r)
+// readChunkFrames bounds how many frames readWAV allocates ahead of the data
+// actually read, so a header declaring a huge data chunk cannot force a
+// huge allocation.
+const readChunkFrames = 1 << 16
 
-	var riff [4]byte
-	if _, err := io.ReadFull(br, riff[:]); err != nil {
-		return nil, fmt.Errorf("read RIFF header: %w", err)
-	}
-	if string(riff[:]) != "RIFF" {
-		return nil, fmt.Errorf("not a RIFF file")
-	}
-
-	var _riffSize uint32
-	if err := binary.Read(br, binary.LittleEndian, &_riffSize); err != nil {
-		return nil, fmt.Errorf("read RIFF size: %w", err)
+func readWAV(r io.Reader, expectedChannels int) (*AudioData, error) {
+	reader, err := NewReader(r, expectedChannels)
+	if err != nil {
+		return nil, err
 	}
 
-	var wave [4]byte
-	if _, err := io.ReadFull(br, wave[:]); err != nil {
-		return nil, fmt.Errorf("read WAVE header: %w", err)
+	// A data chunk of unknown size (NumFrames -1) is read to the end.
+	numFrames := reader.NumFrames()
+	chunk := readChunkFrames
+	if numFrames >= 0 {
+		chunk = min(numFrames, readChunkFrames)
 	}
-	if string(wave[:]) != "WAVE" {
-		return nil, fmt.Errorf("not a WAVE file")
+	// With AnyChannels, the channel count comes from the header.
+	channels := reader.channels
+	buf := make([][]float64, channels)
+	samplesByChannel := make([][]float64, channels)
+	for ch := 0; ch < channels; ch++ {
+		buf[ch] = make([]float64, chunk)
+		samplesByChannel[ch] = make([]float64, 0, chunk)
 	}
-
-	var fmtChunk *wavFormat
-	for {
-		var chunkID [4]byte
-		if _, err := io.ReadFull(br, chunkID[:]); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, fmt.Errorf("read chunk id: %w", err)
+	for numFrames < 0 || len(samplesByChannel[0]) < numFrames {
+		n, err := reader.ReadFrames(buf)
+		if numFrames < 0 && errors.Is(err, io.EOF) {
+			break
 		}
-		var chunkSize uint32
-		if err := binary.Read(br, binary.LittleEndian, &chunkSize); err != nil {
-			return nil, fmt.Errorf("read chunk size: %w", err)
+		if err != nil {
+			return nil, err
 		}
-
-		switch string(chunkID[:]) {
-		case "fmt ":
-			if chunkSize < 16 {
-				return nil, fmt.Errorf("invalid fmt chunk size %d", chunkSize)
-			}
-			f := &wavFormat{}
-			if err := binary.Read(br, binary.LittleEndian, &f.audioFormat); err != nil {
-				return nil, fmt.Errorf("read audio format: %w", err)
-			}
-			if err := binary.Read(br, binary.LittleEndian, &f.numChannels); err != nil {
-				return nil, fmt.Errorf("read num channels: %w", err)
-			}
-			if err := binary.Read(br, binary.LittleEndian, &f.sampleRate); err != nil {
-				return nil, fmt.Errorf("read sample rate: %w", err)
-			}
-			if err := binary.Read(br, binary.LittleEndian, &f.byteRate); err != nil {
-				return nil, fmt.Errorf("read byte rate: %w", err)
-			}
-			if err := binary.Read(br, binary.LittleEndian, &f.blockAlign); err != nil {
-				return nil, fmt.Errorf("read block align: %w", err)
-			}
-			if err := binary.Read(br, binary.LittleEndian, &f.bitsPerSample); err != nil {
-				return nil, fmt.Errorf("read bits per sample: %w", err)
-			}
-
-			remaining := int64(chunkSize) - 16
-			if remaining > 0 {
-				if _, err := io.CopyN(io.Discard, br, remaining); err != nil {
-					return nil, fmt.Errorf("skip fmt extension: %w", err)
-				}
-			}
-
-			fmtChunk = f
-
-		case "data":
-			if fmtChunk == nil {
-				return nil, fmt.Errorf("data chunk before fmt chunk")
-			}
-			if int(fmtChunk.numChannels) != expectedChannels {
-				return nil, fmt.Errorf("input must have %d channels, got %d channels", expectedChannels, fmtChunk.numChannels)
-			}
-			if fmtChunk.blockAlign == 0 {
-				return nil, fmt.Errorf("invalid blockAlign=0")
-			}
-			if chunkSize%uint32(fmtChunk.blockAlign) != 0 {
-				return nil, fmt.Errorf("data chunk not aligned to block size")
-			}
-
-			numFrames := int(chunkSize / uint32(fmtChunk.blockAlign))
-			samplesByChannel := make([][]float64, expectedChannels)
-			for ch := 0; ch < expectedChannels; ch++ {
-				samplesByChannel[ch] = make([]float64, numFrames)
-			}
-
-			switch fmtChunk.audioFormat {
-			case 1: // PCM
-				switch fmtChunk.bitsPerSample {
-				case 16:
-					for i := range numFrames {
-						for ch := range expectedChannels {
-							var v int16
-							if err := binary.Read(br, binary.LittleEndian, &v); err != nil {
-								return nil, fmt.Errorf("read PCM16 sample: %w", err)
-							}
-							samplesByChannel[ch][i] = float64(v) / 32768.0
-						}
-					}
-				case 24:
-					for i := range numFrames {
-						for ch := range expectedChannels {
-							v, err := readPCM24Sample(br)
-							if err != nil {
-								return nil, fmt.Errorf("read PCM24 sample: %w", err)
-							}
-							samplesByChannel[ch][i] = float64(v) / 8388608.0
-						}
-					}
-				default:
-					return nil, fmt.Errorf("unsupported PCM bit depth %d", fmtChunk.bitsPerSample)
-				}
-
-			case 3: // IEEE float
-				if fmtChunk.bitsPerSample != 32 {
-					return nil, fmt.Errorf("unsupported IEEE float bit depth %d", fmtChunk.bitsPerSample)
-				}
-				for i := 0; i < numFrames; i++ {
-					for ch := 0; ch < expectedChannels; ch++ {
-						var v float32
-						if err := binary.Read(br, binary.LittleEndian, &v); err != nil {
-							return nil, fmt.Errorf("read float32 sample: %w", err)
-						}
-						fv := float64(v)
-						if math.IsNaN(fv) || math.IsInf(fv, 0) {
-							fv = 0
-						}
-						if fv > 1.0 {
-							fv = 1.0
-						} else if fv < -1.0 {
-							fv = -1.0
-						}
-						samplesByChannel[ch][i] = fv
-					}
-				}
-
-			default:
-				return nil, fmt.Errorf("unsupported WAV audio format %d", fmtChunk.audioFormat)
-			}
-
-			// Chunks are word-aligned; if size is odd, a pad byte follows.
-			if chunkSize%2 == 1 {
-				if _, err := br.ReadByte(); err != nil {
-					return nil, fmt.Errorf("read data pad byte: %w", err)
-				}
-			}
-
-			return &AudioData{
-				SampleRate: fmtChunk.sampleRate,
-				Samples:    samplesByChannel,
-				NumSamples: numFrames,
-			}, nil
-
-		default:
-			// Skip unknown chunk (plus pad byte if needed)
-			if _, err := io.CopyN(io.Discard, br, int64(chunkSize)); err != nil {
-				return nil, fmt.Errorf("skip chunk %q: %w", string(chunkID[:]), err)
-			}
-			if chunkSize%2 == 1 {
-				if _, err := br.ReadByte(); err != nil {
-					return nil, fmt.Errorf("read pad byte: %w", err)
-				}
-			}
+		for ch := range buf {
+			samplesByChannel[ch] = append(samplesByChannel[ch], buf[ch][:n]...)
 		}
 	}
-
-	return nil, fmt.Errorf("no data chunk found")
-}
-
-func floatToPCM16(v float64) int16 {
-	if math.IsNaN(v) || math.IsInf(v, 0) {
-		v = 0
-	}
-	if v >= 1.0 {
-		return 32767
-	}
-	if v <= -1.0 {
-		return -32768
-	}
-	return int16(math.Round(v * 32767.0))
+	numFrames = len(samplesByChannel[0])
+
+	return &AudioData{
+		SampleRate:    reader.SampleRate(),
+		Samples:       samplesByChannel,
+		NumSamples:    numFrames,
+		Loops:         reader.Loops(),
+		Cues:          reader.Cues(),
+		BitsPerSample: int(reader.format.bitsPerSample),
+		Float:         reader.format.audioFormat == 3,
+	}, nil
 }
 
 func readPCM24Sample(r io.Reader) (int32, error) {
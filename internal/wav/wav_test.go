@@ -1,8 +1,14 @@
 package wav
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
 	"math"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -75,3 +81,684 @@ func TestReadWAVChannels_ChannelMismatch(t *testing.T) {
 		t.Fatalf("ReadWAVChannels() expected error, got nil")
 	}
 }
+
+func TestWriteWAVWithOptions_ChunkLayout(t *testing.T) {
+	t.Parallel()
+
+	data := &AudioData{
+		SampleRate: 48000,
+		Samples: [][]float64{
+			{0.0, 0.25, -0.25},
+			{0.5, -0.5, 0.0},
+		},
+		NumSamples: 3,
+	}
+
+	tests := []struct {
+		name   string
+		layout ChunkLayout
+		want   []string
+	}{
+		{"default", nil, []string{"fmt ", "data"}},
+		{"minimal", LayoutMinimal, []string{"fmt ", "data"}},
+		{"standard", LayoutStandard, []string{"fmt ", "fact", "LIST", "data"}},
+		{"trailing", LayoutTrailing, []string{"fmt ", "data", "fact", "LIST"}},
+	}
+	for _, tt := range tests {
+		for _, format := range []SampleFormat{FormatPCM16, FormatFloat32} {
+			var buf bytes.Buffer
+			options := WriteOptions{Format: format, Layout: tt.layout}
+			if err := WriteWAVWithOptionsToWriter(&buf, data, 2, options); err != nil {
+				t.Fatalf("%s: WriteWAVWithOptionsToWriter() error = %v", tt.name, err)
+			}
+
+			raw := buf.Bytes()
+			if got := binary.LittleEndian.Uint32(raw[4:8]); int(got) != len(raw)-8 {
+				t.Fatalf("%s: RIFF size = %d, want %d", tt.name, got, len(raw)-8)
+			}
+			if got := chunkIDs(t, raw); !equalStrings(got, tt.want) {
+				t.Fatalf("%s: chunk order = %q, want %q", tt.name, got, tt.want)
+			}
+
+			out, err := ReadWAVBytes(raw, 2)
+			if err != nil {
+				t.Fatalf("%s: ReadWAVBytes() error = %v", tt.name, err)
+			}
+			if out.NumSamples != data.NumSamples {
+				t.Fatalf("%s: NumSamples = %d, want %d", tt.name, out.NumSamples, data.NumSamples)
+			}
+		}
+	}
+}
+
+func TestChunkLayout_Validate(t *testing.T) {
+	t.Parallel()
+
+	invalid := []ChunkLayout{
+		{ChunkData, ChunkFmt},
+		{ChunkFmt},
+		{ChunkFmt, ChunkList, ChunkList, ChunkData},
+		{ChunkFmt, "junk", ChunkData},
+	}
+	for _, layout := range invalid {
+		if err := layout.Validate(); err == nil {
+			t.Fatalf("Validate(%q) expected error", layout)
+		}
+	}
+	if _, err := ParseChunkLayout("bogus"); err == nil {
+		t.Fatalf("ParseChunkLayout() expected error for unknown name")
+	}
+}
+
+func chunkIDs(t *testing.T, raw []byte) []string {
+	t.Helper()
+
+	var ids []string
+	pos := 12
+	for pos+8 <= len(raw) {
+		id := string(raw[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(raw[pos+4 : pos+8]))
+		ids = append(ids, id)
+		pos += 8 + size + size%2
+	}
+	if pos != len(raw) {
+		t.Fatalf("chunk walk ended at %d, want %d", pos, len(raw))
+	}
+	return ids
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWriteWAVWithOptions_PCM8RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	in := &AudioData{
+		SampleRate: 22050,
+		Samples: [][]float64{
+			{0.0, 0.5, -0.5, 1.0, -1.0, 0.25, -0.25},
+			{0.1, -0.1, 0.9, -0.9, 0.0, 0.75, -0.75},
+		},
+		NumSamples: 7,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteWAVWithOptionsToWriter(&buf, in, 2, WriteOptions{Format: FormatPCM8}); err != nil {
+		t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+
+	// 14 data bytes: header (44) + data, no pad byte.
+	if got, want := buf.Len(), 44+14; got != want {
+		t.Fatalf("len = %d, want %d", got, want)
+	}
+	raw := buf.Bytes()
+	if bits := binary.LittleEndian.Uint16(raw[34:]); bits != 8 {
+		t.Fatalf("bitsPerSample = %d, want 8", bits)
+	}
+	// Silence is stored as 128 (unsigned, biased).
+	if raw[44] != 128 {
+		t.Fatalf("first sample byte = %d, want 128", raw[44])
+	}
+
+	out, err := ReadWAVBytes(raw, 2)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+	if out.NumSamples != in.NumSamples {
+		t.Fatalf("NumSamples = %d, want %d", out.NumSamples, in.NumSamples)
+	}
+
+	const tol = 2.0 / 127.0 // coarse 8-bit LSB tolerance
+	for ch := 0; ch < 2; ch++ {
+		for i := 0; i < in.NumSamples; i++ {
+			got := out.Samples[ch][i]
+			want := in.Samples[ch][i]
+			if math.Abs(got-want) > tol {
+				t.Fatalf("sample[%d][%d] = %.6f, want %.6f (tol %.6f)", ch, i, got, want, tol)
+			}
+		}
+	}
+}
+
+func TestWriteWAVWithOptions_PCM24RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	in := &AudioData{
+		SampleRate: 22050,
+		Samples: [][]float64{
+			{0.0, 0.5, -0.5, 1.0, -1.0, 1.5, 1.0 / 8388607},
+			{0.1, -0.1, 0.9, -0.9, -2.0, math.NaN(), -0.4 / 8388607},
+		},
+		NumSamples: 7,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteWAVWithOptionsToWriter(&buf, in, 2, WriteOptions{Format: FormatPCM24}); err != nil {
+		t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+	raw := buf.Bytes()
+	if got, want := len(raw), 44+7*2*3; got != want {
+		t.Fatalf("len = %d, want %d", got, want)
+	}
+	if bits, align, rate := binary.LittleEndian.Uint16(raw[34:]), binary.LittleEndian.Uint16(raw[32:]), binary.LittleEndian.Uint32(raw[28:]); bits != 24 || align != 6 || rate != 22050*6 {
+		t.Fatalf("bitsPerSample, blockAlign, byteRate = %d, %d, %d, want 24, 6, %d", bits, align, rate, 22050*6)
+	}
+	// The data chunk holds little-endian three-byte codes, clamped and
+	// rounded like 16-bit: 1 maps to 8388607, -1 to -8388608 and NaN to
+	// silence.
+	var data []byte
+	for _, code := range []int32{0, 838861, 4194304, -838861, -4194304, 7549746, 8388607, -7549746, -8388608, -8388608, 8388607, 0, 1, 0} {
+		data = append(data, byte(code), byte(code>>8), byte(code>>16))
+	}
+	if !bytes.Equal(raw[44:], data) {
+		t.Fatalf("data chunk = % x, want % x", raw[44:], data)
+	}
+
+	out, err := ReadWAVBytes(raw, 2)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+	for ch := range in.Samples {
+		for i, v := range in.Samples[ch] {
+			want := Int24ToFloat64(Float64ToInt24(v))
+			if got := out.Samples[ch][i]; got != want {
+				t.Fatalf("read sample[%d][%d] = %v, want %v", ch, i, got, want)
+			}
+		}
+	}
+}
+
+func TestParseBitDepth(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]SampleFormat{"8": FormatPCM8, "16": FormatPCM16, "24": FormatPCM24, "32F": FormatFloat32} {
+		if got, err := ParseBitDepth(name); err != nil || got != want {
+			t.Fatalf("ParseBitDepth(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"32", "12", ""} {
+		if _, err := ParseBitDepth(name); err == nil {
+			t.Fatalf("ParseBitDepth(%q) succeeded", name)
+		}
+	}
+}
+
+func TestRemapChannels(t *testing.T) {
+	t.Parallel()
+
+	data := &AudioData{
+		SampleRate: 44100,
+		NumSamples: 2,
+		Samples:    [][]float64{{0, 0.1}, {1, 1.1}, {2, 2.1}, {3, 3.1}},
+	}
+	lb := &data.Samples[1][0]
+	if err := RemapChannels(data, []int{0, 2, 1, 3}); err != nil {
+		t.Fatalf("RemapChannels() error = %v", err)
+	}
+	for i, want := range []float64{0, 2, 1, 3} {
+		if data.Samples[i][0] != want || data.Samples[i][1] != want+0.1 {
+			t.Fatalf("channel %d = %v, want input channel %v", i, data.Samples[i], want)
+		}
+	}
+	if &data.Samples[2][0] != lb {
+		t.Fatalf("RemapChannels() copied the samples instead of moving the channel")
+	}
+
+	for _, order := range [][]int{{0, 1, 2}, {0, 1, 1, 3}, {0, 1, 2, 4}, {-1, 1, 2, 3}} {
+		if err := RemapChannels(data, order); err == nil {
+			t.Fatalf("RemapChannels(%v) expected error", order)
+		}
+	}
+}
+
+func TestWriter_Clipping(t *testing.T) {
+	t.Parallel()
+
+	hot := [][]float64{{0.5, 1.0, 1.5, -2}, {0, -1, 0.25, 0}}
+	for _, format := range []SampleFormat{FormatPCM16, FormatFloat32, FormatPCM8} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, 44100, 2, 4, WriteOptions{Format: format})
+		if err != nil {
+			t.Fatalf("NewWriter() error = %v", err)
+		}
+		if err := w.WriteFrames(hot); err != nil {
+			t.Fatalf("%s: WriteFrames() error = %v", format, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() error = %v", format, err)
+		}
+		// ±1.0 is full scale, not clipped.
+		if got := w.Clipped(); got != 2 {
+			t.Fatalf("%s: Clipped() = %d, want 2", format, got)
+		}
+
+		buf.Reset()
+		w, err = NewWriter(&buf, 44100, 2, 4, WriteOptions{Format: format, ErrorOnClip: true})
+		if err != nil {
+			t.Fatalf("NewWriter() error = %v", err)
+		}
+		if err := w.WriteFrames(hot); !errors.Is(err, ErrClipped) {
+			t.Fatalf("%s: WriteFrames() with ErrorOnClip error = %v, want ErrClipped", format, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	data := &AudioData{SampleRate: 44100, NumSamples: 4, Samples: hot}
+	if err := WriteWAVWithOptionsToWriter(&buf, data, 2, WriteOptions{ErrorOnClip: true}); !errors.Is(err, ErrClipped) {
+		t.Fatalf("WriteWAVWithOptionsToWriter() error = %v, want ErrClipped", err)
+	}
+	data.Samples = [][]float64{{0.5, 1, -1, 0}, {0, 0, 0, 0}}
+	if err := WriteWAVWithOptionsToWriter(&buf, data, 2, WriteOptions{ErrorOnClip: true}); err != nil {
+		t.Fatalf("WriteWAVWithOptionsToWriter(in range) error = %v", err)
+	}
+}
+
+func TestReaderAt(t *testing.T) {
+	t.Parallel()
+
+	const frames = 1000
+	in := &AudioData{SampleRate: 48000, NumSamples: frames, Samples: [][]float64{make([]float64, frames), make([]float64, frames)}}
+	for i := 0; i < frames; i++ {
+		in.Samples[0][i] = math.Sin(float64(i) / 7)
+		in.Samples[1][i] = float64(i) / frames
+	}
+	// The standard layout puts fact and LIST between fmt and data.
+	var buf bytes.Buffer
+	if err := WriteWAVWithOptionsToWriter(&buf, in, 2, WriteOptions{Format: FormatFloat32, Layout: LayoutStandard}); err != nil {
+		t.Fatalf("WriteWAVWithOptionsToWriter() error = %v", err)
+	}
+	whole, err := ReadWAVBytes(buf.Bytes(), 2)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes() error = %v", err)
+	}
+
+	r, err := NewReaderAt(bytes.NewReader(buf.Bytes()), 2)
+	if err != nil {
+		t.Fatalf("NewReaderAt() error = %v", err)
+	}
+	if r.NumFrames() != frames || r.SampleRate() != 48000 {
+		t.Fatalf("NumFrames() = %d, SampleRate() = %d", r.NumFrames(), r.SampleRate())
+	}
+	for _, start := range []int{500, 0, 937, 123} {
+		dst := [][]float64{make([]float64, 100), make([]float64, 100)}
+		n, err := r.ReadFramesAt(dst, start)
+		want := min(100, frames-start)
+		if n != want || (err != nil) != (want < 100) {
+			t.Fatalf("ReadFramesAt(%d) = %d, %v, want %d frames", start, n, err, want)
+		}
+		for ch := range dst {
+			for i := 0; i < n; i++ {
+				if dst[ch][i] != whole.Samples[ch][start+i] {
+					t.Fatalf("frame %d ch %d = %v, want %v", start+i, ch, dst[ch][i], whole.Samples[ch][start+i])
+				}
+			}
+		}
+	}
+	if _, err := r.ReadFramesAt([][]float64{make([]float64, 1), make([]float64, 1)}, frames); !errors.Is(err, io.EOF) {
+		t.Fatalf("ReadFramesAt(end) error = %v, want io.EOF", err)
+	}
+	if _, err := NewReaderAt(bytes.NewReader(buf.Bytes()), 4); err == nil {
+		t.Fatalf("NewReaderAt(4 channels) expected error")
+	}
+}
+
+func TestWriteWAV_InfoTags(t *testing.T) {
+	t.Parallel()
+
+	data := &AudioData{SampleRate: 44100, NumSamples: 3, Samples: [][]float64{{0.1, 0.2, 0.3}, {-0.1, -0.2, -0.3}}}
+	tags := InfoTags{InfoTitle: "Side A", InfoArtist: "Ensemble", InfoTrack: "1"}
+	for _, layout := range []ChunkLayout{LayoutMinimal, LayoutStandard} {
+		var buf bytes.Buffer
+		if err := WriteWAVWithOptionsToWriter(&buf, data, 2, WriteOptions{Layout: layout, Info: tags}); err != nil {
+			t.Fatalf("%v: WriteWAVWithOptionsToWriter() error = %v", layout, err)
+		}
+
+		got, err := ScanInfo(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%v: ScanInfo() error = %v", layout, err)
+		}
+		for id, want := range tags {
+			if got[id] != want {
+				t.Fatalf("%v: tag %s = %q, want %q", layout, id, got[id], want)
+			}
+		}
+		if got[InfoSoftware] != "go-sq-tool" {
+			t.Fatalf("%v: software = %q", layout, got[InfoSoftware])
+		}
+
+		// The minimal layout keeps fmt directly before data.
+		if layout[1] == ChunkData && string(buf.Bytes()[36:40]) != ChunkData {
+			t.Fatalf("%v: chunk after fmt is %q, want data", layout, buf.Bytes()[36:40])
+		}
+		read, err := ReadWAVBytes(buf.Bytes(), 2)
+		if err != nil || read.NumSamples != 3 {
+			t.Fatalf("%v: ReadWAVBytes() = %v frames, %v", layout, read, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteWAVWithOptionsToWriter(&buf, data, 2, WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ScanInfo(bytes.NewReader(buf.Bytes())); got != nil || err != nil {
+		t.Fatalf("ScanInfo(no LIST) = %v, %v, want nil", got, err)
+	}
+
+	// A list declaring nearly 4 GiB in a short file fails after reading
+	// what is there, without allocating the declared size. The bound is
+	// loose, as the other parallel tests allocate as well.
+	file := append(buf.Bytes(), "LIST\xf0\xff\xff\xffINFOISFT\x04\x00\x00\x00sq\x00\x00"...)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := ScanInfo(bytes.NewReader(file))
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("ScanInfo(huge LIST) error = %v, want unexpected EOF", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<26 {
+		t.Fatalf("ScanInfo(huge LIST) allocated %d bytes", allocated)
+	}
+}
+
+// pcmFile builds a WAV file with the given fmt format code and bit depth
+// around raw little-endian sample data. With extensible set, the fmt chunk
+// is a WAVE_FORMAT_EXTENSIBLE one naming format in its sub-format GUID.
+func pcmFile(format uint16, bits, channels int, data []byte, extensible bool) []byte {
+	fmtChunk := binary.LittleEndian.AppendUint16(nil, format)
+	if extensible {
+		fmtChunk = binary.LittleEndian.AppendUint16(nil, 0xfffe)
+	}
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(channels))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, 48000)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(48000*channels*bits/8))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(channels*bits/8))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(bits))
+	if extensible {
+		fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 22)
+		fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(bits))
+		fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, 0x3)
+		fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, format)
+		fmtChunk = append(fmtChunk, "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71"...)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+8+len(fmtChunk)+8+len(data)+len(data)%2))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(fmtChunk)))
+	buf.Write(fmtChunk)
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func TestReadWAVBytes_HighBitDepths(t *testing.T) {
+	t.Parallel()
+
+	want := [][]float64{
+		{0, 0.5, -1, 8388607.0 / 8388608, 1.0 / 8388608},
+		{-0.5, 0.25, 0.125, -1.0 / 8388608, 0},
+	}
+	// The same values as 24-bit and 32-bit codes, frame by frame.
+	var pcm24, pcm32 []byte
+	for i := range want[0] {
+		for ch := range want {
+			v := int32(math.Round(want[ch][i] * 8388608))
+			pcm24 = append(pcm24, byte(v), byte(v>>8), byte(v>>16))
+			pcm32 = binary.LittleEndian.AppendUint32(pcm32, uint32(v<<8))
+		}
+	}
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"pcm24", pcmFile(1, 24, 2, pcm24, false)},
+		{"pcm32", pcmFile(1, 32, 2, pcm32, false)},
+		{"extensible pcm24", pcmFile(1, 24, 2, pcm24, true)},
+		{"extensible pcm32", pcmFile(1, 32, 2, pcm32, true)},
+	}
+	for _, tt := range tests {
+		got, err := ReadWAVBytes(tt.file, 2)
+		if err != nil {
+			t.Fatalf("%s: ReadWAVBytes() error = %v", tt.name, err)
+		}
+		if got.SampleRate != 48000 || got.NumSamples != len(want[0]) {
+			t.Fatalf("%s: %d Hz, %d frames", tt.name, got.SampleRate, got.NumSamples)
+		}
+		for ch := range want {
+			for i, v := range want[ch] {
+				if got.Samples[ch][i] != v {
+					t.Fatalf("%s: channel %d sample %d = %v, want %v", tt.name, ch, i, got.Samples[ch][i], v)
+				}
+			}
+		}
+		info, err := ReadInfo(bytes.NewReader(tt.file))
+		if err != nil || info.Float {
+			t.Fatalf("%s: ReadInfo() = %+v, %v", tt.name, info, err)
+		}
+	}
+}
+
+func TestReadWAVBytes_AllFormats(t *testing.T) {
+	t.Parallel()
+
+	want := []float64{0, 0.5, -0.5, 0.99, -1, 0.1234567, -0.7654321}
+	var pcm8, pcm16, float32s, float64s []byte
+	for _, v := range want {
+		pcm8 = append(pcm8, byte(int(math.Round(v*128))+128))
+		pcm16 = binary.LittleEndian.AppendUint16(pcm16, uint16(int16(math.Round(v*32768))))
+		float32s = binary.LittleEndian.AppendUint32(float32s, math.Float32bits(float32(v)))
+		float64s = binary.LittleEndian.AppendUint64(float64s, math.Float64bits(v))
+	}
+	// Every format must read within one step of its quantization.
+	tests := []struct {
+		name    string
+		format  uint16
+		bits    int
+		data    []byte
+		lsb     float64
+		float   bool
+		extible bool
+	}{
+		{"pcm8", 1, 8, pcm8, 1.0 / 128, false, false},
+		{"pcm16", 1, 16, pcm16, 1.0 / 32768, false, false},
+		{"extensible pcm16", 1, 16, pcm16, 1.0 / 32768, false, true},
+		{"float32", 3, 32, float32s, 1.0 / (1 << 24), true, false},
+		{"extensible float32", 3, 32, float32s, 1.0 / (1 << 24), true, true},
+		{"float64", 3, 64, float64s, 0, true, false},
+		{"extensible float64", 3, 64, float64s, 0, true, true},
+	}
+	for _, tt := range tests {
+		got, err := ReadWAVBytes(pcmFile(tt.format, tt.bits, 1, tt.data, tt.extible), 1)
+		if err != nil {
+			t.Fatalf("%s: ReadWAVBytes() error = %v", tt.name, err)
+		}
+		if got.NumSamples != len(want) || got.BitsPerSample != tt.bits || got.Float != tt.float {
+			t.Fatalf("%s: %d frames, %d bits, float %v", tt.name, got.NumSamples, got.BitsPerSample, got.Float)
+		}
+		for i, v := range want {
+			if math.Abs(got.Samples[0][i]-v) > tt.lsb {
+				t.Fatalf("%s: sample %d = %v, want %v within %v", tt.name, i, got.Samples[0][i], v, tt.lsb)
+			}
+		}
+	}
+}
+
+func TestReadWAVBytes_UnsupportedBitDepth(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		format uint16
+		bits   int
+		want   string
+	}{
+		{1, 12, "unsupported PCM bit depth 12"},
+		{1, 40, "unsupported PCM bit depth 40"},
+		{3, 16, "unsupported IEEE float bit depth 16"},
+	} {
+		file := pcmFile(tt.format, tt.bits, 1, make([]byte, 2*tt.bits/8), tt.bits != 12)
+		_, err := ReadWAVBytes(file, 1)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%d-bit format %d error = %v, want %q", tt.bits, tt.format, err, tt.want)
+		}
+	}
+}
+
+func TestReadWAVBytes_RF64(t *testing.T) {
+	t.Parallel()
+
+	data := binary.LittleEndian.AppendUint16(nil, 0x4000) // 0.5
+	data = binary.LittleEndian.AppendUint16(data, 0xc000) // -0.5
+	data = binary.LittleEndian.AppendUint16(data, 0x2000) // 0.25
+	data = binary.LittleEndian.AppendUint16(data, 0xe000) // -0.25
+	riff := pcmFile(1, 16, 2, data, false)
+
+	// RF64 keeps its sizes in a ds64 chunk after the WAVE id, with a table
+	// of one entry, and marks the 32-bit data size as unknown.
+	ds64 := binary.LittleEndian.AppendUint64(nil, uint64(len(riff)+36))
+	ds64 = binary.LittleEndian.AppendUint64(ds64, uint64(len(data)))
+	ds64 = binary.LittleEndian.AppendUint64(ds64, 2)
+	ds64 = binary.LittleEndian.AppendUint32(ds64, 1)
+	ds64 = append(ds64, "LIST\x00\x00\x00\x00\x00\x00\x00\x00"...)
+	var buf bytes.Buffer
+	buf.WriteString("RF64\xff\xff\xff\xffWAVEds64")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(ds64)))
+	buf.Write(ds64)
+	rest := bytes.Clone(riff[12:])
+	binary.LittleEndian.PutUint32(rest[len(rest)-len(data)-4:], 0xffffffff)
+	buf.Write(rest)
+
+	audio, err := ReadWAVBytes(buf.Bytes(), 2)
+	if err != nil {
+		t.Fatalf("ReadWAVBytes(RF64) error = %v", err)
+	}
+	want := [][]float64{{0.5, 0.25}, {-0.5, -0.25}}
+	if audio.NumSamples != 2 || audio.BitsPerSample != 16 || audio.Float {
+		t.Fatalf("got %d frames, %d bits, float %v, want 2 frames of 16-bit PCM", audio.NumSamples, audio.BitsPerSample, audio.Float)
+	}
+	for ch := range want {
+		for i := range want[ch] {
+			if audio.Samples[ch][i] != want[ch][i] {
+				t.Fatalf("channel %d sample %d = %v, want %v", ch, i, audio.Samples[ch][i], want[ch][i])
+			}
+		}
+	}
+}
+
+func TestReader_UnknownDataSize(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 0, 2*3*2)
+	for _, v := range []uint16{0x4000, 0xc000, 0x2000, 0xe000, 0x1000, 0xf000} {
+		data = binary.LittleEndian.AppendUint16(data, v)
+	}
+	// A writer to a pipe cannot seek back, so it leaves the sizes at
+	// 0xFFFFFFFF; the data then runs to the end of the stream.
+	file := pcmFile(1, 16, 2, data, false)
+	binary.LittleEndian.PutUint32(file[4:], 0xffffffff)
+	binary.LittleEndian.PutUint32(file[len(file)-len(data)-4:], 0xffffffff)
+
+	reader, err := NewReader(bytes.NewReader(file), 2)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if reader.NumFrames() != -1 {
+		t.Fatalf("NumFrames() = %d, want -1", reader.NumFrames())
+	}
+	dst := [][]float64{make([]float64, 8), make([]float64, 8)}
+	n, err := reader.ReadFrames(dst)
+	if n != 3 || (err != nil && !errors.Is(err, io.EOF)) {
+		t.Fatalf("ReadFrames() = %d, %v, want 3 frames", n, err)
+	}
+	if dst[0][2] != 0.125 || dst[1][2] != -0.125 {
+		t.Fatalf("last frame = %v, %v, want 0.125, -0.125", dst[0][2], dst[1][2])
+	}
+
+	audio, err := ReadWAVBytes(file, 2)
+	if err != nil || audio.NumSamples != 3 {
+		t.Fatalf("ReadWAVBytes() = %v, %v, want 3 frames", audio, err)
+	}
+	if _, err := NewReaderAt(bytes.NewReader(file), 2); err == nil {
+		t.Fatalf("NewReaderAt() accepted a file without a data size")
+	}
+}
+
+func TestNewReader_AnyChannels(t *testing.T) {
+	t.Parallel()
+
+	// One frame of six 24-bit channels, the nth at n/8 of full scale.
+	var data []byte
+	for ch := range 6 {
+		v := uint32(ch) << 20
+		data = append(data, byte(v), byte(v>>8), byte(v>>16))
+	}
+	file := pcmFile(1, 24, 6, data, false)
+
+	if _, err := NewReader(bytes.NewReader(file), 2); err == nil {
+		t.Fatalf("NewReader(2) accepted a 6-channel file")
+	}
+	reader, err := NewReader(bytes.NewReader(file), AnyChannels)
+	if err != nil {
+		t.Fatalf("NewReader(AnyChannels) error = %v", err)
+	}
+	want := Info{SampleRate: 48000, Channels: 6, BitsPerSample: 24, NumFrames: 1}
+	if got := reader.Info(); got != want {
+		t.Fatalf("Info() = %+v, want %+v", got, want)
+	}
+	dst := make([][]float64, 6)
+	for ch := range dst {
+		dst[ch] = make([]float64, 1)
+	}
+	if n, err := reader.ReadFrames(dst); n != 1 || err != nil {
+		t.Fatalf("ReadFrames() = %d, %v, want 1 frame", n, err)
+	}
+	for ch := range dst {
+		if want := float64(ch) / 8; dst[ch][0] != want {
+			t.Fatalf("channel %d = %v, want %v", ch, dst[ch][0], want)
+		}
+	}
+}
+
+func TestAudioData_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data *AudioData
+		ok   bool
+	}{
+		{"consistent", NewAudioData(44100, [][]float64{{0, 0.5, 0}, {0, -0.5, 0}}), true},
+		{"empty", NewAudioData(44100, [][]float64{{}, {}}), true},
+		{"stale NumSamples", &AudioData{SampleRate: 44100, Samples: [][]float64{{0, 0.5, 0}, {0, -0.5, 0}}, NumSamples: 2}, false},
+		{"ragged", &AudioData{SampleRate: 44100, Samples: [][]float64{{0, 0.5, 0}, {0, -0.5}}, NumSamples: 3}, false},
+		{"no channels", &AudioData{SampleRate: 44100}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.data.Validate(); (err == nil) != tt.ok {
+			t.Fatalf("%s: Validate() error = %v, want ok %t", tt.name, err, tt.ok)
+		}
+		if !tt.ok && len(tt.data.Samples) == 2 {
+			// The writers refuse the data rather than write a header that
+			// does not match the samples.
+			if err := WriteWAVWithOptionsToWriter(io.Discard, tt.data, 2, WriteOptions{}); err == nil {
+				t.Fatalf("%s: WriteWAVWithOptionsToWriter() error = nil, want error", tt.name)
+			}
+			if err := WriteCAFToWriter(io.Discard, tt.data, 2, WriteOptions{}); err == nil {
+				t.Fatalf("%s: WriteCAFToWriter() error = nil, want error", tt.name)
+			}
+		}
+	}
+}
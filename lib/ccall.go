@@ -0,0 +1,60 @@
+//go:build cgo
+
+package main
+
+/*
+#include <stdlib.h>
+
+#include "sq.h"
+
+typedef long (*sq_process_fn)(uintptr_t, const float *, float *, long);
+
+static long sq_call_process(int encoder, uintptr_t h, const float *in, float *out, long frames) {
+	sq_process_fn fn = encoder ? sq_encoder_process : sq_decoder_process;
+	return fn(h, in, out, frames);
+}
+*/
+import "C"
+
+import "unsafe"
+
+// The functions below call the exports through C, the way a host does, so
+// that the tests cover the C calling convention. Go test files cannot use
+// cgo themselves.
+
+func cDecoderNew(blockSize, overlap, sampleRate int, logic bool) uintptr {
+	l := C.int(0)
+	if logic {
+		l = 1
+	}
+	return uintptr(C.sq_decoder_new(C.int(blockSize), C.int(overlap), C.int(sampleRate), l))
+}
+
+func cEncoderNew(blockSize, overlap int) uintptr {
+	return uintptr(C.sq_encoder_new(C.int(blockSize), C.int(overlap)))
+}
+
+// cProcess runs frames interleaved frames of in through the decoder or
+// encoder h, using C-allocated buffers like a host.
+func cProcess(encoder bool, h uintptr, in []float32, outChannels, frames int) ([]float32, int) {
+	size := C.size_t(unsafe.Sizeof(C.float(0)))
+	cin := (*C.float)(C.calloc(C.size_t(max(len(in), 1)), size))
+	defer C.free(unsafe.Pointer(cin))
+	cout := (*C.float)(C.calloc(C.size_t(max(frames*outChannels, 1)), size))
+	defer C.free(unsafe.Pointer(cout))
+	copy(unsafe.Slice((*float32)(unsafe.Pointer(cin)), len(in)), in)
+
+	e := C.int(0)
+	if encoder {
+		e = 1
+	}
+	n := int(C.sq_call_process(e, C.uintptr_t(h), cin, cout, C.long(frames)))
+	out := make([]float32, frames*outChannels)
+	copy(out, unsafe.Slice((*float32)(unsafe.Pointer(cout)), len(out)))
+	return out, n
+}
+
+func cDecoderLatency(h uintptr) int { return int(C.sq_decoder_latency(C.uintptr_t(h))) }
+func cEncoderLatency(h uintptr) int { return int(C.sq_encoder_latency(C.uintptr_t(h))) }
+func cDecoderFree(h uintptr)        { C.sq_decoder_free(C.uintptr_t(h)) }
+func cEncoderFree(h uintptr)        { C.sq_encoder_free(C.uintptr_t(h)) }
@@ -0,0 +1,62 @@
+/*
+ * example.c - encodes a tone in the left back speaker to SQ stereo, decodes
+ * it again and prints the level of each decoded channel.
+ *
+ *     go build -buildmode=c-shared -o libsq.so ./lib
+ *     cc -I lib -o sq-example lib/example/example.c -L . -lsq -lm
+ *     LD_LIBRARY_PATH=. ./sq-example
+ */
+#include <math.h>
+#include <stdio.h>
+#include <stdlib.h>
+
+#include "sq.h"
+
+#define SAMPLE_RATE 44100
+#define BLOCK 512
+
+int main(void) {
+    uintptr_t enc = sq_encoder_new(1024, 512);
+    uintptr_t dec = sq_decoder_new(1024, 512, SAMPLE_RATE, 0);
+    if (enc == 0 || dec == 0) {
+        fprintf(stderr, "invalid parameters\n");
+        return 1;
+    }
+
+    /* The caller owns all buffers; the library only uses them per call. */
+    float quad[BLOCK * 4], stereo[BLOCK * 2], decoded[BLOCK * 4];
+    double energy[4] = {0};
+    long frames = SAMPLE_RATE;
+    /* Drain both stages by pushing their latency in silence. */
+    long total = frames + sq_encoder_latency(enc) + sq_decoder_latency(dec);
+    const char *names[4] = {"LF", "RF", "LB", "RB"};
+
+    for (long pos = 0; pos < total; pos += BLOCK) {
+        long n = total - pos < BLOCK ? total - pos : BLOCK;
+        for (long i = 0; i < n; i++) {
+            double v = pos + i < frames ? 0.5 * sin(2 * M_PI * 440.0 * (pos + i) / SAMPLE_RATE) : 0;
+            quad[i * 4 + 0] = 0;
+            quad[i * 4 + 1] = 0;
+            quad[i * 4 + 2] = (float)v;
+            quad[i * 4 + 3] = 0;
+        }
+        if (sq_encoder_process(enc, quad, stereo, n) != n ||
+            sq_decoder_process(dec, stereo, decoded, n) != n) {
+            fprintf(stderr, "processing failed\n");
+            return 1;
+        }
+        for (long i = 0; i < n; i++) {
+            for (int ch = 0; ch < 4; ch++) {
+                energy[ch] += (double)decoded[i * 4 + ch] * decoded[i * 4 + ch];
+            }
+        }
+    }
+
+    for (int ch = 0; ch < 4; ch++) {
+        printf("%s: %6.1f dB\n", names[ch], 10 * log10(energy[ch] / frames + 1e-20));
+    }
+
+    sq_decoder_free(dec);
+    sq_encoder_free(enc);
+    return 0;
+}
@@ -0,0 +1,174 @@
+//go:build cgo
+
+// Command lib builds the SQ decoder and encoder as a C shared library:
+//
+//	go build -buildmode=c-shared -o libsq.so ./lib
+//
+// sq.h declares the exported functions. Decoders and encoders are opaque
+// handles; 0 is never a valid handle and is returned on invalid parameters.
+//
+// Memory ownership: the caller allocates and owns every sample buffer. The
+// library reads and writes them only for the duration of a call and keeps
+// no pointers to them. The library owns the state behind a handle until it
+// is released with sq_decoder_free or sq_encoder_free; the handle must not
+// be used afterwards. A handle must not be used from two threads at once,
+// but different handles are independent.
+package main
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"unsafe"
+
+	"github.com/cwbudde/go-sq-tool/internal/pipeline"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+)
+
+func main() {}
+
+// stream is the state behind a handle.
+type stream struct {
+	*pipeline.Stream
+	inChannels, outChannels int
+}
+
+// validParams reports whether blockSize is a power of 2 in [64, 65536] and
+// overlap is in [1, blockSize], the limits the server applies.
+func validParams(blockSize, overlap int) bool {
+	return blockSize >= 64 && blockSize <= 1<<16 && blockSize&(blockSize-1) == 0 &&
+		overlap > 0 && overlap <= blockSize
+}
+
+func newHandle(process pipeline.Processor, inChannels, outChannels, blockSize, overlap int) C.uintptr_t {
+	s, err := pipeline.NewStream(process, inChannels, outChannels, overlap, blockSize-overlap)
+	if err != nil {
+		return 0
+	}
+	return C.uintptr_t(cgo.NewHandle(&stream{Stream: s, inChannels: inChannels, outChannels: outChannels}))
+}
+
+// lookup returns the stream behind h, or nil when h is 0 or belongs to the
+// other kind of handle.
+func lookup(h C.uintptr_t, inChannels int) *stream {
+	if h == 0 {
+		return nil
+	}
+	s, _ := cgo.Handle(h).Value().(*stream)
+	if s == nil || s.inChannels != inChannels {
+		return nil
+	}
+	return s
+}
+
+// process runs frames interleaved frames of in through the stream behind h
+// and writes as many interleaved frames to out.
+func process(h C.uintptr_t, inChannels int, in, out *C.float, frames C.long) C.long {
+	s := lookup(h, inChannels)
+	if s == nil || frames < 0 {
+		return -1
+	}
+	n := int(frames)
+	if n == 0 {
+		return 0
+	}
+	src := unsafe.Slice((*float32)(unsafe.Pointer(in)), n*s.inChannels)
+	dst := unsafe.Slice((*float32)(unsafe.Pointer(out)), n*s.outChannels)
+
+	input := make([][]float64, s.inChannels)
+	for ch := range input {
+		input[ch] = make([]float64, n)
+		for i := range input[ch] {
+			input[ch][i] = float64(src[i*s.inChannels+ch])
+		}
+	}
+	output, err := s.Process(input)
+	if err != nil {
+		return -1
+	}
+	for ch, samples := range output {
+		for i, v := range samples {
+			dst[i*s.outChannels+ch] = float32(v)
+		}
+	}
+	return frames
+}
+
+func latency(h C.uintptr_t, inChannels int) C.long {
+	s := lookup(h, inChannels)
+	if s == nil {
+		return -1
+	}
+	return C.long(s.Latency())
+}
+
+func free(h C.uintptr_t, inChannels int) {
+	if lookup(h, inChannels) != nil {
+		cgo.Handle(h).Delete()
+	}
+}
+
+//export sq_decoder_new
+func sq_decoder_new(blockSize, overlap, sampleRate, logic C.int) C.uintptr_t {
+	if !validParams(int(blockSize), int(overlap)) || sampleRate <= 0 {
+		return 0
+	}
+	options := []sq.Option{
+		sq.WithBlockSize(int(blockSize)),
+		sq.WithOverlap(int(overlap)),
+		sq.WithSampleRate(int(sampleRate)),
+	}
+	if logic != 0 {
+		options = append(options, sq.WithLogicSteering(sq.DefaultLogicSteeringConfig()))
+	}
+	d, err := sq.NewDecoder(options...)
+	if err != nil {
+		return 0
+	}
+	return newHandle(d.ProcessSegment, 2, 4, int(blockSize), int(overlap))
+}
+
+//export sq_decoder_process
+func sq_decoder_process(h C.uintptr_t, in, out *C.float, frames C.long) C.long {
+	return process(h, 2, in, out, frames)
+}
+
+//export sq_decoder_latency
+func sq_decoder_latency(h C.uintptr_t) C.long {
+	return latency(h, 2)
+}
+
+//export sq_decoder_free
+func sq_decoder_free(h C.uintptr_t) {
+	free(h, 2)
+}
+
+//export sq_encoder_new
+func sq_encoder_new(blockSize, overlap C.int) C.uintptr_t {
+	if !validParams(int(blockSize), int(overlap)) {
+		return 0
+	}
+	e, err := sq.NewEncoder(sq.WithBlockSize(int(blockSize)), sq.WithOverlap(int(overlap)))
+	if err != nil {
+		return 0
+	}
+	return newHandle(e.ProcessSegment, 4, 2, int(blockSize), int(overlap))
+}
+
+//export sq_encoder_process
+func sq_encoder_process(h C.uintptr_t, in, out *C.float, frames C.long) C.long {
+	return process(h, 4, in, out, frames)
+}
+
+//export sq_encoder_latency
+func sq_encoder_latency(h C.uintptr_t) C.long {
+	return latency(h, 4)
+}
+
+//export sq_encoder_free
+func sq_encoder_free(h C.uintptr_t) {
+	free(h, 4)
+}
@@ -0,0 +1,146 @@
+//go:build cgo
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/testsignal"
+)
+
+const (
+	blockSize = 1024
+	overlap   = 512
+)
+
+// interleave converts signal to float32 frames and back, the precision the
+// library works at.
+func interleave(signal [][]float64) ([]float32, [][]float64) {
+	frames := len(signal[0])
+	out := make([]float32, frames*len(signal))
+	quantized := make([][]float64, len(signal))
+	for ch := range signal {
+		quantized[ch] = make([]float64, frames)
+		for i, v := range signal[ch] {
+			out[i*len(signal)+ch] = float32(v)
+			quantized[ch][i] = float64(float32(v))
+		}
+	}
+	return out, quantized
+}
+
+// run pushes signal and latency frames of silence through h in blocks of
+// random size and compares the delayed output with want.
+func run(t *testing.T, encode bool, h uintptr, latency int, signal, want [][]float64) {
+	t.Helper()
+
+	inCh, outCh := len(signal), len(want)
+	in, _ := interleave(signal)
+	frames := len(signal[0])
+	in = append(in, make([]float32, latency*inCh)...)
+	var got []float32
+	rng := rand.New(rand.NewSource(1))
+	for pos, total := 0, frames+latency; pos < total; {
+		n := min(rng.Intn(900), total-pos)
+		out, done := cProcess(encode, h, in[pos*inCh:(pos+n)*inCh], outCh, n)
+		if done != n {
+			t.Fatalf("process(%d frames) = %d", n, done)
+		}
+		got = append(got, out...)
+		pos += n
+	}
+
+	for ch := range want {
+		for i, w := range want[ch] {
+			if g := float64(got[(latency+i)*outCh+ch]); math.Abs(g-w) > 1e-6 {
+				t.Fatalf("ch %d frame %d: got %v, want %v", ch, i, g, w)
+			}
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	t.Parallel()
+
+	h := cDecoderNew(blockSize, overlap, 48000, true)
+	if h == 0 {
+		t.Fatalf("sq_decoder_new() = 0")
+	}
+	defer cDecoderFree(h)
+	latency := cDecoderLatency(h)
+	if latency != blockSize-1 {
+		t.Fatalf("sq_decoder_latency() = %d, want %d", latency, blockSize-1)
+	}
+
+	stereo := testsignal.QuadTones(48000, 8*overlap+33, 0.4, 0.05)[:2]
+	_, quantized := interleave(stereo)
+	d := decoder.NewSQDecoderWithParams(blockSize, overlap)
+	d.SetSampleRate(48000)
+	d.EnableLogicSteering(true)
+	want, err := d.Process(quantized)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	run(t, false, h, latency, stereo, want)
+}
+
+func TestEncoder(t *testing.T) {
+	t.Parallel()
+
+	h := cEncoderNew(blockSize, overlap)
+	if h == 0 {
+		t.Fatalf("sq_encoder_new() = 0")
+	}
+	defer cEncoderFree(h)
+	latency := cEncoderLatency(h)
+
+	quad := testsignal.QuadTones(44100, 8*overlap+33, 0.4, 0.05)
+	_, quantized := interleave(quad)
+	want, err := encoder.NewSQEncoderWithParams(blockSize, overlap).Process(quantized)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	run(t, true, h, latency, quad, want)
+}
+
+func TestInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range [][2]int{{1000, 500}, {32, 16}, {1024, 0}, {1024, 2048}} {
+		if h := cDecoderNew(p[0], p[1], 44100, false); h != 0 {
+			t.Fatalf("sq_decoder_new(%d, %d) = %d, want 0", p[0], p[1], h)
+		}
+		if h := cEncoderNew(p[0], p[1]); h != 0 {
+			t.Fatalf("sq_encoder_new(%d, %d) = %d, want 0", p[0], p[1], h)
+		}
+	}
+	if h := cDecoderNew(blockSize, overlap, 0, false); h != 0 {
+		t.Fatalf("sq_decoder_new(sample rate 0) = %d, want 0", h)
+	}
+
+	if _, n := cProcess(false, 0, nil, 4, 0); n != -1 {
+		t.Fatalf("process(0 handle) = %d, want -1", n)
+	}
+	if got := cDecoderLatency(0); got != -1 {
+		t.Fatalf("sq_decoder_latency(0) = %d, want -1", got)
+	}
+	cDecoderFree(0)
+
+	// A handle only works with the functions of its kind.
+	enc := cEncoderNew(blockSize, overlap)
+	defer cEncoderFree(enc)
+	if _, n := cProcess(false, enc, make([]float32, 2), 4, 1); n != -1 {
+		t.Fatalf("sq_decoder_process(encoder) = %d, want -1", n)
+	}
+	if got := cDecoderLatency(enc); got != -1 {
+		t.Fatalf("sq_decoder_latency(encoder) = %d, want -1", got)
+	}
+	cDecoderFree(enc)
+	if got := cEncoderLatency(enc); got != blockSize-1 {
+		t.Fatalf("encoder freed by sq_decoder_free: latency %d", got)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * sq.h - C interface of the SQ quadraphonic decoder and encoder.
+ *
+ * Build the library with
+ *
+ *     go build -buildmode=c-shared -o libsq.so ./lib
+ *
+ * and link against it with -lsq.
+ *
+ * Memory ownership:
+ *   - The caller allocates and owns every sample buffer. The library reads
+ *     `in` and writes `out` only during the call and keeps no pointers.
+ *   - The library owns the state behind a handle until it is released with
+ *     sq_decoder_free or sq_encoder_free. Do not use a handle after freeing
+ *     it. Freeing 0 does nothing.
+ *   - A handle must not be used from two threads at the same time;
+ *     separate handles are independent.
+ *
+ * Samples are interleaved 32-bit floats at full scale +-1.0.
+ */
+#ifndef SQ_H
+#define SQ_H
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/*
+ * sq_decoder_new creates an SQ decoder. block_size is the FFT size, a power
+ * of 2 in [64, 65536]; overlap is the hop size in [1, block_size] (the CLI
+ * defaults are 1024 and 512). logic != 0 enables logic steering. Returns 0
+ * on invalid parameters.
+ */
+extern uintptr_t sq_decoder_new(int block_size, int overlap, int sample_rate, int logic);
+
+/*
+ * sq_decoder_process decodes `frames` frames of LT, RT from `in`
+ * (2 * frames floats) and writes as many frames of LF, RF, LB, RB to `out`
+ * (4 * frames floats). Returns frames, or -1 on an invalid handle.
+ */
+extern long sq_decoder_process(uintptr_t decoder, const float *in, float *out, long frames);
+
+/*
+ * sq_decoder_latency returns the delay of the output in frames. Output
+ * frame latency + i belongs to input frame i; push latency frames of
+ * silence after the signal to drain the decoder.
+ */
+extern long sq_decoder_latency(uintptr_t decoder);
+
+/* sq_decoder_free releases the decoder. */
+extern void sq_decoder_free(uintptr_t decoder);
+
+/*
+ * sq_encoder_new creates an SQ encoder with the same parameters as
+ * sq_decoder_new. Returns 0 on invalid parameters.
+ */
+extern uintptr_t sq_encoder_new(int block_size, int overlap);
+
+/*
+ * sq_encoder_process encodes `frames` frames of LF, RF, LB, RB from `in`
+ * (4 * frames floats) and writes as many frames of LT, RT to `out`
+ * (2 * frames floats). Returns frames, or -1 on an invalid handle.
+ */
+extern long sq_encoder_process(uintptr_t encoder, const float *in, float *out, long frames);
+
+/* sq_encoder_latency returns the delay of the output in frames. */
+extern long sq_encoder_latency(uintptr_t encoder);
+
+/* sq_encoder_free releases the encoder. */
+extern void sq_encoder_free(uintptr_t encoder);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* SQ_H */
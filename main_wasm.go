@@ -9,17 +9,10 @@ import (
 	"fmt"
 	"syscall/js"
 
-	"github.com/cwbudde/go-sq-tool/internal/decoder"
 	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
 )
 
-type decodeOptions struct {
-	BlockSize int
-	Overlap   int
-	Logic     bool
-	Float32   bool
-}
-
 var decodeFunc js.Func
 
 func main() {
@@ -50,10 +43,7 @@ func decodeWavJS(this js.Value, args []js.Value) interface{} {
 }
 
 func parseOptions(args []js.Value) decodeOptions {
-	opts := decodeOptions{
-		BlockSize: decoder.DefaultBlockSize,
-		Overlap:   decoder.DefaultOverlap,
-	}
+	var opts decodeOptions
 	if len(args) < 2 {
 		return opts
 	}
@@ -105,10 +95,18 @@ func decodeWavBytes(input []byte, opts decodeOptions) ([]byte, error) {
 		return nil, fmt.Errorf("read wav: %w", err)
 	}
 
-	sqDecoder := decoder.NewSQDecoderWithParams(opts.BlockSize, opts.Overlap)
-	sqDecoder.SetSampleRate(int(audioData.SampleRate))
+	opts = opts.withSampleRate(int(audioData.SampleRate))
+	options := []sq.Option{
+		sq.WithBlockSize(opts.BlockSize),
+		sq.WithOverlap(opts.Overlap),
+		sq.WithSampleRate(int(audioData.SampleRate)),
+	}
 	if opts.Logic {
-		sqDecoder.EnableLogicSteering(true)
+		options = append(options, sq.WithLogicSteering(sq.DefaultLogicSteeringConfig()))
+	}
+	sqDecoder, err := sq.NewDecoder(options...)
+	if err != nil {
+		return nil, fmt.Errorf("decoder: %w", err)
 	}
 
 	output, err := sqDecoder.Process(audioData.Samples)
@@ -116,11 +114,7 @@ func decodeWavBytes(input []byte, opts decodeOptions) ([]byte, error) {
 		return nil, fmt.Errorf("decode: %w", err)
 	}
 
-	outputData := &wav.AudioData{
-		SampleRate: audioData.SampleRate,
-		Samples:    output,
-		NumSamples: audioData.NumSamples,
-	}
+	outputData := wav.NewAudioData(audioData.SampleRate, output)
 
 	var buf bytes.Buffer
 	if opts.Float32 {
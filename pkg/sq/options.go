@@ -0,0 +1,101 @@
+package sq
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// Option configures NewDecoder and NewEncoder.
+type Option func(*config)
+
+// config collects the options of a constructor.
+type config struct {
+	blockSize  int
+	overlap    int
+	sampleRate int
+	window     sqmath.WindowType
+	precision  sqmath.Precision
+	scale      float64
+	matrix     Matrix
+	logic      *LogicSteeringConfig
+}
+
+// newConfig applies opts to the defaults and checks the settings that the
+// block size and overlap do not depend on. Without WithOverlap the overlap
+// keeps the default ratio to the block size.
+func newConfig(opts []Option) (config, error) {
+	c := config{sampleRate: 44100, scale: sqmath.DefaultHilbertScale}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.blockSize == 0 {
+		c.blockSize = DefaultBlockSize
+	}
+	if c.overlap == 0 {
+		c.overlap = max(c.blockSize*DefaultOverlap/DefaultBlockSize, 1)
+	}
+	if c.sampleRate <= 0 {
+		return config{}, fmt.Errorf("sample rate %d is not positive", c.sampleRate)
+	}
+	if c.window != "" {
+		if _, err := sqmath.ParseWindowType(string(c.window)); err != nil {
+			return config{}, err
+		}
+	}
+	if c.scale <= 0 || math.IsInf(c.scale, 0) || math.IsNaN(c.scale) {
+		return config{}, fmt.Errorf("scale of the Hilbert filter %g is not positive and finite", c.scale)
+	}
+	return c, nil
+}
+
+// WithBlockSize sets the FFT block size, a power of 2 (default
+// DefaultBlockSize).
+func WithBlockSize(n int) Option {
+	return func(c *config) { c.blockSize = n }
+}
+
+// WithOverlap sets the hop between blocks, between 1 and the block size
+// (default half the block size). An overlap equal to the block size selects
+// the faster no-overlap mode.
+func WithOverlap(n int) Option {
+	return func(c *config) { c.overlap = n }
+}
+
+// WithSampleRate sets the sample rate of the signal in Hz, which the time
+// constants of logic steering depend on (default 44100). Decoder only.
+func WithSampleRate(hz int) Option {
+	return func(c *config) { c.sampleRate = hz }
+}
+
+// WithWindow selects the window of the Hilbert filter (default Hann).
+func WithWindow(window sqmath.WindowType) Option {
+	return func(c *config) { c.window = window }
+}
+
+// WithPrecision selects float64 (default) or float32 FFTs.
+func WithPrecision(precision sqmath.Precision) Option {
+	return func(c *config) { c.precision = precision }
+}
+
+// WithHilbertScale sets the gain of the built-in Hilbert filter (default
+// sqmath.DefaultHilbertScale).
+func WithHilbertScale(scale float64) Option {
+	return func(c *config) { c.scale = scale }
+}
+
+// WithMatrix selects the matrix system (default MatrixSQ).
+func WithMatrix(matrix Matrix) Option {
+	return func(c *config) { c.matrix = matrix }
+}
+
+// WithLogicSteering enables logic steering with logic; its Enabled field is
+// ignored. Use DefaultLogicSteeringConfig for the default settings. Decoder
+// only.
+func WithLogicSteering(logic LogicSteeringConfig) Option {
+	return func(c *config) {
+		logic.Enabled = true
+		c.logic = &logic
+	}
+}
@@ -0,0 +1,147 @@
+// Package sq is the public API of the SQ quadraphonic decoder and encoder.
+//
+// A Decoder turns a matrixed stereo signal (LT, RT) into four channels
+// (LF, RF, LB, RB) and an Encoder does the reverse:
+//
+//	d, err := sq.NewDecoder(sq.WithBlockSize(2048), sq.WithSampleRate(48000))
+//	if err != nil {
+//		return err
+//	}
+//	quad, err := d.Process(stereo)
+//
+// Constructors take functional options, so settings added later do not
+// change their signature. The types are those the sq command line tool
+// decodes with; their other methods, such as ProcessStream and Snapshot,
+// are available as well, and every type those methods take or return is
+// re-exported here.
+package sq
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+	"github.com/cwbudde/go-sq-tool/internal/wav"
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// Decoder decodes a 2-channel SQ signal into 4 channels.
+type Decoder = decoder.SQDecoder
+
+// Encoder encodes 4 channels into a 2-channel SQ signal.
+type Encoder = encoder.SQEncoder
+
+// AudioData is multi-channel audio with its sample rate, as read by ReadWAV.
+type AudioData = wav.AudioData
+
+// LogicSteeringConfig configures logic steering; see WithLogicSteering.
+type LogicSteeringConfig = decoder.LogicSteeringConfig
+
+// DefaultLogicSteeringConfig returns the default logic steering settings.
+func DefaultLogicSteeringConfig() LogicSteeringConfig {
+	return decoder.DefaultLogicSteeringConfig()
+}
+
+// Matrix selects the matrix system; see WithMatrix.
+type Matrix = decoder.Matrix
+
+// Matrix systems.
+const (
+	// MatrixSQ is the CBS SQ matrix (the default).
+	MatrixSQ = decoder.MatrixSQ
+	// MatrixQS is the Sansui QS Regular Matrix.
+	MatrixQS = decoder.MatrixQS
+)
+
+// SampleFormat selects the sample encoding of WriteWAV.
+type SampleFormat = wav.SampleFormat
+
+// Sample formats.
+const (
+	FormatPCM16   = wav.FormatPCM16
+	FormatPCM24   = wav.FormatPCM24
+	FormatFloat32 = wav.FormatFloat32
+)
+
+// Default block size and overlap.
+const (
+	DefaultBlockSize = decoder.DefaultBlockSize
+	DefaultOverlap   = decoder.DefaultOverlap
+)
+
+// ParamError is the error of NewDecoder and NewEncoder for an unusable block
+// size or overlap; Param names which.
+type ParamError = decoder.ParamError
+
+// NewDecoder returns a decoder configured by opts. It fails with a
+// descriptive error, such as "overlap 2048 exceeds block size 1024", if an
+// option is out of range; the block size and overlap fail with a
+// *ParamError.
+func NewDecoder(opts ...Option) (*Decoder, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	d, err := decoder.NewSQDecoderWithParamsE(c.blockSize, c.overlap)
+	if err != nil {
+		return nil, err
+	}
+	if c.window != "" {
+		d.SetWindow(c.window)
+	}
+	d.SetPrecision(c.precision)
+	if c.scale != sqmath.DefaultHilbertScale {
+		d.SetHilbertScale(c.scale)
+	}
+	d.SetMatrix(c.matrix)
+	if c.logic != nil {
+		d.SetLogicSteeringConfig(*c.logic)
+	}
+	d.SetSampleRate(c.sampleRate)
+	return d, nil
+}
+
+// NewEncoder returns an encoder configured by opts. It fails like
+// NewDecoder if an option is out of range. Options that only concern decoding, the sample
+// rate and logic steering, are ignored.
+func NewEncoder(opts ...Option) (*Encoder, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.ValidateParams(c.blockSize, c.overlap); err != nil {
+		return nil, err
+	}
+	e := encoder.NewSQEncoderWithParams(c.blockSize, c.overlap)
+	if c.window != "" {
+		e.SetWindow(c.window)
+	}
+	e.SetPrecision(c.precision)
+	if c.scale != sqmath.DefaultHilbertScale {
+		e.SetHilbertScale(c.scale)
+	}
+	e.SetMatrix(c.matrix)
+	return e, nil
+}
+
+// NewAudioData returns samples, indexed [channel][frame], at sampleRate as
+// AudioData.
+func NewAudioData(sampleRate uint32, samples [][]float64) *AudioData {
+	return wav.NewAudioData(sampleRate, samples)
+}
+
+// ReadWAV reads a WAV stream of the given channel count: 2 for a decoder
+// input, 4 for an encoder input. A stream of another channel count is an
+// error.
+func ReadWAV(r io.Reader, channels int) (*AudioData, error) {
+	return wav.ReadWAVFromReader(r, channels)
+}
+
+// WriteWAV writes data as a WAV stream with all its channels in format.
+func WriteWAV(w io.Writer, data *AudioData, format SampleFormat) error {
+	if len(data.Samples) == 0 {
+		return fmt.Errorf("audio data has no channels")
+	}
+	return wav.WriteWAVWithOptionsToWriter(w, data, len(data.Samples), wav.WriteOptions{Format: format})
+}
@@ -0,0 +1,178 @@
+package sq_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sq"
+)
+
+func TestNewDecoder_Options(t *testing.T) {
+	t.Parallel()
+
+	for _, opt := range []sq.Option{sq.WithBlockSize(1000), sq.WithOverlap(4096)} {
+		if _, err := sq.NewDecoder(opt); err == nil {
+			t.Fatalf("NewDecoder with an invalid block size or overlap succeeded")
+		}
+		if _, err := sq.NewEncoder(opt); err == nil {
+			t.Fatalf("NewEncoder with an invalid block size or overlap succeeded")
+		}
+	}
+	if _, err := sq.NewDecoder(sq.WithSampleRate(-1)); err == nil {
+		t.Fatalf("NewDecoder with a negative sample rate succeeded")
+	}
+
+	for _, test := range []struct {
+		opts  []sq.Option
+		param string
+		want  string
+	}{
+		{[]sq.Option{sq.WithBlockSize(1000)}, "block size", "block size 1000 is not a power of two"},
+		{[]sq.Option{sq.WithBlockSize(-4)}, "block size", "block size -4 is below the minimum of 2"},
+		{[]sq.Option{sq.WithBlockSize(1)}, "block size", "block size 1 is below the minimum of 2"},
+		{[]sq.Option{sq.WithBlockSize(1024), sq.WithOverlap(2048)}, "overlap", "overlap 2048 exceeds block size 1024"},
+		{[]sq.Option{sq.WithOverlap(-1)}, "overlap", "overlap -1 is not positive"},
+		{[]sq.Option{sq.WithBlockSize(1000), sq.WithOverlap(2048)}, "block size", "block size 1000 is not a power of two"},
+	} {
+		_, decErr := sq.NewDecoder(test.opts...)
+		_, encErr := sq.NewEncoder(test.opts...)
+		for _, err := range []error{decErr, encErr} {
+			var paramErr *sq.ParamError
+			if !errors.As(err, &paramErr) || paramErr.Param != test.param || err.Error() != test.want {
+				t.Fatalf("error = %v, want %s error %q", err, test.param, test.want)
+			}
+		}
+	}
+	for _, opt := range []sq.Option{sq.WithSampleRate(0), sq.WithWindow("triangle"), sq.WithHilbertScale(0), sq.WithHilbertScale(math.Inf(1))} {
+		if _, err := sq.NewDecoder(opt); err == nil {
+			t.Fatalf("NewDecoder with an invalid option succeeded")
+		}
+	}
+
+	// Without WithOverlap the overlap keeps the default ratio.
+	d, err := sq.NewDecoder(sq.WithBlockSize(2048))
+	if err != nil {
+		t.Fatalf("NewDecoder error = %v", err)
+	}
+	want, err := sq.NewDecoder(sq.WithBlockSize(2048), sq.WithOverlap(2048*sq.DefaultOverlap/sq.DefaultBlockSize))
+	if err != nil {
+		t.Fatalf("NewDecoder error = %v", err)
+	}
+	if d.GetLatency() != want.GetLatency() {
+		t.Fatalf("latency = %d, want %d", d.GetLatency(), want.GetLatency())
+	}
+}
+
+func TestEncodeDecodeWAV(t *testing.T) {
+	t.Parallel()
+
+	const sampleRate = 48000
+	const n = 8192
+	quad := make([][]float64, 4)
+	for ch := range quad {
+		quad[ch] = make([]float64, n)
+	}
+	for i := range n {
+		quad[0][i] = 0.5 * math.Sin(2*math.Pi*1000*float64(i)/sampleRate)
+	}
+
+	opts := []sq.Option{sq.WithSampleRate(sampleRate), sq.WithMatrix(sq.MatrixQS)}
+	e, err := sq.NewEncoder(opts...)
+	if err != nil {
+		t.Fatalf("NewEncoder error = %v", err)
+	}
+	stereo, err := e.Process(quad)
+	if err != nil {
+		t.Fatalf("encode error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := sq.WriteWAV(&buf, sq.NewAudioData(sampleRate, stereo), sq.FormatFloat32); err != nil {
+		t.Fatalf("WriteWAV error = %v", err)
+	}
+	data, err := sq.ReadWAV(&buf, 2)
+	if err != nil {
+		t.Fatalf("ReadWAV error = %v", err)
+	}
+	if data.SampleRate != sampleRate || data.Frames() != n {
+		t.Fatalf("read %d frames at %d Hz, want %d at %d", data.Frames(), data.SampleRate, n, sampleRate)
+	}
+
+	d, err := sq.NewDecoder(opts...)
+	if err != nil {
+		t.Fatalf("NewDecoder error = %v", err)
+	}
+	decoded, err := d.Process(data.Samples)
+	if err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if len(decoded) != 4 || len(decoded[0]) != n {
+		t.Fatalf("decoded %d channels of %d samples, want 4 of %d", len(decoded), len(decoded[0]), n)
+	}
+	// LF dominates its neighbours in the steady middle of the signal.
+	var energy [4]float64
+	for ch := range decoded {
+		for _, v := range decoded[ch][n/4 : 3*n/4] {
+			energy[ch] += v * v
+		}
+	}
+	for ch := 1; ch < 4; ch++ {
+		if energy[ch] >= energy[0] {
+			t.Fatalf("channel %d energy %g not below LF %g", ch, energy[ch], energy[0])
+		}
+	}
+}
+
+// TestMethodTypesExported checks that every type from an internal package
+// in the method signatures of Decoder and Encoder is re-exported, so that
+// callers can name it.
+func TestMethodTypesExported(t *testing.T) {
+	t.Parallel()
+
+	exported := make(map[reflect.Type]bool)
+	for _, v := range []any{
+		sq.BackChannelMode(0), sq.OutputLayout(0), sq.TailHandling(0), sq.Matrix(0),
+		sq.SilenceSkipConfig{}, sq.SilenceStats{}, sq.ProcessResult{}, sq.LogicPlan{},
+		sq.LogicSteeringConfig{}, sq.BlockStage(0), sq.BlockHook(nil),
+		sq.EncoderBlockStage(0), sq.EncoderBlockHook(nil), sq.EnergyStats{},
+	} {
+		exported[reflect.TypeOf(v)] = true
+	}
+	for _, typ := range []reflect.Type{reflect.TypeFor[*sq.Decoder](), reflect.TypeFor[*sq.Encoder]()} {
+		for i := range typ.NumMethod() {
+			method := typ.Method(i)
+			var params []reflect.Type
+			for j := range method.Type.NumIn() {
+				params = append(params, method.Type.In(j))
+			}
+			for j := range method.Type.NumOut() {
+				params = append(params, method.Type.Out(j))
+			}
+			for _, param := range params[1:] {
+				for param.Kind() == reflect.Pointer || param.Kind() == reflect.Slice {
+					param = param.Elem()
+				}
+				if strings.Contains(param.PkgPath(), "/internal/") && !exported[param] {
+					t.Errorf("%s.%s uses %s, which package sq does not re-export", typ.Elem().Name(), method.Name, param)
+				}
+			}
+		}
+	}
+
+	// The re-exported constants configure a decoder from outside the
+	// module.
+	d, err := sq.NewDecoder()
+	if err != nil {
+		t.Fatalf("NewDecoder error = %v", err)
+	}
+	d.SetBackChannelMode(sq.BackChannelDiscreteAndSumDiff)
+	d.SetTailHandling(sq.TailMirror)
+	d.SetBlockHook(sq.HookAfterMatrix, func(int, [][]float64) {})
+	if d.OutputChannels() != 6 || d.TailHandling() != sq.TailMirror {
+		t.Fatalf("decoder has %d channels and tail %v, want 6 and mirror", d.OutputChannels(), d.TailHandling())
+	}
+}
@@ -0,0 +1,109 @@
+package sq
+
+import (
+	"github.com/cwbudde/go-sq-tool/internal/decoder"
+	"github.com/cwbudde/go-sq-tool/internal/encoder"
+)
+
+// The types and constants below are taken or returned by methods of
+// Decoder and Encoder. They are re-exported so that callers outside this
+// module can name them.
+
+// BackChannelMode arranges the decoded back channels; see
+// Decoder.SetBackChannelMode.
+type BackChannelMode = decoder.BackChannelMode
+
+// Back channel modes.
+const (
+	// BackChannelDiscrete outputs LF, RF, LB, RB (the default).
+	BackChannelDiscrete = decoder.BackChannelDiscrete
+	// BackChannelSumDiff outputs LF, RF, LB+RB, LB-RB.
+	BackChannelSumDiff = decoder.BackChannelSumDiff
+	// BackChannelDiscreteAndSumDiff outputs LF, RF, LB, RB, LB+RB, LB-RB.
+	BackChannelDiscreteAndSumDiff = decoder.BackChannelDiscreteAndSumDiff
+)
+
+// OutputLayout selects speaker feeds or ambisonic output; see
+// Decoder.SetOutputLayout.
+type OutputLayout = decoder.OutputLayout
+
+// Output layouts.
+const (
+	// LayoutQuad outputs the speaker feeds (the default).
+	LayoutQuad = decoder.LayoutQuad
+	// LayoutAmbisonicB outputs first-order B-format W, X, Y. Experimental.
+	LayoutAmbisonicB = decoder.LayoutAmbisonicB
+)
+
+// TailHandling selects how the decoder pads the blocks past the end of the
+// signal; see Decoder.SetTailHandling.
+type TailHandling = decoder.TailHandling
+
+// Tail handling modes.
+const (
+	// TailZeroPad pads with zeros (the default).
+	TailZeroPad = decoder.TailZeroPad
+	// TailMirror pads with the signal point-reflected about its last sample.
+	TailMirror = decoder.TailMirror
+	// TailHold repeats the last sample.
+	TailHold = decoder.TailHold
+)
+
+// SilenceSkipConfig configures skipping silent blocks; see
+// Decoder.SetSilenceSkip.
+type SilenceSkipConfig = decoder.SilenceSkipConfig
+
+// DefaultSilenceSkipConfig returns the silence skip defaults (disabled).
+func DefaultSilenceSkipConfig() SilenceSkipConfig {
+	return decoder.DefaultSilenceSkipConfig()
+}
+
+// SilenceStats counts the decoded and skipped blocks; see
+// Decoder.SilenceStats.
+type SilenceStats = decoder.SilenceStats
+
+// ProcessResult is the output of Decoder.ProcessWithResult with its
+// diagnostics.
+type ProcessResult = decoder.ProcessResult
+
+// LogicPlan is the first pass of two-pass logic steering; see
+// Decoder.StartLogicAnalysis and Decoder.SetLogicPlan.
+type LogicPlan = decoder.LogicPlan
+
+// BlockStage selects where a BlockHook of a Decoder runs; see
+// Decoder.SetBlockHook.
+type BlockStage = decoder.BlockStage
+
+// BlockHook is called with every block of a Decoder at its stage.
+type BlockHook = decoder.BlockHook
+
+// Decoder hook stages.
+const (
+	// HookBeforeMatrix runs before the decode matrix on LT, RT, H(LT),
+	// H(RT).
+	HookBeforeMatrix = decoder.HookBeforeMatrix
+	// HookAfterMatrix runs on the output after logic steering.
+	HookAfterMatrix = decoder.HookAfterMatrix
+	// HookIntermediate taps H(LT), H(RT) and the pre-logic LB, RB.
+	HookIntermediate = decoder.HookIntermediate
+)
+
+// EncoderBlockStage selects where an EncoderBlockHook runs; see
+// Encoder.SetBlockHook.
+type EncoderBlockStage = encoder.BlockStage
+
+// EncoderBlockHook is called with every block of an Encoder at its stage.
+type EncoderBlockHook = encoder.BlockHook
+
+// Encoder hook stages.
+const (
+	// EncoderHookBeforeMatrix runs before the encode matrix on LF, RF, LB,
+	// RB, H(LB), H(RB).
+	EncoderHookBeforeMatrix = encoder.HookBeforeMatrix
+	// EncoderHookAfterMatrix runs on the encoded LT, RT.
+	EncoderHookAfterMatrix = encoder.HookAfterMatrix
+)
+
+// EnergyStats is the energy measurement of an Encoder; see
+// Encoder.SetEnergyMeasurement.
+type EnergyStats = encoder.EnergyStats
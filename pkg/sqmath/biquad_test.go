@@ -0,0 +1,112 @@
+package sqmath_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// crossoverGains returns the steady-state amplitude of the low band, the
+// high band and their sum for a unit sine at freq, measured over the second
+// half of one second of output.
+func crossoverGains(sampleRate, crossover, freq float64) (low, high, sum float64) {
+	c := sqmath.NewCrossover(sampleRate, crossover)
+	n := int(sampleRate)
+	var proj [3]complex128
+	for i := range n {
+		phase := 2.0 * math.Pi * freq * float64(i) / sampleRate
+		l, h := c.Process(math.Sin(phase))
+		if i >= n/2 {
+			ref := complex(math.Cos(phase), math.Sin(phase))
+			proj[0] += complex(l, 0) * ref
+			proj[1] += complex(h, 0) * ref
+			proj[2] += complex(l+h, 0) * ref
+		}
+	}
+	scale := 2.0 / float64(n-n/2)
+	return scale * cmplx.Abs(proj[0]), scale * cmplx.Abs(proj[1]), scale * cmplx.Abs(proj[2])
+}
+
+func TestCrossover_BandsSumFlat(t *testing.T) {
+	t.Parallel()
+
+	const sampleRate, crossover = 48000.0, 120.0
+	for _, freq := range []float64{30, 60, 120, 240, 1000, 8000} {
+		_, _, sum := crossoverGains(sampleRate, crossover, freq)
+		if math.Abs(sum-1) > 0.01 {
+			t.Fatalf("%g Hz: |low+high| = %g, want 1", freq, sum)
+		}
+	}
+
+	// Linkwitz-Riley bands are each -6 dB at the crossover frequency.
+	low, high, _ := crossoverGains(sampleRate, crossover, crossover)
+	if math.Abs(low-0.5) > 0.01 || math.Abs(high-0.5) > 0.01 {
+		t.Fatalf("at crossover: low = %g, high = %g, want 0.5", low, high)
+	}
+	low, high, _ = crossoverGains(sampleRate, crossover, 8000)
+	if low > 1e-3 || math.Abs(high-1) > 0.01 {
+		t.Fatalf("8 kHz: low = %g, high = %g, want ~0 and 1", low, high)
+	}
+}
+
+func TestCrossover_StateResumesStream(t *testing.T) {
+	t.Parallel()
+
+	input := make([]float64, 512)
+	for i := range input {
+		input[i] = math.Sin(float64(i)*0.05) + 0.3*math.Sin(float64(i)*1.3)
+	}
+
+	whole := sqmath.NewCrossover(44100, 100)
+	split := sqmath.NewCrossover(44100, 100)
+	for _, x := range input[:200] {
+		whole.Process(x)
+		split.Process(x)
+	}
+	resumed := sqmath.NewCrossover(44100, 100)
+	resumed.SetState(split.State())
+	for i, x := range input[200:] {
+		wl, wh := whole.Process(x)
+		rl, rh := resumed.Process(x)
+		if wl != rl || wh != rh {
+			t.Fatalf("sample %d: resumed (%g, %g), want (%g, %g)", i, rl, rh, wl, wh)
+		}
+	}
+}
+
+// biquadGain measures the steady-state gain of f at freq by projection.
+func biquadGain(f sqmath.Biquad, sampleRate, freq float64) float64 {
+	n := int(sampleRate)
+	var proj complex128
+	for i := range n {
+		phase := 2.0 * math.Pi * freq * float64(i) / sampleRate
+		y := f.Process(math.Sin(phase))
+		if i >= n/2 {
+			proj += complex(y, 0) * complex(math.Cos(phase), math.Sin(phase))
+		}
+	}
+	return 2.0 / float64(n-n/2) * cmplx.Abs(proj)
+}
+
+func TestHighShelf(t *testing.T) {
+	t.Parallel()
+
+	const sampleRate = 48000
+	for _, gainDB := range []float64{-4, 2} {
+		shelf := sqmath.NewHighShelf(sampleRate, 3000, gainDB, sqmath.ButterworthQ)
+		for _, tt := range []struct {
+			freq, want float64
+		}{
+			{50, 0},
+			{3000, gainDB / 2},
+			{20000, gainDB},
+		} {
+			got := 20 * math.Log10(biquadGain(shelf, sampleRate, tt.freq))
+			if math.Abs(got-tt.want) > 0.3 {
+				t.Fatalf("%+g dB shelf at %g Hz: %.2f dB, want %.2f dB", gainDB, tt.freq, got, tt.want)
+			}
+		}
+	}
+}
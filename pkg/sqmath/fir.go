@@ -0,0 +1,34 @@
+package sqmath
+
+import "math"
+
+// LowpassFIR returns a linear-phase low-pass FIR with cutoff freq Hz: a
+// sinc windowed by a Blackman window, long enough for a transition band of
+// width Hz centered on the cutoff and about 74 dB of stopband attenuation.
+// The length is odd, so the group delay is a whole (len-1)/2 samples, and
+// the taps are normalized to unity gain at DC.
+func LowpassFIR(sampleRate, freq, width float64) []float64 {
+	taps := int(math.Ceil(5.5*sampleRate/width)) | 1
+	window := blackmanWindow(taps)
+	center := taps / 2
+	fc := freq / sampleRate
+	h := make([]float64, taps)
+	sum := 0.0
+	// Mirror the first half so the taps are exactly symmetric.
+	for i := 0; i <= center; i++ {
+		n := float64(center - i)
+		v := 2 * fc
+		if n != 0 {
+			v = math.Sin(2*math.Pi*fc*n) / (math.Pi * n)
+		}
+		h[i] = v * window[i]
+		h[taps-1-i] = h[i]
+	}
+	for _, v := range h {
+		sum += v
+	}
+	for i := range h {
+		h[i] /= sum
+	}
+	return h
+}
@@ -0,0 +1,49 @@
+package sqmath_test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+// firGain returns the magnitude response of h at freq Hz.
+func firGain(h []float64, sampleRate, freq float64) float64 {
+	var sum complex128
+	for i, v := range h {
+		sum += complex(v, 0) * cmplx.Exp(complex(0, -2*math.Pi*freq*float64(i)/sampleRate))
+	}
+	return cmplx.Abs(sum)
+}
+
+func TestLowpassFIR(t *testing.T) {
+	t.Parallel()
+
+	const sampleRate, cutoff, width = 44100.0, 10000.0, 2000.0
+	h := sqmath.LowpassFIR(sampleRate, cutoff, width)
+	if len(h)%2 != 1 {
+		t.Fatalf("len = %d, want odd", len(h))
+	}
+	// Linear phase: the taps are symmetric about the center.
+	for i := range h {
+		if h[i] != h[len(h)-1-i] {
+			t.Fatalf("tap %d = %g, tap %d = %g, want symmetric", i, h[i], len(h)-1-i, h[len(h)-1-i])
+		}
+	}
+
+	for _, tt := range []struct {
+		freq, min, max float64
+	}{
+		{0, 0.999999, 1.000001},
+		{1000, 0.999, 1.001},
+		{cutoff - width/2, 0.99, 1.01},
+		{cutoff, 0.45, 0.55},
+		{cutoff + width/2, 0, 1e-3},
+		{15000, 0, 1e-3},
+	} {
+		if g := firGain(h, sampleRate, tt.freq); g < tt.min || g > tt.max {
+			t.Fatalf("gain at %g Hz = %g, want [%g, %g]", tt.freq, g, tt.min, tt.max)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package sqmath
 
 import (
+	"fmt"
 	"math"
+	"strings"
 
 	algofft "github.com/MeKo-Christian/algo-fft"
 )
@@ -16,17 +18,66 @@ const (
 	WindowRectangular WindowType = "rect"
 )
 
+// WindowInfo describes a window type for listings.
+type WindowInfo struct {
+	Type        WindowType
+	Description string
+}
+
+// windows are the window types ParseWindowType accepts, without aliases.
+var windows = []WindowInfo{
+	{WindowHann, "raised cosine, the default; good leakage at moderate main-lobe width (alias hanning)"},
+	{WindowHamming, "raised cosine on a pedestal; lower first sidelobe, slower sidelobe decay than hann"},
+	{WindowBlackman, "three-term cosine; lowest leakage, widest main lobe"},
+	{WindowRectangular, "no tapering; sharpest main lobe, highest leakage"},
+}
+
+// Windows returns the window types with a one-line description each.
+func Windows() []WindowInfo {
+	return append([]WindowInfo(nil), windows...)
+}
+
+// ParseWindowType converts a window name (hann, hanning, hamming, blackman,
+// rect) into a WindowType.
+func ParseWindowType(name string) (WindowType, error) {
+	wt := WindowType(strings.ToLower(strings.TrimSpace(name)))
+	if wt == WindowHanning {
+		return wt, nil
+	}
+	names := make([]string, len(windows))
+	for i, w := range windows {
+		if wt == w.Type {
+			return wt, nil
+		}
+		names[i] = string(w.Type)
+	}
+	return "", fmt.Errorf("unknown window type %q (want %s or %s)", name,
+		strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
+}
+
+// DefaultHilbertScale is the gain of the built-in Hilbert design, taken
+// from the original implementation.
+const DefaultHilbertScale = 1.8
+
 // HilbertTransformer performs 90-degree phase shift using FFT
 type HilbertTransformer struct {
-	blockSize   int
-	overlap     int
-	fftSize     int
-	fftPlan     *algofft.Plan[complex128]
-	windowType  WindowType
-	window      []float64
-	transferFn  []complex128
+	blockSize  int
+	overlap    int
+	fftSize    int
+	fftPlan    *algofft.Plan[complex128]
+	windowType WindowType
+	window     []float64
+	transferFn []complex128
+	// impulse is the response before scaling by scale.
+	impulse     []float64
+	scale       float64
 	inputBuffer []float64
 	initialized bool
+	// precision selects the FFT path; fftPlan32 and transferFn32 are only
+	// set for Precision32.
+	precision    Precision
+	fftPlan32    *algofft.Plan[complex64]
+	transferFn32 []complex64
 }
 
 // NewHilbertTransformer creates a new Hilbert transformer
@@ -50,6 +101,7 @@ func NewHilbertTransformerWithWindow(blockSize, overlap int, windowType WindowTy
 		fftSize:     blockSize,
 		fftPlan:     plan,
 		windowType:  windowType,
+		scale:       DefaultHilbertScale,
 		inputBuffer: make([]float64, blockSize),
 	}
 
@@ -57,6 +109,37 @@ func NewHilbertTransformerWithWindow(blockSize, overlap int, windowType WindowTy
 	return ht
 }
 
+// NewHilbertTransformerFromImpulse creates a Hilbert transformer that
+// applies the given impulse response instead of the built-in windowed
+// design: ProcessBlock returns the circular convolution of the block with
+// impulse, unwindowed and at unity gain. The response may not be longer
+// than blockSize.
+func NewHilbertTransformerFromImpulse(blockSize int, impulse []float64) (*HilbertTransformer, error) {
+	if len(impulse) == 0 || len(impulse) > blockSize {
+		return nil, fmt.Errorf("impulse response has %d taps, want 1 to %d", len(impulse), blockSize)
+	}
+	plan, err := algofft.NewPlan64(blockSize)
+	if err != nil {
+		return nil, err
+	}
+	ht := &HilbertTransformer{
+		blockSize:   blockSize,
+		overlap:     len(impulse),
+		fftSize:     blockSize,
+		fftPlan:     plan,
+		scale:       1,
+		inputBuffer: make([]float64, blockSize),
+	}
+	// ProcessBlock rescales by 1/fftSize after the inverse FFT, which the
+	// built-in design's gain absorbs; compensate it here.
+	scaled := make([]float64, len(impulse))
+	for i, v := range impulse {
+		scaled[i] = v * float64(blockSize)
+	}
+	ht.setImpulse(scaled)
+	return ht, nil
+}
+
 // makeFilter constructs the Hilbert transform transfer function
 // Based on SQ² decoder implementation from VSTDataModule.pas
 func (ht *HilbertTransformer) makeFilter() {
@@ -78,18 +161,18 @@ func (ht *HilbertTransformer) makeFilter() {
 		impulse[i] *= ht.window[i]
 	}
 
-	// Scale by 1.8 (from original implementation)
-	for i := 0; i < ht.overlap; i++ {
-		impulse[i] *= 1.8
-	}
+	ht.setImpulse(impulse)
+}
 
-	// Convert to complex for FFT
+// setImpulse sets the transfer function to the FFT of impulse times the
+// scale.
+func (ht *HilbertTransformer) setImpulse(impulse []float64) {
+	ht.impulse = impulse
 	impulseComplex := make([]complex128, ht.fftSize)
 	for i := range impulse {
-		impulseComplex[i] = complex(impulse[i], 0)
+		impulseComplex[i] = complex(impulse[i]*ht.scale, 0)
 	}
 
-	// FFT to get transfer function
 	ht.transferFn = make([]complex128, ht.fftSize)
 	if err := ht.fftPlan.Forward(ht.transferFn, impulseComplex); err != nil {
 		panic(err)
@@ -97,6 +180,15 @@ func (ht *HilbertTransformer) makeFilter() {
 	ht.initialized = true
 }
 
+// Window returns the symmetric window of the given type and size.
+func Window(windowType WindowType, size int) ([]float64, error) {
+	wt, err := ParseWindowType(string(windowType))
+	if err != nil {
+		return nil, err
+	}
+	return makeWindow(wt, size), nil
+}
+
 func makeWindow(windowType WindowType, size int) []float64 {
 	switch windowType {
 	case WindowHann, WindowHanning:
@@ -164,11 +256,53 @@ func rectangularWindow(size int) []float64 {
 	return window
 }
 
+// SetPrecision selects the floating-point width of the FFTs. The transfer
+// function is always designed in float64 and rounded for Precision32.
+func (ht *HilbertTransformer) SetPrecision(precision Precision) {
+	ht.precision = precision
+	ht.fftPlan32 = nil
+	ht.transferFn32 = nil
+	if precision != Precision32 {
+		return
+	}
+	plan, err := algofft.NewPlan32(ht.fftSize)
+	if err != nil {
+		panic(err)
+	}
+	ht.fftPlan32 = plan
+	ht.transferFn32 = make([]complex64, ht.fftSize)
+	for i, v := range ht.transferFn {
+		ht.transferFn32[i] = complex64(v)
+	}
+}
+
+// SetScale sets the gain the impulse response is multiplied by:
+// DefaultHilbertScale for the built-in design, 1 for a response given to
+// NewHilbertTransformerFromImpulse. The output scales linearly with it.
+func (ht *HilbertTransformer) SetScale(scale float64) {
+	ht.scale = scale
+	ht.setImpulse(ht.impulse)
+	ht.SetPrecision(ht.precision)
+}
+
+// Scale returns the gain set by SetScale.
+func (ht *HilbertTransformer) Scale() float64 {
+	return ht.scale
+}
+
+// Precision returns the floating-point width of the FFTs.
+func (ht *HilbertTransformer) Precision() Precision {
+	return ht.precision
+}
+
 // ProcessBlock applies Hilbert transform to a block of samples
 func (ht *HilbertTransformer) ProcessBlock(input []float64) []float64 {
 	if len(input) != ht.blockSize {
 		panic("input size must match block size")
 	}
+	if ht.precision == Precision32 {
+		return ht.processBlock32(input)
+	}
 
 	// Convert to complex
 	inputComplex := make([]complex128, ht.fftSize)
@@ -202,3 +336,30 @@ func (ht *HilbertTransformer) ProcessBlock(input []float64) []float64 {
 
 	return output
 }
+
+// processBlock32 is ProcessBlock with complex64 FFTs.
+func (ht *HilbertTransformer) processBlock32(input []float64) []float64 {
+	inputComplex := make([]complex64, ht.fftSize)
+	for i, v := range input {
+		inputComplex[i] = complex(float32(v), 0)
+	}
+
+	freqDomain := make([]complex64, ht.fftSize)
+	if err := ht.fftPlan32.Forward(freqDomain, inputComplex); err != nil {
+		panic(err)
+	}
+	for i := range freqDomain {
+		freqDomain[i] *= ht.transferFn32[i]
+	}
+	timeDomain := make([]complex64, ht.fftSize)
+	if err := ht.fftPlan32.Inverse(timeDomain, freqDomain); err != nil {
+		panic(err)
+	}
+
+	output := make([]float64, ht.blockSize)
+	scale := 1.0 / float64(ht.fftSize)
+	for i := range output {
+		output[i] = float64(real(timeDomain[i])) * scale
+	}
+	return output
+}
@@ -0,0 +1,116 @@
+package sqmath
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+)
+
+func benchmarkBlock(size int) []float64 {
+	block := make([]float64, size)
+	for i := range block {
+		block[i] = 0.5*math.Sin(2*math.Pi*float64(i)/97) + 0.25*math.Sin(2*math.Pi*float64(i)/13)
+	}
+	return block
+}
+
+func BenchmarkHilbertTransformer_ProcessBlock(b *testing.B) {
+	for _, size := range []int{512, 1024, 4096} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			ht := NewHilbertTransformer(size, size/2)
+			block := benchmarkBlock(size)
+			b.ReportAllocs()
+			b.SetBytes(int64(8 * size))
+			for b.Loop() {
+				ht.ProcessBlock(block)
+			}
+		})
+	}
+}
+
+// realFFTHilbert is a prototype of the planned real-FFT mode: the same
+// transfer function applied to the N/2+1 bins of a real-to-complex
+// transform, with preallocated buffers. It exists so that the mode's PR and
+// the current complex path are measured by the same harness.
+type realFFTHilbert struct {
+	ht       *HilbertTransformer
+	plan     *algofft.PlanRealT[float64, complex128]
+	spectrum []complex128
+	output   []float64
+}
+
+func newRealFFTHilbert(size int) (*realFFTHilbert, error) {
+	plan, err := algofft.NewPlanReal64(size)
+	if err != nil {
+		return nil, err
+	}
+	return &realFFTHilbert{
+		ht:       NewHilbertTransformer(size, size/2),
+		plan:     plan,
+		spectrum: make([]complex128, plan.SpectrumLen()),
+		output:   make([]float64, size),
+	}, nil
+}
+
+func (r *realFFTHilbert) ProcessBlock(input []float64) []float64 {
+	if err := r.plan.Forward(r.spectrum, input); err != nil {
+		panic(err)
+	}
+	for i := range r.spectrum {
+		r.spectrum[i] *= r.ht.transferFn[i]
+	}
+	if err := r.plan.Inverse(r.output, r.spectrum); err != nil {
+		panic(err)
+	}
+	scale := 1.0 / float64(r.ht.fftSize)
+	for i := range r.output {
+		r.output[i] *= scale
+	}
+	return r.output
+}
+
+// BenchmarkHilbertModes compares the current complex-FFT path with the
+// real-FFT prototype and the float32 path. The real-FFT output is checked to
+// match before timing; the float32 output to within its tolerance.
+func BenchmarkHilbertModes(b *testing.B) {
+	for _, size := range []int{512, 1024, 4096} {
+		block := benchmarkBlock(size)
+		realMode, err := newRealFFTHilbert(size)
+		if err != nil {
+			b.Fatalf("newRealFFTHilbert() error = %v", err)
+		}
+		want := NewHilbertTransformer(size, size/2).ProcessBlock(block)
+		for i, v := range realMode.ProcessBlock(block) {
+			if math.Abs(v-want[i]) > 1e-9 {
+				b.Fatalf("real-FFT output differs at %d: %v, want %v", i, v, want[i])
+			}
+		}
+		float32Mode := NewHilbertTransformer(size, size/2)
+		float32Mode.SetPrecision(Precision32)
+		for i, v := range float32Mode.ProcessBlock(block) {
+			if math.Abs(v-want[i]) > 1e-5 {
+				b.Fatalf("float32 output differs at %d: %v, want %v", i, v, want[i])
+			}
+		}
+
+		modes := []struct {
+			name    string
+			process func([]float64) []float64
+		}{
+			{"complex", NewHilbertTransformer(size, size/2).ProcessBlock},
+			{"real", realMode.ProcessBlock},
+			{"float32", float32Mode.ProcessBlock},
+		}
+		for _, mode := range modes {
+			b.Run(fmt.Sprintf("%s/%d", mode.name, size), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(8 * size))
+				for b.Loop() {
+					mode.process(block)
+				}
+			})
+		}
+	}
+}
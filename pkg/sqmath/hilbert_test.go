@@ -2,6 +2,7 @@ package sqmath_test
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
@@ -118,3 +119,133 @@ func normalizedDot(a, b []float64) float64 {
 	}
 	return dot / math.Sqrt(na*nb)
 }
+
+func TestParseWindowType(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]sqmath.WindowType{
+		"hann":     sqmath.WindowHann,
+		"Hanning":  sqmath.WindowHanning,
+		"hamming":  sqmath.WindowHamming,
+		"BLACKMAN": sqmath.WindowBlackman,
+		"rect":     sqmath.WindowRectangular,
+	} {
+		got, err := sqmath.ParseWindowType(name)
+		if err != nil || got != want {
+			t.Fatalf("ParseWindowType(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := sqmath.ParseWindowType("kaiser"); err == nil {
+		t.Fatalf("ParseWindowType(kaiser) error = nil, want error")
+	}
+}
+
+func TestHilbertTransformer_Precision32WithinTolerance(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range []int{512, 1024, 4096} {
+		block := make([]float64, size)
+		for i := range block {
+			block[i] = rng.Float64()*2 - 1
+		}
+		want := sqmath.NewHilbertTransformer(size, size/2).ProcessBlock(block)
+		ht := sqmath.NewHilbertTransformer(size, size/2)
+		ht.SetPrecision(sqmath.Precision32)
+		got := ht.ProcessBlock(block)
+
+		var maxErr, peak float64
+		for i := range got {
+			maxErr = math.Max(maxErr, math.Abs(got[i]-want[i]))
+			peak = math.Max(peak, math.Abs(want[i]))
+		}
+		// Float32 rounding, relative to the output level; a deviation of
+		// exactly 0 would mean the float64 path ran.
+		if rel := maxErr / peak; rel > 1e-5 || rel == 0 {
+			t.Fatalf("size %d: float32 deviates by %g of the peak, want (0, 1e-5]", size, rel)
+		}
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]sqmath.Precision{
+		"32":      sqmath.Precision32,
+		"float32": sqmath.Precision32,
+		"64":      sqmath.Precision64,
+		"Float64": sqmath.Precision64,
+	} {
+		got, err := sqmath.ParsePrecision(name)
+		if err != nil || got != want {
+			t.Fatalf("ParsePrecision(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := sqmath.ParsePrecision("16"); err == nil {
+		t.Fatalf("ParsePrecision(16) error = nil, want error")
+	}
+}
+
+func TestNewHilbertTransformerFromImpulse(t *testing.T) {
+	t.Parallel()
+
+	// A delayed unit impulse delays the block circularly.
+	ht, err := sqmath.NewHilbertTransformerFromImpulse(64, []float64{0, 0, 0.5})
+	if err != nil {
+		t.Fatalf("NewHilbertTransformerFromImpulse() error = %v", err)
+	}
+	in := make([]float64, 64)
+	for i := range in {
+		in[i] = float64(i + 1)
+	}
+	out := ht.ProcessBlock(in)
+	for i := range out {
+		want := 0.5 * in[(i+62)%64]
+		if math.Abs(out[i]-want) > 1e-9 {
+			t.Fatalf("out[%d] = %g, want %g", i, out[i], want)
+		}
+	}
+
+	for _, n := range []int{0, 65} {
+		if _, err := sqmath.NewHilbertTransformerFromImpulse(64, make([]float64, n)); err == nil {
+			t.Fatalf("%d taps: expected error", n)
+		}
+	}
+}
+
+func TestHilbertTransformer_SetScale(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(2))
+	block := make([]float64, 1024)
+	for i := range block {
+		block[i] = rng.Float64()*2 - 1
+	}
+	want := sqmath.NewHilbertTransformer(1024, 512).ProcessBlock(block)
+	for _, scale := range []float64{0.5, 0.9, 3.6} {
+		ht := sqmath.NewHilbertTransformer(1024, 512)
+		ht.SetScale(scale)
+		if ht.Scale() != scale {
+			t.Fatalf("Scale() = %g, want %g", ht.Scale(), scale)
+		}
+		got := ht.ProcessBlock(block)
+		ratio := scale / sqmath.DefaultHilbertScale
+		for i := range got {
+			if math.Abs(got[i]-ratio*want[i]) > 1e-12 {
+				t.Fatalf("scale %g: out[%d] = %g, want %g", scale, i, got[i], ratio*want[i])
+			}
+		}
+	}
+
+	// An impulse response starts at unity gain.
+	ht, err := sqmath.NewHilbertTransformerFromImpulse(64, []float64{1})
+	if err != nil {
+		t.Fatalf("NewHilbertTransformerFromImpulse() error = %v", err)
+	}
+	ht.SetScale(2)
+	for i, v := range ht.ProcessBlock(block[:64]) {
+		if math.Abs(v-2*block[i]) > 1e-12 {
+			t.Fatalf("scale 2: out[%d] = %g, want %g", i, v, 2*block[i])
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package sqmath
+
+import (
+	"fmt"
+	"math"
+
+	algofft "github.com/MeKo-Christian/algo-fft"
+)
+
+// FrameFunc modifies the spectrum of one frame in place. freq holds all
+// size bins of the FFT of a real frame; a function that keeps the result
+// real keeps bin k the complex conjugate of bin size-k.
+type FrameFunc func(freq []complex128)
+
+// OverlapAddProcessor runs a FrameFunc over a signal by weighted overlap-
+// add: the input is cut into frames of size samples every hop samples,
+// each frame is windowed, transformed, handed to the frame function,
+// transformed back, windowed again and added into the output. Analysis and
+// synthesis use the square root of the periodic window, so the squared
+// windows overlap to a constant for the COLA hops of the window, size/k
+// for an integer k of at least 1 for rect, 2 for hann and hamming and 3
+// for blackman. Other hops leave a ripple, see Ripple.
+type OverlapAddProcessor struct {
+	size, hop int
+	plan      *algofft.Plan[complex128]
+	window    []float64
+	// gain undoes the mean overlap of the squared windows.
+	gain   float64
+	ripple float64
+	frame  FrameFunc
+
+	input  []float64
+	output []float64
+	ready  []float64
+	pos    int
+	freq   []complex128
+}
+
+// NewOverlapAddProcessor returns a processor running frame over frames of
+// size samples, a power of two, every hop samples.
+func NewOverlapAddProcessor(size, hop int, windowType WindowType, frame FrameFunc) (*OverlapAddProcessor, error) {
+	if size < 2 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("frame size must be a power of 2, got %d", size)
+	}
+	if hop <= 0 || hop > size {
+		return nil, fmt.Errorf("hop must be between 1 and the frame size %d, got %d", size, hop)
+	}
+	if frame == nil {
+		return nil, fmt.Errorf("no frame function")
+	}
+	wt, err := ParseWindowType(string(windowType))
+	if err != nil {
+		return nil, err
+	}
+	plan, err := algofft.NewPlan64(size)
+	if err != nil {
+		return nil, fmt.Errorf("overlap-add FFT: %w", err)
+	}
+
+	// The periodic window is the symmetric one of size+1 without its last
+	// sample.
+	window := makeWindow(wt, size+1)[:size]
+	sum := make([]float64, hop)
+	for i, w := range window {
+		sum[i%hop] += w
+	}
+	lo, hi, mean := math.Inf(1), math.Inf(-1), 0.0
+	for _, s := range sum {
+		lo, hi = min(lo, s), max(hi, s)
+		mean += s / float64(hop)
+	}
+	for i, w := range window {
+		window[i] = math.Sqrt(w)
+	}
+
+	p := &OverlapAddProcessor{
+		size:   size,
+		hop:    hop,
+		plan:   plan,
+		window: window,
+		gain:   1 / mean,
+		ripple: (hi - lo) / mean,
+		frame:  frame,
+		input:  make([]float64, size),
+		output: make([]float64, size),
+		ready:  make([]float64, hop),
+		freq:   make([]complex128, size),
+	}
+	return p, nil
+}
+
+// Latency returns the delay of the output of Process in samples.
+func (p *OverlapAddProcessor) Latency() int {
+	return p.size
+}
+
+// Ripple returns the peak-to-peak deviation of the overlapped squared
+// windows relative to their mean: 0 for a COLA hop, otherwise the
+// amplitude modulation an identity frame function leaves on the output.
+func (p *OverlapAddProcessor) Ripple() float64 {
+	return p.ripple
+}
+
+// Reset clears the buffered signal.
+func (p *OverlapAddProcessor) Reset() {
+	clear(p.input)
+	clear(p.output)
+	clear(p.ready)
+	p.pos = 0
+}
+
+// Process feeds x through the processor and returns as many output
+// samples, delayed by Latency. The state carries over between calls, so x
+// may be split anywhere.
+func (p *OverlapAddProcessor) Process(x []float64) ([]float64, error) {
+	out := make([]float64, len(x))
+	for i, v := range x {
+		p.input[p.size-p.hop+p.pos] = v
+		out[i] = p.ready[p.pos]
+		if p.pos++; p.pos == p.hop {
+			if err := p.processFrame(); err != nil {
+				return nil, err
+			}
+			p.pos = 0
+		}
+	}
+	return out, nil
+}
+
+// Apply processes all of x from a cleared state and returns the output
+// aligned with x.
+func (p *OverlapAddProcessor) Apply(x []float64) ([]float64, error) {
+	p.Reset()
+	out, err := p.Process(append(append(make([]float64, 0, len(x)+p.size), x...), make([]float64, p.size)...))
+	if err != nil {
+		return nil, err
+	}
+	p.Reset()
+	return out[p.size:], nil
+}
+
+// processFrame runs the frame function on the current input frame, adds
+// the result into the output and moves the completed hop to ready.
+func (p *OverlapAddProcessor) processFrame() error {
+	for i, v := range p.input {
+		p.freq[i] = complex(v*p.window[i], 0)
+	}
+	if err := p.plan.Forward(p.freq, p.freq); err != nil {
+		return fmt.Errorf("overlap-add FFT: %w", err)
+	}
+	p.frame(p.freq)
+	if err := p.plan.Inverse(p.freq, p.freq); err != nil {
+		return fmt.Errorf("overlap-add FFT: %w", err)
+	}
+	for i, v := range p.freq {
+		p.output[i] += real(v) * p.window[i] * p.gain
+	}
+
+	copy(p.ready, p.output[:p.hop])
+	copy(p.output, p.output[p.hop:])
+	clear(p.output[p.size-p.hop:])
+	copy(p.input, p.input[p.hop:])
+	return nil
+}
@@ -0,0 +1,148 @@
+package sqmath_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+func noiseSignal(seed int64, n int) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = 2*r.Float64() - 1
+	}
+	return x
+}
+
+func TestOverlapAddProcessor_IdentityReconstructs(t *testing.T) {
+	t.Parallel()
+
+	x := noiseSignal(1, 5000)
+	tests := []struct {
+		window sqmath.WindowType
+		size   int
+		hop    int
+	}{
+		{sqmath.WindowHann, 256, 128},
+		{sqmath.WindowHann, 256, 64},
+		{sqmath.WindowHamming, 512, 256},
+		{sqmath.WindowBlackman, 256, 64},
+		{sqmath.WindowRectangular, 128, 128},
+		{sqmath.WindowBlackman, 256, 128}, // not COLA
+	}
+	for _, tt := range tests {
+		p, err := sqmath.NewOverlapAddProcessor(tt.size, tt.hop, tt.window, func([]complex128) {})
+		if err != nil {
+			t.Fatalf("%s %d/%d: NewOverlapAddProcessor() error = %v", tt.window, tt.size, tt.hop, err)
+		}
+		y, err := p.Apply(x)
+		if err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		if len(y) != len(x) {
+			t.Fatalf("%s %d/%d: %d samples, want %d", tt.window, tt.size, tt.hop, len(y), len(x))
+		}
+		// Each output sample is the input scaled by the window overlap at
+		// its position, within ripple/2 of 1.
+		tolerance := p.Ripple()/2 + 1e-12
+		if tt.window == sqmath.WindowBlackman && tt.hop == tt.size/2 {
+			if p.Ripple() < 0.01 {
+				t.Fatalf("blackman at half overlap ripple = %v, want a visible ripple", p.Ripple())
+			}
+		} else if p.Ripple() > 1e-12 {
+			t.Fatalf("%s %d/%d: ripple = %v, want COLA", tt.window, tt.size, tt.hop, p.Ripple())
+		}
+		for i := range x {
+			if math.Abs(y[i]-x[i]) > tolerance*math.Abs(x[i])+1e-12 {
+				t.Fatalf("%s %d/%d: sample %d = %v, want %v", tt.window, tt.size, tt.hop, i, y[i], x[i])
+			}
+		}
+	}
+}
+
+func TestOverlapAddProcessor_GainScales(t *testing.T) {
+	t.Parallel()
+
+	x := noiseSignal(2, 3000)
+	p, err := sqmath.NewOverlapAddProcessor(512, 256, sqmath.WindowHann, func(freq []complex128) {
+		for k := range freq {
+			freq[k] *= 0.25
+		}
+	})
+	if err != nil {
+		t.Fatalf("NewOverlapAddProcessor() error = %v", err)
+	}
+	y, err := p.Apply(x)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	for i := range x {
+		if math.Abs(y[i]-0.25*x[i]) > 1e-12 {
+			t.Fatalf("sample %d = %v, want %v", i, y[i], 0.25*x[i])
+		}
+	}
+}
+
+func TestOverlapAddProcessor_StreamingMatchesApply(t *testing.T) {
+	t.Parallel()
+
+	x := noiseSignal(3, 4000)
+	// Zeroing the upper half of the band is not a pure gain, so the frames
+	// interact across their overlap.
+	lowpass := func(freq []complex128) {
+		n := len(freq)
+		for k := n / 4; k <= n-n/4; k++ {
+			freq[k] = 0
+		}
+	}
+	p, err := sqmath.NewOverlapAddProcessor(256, 64, sqmath.WindowHann, lowpass)
+	if err != nil {
+		t.Fatalf("NewOverlapAddProcessor() error = %v", err)
+	}
+	want, err := p.Apply(x)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	var got []float64
+	for start := 0; start < len(x)+p.Latency(); start += 333 {
+		chunk := make([]float64, 333)
+		if start < len(x) {
+			copy(chunk, x[start:])
+		}
+		out, err := p.Process(chunk)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		got = append(got, out...)
+	}
+	for i := range want {
+		if d := math.Abs(got[i+p.Latency()] - want[i]); d > 1e-12 {
+			t.Fatalf("streamed sample %d = %v, want %v", i, got[i+p.Latency()], want[i])
+		}
+	}
+}
+
+func TestNewOverlapAddProcessor_Errors(t *testing.T) {
+	t.Parallel()
+
+	identity := func([]complex128) {}
+	tests := []struct {
+		size, hop int
+		window    sqmath.WindowType
+		frame     sqmath.FrameFunc
+	}{
+		{1000, 500, sqmath.WindowHann, identity},
+		{256, 0, sqmath.WindowHann, identity},
+		{256, 512, sqmath.WindowHann, identity},
+		{256, 128, "kaiser", identity},
+		{256, 128, sqmath.WindowHann, nil},
+	}
+	for _, tt := range tests {
+		if _, err := sqmath.NewOverlapAddProcessor(tt.size, tt.hop, tt.window, tt.frame); err == nil {
+			t.Fatalf("NewOverlapAddProcessor(%d, %d, %q) succeeded", tt.size, tt.hop, tt.window)
+		}
+	}
+}
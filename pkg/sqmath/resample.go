@@ -0,0 +1,48 @@
+package sqmath
+
+import "math"
+
+// resampleZeroCrossings is the number of sinc zero crossings on each side
+// of the interpolation kernel.
+const resampleZeroCrossings = 16
+
+// Resample converts x from sample rate from to sample rate to with a
+// Blackman-windowed sinc interpolator. When downsampling, the kernel cutoff
+// follows the lower Nyquist frequency, so content above it is removed
+// rather than aliased. Sample values keep their amplitude; the result has
+// ceil(len(x)·to/from) samples and x is treated as zero outside its range.
+func Resample(x []float64, from, to int) []float64 {
+	if from == to {
+		return append([]float64(nil), x...)
+	}
+	ratio := float64(to) / float64(from)
+	cutoff := math.Min(1, ratio)
+	// Kernel radius in input samples.
+	radius := resampleZeroCrossings / cutoff
+
+	out := make([]float64, int(math.Ceil(float64(len(x))*ratio)))
+	for i := range out {
+		t := float64(i) / ratio
+		lo := max(int(math.Ceil(t-radius)), 0)
+		hi := min(int(math.Floor(t+radius)), len(x)-1)
+		var sum float64
+		for j := lo; j <= hi; j++ {
+			d := t - float64(j)
+			sum += x[j] * cutoff * sinc(cutoff*d) * blackman(d/radius)
+		}
+		out[i] = sum
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman is the Blackman window over u in [-1, 1].
+func blackman(u float64) float64 {
+	return 0.42 + 0.5*math.Cos(math.Pi*u) + 0.08*math.Cos(2*math.Pi*u)
+}
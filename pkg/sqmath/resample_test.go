@@ -0,0 +1,60 @@
+package sqmath_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cwbudde/go-sq-tool/pkg/sqmath"
+)
+
+func sine(freq float64, sampleRate, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.Sin(2 * math.Pi * freq * float64(i) / float64(sampleRate))
+	}
+	return out
+}
+
+func TestResample_Sine(t *testing.T) {
+	t.Parallel()
+
+	for _, rates := range [][2]int{{44100, 48000}, {48000, 44100}, {48000, 96000}, {96000, 44100}} {
+		from, to := rates[0], rates[1]
+		got := sqmath.Resample(sine(1000, from, from/10), from, to)
+		if want := int(math.Ceil(float64(from/10) * float64(to) / float64(from))); len(got) != want {
+			t.Fatalf("%d -> %d: %d samples, want %d", from, to, len(got), want)
+		}
+		want := sine(1000, to, len(got))
+		// Away from the edges, where the kernel runs off the input.
+		for i := 100; i < len(got)-100; i++ {
+			if math.Abs(got[i]-want[i]) > 1e-3 {
+				t.Fatalf("%d -> %d: sample %d = %g, want %g", from, to, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestResample_RemovesContentAboveNewNyquist(t *testing.T) {
+	t.Parallel()
+
+	// 30 kHz is above the Nyquist frequency of 44.1 kHz.
+	got := sqmath.Resample(sine(30000, 96000, 9600), 96000, 44100)
+	var peak float64
+	for _, v := range got[100 : len(got)-100] {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	if peak > 1e-3 {
+		t.Fatalf("aliased peak = %g, want < 1e-3", peak)
+	}
+}
+
+func TestResample_SameRateCopies(t *testing.T) {
+	t.Parallel()
+
+	x := []float64{1, 2, 3}
+	got := sqmath.Resample(x, 48000, 48000)
+	got[0] = 0
+	if x[0] != 1 || len(got) != 3 {
+		t.Fatalf("Resample() at the same rate must return a copy")
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import "github.com/cwbudde/go-sq-tool/internal/decoder"
+
+// decodeOptions are the options accepted by the WASM sqDecodeWav function.
+// A zero BlockSize or Overlap means "not specified by the caller".
+type decodeOptions struct {
+	BlockSize int
+	Overlap   int
+	Logic     bool
+	Float32   bool
+}
+
+// withSampleRate fills in unspecified block parameters: the block size is
+// picked for the file's sample rate and the overlap defaults to half the
+// block size.
+func (o decodeOptions) withSampleRate(sampleRate int) decodeOptions {
+	if o.BlockSize == 0 {
+		recommendedBlock, recommendedOverlap := decoder.RecommendedParams(sampleRate)
+		o.BlockSize = recommendedBlock
+		if o.Overlap == 0 {
+			o.Overlap = recommendedOverlap
+		}
+	}
+	if o.Overlap == 0 {
+		o.Overlap = o.BlockSize / 2
+	}
+	return o
+}
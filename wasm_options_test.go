@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestDecodeOptions_WithSampleRate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		opts       decodeOptions
+		sampleRate int
+		want       decodeOptions
+	}{
+		{"44.1k default", decodeOptions{}, 44100, decodeOptions{BlockSize: 1024, Overlap: 512}},
+		{"96k default", decodeOptions{}, 96000, decodeOptions{BlockSize: 2048, Overlap: 1024}},
+		{"96k explicit block", decodeOptions{BlockSize: 1024}, 96000, decodeOptions{BlockSize: 1024, Overlap: 512}},
+		{"96k explicit overlap", decodeOptions{Overlap: 256}, 96000, decodeOptions{BlockSize: 2048, Overlap: 256}},
+		{"explicit both", decodeOptions{BlockSize: 4096, Overlap: 1024, Logic: true}, 44100, decodeOptions{BlockSize: 4096, Overlap: 1024, Logic: true}},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.withSampleRate(tt.sampleRate); got != tt.want {
+			t.Fatalf("%s: withSampleRate(%d) = %+v, want %+v", tt.name, tt.sampleRate, got, tt.want)
+		}
+	}
+}